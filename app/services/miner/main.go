@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"time"
+
+	"github.com/ardanlabs/conf/v3"
+
+	"github.com/qcbit/blockchain/app/services/miner/mining"
+	"github.com/qcbit/blockchain/foundation/logger"
+)
+
+// build is the git version of this program. It is set using build flags in the makefile.
+var build = "develop"
+
+func main() {
+
+	// Perform the startup and shutdown sequence. Errors are logged by run
+	// once the logger has been constructed from the configuration.
+	if err := run(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func run() (err error) {
+
+	// =========================================================================
+	// Configuration
+
+	cfg := struct {
+		conf.Version
+		Web struct {
+			DebugHost string `conf:"default:0.0.0.0:7090"`
+		}
+		Mining struct {
+			Nodes        []string      `conf:"default:0.0.0.0:9080"`
+			Workers      int           // Defaults to the number of CPUs.
+			PollInterval time.Duration `conf:"default:1s"`
+			MaxHashRate  int           // Hashes per second, 0 is unlimited.
+		}
+		Log struct {
+			Level string `conf:"default:info"` // debug, info, warn or error
+		}
+	}{
+		Version: conf.Version{
+			Build: build,
+			Desc:  "© 2023 WTFPL",
+		},
+	}
+
+	// Parse will set the defaults and then look for any overriding values
+	// in environment variables and command line flags.
+	const prefix = "MINER"
+	help, err := conf.Parse(prefix, &cfg)
+	if err != nil {
+		if errors.Is(err, conf.ErrHelpWanted) {
+			fmt.Println(help)
+			return nil
+		}
+		fmt.Println("parsing config:", err)
+		return err
+	}
+
+	if cfg.Mining.Workers <= 0 {
+		cfg.Mining.Workers = runtime.NumCPU()
+	}
+
+	// =========================================================================
+	// Logger Support
+
+	log, _, err := logger.NewWithConfig(logger.Config{
+		Service: prefix,
+		Level:   cfg.Log.Level,
+	})
+	if err != nil {
+		fmt.Println("constructing logger:", err)
+		return err
+	}
+	defer log.Sync()
+
+	defer func() {
+		if err != nil {
+			log.Errorw("startup", "ERROR", err)
+		}
+	}()
+
+	// =========================================================================
+	// App Starting
+
+	log.Infow("starting service", "version", build)
+	defer log.Infow("shutdown complete")
+
+	out, err := conf.String(&cfg)
+	if err != nil {
+		return fmt.Errorf("generating config for output: %w", err)
+	}
+	log.Infow("startup", "config", out)
+
+	// =========================================================================
+	// Start Debug Service
+
+	// The mining metrics are published through expvar.
+	debugMux := http.NewServeMux()
+	debugMux.Handle("/debug/vars", expvar.Handler())
+
+	go func() {
+		log.Infow("startup", "status", "debug router started", "host", cfg.Web.DebugHost)
+		if err := http.ListenAndServe(cfg.Web.DebugHost, debugMux); err != nil {
+			log.Errorw("shutdown", "status", "debug router closed", "host", cfg.Web.DebugHost, "ERROR", err)
+		}
+	}()
+
+	// =========================================================================
+	// Start Mining
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	miner := mining.New(mining.Config{
+		Log:          log,
+		Nodes:        cfg.Mining.Nodes,
+		Workers:      cfg.Mining.Workers,
+		PollInterval: cfg.Mining.PollInterval,
+		MaxHashRate:  cfg.Mining.MaxHashRate,
+	})
+
+	log.Infow("startup", "status", "mining started", "nodes", cfg.Mining.Nodes, "workers", cfg.Mining.Workers)
+	miner.Run(ctx)
+	log.Infow("shutdown", "status", "mining stopped")
+
+	return nil
+}
@@ -0,0 +1,289 @@
+// Package mining implements the external miner. It pulls work from nodes,
+// solves the block header on all the cores it's allowed to use and submits
+// the nonce that solves it back to the node the work came from.
+package mining
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+	"github.com/qcbit/blockchain/foundation/blockchain/signature"
+	"github.com/qcbit/blockchain/foundation/blockchain/state"
+)
+
+// batchSize is the number of hashes a worker performs between checking
+// for cancellation and applying throttling.
+const batchSize = 1_000
+
+// metrics represents the set of metrics the miner publishes through expvar.
+var metrics = struct {
+	hashes    *expvar.Int
+	hashRate  *expvar.Int
+	jobs      *expvar.Int
+	solutions *expvar.Int
+	rejected  *expvar.Int
+	nodeErrs  *expvar.Int
+}{
+	hashes:    expvar.NewInt("hashes"),
+	hashRate:  expvar.NewInt("hash_rate"),
+	jobs:      expvar.NewInt("jobs"),
+	solutions: expvar.NewInt("solutions"),
+	rejected:  expvar.NewInt("rejected"),
+	nodeErrs:  expvar.NewInt("node_errors"),
+}
+
+// Config represents the configuration of the miner.
+type Config struct {
+	Log          *zap.SugaredLogger
+	Nodes        []string      // Hosts of the private API of the nodes to pull work from.
+	Workers      int           // Number of goroutines solving the header.
+	PollInterval time.Duration // How often the nodes are asked for new work.
+	MaxHashRate  int           // Hashes per second across all workers, 0 is unlimited.
+}
+
+// Miner pulls work from the nodes and solves it.
+type Miner struct {
+	cfg    Config
+	client http.Client
+}
+
+// New constructs a miner for use.
+func New(cfg Config) *Miner {
+	return &Miner{
+		cfg:    cfg,
+		client: http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// job represents work being solved and the node it came from.
+type job struct {
+	node   string
+	work   state.Work
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// solution represents the nonce a worker found for a job.
+type solution struct {
+	job   *job
+	nonce uint64
+}
+
+// Run pulls work and mines it until the context is canceled.
+func (m *Miner) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg.PollInterval)
+	defer ticker.Stop()
+
+	solutions := make(chan solution, 1)
+	lastHashes, lastTick := metrics.hashes.Value(), time.Now()
+
+	var current *job
+	stop := func() {
+		if current != nil {
+			current.cancel()
+			current.wg.Wait()
+			current = nil
+		}
+	}
+	defer stop()
+
+	for {
+		node, work, found := m.fetchWork()
+		switch {
+		case !found:
+			stop()
+
+		case current == nil || current.node != node || current.work.ID != work.ID:
+			stop()
+			current = m.start(ctx, node, work, solutions)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+
+		case sol := <-solutions:
+			stop()
+			m.submit(sol)
+
+		case <-ticker.C:
+			hashes, now := metrics.hashes.Value(), time.Now()
+			metrics.hashRate.Set(int64(float64(hashes-lastHashes) / now.Sub(lastTick).Seconds()))
+			lastHashes, lastTick = hashes, now
+		}
+	}
+}
+
+// fetchWork asks every node for work and picks the work for the highest
+// block. Work from nodes earlier in the list wins a tie.
+func (m *Miner) fetchWork() (string, state.Work, bool) {
+	var bestNode string
+	var best state.Work
+	var found bool
+
+	for _, node := range m.cfg.Nodes {
+		work, ok, err := m.getWork(node)
+		if err != nil {
+			metrics.nodeErrs.Add(1)
+			m.cfg.Log.Infow("fetch work", "node", node, "ERROR", err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		if !found || work.Header.Number > best.Header.Number {
+			bestNode, best, found = node, work, true
+		}
+	}
+
+	return bestNode, best, found
+}
+
+// start launches the workers to solve the work.
+func (m *Miner) start(ctx context.Context, node string, work state.Work, solutions chan<- solution) *job {
+	if !signature.IsHashAlgorithm(work.HashAlgorithm) {
+		err := fmt.Errorf("hash algorithm %q does not exist", work.HashAlgorithm)
+		m.cfg.Log.Infow("start job", "node", node, "work", work.ID, "ERROR", err)
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	j := job{
+		node:   node,
+		work:   work,
+		cancel: cancel,
+	}
+
+	metrics.jobs.Add(1)
+	m.cfg.Log.Infow("start job", "node", node, "work", work.ID, "blk", work.Header.Number,
+		"difficulty", work.Header.Difficulty, "workers", m.cfg.Workers)
+
+	j.wg.Add(m.cfg.Workers)
+	for i := 0; i < m.cfg.Workers; i++ {
+		go func() {
+			defer j.wg.Done()
+			m.solve(ctx, &j, solutions)
+		}()
+	}
+
+	return &j
+}
+
+// solve searches for a nonce that solves the header starting from a random
+// nonce so the workers don't search the same nonces.
+func (m *Miner) solve(ctx context.Context, j *job, solutions chan<- solution) {
+	block := database.Block{
+		Header: j.work.Header,
+	}
+
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return
+	}
+	block.Header.Nonce = binary.BigEndian.Uint64(b[:])
+
+	// Each worker gets an equal share of the allowed hash rate.
+	var perBatch time.Duration
+	if m.cfg.MaxHashRate > 0 {
+		perBatch = time.Duration(float64(time.Second) * batchSize * float64(m.cfg.Workers) / float64(m.cfg.MaxHashRate))
+	}
+
+	for {
+		start := time.Now()
+
+		for i := 0; i < batchSize; i++ {
+			if block.IsSolvedWith(j.work.HashAlgorithm) {
+				metrics.hashes.Add(int64(i + 1))
+				select {
+				case solutions <- solution{job: j, nonce: block.Header.Nonce}:
+				case <-ctx.Done():
+				}
+				return
+			}
+			block.Header.Nonce++
+		}
+		metrics.hashes.Add(batchSize)
+
+		if wait := perBatch - time.Since(start); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// getWork asks the node for work. The bool is false when the node has no
+// transactions to mine.
+func (m *Miner) getWork(node string) (state.Work, bool, error) {
+	resp, err := m.client.Get(fmt.Sprintf("http://%s/v1/node/mining/work", node))
+	if err != nil {
+		return state.Work{}, false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNoContent:
+		return state.Work{}, false, nil
+	default:
+		return state.Work{}, false, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	var work state.Work
+	if err := json.NewDecoder(resp.Body).Decode(&work); err != nil {
+		return state.Work{}, false, err
+	}
+
+	return work, true, nil
+}
+
+// submit sends the solution to the node the work came from.
+func (m *Miner) submit(sol solution) {
+	metrics.solutions.Add(1)
+
+	data, err := json.Marshal(struct {
+		ID    string `json:"id"`
+		Nonce uint64 `json:"nonce"`
+	}{
+		ID:    sol.job.work.ID,
+		Nonce: sol.nonce,
+	})
+	if err != nil {
+		return
+	}
+
+	url := fmt.Sprintf("http://%s/v1/node/mining/submit", sol.job.node)
+	resp, err := m.client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		metrics.nodeErrs.Add(1)
+		m.cfg.Log.Infow("submit", "node", sol.job.node, "work", sol.job.work.ID, "ERROR", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		metrics.rejected.Add(1)
+		m.cfg.Log.Infow("submit", "node", sol.job.node, "work", sol.job.work.ID, "nonce", sol.nonce, "status", resp.StatusCode)
+		return
+	}
+
+	m.cfg.Log.Infow("submit", "node", sol.job.node, "work", sol.job.work.ID, "nonce", sol.nonce, "status", "accepted")
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/qcbit/blockchain/business/web/cache"
+	webv1 "github.com/qcbit/blockchain/business/web/v1"
+	"github.com/qcbit/blockchain/foundation/blockchain/state"
+)
+
+// Chain represents one of the extra chains hosted in the process next to
+// the main chain, with its own state and response cache.
+type Chain struct {
+	ID    string
+	State *state.State
+	Cache *cache.Cache
+}
+
+// chainInfo describes a chain hosted in the process.
+type chainInfo struct {
+	ID          string `json:"id"`
+	ChainID     uint16 `json:"chain_id"`
+	LatestBlock uint64 `json:"latest_block"`
+}
+
+// ChainsMux serves the extra chains under /{version}/chains/{id}/ next to
+// the main chain's mux. The mux of each chain is constructed by the build
+// function from the main chain's config, so /v1/chains/class1/accounts/list
+// is served as /v1/accounts/list by the class1 chain. GET /v1/chains lists
+// the chains and every other request goes to the main chain.
+func ChainsMux(main http.Handler, chains []Chain, cfg MuxConfig, build func(MuxConfig) http.Handler) http.Handler {
+	if len(chains) == 0 {
+		return main
+	}
+
+	muxes := make(map[string]http.Handler, len(chains))
+	for _, chain := range chains {
+		chainCfg := cfg
+		chainCfg.State = chain.State
+		chainCfg.Cache = chain.Cache
+		chainCfg.Spec = nil
+		muxes[chain.ID] = build(chainCfg)
+	}
+
+	h := func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 4)
+		if len(parts) < 2 || parts[1] != "chains" {
+			main.ServeHTTP(w, r)
+			return
+		}
+
+		if len(parts) == 2 {
+			if r.Method != http.MethodGet {
+				respondJSON(w, webv1.ErrorResponse{Error: http.StatusText(http.StatusMethodNotAllowed)}, http.StatusMethodNotAllowed)
+				return
+			}
+
+			infos := make([]chainInfo, len(chains))
+			for i, chain := range chains {
+				infos[i] = chainInfo{
+					ID:          chain.ID,
+					ChainID:     chain.State.Genesis().ChainID,
+					LatestBlock: chain.State.LatestBlock().Header.Number,
+				}
+			}
+			respondJSON(w, infos, http.StatusOK)
+			return
+		}
+
+		mux, exists := muxes[parts[2]]
+		if !exists {
+			respondJSON(w, webv1.ErrorResponse{Error: "chain " + parts[2] + " not found"}, http.StatusNotFound)
+			return
+		}
+
+		// Serve the request as if it was made to the chain's own host.
+		var rest string
+		if len(parts) == 4 {
+			rest = parts[3]
+		}
+		url := *r.URL
+		url.Path = "/" + parts[0] + "/" + rest
+		url.RawPath = ""
+
+		cr := r.Clone(r.Context())
+		cr.URL = &url
+		cr.RequestURI = url.RequestURI()
+		mux.ServeHTTP(w, cr)
+	}
+
+	return http.HandlerFunc(h)
+}
+
+// respondJSON writes the value for the requests handled outside of a mux.
+func respondJSON(w http.ResponseWriter, v any, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(v)
+}
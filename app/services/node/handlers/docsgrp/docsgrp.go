@@ -0,0 +1,65 @@
+// Package docsgrp maintains the handlers that serve the OpenAPI document of
+// the public API so client SDKs can be generated from it.
+package docsgrp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/qcbit/blockchain/foundation/openapi"
+	"github.com/qcbit/blockchain/foundation/web"
+)
+
+// Handlers manages the set of documentation endpoints.
+type Handlers struct {
+	Log  *zap.SugaredLogger
+	Spec *openapi.Spec
+}
+
+// OpenAPI returns the OpenAPI document of the public API.
+func (h Handlers) OpenAPI(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	return web.Respond(ctx, w, h.Spec.Document(), http.StatusOK)
+}
+
+// Document returns the OpenAPI document on the debug mux for the Swagger UI.
+func (h Handlers) Document(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(h.Spec.Document()); err != nil {
+		h.Log.Errorw("openapi", "ERROR", err)
+	}
+}
+
+// SwaggerUI returns a page that browses the OpenAPI document served next to
+// it on the debug mux. The Swagger UI assets are loaded from a CDN.
+func (h Handlers) SwaggerUI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write([]byte(swaggerUI)); err != nil {
+		h.Log.Errorw("swagger", "ERROR", err)
+	}
+}
+
+const swaggerUI = `<!DOCTYPE html>
+<html lang="en">
+<head>
+	<meta charset="utf-8">
+	<title>Node API</title>
+	<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+	<div id="swagger-ui"></div>
+	<script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+	<script>
+		window.onload = function() {
+			window.ui = SwaggerUIBundle({url: "/debug/openapi.json", dom_id: "#swagger-ui"});
+		};
+	</script>
+</body>
+</html>
+`
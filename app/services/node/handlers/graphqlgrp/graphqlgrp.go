@@ -0,0 +1,55 @@
+// Package graphqlgrp maintains the GraphQL endpoint explorers use to fetch
+// blocks, transactions, accounts and receipts in a single request.
+package graphqlgrp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	v1 "github.com/qcbit/blockchain/business/web/v1"
+	"github.com/qcbit/blockchain/foundation/blockchain/nameservice"
+	"github.com/qcbit/blockchain/foundation/blockchain/state"
+	"github.com/qcbit/blockchain/foundation/graphql"
+	"github.com/qcbit/blockchain/foundation/web"
+)
+
+// Handlers manages the GraphQL endpoint.
+type Handlers struct {
+	Log   *zap.SugaredLogger
+	State *state.State
+	NS    *nameservice.NameService
+}
+
+// Query runs a GraphQL query. The query is posted as a JSON document or
+// passed in the query string of a GET request.
+func (h Handlers) Query(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var req graphql.Request
+
+	switch r.Method {
+	case http.MethodGet:
+		req.Query = r.URL.Query().Get("query")
+		req.OperationName = r.URL.Query().Get("operationName")
+		if vars := r.URL.Query().Get("variables"); vars != "" {
+			if err := json.Unmarshal([]byte(vars), &req.Variables); err != nil {
+				return v1.NewRequestError(fmt.Errorf("unable to decode variables: %w", err), http.StatusBadRequest)
+			}
+		}
+
+	default:
+		if err := web.Decode(r, &req); err != nil {
+			return v1.NewRequestError(fmt.Errorf("unable to decode payload: %w", err), http.StatusBadRequest)
+		}
+	}
+
+	if req.Query == "" {
+		return v1.NewRequestError(fmt.Errorf("query is required"), http.StatusBadRequest)
+	}
+
+	resp := graphql.Execute(ctx, h.query(), req)
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
@@ -0,0 +1,391 @@
+package graphqlgrp
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+	"github.com/qcbit/blockchain/foundation/blockchain/state"
+	"github.com/qcbit/blockchain/foundation/graphql"
+)
+
+// The page sizes of the lists.
+const (
+	defaultPageSize = 10
+	maxPageSize     = 100
+)
+
+// field returns a resolver for a value that's already known.
+func field(v any) graphql.Resolver {
+	return func(ctx context.Context, args graphql.Args) (any, error) {
+		return v, nil
+	}
+}
+
+// pageSize returns the number of items asked for with the first argument.
+func pageSize(args graphql.Args) (int, error) {
+	first, ok, err := args.Uint("first")
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return defaultPageSize, nil
+	}
+	if first > maxPageSize {
+		return 0, fmt.Errorf("first can't be more than %d", maxPageSize)
+	}
+	return int(first), nil
+}
+
+// connection returns a page of a list with the cursor to ask for the page
+// after it.
+func connection(name string, nodes []graphql.Object, endCursor any, hasNextPage bool) graphql.Object {
+	return graphql.Object{
+		Name: name,
+		Fields: map[string]graphql.Resolver{
+			"nodes": field(nodes),
+			"pageInfo": field(graphql.Object{
+				Name: "PageInfo",
+				Fields: map[string]graphql.Resolver{
+					"endCursor":   field(endCursor),
+					"hasNextPage": field(hasNextPage),
+				},
+			}),
+		},
+	}
+}
+
+// =============================================================================
+
+// query returns the root of the schema.
+func (h Handlers) query() graphql.Object {
+	return graphql.Object{
+		Name: "Query",
+		Fields: map[string]graphql.Resolver{
+			"latestBlock": func(ctx context.Context, args graphql.Args) (any, error) {
+				return h.block(h.State.LatestBlock()), nil
+			},
+			"block": func(ctx context.Context, args graphql.Args) (any, error) {
+				number, ok, err := args.Uint("number")
+				if err != nil {
+					return nil, err
+				}
+				if !ok {
+					return nil, fmt.Errorf("number is required")
+				}
+				return h.blockByNumber(ctx, number)
+			},
+			"blocks":      h.blocks,
+			"transaction": h.transaction,
+			"receipt": func(ctx context.Context, args graphql.Args) (any, error) {
+				hash, _, err := args.String("hash")
+				if err != nil {
+					return nil, err
+				}
+				receipt, exists := h.State.QueryReceipt(hash)
+				if !exists {
+					return nil, nil
+				}
+				return h.receipt(receipt), nil
+			},
+			"account": func(ctx context.Context, args graphql.Args) (any, error) {
+				id, _, err := args.String("id")
+				if err != nil {
+					return nil, err
+				}
+				accountID, err := database.ToAccountID(id)
+				if err != nil {
+					return nil, err
+				}
+				return h.account(accountID), nil
+			},
+			"accounts": h.accounts,
+		},
+	}
+}
+
+// blocks returns a page of blocks from the latest block back. The cursor
+// is the number of the last block in the page.
+func (h Handlers) blocks(ctx context.Context, args graphql.Args) (any, error) {
+	first, err := pageSize(args)
+	if err != nil {
+		return nil, err
+	}
+
+	to := h.State.LatestBlock().Header.Number
+	if before, ok, err := args.Uint("before"); err != nil {
+		return nil, err
+	} else if ok {
+		if before <= 1 {
+			return connection("BlockConnection", []graphql.Object{}, nil, false), nil
+		}
+		to = before - 1
+	}
+	if to == 0 || first == 0 {
+		return connection("BlockConnection", []graphql.Object{}, nil, false), nil
+	}
+
+	from := uint64(1)
+	if to > uint64(first) {
+		from = to - uint64(first) + 1
+	}
+
+	blocks, err := h.State.QueryBlocksByNumber(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]graphql.Object, 0, len(blocks))
+	for i := len(blocks) - 1; i >= 0; i-- {
+		nodes = append(nodes, h.block(blocks[i]))
+	}
+
+	return connection("BlockConnection", nodes, from, from > 1), nil
+}
+
+// blockByNumber returns the block with the number or nil when there isn't
+// one.
+func (h Handlers) blockByNumber(ctx context.Context, number uint64) (any, error) {
+	if number == 0 || number > h.State.LatestBlock().Header.Number {
+		return nil, nil
+	}
+
+	blocks, err := h.State.QueryBlocksByNumber(ctx, number, number)
+	if err != nil {
+		return nil, err
+	}
+	if len(blocks) == 0 {
+		return nil, nil
+	}
+
+	return h.block(blocks[0]), nil
+}
+
+// transaction returns the mined transaction with the hash or nil when it
+// hasn't been mined.
+func (h Handlers) transaction(ctx context.Context, args graphql.Args) (any, error) {
+	hash, _, err := args.String("hash")
+	if err != nil {
+		return nil, err
+	}
+
+	receipt, exists := h.State.QueryReceipt(hash)
+	if !exists {
+		return nil, nil
+	}
+
+	blocks, err := h.State.QueryBlocksByNumber(ctx, receipt.BlockNumber, receipt.BlockNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, block := range blocks {
+		algorithm := h.State.HashAlgorithm(block.Header.Number)
+		for _, tx := range block.MerkleTree.Values() {
+			if tx.TxHash(algorithm) == hash {
+				return h.tx(algorithm, tx), nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// accounts returns a page of the accounts sorted by account ID. The cursor
+// is the ID of the last account in the page.
+func (h Handlers) accounts(ctx context.Context, args graphql.Args) (any, error) {
+	first, err := pageSize(args)
+	if err != nil {
+		return nil, err
+	}
+
+	after, _, err := args.String("after")
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, err := h.State.QueryAccountsAt(state.QueryLatest)
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []graphql.Object
+	var endCursor any
+	hasNextPage := false
+	for _, account := range accounts {
+		if after != "" && string(account.AccountID) <= after {
+			continue
+		}
+		if len(nodes) == first {
+			hasNextPage = true
+			break
+		}
+		nodes = append(nodes, h.account(account.AccountID))
+		endCursor = string(account.AccountID)
+	}
+
+	if nodes == nil {
+		nodes = []graphql.Object{}
+	}
+
+	return connection("AccountConnection", nodes, endCursor, hasNextPage), nil
+}
+
+// =============================================================================
+
+// block returns the fields of the block.
+func (h Handlers) block(block database.Block) graphql.Object {
+	hdr := block.Header
+
+	var trans []database.BlockTx
+	if block.MerkleTree != nil {
+		trans = block.MerkleTree.Values()
+	}
+
+	return graphql.Object{
+		Name: "Block",
+		Fields: map[string]graphql.Resolver{
+			"version":          field(hdr.Version),
+			"number":           field(hdr.Number),
+			"hash":             field(block.Hash()),
+			"prevBlockHash":    field(hdr.PrevBlockHash),
+			"timestamp":        field(hdr.TimeStamp),
+			"beneficiary":      field(string(hdr.BeneficiaryID)),
+			"difficulty":       field(hdr.Difficulty),
+			"miningReward":     field(hdr.MiningReward),
+			"stateRoot":        field(hdr.StateRoot),
+			"transRoot":        field(hdr.TransRoot),
+			"nonce":            field(hdr.Nonce),
+			"extraData":        field(hdr.ExtraData),
+			"transactionCount": field(len(trans)),
+
+			// The cursor is the index of the last transaction in the page.
+			"transactions": func(ctx context.Context, args graphql.Args) (any, error) {
+				first, err := pageSize(args)
+				if err != nil {
+					return nil, err
+				}
+
+				start := 0
+				if after, ok, err := args.Uint("after"); err != nil {
+					return nil, err
+				} else if ok {
+					start = int(after) + 1
+				}
+
+				nodes := []graphql.Object{}
+				var endCursor any
+				for i := start; i < len(trans) && len(nodes) < first; i++ {
+					nodes = append(nodes, h.tx(h.State.HashAlgorithm(hdr.Number), trans[i]))
+					endCursor = i
+				}
+
+				hasNextPage := start+len(nodes) < len(trans)
+				return connection("TransactionConnection", nodes, endCursor, hasNextPage), nil
+			},
+			"parent": func(ctx context.Context, args graphql.Args) (any, error) {
+				if hdr.Number <= 1 {
+					return nil, nil
+				}
+				return h.blockByNumber(ctx, hdr.Number-1)
+			},
+		},
+	}
+}
+
+// tx returns the fields of the transaction. The transaction is identified
+// by its hash with the algorithm of the block it's in.
+func (h Handlers) tx(algorithm string, tx database.BlockTx) graphql.Object {
+	hash := tx.TxHash(algorithm)
+
+	return graphql.Object{
+		Name: "Transaction",
+		Fields: map[string]graphql.Resolver{
+			"hash":      field(hash),
+			"from":      field(string(tx.FromID)),
+			"fromName":  field(h.NS.Lookup(tx.FromID)),
+			"to":        field(string(tx.ToID)),
+			"toName":    field(h.NS.Lookup(tx.ToID)),
+			"value":     field(tx.Value),
+			"tip":       field(tx.Tip),
+			"nonce":     field(tx.Nonce),
+			"data":      field(hex.EncodeToString(tx.Data)),
+			"timestamp": field(tx.TimeStamp),
+			"gasPrice":  field(tx.GasPrice),
+			"gasUnits":  field(tx.GasUnits),
+			"fromAccount": func(ctx context.Context, args graphql.Args) (any, error) {
+				return h.account(tx.FromID), nil
+			},
+			"toAccount": func(ctx context.Context, args graphql.Args) (any, error) {
+				return h.account(tx.ToID), nil
+			},
+			"receipt": func(ctx context.Context, args graphql.Args) (any, error) {
+				receipt, exists := h.State.QueryReceipt(hash)
+				if !exists {
+					return nil, nil
+				}
+				return h.receipt(receipt), nil
+			},
+		},
+	}
+}
+
+// receipt returns the fields of the receipt.
+func (h Handlers) receipt(receipt database.Receipt) graphql.Object {
+	return graphql.Object{
+		Name: "Receipt",
+		Fields: map[string]graphql.Resolver{
+			"blockNumber": field(receipt.BlockNumber),
+			"status":      field(receipt.Status),
+			"error":       field(receipt.Error),
+			"block": func(ctx context.Context, args graphql.Args) (any, error) {
+				return h.blockByNumber(ctx, receipt.BlockNumber)
+			},
+		},
+	}
+}
+
+// account returns the fields of the account. An account that has never
+// been used has a zero balance.
+func (h Handlers) account(accountID database.AccountID) graphql.Object {
+	lookup := func() database.Account {
+		account, err := h.State.QueryAccount(accountID)
+		if err != nil {
+			return database.Account{AccountID: accountID}
+		}
+		return account
+	}
+
+	return graphql.Object{
+		Name: "Account",
+		Fields: map[string]graphql.Resolver{
+			"id":   field(string(accountID)),
+			"name": field(h.NS.Lookup(accountID)),
+			"balance": func(ctx context.Context, args graphql.Args) (any, error) {
+				return lookup().Balance, nil
+			},
+			"nonce": func(ctx context.Context, args graphql.Args) (any, error) {
+				return lookup().Nonce, nil
+			},
+			"frozen": func(ctx context.Context, args graphql.Args) (any, error) {
+				return lookup().Frozen, nil
+			},
+			"summary": func(ctx context.Context, args graphql.Args) (any, error) {
+				s := h.State.QuerySummary(accountID)
+				return graphql.Object{
+					Name: "AccountSummary",
+					Fields: map[string]graphql.Resolver{
+						"transactions":   field(s.Transactions),
+						"totalSent":      field(s.TotalSent),
+						"totalReceived":  field(s.TotalReceived),
+						"feesPaid":       field(s.FeesPaid),
+						"firstBlock":     field(s.FirstBlock),
+						"lastBlock":      field(s.LastBlock),
+						"counterparties": field(s.Counterparties),
+					},
+				}, nil
+			},
+		},
+	}
+}
@@ -4,28 +4,86 @@ package handlers
 import (
 	"context"
 	"expvar"
+	"fmt"
 	"net/http"
 	"net/http/pprof"
 	"os"
+	"time"
 
 	"go.uber.org/zap"
 
 	"github.com/qcbit/blockchain/app/services/node/handlers/debug/checkgrp"
+	"github.com/qcbit/blockchain/app/services/node/handlers/docsgrp"
+	"github.com/qcbit/blockchain/app/services/node/handlers/graphqlgrp"
 	v1 "github.com/qcbit/blockchain/app/services/node/handlers/v1"
+	v2 "github.com/qcbit/blockchain/app/services/node/handlers/v2"
+	"github.com/qcbit/blockchain/business/web/apikey"
+	"github.com/qcbit/blockchain/business/web/audit"
+	"github.com/qcbit/blockchain/business/web/cache"
+	webv1 "github.com/qcbit/blockchain/business/web/v1"
 	"github.com/qcbit/blockchain/business/web/v1/mid"
 	"github.com/qcbit/blockchain/foundation/blockchain/nameservice"
 	"github.com/qcbit/blockchain/foundation/blockchain/state"
+	"github.com/qcbit/blockchain/foundation/graphql"
+	"github.com/qcbit/blockchain/foundation/openapi"
 	"github.com/qcbit/blockchain/foundation/web"
 )
 
 // MuxConfig contains all the mandatory systems required by handlers.
 type MuxConfig struct {
-	Shutdown chan os.Signal
-	Log      *zap.SugaredLogger
-	State    *state.State
-	NS       *nameservice.NameService
+	Shutdown      chan os.Signal
+	Log           *zap.SugaredLogger
+	State         *state.State
+	NS            *nameservice.NameService
+	Audit         *audit.Log
+	KeyPath       string
+	Build         string
+	Timeout       time.Duration // Deadline of each request, usually the write timeout.
+	GraphQL       bool          // Serve the GraphQL endpoint for explorers.
+	Spec          *openapi.Spec // Describes the public routes, see NewSpec.
+	Cache         *cache.Cache  // Holds the responses of hot read routes, see NewCache.
+	APIKeys       *apikey.Keys  // Keys required on the public API, nil leaves it open.
+	StringNumbers bool          // Encode the uint64 fields of v2 responses as strings.
 }
 
+// NewSpec constructs the OpenAPI description of the public API. The routes
+// are described as they are loaded by PublicMux.
+func NewSpec(build string) *openapi.Spec {
+	info := openapi.Info{
+		Title:       "Blockchain Node API",
+		Version:     build,
+		Description: "The public API of a blockchain node. The v1 routes are superseded by the v2 routes.",
+	}
+
+	return openapi.New(info, webv1.ErrorResponse{})
+}
+
+// NewCache constructs the cache of the responses of hot read routes. The
+// responses are kept for the latest block and mempool length they were
+// built for, and responses about a single account are kept until a block
+// changes the account.
+func NewCache(st *state.State, maxEntries int) *cache.Cache {
+	version := func() string {
+		return fmt.Sprintf("%s/%d", st.LatestBlock().Hash(), st.MempoolLength())
+	}
+	c := cache.New(version, maxEntries)
+
+	st.Subscribe(state.SubscriberFuncs{
+		AccountsChanged: func(change state.AccountsChange) {
+			accounts := make([]string, len(change.Accounts))
+			for i, accountID := range change.Accounts {
+				accounts[i] = string(accountID)
+			}
+			c.Invalidate(accounts)
+		},
+	})
+
+	return c
+}
+
+// maxGraphQLBodySize is the limit on the size of a posted GraphQL query.
+const maxGraphQLBodySize = 64 << 10 // 64KB
+
 // PublicMux constructs a http.Handler with all application routes defined.
 func PublicMux(cfg MuxConfig) http.Handler {
 
@@ -33,9 +91,12 @@ func PublicMux(cfg MuxConfig) http.Handler {
 	app := web.NewApp(
 		cfg.Shutdown,
 		mid.Logger(cfg.Log),
+		mid.Audit(cfg.Log, cfg.Audit, "public"),
 		mid.Errors(cfg.Log),
 		mid.Metrics(),
 		mid.Cors("*"),
+		mid.APIKey(cfg.APIKeys),
+		mid.Deadline(cfg.Timeout),
 		mid.Panics(),
 	)
 
@@ -52,8 +113,69 @@ func PublicMux(cfg MuxConfig) http.Handler {
 		Log:   cfg.Log,
 		State: cfg.State,
 		NS:    cfg.NS,
+		Audit: cfg.Audit,
+		Spec:  cfg.Spec,
+		Cache: cfg.Cache,
+	})
+
+	// Load the v2 routes.
+	v2.PublicRoutes(app, v2.Config{
+		Log:           cfg.Log,
+		State:         cfg.State,
+		NS:            cfg.NS,
+		Spec:          cfg.Spec,
+		Cache:         cfg.Cache,
+		StringNumbers: cfg.StringNumbers,
 	})
 
+	// Load the GraphQL endpoint for explorers when turned on.
+	if cfg.GraphQL {
+		gql := graphqlgrp.Handlers{
+			Log:   cfg.Log,
+			State: cfg.State,
+			NS:    cfg.NS,
+		}
+		app.Handle(http.MethodGet, "", "/graphql", gql.Query, mid.Compress(), mid.Cache(cfg.Cache, ""))
+		app.Handle(http.MethodPost, "", "/graphql", gql.Query, mid.Compress(), mid.MaxBodySize(maxGraphQLBodySize))
+
+		if cfg.Spec != nil {
+			cfg.Spec.Describe("", openapi.Operations{
+				"GET /graphql": {
+					Summary: "Runs the GraphQL query passed in the query, operationName and variables parameters.",
+					Query: []openapi.Param{
+						{Name: "query", Required: true},
+						{Name: "operationName"},
+						{Name: "variables", Description: "Values of the variables as a JSON object."},
+					},
+					Response: graphql.Response{},
+				},
+				"POST /graphql": {
+					Summary:  "Runs the posted GraphQL query.",
+					Request:  graphql.Request{},
+					Response: graphql.Response{},
+				},
+			})
+		}
+	}
+
+	// Serve the OpenAPI document built from the routes loaded above so it
+	// always matches what the mux serves.
+	if cfg.Spec != nil {
+		docs := docsgrp.Handlers{
+			Log:  cfg.Log,
+			Spec: cfg.Spec,
+		}
+		app.Handle(http.MethodGet, "v1", "/openapi.json", docs.OpenAPI)
+
+		cfg.Spec.Describe("v1", openapi.Operations{
+			"GET /openapi.json": {
+				Summary:  "Returns this document.",
+				Response: map[string]any{},
+			},
+		})
+		cfg.Spec.SetRoutes(app.Routes())
+	}
+
 	return app
 }
 
@@ -64,9 +186,12 @@ func PrivateMux(cfg MuxConfig) http.Handler {
 	app := web.NewApp(
 		cfg.Shutdown,
 		mid.Logger(cfg.Log),
+		mid.Audit(cfg.Log, cfg.Audit, "private"),
 		mid.Errors(cfg.Log),
 		mid.Metrics(),
 		mid.Cors("*"),
+		mid.Bandwidth(cfg.State.Bandwidth()),
+		mid.Deadline(cfg.Timeout),
 		mid.Panics(),
 	)
 
@@ -80,9 +205,12 @@ func PrivateMux(cfg MuxConfig) http.Handler {
 
 	// Load the v1 routes.
 	v1.PrivateRoutes(app, v1.Config{
-		Log:   cfg.Log,
-		State: cfg.State,
-		NS:    cfg.NS,
+		Log:     cfg.Log,
+		State:   cfg.State,
+		NS:      cfg.NS,
+		Audit:   cfg.Audit,
+		KeyPath: cfg.KeyPath,
+		Build:   cfg.Build,
 	})
 
 	return app
@@ -110,7 +238,7 @@ func DebugStandardLibraryMux() *http.ServeMux {
 // debug application routes for the service. This bypassing the use of the
 // DefaultServerMux. Using the DefaultServerMux would be a security risk since
 // a dependency could inject a handler into our service without us knowing it.
-func DebugMux(build string, log *zap.SugaredLogger) http.Handler {
+func DebugMux(build string, log *zap.SugaredLogger, level zap.AtomicLevel, spec *openapi.Spec) http.Handler {
 	mux := DebugStandardLibraryMux()
 
 	// Register debug check endpoints.
@@ -121,5 +249,19 @@ func DebugMux(build string, log *zap.SugaredLogger) http.Handler {
 	mux.HandleFunc("/debug/readiness", cgh.Readiness)
 	mux.HandleFunc("/debug/liveness", cgh.Liveness)
 
+	// GET returns the current logging level and PUT changes it at runtime
+	// using a JSON body like {"level":"debug"}.
+	mux.Handle("/debug/loglevel", level)
+
+	// Browse the OpenAPI document of the public API.
+	if spec != nil {
+		dgh := docsgrp.Handlers{
+			Log:  log,
+			Spec: spec,
+		}
+		mux.HandleFunc("/debug/openapi.json", dgh.Document)
+		mux.HandleFunc("/debug/swagger", dgh.SwaggerUI)
+	}
+
 	return mux
 }
@@ -0,0 +1,100 @@
+package private
+
+import (
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+	"github.com/qcbit/blockchain/foundation/blockchain/payout"
+	"github.com/qcbit/blockchain/foundation/blockchain/peer"
+	"github.com/qcbit/blockchain/foundation/blockchain/state"
+)
+
+type candidate struct {
+	Header  database.BlockHeader `json:"header"`
+	Trans   []database.BlockTx   `json:"trans"`
+	GasFees uint64               `json:"gas_fees"`
+	Tips    uint64               `json:"tips"`
+}
+
+type workSolution struct {
+	ID    string `json:"id"`
+	Nonce uint64 `json:"nonce"`
+}
+
+type peerBandwidth struct {
+	Limit int64                 `json:"limit"`
+	Peers map[string]peer.Usage `json:"peers"`
+}
+
+type peerInfo struct {
+	Host               string  `json:"host"`
+	Reported           bool    `json:"reported"`
+	ProtocolVersion    uint16  `json:"protocol_version"`
+	MinProtocolVersion uint16  `json:"min_protocol_version"`
+	Build              string  `json:"build,omitempty"`
+	Compatible         bool    `json:"compatible"`
+	LatencyMS          int64   `json:"latency_ms"`
+	Throughput         float64 `json:"throughput_bps"`
+	Failures           int     `json:"failures"`
+}
+
+type blockRevenue struct {
+	Number          uint64             `json:"number"`
+	Hash            string             `json:"hash"`
+	Beneficiary     database.AccountID `json:"beneficiary"`
+	Reward          uint64             `json:"reward"`
+	Tips            uint64             `json:"tips"`
+	GasFees         uint64             `json:"gas_fees"`
+	Total           uint64             `json:"total"`
+	Trans           int                `json:"trans"`
+	MissingReceipts int                `json:"missing_receipts,omitempty"`
+}
+
+type miningRevenue struct {
+	FromBlock uint64         `json:"from_block"`
+	ToBlock   uint64         `json:"to_block"`
+	Mined     int            `json:"mined"`
+	Reward    uint64         `json:"reward"`
+	Tips      uint64         `json:"tips"`
+	GasFees   uint64         `json:"gas_fees"`
+	Total     uint64         `json:"total"`
+	Blocks    []blockRevenue `json:"blocks"`
+}
+
+type poolPayouts struct {
+	Shares  []payout.Share  `json:"shares"`
+	Payouts []payout.Payout `json:"payouts"`
+}
+
+type accountExport struct {
+	Account database.AccountID `json:"account"`
+	Balance uint64             `json:"balance"`
+	Nonce   uint64             `json:"nonce"`
+}
+
+type backupInfo struct {
+	Path   string `json:"path"`
+	Blocks uint64 `json:"blocks"`
+	Size   int64  `json:"size"`
+}
+
+type newWebhook struct {
+	URL      string             `json:"url"`
+	Account  database.AccountID `json:"account"`
+	MinValue uint64             `json:"min_value"`
+}
+
+type syncProgress struct {
+	Syncing          bool   `json:"syncing"`
+	StartingBlock    uint64 `json:"starting_block"`
+	CurrentBlock     uint64 `json:"current_block"`
+	HighestBlock     uint64 `json:"highest_block"`
+	RemainingSeconds int64  `json:"remaining_seconds"`
+}
+
+type chainResetRequest struct {
+	ChainID uint16 `json:"chain_id"`
+}
+
+type chainReset struct {
+	state.ChainReset
+	Peers []state.PeerReset `json:"peers"`
+}
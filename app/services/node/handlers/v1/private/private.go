@@ -3,45 +3,86 @@ package private
 
 import (
 	"context"
+	"encoding/csv"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"os"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"go.uber.org/zap"
 
+	"github.com/qcbit/blockchain/business/web/audit"
 	v1 "github.com/qcbit/blockchain/business/web/v1"
+	"github.com/qcbit/blockchain/business/web/v1/mid"
+	"github.com/qcbit/blockchain/foundation/blockchain/backup"
 	"github.com/qcbit/blockchain/foundation/blockchain/database"
 	"github.com/qcbit/blockchain/foundation/blockchain/nameservice"
 	"github.com/qcbit/blockchain/foundation/blockchain/peer"
+	"github.com/qcbit/blockchain/foundation/blockchain/protocol"
 	"github.com/qcbit/blockchain/foundation/blockchain/state"
+	"github.com/qcbit/blockchain/foundation/blockchain/webhook"
 	"github.com/qcbit/blockchain/foundation/web"
 )
 
 // Handlers manages the set of bar ledger endpoints.
 type Handlers struct {
-	Log   *zap.SugaredLogger
-	State *state.State
-	NS    *nameservice.NameService
+	Log     *zap.SugaredLogger
+	State   *state.State
+	NS      *nameservice.NameService
+	Audit   *audit.Log
+	KeyPath string // The node's private key file included in backups.
+	Build   string
 }
 
 // Status returns the current status of the node.
 func (h Handlers) Status(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 	latestBlock := h.State.LatestBlock()
+	drift, _ := h.State.ClockDrift()
 
 	status := peer.PeerStatus{
-		LatestBlockHash:   latestBlock.Hash(),
-		LatestBlockNumber: latestBlock.Header.Number,
-		KnownPeers:        h.State.KnownExternalPeers(),
+		LatestBlockHash:    latestBlock.Hash(),
+		LatestBlockNumber:  latestBlock.Header.Number,
+		KnownPeers:         h.State.KnownExternalPeers(),
+		ProtocolVersion:    protocol.CurrentVersion,
+		MinProtocolVersion: protocol.MinVersion,
+		Build:              h.Build,
+		FullyValidated:     h.State.FullyValidated(),
+		ClockDrift:         drift.Milliseconds(),
+		RelayOnly:          !h.State.CanMine(),
+	}
+
+	if err := h.State.SignStatus(&status); err != nil {
+		return fmt.Errorf("signing status: %w", err)
 	}
 
 	return web.Respond(ctx, w, status, http.StatusOK)
 }
 
-// Mempool returns the set of uncommitted transactions.
+// Sync returns how far the node is through syncing the chain from its peers.
+func (h Handlers) Sync(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	progress := h.State.SyncProgress()
+
+	resp := syncProgress{
+		Syncing:          progress.Syncing,
+		StartingBlock:    progress.StartingBlock,
+		CurrentBlock:     progress.CurrentBlock,
+		HighestBlock:     progress.HighestBlock,
+		RemainingSeconds: int64(progress.Remaining.Round(time.Second).Seconds()),
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// Mempool returns the set of uncommitted transactions in the protocol
+// version the requesting node speaks.
 func (h Handlers) Mempool(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 	txs := h.State.Mempool()
-	return web.Respond(ctx, w, txs, http.StatusOK)
+	return web.Respond(ctx, w, protocol.EncodeMempool(protocol.Requested(r), txs), http.StatusOK)
 }
 
 // BlocksByNumber returns all the blocks based on the specified to/from values.
@@ -69,17 +110,119 @@ func (h Handlers) BlocksByNumber(ctx context.Context, w http.ResponseWriter, r *
 		return v1.NewRequestError(errors.New("from greater than to"), http.StatusBadRequest)
 	}
 
-	blocks := h.State.QueryBlocksByNumber(from, to)
+	blocks, err := h.State.QueryBlocksByNumber(ctx, from, to)
+	if err != nil {
+		return err
+	}
 	if len(blocks) == 0 {
 		return web.Respond(ctx, w, nil, http.StatusNoContent)
 	}
 
-	blockData := make([]database.BlockData, len(blocks))
-	for i, block := range blocks {
-		blockData[i] = database.NewBlockData(block)
+	return web.Respond(ctx, w, protocol.EncodeBlocks(protocol.Requested(r), blocks), http.StatusOK)
+}
+
+// The limits on how long a read replica's request for blocks is held.
+const (
+	defaultBlockWait = 30 * time.Second
+	maxBlockWait     = 2 * time.Minute
+)
+
+// WaitForBlocks holds the request until a block higher than the after query
+// parameter is accepted and returns the blocks after it. Read replicas use
+// this to tail the chain. If no block arrives before the timeout, no content
+// is returned so the replica can ask again.
+func (h Handlers) WaitForBlocks(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	after, err := strconv.ParseUint(r.URL.Query().Get("after"), 10, 64)
+	if err != nil {
+		return v1.NewRequestError(fmt.Errorf("invalid after: %w", err), http.StatusBadRequest)
+	}
+
+	wait := defaultBlockWait
+	if timeoutStr := r.URL.Query().Get("timeout"); timeoutStr != "" {
+		wait, err = time.ParseDuration(timeoutStr)
+		if err != nil || wait <= 0 {
+			return v1.NewRequestError(errors.New("invalid timeout"), http.StatusBadRequest)
+		}
+		if wait > maxBlockWait {
+			wait = maxBlockWait
+		}
 	}
 
-	return web.Respond(ctx, w, blockData, http.StatusOK)
+	// The wait can outlast the server's write timeout so extend the
+	// deadline for this request to allow the response to be written.
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Now().Add(wait + 5*time.Second)); err != nil {
+		h.Log.Infow("wait for blocks", "ERROR", err)
+	}
+
+	// The wait outlasts the request deadline so the request is given one
+	// that matches the write deadline.
+	ctx, cancel := mid.ExtendDeadline(ctx, r, wait+5*time.Second)
+	defer cancel()
+
+	waitCtx, waitCancel := context.WithTimeout(ctx, wait)
+	defer waitCancel()
+
+	latest, err := h.State.WaitForBlock(waitCtx, after)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return web.Respond(ctx, w, nil, http.StatusNoContent)
+		}
+		return err
+	}
+
+	blocks, err := h.State.QueryBlocksByNumber(ctx, after+1, latest.Header.Number)
+	if err != nil {
+		return err
+	}
+
+	return web.Respond(ctx, w, protocol.EncodeBlocks(protocol.Requested(r), blocks), http.StatusOK)
+}
+
+// PeerBandwidth returns the traffic exchanged with each peer.
+func (h Handlers) PeerBandwidth(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	resp := peerBandwidth{
+		Limit: h.State.Bandwidth().Limit(),
+		Peers: h.State.Bandwidth().Usage(),
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// Peers returns the known peers with the versions they reported so peers
+// this node can't sync with stand out, and how well they respond. A peer
+// that hasn't reported its versions is assumed to speak the original
+// protocol.
+func (h Handlers) Peers(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	peers := h.State.KnownExternalPeers()
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].Host < peers[j].Host
+	})
+
+	resp := make([]peerInfo, len(peers))
+	for i, p := range peers {
+		version, reported := h.State.PeerVersion(p)
+		quality, _ := h.State.PeerQuality(p)
+		resp[i] = peerInfo{
+			Host:               p.Host,
+			Reported:           reported,
+			ProtocolVersion:    version.Protocol,
+			MinProtocolVersion: version.MinProtocol,
+			Build:              version.Build,
+			Compatible:         protocol.Compatible(version.Protocol, version.MinProtocol),
+			LatencyMS:          quality.Latency.Milliseconds(),
+			Throughput:         quality.Throughput,
+			Failures:           quality.Failures,
+		}
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// PeerPropagation returns how long the blocks proposed by each peer took
+// from being mined to being accepted by this node.
+func (h Handlers) PeerPropagation(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	return web.Respond(ctx, w, h.State.Propagation().Latencies(), http.StatusOK)
 }
 
 // SubmitPeer is called by a node so they can be added to the known peer list.
@@ -89,18 +232,108 @@ func (h Handlers) SubmitPeer(ctx context.Context, w http.ResponseWriter, r *http
 		return web.NewShutdownError("web value missing from context")
 	}
 
-	var peer peer.Peer
-	if err := web.Decode(r, &peer); err != nil {
+	var p peer.Peer
+	if err := web.Decode(r, &p); err != nil {
 		return fmt.Errorf("unable to decode payload: %w", err)
 	}
 
-	if !h.State.AddKnownPeer(peer) {
-		h.Log.Infow("adding peer", "traceid", v.TraceID, "host", peer.Host)
+	added, err := h.State.AddInboundPeer(p)
+	if err != nil {
+		if errors.Is(err, peer.ErrPeerDenied) {
+			return v1.NewRequestError(err, http.StatusForbidden)
+		}
+
+		// Tell the peer where else it can join the network.
+		var hosts []string
+		for _, alt := range h.State.AlternatePeers(p) {
+			hosts = append(hosts, alt.Host)
+		}
+		return v1.NewRequestErrorFields(err, http.StatusServiceUnavailable, map[string]string{"peers": strings.Join(hosts, ",")})
+	}
+
+	if added {
+		h.Log.Infow("adding peer", "traceid", v.TraceID, "host", p.Host)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusOK)
+}
+
+// PeerLeaving is called by a node shutting down so it's removed from the
+// known peer list right away.
+func (h Handlers) PeerLeaving(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var env peer.Envelope
+	if err := web.Decode(r, &env); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+
+	// The notice is only taken from the account the host is bound to, it
+	// never binds the host.
+	signer, err := h.State.AcceptLeaveNotice(env)
+	if err != nil {
+		return v1.NewRequestError(fmt.Errorf("envelope not accepted: %w", err), http.StatusUnauthorized)
+	}
+	audit.SetWho(ctx, fmt.Sprintf("%s:%s", env.Host, signer))
+
+	if err := protocol.CheckVersion(env.Version); err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	var notice state.LeaveNotice
+	if err := json.Unmarshal(env.Payload, &notice); err != nil {
+		return v1.NewRequestError(fmt.Errorf("unable to decode notice: %w", err), http.StatusBadRequest)
+	}
+
+	if h.State.RemoveLeavingPeer(env.Host, notice) {
+		h.Log.Infow("removing peer", "traceid", v.TraceID, "host", env.Host, "reason", notice.Reason)
 	}
 
 	return web.Respond(ctx, w, nil, http.StatusOK)
 }
 
+// BlockRejected records why a peer didn't accept a block this node proposed.
+func (h Handlers) BlockRejected(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var env peer.Envelope
+	if err := web.Decode(r, &env); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+
+	signer, err := h.State.AcceptEnvelope(env)
+	if err != nil {
+		return v1.NewRequestError(fmt.Errorf("envelope not accepted: %w", err), http.StatusUnauthorized)
+	}
+	audit.SetWho(ctx, fmt.Sprintf("%s:%s", env.Host, signer))
+
+	if err := h.State.CheckPeer(env.Host, signer); err != nil {
+		return v1.NewRequestError(err, http.StatusForbidden)
+	}
+
+	if err := protocol.CheckVersion(env.Version); err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	var rejection state.BlockRejection
+	if err := json.Unmarshal(env.Payload, &rejection); err != nil {
+		return v1.NewRequestError(fmt.Errorf("unable to decode rejection: %w", err), http.StatusBadRequest)
+	}
+
+	h.Log.Infow("propose block", "traceid", v.TraceID, "blk", rejection.Number, "peer", env.Host, "status", "rejected",
+		"check", rejection.Check, "got", rejection.Got, "expected", rejection.Expected, "error", rejection.Error)
+
+	h.State.BlockRejected(env.Host, rejection)
+
+	return web.Respond(ctx, w, nil, http.StatusOK)
+}
+
 // SubmitNodeTransaction adds new node transactions to the mempool.
 func (h Handlers) SubmitNodeTransaction(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 	v, err := web.GetValues(ctx)
@@ -108,15 +341,48 @@ func (h Handlers) SubmitNodeTransaction(ctx context.Context, w http.ResponseWrit
 		return web.NewShutdownError("web value missing from context")
 	}
 
-	// Decode the JSON in the post call into a block transaction.
-	var tx database.BlockTx
-	if err := web.Decode(r, &tx); err != nil {
+	// Decode the JSON in the post call into a signed envelope and
+	// identify the node that sent it.
+	var env peer.Envelope
+	if err := web.Decode(r, &env); err != nil {
 		return fmt.Errorf("unable to decode payload: %w", err)
 	}
 
+	signer, err := h.State.AcceptEnvelope(env)
+	if err != nil {
+		return v1.NewRequestError(fmt.Errorf("envelope not accepted: %w", err), http.StatusUnauthorized)
+	}
+	audit.SetWho(ctx, fmt.Sprintf("%s:%s", env.Host, signer))
+
+	if err := h.State.CheckPeer(env.Host, signer); err != nil {
+		return v1.NewRequestError(err, http.StatusForbidden)
+	}
+
+	if err := protocol.CheckVersion(env.Version); err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	// Decode the payload of the envelope into a block transaction
+	// from whichever protocol version the peer speaks.
+	tx, err := protocol.DecodeTx(env.Payload)
+	if err != nil {
+		return v1.NewRequestError(fmt.Errorf("unable to decode payload: %w", err), http.StatusBadRequest)
+	}
+
+	// A copy of a transaction already in the mempool is acknowledged
+	// without validating it again.
+	if h.State.SeenTx(tx.TxHash(h.State.Rules().HashAlgorithm)) {
+		resp := struct {
+			Status string `json:"status"`
+		}{
+			Status: "transaction already seen",
+		}
+		return web.Respond(ctx, w, resp, http.StatusOK)
+	}
+
 	// Ask the state package to add this transaction to the mempool and perform any other business logic.
 	h.Log.Infow("add tran", "traceid", v.TraceID, "sig:nonce", tx, "from", tx.FromID, "to",
-		tx.ToID, "value", tx.Value, "tip", tx.Tip)
+		tx.ToID, "value", tx.Value, "tip", tx.Tip, "peer", env.Host, "signer", h.NS.Lookup(database.AccountID(signer)))
 	if err := h.State.UpsertNodeTransaction(tx); err != nil {
 		return v1.NewRequestError(err, http.StatusBadRequest)
 	}
@@ -133,34 +399,585 @@ func (h Handlers) SubmitNodeTransaction(ctx context.Context, w http.ResponseWrit
 // ProposeBlock takes a block received from a peer, validates
 // it and if valid, adds the block to the local blockchain.
 func (h Handlers) ProposeBlock(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
-	// Decode the JSON in the post call into a filesystem block.
-	var blockData database.BlockData
-	if err := web.Decode(r, &blockData); err != nil {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	// Decode the JSON in the post call into a signed envelope and
+	// identify the node that sent it.
+	var env peer.Envelope
+	if err := web.Decode(r, &env); err != nil {
 		return fmt.Errorf("unable to decode payload: %w", err)
 	}
 
-	// Convert the block data into a block. This action will create a merkle
+	signer, err := h.State.AcceptEnvelope(env)
+	if err != nil {
+		return v1.NewRequestError(fmt.Errorf("envelope not accepted: %w", err), http.StatusUnauthorized)
+	}
+	audit.SetWho(ctx, fmt.Sprintf("%s:%s", env.Host, signer))
+
+	if err := h.State.CheckPeer(env.Host, signer); err != nil {
+		return v1.NewRequestError(err, http.StatusForbidden)
+	}
+
+	if err := protocol.CheckVersion(env.Version); err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	// Decode the payload of the envelope into a block from whichever
+	// protocol version the peer speaks. This action will create a merkle
 	// tree for the set of transactions required for blockchain operations.
-	block, err := database.ToBlock(blockData)
+	block, err := protocol.DecodeBlock(env.Payload, h.State.HashAlgorithm)
+	if err != nil {
+		return v1.NewRequestError(fmt.Errorf("unable to decode block: %w", err), http.StatusBadRequest)
+	}
+
+	h.Log.Infow("propose block", "traceid", v.TraceID, "blk", block.Header.Number, "peer", env.Host,
+		"signer", h.NS.Lookup(database.AccountID(signer)))
+
+	// A copy of a block already accepted from another peer is
+	// acknowledged without validating it again.
+	if h.State.SeenBlock(block.Hash()) {
+		resp := struct {
+			Status string `json:"status"`
+		}{
+			Status: "block already seen",
+		}
+		return web.Respond(ctx, w, resp, http.StatusOK)
+	}
+
+	// Hand the block to the worker to validate and add to the blockchain
+	// so the peer isn't held up by this node's disk. A rejected block is
+	// reported back to the peer with the check it failed.
+	if err := h.State.QueueProposedBlock(env.Host, block); err != nil {
+		return v1.NewRequestError(err, http.StatusServiceUnavailable)
+	}
+
+	resp := struct {
+		Status string `json:"status"`
+	}{
+		Status: "block queued",
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusAccepted)
+}
+
+// MiningCandidate returns the block this node would mine right now with
+// the selected transactions and the fees it expects to collect.
+func (h Handlers) MiningCandidate(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	cand, err := h.State.MiningCandidate()
 	if err != nil {
-		return fmt.Errorf("unable to decode block: %w", err)
+		if errors.Is(err, state.ErrNoTransactions) {
+			return web.Respond(ctx, w, nil, http.StatusNoContent)
+		}
+		return err
+	}
+
+	resp := candidate{
+		Header:  cand.Block.Header,
+		Trans:   cand.Block.MerkleTree.Values(),
+		GasFees: cand.GasFees,
+		Tips:    cand.Tips,
 	}
 
-	// Ask the state package to validate the proposed block. If
-	// the block is valid, add it to the blockchain database.
-	if err := h.State.ProcessProposedBlock(block); err != nil {
-		// if errors.Is(err, database.ErrChainForked) {
-		// 	h.State.Reorganize()
-		// }
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
 
-		return v1.NewRequestError(errors.New("block not accepted"), http.StatusNotAcceptable)
+// MiningWork returns the block header an external miner should solve.
+func (h Handlers) MiningWork(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	work, err := h.State.MiningWork()
+	if err != nil {
+		if errors.Is(err, state.ErrNoTransactions) {
+			return web.Respond(ctx, w, nil, http.StatusNoContent)
+		}
+		return err
 	}
 
+	return web.Respond(ctx, w, work, http.StatusOK)
+}
+
+// SubmitWork accepts the nonce an external miner found for a piece of work.
+func (h Handlers) SubmitWork(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var sol workSolution
+	if err := web.Decode(r, &sol); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+
+	block, err := h.State.SubmitWork(sol.ID, sol.Nonce)
+	if err != nil {
+		h.Log.Infow("submit work", "traceid", v.TraceID, "id", sol.ID, "nonce", sol.Nonce, "ERROR", err)
+		return v1.NewRequestError(err, http.StatusNotAcceptable)
+	}
+
+	h.Log.Infow("submit work", "traceid", v.TraceID, "id", sol.ID, "nonce", sol.Nonce, "blk", block.Hash())
+
 	resp := struct {
 		Status string `json:"status"`
+		Hash   string `json:"hash"`
 	}{
 		Status: "block accepted",
+		Hash:   block.Hash(),
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// AuditLog returns the most recent state-changing API calls recorded in
+// the audit log, optionally filtered by who, path and outcome.
+func (h Handlers) AuditLog(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	filter := audit.Filter{
+		Who:     r.URL.Query().Get("who"),
+		Path:    r.URL.Query().Get("path"),
+		Outcome: r.URL.Query().Get("outcome"),
+		Limit:   100,
+	}
+
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil {
+			return v1.NewRequestError(fmt.Errorf("invalid limit: %w", err), http.StatusBadRequest)
+		}
+		filter.Limit = limit
+	}
+
+	records, err := h.Audit.Query(filter)
+	if err != nil {
+		return err
+	}
+
+	return web.Respond(ctx, w, records, http.StatusOK)
+}
+
+// Backup writes a gzipped tarball of the chain, mempool, known peers and
+// node key. The tarball is streamed back unless the path query parameter
+// names a file on the node to write it to.
+func (h Handlers) Backup(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	dest := r.URL.Query().Get("path")
+
+	if dest == "" {
+		// The backup can outlast the server's write timeout so remove the
+		// deadline for this request to allow the whole tarball to be sent.
+		rc := http.NewResponseController(w)
+		if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+			h.Log.Infow("backup", "ERROR", err)
+		}
+
+		return h.State.Backup(func(src backup.Source) error {
+			w.Header().Set("Content-Type", "application/gzip")
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"backup-%d.tar.gz\"", src.LatestBlock))
+			web.SetStatusCode(ctx, http.StatusOK)
+
+			return backup.Write(w, src, h.KeyPath)
+		})
+	}
+
+	// Write to a temporary file first so a failed backup never replaces
+	// a good one.
+	tmp := dest + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return v1.NewRequestError(fmt.Errorf("unable to create backup: %w", err), http.StatusBadRequest)
+	}
+	defer os.Remove(tmp)
+
+	var blocks uint64
+	err = h.State.Backup(func(src backup.Source) error {
+		blocks = src.LatestBlock
+		return backup.Write(f, src, h.KeyPath)
+	})
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("writing backup: %w", err)
+	}
+
+	if err := os.Rename(tmp, dest); err != nil {
+		return fmt.Errorf("writing backup: %w", err)
+	}
+
+	info, err := os.Stat(dest)
+	if err != nil {
+		return err
+	}
+
+	resp := backupInfo{
+		Path:   dest,
+		Blocks: blocks,
+		Size:   info.Size(),
 	}
 
 	return web.Respond(ctx, w, resp, http.StatusOK)
 }
+
+// Beneficiaries returns how the account paid for blocks this node mines
+// is rotated.
+func (h Handlers) Beneficiaries(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	return web.Respond(ctx, w, h.State.Rotation(), http.StatusOK)
+}
+
+// SetBeneficiaries replaces how the account paid for blocks this node mines
+// is rotated. Beneficiaries can be named by account or by account name.
+func (h Handlers) SetBeneficiaries(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var rotation state.Rotation
+	if err := web.Decode(r, &rotation); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+
+	for i, ben := range rotation.Beneficiaries {
+		if accountID, ok := h.NS.Resolve(string(ben.AccountID)); ok {
+			rotation.Beneficiaries[i].AccountID = accountID
+		}
+	}
+
+	if err := h.State.SetRotation(rotation); err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	h.Log.Infow("set beneficiaries", "traceid", v.TraceID, "strategy", rotation.Strategy,
+		"beneficiaries", len(rotation.Beneficiaries))
+
+	return web.Respond(ctx, w, h.State.Rotation(), http.StatusOK)
+}
+
+// PoolPayouts returns the shares of the mining rewards paid to the pool
+// contributors and the most recent payouts.
+func (h Handlers) PoolPayouts(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	limit := 100
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil {
+			return v1.NewRequestError(fmt.Errorf("invalid limit: %w", err), http.StatusBadRequest)
+		}
+	}
+
+	resp := poolPayouts{
+		Shares:  h.State.PoolShares(),
+		Payouts: h.State.Payouts(limit),
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// maxRevenueBlocks is the most blocks the mining revenue is reported over.
+const maxRevenueBlocks = 10_000
+
+// MiningRevenue reports what the beneficiaries were paid for the blocks they
+// mined among the latest blocks, per block and in total, split into the
+// mining reward, tips and gas fees. The blocks query parameter sets how many
+// of the latest blocks are covered and the account parameter, which can be
+// repeated, picks the beneficiaries instead of the node's own.
+func (h Handlers) MiningRevenue(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	blocks := uint64(100)
+	if blocksStr := r.URL.Query().Get("blocks"); blocksStr != "" {
+		var err error
+		blocks, err = strconv.ParseUint(blocksStr, 10, 64)
+		if err != nil || blocks == 0 || blocks > maxRevenueBlocks {
+			return v1.NewRequestError(fmt.Errorf("blocks must be between 1 and %d", maxRevenueBlocks), http.StatusBadRequest)
+		}
+	}
+
+	var accounts []database.AccountID
+	for _, account := range r.URL.Query()["account"] {
+		accountID, ok := h.NS.Resolve(account)
+		if !ok {
+			var err error
+			if accountID, err = database.ToAccountID(account); err != nil {
+				return v1.NewRequestError(err, http.StatusBadRequest)
+			}
+		}
+		accounts = append(accounts, accountID)
+	}
+
+	latest := h.State.LatestBlock().Header.Number
+	from := uint64(1)
+	if latest >= blocks {
+		from = latest - blocks + 1
+	}
+
+	revenue, err := h.State.QueryRevenue(ctx, from, accounts)
+	if err != nil {
+		return err
+	}
+
+	resp := miningRevenue{
+		FromBlock: from,
+		ToBlock:   latest,
+		Blocks:    make([]blockRevenue, len(revenue)),
+	}
+	for i, br := range revenue {
+		resp.Blocks[i] = blockRevenue{
+			Number:          br.Number,
+			Hash:            br.Hash,
+			Beneficiary:     br.Beneficiary,
+			Reward:          br.Reward,
+			Tips:            br.Tips,
+			GasFees:         br.GasFees,
+			Total:           br.Total(),
+			Trans:           br.Trans,
+			MissingReceipts: br.Missing,
+		}
+
+		resp.Mined++
+		resp.Reward += br.Reward
+		resp.Tips += br.Tips
+		resp.GasFees += br.GasFees
+		resp.Total += br.Total()
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// ExportAccounts returns every account's balance and nonce as of the block
+// query parameter, or the latest block, as JSON or as CSV.
+func (h Handlers) ExportAccounts(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	num, err := exportBlock(r)
+	if err != nil {
+		return err
+	}
+
+	accounts, err := h.State.QueryAccountsAt(num)
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	resp := make([]accountExport, len(accounts))
+	for i, account := range accounts {
+		resp[i] = accountExport{
+			Account: account.AccountID,
+			Balance: account.Balance,
+			Nonce:   account.Nonce,
+		}
+	}
+
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+		return web.Respond(ctx, w, resp, http.StatusOK)
+
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		web.SetStatusCode(ctx, http.StatusOK)
+
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"account", "balance", "nonce"})
+		for _, account := range resp {
+			cw.Write([]string{
+				string(account.Account),
+				strconv.FormatUint(account.Balance, 10),
+				strconv.FormatUint(account.Nonce, 10),
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+
+	default:
+		return v1.NewRequestError(fmt.Errorf("unknown format %q, must be json or csv", format), http.StatusBadRequest)
+	}
+}
+
+// ExportGenesis returns a genesis file for a new chain that starts with the
+// accounts as of the block query parameter, or the latest block.
+func (h Handlers) ExportGenesis(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	num, err := exportBlock(r)
+	if err != nil {
+		return err
+	}
+
+	gen, err := h.State.ExportGenesis(num)
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	return web.Respond(ctx, w, gen, http.StatusOK)
+}
+
+// exportBlock returns the block number an export is taken at.
+func exportBlock(r *http.Request) (uint64, error) {
+	numStr := r.URL.Query().Get("block")
+	if numStr == "" || numStr == "latest" {
+		return state.QueryLatest, nil
+	}
+
+	num, err := strconv.ParseUint(numStr, 10, 64)
+	if err != nil {
+		return 0, v1.NewRequestError(fmt.Errorf("invalid block: %w", err), http.StatusBadRequest)
+	}
+
+	return num, nil
+}
+
+// StorageUsage returns the space the chain uses on disk.
+func (h Handlers) StorageUsage(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	usage, err := h.State.StorageUsage()
+	if err != nil {
+		if errors.Is(err, state.ErrNotCompactable) {
+			return v1.NewRequestError(err, http.StatusNotImplemented)
+		}
+		return err
+	}
+
+	return web.Respond(ctx, w, usage, http.StatusOK)
+}
+
+// CompactStorage reclaims space on disk according to the retention config.
+func (h Handlers) CompactStorage(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	result, err := h.State.Compact()
+	if err != nil {
+		if errors.Is(err, state.ErrNotCompactable) {
+			return v1.NewRequestError(err, http.StatusNotImplemented)
+		}
+		return fmt.Errorf("compacting storage: %w", err)
+	}
+
+	h.Log.Infow("compact storage", "traceid", v.TraceID, "compacted", result.Compacted,
+		"pruned", result.Pruned, "offloaded", result.Offloaded, "reclaimed", result.Reclaimed)
+
+	return web.Respond(ctx, w, result, http.StatusOK)
+}
+
+// AddWebhook registers a callback URL to be notified when transactions
+// matching the filter are mined.
+func (h Handlers) AddWebhook(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var nw newWebhook
+	if err := web.Decode(r, &nw); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+
+	sub, err := h.State.AddWebhook(webhook.Subscription{
+		URL:      nw.URL,
+		Account:  nw.Account,
+		MinValue: nw.MinValue,
+	})
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	h.Log.Infow("add webhook", "traceid", v.TraceID, "id", sub.ID, "url", sub.URL,
+		"account", sub.Account, "minvalue", sub.MinValue)
+
+	return web.Respond(ctx, w, sub, http.StatusCreated)
+}
+
+// Webhooks returns the registered webhook subscriptions.
+func (h Handlers) Webhooks(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	return web.Respond(ctx, w, h.State.Webhooks(), http.StatusOK)
+}
+
+// RemoveWebhook removes the webhook subscription.
+func (h Handlers) RemoveWebhook(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	if err := h.State.RemoveWebhook(web.Param(r, "id")); err != nil {
+		return v1.NewRequestError(err, http.StatusNotFound)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// ResetChain wipes the chain of a development network back to genesis and
+// asks the known peers to do the same. The node has to be started with
+// resetting allowed and the request has to name the chain being reset.
+func (h Handlers) ResetChain(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	if !h.State.ResetAllowed() {
+		return v1.NewRequestError(state.ErrResetDisabled, http.StatusForbidden)
+	}
+
+	var req chainResetRequest
+	if err := web.Decode(r, &req); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+
+	if chainID := h.State.Genesis().ChainID; req.ChainID != chainID {
+		return v1.NewRequestError(fmt.Errorf("chain_id must be %d to confirm the chain being reset", chainID), http.StatusBadRequest)
+	}
+
+	reset, err := h.State.ResetChain("asked by an operator")
+	if err != nil {
+		return fmt.Errorf("resetting chain: %w", err)
+	}
+
+	resp := chainReset{
+		ChainReset: reset,
+		Peers:      h.State.NetSendChainResetToPeers(),
+	}
+
+	h.Log.Infow("reset chain", "traceid", v.TraceID, "blocks", reset.Blocks, "mempool", reset.Mempool, "peers", len(resp.Peers))
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// ChainResetNotice resets the chain when a peer that reset its chain asks
+// for it. The notice is only accepted from a peer allowed to propose blocks
+// and only when resetting is allowed on this node too.
+func (h Handlers) ChainResetNotice(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var env peer.Envelope
+	if err := web.Decode(r, &env); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+
+	signer, err := h.State.AcceptEnvelope(env)
+	if err != nil {
+		return v1.NewRequestError(fmt.Errorf("envelope not accepted: %w", err), http.StatusUnauthorized)
+	}
+	audit.SetWho(ctx, fmt.Sprintf("%s:%s", env.Host, signer))
+
+	if err := h.State.CheckPeer(env.Host, signer); err != nil {
+		return v1.NewRequestError(err, http.StatusForbidden)
+	}
+
+	if err := protocol.CheckVersion(env.Version); err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	if !h.State.ResetAllowed() {
+		return v1.NewRequestError(state.ErrResetDisabled, http.StatusForbidden)
+	}
+
+	var notice state.ChainResetNotice
+	if err := json.Unmarshal(env.Payload, &notice); err != nil {
+		return v1.NewRequestError(fmt.Errorf("unable to decode notice: %w", err), http.StatusBadRequest)
+	}
+
+	if chainID := h.State.Genesis().ChainID; notice.ChainID != chainID {
+		return v1.NewRequestError(fmt.Errorf("reset is for chain %d, this node runs chain %d", notice.ChainID, chainID), http.StatusBadRequest)
+	}
+
+	reset, err := h.State.ResetChain(fmt.Sprintf("asked by peer %s", env.Host))
+	if err != nil {
+		return fmt.Errorf("resetting chain: %w", err)
+	}
+
+	h.Log.Infow("reset chain", "traceid", v.TraceID, "peer", env.Host, "blocks", reset.Blocks, "mempool", reset.Mempool)
+
+	return web.Respond(ctx, w, reset, http.StatusOK)
+}
@@ -0,0 +1,133 @@
+package public
+
+import (
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+	"github.com/qcbit/blockchain/foundation/blockchain/genesis"
+	"github.com/qcbit/blockchain/foundation/openapi"
+)
+
+// txAdded is the response of the routes that add a transaction.
+type txAdded struct {
+	Status string `json:"status"`
+}
+
+// waitQuery describes the query string of the routes that wait for a block.
+var waitQuery = []openapi.Param{
+	{Name: "after", Description: "Number of the block to wait past.", Required: true},
+	{Name: "timeout", Description: "How long to wait as a duration such as 30s, up to 2m."},
+}
+
+// positionQuery describes the query string of the route that previews a
+// transaction's place in the mempool.
+var positionQuery = []openapi.Param{
+	{Name: "tip", Description: "Tip the transaction would pay.", Required: true},
+	{Name: "size", Description: "Size of the transaction in bytes, not weighed by the current strategies."},
+}
+
+// Operations describes the public routes for the OpenAPI document. The
+// routes that have a v2 replacement are marked deprecated.
+func Operations() openapi.Operations {
+	return openapi.Operations{
+		"GET /genesis/list": {
+			Summary:    "Returns the genesis information of the chain.",
+			Deprecated: true,
+			Response:   genesis.Genesis{},
+		},
+		"GET /chain/id": {
+			Summary:    "Returns the chain ID and hash algorithm transactions are signed for.",
+			Deprecated: true,
+			Response:   chainInfo{},
+		},
+		"GET /chain/signing-info": {
+			Summary:    "Returns what an external wallet needs to sign transactions.",
+			Deprecated: true,
+			Response:   signingInfo{},
+		},
+		"GET /accounts/list": {
+			Summary:    "Returns the balances of all accounts.",
+			Deprecated: true,
+			Response:   acctInfo{},
+		},
+		"GET /accounts/list/:account": {
+			Summary:    "Returns the balance of the account.",
+			Deprecated: true,
+			Response:   acctInfo{},
+		},
+		"GET /accounts/:account/nonce": {
+			Summary:    "Returns the confirmed and next nonce of the account.",
+			Deprecated: true,
+			Response:   acctNonce{},
+		},
+		"POST /accounts/balances": {
+			Summary:  "Returns the balances and nonces of up to 500 accounts as of the same block, accounts that don't exist have none.",
+			Request:  acctBalancesRequest{},
+			Response: acctBalances{},
+		},
+		"GET /accounts/:account/summary": {
+			Summary:  "Returns an overview of the account's mined transactions.",
+			Response: acctSummary{},
+		},
+		"GET /block/wait": {
+			Summary:    "Waits for a block past the after parameter, no content is returned on timeout.",
+			Deprecated: true,
+			Query:      waitQuery,
+			Response:   blockHeader{},
+		},
+		"GET /block/orphans": {
+			Summary:    "Returns the valid blocks that lost to a competing block.",
+			Deprecated: true,
+			Response:   []orphan{},
+		},
+		"GET /block/:number/diff": {
+			Summary:    "Returns the accounts the block changed with their values before and after.",
+			Deprecated: true,
+			Response:   blockDiff{},
+		},
+		"GET /checkpoint/:number": {
+			Summary:  "Returns the accounts after the block, or the latest block, signed by the node.",
+			Response: database.SignedCheckpoint{},
+		},
+		"GET /tx/status/:hash": {
+			Summary:    "Reports whether the transaction is unknown, pending or mined.",
+			Deprecated: true,
+			Response:   txStatus{},
+		},
+		"GET /tx/position": {
+			Summary:  "Previews where a transaction paying the tip would rank in the mempool and in how many blocks it would likely be mined.",
+			Query:    positionQuery,
+			Response: txPosition{},
+		},
+		"GET /tx/uncommitted/list": {
+			Summary:    "Returns the transactions in the mempool.",
+			Deprecated: true,
+			Response:   []tx{},
+		},
+		"GET /tx/uncommitted/list/:account": {
+			Summary:    "Returns the transactions in the mempool from or to the account.",
+			Deprecated: true,
+			Response:   []tx{},
+		},
+		"POST /tx/submit": {
+			Summary:    "Adds the signed transaction to the mempool.",
+			Deprecated: true,
+			Request:    database.SignedTx{},
+			Response:   txAdded{},
+		},
+		"POST /tx/sendRaw": {
+			Summary:    "Adds the raw hex encoded transaction to the mempool.",
+			Deprecated: true,
+			Request:    rawTx{},
+			Response:   txAdded{},
+		},
+		"POST /tx/validate": {
+			Summary:  "Runs the checks on the transaction without adding it to the mempool.",
+			Request:  database.SignedTx{},
+			Response: txVerdict{},
+		},
+		"POST /tx/proof/:block/": {
+			Summary:  "Adds the signed transaction to the mempool.",
+			Request:  database.SignedTx{},
+			Response: txAdded{},
+		},
+	}
+}
@@ -1,14 +1,65 @@
 package public
 
-import "github.com/qcbit/blockchain/foundation/blockchain/database"
+import (
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+	"github.com/qcbit/blockchain/foundation/blockchain/state"
+)
+
+type chainInfo struct {
+	ChainID       uint16 `json:"chain_id"`
+	HashAlgorithm string `json:"hash_algorithm"`
+}
+
+type signingInfo struct {
+	StampPrefix        string `json:"stamp_prefix"`
+	StampHashAlgorithm string `json:"stamp_hash_algorithm"`
+	QID                int    `json:"qid"`
+	ChainID            uint16 `json:"chain_id"`
+	HashAlgorithm      string `json:"hash_algorithm"`
+}
 
 type acct struct {
+	Account   database.AccountID `json:"account"`
+	Name      string             `json:"name"`
+	Balance   uint64             `json:"balance"`
+	Locked    uint64             `json:"locked"`
+	Spendable uint64             `json:"spendable"`
+	Frozen    bool               `json:"frozen"`
+	Nonce     uint64             `json:"nonce"`
+}
+
+type acctNonce struct {
+	Account   database.AccountID `json:"account"`
+	Confirmed uint64             `json:"confirmed"`
+	Next      uint64             `json:"next"`
+}
+
+type acctSummary struct {
+	Account        database.AccountID `json:"account"`
+	Transactions   int                `json:"transactions"`
+	TotalSent      uint64             `json:"total_sent"`
+	TotalReceived  uint64             `json:"total_received"`
+	FeesPaid       uint64             `json:"fees_paid"`
+	FirstBlock     uint64             `json:"first_block"`
+	LastBlock      uint64             `json:"last_block"`
+	Counterparties int                `json:"counterparties"`
+}
+
+type acctBalancesRequest struct {
+	Accounts []string `json:"accounts"`
+}
+
+type acctBalance struct {
 	Account database.AccountID `json:"account"`
-	Name    string             `json:"name"`
 	Balance uint64             `json:"balance"`
 	Nonce   uint64             `json:"nonce"`
 }
 
+type acctBalances struct {
+	LatestBlock string        `json:"latest_block"`
+	Accounts    []acctBalance `json:"accounts"`
+}
+
 type acctInfo struct {
 	LatestBlock string `json:"latest_block"`
 	Uncommitted int    `json:"uncommitted"`
@@ -16,17 +67,140 @@ type acctInfo struct {
 }
 
 type tx struct {
-	FromAccount database.AccountID `json:"from"`
-	FromName    string             `json:"from_name"`
-	To          database.AccountID `json:"to"`
-	ToName      string             `json:"to_name"`
-	ChainID     uint16             `json:"chain_id"`
-	Nonce       uint64             `json:"nonce"`
-	Value       uint64             `json:"value"`
-	Tip         uint64             `json:"tip"`
-	Data        []byte             `json:"data"`
-	TimeStamp   uint64             `json:"timestamp"`
-	GasPrice    uint64             `json:"gas_price"`
-	GasUnits    uint64             `json:"gas_units"`
-	Sig         string             `json:"sig"`
+	FromAccount database.AccountID   `json:"from"`
+	FromName    string               `json:"from_name"`
+	To          database.AccountID   `json:"to"`
+	ToName      string               `json:"to_name"`
+	ChainID     uint16               `json:"chain_id"`
+	Nonce       uint64               `json:"nonce"`
+	Value       uint64               `json:"value"`
+	Tip         uint64               `json:"tip"`
+	Data        []byte               `json:"data"`
+	TimeStamp   uint64               `json:"timestamp"`
+	GasPrice    uint64               `json:"gas_price"`
+	GasUnits    uint64               `json:"gas_units"`
+	LockUntil   uint64               `json:"lock_until,omitempty"`
+	Governance  *database.Governance `json:"governance,omitempty"`
+	ValidUntil  uint64               `json:"valid_until_block,omitempty"`
+	Sponsor     *database.Sponsor    `json:"sponsor,omitempty"`
+	Sig         string               `json:"sig"`
+}
+
+type acctState struct {
+	Balance uint64 `json:"balance"`
+	Nonce   uint64 `json:"nonce"`
+}
+
+type acctDiff struct {
+	Account database.AccountID `json:"account"`
+	Name    string             `json:"name"`
+	Before  acctState          `json:"before"`
+	After   acctState          `json:"after"`
+}
+
+type blockDiff struct {
+	Number   uint64     `json:"number"`
+	Hash     string     `json:"hash"`
+	Accounts []acctDiff `json:"accounts"`
+}
+
+type orphan struct {
+	Number        uint64             `json:"number"`
+	Hash          string             `json:"hash"`
+	PrevBlockHash string             `json:"prev_block_hash"`
+	TimeStamp     uint64             `json:"timestamp"`
+	Beneficiary   database.AccountID `json:"beneficiary"`
+	Trans         int                `json:"trans"`
+	WinnerHash    string             `json:"winner_hash"`
+	Reason        string             `json:"reason"`
+	SeenAt        uint64             `json:"seen_at"`
+}
+
+type blockHeader struct {
+	Hash   string               `json:"hash"`
+	Header database.BlockHeader `json:"header"`
+}
+
+type txMempool struct {
+	Position int `json:"position"`
+	TipRank  int `json:"tip_rank"`
+	Size     int `json:"size"`
+}
+
+type txBlock struct {
+	Number        uint64 `json:"number"`
+	Hash          string `json:"hash"`
+	Confirmations uint64 `json:"confirmations"`
+	Receipt       string `json:"receipt"`
+	Error         string `json:"error,omitempty"`
+}
+
+type txStatus struct {
+	Hash    string     `json:"hash"`
+	Status  string     `json:"status"`
+	Mempool *txMempool `json:"mempool,omitempty"`
+	Block   *txBlock   `json:"block,omitempty"`
+}
+
+type txPosition struct {
+	Tip         uint64 `json:"tip"`
+	Size        uint64 `json:"size"`
+	Position    int    `json:"position"`
+	Blocks      int    `json:"blocks"`
+	Included    bool   `json:"included"`
+	MempoolSize int    `json:"mempool_size"`
+}
+
+type rawTx struct {
+	Raw string `json:"raw"`
+}
+
+type txCheck struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Error  string `json:"error,omitempty"`
+}
+
+type txDebit struct {
+	Account database.AccountID `json:"account"`
+	Amount  uint64             `json:"amount"`
+	Reason  string             `json:"reason"`
+}
+
+type txVerdict struct {
+	Valid     bool      `json:"valid"`
+	Checks    []txCheck `json:"checks"`
+	Hash      string    `json:"hash"`
+	NextNonce uint64    `json:"next_nonce"`
+	GasFee    uint64    `json:"gas_fee"`
+	Debits    []txDebit `json:"debits"`
+}
+
+func toTxVerdict(verdict state.TxVerdict) txVerdict {
+	checks := make([]txCheck, len(verdict.Checks))
+	for i, check := range verdict.Checks {
+		checks[i] = txCheck{
+			Name:   check.Name,
+			Passed: check.Error == "",
+			Error:  check.Error,
+		}
+	}
+
+	debits := make([]txDebit, len(verdict.Debits))
+	for i, debit := range verdict.Debits {
+		debits[i] = txDebit{
+			Account: debit.AccountID,
+			Amount:  debit.Amount,
+			Reason:  debit.Reason,
+		}
+	}
+
+	return txVerdict{
+		Valid:     verdict.Valid,
+		Checks:    checks,
+		Hash:      verdict.TxHash,
+		NextNonce: verdict.NextNonce,
+		GasFee:    verdict.GasFee,
+		Debits:    debits,
+	}
 }
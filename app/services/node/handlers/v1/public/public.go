@@ -3,14 +3,20 @@ package public
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"go.uber.org/zap"
 
 	v1 "github.com/qcbit/blockchain/business/web/v1"
+	"github.com/qcbit/blockchain/business/web/v1/mid"
 	"github.com/qcbit/blockchain/foundation/blockchain/database"
 	"github.com/qcbit/blockchain/foundation/blockchain/nameservice"
+	"github.com/qcbit/blockchain/foundation/blockchain/signature"
 	"github.com/qcbit/blockchain/foundation/blockchain/state"
 	"github.com/qcbit/blockchain/foundation/web"
 )
@@ -55,12 +61,91 @@ func (h Handlers) SubmitWalletTransaction(ctx context.Context, w http.ResponseWr
 	return web.Respond(ctx, w, resp, http.StatusOK)
 }
 
+// SubmitRawTransaction adds a new transaction provided in the raw hex
+// encoding to the mempool.
+func (h Handlers) SubmitRawTransaction(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var raw rawTx
+	if err := web.Decode(r, &raw); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+
+	// Convert the raw encoding into a signed transaction.
+	signedTx, err := database.DecodeRawHex(raw.Raw)
+	if err != nil {
+		return v1.NewRequestError(fmt.Errorf("unable to decode raw transaction: %w", err), http.StatusBadRequest)
+	}
+
+	h.Log.Infow("add raw tran", "traceid", v.TraceID, "sig:nonce", signedTx, "from", signedTx.FromID,
+		"to", signedTx.ToID, "value", signedTx.Value, "tip", signedTx.Tip)
+
+	if err := h.State.UpsertWalletTransaction(signedTx); err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	resp := struct {
+		Status string `json:"status"`
+	}{
+		Status: "transaction added to mempool",
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// ValidateTransaction runs the checks a transaction goes through against the
+// current state and returns the outcome of each with what the transaction
+// would take from the accounts involved. The transaction is not added to
+// the mempool. A transaction that fails a check is still a valid request.
+func (h Handlers) ValidateTransaction(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var signedTx database.SignedTx
+	if err := web.Decode(r, &signedTx); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+
+	verdict := h.State.ValidateTransaction(signedTx)
+
+	return web.Respond(ctx, w, toTxVerdict(verdict), http.StatusOK)
+}
+
 // Genesis returns the genesis information.
 func (h Handlers) Genesis(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 	gen := h.State.Genesis()
 	return web.Respond(ctx, w, gen, http.StatusOK)
 }
 
+// ChainID returns the chain ID transactions must be signed for and the
+// hash algorithm currently used to identify them.
+func (h Handlers) ChainID(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	gen := h.State.Genesis()
+
+	info := chainInfo{
+		ChainID:       gen.ChainID,
+		HashAlgorithm: h.State.Rules().HashAlgorithm,
+	}
+
+	return web.Respond(ctx, w, info, http.StatusOK)
+}
+
+// SigningInfo returns what an external wallet needs to sign transactions
+// the way this node verifies them: the stamp written in front of the data,
+// the algorithm the stamped data is hashed with, the value added to the v
+// component of the signature, the chain ID and the chain's hash algorithm.
+func (h Handlers) SigningInfo(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	info := signingInfo{
+		StampPrefix:        signature.StampPrefix,
+		StampHashAlgorithm: signature.StampHashAlgorithm,
+		QID:                signature.QID,
+		ChainID:            h.State.Genesis().ChainID,
+		HashAlgorithm:      h.State.Rules().HashAlgorithm,
+	}
+
+	return web.Respond(ctx, w, info, http.StatusOK)
+}
+
 // Accounts returns the current balances for all users.
 func (h Handlers) Accounts(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 	accountStr := web.Param(r, "account")
@@ -82,13 +167,19 @@ func (h Handlers) Accounts(ctx context.Context, w http.ResponseWriter, r *http.R
 		accounts = map[database.AccountID]database.Account{accountID: account}
 	}
 
+	// Report what can be spent in the next block.
+	next := h.State.LatestBlock().Header.Number + 1
+
 	resp := make([]acct, 0, len(accounts))
 	for account, info := range accounts {
 		acct := acct{
-			Account: account,
-			Name:    h.NS.Lookup(account),
-			Balance: info.Balance,
-			Nonce:   info.Nonce,
+			Account:   account,
+			Name:      h.NS.Lookup(account),
+			Balance:   info.Balance,
+			Locked:    info.Locked(next),
+			Spendable: info.Spendable(next),
+			Frozen:    info.Frozen,
+			Nonce:     info.Nonce,
 		}
 		resp = append(resp, acct)
 	}
@@ -102,6 +193,331 @@ func (h Handlers) Accounts(ctx context.Context, w http.ResponseWriter, r *http.R
 	return web.Respond(ctx, w, ai, http.StatusOK)
 }
 
+// maxBalanceAccounts is the most accounts whose balances can be queried at once.
+const maxBalanceAccounts = 500
+
+// Balances returns the balances and nonces of the posted accounts as of the
+// same block, so wallets tracking many accounts need a single request.
+func (h Handlers) Balances(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var req acctBalancesRequest
+	if err := web.Decode(r, &req); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+
+	if len(req.Accounts) > maxBalanceAccounts {
+		return v1.NewRequestError(fmt.Errorf("at most %d accounts can be queried at once", maxBalanceAccounts), http.StatusBadRequest)
+	}
+
+	accountIDs := make([]database.AccountID, len(req.Accounts))
+	for i, accountStr := range req.Accounts {
+		accountID, err := database.ToAccountID(accountStr)
+		if err != nil {
+			return v1.NewRequestErrorFields(err, http.StatusBadRequest, map[string]string{
+				fmt.Sprintf("accounts[%d]", i): err.Error(),
+			})
+		}
+		accountIDs[i] = accountID
+	}
+
+	latest, accounts := h.State.QueryAccounts(accountIDs)
+
+	resp := acctBalances{
+		LatestBlock: latest.Hash(),
+		Accounts:    make([]acctBalance, len(accountIDs)),
+	}
+	for i, accountID := range accountIDs {
+		resp.Accounts[i] = acctBalance{
+			Account: accountID,
+			Balance: accounts[accountID].Balance,
+			Nonce:   accounts[accountID].Nonce,
+		}
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// BlockDiff returns the accounts whose balance or nonce changed in the
+// specified block with their values before and after the block.
+func (h Handlers) BlockDiff(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	num, err := strconv.ParseUint(web.Param(r, "number"), 10, 64)
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	diffs, err := h.State.QueryBlockDiff(num)
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusNotFound)
+	}
+
+	blocks, err := h.State.QueryBlocksByNumber(ctx, num, num)
+	if err != nil {
+		return err
+	}
+	if len(blocks) == 0 {
+		return v1.NewRequestError(fmt.Errorf("block %d not found", num), http.StatusNotFound)
+	}
+
+	resp := blockDiff{
+		Number:   num,
+		Hash:     blocks[0].Hash(),
+		Accounts: make([]acctDiff, len(diffs)),
+	}
+	for i, diff := range diffs {
+		resp.Accounts[i] = acctDiff{
+			Account: diff.AccountID,
+			Name:    h.NS.Lookup(diff.AccountID),
+			Before:  acctState{Balance: diff.Before.Balance, Nonce: diff.Before.Nonce},
+			After:   acctState{Balance: diff.After.Balance, Nonce: diff.After.Nonce},
+		}
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// Orphans returns the valid blocks that lost to a competing block at the
+// same height so the network's fork rate can be monitored.
+func (h Handlers) Orphans(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	orphans := h.State.QueryOrphans()
+
+	resp := make([]orphan, len(orphans))
+	for i, o := range orphans {
+		resp[i] = orphan{
+			Number:        o.Block.Header.Number,
+			Hash:          o.Block.Hash,
+			PrevBlockHash: o.Block.Header.PrevBlockHash,
+			TimeStamp:     o.Block.Header.TimeStamp,
+			Beneficiary:   o.Block.Header.BeneficiaryID,
+			Trans:         len(o.Block.Trans),
+			WinnerHash:    o.WinnerHash,
+			Reason:        o.Reason,
+			SeenAt:        o.SeenAt,
+		}
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// The range of time a client can ask to wait for a new block.
+const (
+	defaultBlockWait = 30 * time.Second
+	maxBlockWait     = 2 * time.Minute
+)
+
+// WaitForBlock holds the request until a block higher than the after
+// query parameter is accepted and returns the new block header. If no
+// block arrives before the timeout, no content is returned so the client
+// can ask again.
+func (h Handlers) WaitForBlock(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	after, err := strconv.ParseUint(r.URL.Query().Get("after"), 10, 64)
+	if err != nil {
+		return v1.NewRequestError(fmt.Errorf("invalid after: %w", err), http.StatusBadRequest)
+	}
+
+	wait := defaultBlockWait
+	if timeoutStr := r.URL.Query().Get("timeout"); timeoutStr != "" {
+		wait, err = time.ParseDuration(timeoutStr)
+		if err != nil || wait <= 0 {
+			return v1.NewRequestError(errors.New("invalid timeout"), http.StatusBadRequest)
+		}
+		if wait > maxBlockWait {
+			wait = maxBlockWait
+		}
+	}
+
+	// The wait can outlast the server's write timeout so extend the
+	// deadline for this request to allow the response to be written.
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Now().Add(wait + 5*time.Second)); err != nil {
+		h.Log.Infow("wait for block", "ERROR", err)
+	}
+
+	ctx, cancel := mid.ExtendDeadline(ctx, r, wait)
+	defer cancel()
+
+	block, err := h.State.WaitForBlock(ctx, after)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return web.Respond(ctx, w, nil, http.StatusNoContent)
+		}
+		return err
+	}
+
+	resp := blockHeader{
+		Hash:   block.Hash(),
+		Header: block.Header,
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// Nonce returns the nonce of the account's last mined transaction and the
+// next nonce to use for a new transaction, including the account's
+// transactions waiting in the mempool.
+func (h Handlers) Nonce(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	accountID, err := database.ToAccountID(web.Param(r, "account"))
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	confirmed, next := h.State.QueryNonce(accountID)
+
+	resp := acctNonce{
+		Account:   accountID,
+		Confirmed: confirmed,
+		Next:      next,
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// Checkpoint returns the accounts as they were after the specified block,
+// or the latest block, signed by this node so a light client that trusts
+// the node can start from the block instead of genesis.
+func (h Handlers) Checkpoint(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	num := state.QueryLatest
+	if numStr := web.Param(r, "number"); numStr != "latest" {
+		var err error
+		if num, err = strconv.ParseUint(numStr, 10, 64); err != nil {
+			return v1.NewRequestError(err, http.StatusBadRequest)
+		}
+	}
+
+	checkpoint, err := h.State.QueryCheckpoint(num)
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusNotFound)
+	}
+
+	return web.Respond(ctx, w, checkpoint, http.StatusOK)
+}
+
+// Summary returns an overview of the account's activity in the mined
+// transactions so a wallet can show it in one call. The first and last
+// blocks are zero for an account that has never transacted.
+func (h Handlers) Summary(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	accountID, err := database.ToAccountID(web.Param(r, "account"))
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	summary := h.State.QuerySummary(accountID)
+
+	resp := acctSummary{
+		Account:        summary.AccountID,
+		Transactions:   summary.Transactions,
+		TotalSent:      summary.TotalSent,
+		TotalReceived:  summary.TotalReceived,
+		FeesPaid:       summary.FeesPaid,
+		FirstBlock:     summary.FirstBlock,
+		LastBlock:      summary.LastBlock,
+		Counterparties: summary.Counterparties,
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// The set of statuses for a transaction.
+const (
+	txStatusUnknown = "unknown"
+	txStatusPending = "pending"
+	txStatusMined   = "mined"
+)
+
+// TxStatus reports whether the transaction with the specified hash is
+// unknown, pending in the mempool or mined into a block.
+func (h Handlers) TxStatus(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	hash := strings.ToLower(web.Param(r, "hash"))
+
+	resp := txStatus{
+		Hash:   hash,
+		Status: txStatusUnknown,
+	}
+
+	if receipt, mined := h.State.QueryReceipt(hash); mined {
+		latest := h.State.LatestBlock()
+
+		block := txBlock{
+			Number:        receipt.BlockNumber,
+			Confirmations: latest.Header.Number - receipt.BlockNumber + 1,
+			Receipt:       receipt.Status,
+			Error:         receipt.Error,
+		}
+		blocks, err := h.State.QueryBlocksByNumber(ctx, receipt.BlockNumber, receipt.BlockNumber)
+		if err != nil {
+			return err
+		}
+		if len(blocks) == 1 {
+			block.Hash = blocks[0].Hash()
+		}
+
+		resp.Status = txStatusMined
+		resp.Block = &block
+
+		return web.Respond(ctx, w, resp, http.StatusOK)
+	}
+
+	// The mempool is returned in the order the transactions would be
+	// selected for the next block.
+	mempool := h.State.Mempool()
+	algorithm := h.State.Rules().HashAlgorithm
+	for i, tran := range mempool {
+		if tran.TxHash(algorithm) != hash {
+			continue
+		}
+
+		rank := 1
+		for _, other := range mempool {
+			if other.Tip > tran.Tip {
+				rank++
+			}
+		}
+
+		resp.Status = txStatusPending
+		resp.Mempool = &txMempool{
+			Position: i + 1,
+			TipRank:  rank,
+			Size:     len(mempool),
+		}
+		break
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// The most blocks looked ahead when previewing a transaction.
+const maxPositionBlocks = 100
+
+// TxPosition reports where a transaction paying the tip query parameter
+// would rank in the mempool under the node's selection strategy and in
+// how many blocks it would likely be mined, so a wallet can offer a choice
+// of fees. The size query parameter is accepted for the strategies that
+// weigh it, the current ones don't.
+func (h Handlers) TxPosition(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	tip, err := strconv.ParseUint(r.URL.Query().Get("tip"), 10, 64)
+	if err != nil {
+		return v1.NewRequestError(fmt.Errorf("invalid tip: %w", err), http.StatusBadRequest)
+	}
+
+	var size uint64
+	if sizeStr := r.URL.Query().Get("size"); sizeStr != "" {
+		if size, err = strconv.ParseUint(sizeStr, 10, 64); err != nil {
+			return v1.NewRequestError(fmt.Errorf("invalid size: %w", err), http.StatusBadRequest)
+		}
+	}
+
+	position := h.State.PreviewMempool(tip, maxPositionBlocks)
+
+	resp := txPosition{
+		Tip:         tip,
+		Size:        size,
+		Position:    position.Position,
+		Blocks:      position.Blocks,
+		Included:    position.Included,
+		MempoolSize: h.State.MempoolLength(),
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
 // Mempool returns the current uncommitted transactions.
 func (h Handlers) Mempool(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 	acct := web.Param(r, "account")
@@ -127,6 +543,10 @@ func (h Handlers) Mempool(ctx context.Context, w http.ResponseWriter, r *http.Re
 			TimeStamp:   tran.TimeStamp,
 			GasPrice:    tran.GasPrice,
 			GasUnits:    tran.GasUnits,
+			LockUntil:   tran.LockUntil,
+			Governance:  tran.Governance,
+			ValidUntil:  tran.ValidUntilBlock,
+			Sponsor:     tran.Sponsor,
 			Sig:         tran.SignatureString(),
 		})
 	}
@@ -9,18 +9,35 @@ import (
 
 	"github.com/qcbit/blockchain/app/services/node/handlers/v1/private"
 	"github.com/qcbit/blockchain/app/services/node/handlers/v1/public"
+	"github.com/qcbit/blockchain/business/web/audit"
+	"github.com/qcbit/blockchain/business/web/cache"
+	"github.com/qcbit/blockchain/business/web/v1/mid"
 	"github.com/qcbit/blockchain/foundation/blockchain/nameservice"
 	"github.com/qcbit/blockchain/foundation/blockchain/state"
+	"github.com/qcbit/blockchain/foundation/openapi"
 	"github.com/qcbit/blockchain/foundation/web"
 )
 
 const version = "v1"
 
+// The set of limits on the size of request bodies. Transactions are small
+// but can carry a data payload, blocks hold many transactions.
+const (
+	maxPeerBodySize  = 1 << 10  // 1KB
+	maxTxBodySize    = 64 << 10 // 64KB
+	maxBlockBodySize = 8 << 20  // 8MB
+)
+
 // Config contains all the mandatory systems required by handlers.
 type Config struct {
-	Log   *zap.SugaredLogger
-	State *state.State
-	NS    *nameservice.NameService
+	Log     *zap.SugaredLogger
+	State   *state.State
+	NS      *nameservice.NameService
+	Audit   *audit.Log
+	KeyPath string
+	Build   string
+	Spec    *openapi.Spec // Describes the public routes when set.
+	Cache   *cache.Cache  // Holds the responses of hot read routes when set.
 }
 
 // PublicRoutes binds all the version 1 public routes.
@@ -31,27 +48,78 @@ func PublicRoutes(app *web.App, cfg Config) {
 		NS:    cfg.NS,
 	}
 
-	app.Handle(http.MethodGet, version, "/genesis/list", pbl.Genesis)
-	app.Handle(http.MethodGet, version, "/accounts/list", pbl.Accounts)
-	app.Handle(http.MethodGet, version, "/accounts/list/:account", pbl.Accounts)
-	app.Handle(http.MethodGet, version, "/tx/uncommitted/list", pbl.Mempool)
-	app.Handle(http.MethodGet, version, "/tx/uncommitted/list/:account", pbl.Mempool)
-	app.Handle(http.MethodPost, version, "/tx/submit", pbl.SubmitWalletTransaction)
-	app.Handle(http.MethodPost, version, "/tx/proof/:block/", pbl.SubmitWalletTransaction)
+	if cfg.Spec != nil {
+		cfg.Spec.Describe(version, public.Operations())
+	}
+
+	// The public routes are superseded by the v2 routes.
+	dep := mid.Deprecated(version, "v2")
+
+	// Responses polled by explorers are served from the cache.
+	cached := mid.Cache(cfg.Cache, "")
+
+	app.Handle(http.MethodGet, version, "/genesis/list", pbl.Genesis, dep, cached)
+	app.Handle(http.MethodGet, version, "/chain/id", pbl.ChainID, dep, cached)
+	app.Handle(http.MethodGet, version, "/chain/signing-info", pbl.SigningInfo, dep, cached)
+	app.Handle(http.MethodGet, version, "/accounts/list", pbl.Accounts, dep, cached)
+	app.Handle(http.MethodGet, version, "/accounts/list/:account", pbl.Accounts, dep, cached)
+	app.Handle(http.MethodGet, version, "/accounts/:account/nonce", pbl.Nonce, dep)
+	app.Handle(http.MethodGet, version, "/accounts/:account/summary", pbl.Summary, mid.Cache(cfg.Cache, "account"))
+	app.Handle(http.MethodPost, version, "/accounts/balances", pbl.Balances, mid.MaxBodySize(maxTxBodySize))
+	app.Handle(http.MethodGet, version, "/block/wait", pbl.WaitForBlock, dep)
+	app.Handle(http.MethodGet, version, "/block/orphans", pbl.Orphans, dep)
+	app.Handle(http.MethodGet, version, "/block/:number/diff", pbl.BlockDiff, dep)
+	app.Handle(http.MethodGet, version, "/checkpoint/:number", pbl.Checkpoint, mid.Compress())
+	app.Handle(http.MethodGet, version, "/tx/status/:hash", pbl.TxStatus, dep)
+	app.Handle(http.MethodGet, version, "/tx/position", pbl.TxPosition)
+	app.Handle(http.MethodGet, version, "/tx/uncommitted/list", pbl.Mempool, dep)
+	app.Handle(http.MethodGet, version, "/tx/uncommitted/list/:account", pbl.Mempool, dep)
+	app.Handle(http.MethodPost, version, "/tx/submit", pbl.SubmitWalletTransaction, dep, mid.MaxBodySize(maxTxBodySize))
+	app.Handle(http.MethodPost, version, "/tx/sendRaw", pbl.SubmitRawTransaction, dep, mid.MaxBodySize(maxTxBodySize))
+	app.Handle(http.MethodPost, version, "/tx/validate", pbl.ValidateTransaction, mid.MaxBodySize(maxTxBodySize))
+	app.Handle(http.MethodPost, version, "/tx/proof/:block/", pbl.SubmitWalletTransaction, mid.MaxBodySize(maxTxBodySize))
 }
 
 // PrivateRoutes binds all the version 1 private routes.
 func PrivateRoutes(app *web.App, cfg Config) {
 	prv := private.Handlers{
-		Log:   cfg.Log,
-		State: cfg.State,
-		NS:    cfg.NS,
+		Log:     cfg.Log,
+		State:   cfg.State,
+		NS:      cfg.NS,
+		Audit:   cfg.Audit,
+		KeyPath: cfg.KeyPath,
+		Build:   cfg.Build,
 	}
 
-	app.Handle(http.MethodPost, version, "/node/peers", prv.SubmitPeer)
+	app.Handle(http.MethodGet, version, "/node/peers", prv.Peers)
+	app.Handle(http.MethodPost, version, "/node/peers", prv.SubmitPeer, mid.MaxBodySize(maxPeerBodySize))
+	app.Handle(http.MethodPost, version, "/node/peers/leave", prv.PeerLeaving, mid.MaxBodySize(maxPeerBodySize))
+	app.Handle(http.MethodGet, version, "/node/peers/bandwidth", prv.PeerBandwidth)
+	app.Handle(http.MethodGet, version, "/node/peers/propagation", prv.PeerPropagation)
 	app.Handle(http.MethodGet, version, "/node/status", prv.Status)
-	app.Handle(http.MethodGet, version, "/node/tx/list", prv.Mempool)
-	app.Handle(http.MethodGet, version, "/node/block/list/:from/:to", prv.BlocksByNumber)
-	app.Handle(http.MethodPost, version, "/node/tx/submit", prv.SubmitNodeTransaction)
-	app.Handle(http.MethodPost, version, "/node/block/propose", prv.ProposeBlock)
+	app.Handle(http.MethodGet, version, "/node/sync", prv.Sync)
+	app.Handle(http.MethodGet, version, "/node/tx/list", prv.Mempool, mid.Compress())
+	app.Handle(http.MethodGet, version, "/node/block/list/:from/:to", prv.BlocksByNumber, mid.Compress())
+	app.Handle(http.MethodGet, version, "/node/block/wait", prv.WaitForBlocks, mid.Compress())
+	app.Handle(http.MethodGet, version, "/node/mining/candidate", prv.MiningCandidate)
+	app.Handle(http.MethodGet, version, "/node/mining/work", prv.MiningWork)
+	app.Handle(http.MethodGet, version, "/node/mining/revenue", prv.MiningRevenue)
+	app.Handle(http.MethodPost, version, "/node/mining/submit", prv.SubmitWork, mid.MaxBodySize(maxPeerBodySize))
+	app.Handle(http.MethodPost, version, "/node/tx/submit", prv.SubmitNodeTransaction, mid.MaxBodySize(maxTxBodySize))
+	app.Handle(http.MethodPost, version, "/node/block/propose", prv.ProposeBlock, mid.MaxBodySize(maxBlockBodySize))
+	app.Handle(http.MethodPost, version, "/node/block/rejected", prv.BlockRejected, mid.MaxBodySize(maxPeerBodySize))
+	app.Handle(http.MethodGet, version, "/node/audit/list", prv.AuditLog)
+	app.Handle(http.MethodPost, version, "/node/backup", prv.Backup)
+	app.Handle(http.MethodPost, version, "/node/chain/reset", prv.ResetChain, mid.MaxBodySize(maxPeerBodySize))
+	app.Handle(http.MethodPost, version, "/node/chain/reset/notice", prv.ChainResetNotice, mid.MaxBodySize(maxPeerBodySize))
+	app.Handle(http.MethodGet, version, "/node/beneficiaries", prv.Beneficiaries)
+	app.Handle(http.MethodPost, version, "/node/beneficiaries", prv.SetBeneficiaries, mid.MaxBodySize(maxPeerBodySize))
+	app.Handle(http.MethodGet, version, "/node/pool/payouts", prv.PoolPayouts)
+	app.Handle(http.MethodGet, version, "/node/accounts/export", prv.ExportAccounts, mid.Compress())
+	app.Handle(http.MethodGet, version, "/node/genesis/export", prv.ExportGenesis)
+	app.Handle(http.MethodGet, version, "/node/storage", prv.StorageUsage)
+	app.Handle(http.MethodPost, version, "/node/storage/compact", prv.CompactStorage)
+	app.Handle(http.MethodPost, version, "/node/webhooks", prv.AddWebhook, mid.MaxBodySize(maxPeerBodySize))
+	app.Handle(http.MethodGet, version, "/node/webhooks/list", prv.Webhooks)
+	app.Handle(http.MethodDelete, version, "/node/webhooks/:id", prv.RemoveWebhook)
 }
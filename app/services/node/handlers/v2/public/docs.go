@@ -0,0 +1,84 @@
+package public
+
+import (
+	"github.com/qcbit/blockchain/foundation/openapi"
+)
+
+// waitQuery describes the query string of the routes that wait for a block.
+var waitQuery = []openapi.Param{
+	{Name: "after", Description: "Number of the block to wait past.", Required: true},
+	{Name: "timeout", Description: "How long to wait as a duration such as 30s, up to 2m."},
+}
+
+// Operations describes the public routes for the OpenAPI document.
+func Operations() openapi.Operations {
+	return openapi.Operations{
+		"GET /genesis/list": {
+			Summary:  "Returns the genesis information of the chain.",
+			Response: genesisInfo{},
+		},
+		"GET /chain/id": {
+			Summary:  "Returns the chain ID and hash algorithm transactions are signed for.",
+			Response: chainInfo{},
+		},
+		"GET /chain/signing-info": {
+			Summary:  "Returns what an external wallet needs to sign transactions.",
+			Response: signingInfo{},
+		},
+		"GET /accounts/list": {
+			Summary:  "Returns the balances of all accounts.",
+			Response: accountList{},
+		},
+		"GET /accounts/list/:account": {
+			Summary:  "Returns the balance of the account.",
+			Response: accountList{},
+		},
+		"GET /accounts/frozen": {
+			Summary:  "Returns the accounts that are frozen.",
+			Response: frozenList{},
+		},
+		"GET /accounts/:account/nonce": {
+			Summary:  "Returns the confirmed and next nonce of the account.",
+			Response: accountNonce{},
+		},
+		"GET /names/:name": {
+			Summary:  "Resolves the name to its account.",
+			Response: accountName{},
+		},
+		"GET /block/wait": {
+			Summary:  "Waits for a block past the after parameter, no content is returned on timeout.",
+			Query:    waitQuery,
+			Response: blockHeader{},
+		},
+		"GET /block/orphans": {
+			Summary:  "Returns the valid blocks that lost to a competing block.",
+			Response: []orphan{},
+		},
+		"GET /block/:number/diff": {
+			Summary:  "Returns the accounts the block changed with their values before and after.",
+			Response: blockDiff{},
+		},
+		"GET /tx/status/:hash": {
+			Summary:  "Reports whether the transaction is unknown, pending or mined.",
+			Response: txStatus{},
+		},
+		"GET /tx/uncommitted/list": {
+			Summary:  "Returns the transactions in the mempool.",
+			Response: []tx{},
+		},
+		"GET /tx/uncommitted/list/:account": {
+			Summary:  "Returns the transactions in the mempool from or to the account.",
+			Response: []tx{},
+		},
+		"POST /tx/submit": {
+			Summary:  "Adds the signed transaction to the mempool.",
+			Request:  newTx{},
+			Response: txSubmitted{},
+		},
+		"POST /tx/sendRaw": {
+			Summary:  "Adds the raw hex encoded transaction to the mempool.",
+			Request:  rawTx{},
+			Response: txSubmitted{},
+		},
+	}
+}
@@ -0,0 +1,268 @@
+package public
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+	"github.com/qcbit/blockchain/foundation/blockchain/genesis"
+	"github.com/qcbit/blockchain/foundation/blockchain/signature"
+)
+
+// CORE NOTE: The v2 models are the contract with clients and are kept apart
+// from the database and genesis types so those can change without breaking
+// the API. Every field uses snake_case, account fields are named after what
+// they hold, binary data and signatures are hex encoded with a 0x prefix.
+// uint64 fields are numbers so they can be encoded as strings, see
+// SetStringNumbers.
+
+type genesisInfo struct {
+	Date          time.Time                 `json:"date"`
+	Version       uint16                    `json:"version"`
+	ChainID       uint16                    `json:"chain_id"`
+	TransPerBlock uint16                    `json:"trans_per_block"`
+	Difficulty    uint16                    `json:"difficulty"`
+	MiningReward  number                    `json:"mining_reward"`
+	GasPrice      number                    `json:"gas_price"`
+	HashAlgorithm string                    `json:"hash_algorithm"`
+	Balances      map[string]number         `json:"balances"`
+	Locks         map[string][]genesis.Lock `json:"locks,omitempty"`
+	Upgrades      []genesis.Upgrade         `json:"upgrades,omitempty"`
+}
+
+func toGenesisInfo(gen genesis.Genesis) genesisInfo {
+	return genesisInfo{
+		Date:          gen.Date,
+		Version:       gen.Version,
+		ChainID:       gen.ChainID,
+		TransPerBlock: gen.TransPerBlock,
+		Difficulty:    gen.Difficulty,
+		MiningReward:  number(gen.MiningReward),
+		GasPrice:      number(gen.GasPrice),
+		HashAlgorithm: gen.HashAlgorithm(),
+		Balances:      toNumbers(gen.Balances),
+		Locks:         gen.Locks,
+		Upgrades:      gen.Upgrades,
+	}
+}
+
+// toNumbers converts the uint64 values of the map.
+func toNumbers(values map[string]uint64) map[string]number {
+	numbers := make(map[string]number, len(values))
+	for key, value := range values {
+		numbers[key] = number(value)
+	}
+	return numbers
+}
+
+type chainInfo struct {
+	ChainID       uint16 `json:"chain_id"`
+	HashAlgorithm string `json:"hash_algorithm"`
+}
+
+type signingInfo struct {
+	StampPrefix        string `json:"stamp_prefix"`
+	StampHashAlgorithm string `json:"stamp_hash_algorithm"`
+	QID                int    `json:"qid"`
+	ChainID            uint16 `json:"chain_id"`
+	HashAlgorithm      string `json:"hash_algorithm"`
+}
+
+type account struct {
+	Account   database.AccountID `json:"account"`
+	Name      string             `json:"name"`
+	Balance   number             `json:"balance"`
+	Locked    number             `json:"locked"`
+	Spendable number             `json:"spendable"`
+	Frozen    bool               `json:"frozen"`
+	Nonce     number             `json:"nonce"`
+}
+
+type accountList struct {
+	LatestBlockHash string    `json:"latest_block_hash"`
+	MempoolLength   int       `json:"mempool_length"`
+	Accounts        []account `json:"accounts"`
+}
+
+type frozenList struct {
+	Accounts []database.AccountID `json:"accounts"`
+}
+
+type accountNonce struct {
+	Account   database.AccountID `json:"account"`
+	Confirmed number             `json:"confirmed"`
+	Next      number             `json:"next"`
+}
+
+type accountName struct {
+	Name    string             `json:"name"`
+	Account database.AccountID `json:"account"`
+}
+
+type accountState struct {
+	Balance number `json:"balance"`
+	Nonce   number `json:"nonce"`
+}
+
+type accountDiff struct {
+	Account database.AccountID `json:"account"`
+	Name    string             `json:"name"`
+	Before  accountState       `json:"before"`
+	After   accountState       `json:"after"`
+}
+
+type blockDiff struct {
+	Number   number        `json:"number"`
+	Hash     string        `json:"hash"`
+	Accounts []accountDiff `json:"accounts"`
+}
+
+type orphan struct {
+	Number        number             `json:"number"`
+	Hash          string             `json:"hash"`
+	PrevBlockHash string             `json:"prev_block_hash"`
+	TimeStamp     number             `json:"timestamp"`
+	Beneficiary   database.AccountID `json:"beneficiary"`
+	Trans         int                `json:"trans"`
+	WinnerHash    string             `json:"winner_hash"`
+	Reason        string             `json:"reason"`
+	SeenAt        number             `json:"seen_at"`
+}
+
+type blockHeader struct {
+	Version       uint16             `json:"version"`
+	Number        number             `json:"number"`
+	Hash          string             `json:"hash"`
+	PrevBlockHash string             `json:"prev_block_hash"`
+	TimeStamp     number             `json:"timestamp"`
+	Beneficiary   database.AccountID `json:"beneficiary"`
+	Difficulty    uint16             `json:"difficulty"`
+	MiningReward  number             `json:"mining_reward"`
+	StateRoot     string             `json:"state_root"`
+	TransRoot     string             `json:"trans_root"`
+	Nonce         number             `json:"nonce"`
+	ExtraData     string             `json:"extra_data,omitempty"`
+}
+
+func toBlockHeader(block database.Block) blockHeader {
+	return blockHeader{
+		Version:       block.Header.Version,
+		Number:        number(block.Header.Number),
+		Hash:          block.Hash(),
+		PrevBlockHash: block.Header.PrevBlockHash,
+		TimeStamp:     number(block.Header.TimeStamp),
+		Beneficiary:   block.Header.BeneficiaryID,
+		Difficulty:    block.Header.Difficulty,
+		MiningReward:  number(block.Header.MiningReward),
+		StateRoot:     block.Header.StateRoot,
+		TransRoot:     block.Header.TransRoot,
+		Nonce:         number(block.Header.Nonce),
+		ExtraData:     block.Header.ExtraData,
+	}
+}
+
+type tx struct {
+	Hash       string               `json:"hash"`
+	From       database.AccountID   `json:"from"`
+	FromName   string               `json:"from_name"`
+	To         database.AccountID   `json:"to"`
+	ToName     string               `json:"to_name"`
+	ChainID    uint16               `json:"chain_id"`
+	Nonce      number               `json:"nonce"`
+	Value      number               `json:"value"`
+	Tip        number               `json:"tip"`
+	Data       string               `json:"data"`
+	TimeStamp  number               `json:"timestamp"`
+	GasPrice   number               `json:"gas_price"`
+	GasUnits   number               `json:"gas_units"`
+	LockUntil  number               `json:"lock_until,omitempty"`
+	Governance *database.Governance `json:"governance,omitempty"`
+	ValidUntil number               `json:"valid_until_block,omitempty"`
+	Sponsor    *database.Sponsor    `json:"sponsor,omitempty"`
+	Signature  string               `json:"signature"`
+}
+
+type txMempool struct {
+	Position int `json:"position"`
+	TipRank  int `json:"tip_rank"`
+	Size     int `json:"size"`
+}
+
+type txBlock struct {
+	Number        number `json:"number"`
+	Hash          string `json:"hash"`
+	Confirmations number `json:"confirmations"`
+	Receipt       string `json:"receipt"`
+	Error         string `json:"error,omitempty"`
+}
+
+type txStatus struct {
+	Hash    string     `json:"hash"`
+	Status  string     `json:"status"`
+	Mempool *txMempool `json:"mempool,omitempty"`
+	Block   *txBlock   `json:"block,omitempty"`
+}
+
+type newTx struct {
+	ChainID    uint16               `json:"chain_id"`
+	From       database.AccountID   `json:"from"`
+	To         database.AccountID   `json:"to"`
+	Value      number               `json:"value"`
+	Nonce      number               `json:"nonce"`
+	Tip        number               `json:"tip"`
+	Data       string               `json:"data"`
+	LockUntil  number               `json:"lock_until,omitempty"`
+	Governance *database.Governance `json:"governance,omitempty"`
+	ValidUntil number               `json:"valid_until_block,omitempty"`
+	Sponsor    *database.Sponsor    `json:"sponsor,omitempty"`
+	Signature  string               `json:"signature"`
+}
+
+// toSignedTx converts the transaction into the signed transaction the
+// signature was produced for.
+func (ntx newTx) toSignedTx() (database.SignedTx, error) {
+	var data []byte
+	if ntx.Data != "" {
+		var err error
+		if data, err = hexutil.Decode(ntx.Data); err != nil {
+			return database.SignedTx{}, errors.New("data must be hex encoded with a 0x prefix")
+		}
+	}
+
+	v, r, s, err := signature.FromSignatureString(ntx.Signature)
+	if err != nil {
+		return database.SignedTx{}, errors.New("signature must be a hex encoded 65 byte [R|S|V] signature")
+	}
+
+	signedTx := database.SignedTx{
+		Tx: database.Tx{
+			ChainID:         ntx.ChainID,
+			FromID:          ntx.From,
+			ToID:            ntx.To,
+			Value:           uint64(ntx.Value),
+			Nonce:           uint64(ntx.Nonce),
+			Tip:             uint64(ntx.Tip),
+			Data:            data,
+			LockUntil:       uint64(ntx.LockUntil),
+			Governance:      ntx.Governance,
+			ValidUntilBlock: uint64(ntx.ValidUntil),
+			Sponsor:         ntx.Sponsor,
+		},
+		V: v,
+		R: r,
+		S: s,
+	}
+
+	return signedTx, nil
+}
+
+type rawTx struct {
+	Raw string `json:"raw"`
+}
+
+type txSubmitted struct {
+	Hash   string `json:"hash"`
+	Status string `json:"status"`
+}
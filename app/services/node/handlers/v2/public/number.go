@@ -0,0 +1,54 @@
+package public
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"sync/atomic"
+)
+
+// stringNumbers reports whether the uint64 fields of the models are encoded
+// as strings. It's set when the routes are loaded, see SetStringNumbers.
+var stringNumbers atomic.Bool
+
+// SetStringNumbers sets whether the uint64 fields of the v2 models are
+// encoded as strings. Balances and other uint64 values can be larger than
+// the integers JavaScript represents exactly, so browser clients would read
+// them wrong as JSON numbers.
+func SetStringNumbers(on bool) {
+	stringNumbers.Store(on)
+}
+
+// number represents a uint64 field of a model. It's encoded as a string or
+// a number depending on SetStringNumbers and decoded from either form.
+type number uint64
+
+// MarshalJSON implements the json.Marshaler interface.
+func (n number) MarshalJSON() ([]byte, error) {
+	s := strconv.FormatUint(uint64(n), 10)
+	if stringNumbers.Load() {
+		return []byte(strconv.Quote(s)), nil
+	}
+
+	return []byte(s), nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (n *number) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	s := string(data)
+	if len(data) > 1 && data[0] == '"' && data[len(data)-1] == '"' {
+		s = string(data[1 : len(data)-1])
+	}
+
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return errors.New("must be an unsigned integer as a number or a string")
+	}
+	*n = number(v)
+
+	return nil
+}
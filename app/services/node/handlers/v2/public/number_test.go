@@ -0,0 +1,49 @@
+package public
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func Test_Number(t *testing.T) {
+	defer SetStringNumbers(false)
+
+	// 2^53 + 1 is the first integer JavaScript can't represent exactly.
+	acct := accountState{Balance: 9007199254740993, Nonce: 2}
+
+	for on, exp := range map[bool]string{
+		true:  `{"balance":"9007199254740993","nonce":"2"}`,
+		false: `{"balance":9007199254740993,"nonce":2}`,
+	} {
+		SetStringNumbers(on)
+
+		data, err := json.Marshal(acct)
+		if err != nil {
+			t.Fatalf("encoding: %v", err)
+		}
+		if string(data) != exp {
+			t.Fatalf("encoding with strings %t: got %s, exp %s", on, data, exp)
+		}
+
+		// Both forms are decoded no matter how numbers are encoded.
+		for _, data := range []string{
+			`{"balance":"9007199254740993","nonce":"2"}`,
+			`{"balance":9007199254740993,"nonce":2}`,
+		} {
+			var got accountState
+			if err := json.Unmarshal([]byte(data), &got); err != nil {
+				t.Fatalf("decoding %s: %v", data, err)
+			}
+			if got != acct {
+				t.Fatalf("decoding %s: got %+v, exp %+v", data, got, acct)
+			}
+		}
+	}
+
+	for _, data := range []string{`{"balance":-1}`, `{"balance":"1.5"}`, `{"balance":"0x10"}`, `{"balance":true}`} {
+		var got accountState
+		if err := json.Unmarshal([]byte(data), &got); err == nil {
+			t.Fatalf("decoding %s should fail", data)
+		}
+	}
+}
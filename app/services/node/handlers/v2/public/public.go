@@ -0,0 +1,432 @@
+// Package public maintains the group of handlers for version 2 public access.
+package public
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"go.uber.org/zap"
+
+	v1 "github.com/qcbit/blockchain/business/web/v1"
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+	"github.com/qcbit/blockchain/foundation/blockchain/nameservice"
+	"github.com/qcbit/blockchain/foundation/blockchain/signature"
+	"github.com/qcbit/blockchain/foundation/blockchain/state"
+	"github.com/qcbit/blockchain/foundation/web"
+)
+
+// Handlers manages the set of bar ledger endpoints.
+type Handlers struct {
+	Log   *zap.SugaredLogger
+	State *state.State
+	NS    *nameservice.NameService
+}
+
+// SubmitWalletTransaction adds new transactions to the mempool.
+func (h Handlers) SubmitWalletTransaction(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var ntx newTx
+	if err := web.Decode(r, &ntx); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+
+	signedTx, err := ntx.toSignedTx()
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	h.Log.Infow("add tran", "traceid", v.TraceID, "sig:nonce", signedTx, "from", signedTx.FromID,
+		"to", signedTx.ToID, "value", signedTx.Value, "tip", signedTx.Tip)
+
+	if err := h.State.UpsertWalletTransaction(signedTx); err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	resp := txSubmitted{
+		Hash:   signedTx.TxHash(h.State.Rules().HashAlgorithm),
+		Status: txStatusPending,
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// SubmitRawTransaction adds a new transaction provided in the raw hex
+// encoding to the mempool.
+func (h Handlers) SubmitRawTransaction(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var raw rawTx
+	if err := web.Decode(r, &raw); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+
+	signedTx, err := database.DecodeRawHex(raw.Raw)
+	if err != nil {
+		return v1.NewRequestError(fmt.Errorf("unable to decode raw transaction: %w", err), http.StatusBadRequest)
+	}
+
+	h.Log.Infow("add raw tran", "traceid", v.TraceID, "sig:nonce", signedTx, "from", signedTx.FromID,
+		"to", signedTx.ToID, "value", signedTx.Value, "tip", signedTx.Tip)
+
+	if err := h.State.UpsertWalletTransaction(signedTx); err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	resp := txSubmitted{
+		Hash:   signedTx.TxHash(h.State.Rules().HashAlgorithm),
+		Status: txStatusPending,
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// Genesis returns the genesis information.
+func (h Handlers) Genesis(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	return web.Respond(ctx, w, toGenesisInfo(h.State.Genesis()), http.StatusOK)
+}
+
+// ChainID returns the chain ID transactions must be signed for and the
+// hash algorithm currently used to identify them.
+func (h Handlers) ChainID(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	gen := h.State.Genesis()
+
+	info := chainInfo{
+		ChainID:       gen.ChainID,
+		HashAlgorithm: h.State.Rules().HashAlgorithm,
+	}
+
+	return web.Respond(ctx, w, info, http.StatusOK)
+}
+
+// SigningInfo returns what an external wallet needs to sign transactions
+// the way this node verifies them: the stamp written in front of the data,
+// the algorithm the stamped data is hashed with, the value added to the v
+// component of the signature, the chain ID and the chain's hash algorithm.
+func (h Handlers) SigningInfo(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	info := signingInfo{
+		StampPrefix:        signature.StampPrefix,
+		StampHashAlgorithm: signature.StampHashAlgorithm,
+		QID:                signature.QID,
+		ChainID:            h.State.Genesis().ChainID,
+		HashAlgorithm:      h.State.Rules().HashAlgorithm,
+	}
+
+	return web.Respond(ctx, w, info, http.StatusOK)
+}
+
+// Accounts returns the current balances for all users.
+func (h Handlers) Accounts(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	accountStr := web.Param(r, "account")
+
+	var accounts map[database.AccountID]database.Account
+	switch accountStr {
+	case "":
+		accounts = h.State.Accounts()
+
+	default:
+		accountID, err := database.ToAccountID(accountStr)
+		if err != nil {
+			return v1.NewRequestError(err, http.StatusBadRequest)
+		}
+		acct, err := h.State.QueryAccount(accountID)
+		if err != nil {
+			return v1.NewRequestError(err, http.StatusNotFound)
+		}
+		accounts = map[database.AccountID]database.Account{accountID: acct}
+	}
+
+	// Report what can be spent in the next block.
+	next := h.State.LatestBlock().Header.Number + 1
+
+	resp := accountList{
+		LatestBlockHash: h.State.LatestBlock().Hash(),
+		MempoolLength:   h.State.MempoolLength(),
+		Accounts:        make([]account, 0, len(accounts)),
+	}
+	for accountID, info := range accounts {
+		resp.Accounts = append(resp.Accounts, account{
+			Account:   accountID,
+			Name:      h.NS.Lookup(accountID),
+			Balance:   number(info.Balance),
+			Locked:    number(info.Locked(next)),
+			Spendable: number(info.Spendable(next)),
+			Frozen:    info.Frozen,
+			Nonce:     number(info.Nonce),
+		})
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// Frozen returns the accounts frozen by the validators. Transactions from
+// these accounts are rejected.
+func (h Handlers) Frozen(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	resp := frozenList{
+		Accounts: h.State.QueryFrozen(),
+	}
+	if resp.Accounts == nil {
+		resp.Accounts = []database.AccountID{}
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// Nonce returns the nonce of the account's last mined transaction and the
+// next nonce to use for a new transaction.
+func (h Handlers) Nonce(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	accountID, err := database.ToAccountID(web.Param(r, "account"))
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	confirmed, next := h.State.QueryNonce(accountID)
+
+	resp := accountNonce{
+		Account:   accountID,
+		Confirmed: number(confirmed),
+		Next:      number(next),
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// ResolveName returns the account ID of the account with the specified name
+// in the node's name service.
+func (h Handlers) ResolveName(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	name := web.Param(r, "name")
+
+	accountID, ok := h.NS.Resolve(name)
+	if !ok {
+		return v1.NewRequestError(fmt.Errorf("name %q not found", name), http.StatusNotFound)
+	}
+
+	resp := accountName{
+		Name:    name,
+		Account: accountID,
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// BlockDiff returns the accounts whose balance or nonce changed in the
+// specified block with their values before and after the block.
+func (h Handlers) BlockDiff(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	num, err := strconv.ParseUint(web.Param(r, "number"), 10, 64)
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	diffs, err := h.State.QueryBlockDiff(num)
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusNotFound)
+	}
+
+	blocks, err := h.State.QueryBlocksByNumber(ctx, num, num)
+	if err != nil {
+		return err
+	}
+	if len(blocks) == 0 {
+		return v1.NewRequestError(fmt.Errorf("block %d not found", num), http.StatusNotFound)
+	}
+
+	resp := blockDiff{
+		Number:   number(num),
+		Hash:     blocks[0].Hash(),
+		Accounts: make([]accountDiff, len(diffs)),
+	}
+	for i, diff := range diffs {
+		resp.Accounts[i] = accountDiff{
+			Account: diff.AccountID,
+			Name:    h.NS.Lookup(diff.AccountID),
+			Before:  accountState{Balance: number(diff.Before.Balance), Nonce: number(diff.Before.Nonce)},
+			After:   accountState{Balance: number(diff.After.Balance), Nonce: number(diff.After.Nonce)},
+		}
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// Orphans returns the valid blocks that lost to a competing block at the
+// same height so the network's fork rate can be monitored.
+func (h Handlers) Orphans(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	orphans := h.State.QueryOrphans()
+
+	resp := make([]orphan, len(orphans))
+	for i, o := range orphans {
+		resp[i] = orphan{
+			Number:        number(o.Block.Header.Number),
+			Hash:          o.Block.Hash,
+			PrevBlockHash: o.Block.Header.PrevBlockHash,
+			TimeStamp:     number(o.Block.Header.TimeStamp),
+			Beneficiary:   o.Block.Header.BeneficiaryID,
+			Trans:         len(o.Block.Trans),
+			WinnerHash:    o.WinnerHash,
+			Reason:        o.Reason,
+			SeenAt:        number(o.SeenAt),
+		}
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// The range of time a client can ask to wait for a new block.
+const (
+	defaultBlockWait = 30 * time.Second
+	maxBlockWait     = 2 * time.Minute
+)
+
+// WaitForBlock holds the request until a block higher than the after
+// query parameter is accepted and returns the new block header. If no
+// block arrives before the timeout, no content is returned.
+func (h Handlers) WaitForBlock(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	after, err := strconv.ParseUint(r.URL.Query().Get("after"), 10, 64)
+	if err != nil {
+		return v1.NewRequestError(fmt.Errorf("invalid after: %w", err), http.StatusBadRequest)
+	}
+
+	wait := defaultBlockWait
+	if timeoutStr := r.URL.Query().Get("timeout"); timeoutStr != "" {
+		wait, err = time.ParseDuration(timeoutStr)
+		if err != nil || wait <= 0 {
+			return v1.NewRequestError(errors.New("invalid timeout"), http.StatusBadRequest)
+		}
+		if wait > maxBlockWait {
+			wait = maxBlockWait
+		}
+	}
+
+	// The wait can outlast the server's write timeout so extend the
+	// deadline for this request to allow the response to be written.
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Now().Add(wait + 5*time.Second)); err != nil {
+		h.Log.Infow("wait for block", "ERROR", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+
+	block, err := h.State.WaitForBlock(ctx, after)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return web.Respond(ctx, w, nil, http.StatusNoContent)
+		}
+		return err
+	}
+
+	return web.Respond(ctx, w, toBlockHeader(block), http.StatusOK)
+}
+
+// The set of statuses for a transaction.
+const (
+	txStatusUnknown = "unknown"
+	txStatusPending = "pending"
+	txStatusMined   = "mined"
+)
+
+// TxStatus reports whether the transaction with the specified hash is
+// unknown, pending in the mempool or mined into a block.
+func (h Handlers) TxStatus(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	hash := strings.ToLower(web.Param(r, "hash"))
+
+	resp := txStatus{
+		Hash:   hash,
+		Status: txStatusUnknown,
+	}
+
+	if receipt, mined := h.State.QueryReceipt(hash); mined {
+		latest := h.State.LatestBlock()
+
+		block := txBlock{
+			Number:        number(receipt.BlockNumber),
+			Confirmations: number(latest.Header.Number - receipt.BlockNumber + 1),
+			Receipt:       receipt.Status,
+			Error:         receipt.Error,
+		}
+		blocks, err := h.State.QueryBlocksByNumber(ctx, receipt.BlockNumber, receipt.BlockNumber)
+		if err != nil {
+			return err
+		}
+		if len(blocks) == 1 {
+			block.Hash = blocks[0].Hash()
+		}
+
+		resp.Status = txStatusMined
+		resp.Block = &block
+
+		return web.Respond(ctx, w, resp, http.StatusOK)
+	}
+
+	mempool := h.State.Mempool()
+	algorithm := h.State.Rules().HashAlgorithm
+	for i, tran := range mempool {
+		if tran.TxHash(algorithm) != hash {
+			continue
+		}
+
+		rank := 1
+		for _, other := range mempool {
+			if other.Tip > tran.Tip {
+				rank++
+			}
+		}
+
+		resp.Status = txStatusPending
+		resp.Mempool = &txMempool{
+			Position: i + 1,
+			TipRank:  rank,
+			Size:     len(mempool),
+		}
+		break
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// Mempool returns the current uncommitted transactions.
+func (h Handlers) Mempool(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	acct := database.AccountID(web.Param(r, "account"))
+
+	trans := []tx{}
+	algorithm := h.State.Rules().HashAlgorithm
+	for _, tran := range h.State.Mempool() {
+		if acct != "" && acct != tran.FromID && acct != tran.ToID {
+			continue
+		}
+
+		trans = append(trans, tx{
+			Hash:       tran.TxHash(algorithm),
+			From:       tran.FromID,
+			FromName:   h.NS.Lookup(tran.FromID),
+			To:         tran.ToID,
+			ToName:     h.NS.Lookup(tran.ToID),
+			ChainID:    tran.ChainID,
+			Nonce:      number(tran.Nonce),
+			Value:      number(tran.Value),
+			Tip:        number(tran.Tip),
+			Data:       hexutil.Encode(tran.Data),
+			TimeStamp:  number(tran.TimeStamp),
+			GasPrice:   number(tran.GasPrice),
+			GasUnits:   number(tran.GasUnits),
+			LockUntil:  number(tran.LockUntil),
+			Governance: tran.Governance,
+			ValidUntil: number(tran.ValidUntilBlock),
+			Sponsor:    tran.Sponsor,
+			Signature:  tran.SignatureString(),
+		})
+	}
+
+	return web.Respond(ctx, w, trans, http.StatusOK)
+}
@@ -0,0 +1,69 @@
+// Package v2 contains the full set of handler functions and routes
+// supported by the v2 web api.
+package v2
+
+import (
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/qcbit/blockchain/app/services/node/handlers/v2/public"
+	"github.com/qcbit/blockchain/business/web/cache"
+	"github.com/qcbit/blockchain/business/web/v1/mid"
+	"github.com/qcbit/blockchain/foundation/blockchain/nameservice"
+	"github.com/qcbit/blockchain/foundation/blockchain/state"
+	"github.com/qcbit/blockchain/foundation/openapi"
+	"github.com/qcbit/blockchain/foundation/web"
+)
+
+const version = "v2"
+
+// maxTxBodySize limits the size of request bodies carrying a transaction.
+const maxTxBodySize = 64 << 10 // 64KB
+
+// Config contains all the mandatory systems required by handlers.
+type Config struct {
+	Log   *zap.SugaredLogger
+	State *state.State
+	NS    *nameservice.NameService
+	Spec  *openapi.Spec // Describes the public routes when set.
+	Cache *cache.Cache  // Holds the responses of hot read routes when set.
+
+	// StringNumbers encodes the uint64 fields of responses as strings.
+	StringNumbers bool
+}
+
+// PublicRoutes binds all the version 2 public routes.
+func PublicRoutes(app *web.App, cfg Config) {
+	pbl := public.Handlers{
+		Log:   cfg.Log,
+		State: cfg.State,
+		NS:    cfg.NS,
+	}
+
+	public.SetStringNumbers(cfg.StringNumbers)
+
+	if cfg.Spec != nil {
+		cfg.Spec.Describe(version, public.Operations())
+	}
+
+	// Responses polled by explorers are served from the cache.
+	cached := mid.Cache(cfg.Cache, "")
+
+	app.Handle(http.MethodGet, version, "/genesis/list", pbl.Genesis, cached)
+	app.Handle(http.MethodGet, version, "/chain/id", pbl.ChainID, cached)
+	app.Handle(http.MethodGet, version, "/chain/signing-info", pbl.SigningInfo, cached)
+	app.Handle(http.MethodGet, version, "/accounts/list", pbl.Accounts, cached)
+	app.Handle(http.MethodGet, version, "/accounts/list/:account", pbl.Accounts, cached)
+	app.Handle(http.MethodGet, version, "/accounts/frozen", pbl.Frozen)
+	app.Handle(http.MethodGet, version, "/accounts/:account/nonce", pbl.Nonce)
+	app.Handle(http.MethodGet, version, "/names/:name", pbl.ResolveName)
+	app.Handle(http.MethodGet, version, "/block/wait", pbl.WaitForBlock)
+	app.Handle(http.MethodGet, version, "/block/orphans", pbl.Orphans)
+	app.Handle(http.MethodGet, version, "/block/:number/diff", pbl.BlockDiff)
+	app.Handle(http.MethodGet, version, "/tx/status/:hash", pbl.TxStatus)
+	app.Handle(http.MethodGet, version, "/tx/uncommitted/list", pbl.Mempool)
+	app.Handle(http.MethodGet, version, "/tx/uncommitted/list/:account", pbl.Mempool)
+	app.Handle(http.MethodPost, version, "/tx/submit", pbl.SubmitWalletTransaction, mid.MaxBodySize(maxTxBodySize))
+	app.Handle(http.MethodPost, version, "/tx/sendRaw", pbl.SubmitRawTransaction, mid.MaxBodySize(maxTxBodySize))
+}
@@ -1,12 +1,19 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"crypto/ecdsa"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -14,15 +21,25 @@ import (
 	"github.com/common-nighthawk/go-figure"
 	"github.com/ethereum/go-ethereum/crypto"
 	"go.uber.org/zap"
+	"golang.org/x/term"
 
 	"github.com/qcbit/blockchain/app/services/node/handlers"
+	"github.com/qcbit/blockchain/business/web/apikey"
+	"github.com/qcbit/blockchain/business/web/audit"
+	"github.com/qcbit/blockchain/business/web/cache"
+	"github.com/qcbit/blockchain/foundation/blockchain/backup"
 	"github.com/qcbit/blockchain/foundation/blockchain/database"
 	"github.com/qcbit/blockchain/foundation/blockchain/genesis"
+	"github.com/qcbit/blockchain/foundation/blockchain/keystore"
 	"github.com/qcbit/blockchain/foundation/blockchain/nameservice"
+	"github.com/qcbit/blockchain/foundation/blockchain/netfault"
+	"github.com/qcbit/blockchain/foundation/blockchain/payout"
 	"github.com/qcbit/blockchain/foundation/blockchain/peer"
 	"github.com/qcbit/blockchain/foundation/blockchain/state"
 	"github.com/qcbit/blockchain/foundation/blockchain/storage/disk"
+	"github.com/qcbit/blockchain/foundation/blockchain/storage/s3"
 	"github.com/qcbit/blockchain/foundation/blockchain/worker"
+	"github.com/qcbit/blockchain/foundation/config"
 	"github.com/qcbit/blockchain/foundation/logger"
 )
 
@@ -31,23 +48,14 @@ var build = "develop"
 
 func main() {
 
-	// Construct the application logger.
-	log, err := logger.New("NODE")
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
-	}
-	defer log.Sync()
-
-	// Perform the startup and shutdown sequence.
-	if err := run(log); err != nil {
-		log.Errorw("startup", "ERROR", err)
-		log.Sync()
+	// Perform the startup and shutdown sequence. Errors are logged by run
+	// once the logger has been constructed from the configuration.
+	if err := run(); err != nil {
 		os.Exit(1)
 	}
 }
 
-func run(log *zap.SugaredLogger) error {
+func run() (err error) {
 
 	// =========================================================================
 	// Configuration
@@ -57,7 +65,9 @@ func run(log *zap.SugaredLogger) error {
 	// values.
 	cfg := struct {
 		conf.Version
-		Web struct {
+		Args   conf.Args `conf:"noprint"` // node config print writes the effective configuration as a file.
+		Config string    // Optional YAML or TOML file, the environment and flags override it.
+		Web    struct {
 			ReadTimeout     time.Duration `conf:"default:5s"`
 			WriteTimeout    time.Duration `conf:"default:10s"`
 			IdleTimeout     time.Duration `conf:"default:120s"`
@@ -65,17 +75,99 @@ func run(log *zap.SugaredLogger) error {
 			DebugHost       string        `conf:"default:0.0.0.0:7080"`
 			PublicHost      string        `conf:"default:0.0.0.0:8080"`
 			PrivateHost     string        `conf:"default:0.0.0.0:9080"`
+			GraphQL         bool          `conf:"flag:web-graphql,env:WEB_GRAPHQL"` // Serve the GraphQL endpoint for explorers on the public host.
+			CacheEntries    int           `conf:"default:1000"`                     // Most responses of hot read routes cached, zero turns caching off.
+			APIKeys         []string      `conf:"mask"`                             // Keys required on the public API as name:token or name:token:rate, none leaves it open.
+			APIKeyRate      int           `conf:"default:600"`                      // Requests per minute of keys without their own rate, zero is unlimited.
+			StringNumbers   bool          `conf:"default:true"`                     // Encode the uint64 fields of v2 responses as strings for JavaScript clients.
 		}
 		State struct {
-			Beneficiary    string   `conf:"default:miner1"`
-			SelectStrategy string   `conf:"default:Tip"`
-			DBPath         string   `conf:"default:zblock/miner1/"`
-			OriginPeers    []string `conf:"default:0.0.0.0:9080"`
-			Consensus      string   `conf:"default:POA"` // POW - Proof of Work, POA - Proof of Authority
+			Beneficiary     string        `conf:"default:miner1"` // Account paid for mined blocks, the node only relays without its key.
+			Beneficiaries   []string      // Accounts paid in turn for mined blocks as name or name:weight.
+			Rotation        string        `conf:"default:round-robin"` // round-robin or weighted
+			PoolShares      []string      // Pool contributors paid a share of mining rewards as name:percent.
+			ExtraData       string        // Tag added to mined blocks such as a node name or pool identifier.
+			SelectStrategy  string        `conf:"default:Tip"`
+			DBPath          string        `conf:"default:zblock/miner1/"`
+			RestorePath     string        // Optional backup tarball to restore on startup.
+			IdentityPath    string        // Key a node without a beneficiary key signs peer messages with, next to the db path when empty.
+			OriginPeers     []string      `conf:"default:0.0.0.0:9080"`
+			SeedDNS         string        // DNS name whose A and TXT records list bootstrap peers.
+			SeedPort        string        `conf:"default:9080"` // Private port of the peers listed in A records.
+			SeedInterval    time.Duration `conf:"default:10m"`  // Zero only seeds on startup.
+			MaxPeers        int           // Most inbound and outbound peers together, zero is unlimited.
+			MaxInbound      int           // Most peers that registered with this node.
+			MaxOutbound     int           // Most peers this node learned about.
+			AllowPeers      []string      // Only these peer hosts may register, gossip and propose blocks.
+			DenyPeers       []string      // Peer hosts that are always rejected.
+			AllowPeerKeys   []string      // Only peers signing with these accounts may gossip and propose blocks.
+			DenyPeerKeys    []string      // Accounts whose signed messages are always rejected.
+			MaxClockDrift   time.Duration `conf:"default:1s"` // Most the clock can be off the peers' median before warning, zero never warns.
+			Primary         string        // Private host of the primary node to follow as a read replica.
+			PeerBandwidth   int64         // Bytes per second sent to any one peer, zero is unlimited.
+			Consensus       string        `conf:"default:POA"` // POW - Proof of Work, POA - Proof of Authority
+			ShutdownTimeout time.Duration `conf:"default:10s"`
+			CompactInterval time.Duration // Zero turns off compacting storage in the background.
+			ReadableBlocks  uint64        `conf:"default:100"`   // Latest blocks left readable by compaction.
+			HistoryBlocks   uint64        `conf:"default:10000"` // Latest blocks with diffs, receipts and activity in memory, zero keeps all.
+			LazyLoad        bool          // Serve from the latest snapshot while the chain is audited in the background.
+			Chains          []string      // Extra chains hosted in the process as id:genesis-file, served under /v1/chains/id.
+			AllowReset      bool          // Lets the chain be wiped back to genesis, for development networks only.
+		}
+		ColdStore struct {
+			Endpoint    string // S3 compatible object store old blocks are moved to, off when empty.
+			Region      string `conf:"default:us-east-1"`
+			Bucket      string
+			Prefix      string
+			AccessKey   string `conf:"mask"`
+			SecretKey   string `conf:"mask"`
+			LocalBlocks uint64 `conf:"default:10000"` // Latest blocks kept on the local disk when storage is compacted.
+		}
+		Faults struct {
+			Latency      time.Duration // Added to every call to a peer, for testing only.
+			Jitter       time.Duration // Most random latency added on top.
+			DropRate     float64       // Fraction of calls to peers dropped.
+			ReorderRate  float64       // Fraction of calls to peers held back so later calls overtake them.
+			ReorderDelay time.Duration `conf:"default:1s"`
+			Seed         int64         `conf:"default:1"`
+		}
+		Queues struct {
+			StartMining  int           `conf:"default:1"`
+			CancelMining int           `conf:"default:1"`
+			TxShare      int           `conf:"default:100"`
+			Proposals    int           `conf:"default:32"`
+			Policy       string        `conf:"default:drop"` // drop or block
+			BlockTimeout time.Duration `conf:"default:1s"`   // Most a sender waits for room with the block policy.
+		}
+		Chaos struct {
+			Seed            int64         `conf:"default:1"`
+			MaxSignalDelay  time.Duration // Most a mining signal is delayed, for testing only.
+			GossipDropRate  float64       // Fraction of mined blocks and transactions not shared.
+			RestartInterval time.Duration // Average time between killing and restarting a worker goroutine.
 		}
 		NameService struct {
 			Folder string `conf:"default:zblock/accounts/"`
 		}
+		Key struct {
+			Source         string `conf:"default:file"`             // file, env, keystore or vault
+			Env            string `conf:"default:NODE_PRIVATE_KEY"` // Variable holding the hex key for the env source.
+			PassphraseFile string // File holding the keystore passphrase, prompted for when empty.
+			VaultAddress   string // Vault server holding the key for the vault source.
+			VaultToken     string `conf:"mask"`
+			VaultMount     string `conf:"default:secret"`
+			VaultField     string `conf:"default:private_key"`
+		}
+		Log struct {
+			Level    string `conf:"default:info"` // debug, info, warn or error
+			Path     string // Optional file to also write the logs to.
+			MaxSize  int64  `conf:"default:104857600"`
+			MaxFiles int    `conf:"default:5"`
+		}
+		Audit struct {
+			Path     string `conf:"default:zblock/audit/miner1.log"`
+			MaxSize  int64  `conf:"default:10485760"`
+			MaxFiles int    `conf:"default:5"`
+		}
 	}{
 		Version: conf.Version{
 			Build: build,
@@ -84,17 +176,47 @@ func run(log *zap.SugaredLogger) error {
 	}
 
 	// Parse will set the defaults and then look for any overriding values
-	// in environment variables and command line flags.
+	// in the config file, environment variables and command line flags.
 	const prefix = "NODE"
-	help, err := conf.Parse(prefix, &cfg)
+	help, err := conf.Parse(prefix, &cfg, config.NewFile(os.Args[1:]))
 	if err != nil {
 		if errors.Is(err, conf.ErrHelpWanted) {
 			fmt.Println(help)
 			return nil
 		}
-		return fmt.Errorf("parsing config: %w", err)
+		fmt.Println("parsing config:", err)
+		return err
+	}
+
+	// The effective configuration is written as a file to start from.
+	if cfg.Args.Num(0) == "config" && cfg.Args.Num(1) == "print" {
+		return config.Print(os.Stdout, prefix, &cfg)
 	}
 
+	// =========================================================================
+	// Logger Support
+
+	// Construct the application logger. The level can be changed at runtime
+	// through the debug mux.
+	log, level, err := logger.NewWithConfig(logger.Config{
+		Service:  prefix,
+		Level:    cfg.Log.Level,
+		Path:     cfg.Log.Path,
+		MaxSize:  cfg.Log.MaxSize,
+		MaxFiles: cfg.Log.MaxFiles,
+	})
+	if err != nil {
+		fmt.Println("constructing logger:", err)
+		return err
+	}
+	defer log.Sync()
+
+	defer func() {
+		if err != nil {
+			log.Errorw("startup", "ERROR", err)
+		}
+	}()
+
 	// =========================================================================
 	// App Starting
 
@@ -140,43 +262,197 @@ func run(log *zap.SugaredLogger) error {
 		log.Infow(f.Name())
 	}
 
-	// Need to load the private key file for the configured beneficiary
-	// so the account can get credited with fees and tips.
-	path := fmt.Sprintf("%s%s.ecdsa", cfg.NameService.Folder, cfg.State.Beneficiary)
-	privateKey, err := crypto.LoadECDSA(path)
+	// Move old blocks to an object store when one is configured. Headers
+	// stay on disk and reading an old block goes through to the store.
+	var diskOptions []func(d *disk.Disk)
+	var localBlocks uint64
+	if cfg.ColdStore.Endpoint != "" {
+		cold, err := s3.New(s3.Config{
+			Endpoint:  cfg.ColdStore.Endpoint,
+			Region:    cfg.ColdStore.Region,
+			Bucket:    cfg.ColdStore.Bucket,
+			Prefix:    cfg.ColdStore.Prefix,
+			AccessKey: cfg.ColdStore.AccessKey,
+			SecretKey: cfg.ColdStore.SecretKey,
+		})
+		if err != nil {
+			return fmt.Errorf("unable to create cold store: %w", err)
+		}
+		diskOptions = append(diskOptions, disk.WithColdStore(cold))
+		localBlocks = cfg.ColdStore.LocalBlocks
+
+		log.Infow("startup", "status", "cold store", "endpoint", cfg.ColdStore.Endpoint,
+			"bucket", cfg.ColdStore.Bucket, "local", localBlocks)
+	}
+
+	// Construct the use of disk storage.
+	storage, err := disk.New(cfg.State.DBPath, diskOptions...)
+	if err != nil {
+		return err
+	}
+
+	// Only a key kept in the accounts folder is carried in backups, keys
+	// kept elsewhere stay there.
+	var path string
+	if cfg.Key.Source == "file" {
+		path = keystore.Folder(cfg.NameService.Folder).Path(cfg.State.Beneficiary)
+	}
+
+	// Replace the chain with the one in the backup when asked to. This
+	// happens before the node key is loaded since the backup carries it.
+	var restored backup.Contents
+	if cfg.State.RestorePath != "" {
+		f, err := os.Open(cfg.State.RestorePath)
+		if err != nil {
+			return fmt.Errorf("unable to open backup: %w", err)
+		}
+
+		restored, err = backup.Restore(f, storage, path)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("unable to restore backup: %w", err)
+		}
+
+		log.Infow("startup", "status", "restored backup", "path", cfg.State.RestorePath, "blocks", restored.Blocks,
+			"mempool", len(restored.Mempool), "peers", len(restored.Peers), "key", restored.Key)
+	}
+
+	// Need to load the private key for the configured beneficiary so
+	// the account can get credited with fees and tips.
+	keys, err := keyProvider(cfg.Key.Source, cfg.Key.Env, cfg.Key.PassphraseFile, cfg.NameService.Folder, keystore.VaultConfig{
+		Address: cfg.Key.VaultAddress,
+		Token:   cfg.Key.VaultToken,
+		Mount:   cfg.Key.VaultMount,
+		Field:   cfg.Key.VaultField,
+	})
 	if err != nil {
-		return fmt.Errorf("unable to load private key for node: %w", err)
+		return err
+	}
+
+	// A node without a beneficiary key only relays and validates. It signs
+	// its peer messages with an identity key kept next to its blocks, which
+	// is made on the first start and holds no value.
+	var beneficiaryID database.AccountID
+	var privateKey *ecdsa.PrivateKey
+	if cfg.State.Beneficiary != "" {
+		privateKey, err = keys.PrivateKey(context.Background(), cfg.State.Beneficiary)
+		switch {
+		case err == nil:
+			beneficiaryID = database.PublicKeyToAccountID(privateKey.PublicKey)
+			log.Infow("startup", "status", "node key loaded", "source", cfg.Key.Source, "account", beneficiaryID)
+
+		case errors.Is(err, keystore.ErrNotFound):
+			log.Warnw("startup", "status", "no beneficiary key, relaying only", "beneficiary", cfg.State.Beneficiary, "source", cfg.Key.Source, "ERROR", err)
+
+		default:
+			return fmt.Errorf("unable to load private key for node: %w", err)
+		}
+	}
+
+	if privateKey == nil {
+		path = ""
+		identityPath := cfg.State.IdentityPath
+		if identityPath == "" {
+			identityPath = filepath.Clean(cfg.State.DBPath) + ".identity.ecdsa"
+		}
+		privateKey, err = identityKey(identityPath)
+		if err != nil {
+			return fmt.Errorf("unable to load identity key for node: %w", err)
+		}
+		log.Infow("startup", "status", "relay node", "identity", database.PublicKeyToAccountID(privateKey.PublicKey))
 	}
 
 	// A peer set is a collection of known nodes in the
-	// network so transactions and blocks can be shared.
+	// network so transactions and blocks can be shared. Peers named
+	// by host name are resolved so they aren't known twice when also
+	// referenced by address.
 	peerSet := peer.NewPeerSet()
+	peerSet.SetResolver(net.DefaultResolver)
 	for _, host := range cfg.State.OriginPeers {
 		peerSet.Add(peer.New(host))
 	}
 	peerSet.Add(peer.New(cfg.Web.PrivateHost))
+	for _, p := range restored.Peers {
+		peerSet.Add(p)
+	}
 
 	ev := func(v string, args ...any) {
 		s := fmt.Sprintf(v, args...)
 		log.Infow(s, "traceid", "00000000-0000-0000-0000-000000000000")
 	}
 
-	// Construct the use of disk storage.
-	storage, err := disk.New(cfg.State.DBPath)
+	// Load the genesis file.
+	genesis, err := genesis.Load()
 	if err != nil {
 		return err
 	}
 
-	// Load the genesis file.
-	genesis, err := genesis.Load()
-	if err != nil {
+	// Balances in the genesis file can be allocated to account names.
+	resolve := func(name string) (string, bool) {
+		accountID, ok := ns.Resolve(name)
+		return string(accountID), ok
+	}
+	if err := genesis.ResolveNames(resolve); err != nil {
 		return err
 	}
 
+	// Mined blocks can pay a set of accounts in turn instead of
+	// the node's own account.
+	rotation := state.Rotation{
+		Strategy: cfg.State.Rotation,
+	}
+	for _, ben := range cfg.State.Beneficiaries {
+		name, weightStr, _ := strings.Cut(ben, ":")
+
+		accountID, ok := ns.Resolve(name)
+		if !ok {
+			if accountID, err = database.ToAccountID(name); err != nil {
+				return fmt.Errorf("unable to resolve beneficiary %q", name)
+			}
+		}
+
+		var weight uint64 = 1
+		if weightStr != "" {
+			if weight, err = strconv.ParseUint(weightStr, 10, 64); err != nil {
+				return fmt.Errorf("invalid weight for beneficiary %q: %w", name, err)
+			}
+		}
+
+		rotation.Beneficiaries = append(rotation.Beneficiaries, state.Beneficiary{
+			AccountID: accountID,
+			Weight:    weight,
+		})
+	}
+
+	// Mining rewards paid to the node's account can be shared with the
+	// contributors of a mining pool.
+	var poolShares []payout.Share
+	for _, share := range cfg.State.PoolShares {
+		name, percentStr, _ := strings.Cut(share, ":")
+
+		accountID, ok := ns.Resolve(name)
+		if !ok {
+			if accountID, err = database.ToAccountID(name); err != nil {
+				return fmt.Errorf("unable to resolve pool contributor %q", name)
+			}
+		}
+
+		percent, err := strconv.ParseUint(percentStr, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid share for pool contributor %q: %w", name, err)
+		}
+
+		poolShares = append(poolShares, payout.Share{
+			AccountID: accountID,
+			Percent:   percent,
+		})
+	}
+
 	// The state value represents the blockchain node and manages the blockchain database
 	// and provides the API for the application support.
-	state, err := state.New(state.Config{
-		BeneficiaryID:  database.PublicKeyToAccountID(privateKey.PublicKey),
+	stateCfg := state.Config{
+		BeneficiaryID:  beneficiaryID,
+		PrivateKey:     privateKey,
 		Host:           cfg.Web.PrivateHost,
 		Storage:        storage,
 		Genesis:        genesis,
@@ -184,7 +460,40 @@ func run(log *zap.SugaredLogger) error {
 		KnownPeers:     peerSet,
 		EvHandler:      ev,
 		Consensus:      cfg.State.Consensus,
-	})
+		Primary:        cfg.State.Primary,
+		PeerBandwidth:  cfg.State.PeerBandwidth,
+		Rotation:       rotation,
+		PoolShares:     poolShares,
+		ExtraData:      cfg.State.ExtraData,
+		LazyLoad:       cfg.State.LazyLoad,
+		AllowReset:     cfg.State.AllowReset,
+		PeerLimits: peer.Limits{
+			MaxPeers:    cfg.State.MaxPeers,
+			MaxInbound:  cfg.State.MaxInbound,
+			MaxOutbound: cfg.State.MaxOutbound,
+		},
+		PeerAccess: peer.Access{
+			AllowHosts: cfg.State.AllowPeers,
+			DenyHosts:  cfg.State.DenyPeers,
+			AllowKeys:  cfg.State.AllowPeerKeys,
+			DenyKeys:   cfg.State.DenyPeerKeys,
+		},
+		MaxClockDrift: cfg.State.MaxClockDrift,
+		Faults: netfault.Config{
+			Latency:      cfg.Faults.Latency,
+			Jitter:       cfg.Faults.Jitter,
+			DropRate:     cfg.Faults.DropRate,
+			ReorderRate:  cfg.Faults.ReorderRate,
+			ReorderDelay: cfg.Faults.ReorderDelay,
+			Seed:         cfg.Faults.Seed,
+		},
+		Retention: database.Retention{
+			ReadableBlocks: cfg.State.ReadableBlocks,
+			LocalBlocks:    localBlocks,
+			HistoryBlocks:  cfg.State.HistoryBlocks,
+		},
+	}
+	state, err := state.New(stateCfg)
 	if err != nil {
 		return err
 	}
@@ -192,7 +501,78 @@ func run(log *zap.SugaredLogger) error {
 
 	// The worker package implements the different workflows such as mining, transaction
 	// peer sharing, and peer updates. The worker will register itself with the state.
-	worker.Run(state, ev)
+	// Bootstrap peers can be found from DNS so joining the network doesn't
+	// depend on the origin node.
+	var seeder *peer.Seeder
+	if cfg.State.SeedDNS != "" {
+		seeder = peer.NewSeeder(cfg.State.SeedDNS, cfg.State.SeedPort, nil)
+	}
+
+	worker.Run(state, ev,
+		worker.WithShutdownTimeout(cfg.State.ShutdownTimeout),
+		worker.WithCompaction(cfg.State.CompactInterval),
+		worker.WithSeeding(seeder, cfg.State.SeedInterval),
+		worker.WithQueues(worker.Queues{
+			StartMining:  cfg.Queues.StartMining,
+			CancelMining: cfg.Queues.CancelMining,
+			TxShare:      cfg.Queues.TxShare,
+			Proposals:    cfg.Queues.Proposals,
+			Policy:       cfg.Queues.Policy,
+			BlockTimeout: cfg.Queues.BlockTimeout,
+		}),
+		worker.WithChaos(worker.Chaos{
+			Seed:            cfg.Chaos.Seed,
+			MaxSignalDelay:  cfg.Chaos.MaxSignalDelay,
+			GossipDropRate:  cfg.Chaos.GossipDropRate,
+			RestartInterval: cfg.Chaos.RestartInterval,
+		}),
+	)
+
+	// Put the transactions that were waiting at the time of the backup
+	// back into the mempool now the worker can mine them.
+	for _, tx := range restored.Mempool {
+		if err := state.UpsertNodeTransaction(tx); err != nil {
+			log.Infow("startup", "status", "restore mempool", "tx", tx, "ERROR", err)
+		}
+	}
+
+	// =========================================================================
+	// Extra Chains
+
+	// Independent chains with their own genesis and storage can be hosted
+	// in the process, such as the small networks of a classroom. They are
+	// served under /v1/chains/:id and mined by this node alone.
+	chainIDs := map[uint16]string{genesis.ChainID: "main"}
+	chains := make([]handlers.Chain, 0, len(cfg.State.Chains))
+	for _, spec := range cfg.State.Chains {
+		chainState, id, err := newChain(spec, stateCfg, cfg.State.DBPath, cfg.State.ShutdownTimeout, chainIDs, resolve, diskOptions...)
+		if err != nil {
+			return fmt.Errorf("unable to start chain %q: %w", spec, err)
+		}
+		defer chainState.Shutdown()
+
+		chain := handlers.Chain{
+			ID:    id,
+			State: chainState,
+		}
+		if cfg.Web.CacheEntries > 0 {
+			chain.Cache = handlers.NewCache(chainState, cfg.Web.CacheEntries)
+		}
+		chains = append(chains, chain)
+
+		log.Infow("startup", "status", "chain started", "id", id, "chainID", chainState.Genesis().ChainID)
+	}
+
+	// =========================================================================
+	// Audit Support
+
+	// The audit log records every state-changing API call made against
+	// the node so bad blocks and transactions can be traced to their source.
+	auditLog, err := audit.New(cfg.Audit.Path, cfg.Audit.MaxSize, cfg.Audit.MaxFiles)
+	if err != nil {
+		return fmt.Errorf("unable to open audit log: %w", err)
+	}
+	defer auditLog.Close()
 
 	// =========================================================================
 	// Start Debug Service
@@ -202,8 +582,12 @@ func run(log *zap.SugaredLogger) error {
 	// The Debug function returns a mux to listen and serve on for all the debug
 	// related endpoints. This includes the standard library endpoints.
 
+	// The OpenAPI document of the public API is served on both muxes, the
+	// public mux describes its routes as they are loaded.
+	spec := handlers.NewSpec(build)
+
 	// Construct the mux for the debug calls.
-	debugMux := handlers.DebugMux(build, log)
+	debugMux := handlers.DebugMux(build, log, level, spec)
 
 	// Start the service listening for debug requests.
 	// Not concerned with shutting this down with load shedding.
@@ -230,13 +614,39 @@ func run(log *zap.SugaredLogger) error {
 
 	log.Infow("startup", "status", "initializing V1 public API support")
 
+	// Responses explorers poll for are cached until the chain moves on.
+	var responseCache *cache.Cache
+	if cfg.Web.CacheEntries > 0 {
+		responseCache = handlers.NewCache(state, cfg.Web.CacheEntries)
+	}
+
+	// Only partners holding one of the keys may call the public API when
+	// keys are configured.
+	var apiKeys *apikey.Keys
+	if len(cfg.Web.APIKeys) > 0 {
+		keys, err := apikey.Parse(cfg.Web.APIKeys, cfg.Web.APIKeyRate)
+		if err != nil {
+			return fmt.Errorf("parsing api keys: %w", err)
+		}
+		apiKeys = apikey.New(keys)
+		log.Infow("startup", "status", "public API requires an api key", "keys", len(keys))
+	}
+
 	// Construct the mux for the public API calls.
-	publicMux := handlers.PublicMux(handlers.MuxConfig{
-		Shutdown: shutdown,
-		Log:      log,
-		State:    state,
-		NS:       ns,
-	})
+	publicCfg := handlers.MuxConfig{
+		Shutdown:      shutdown,
+		Log:           log,
+		State:         state,
+		NS:            ns,
+		Audit:         auditLog,
+		Timeout:       cfg.Web.WriteTimeout,
+		GraphQL:       cfg.Web.GraphQL,
+		Spec:          spec,
+		Cache:         responseCache,
+		APIKeys:       apiKeys,
+		StringNumbers: cfg.Web.StringNumbers,
+	}
+	publicMux := handlers.ChainsMux(handlers.PublicMux(publicCfg), chains, publicCfg, handlers.PublicMux)
 
 	// Construct a server to service the requests against the mux.
 	public := http.Server{
@@ -260,12 +670,17 @@ func run(log *zap.SugaredLogger) error {
 	log.Infow("startup", "status", "initializing V1 private API support")
 
 	// Construct the mux for the private API calls.
-	privateMux := handlers.PrivateMux(handlers.MuxConfig{
+	privateCfg := handlers.MuxConfig{
 		Shutdown: shutdown,
 		Log:      log,
 		State:    state,
 		NS:       ns,
-	})
+		Audit:    auditLog,
+		KeyPath:  path,
+		Build:    build,
+		Timeout:  cfg.Web.WriteTimeout,
+	}
+	privateMux := handlers.ChainsMux(handlers.PrivateMux(privateCfg), chains, privateCfg, handlers.PrivateMux)
 
 	// Construct a server to service the requests against the mux.
 	private := http.Server{
@@ -286,11 +701,27 @@ func run(log *zap.SugaredLogger) error {
 	// =========================================================================
 	// Shutdown
 
+	// A failed audit of any chain hosted in the process stops the node.
+	auditFailed := make(chan error, len(chains)+1)
+	go func() {
+		auditFailed <- <-state.AuditFailed()
+	}()
+	for _, chain := range chains {
+		go func(chain handlers.Chain) {
+			auditFailed <- fmt.Errorf("chain %s: %w", chain.ID, <-chain.State.AuditFailed())
+		}(chain)
+	}
+
 	// Blocking main and waiting for shutdown.
 	select {
 	case err := <-serverErrors:
 		return fmt.Errorf("server error: %w", err)
 
+	// A chain that doesn't match the snapshot it was loaded from can't be
+	// served or mined on.
+	case err := <-auditFailed:
+		return fmt.Errorf("snapshot audit failed, restart without lazy loading to replay the chain: %w", err)
+
 	case sig := <-shutdown:
 		log.Infow("shutdown", "status", "shutdown started", "signal", sig)
 		defer log.Infow("shutdown", "status", "shutdown complete", "signal", sig)
@@ -320,3 +751,128 @@ func run(log *zap.SugaredLogger) error {
 
 	return nil
 }
+
+// chainIDPattern is what the id of an extra chain looks like since it's
+// part of the routes the chain is served under.
+var chainIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// newChain constructs and starts an extra chain hosted in the process from
+// its id:genesis-file spec. The chain is configured like the main chain,
+// stores its blocks under dbPath/chains/id and has no peers besides this
+// node. The chain IDs already in use are tracked so the chains stay apart.
+func newChain(spec string, base state.Config, dbPath string, shutdownTimeout time.Duration, chainIDs map[uint16]string, resolve func(name string) (string, bool), diskOptions ...func(d *disk.Disk)) (*state.State, string, error) {
+	id, genesisPath, found := strings.Cut(spec, ":")
+	if !found || !chainIDPattern.MatchString(id) {
+		return nil, "", errors.New("chain should be id:genesis-file with a lower case id")
+	}
+
+	gen, err := genesis.LoadFile(genesisPath)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := gen.ResolveNames(resolve); err != nil {
+		return nil, "", err
+	}
+
+	if other, exists := chainIDs[gen.ChainID]; exists {
+		return nil, "", fmt.Errorf("chain id %d is already used by the %s chain", gen.ChainID, other)
+	}
+	chainIDs[gen.ChainID] = id
+
+	storage, err := disk.New(filepath.Join(dbPath, "chains", id), diskOptions...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	ev := func(v string, args ...any) {
+		base.EvHandler("chain["+id+"]: "+v, args...)
+	}
+
+	// The node is the only peer of the chain so it's always selected to
+	// mine the next block.
+	peerSet := peer.NewPeerSet()
+	peerSet.Add(peer.New(base.Host))
+
+	cfg := base
+	cfg.Chain = id
+	cfg.Genesis = gen
+	cfg.Storage = storage
+	cfg.EvHandler = ev
+	cfg.KnownPeers = peerSet
+	cfg.Primary = ""
+
+	st, err := state.New(cfg)
+	if err != nil {
+		return nil, "", err
+	}
+
+	worker.Run(st, ev, worker.WithShutdownTimeout(shutdownTimeout))
+
+	return st, id, nil
+}
+
+// keyProvider returns where the node key is read from for the source. The
+// keystore passphrase is read from the file when there is one, otherwise
+// it's asked for on the terminal.
+func keyProvider(source string, env string, passphraseFile string, folder string, vault keystore.VaultConfig) (keystore.Provider, error) {
+	switch source {
+	case "file":
+		return keystore.Folder(folder), nil
+
+	case "env":
+		return keystore.Env(env), nil
+
+	case "keystore":
+		passphrase := func(name string) ([]byte, error) {
+			if passphraseFile != "" {
+				data, err := os.ReadFile(passphraseFile)
+				if err != nil {
+					return nil, err
+				}
+				return bytes.TrimRight(data, "\r\n"), nil
+			}
+
+			fd := int(os.Stdin.Fd())
+			if !term.IsTerminal(fd) {
+				return nil, errors.New("no passphrase file and no terminal to ask on")
+			}
+			fmt.Fprintf(os.Stderr, "Passphrase for %s: ", name)
+			defer fmt.Fprintln(os.Stderr)
+			return term.ReadPassword(fd)
+		}
+		return keystore.Encrypted{Folder: folder, Passphrase: passphrase}, nil
+
+	case "vault":
+		v, err := keystore.NewVault(vault)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create vault key provider: %w", err)
+		}
+		return v, nil
+	}
+
+	return nil, fmt.Errorf("unknown key source %q, must be file, env, keystore or vault", source)
+}
+
+// identityKey loads the key a relay node signs its peer messages with,
+// making it when there isn't one yet so the node keeps its identity with
+// its peers across restarts.
+func identityKey(path string) (*ecdsa.PrivateKey, error) {
+	privateKey, err := crypto.LoadECDSA(path)
+	if err == nil || !errors.Is(err, os.ErrNotExist) {
+		return privateKey, err
+	}
+
+	if privateKey, err = crypto.GenerateKey(); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	if err := crypto.SaveECDSA(path, privateKey); err != nil {
+		return nil, err
+	}
+
+	return privateKey, nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ardanlabs/conf/v3"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/qcbit/blockchain/app/services/relayer/relay"
+	"github.com/qcbit/blockchain/foundation/blockchain/bridge"
+	"github.com/qcbit/blockchain/foundation/logger"
+)
+
+// build is the git version of this program. It is set using build flags in the makefile.
+var build = "develop"
+
+func main() {
+
+	// Perform the startup and shutdown sequence. Errors are logged by run
+	// once the logger has been constructed from the configuration.
+	if err := run(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func run() (err error) {
+
+	// =========================================================================
+	// Configuration
+
+	cfg := struct {
+		conf.Version
+		Web struct {
+			DebugHost string `conf:"default:0.0.0.0:7190"`
+		}
+		Source struct {
+			PublicURL  string `conf:"default:http://0.0.0.0:8080/v1"` // Chain the value is locked on.
+			PrivateURL string `conf:"default:http://0.0.0.0:9080/v1"`
+		}
+		Dest struct {
+			PublicURL string `conf:"default:http://0.0.0.0:8080/v1/chains/class1"` // Chain the value is released on.
+		}
+		Bridge struct {
+			KeyPath       string        `conf:"default:zblock/accounts/bridge.ecdsa"` // Key of the bridge account on both chains.
+			LedgerPath    string        `conf:"default:zblock/bridge/ledger.json"`
+			Confirmations uint64        `conf:"default:6"` // Confirmations a lock needs before it's released.
+			PollInterval  time.Duration `conf:"default:5s"`
+			Tip           uint64        // Tip paid for every release.
+		}
+		Log struct {
+			Level string `conf:"default:info"` // debug, info, warn or error
+		}
+	}{
+		Version: conf.Version{
+			Build: build,
+			Desc:  "© 2023 WTFPL",
+		},
+	}
+
+	// Parse will set the defaults and then look for any overriding values
+	// in environment variables and command line flags.
+	const prefix = "RELAYER"
+	help, err := conf.Parse(prefix, &cfg)
+	if err != nil {
+		if errors.Is(err, conf.ErrHelpWanted) {
+			fmt.Println(help)
+			return nil
+		}
+		fmt.Println("parsing config:", err)
+		return err
+	}
+
+	// =========================================================================
+	// Logger Support
+
+	log, _, err := logger.NewWithConfig(logger.Config{
+		Service: prefix,
+		Level:   cfg.Log.Level,
+	})
+	if err != nil {
+		fmt.Println("constructing logger:", err)
+		return err
+	}
+	defer log.Sync()
+
+	defer func() {
+		if err != nil {
+			log.Errorw("startup", "ERROR", err)
+		}
+	}()
+
+	// =========================================================================
+	// App Starting
+
+	log.Infow("starting service", "version", build)
+	defer log.Infow("shutdown complete")
+
+	out, err := conf.String(&cfg)
+	if err != nil {
+		return fmt.Errorf("generating config for output: %w", err)
+	}
+	log.Infow("startup", "config", out)
+
+	// =========================================================================
+	// Bridge Support
+
+	privateKey, err := crypto.LoadECDSA(cfg.Bridge.KeyPath)
+	if err != nil {
+		return fmt.Errorf("unable to load bridge key: %w", err)
+	}
+
+	ledger, err := bridge.OpenLedger(cfg.Bridge.LedgerPath)
+	if err != nil {
+		return err
+	}
+
+	// =========================================================================
+	// Start Debug Service
+
+	// The relay metrics are published through expvar.
+	debugMux := http.NewServeMux()
+	debugMux.Handle("/debug/vars", expvar.Handler())
+
+	go func() {
+		log.Infow("startup", "status", "debug router started", "host", cfg.Web.DebugHost)
+		if err := http.ListenAndServe(cfg.Web.DebugHost, debugMux); err != nil {
+			log.Errorw("shutdown", "status", "debug router closed", "host", cfg.Web.DebugHost, "ERROR", err)
+		}
+	}()
+
+	// =========================================================================
+	// Start Relaying
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	relayer := relay.New(relay.Config{
+		Log:           log,
+		SourcePublic:  cfg.Source.PublicURL,
+		SourcePrivate: cfg.Source.PrivateURL,
+		DestPublic:    cfg.Dest.PublicURL,
+		Key:           privateKey,
+		Ledger:        ledger,
+		Confirmations: cfg.Bridge.Confirmations,
+		PollInterval:  cfg.Bridge.PollInterval,
+		Tip:           cfg.Bridge.Tip,
+	})
+
+	log.Infow("startup", "status", "relaying started", "bridge", relayer.BridgeID(), "next_block", ledger.Next(),
+		"source", cfg.Source.PublicURL, "dest", cfg.Dest.PublicURL)
+	relayer.Run(ctx)
+	log.Infow("shutdown", "status", "relaying stopped")
+
+	return nil
+}
@@ -0,0 +1,399 @@
+// Package relay implements the bridge relayer. It scans the source chain for
+// value locked in the bridge account and, once a lock has enough
+// confirmations, releases the same value from the bridge account on the
+// destination chain. The ledger keeps the locks it has processed so a lock
+// is never released twice, including across restarts.
+package relay
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/bridge"
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+	"github.com/qcbit/blockchain/foundation/blockchain/peer"
+	"github.com/qcbit/blockchain/foundation/blockchain/protocol"
+)
+
+// maxBlocks is the number of source blocks scanned in one poll.
+const maxBlocks = 100
+
+// metrics represents the set of metrics the relayer publishes through expvar.
+var metrics = struct {
+	scanned   *expvar.Int
+	locks     *expvar.Int
+	failed    *expvar.Int
+	submitted *expvar.Int
+	confirmed *expvar.Int
+	nodeErrs  *expvar.Int
+}{
+	scanned:   expvar.NewInt("blocks_scanned"),
+	locks:     expvar.NewInt("locks"),
+	failed:    expvar.NewInt("failed_locks"),
+	submitted: expvar.NewInt("releases_submitted"),
+	confirmed: expvar.NewInt("releases_confirmed"),
+	nodeErrs:  expvar.NewInt("node_errors"),
+}
+
+// Config represents the configuration of the relayer. The node URLs are
+// the base of their version 1 API, such as http://0.0.0.0:8080/v1 or
+// http://0.0.0.0:8080/v1/chains/class1 for a chain hosted next to another.
+type Config struct {
+	Log           *zap.SugaredLogger
+	SourcePublic  string            // Public API of a node of the chain the value is locked on.
+	SourcePrivate string            // Private API of the same node, to read its blocks.
+	DestPublic    string            // Public API of a node of the chain the value is released on.
+	Key           *ecdsa.PrivateKey // Key of the bridge account on both chains.
+	Ledger        *bridge.Ledger
+	Confirmations uint64        // Confirmations a lock needs before it's released.
+	PollInterval  time.Duration // How often the source chain is scanned.
+	Tip           uint64        // Tip paid for every release.
+}
+
+// Relayer releases the value locked on the source chain.
+type Relayer struct {
+	cfg      Config
+	bridgeID database.AccountID
+	client   http.Client
+}
+
+// New constructs a relayer for use.
+func New(cfg Config) *Relayer {
+	if cfg.Confirmations == 0 {
+		cfg.Confirmations = 1
+	}
+
+	return &Relayer{
+		cfg:      cfg,
+		bridgeID: database.PublicKeyToAccountID(cfg.Key.PublicKey),
+		client:   http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// BridgeID returns the bridge account the relayer releases value from.
+func (r *Relayer) BridgeID() database.AccountID {
+	return r.bridgeID
+}
+
+// Run scans and releases until the context is canceled.
+func (r *Relayer) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.relay(ctx); err != nil {
+			metrics.nodeErrs.Add(1)
+			r.cfg.Log.Infow("relay", "ERROR", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// relay scans the confirmed source blocks for new locks and then works
+// the releases that haven't been mined yet.
+func (r *Relayer) relay(ctx context.Context) error {
+	source, err := r.chainInfo(r.cfg.SourcePublic)
+	if err != nil {
+		return fmt.Errorf("source chain: %w", err)
+	}
+	dest, err := r.chainInfo(r.cfg.DestPublic)
+	if err != nil {
+		return fmt.Errorf("destination chain: %w", err)
+	}
+	if source.ChainID == dest.ChainID {
+		return fmt.Errorf("source and destination are the same chain %d", source.ChainID)
+	}
+
+	if err := r.scan(ctx, source, dest); err != nil {
+		return err
+	}
+
+	return r.release(ctx, dest)
+}
+
+// scan records the locks in the source blocks that have enough
+// confirmations.
+func (r *Relayer) scan(ctx context.Context, source chainInfo, dest chainInfo) error {
+	var status peer.PeerStatus
+	if err := r.get(r.cfg.SourcePrivate+"/node/status", &status); err != nil {
+		return fmt.Errorf("source status: %w", err)
+	}
+
+	latest := status.LatestBlockNumber
+	if latest+1 < r.cfg.Confirmations {
+		return nil
+	}
+	to := latest + 1 - r.cfg.Confirmations
+
+	from := r.cfg.Ledger.Next()
+	if from > to {
+		return nil
+	}
+	if to-from >= maxBlocks {
+		to = from + maxBlocks - 1
+	}
+
+	blocks, err := r.blocks(from, to, source.HashAlgorithm)
+	if err != nil {
+		return fmt.Errorf("source blocks: %w", err)
+	}
+
+	for _, block := range blocks {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		for _, ev := range bridge.Events(block, r.bridgeID, dest.ChainID, source.HashAlgorithm) {
+			if r.cfg.Ledger.Processed(ev.Key) {
+				continue
+			}
+
+			// A transaction that failed is still mined, but nothing was
+			// locked by it.
+			locked, err := r.succeeded(ev.TxHash)
+			if err != nil {
+				return fmt.Errorf("lock %s: %w", ev.Key, err)
+			}
+			if !locked {
+				metrics.failed.Add(1)
+				r.cfg.Log.Infow("scan", "lock", ev.Key, "tx", ev.TxHash, "status", "lock failed, not released")
+				continue
+			}
+
+			_, next, err := r.nonce()
+			if err != nil {
+				return err
+			}
+
+			tr, _, err := r.cfg.Ledger.Add(ev, next)
+			if err != nil {
+				return err
+			}
+
+			metrics.locks.Add(1)
+			r.cfg.Log.Infow("scan", "lock", ev.Key, "blk", ev.Block, "to", ev.ToID, "value", ev.Value, "nonce", tr.Nonce)
+		}
+
+		if err := r.cfg.Ledger.Scanned(block.Header.Number); err != nil {
+			return err
+		}
+		metrics.scanned.Add(1)
+	}
+
+	return nil
+}
+
+// release submits the releases that aren't in the destination node's
+// mempool and marks the ones whose nonce has been mined as confirmed.
+func (r *Relayer) release(ctx context.Context, dest chainInfo) error {
+	unconfirmed := r.cfg.Ledger.Unconfirmed()
+	if len(unconfirmed) == 0 {
+		return nil
+	}
+
+	confirmed, _, err := r.nonce()
+	if err != nil {
+		return err
+	}
+
+	for _, tr := range unconfirmed {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if tr.Nonce <= confirmed {
+			if err := r.cfg.Ledger.SetStatus(tr.Key, bridge.StatusConfirmed); err != nil {
+				return err
+			}
+			metrics.confirmed.Add(1)
+			r.cfg.Log.Infow("release", "lock", tr.Key, "nonce", tr.Nonce, "status", bridge.StatusConfirmed)
+			continue
+		}
+
+		signedTx, err := r.sign(dest.ChainID, tr)
+		if err != nil {
+			return fmt.Errorf("lock %s: %w", tr.Key, err)
+		}
+
+		// Signatures are deterministic, so the release has the same hash
+		// every time it's signed.
+		txHash := signedTx.TxHash(dest.HashAlgorithm)
+
+		var status txStatus
+		if err := r.get(r.cfg.DestPublic+"/tx/status/"+txHash, &status); err != nil {
+			return fmt.Errorf("release status: %w", err)
+		}
+		if status.Status == "pending" || status.Status == "mined" {
+			if err := r.cfg.Ledger.SetStatus(tr.Key, bridge.StatusSubmitted); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// The releases after this one wait on its nonce, so they're tried
+		// again with it on the next poll.
+		if err := r.submit(signedTx); err != nil {
+			r.cfg.Log.Infow("release", "lock", tr.Key, "nonce", tr.Nonce, "ERROR", err)
+			return nil
+		}
+		if err := r.cfg.Ledger.SetStatus(tr.Key, bridge.StatusSubmitted); err != nil {
+			return err
+		}
+
+		metrics.submitted.Add(1)
+		r.cfg.Log.Infow("release", "lock", tr.Key, "tx", txHash, "to", tr.ToID, "value", tr.Value, "nonce", tr.Nonce, "status", bridge.StatusSubmitted)
+	}
+
+	return nil
+}
+
+// sign returns the transaction that releases the transfer's value on the
+// destination chain with the nonce the ledger assigned to it.
+func (r *Relayer) sign(chainID uint16, tr bridge.Transfer) (database.SignedTx, error) {
+	data, err := bridge.ReleaseData(tr.Event)
+	if err != nil {
+		return database.SignedTx{}, err
+	}
+
+	tx, err := database.NewTx(chainID, r.bridgeID, tr.ToID, tr.Value, tr.Nonce, r.cfg.Tip, data)
+	if err != nil {
+		return database.SignedTx{}, err
+	}
+
+	return tx.Sign(r.cfg.Key)
+}
+
+// =============================================================================
+
+// chainInfo represents the chain a node runs.
+type chainInfo struct {
+	ChainID       uint16 `json:"chain_id"`
+	HashAlgorithm string `json:"hash_algorithm"`
+}
+
+// txStatus represents what a node knows about a transaction.
+type txStatus struct {
+	Status string `json:"status"`
+	Block  *struct {
+		Receipt string `json:"receipt"`
+	} `json:"block"`
+}
+
+// chainInfo asks the node which chain it runs.
+func (r *Relayer) chainInfo(node string) (chainInfo, error) {
+	var info chainInfo
+	if err := r.get(node+"/chain/id", &info); err != nil {
+		return chainInfo{}, err
+	}
+
+	return info, nil
+}
+
+// succeeded reports whether the source transaction was mined successfully.
+func (r *Relayer) succeeded(txHash string) (bool, error) {
+	var status txStatus
+	if err := r.get(r.cfg.SourcePublic+"/tx/status/"+txHash, &status); err != nil {
+		return false, err
+	}
+
+	return status.Block != nil && status.Block.Receipt == database.ReceiptSuccess, nil
+}
+
+// nonce returns the confirmed and next nonce of the bridge account on the
+// destination chain.
+func (r *Relayer) nonce() (uint64, uint64, error) {
+	var info struct {
+		Confirmed uint64 `json:"confirmed"`
+		Next      uint64 `json:"next"`
+	}
+	if err := r.get(fmt.Sprintf("%s/accounts/%s/nonce", r.cfg.DestPublic, r.bridgeID), &info); err != nil {
+		return 0, 0, fmt.Errorf("bridge nonce: %w", err)
+	}
+
+	return info.Confirmed, info.Next, nil
+}
+
+// blocks returns the source blocks in the range, hashed with the algorithm
+// the source chain reports.
+func (r *Relayer) blocks(from uint64, to uint64, algorithm string) ([]database.Block, error) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/node/block/list/%d/%d", r.cfg.SourcePrivate, from, to), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(protocol.Header, strconv.Itoa(int(protocol.CurrentVersion)))
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNoContent:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return protocol.DecodeBlocks(data, func(uint64) string { return algorithm })
+}
+
+// submit sends the release to the destination node.
+func (r *Relayer) submit(signedTx database.SignedTx) error {
+	data, err := json.Marshal(signedTx)
+	if err != nil {
+		return err
+	}
+
+	resp, err := r.client.Post(r.cfg.DestPublic+"/tx/submit", "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var er struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&er)
+		return fmt.Errorf("status %d: %s", resp.StatusCode, er.Error)
+	}
+
+	return nil
+}
+
+// get decodes the response of the node into the value.
+func (r *Relayer) get(url string, v any) error {
+	resp, err := r.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: status %d", url, resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
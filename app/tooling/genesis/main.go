@@ -0,0 +1,101 @@
+// This program rewrites the balances and locks of a genesis file so they're
+// allocated either by account name or by hex account ID.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+	"github.com/qcbit/blockchain/foundation/blockchain/genesis"
+	"github.com/qcbit/blockchain/foundation/blockchain/nameservice"
+)
+
+// The set of forms the balances can be written in.
+const (
+	formName    = "name"
+	formAccount = "account"
+)
+
+var (
+	accountsFolder string
+	form           string
+)
+
+func init() {
+	flag.StringVar(&accountsFolder, "accounts", "zblock/accounts/", "folder with the account keys used to resolve names")
+	flag.StringVar(&form, "form", formName, "form to write the balances in: name or account")
+}
+
+func main() {
+	flag.Parse()
+
+	if err := run(); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func run() error {
+	path := flag.Arg(0)
+	if path == "" {
+		path = "zblock/genesis.json"
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var gen genesis.Genesis
+	if err := json.Unmarshal(content, &gen); err != nil {
+		return err
+	}
+
+	ns, err := nameservice.New(accountsFolder)
+	if err != nil {
+		return err
+	}
+
+	// Resolve every name first so both forms start from account IDs.
+	resolve := func(name string) (string, bool) {
+		accountID, ok := ns.Resolve(name)
+		return string(accountID), ok
+	}
+	if err := gen.ResolveNames(resolve); err != nil {
+		return err
+	}
+
+	switch form {
+	case formAccount:
+
+	case formName:
+		balances := make(map[string]uint64, len(gen.Balances))
+		for accountID, balance := range gen.Balances {
+			balances[ns.Lookup(database.AccountID(accountID))] = balance
+		}
+		gen.Balances = balances
+
+		if gen.Locks != nil {
+			locks := make(map[string][]genesis.Lock, len(gen.Locks))
+			for accountID, accountLocks := range gen.Locks {
+				locks[ns.Lookup(database.AccountID(accountID))] = accountLocks
+			}
+			gen.Locks = locks
+		}
+
+	default:
+		return fmt.Errorf("unknown form %q, must be %s or %s", form, formName, formAccount)
+	}
+
+	data, err := json.MarshalIndent(gen, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(data))
+
+	return nil
+}
@@ -0,0 +1,225 @@
+// This program generates account keys for test networks into the accounts
+// folder and prints their account IDs. Keys can be derived from a seed and
+// their index so every run creates the same accounts, and the balances of a
+// genesis file allocating to them can be printed along with them.
+//
+// Keys derived from a seed are only as secret as the seed and must never
+// hold real value. Keys can also be written encrypted with a passphrase for
+// nodes reading their key from a keystore, the passphrase coming from the
+// KEYGEN_PASSPHRASE variable or asked for on the terminal.
+package main
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/term"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+	"github.com/qcbit/blockchain/foundation/blockchain/keystore"
+)
+
+var (
+	accountsFolder string
+	count          int
+	prefix         string
+	seed           string
+	start          int
+	balance        uint64
+	force          bool
+	encrypt        bool
+)
+
+func init() {
+	flag.StringVar(&accountsFolder, "accounts", "zblock/accounts/", "folder the keys are written to")
+	flag.IntVar(&count, "n", 1, "number of keys to generate when no names are provided")
+	flag.StringVar(&prefix, "prefix", "account", "name of the keys generated with -n, followed by their index")
+	flag.StringVar(&seed, "seed", "", "derive the keys from the seed and their index instead of randomly")
+	flag.IntVar(&start, "start", 1, "index of the first key")
+	flag.Uint64Var(&balance, "balance", 0, "print the genesis balances giving every account this balance")
+	flag.BoolVar(&force, "force", false, "overwrite keys that already exist")
+	flag.BoolVar(&encrypt, "encrypt", false, "write the keys encrypted with a passphrase as name.key")
+}
+
+func main() {
+	flag.Parse()
+
+	if err := run(); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// account represents a generated key and the file it was written to.
+type account struct {
+	name      string
+	accountID database.AccountID
+}
+
+func run() error {
+	names := flag.Args()
+	if len(names) == 0 {
+		if count <= 0 {
+			return errors.New("the number of keys must be at least 1")
+		}
+		for i := 0; i < count; i++ {
+			names = append(names, fmt.Sprintf("%s%d", prefix, start+i))
+		}
+	}
+
+	// Check every file first so a run doesn't stop halfway through.
+	if !force {
+		for _, name := range names {
+			if _, err := os.Stat(keyPath(name)); err == nil {
+				return fmt.Errorf("key %s already exists, use -force to overwrite it", keyPath(name))
+			}
+		}
+	}
+
+	var passphrase []byte
+	if encrypt {
+		var err error
+		if passphrase, err = readPassphrase(); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(accountsFolder, 0755); err != nil {
+		return err
+	}
+
+	accounts := make([]account, len(names))
+	for i, name := range names {
+		privateKey, err := newKey(start + i)
+		if err != nil {
+			return fmt.Errorf("key %s: %w", name, err)
+		}
+
+		if err := saveKey(name, privateKey, passphrase); err != nil {
+			return fmt.Errorf("key %s: %w", name, err)
+		}
+
+		accounts[i] = account{
+			name:      name,
+			accountID: database.PublicKeyToAccountID(privateKey.PublicKey),
+		}
+	}
+
+	// The balances go to standard output on their own so they can be
+	// written to a file, with the accounts listed on standard error.
+	list := io.Writer(os.Stdout)
+	if balance > 0 {
+		list = os.Stderr
+	}
+	for _, acct := range accounts {
+		fmt.Fprintf(list, "%-20s %s\n", acct.name, acct.accountID)
+	}
+
+	if balance > 0 {
+		return printBalances(accounts)
+	}
+
+	return nil
+}
+
+// newKey returns the key for the index, derived from the seed when there
+// is one. The hash is hashed again in the unlikely case it isn't a valid
+// private key.
+func newKey(index int) (*ecdsa.PrivateKey, error) {
+	if seed == "" {
+		return crypto.GenerateKey()
+	}
+
+	hash := crypto.Keccak256([]byte(fmt.Sprintf("%s/%d", seed, index)))
+	for {
+		privateKey, err := crypto.ToECDSA(hash)
+		if err == nil {
+			return privateKey, nil
+		}
+		hash = crypto.Keccak256(hash)
+	}
+}
+
+// printBalances writes the balances stanza of a genesis file for the
+// accounts, ready to be pasted in.
+func printBalances(accounts []account) error {
+	balances := make(map[database.AccountID]uint64, len(accounts))
+	for _, acct := range accounts {
+		balances[acct.accountID] = balance
+	}
+
+	data, err := json.MarshalIndent(struct {
+		Balances map[database.AccountID]uint64 `json:"balances"`
+	}{
+		Balances: balances,
+	}, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(data))
+
+	return nil
+}
+
+// saveKey writes the key to its file, encrypted when there's a passphrase.
+func saveKey(name string, privateKey *ecdsa.PrivateKey, passphrase []byte) error {
+	if passphrase == nil {
+		return crypto.SaveECDSA(keyPath(name), privateKey)
+	}
+
+	data, err := keystore.Encrypt(privateKey, passphrase)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(keyPath(name), data, 0600)
+}
+
+// readPassphrase returns the passphrase the keys are encrypted with, asking
+// for it twice on the terminal when it isn't in the environment.
+func readPassphrase() ([]byte, error) {
+	if passphrase := os.Getenv("KEYGEN_PASSPHRASE"); passphrase != "" {
+		return []byte(passphrase), nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, errors.New("set KEYGEN_PASSPHRASE or run on a terminal to encrypt the keys")
+	}
+
+	fmt.Fprint(os.Stderr, "Passphrase: ")
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprint(os.Stderr, "Repeat passphrase: ")
+	repeat, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(passphrase) == 0 || string(passphrase) != string(repeat) {
+		return nil, errors.New("passphrases are empty or don't match")
+	}
+
+	return passphrase, nil
+}
+
+// keyPath returns the file the key with the name is written to.
+func keyPath(name string) string {
+	if encrypt {
+		return filepath.Join(accountsFolder, name+".key")
+	}
+	return filepath.Join(accountsFolder, name+".ecdsa")
+}
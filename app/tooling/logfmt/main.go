@@ -6,20 +6,62 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 )
 
-var service string
+var (
+	service string
+	mode    string
+	node    string
+	width   int
+)
 
 func init() {
 	flag.StringVar(&service, "service", "", "filter which service to see")
+	flag.StringVar(&mode, "mode", "", "multi-node view: interleave or columns")
+	flag.StringVar(&node, "node", "", "node label for logs read from stdin, the service is used if empty")
+	flag.IntVar(&width, "width", 60, "width of each node column in columns mode")
+}
+
+// The set of multi-node views.
+const (
+	modeInterleave = "interleave"
+	modeColumns    = "columns"
+)
+
+// entry represents a single log line and the node it came from.
+type entry struct {
+	node string
+	ts   time.Time
+	m    map[string]any
+	raw  string
 }
 
 func main() {
 	flag.Parse()
-	var b strings.Builder
+
+	switch mode {
+	case "":
+		single()
+
+	case modeInterleave, modeColumns:
+		if err := multi(flag.Args()); err != nil {
+			log.Fatalln(err)
+		}
+
+	default:
+		log.Fatalf("unknown mode %q", mode)
+	}
+}
+
+// single reads the log from stdin and writes each line as it's received.
+func single() {
 
 	// Scan standard input for log data per line.
 	scanner := bufio.NewScanner(os.Stdin)
@@ -41,43 +83,246 @@ func main() {
 			continue
 		}
 
-		// I like always having a traceid present in the logs.
-		traceID := "00000000-0000-0000-0000-000000000000"
-		if v, ok := m["traceid"]; ok {
-			traceID = fmt.Sprintf("%v", v)
+		fmt.Println(format(m, fmt.Sprintf("%s: %s: ", m["service"], m["ts"])))
+	}
+
+	if err := scanner.Err(); err != nil {
+		log.Println(err)
+	}
+}
+
+// multi renders logs from several nodes with each line labeled by its node.
+// Each argument is a log file in the form label=path or just path, where the
+// file name is used as the label. Without arguments stdin is read as it's
+// received, otherwise all files are read and merged by timestamp.
+func multi(args []string) error {
+	v := newView()
+
+	if len(args) == 0 {
+		return read(os.Stdin, node, func(e entry) { v.render(e) })
+	}
+
+	var entries []entry
+	for _, arg := range args {
+		label, path, found := strings.Cut(arg, "=")
+		if !found {
+			path = arg
+			label = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
 		}
 
-		// Build out the know portions of the log in the order
-		// I want them in.
-		b.Reset()
-		b.WriteString(fmt.Sprintf("%s: %s: %s: %s: %s: %s: ",
-			m["service"],
-			m["ts"],
-			m["level"],
-			traceID,
-			m["caller"],
-			m["msg"],
-		))
-
-		// Add the rest of the keys ignoring the ones we already
-		// added for the log.
-		for k, v := range m {
-			switch k {
-			case "service", "ts", "level", "traceid", "caller", "msg":
+		err = read(f, label, func(e entry) { entries = append(entries, e) })
+		f.Close()
+		if err != nil {
+			return err
+		}
+
+		v.nodes = append(v.nodes, label)
+	}
+
+	if mode == modeColumns {
+		v.header()
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].ts.Before(entries[j].ts)
+	})
+
+	for _, e := range entries {
+		v.render(e)
+	}
+
+	return nil
+}
+
+// read scans the log data per line and hands each entry to the function.
+func read(r io.Reader, label string, fn func(e entry)) error {
+	var last time.Time
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		s := scanner.Text()
+
+		e := entry{
+			node: label,
+			ts:   last,
+			raw:  s,
+		}
+
+		// Lines that are not JSON keep the timestamp of the previous line
+		// so they stay in place when merged with other nodes.
+		m := make(map[string]any)
+		if err := json.Unmarshal([]byte(s), &m); err == nil {
+			if service != "" && m["service"] != service {
 				continue
 			}
 
-			// It's nice to see the key[value] in this format
-			// especially since map ordering is random.
-			b.WriteString(fmt.Sprintf("%s[%v]: ", k, v))
+			if ts, err := time.Parse("2006-01-02T15:04:05.000Z0700", fmt.Sprint(m["ts"])); err == nil {
+				e.ts = ts
+				last = ts
+			}
+
+			if e.node == "" {
+				e.node = fmt.Sprint(m["service"])
+			}
+			e.m = m
 		}
 
-		// Write the new log format, removing the last :
-		out := b.String()
-		fmt.Println(out[:len(out)-2])
+		fn(e)
 	}
 
-	if err := scanner.Err(); err != nil {
-		log.Println(err)
+	return scanner.Err()
+}
+
+//------------------------------------------------------------------------------
+
+// view maintains what is needed to render the multi-node modes.
+type view struct {
+	start time.Time
+	nodes []string
+}
+
+// newView constructs a view for rendering.
+func newView() *view {
+	return &view{}
+}
+
+// column returns the column for the node, adding the node to the set of
+// columns if it's new. The header is written again when a column is added.
+func (v *view) column(label string) int {
+	for i, n := range v.nodes {
+		if n == label {
+			return i
+		}
+	}
+
+	v.nodes = append(v.nodes, label)
+
+	if mode == modeColumns {
+		v.header()
+	}
+
+	return len(v.nodes) - 1
+}
+
+// header writes the column names for the known nodes.
+func (v *view) header() {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("%-10s", "TIME"))
+	for _, n := range v.nodes {
+		b.WriteString(fmt.Sprintf(" | %-*s", width, clip(n, width)))
+	}
+	fmt.Println(b.String())
+}
+
+// render writes the entry in the configured mode.
+func (v *view) render(e entry) {
+	if v.start.IsZero() && !e.ts.IsZero() {
+		v.start = e.ts
+	}
+
+	rel := "+?"
+	if !e.ts.IsZero() {
+		rel = fmt.Sprintf("+%.3fs", e.ts.Sub(v.start).Seconds())
+	}
+
+	col := v.column(e.node)
+
+	switch mode {
+	case modeInterleave:
+		line := e.raw
+		if e.m != nil {
+			line = format(e.m, "")
+		}
+		fmt.Printf("[%s] %s: %s\n", e.node, rel, line)
+
+	case modeColumns:
+		line := e.raw
+		if e.m != nil {
+			line = formatCompact(e.m)
+		}
+
+		var b strings.Builder
+		b.WriteString(fmt.Sprintf("%-10s", rel))
+		for i := range v.nodes {
+			text := ""
+			if i == col {
+				text = clip(line, width)
+			}
+			b.WriteString(fmt.Sprintf(" | %-*s", width, text))
+		}
+		fmt.Println(strings.TrimRight(b.String(), " "))
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// format builds the readable form of the log line starting with the prefix.
+func format(m map[string]any, prefix string) string {
+	var b strings.Builder
+
+	// I like always having a traceid present in the logs.
+	traceID := "00000000-0000-0000-0000-000000000000"
+	if v, ok := m["traceid"]; ok {
+		traceID = fmt.Sprintf("%v", v)
+	}
+
+	// Build out the know portions of the log in the order
+	// I want them in.
+	b.WriteString(prefix)
+	b.WriteString(fmt.Sprintf("%s: %s: %s: %s: ",
+		m["level"],
+		traceID,
+		m["caller"],
+		m["msg"],
+	))
+
+	// Add the rest of the keys ignoring the ones we already
+	// added for the log.
+	for k, v := range m {
+		switch k {
+		case "service", "ts", "level", "traceid", "caller", "msg":
+			continue
+		}
+
+		// It's nice to see the key[value] in this format
+		// especially since map ordering is random.
+		b.WriteString(fmt.Sprintf("%s[%v]: ", k, v))
+	}
+
+	// Return the new log format, removing the last :
+	out := b.String()
+	return out[:len(out)-2]
+}
+
+// formatCompact builds a short form of the log line with just the message
+// and the extra keys so it fits in a column.
+func formatCompact(m map[string]any) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprint(m["msg"]))
+
+	for k, v := range m {
+		switch k {
+		case "service", "ts", "level", "traceid", "caller", "msg":
+			continue
+		}
+		b.WriteString(fmt.Sprintf(": %s[%v]", k, v))
+	}
+
+	return b.String()
+}
+
+// clip shortens the string to fit the width.
+func clip(s string, width int) string {
+	if len(s) <= width {
+		return s
+	}
+	if width <= 3 {
+		return s[:width]
 	}
+	return s[:width-3] + "..."
 }
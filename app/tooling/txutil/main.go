@@ -0,0 +1,118 @@
+// This program decodes a signed transaction, provided as JSON or in the raw
+// hex encoding, and reports whether it verifies.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+	"github.com/qcbit/blockchain/foundation/blockchain/signature"
+)
+
+var (
+	chainID  uint
+	hashAlgo string
+)
+
+func init() {
+	flag.UintVar(&chainID, "chain-id", 1, "chain id the transaction must be signed for")
+	flag.StringVar(&hashAlgo, "hash", signature.HashKeccak256, "hash algorithm used by the chain")
+}
+
+func main() {
+	flag.Parse()
+
+	if err := run(); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func run() error {
+	if !signature.IsHashAlgorithm(hashAlgo) {
+		return fmt.Errorf("hash algorithm %q does not exist", hashAlgo)
+	}
+
+	// Read the transaction from the file provided or from standard input.
+	input, err := readInput(flag.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to read transaction: %w", err)
+	}
+
+	signedTx, err := decode(input)
+	if err != nil {
+		return fmt.Errorf("failed to decode transaction: %w", err)
+	}
+
+	fmt.Println("from:      ", signedTx.FromID)
+	fmt.Println("to:        ", signedTx.ToID)
+	fmt.Println("value:     ", signedTx.Value)
+	fmt.Println("nonce:     ", signedTx.Nonce)
+	fmt.Println("tip:       ", signedTx.Tip)
+	fmt.Println("tx hash:   ", signedTx.TxHash(hashAlgo))
+	fmt.Println("v:         ", signedTx.V)
+	fmt.Println("r:         ", signedTx.R)
+	fmt.Println("s:         ", signedTx.S)
+
+	// Without all the signature components nothing more can be checked.
+	if signedTx.V == nil || signedTx.R == nil || signedTx.S == nil {
+		fmt.Println("signature:  MISSING")
+		return nil
+	}
+	fmt.Println("signature: ", signedTx.SignatureString())
+
+	// Check the chain id matches the chain the transaction is meant for.
+	chainStatus := "valid"
+	if signedTx.ChainID != uint16(chainID) {
+		chainStatus = fmt.Sprintf("INVALID, expected %d", chainID)
+	}
+	fmt.Printf("chain id:   %d (%s)\n", signedTx.ChainID, chainStatus)
+
+	// Recover the address of the account that signed the transaction.
+	address, err := signature.FromAddress(signedTx.Tx, signedTx.V, signedTx.R, signedTx.S)
+	if err != nil {
+		fmt.Println("recovered:  ERROR:", err)
+	} else {
+		fmt.Println("recovered: ", address)
+	}
+
+	// Run the same validation the node performs on submission.
+	if err := signedTx.Validate(uint16(chainID)); err != nil {
+		fmt.Println("verifies:   false:", err)
+		return nil
+	}
+	fmt.Println("verifies:   true")
+
+	return nil
+}
+
+// readInput reads the contents of the named file. If no file is
+// named, standard input is read instead.
+func readInput(name string) ([]byte, error) {
+	if name == "" || name == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+
+	return os.ReadFile(name)
+}
+
+// decode converts the input into a signed transaction. Input starting
+// with 0x is treated as the raw hex encoding, anything else as JSON.
+func decode(input []byte) (database.SignedTx, error) {
+	input = bytes.TrimSpace(input)
+	if bytes.HasPrefix(input, []byte("0x")) {
+		return database.DecodeRawHex(string(input))
+	}
+
+	var signedTx database.SignedTx
+	if err := json.Unmarshal(input, &signedTx); err != nil {
+		return database.SignedTx{}, err
+	}
+
+	return signedTx, nil
+}
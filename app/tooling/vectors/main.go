@@ -0,0 +1,242 @@
+// This program emits canonical test vectors for the signature scheme and
+// the hashing used by the node so other clients can check they produce
+// the same bytes, hashes and signatures.
+package main
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+	"github.com/qcbit/blockchain/foundation/blockchain/genesis"
+	"github.com/qcbit/blockchain/foundation/blockchain/signature"
+)
+
+// hashAlgorithms are the algorithms hashes are produced with. Signatures
+// are always made over a Keccak-256 hash regardless of the chain's hashing.
+var hashAlgorithms = []string{signature.HashSHA256, signature.HashKeccak256}
+
+// vectors is the document written to standard output.
+type vectors struct {
+	StampPrefix string         `json:"stamp_prefix"`
+	QID         int            `json:"qid"`
+	Keys        []keyVector    `json:"keys"`
+	Txs         []txVector     `json:"txs"`
+	Headers     []headerVector `json:"headers"`
+}
+
+type keyVector struct {
+	PrivateKey string             `json:"private_key"`
+	PublicKey  string             `json:"public_key"`
+	AccountID  database.AccountID `json:"account_id"`
+}
+
+type txVector struct {
+	Name       string             `json:"name"`
+	PrivateKey string             `json:"private_key"`
+	Tx         json.RawMessage    `json:"tx"`
+	Stamped    string             `json:"stamped"`
+	StampHash  string             `json:"stamp_hash"`
+	V          string             `json:"v"`
+	R          string             `json:"r"`
+	S          string             `json:"s"`
+	Signature  string             `json:"signature"`
+	Recovered  database.AccountID `json:"recovered"`
+	Raw        string             `json:"raw"`
+	TxHash     map[string]string  `json:"tx_hash"`
+}
+
+type headerVector struct {
+	Name   string            `json:"name"`
+	Header json.RawMessage   `json:"header"`
+	Hash   map[string]string `json:"hash"`
+}
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func run() error {
+	keys, err := testKeys(2)
+	if err != nil {
+		return err
+	}
+
+	out := vectors{
+		StampPrefix: signature.StampPrefix,
+		QID:         signature.QID,
+	}
+
+	for _, key := range keys {
+		out.Keys = append(out.Keys, keyVector{
+			PrivateKey: hexutil.Encode(crypto.FromECDSA(key)),
+			PublicKey:  hexutil.Encode(crypto.FromECDSAPub(&key.PublicKey)),
+			AccountID:  database.PublicKeyToAccountID(key.PublicKey),
+		})
+	}
+
+	from := database.PublicKeyToAccountID(keys[0].PublicKey)
+	to := database.PublicKeyToAccountID(keys[1].PublicKey)
+
+	txs := []struct {
+		name string
+		tx   database.Tx
+	}{
+		{"transfer", database.Tx{ChainID: 1, FromID: from, ToID: to, Value: 100, Nonce: 1}},
+		{"tip and data", database.Tx{ChainID: 1, FromID: from, ToID: to, Value: 250, Nonce: 2, Tip: 10, Data: []byte("hello")}},
+		{"zero value", database.Tx{ChainID: 1, FromID: from, ToID: to, Nonce: 3}},
+		{"other chain", database.Tx{ChainID: 2, FromID: from, ToID: to, Value: 100, Nonce: 1}},
+		{"lock and expiry", database.Tx{ChainID: 1, FromID: from, ToID: to, Value: 100, Nonce: 4, LockUntil: 50, ValidUntilBlock: 20}},
+	}
+
+	for _, tst := range txs {
+		vector, err := newTxVector(tst.name, keys[0], tst.tx)
+		if err != nil {
+			return fmt.Errorf("%s: %w", tst.name, err)
+		}
+		out.Txs = append(out.Txs, vector)
+	}
+
+	headers := []struct {
+		name   string
+		header database.BlockHeader
+	}{
+		{"first block", database.BlockHeader{
+			Number:        1,
+			PrevBlockHash: signature.ZeroHash,
+			TimeStamp:     1672531200000,
+			BeneficiaryID: from,
+			Difficulty:    2,
+			MiningReward:  700,
+			StateRoot:     signature.ZeroHash,
+			TransRoot:     signature.ZeroHash,
+			Nonce:         42,
+		}},
+		{"tagged block", database.BlockHeader{
+			Number:        2,
+			PrevBlockHash: signature.ZeroHash,
+			TimeStamp:     1672531260000,
+			BeneficiaryID: from,
+			Difficulty:    2,
+			MiningReward:  700,
+			StateRoot:     signature.ZeroHash,
+			TransRoot:     signature.ZeroHash,
+			Nonce:         7,
+			ExtraData:     "pool-1",
+		}},
+		{"versioned block", database.BlockHeader{
+			Version:       genesis.HeaderVersion1,
+			Number:        3,
+			PrevBlockHash: signature.ZeroHash,
+			TimeStamp:     1672531320000,
+			BeneficiaryID: from,
+			Difficulty:    2,
+			MiningReward:  700,
+			StateRoot:     signature.ZeroHash,
+			TransRoot:     signature.ZeroHash,
+			Nonce:         9,
+		}},
+	}
+
+	for _, tst := range headers {
+		header, err := json.Marshal(tst.header)
+		if err != nil {
+			return fmt.Errorf("%s: %w", tst.name, err)
+		}
+
+		out.Headers = append(out.Headers, headerVector{
+			Name:   tst.name,
+			Header: header,
+			Hash:   hashes(tst.header),
+		})
+	}
+
+	data, err := json.MarshalIndent(out, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(data))
+
+	return nil
+}
+
+// testKeys returns private keys derived from fixed seeds so the vectors
+// are the same on every run. Signing is deterministic (RFC 6979) so the
+// signatures are too.
+func testKeys(n int) ([]*ecdsa.PrivateKey, error) {
+	keys := make([]*ecdsa.PrivateKey, n)
+	for i := range keys {
+		seed := crypto.Keccak256([]byte(fmt.Sprintf("test vector key %d", i)))
+
+		key, err := crypto.ToECDSA(seed)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = key
+	}
+
+	return keys, nil
+}
+
+// newTxVector signs the transaction and records every step in between.
+func newTxVector(name string, key *ecdsa.PrivateKey, tx database.Tx) (txVector, error) {
+	txJSON, err := json.Marshal(tx)
+	if err != nil {
+		return txVector{}, err
+	}
+
+	stamped, err := signature.Stamped(tx)
+	if err != nil {
+		return txVector{}, err
+	}
+
+	signedTx, err := tx.Sign(key)
+	if err != nil {
+		return txVector{}, err
+	}
+
+	recovered, err := signature.FromAddress(signedTx.Tx, signedTx.V, signedTx.R, signedTx.S)
+	if err != nil {
+		return txVector{}, err
+	}
+
+	vector := txVector{
+		Name:       name,
+		PrivateKey: hexutil.Encode(crypto.FromECDSA(key)),
+		Tx:         txJSON,
+		Stamped:    hexutil.Encode(stamped),
+		StampHash:  hexutil.Encode(crypto.Keccak256(stamped)),
+		V:          signedTx.V.String(),
+		R:          signedTx.R.String(),
+		S:          signedTx.S.String(),
+		Signature:  signedTx.SignatureString(),
+		Recovered:  database.AccountID(recovered),
+		TxHash:     hashes(signedTx),
+	}
+
+	raw, err := signedTx.EncodeRawHex()
+	if err != nil {
+		return txVector{}, fmt.Errorf("%s: raw encoding: %w", name, err)
+	}
+	vector.Raw = raw
+
+	return vector, nil
+}
+
+// hashes returns the hash of the value with each hash algorithm.
+func hashes(value any) map[string]string {
+	out := make(map[string]string, len(hashAlgorithms))
+	for _, algorithm := range hashAlgorithms {
+		out[algorithm] = signature.HashWith(algorithm, value)
+	}
+
+	return out
+}
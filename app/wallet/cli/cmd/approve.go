@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/spf13/cobra"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+)
+
+var approveCmd = &cobra.Command{
+	Use:   "approve",
+	Short: "Approve a governance action as a validator",
+	Run:   approveRun,
+}
+
+func init() {
+	rootCmd.AddCommand(approveCmd)
+	approveCmd.Flags().StringVarP(&url, "url", "w", "http://localhost:8080", "URL of the node.")
+	approveCmd.Flags().StringVarP(&from, "from", "f", "", "Sender of the governance transaction.")
+	approveCmd.Flags().Uint64VarP(&nonce, "nonce", "n", 0, "Nonce of the governance transaction, the sender's next nonce is asked of the node when not provided.")
+	approveCmd.Flags().StringVar(&governance, "governance", "", "Governance action to approve: freeze or unfreeze.")
+	approveCmd.Flags().StringVar(&target, "target", "", "Account the governance action is taken on.")
+}
+
+func approveRun(cmd *cobra.Command, args []string) {
+	privateKey, err := crypto.LoadECDSA(getPrivateKeyPath())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fromAccount, err := toAccountID(from)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	targetAccount, err := toAccountID(target)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	nodeChainID, _, err := getChainID()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The approval is only good for the sender's transaction with this
+	// nonce so it must match the nonce the sender will use.
+	if nonce == 0 {
+		nonce, err = getNextNonce(fromAccount)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	proposal, err := database.NewProposal(nodeChainID, fromAccount, nonce, governance, targetAccount)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	approval, err := proposal.Approve(privateKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(approval)
+}
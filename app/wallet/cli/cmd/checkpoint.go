@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+)
+
+var (
+	checkpointSigner string
+	checkpointHash   string
+	checkpointOut    string
+)
+
+var checkpointCmd = &cobra.Command{
+	Use:   "checkpoint [number|latest]",
+	Short: "Fetch and verify a signed state checkpoint to start a light node from",
+	Args:  cobra.MaximumNArgs(1),
+	Run:   checkpointRun,
+}
+
+func init() {
+	rootCmd.AddCommand(checkpointCmd)
+	checkpointCmd.Flags().StringVarP(&url, "url", "w", "http://localhost:8080", "URL of the node.")
+	checkpointCmd.Flags().StringVar(&checkpointSigner, "signer", "", "Account of the trusted node that must have signed the checkpoint.")
+	checkpointCmd.Flags().StringVar(&checkpointHash, "hash", "", "Trusted hash of the checkpoint block, checked when provided.")
+	checkpointCmd.Flags().StringVarP(&checkpointOut, "out", "o", "", "File to write the verified checkpoint to, printed when not provided.")
+	checkpointCmd.MarkFlagRequired("signer")
+}
+
+func checkpointRun(cmd *cobra.Command, args []string) {
+	number := "latest"
+	if len(args) == 1 {
+		number = args[0]
+	}
+
+	signer, err := database.ToAccountID(checkpointSigner)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The checkpoint carries the hash algorithm its block and accounts
+	// were hashed with, only the chain needs to be checked.
+	chainID, _, err := getChainID()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var checkpoint database.SignedCheckpoint
+	if err := getJSON(fmt.Sprintf("%s/v1/checkpoint/%s", url, number), &checkpoint); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := verifyCheckpoint(checkpoint, chainID, signer, checkpointHash); err != nil {
+		log.Fatal(err)
+	}
+
+	data, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if checkpointOut == "" {
+		fmt.Println(string(data))
+		return
+	}
+
+	if err := os.WriteFile(checkpointOut, data, 0600); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("checkpoint at block %d verified: hash[%s] accounts[%d] written to %s\n", checkpoint.Header.Number, checkpoint.BlockHash, len(checkpoint.Accounts), checkpointOut)
+}
+
+// verifyCheckpoint checks the checkpoint is consistent, is for the chain the
+// node is running and was signed by the trusted node.
+func verifyCheckpoint(checkpoint database.SignedCheckpoint, chainID uint16, signer database.AccountID, hash string) error {
+	accountID, err := checkpoint.Verify()
+	if err != nil {
+		return fmt.Errorf("checkpoint failed verification: %w", err)
+	}
+
+	if accountID != signer {
+		return fmt.Errorf("checkpoint signed by %s, expected %s", accountID, signer)
+	}
+
+	if checkpoint.ChainID != chainID {
+		return fmt.Errorf("checkpoint for chain %d, node is running chain %d", checkpoint.ChainID, chainID)
+	}
+
+	if hash != "" && checkpoint.BlockHash != hash {
+		return errors.New("checkpoint block hash does not match the trusted hash")
+	}
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+)
+
+var contactsPath string
+
+var contactsCmd = &cobra.Command{
+	Use:   "contacts",
+	Short: "Manage the address book",
+}
+
+var contactsAddCmd = &cobra.Command{
+	Use:   "add <name> <account>",
+	Short: "Add a contact or change its account",
+	Args:  cobra.ExactArgs(2),
+	Run:   contactsAddRun,
+}
+
+var contactsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the contacts",
+	Args:  cobra.NoArgs,
+	Run:   contactsListRun,
+}
+
+var contactsRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove a contact",
+	Args:  cobra.ExactArgs(1),
+	Run:   contactsRemoveRun,
+}
+
+func init() {
+	rootCmd.AddCommand(contactsCmd)
+	contactsCmd.AddCommand(contactsAddCmd, contactsListCmd, contactsRemoveCmd)
+	rootCmd.PersistentFlags().StringVar(&contactsPath, "contacts", "zblock/contacts.json", "Path to the address book.")
+}
+
+func contactsAddRun(cmd *cobra.Command, args []string) {
+	name := args[0]
+	if strings.HasPrefix(name, "0x") {
+		log.Fatalf("contact name %q can't look like an account ID", name)
+	}
+
+	accountID, err := toAccountID(args[1])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	contacts, err := loadContacts()
+	if err != nil {
+		log.Fatal(err)
+	}
+	contacts[name] = accountID
+
+	if err := saveContacts(contacts); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func contactsListRun(cmd *cobra.Command, args []string) {
+	contacts, err := loadContacts()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, name := range sortedNames(contacts) {
+		fmt.Fprintf(tw, "%s\t%s\n", name, contacts[name])
+	}
+	tw.Flush()
+}
+
+func contactsRemoveRun(cmd *cobra.Command, args []string) {
+	contacts, err := loadContacts()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if _, exists := contacts[args[0]]; !exists {
+		log.Fatalf("contact %q not found", args[0])
+	}
+	delete(contacts, args[0])
+
+	if err := saveContacts(contacts); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// resolveAccountID converts an account ID or the name of an account to an
+// account ID. Names are looked up in the address book first and then in
+// the name service of the node.
+func resolveAccountID(nameOrID string) (database.AccountID, error) {
+	if strings.HasPrefix(nameOrID, "0x") {
+		return toAccountID(nameOrID)
+	}
+
+	contacts, err := loadContacts()
+	if err != nil {
+		return "", err
+	}
+	if accountID, exists := contacts[nameOrID]; exists {
+		return toAccountID(string(accountID))
+	}
+
+	var resolved struct {
+		Account database.AccountID `json:"account"`
+	}
+	if err := getJSON(fmt.Sprintf("%s/v2/names/%s", url, nameOrID), &resolved); err != nil {
+		return "", fmt.Errorf("resolving %q: %w", nameOrID, err)
+	}
+
+	return toAccountID(string(resolved.Account))
+}
+
+// loadContacts reads the address book. A missing address book has no
+// contacts.
+func loadContacts() (map[string]database.AccountID, error) {
+	contacts := make(map[string]database.AccountID)
+
+	data, err := os.ReadFile(contactsPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return contacts, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &contacts); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", contactsPath, err)
+	}
+
+	return contacts, nil
+}
+
+// saveContacts writes the address book.
+func saveContacts(contacts map[string]database.AccountID) error {
+	data, err := json.MarshalIndent(contacts, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(contactsPath), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(contactsPath, data, 0600)
+}
@@ -1,10 +1,13 @@
 package cmd
 
 import (
+	"fmt"
 	"log"
 
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/spf13/cobra"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
 )
 
 var generateCmd = &cobra.Command{
@@ -26,4 +29,6 @@ func generateRun(cmd *cobra.Command, args []string) {
 	if err := crypto.SaveECDSA(getPrivateKeyPath(), privateKey); err != nil {
 		log.Fatal(err)
 	}
+
+	fmt.Println(database.PublicKeyToAccountID(privateKey.PublicKey))
 }
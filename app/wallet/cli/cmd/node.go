@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+	"github.com/qcbit/blockchain/foundation/blockchain/peer"
+	"github.com/qcbit/blockchain/foundation/blockchain/state"
+)
+
+var (
+	nodeURL      string
+	resetChainID uint16
+)
+
+var nodeCmd = &cobra.Command{
+	Use:   "node",
+	Short: "Node operations",
+}
+
+var nodeStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the status of a node",
+	Run:   nodeStatusRun,
+}
+
+var nodeResetCmd = &cobra.Command{
+	Use:   "reset",
+	Short: "Wipe a development chain back to genesis on the node and its peers",
+	Run:   nodeResetRun,
+}
+
+func init() {
+	rootCmd.AddCommand(nodeCmd)
+	nodeCmd.AddCommand(nodeStatusCmd)
+	nodeCmd.AddCommand(nodeResetCmd)
+	nodeCmd.PersistentFlags().StringVarP(&nodeURL, "node-url", "u", "http://localhost:9080", "URL of the node's private API.")
+	nodeResetCmd.Flags().Uint16Var(&resetChainID, "chain-id", 0, "ID of the chain being reset, to confirm it.")
+	nodeResetCmd.MarkFlagRequired("chain-id")
+}
+
+func nodeStatusRun(cmd *cobra.Command, args []string) {
+	var status peer.PeerStatus
+	if err := getJSON(fmt.Sprintf("%s/v1/node/status", nodeURL), &status); err != nil {
+		log.Fatal(err)
+	}
+
+	var mempool []database.BlockTx
+	if err := getJSON(fmt.Sprintf("%s/v1/node/tx/list", nodeURL), &mempool); err != nil {
+		log.Fatal(err)
+	}
+
+	// Ask each known peer for its status to see how far behind this node is.
+	highest := status.LatestBlockNumber
+	peerStatus := make(map[peer.Peer]string)
+	for _, p := range status.KnownPeers {
+		var ps peer.PeerStatus
+		if err := getJSON(fmt.Sprintf("http://%s/v1/node/status", p.Host), &ps); err != nil {
+			peerStatus[p] = "unreachable"
+			continue
+		}
+
+		peerStatus[p] = fmt.Sprintf("block %d", ps.LatestBlockNumber)
+		if ps.LatestBlockNumber > highest {
+			highest = ps.LatestBlockNumber
+		}
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer tw.Flush()
+
+	fmt.Fprintf(tw, "Node:\t%s\n", nodeURL)
+	fmt.Fprintf(tw, "Latest Block:\t%d\n", status.LatestBlockNumber)
+	fmt.Fprintf(tw, "Latest Hash:\t%s\n", status.LatestBlockHash)
+	fmt.Fprintf(tw, "Mempool:\t%d transactions\n", len(mempool))
+	fmt.Fprintf(tw, "Peers:\t%d\n", len(status.KnownPeers))
+	for _, p := range status.KnownPeers {
+		fmt.Fprintf(tw, "  %s\t%s\n", p.Host, peerStatus[p])
+	}
+
+	sync := "in sync"
+	if highest > status.LatestBlockNumber {
+		sync = fmt.Sprintf("behind by %d blocks", highest-status.LatestBlockNumber)
+	}
+	progress := 100.0
+	if highest > 0 {
+		progress = float64(status.LatestBlockNumber) / float64(highest) * 100
+	}
+	fmt.Fprintf(tw, "Sync:\t%d/%d (%.1f%%) %s\n", status.LatestBlockNumber, highest, progress, sync)
+}
+
+func nodeResetRun(cmd *cobra.Command, args []string) {
+	req := struct {
+		ChainID uint16 `json:"chain_id"`
+	}{
+		ChainID: resetChainID,
+	}
+
+	var resp struct {
+		state.ChainReset
+		Peers []state.PeerReset `json:"peers"`
+	}
+	if err := postJSON(fmt.Sprintf("%s/v1/node/chain/reset", nodeURL), req, &resp); err != nil {
+		log.Fatal(err)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer tw.Flush()
+
+	fmt.Fprintf(tw, "Node:\t%s\n", nodeURL)
+	fmt.Fprintf(tw, "Blocks Removed:\t%d\n", resp.Blocks)
+	fmt.Fprintf(tw, "Mempool Cleared:\t%d transactions\n", resp.Mempool)
+	fmt.Fprintf(tw, "Accounts Restored:\t%d\n", resp.Accounts)
+	fmt.Fprintf(tw, "Peers:\t%d\n", len(resp.Peers))
+	for _, p := range resp.Peers {
+		result := "reset"
+		if !p.Reset {
+			result = "not reset: " + p.Error
+		}
+		fmt.Fprintf(tw, "  %s\t%s\n", p.Host, result)
+	}
+}
+
+// getJSON performs a GET call against the url and decodes the response.
+func getJSON(url string, v any) error {
+	client := http.Client{
+		Timeout: 5 * time.Second,
+	}
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: status %d", url, resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// postJSON performs a POST call of the value against the url and decodes
+// the response. The error the node responded with is returned when the call
+// doesn't succeed.
+func postJSON(url string, v any, resp any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	client := http.Client{
+		Timeout: 30 * time.Second,
+	}
+
+	r, err := client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		var er struct {
+			Error string `json:"error"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&er); err == nil && er.Error != "" {
+			return fmt.Errorf("%s: status %d: %s", url, r.StatusCode, er.Error)
+		}
+		return fmt.Errorf("%s: status %d", url, r.StatusCode)
+	}
+
+	return json.NewDecoder(r.Body).Decode(resp)
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/skip2/go-qrcode"
+	"github.com/spf13/cobra"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+)
+
+var receiveCmd = &cobra.Command{
+	Use:   "receive",
+	Short: "Show the account ID to receive funds at as a QR code",
+	Run:   receiveRun,
+}
+
+func init() {
+	rootCmd.AddCommand(receiveCmd)
+}
+
+func receiveRun(cmd *cobra.Command, args []string) {
+	privateKey, err := crypto.LoadECDSA(getPrivateKeyPath())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	accountID := database.PublicKeyToAccountID(privateKey.PublicKey)
+
+	if err := printQR(string(accountID)); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(accountID)
+}
+
+// printQR prints the content as a QR code drawn with block characters so
+// it can be scanned off the terminal.
+func printQR(content string) error {
+	code, err := qrcode.New(content, qrcode.Low)
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(code.ToSmallString(false))
+
+	return nil
+}
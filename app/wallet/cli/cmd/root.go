@@ -7,11 +7,14 @@ import (
 	"strings"
 
 	"github.com/spf13/cobra"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
 )
 
 var (
-	accountName string
-	accountPath string
+	accountName     string
+	accountPath     string
+	requireChecksum bool
 )
 
 const (
@@ -22,6 +25,7 @@ func init() {
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 	rootCmd.PersistentFlags().StringVarP(&accountName, "account", "a", "private.ecdsa", "The account to use.")
 	rootCmd.PersistentFlags().StringVarP(&accountPath, "account-path", "p", "zblock/accounts/", "Path to the directory with private keys.")
+	rootCmd.PersistentFlags().BoolVar(&requireChecksum, "require-checksum", false, "Reject account IDs that aren't checksummed.")
 }
 
 var rootCmd = &cobra.Command{
@@ -43,3 +47,14 @@ func getPrivateKeyPath() string {
 
 	return filepath.Join(accountPath, accountName)
 }
+
+// toAccountID converts the account ID given on the command line to its
+// checksummed form. Account IDs in a single case are rejected when a
+// checksum is required.
+func toAccountID(hex string) (database.AccountID, error) {
+	if requireChecksum {
+		return database.ToChecksummedAccountID(hex)
+	}
+
+	return database.ToAccountID(hex)
+}
@@ -7,21 +7,39 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/spf13/cobra"
 
+	"github.com/qcbit/blockchain/foundation/blockchain/bridge"
 	"github.com/qcbit/blockchain/foundation/blockchain/database"
+	"github.com/qcbit/blockchain/foundation/blockchain/signature"
 )
 
 var (
-	url   string
-	nonce uint64
-	from  string
-	to    string
-	value uint64
-	tip   uint64
-	data  []byte
+	url        string
+	chainID    uint16
+	nonce      uint64
+	from       string
+	to         string
+	value      uint64
+	tip        uint64
+	data       []byte
+	lockUntil  uint64
+	validUntil uint64
+	sponsor    string
+	sponsorSig string
+	asSponsor  bool
+	governance string
+	target     string
+	approvals  []string
+	raw        bool
+	dryRun     bool
+	bridgeTo   string
 )
 
 var sendCmd = &cobra.Command{
@@ -33,12 +51,24 @@ var sendCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(sendCmd)
 	sendCmd.Flags().StringVarP(&url, "url", "w", "http://localhost:8080", "URL of the node.")
-	sendCmd.Flags().Uint64VarP(&nonce, "nonce", "n", 0, "Transaction ID.")
+	sendCmd.Flags().Uint16Var(&chainID, "chain-id", 0, "Chain ID, must match the node's chain ID when provided.")
+	sendCmd.Flags().Uint64VarP(&nonce, "nonce", "n", 0, "Transaction ID, the next nonce is asked of the node when not provided.")
 	sendCmd.Flags().StringVarP(&from, "from", "f", "", "Sender.")
-	sendCmd.Flags().StringVarP(&to, "to", "t", "", "Recipient.")
+	sendCmd.Flags().StringVarP(&to, "to", "t", "", "Recipient account ID or the name of a contact.")
 	sendCmd.Flags().Uint64VarP(&value, "value", "v", 0, "Send amount.")
 	sendCmd.Flags().Uint64VarP(&tip, "tip", "c", 0, "Tip amount.")
 	sendCmd.Flags().BytesHexVarP(&data, "data", "d", nil, "Data payload.")
+	sendCmd.Flags().Uint64Var(&lockUntil, "lock-until", 0, "Block height the sent value is locked until in the recipient's account.")
+	sendCmd.Flags().Uint64Var(&validUntil, "valid-until", 0, "Last block the transaction can be mined in, after which the nonce can be used again.")
+	sendCmd.Flags().StringVar(&sponsor, "sponsor", "", "Sponsor paying the gas fee of the transaction.")
+	sendCmd.Flags().StringVar(&sponsorSig, "sponsor-sig", "", "Sponsor signature of the transaction.")
+	sendCmd.Flags().BoolVar(&asSponsor, "as-sponsor", false, "Sign the transaction as its sponsor and print the signature instead of sending it.")
+	sendCmd.Flags().StringVar(&governance, "governance", "", "Governance action to take on the target account: freeze or unfreeze.")
+	sendCmd.Flags().StringVar(&target, "target", "", "Account the governance action is taken on.")
+	sendCmd.Flags().StringArrayVar(&approvals, "approval", nil, "Validator approval of the governance action, repeat for each validator.")
+	sendCmd.Flags().StringVar(&bridgeTo, "bridge", "", "Lock the value in the bridge account for release on another chain: chain-id:account.")
+	sendCmd.Flags().BoolVarP(&raw, "raw", "r", false, "Submit using the raw hex encoding.")
+	sendCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Check the transaction with the node and show what it costs without sending it.")
 }
 
 func sendRun(cmd *cobra.Command, args []string) {
@@ -51,27 +81,72 @@ func sendRun(cmd *cobra.Command, args []string) {
 }
 
 func sendWithDetails(privateKey *ecdsa.PrivateKey) {
-	fromAccount, err := database.ToAccountID(from)
+	fromAccount, err := toAccountID(from)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	toAccount, err := database.ToAccountID(to)
+	toAccount, err := resolveAccountID(to)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	const chainID = 1
-	tx, err := database.NewTx(chainID, fromAccount, toAccount, value, nonce, tip, data)
+	nodeChainID, hashAlgorithm, err := getChainID()
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	// Refuse to sign a transaction for a different chain than the node
+	// is running, which would allow the transaction to be replayed there.
+	if chainID != 0 && chainID != nodeChainID {
+		log.Fatalf("chain ID %d does not match the node's chain ID %d", chainID, nodeChainID)
+	}
+
+	// Nonces start at 1 so ask the node for the next nonce to use when
+	// one isn't provided.
+	if nonce == 0 {
+		nonce, err = getNextNonce(fromAccount)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	tx, err := newTx(nodeChainID, fromAccount, toAccount)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The sponsor signs the transaction first and hands the signature
+	// to the sender.
+	if asSponsor {
+		sig, err := tx.SponsorSign(privateKey)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Println(sig)
+		return
+	}
+
 	signedTx, err := tx.Sign(privateKey)
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	if dryRun {
+		validate(signedTx)
+		return
+	}
+
+	// The hash can be used to ask the node for the status of the transaction
+	// since it's hashed the same way the node does.
+	fmt.Println("tx hash:", signedTx.TxHash(hashAlgorithm))
+
+	if raw {
+		sendRaw(signedTx)
+		return
+	}
+
 	data, err := json.Marshal(signedTx)
 	if err != nil {
 		log.Fatal(err)
@@ -83,3 +158,203 @@ func sendWithDetails(privateKey *ecdsa.PrivateKey) {
 	}
 	defer resp.Body.Close()
 }
+
+// newTx constructs the transaction described by the flags.
+func newTx(chainID uint16, fromAccount database.AccountID, toAccount database.AccountID) (database.Tx, error) {
+	tx, err := database.NewTx(chainID, fromAccount, toAccount, value, nonce, tip, data)
+	if err != nil {
+		return database.Tx{}, err
+	}
+	tx.LockUntil = lockUntil
+	tx.ValidUntilBlock = validUntil
+
+	if bridgeTo != "" {
+		if tx.Data, err = bridgeLockData(bridgeTo); err != nil {
+			return database.Tx{}, err
+		}
+	}
+
+	if governance != "" {
+		targetAccount, err := toAccountID(target)
+		if err != nil {
+			return database.Tx{}, err
+		}
+
+		tx.Governance = &database.Governance{
+			Action:    governance,
+			AccountID: targetAccount,
+			Approvals: approvals,
+		}
+	}
+
+	if sponsor != "" {
+		sponsorAccount, err := toAccountID(sponsor)
+		if err != nil {
+			return database.Tx{}, err
+		}
+
+		tx.Sponsor = &database.Sponsor{
+			AccountID: sponsorAccount,
+			Signature: sponsorSig,
+		}
+	}
+
+	return tx, nil
+}
+
+// bridgeLockData returns the data that locks the transfer in the bridge
+// account for release on the chain:account destination.
+func bridgeLockData(dest string) ([]byte, error) {
+	chainStr, account, found := strings.Cut(dest, ":")
+	if !found {
+		return nil, fmt.Errorf("bridge destination %q should be chain-id:account", dest)
+	}
+
+	destChainID, err := strconv.ParseUint(chainStr, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("bridge destination chain ID: %w", err)
+	}
+
+	toAccount, err := resolveAccountID(account)
+	if err != nil {
+		return nil, err
+	}
+
+	return bridge.LockData(uint16(destChainID), toAccount)
+}
+
+func sendRaw(signedTx database.SignedTx) {
+	rawHex, err := signedTx.EncodeRawHex()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(rawHex)
+
+	data, err := json.Marshal(struct {
+		Raw string `json:"raw"`
+	}{
+		Raw: rawHex,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	resp, err := http.Post(fmt.Sprintf("%s/v1/tx/sendRaw", url), "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer resp.Body.Close()
+}
+
+// validate asks the node to run the checks the transaction goes through
+// and prints the outcome of each and what it would take from the accounts
+// involved.
+func validate(signedTx database.SignedTx) {
+	data, err := json.Marshal(signedTx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	resp, err := http.Post(fmt.Sprintf("%s/v1/tx/validate", url), "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		log.Fatalf("unable to validate transaction: status %d", resp.StatusCode)
+	}
+
+	var verdict struct {
+		Valid  bool `json:"valid"`
+		Checks []struct {
+			Name   string `json:"name"`
+			Passed bool   `json:"passed"`
+			Error  string `json:"error"`
+		} `json:"checks"`
+		Hash   string `json:"hash"`
+		GasFee uint64 `json:"gas_fee"`
+		Debits []struct {
+			Account database.AccountID `json:"account"`
+			Amount  uint64             `json:"amount"`
+			Reason  string             `json:"reason"`
+		} `json:"debits"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&verdict); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("tx hash:", verdict.Hash)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "checks:")
+	for _, check := range verdict.Checks {
+		result := "ok"
+		if !check.Passed {
+			result = "FAILED: " + check.Error
+		}
+		fmt.Fprintf(tw, "  %s\t%s\n", check.Name, result)
+	}
+
+	fmt.Fprintln(tw, "debits:")
+	var total uint64
+	for _, debit := range verdict.Debits {
+		fmt.Fprintf(tw, "  %s\t%d\t%s\n", debit.Account, debit.Amount, debit.Reason)
+		total += debit.Amount
+	}
+	fmt.Fprintf(tw, "  total\t%d\t\n", total)
+	tw.Flush()
+
+	if !verdict.Valid {
+		log.Fatal("transaction would be rejected")
+	}
+}
+
+func getChainID() (uint16, string, error) {
+	resp, err := http.Get(fmt.Sprintf("%s/v1/chain/id", url))
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, "", fmt.Errorf("unable to retrieve chain ID: status %d", resp.StatusCode)
+	}
+
+	var info struct {
+		ChainID       uint16 `json:"chain_id"`
+		HashAlgorithm string `json:"hash_algorithm"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return 0, "", err
+	}
+
+	// Nodes that don't report the algorithm use the original one.
+	if info.HashAlgorithm == "" {
+		info.HashAlgorithm = signature.HashSHA256
+	}
+
+	return info.ChainID, info.HashAlgorithm, nil
+}
+
+func getNextNonce(account database.AccountID) (uint64, error) {
+	resp, err := http.Get(fmt.Sprintf("%s/v1/accounts/%s/nonce", url, account))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unable to retrieve nonce: status %d", resp.StatusCode)
+	}
+
+	var info struct {
+		Next uint64 `json:"next"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return 0, err
+	}
+
+	return info.Next, nil
+}
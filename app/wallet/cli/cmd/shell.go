@@ -0,0 +1,402 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+)
+
+var shellCmd = &cobra.Command{
+	Use:   "shell",
+	Short: "Start an interactive wallet session",
+	Run:   shellRun,
+}
+
+func init() {
+	rootCmd.AddCommand(shellCmd)
+	shellCmd.Flags().StringVarP(&url, "url", "w", "http://localhost:8080", "URL of the node.")
+}
+
+// shellCommands are the commands of the shell with their usage.
+var shellCommands = map[string]string{
+	"account":  "account [name]           show or switch the account used to send",
+	"accounts": "accounts                 list the accounts in the account path",
+	"contacts": "contacts                 list the contacts in the address book",
+	"send":     "send <to> <value> [tip]  send value to an account ID or name",
+	"nonce":    "nonce [reset]            show the next nonce or ask the node again",
+	"history":  "history                  list the commands entered",
+	"help":     "help                     list the commands",
+	"exit":     "exit                     end the session",
+}
+
+func shellRun(cmd *cobra.Command, args []string) {
+	sh, err := newShell()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Line editing, history and completion need the terminal in raw mode.
+	// Input that isn't a terminal is read a line at a time.
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer term.Restore(fd, state)
+
+		t := term.NewTerminal(struct {
+			io.Reader
+			io.Writer
+		}{os.Stdin, os.Stdout}, "")
+		t.AutoCompleteCallback = sh.complete
+
+		sh.out = t
+		sh.readLine = t.ReadLine
+		sh.setPrompt = t.SetPrompt
+	} else {
+		scanner := bufio.NewScanner(os.Stdin)
+
+		sh.out = os.Stdout
+		sh.readLine = func() (string, error) {
+			if !scanner.Scan() {
+				if err := scanner.Err(); err != nil {
+					return "", err
+				}
+				return "", io.EOF
+			}
+			return scanner.Text(), nil
+		}
+		sh.setPrompt = func(string) {}
+	}
+
+	sh.run()
+}
+
+// =============================================================================
+
+// shell is an interactive wallet session. The chain information is asked of
+// the node once and the connection to the node is kept open between
+// commands. The next nonce of each account is tracked so transactions can be
+// sent one after the other without asking the node.
+type shell struct {
+	client    http.Client
+	chainID   uint16
+	hashAlg   string // Algorithm the node hashes new transactions with.
+	key       *ecdsa.PrivateKey
+	account   database.AccountID
+	nonces    map[database.AccountID]uint64
+	history   []string
+	out       io.Writer
+	readLine  func() (string, error)
+	setPrompt func(prompt string)
+}
+
+func newShell() (*shell, error) {
+	sh := shell{
+		client: http.Client{
+			Timeout: 5 * time.Second,
+		},
+		nonces: make(map[database.AccountID]uint64),
+	}
+
+	nodeChainID, hashAlgorithm, err := getChainID()
+	if err != nil {
+		return nil, err
+	}
+	sh.chainID = nodeChainID
+
+	// Hash transactions the same way the node does.
+	sh.hashAlg = hashAlgorithm
+
+	if err := sh.useAccount(accountName); err != nil {
+		return nil, err
+	}
+
+	return &sh, nil
+}
+
+// run reads and performs commands until the session ends.
+func (sh *shell) run() {
+	fmt.Fprintf(sh.out, "connected to %s, chain %d, type help for the commands\n", url, sh.chainID)
+
+	for {
+		sh.setPrompt(fmt.Sprintf("%s> ", strings.TrimSuffix(accountName, keyExtension)))
+
+		line, err := sh.readLine()
+		if err != nil {
+			return
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		sh.history = append(sh.history, line)
+
+		if fields[0] == "exit" {
+			return
+		}
+
+		if err := sh.perform(fields[0], fields[1:]); err != nil {
+			fmt.Fprintln(sh.out, "ERROR:", err)
+		}
+	}
+}
+
+// perform runs the command with its arguments.
+func (sh *shell) perform(command string, args []string) error {
+	switch command {
+	case "help":
+		names := make([]string, 0, len(shellCommands))
+		for name := range shellCommands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			fmt.Fprintln(sh.out, shellCommands[name])
+		}
+
+	case "account":
+		if len(args) == 1 {
+			if err := sh.useAccount(args[0]); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintln(sh.out, sh.account)
+
+	case "accounts":
+		for _, name := range accountNames() {
+			fmt.Fprintln(sh.out, name)
+		}
+
+	case "contacts":
+		contacts, err := loadContacts()
+		if err != nil {
+			return err
+		}
+		for _, name := range sortedNames(contacts) {
+			fmt.Fprintf(sh.out, "%s  %s\n", name, contacts[name])
+		}
+
+	case "nonce":
+		if len(args) == 1 && args[0] == "reset" {
+			delete(sh.nonces, sh.account)
+		}
+
+		next, err := sh.nextNonce()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(sh.out, next)
+
+	case "send":
+		return sh.send(args)
+
+	case "history":
+		for i, line := range sh.history {
+			fmt.Fprintf(sh.out, "%4d  %s\n", i+1, line)
+		}
+
+	default:
+		return fmt.Errorf("unknown command %q, type help for the commands", command)
+	}
+
+	return nil
+}
+
+// useAccount switches to the account with the private key of the specified
+// name in the account path.
+func (sh *shell) useAccount(name string) error {
+	accountName = name
+
+	key, err := crypto.LoadECDSA(getPrivateKeyPath())
+	if err != nil {
+		return err
+	}
+
+	sh.key = key
+	sh.account = database.PublicKeyToAccountID(key.PublicKey)
+
+	return nil
+}
+
+// nextNonce returns the nonce to use for the account's next transaction.
+// The node is only asked the first time.
+func (sh *shell) nextNonce() (uint64, error) {
+	if next, exists := sh.nonces[sh.account]; exists {
+		return next, nil
+	}
+
+	next, err := getNextNonce(sh.account)
+	if err != nil {
+		return 0, err
+	}
+	sh.nonces[sh.account] = next
+
+	return next, nil
+}
+
+// send signs and submits a transaction from the current account.
+func (sh *shell) send(args []string) error {
+	if len(args) < 2 || len(args) > 3 {
+		return errors.New("usage: " + shellCommands["send"])
+	}
+
+	toAccount, err := resolveAccountID(args[0])
+	if err != nil {
+		return err
+	}
+
+	value, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid value: %w", err)
+	}
+
+	var tip uint64
+	if len(args) == 3 {
+		if tip, err = strconv.ParseUint(args[2], 10, 64); err != nil {
+			return fmt.Errorf("invalid tip: %w", err)
+		}
+	}
+
+	next, err := sh.nextNonce()
+	if err != nil {
+		return err
+	}
+
+	tx, err := database.NewTx(sh.chainID, sh.account, toAccount, value, next, tip, nil)
+	if err != nil {
+		return err
+	}
+
+	signedTx, err := tx.Sign(sh.key)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(signedTx)
+	if err != nil {
+		return err
+	}
+
+	resp, err := sh.client.Post(fmt.Sprintf("%s/v1/tx/submit", url), "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// The node may know a different nonce than the one tracked, so it's
+	// asked again for the next transaction.
+	if resp.StatusCode != http.StatusOK {
+		delete(sh.nonces, sh.account)
+		return fmt.Errorf("unable to submit transaction: status %d", resp.StatusCode)
+	}
+	sh.nonces[sh.account] = next + 1
+
+	fmt.Fprintln(sh.out, "tx hash:", signedTx.TxHash(sh.hashAlg))
+
+	return nil
+}
+
+// complete fills in the command or the account name being typed when the
+// tab key is pressed.
+func (sh *shell) complete(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' {
+		return "", 0, false
+	}
+
+	head := line[:pos]
+	start := strings.LastIndex(head, " ") + 1
+	word := head[start:]
+
+	var candidates []string
+	switch fields := strings.Fields(head[:start]); {
+	case len(fields) == 0:
+		for name := range shellCommands {
+			candidates = append(candidates, name)
+		}
+
+	case fields[0] == "account" && len(fields) == 1:
+		candidates = accountNames()
+
+	case fields[0] == "send" && len(fields) == 1:
+		contacts, _ := loadContacts()
+		candidates = append(sortedNames(contacts), accountNames()...)
+	}
+
+	var matches []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, word) {
+			matches = append(matches, candidate)
+		}
+	}
+	if len(matches) == 0 {
+		return "", 0, false
+	}
+
+	completed := commonPrefix(matches)
+	if len(matches) == 1 {
+		completed += " "
+	}
+
+	return head[:start] + completed + line[pos:], start + len(completed), true
+}
+
+// accountNames returns the names of the private keys in the account path.
+func accountNames() []string {
+	entries, err := os.ReadDir(accountPath)
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if name, found := strings.CutSuffix(entry.Name(), keyExtension); found && !entry.IsDir() {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// sortedNames returns the names of the contacts in order.
+func sortedNames(contacts map[string]database.AccountID) []string {
+	names := make([]string, 0, len(contacts))
+	for name := range contacts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// commonPrefix returns the longest prefix the words share.
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, word := range words[1:] {
+		for !strings.HasPrefix(word, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+
+	return prefix
+}
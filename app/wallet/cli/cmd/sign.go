@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/spf13/cobra"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+)
+
+var qr bool
+
+var signCmd = &cobra.Command{
+	Use:   "sign",
+	Short: "Sign a transaction without a node and print it for sending elsewhere",
+	Run:   signRun,
+}
+
+func init() {
+	rootCmd.AddCommand(signCmd)
+	signCmd.Flags().Uint16Var(&chainID, "chain-id", 0, "Chain ID the transaction is for.")
+	signCmd.Flags().Uint64VarP(&nonce, "nonce", "n", 0, "Transaction ID.")
+	signCmd.Flags().StringVarP(&to, "to", "t", "", "Recipient account ID or the name of a contact.")
+	signCmd.Flags().Uint64VarP(&value, "value", "v", 0, "Send amount.")
+	signCmd.Flags().Uint64VarP(&tip, "tip", "c", 0, "Tip amount.")
+	signCmd.Flags().BytesHexVarP(&data, "data", "d", nil, "Data payload.")
+	signCmd.Flags().Uint64Var(&lockUntil, "lock-until", 0, "Block height the sent value is locked until in the recipient's account.")
+	signCmd.Flags().Uint64Var(&validUntil, "valid-until", 0, "Last block the transaction can be mined in, after which the nonce can be used again.")
+	signCmd.Flags().StringVar(&sponsor, "sponsor", "", "Sponsor paying the gas fee of the transaction.")
+	signCmd.Flags().StringVar(&sponsorSig, "sponsor-sig", "", "Sponsor signature of the transaction.")
+	signCmd.Flags().StringVar(&bridgeTo, "bridge", "", "Lock the value in the bridge account for release on another chain: chain-id:account.")
+	signCmd.Flags().BoolVar(&qr, "qr", false, "Print the signed transaction as a QR code.")
+}
+
+// signRun signs the transaction on a machine that may be offline, so the
+// chain ID and nonce must be provided instead of being asked of a node. The
+// raw encoding printed is submitted to a node's sendRaw endpoint.
+func signRun(cmd *cobra.Command, args []string) {
+	if chainID == 0 || nonce == 0 {
+		log.Fatal(errors.New("chain ID and nonce are required to sign without a node"))
+	}
+
+	privateKey, err := crypto.LoadECDSA(getPrivateKeyPath())
+	if err != nil {
+		log.Fatal(err)
+	}
+	fromAccount := database.PublicKeyToAccountID(privateKey.PublicKey)
+
+	toAccount, err := resolveAccountID(to)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	tx, err := newTx(chainID, fromAccount, toAccount)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	signedTx, err := tx.Sign(privateKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	rawHex, err := signedTx.EncodeRawHex()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if qr {
+		if err := printQR(rawHex); err != nil {
+			log.Fatal(err)
+		}
+	}
+	fmt.Println(rawHex)
+}
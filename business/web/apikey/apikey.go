@@ -0,0 +1,145 @@
+// Package apikey maintains the API keys partners use to call a node's public
+// API when it's only open to them, with a rate limit and usage counters for
+// each key.
+package apikey
+
+import (
+	"crypto/sha256"
+	"errors"
+	"expvar"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Set of errors returned when a request is checked.
+var (
+	ErrMissingKey  = errors.New("api key required")
+	ErrUnknownKey  = errors.New("api key not recognized")
+	ErrRateLimited = errors.New("api key rate limit exceeded")
+)
+
+// Window is the period rate limits are counted over.
+const Window = time.Minute
+
+// stats holds the usage of each key published through expvar. Everything
+// inside of expvar is registered as a singleton so the counters are
+// published once under the "api_keys" name, keyed by the name of the key.
+var stats = expvar.NewMap("api_keys")
+
+// Key represents an API key handed to a partner. Rate is the most requests
+// accepted with the key per window, zero is unlimited.
+type Key struct {
+	Name  string
+	Token string
+	Rate  int
+}
+
+// Parse reads keys specified as name:token or name:token:rate. Keys
+// without a rate are limited to defaultRate requests per window.
+func Parse(specs []string, defaultRate int) ([]Key, error) {
+	keys := make([]Key, 0, len(specs))
+	names := make(map[string]struct{}, len(specs))
+
+	for _, spec := range specs {
+		parts := strings.Split(spec, ":")
+		if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("api key %q should be name:token or name:token:rate", spec)
+		}
+
+		key := Key{
+			Name:  parts[0],
+			Token: parts[1],
+			Rate:  defaultRate,
+		}
+
+		if len(parts) == 3 {
+			rate, err := strconv.Atoi(parts[2])
+			if err != nil || rate < 0 {
+				return nil, fmt.Errorf("api key %q has an invalid rate", key.Name)
+			}
+			key.Rate = rate
+		}
+
+		if _, exists := names[key.Name]; exists {
+			return nil, fmt.Errorf("api key %q is specified twice", key.Name)
+		}
+		names[key.Name] = struct{}{}
+
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// usage tracks the requests made with a key in the current window.
+type usage struct {
+	name  string
+	rate  int
+	start time.Time
+	count int
+	vars  *expvar.Map
+}
+
+// Keys holds the API keys accepted by the public API. Tokens are held by
+// their hash so a lookup doesn't compare the secret a byte at a time.
+type Keys struct {
+	mu   sync.Mutex
+	keys map[[sha256.Size]byte]*usage
+}
+
+// New constructs the set of accepted API keys.
+func New(keys []Key) *Keys {
+	k := Keys{
+		keys: make(map[[sha256.Size]byte]*usage, len(keys)),
+	}
+
+	for _, key := range keys {
+		vars := new(expvar.Map).Init()
+		vars.Add("requests", 0)
+		vars.Add("limited", 0)
+		stats.Set(key.Name, vars)
+
+		k.keys[sha256.Sum256([]byte(key.Token))] = &usage{
+			name: key.Name,
+			rate: key.Rate,
+			vars: vars,
+		}
+	}
+
+	return &k
+}
+
+// Allow checks the token of a request made at the specified time and
+// returns the name of its key. When the key is over its rate limit, the
+// time until the next window starts is returned with the error.
+func (k *Keys) Allow(token string, now time.Time) (string, time.Duration, error) {
+	if token == "" {
+		return "", 0, ErrMissingKey
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	u, exists := k.keys[sha256.Sum256([]byte(token))]
+	if !exists {
+		return "", 0, ErrUnknownKey
+	}
+
+	if now.Sub(u.start) >= Window {
+		u.start = now
+		u.count = 0
+	}
+
+	if u.rate > 0 && u.count >= u.rate {
+		u.vars.Add("limited", 1)
+		return u.name, u.start.Add(Window).Sub(now), ErrRateLimited
+	}
+
+	u.count++
+	u.vars.Add("requests", 1)
+
+	return u.name, 0, nil
+}
@@ -0,0 +1,82 @@
+package apikey_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/qcbit/blockchain/business/web/apikey"
+)
+
+func Test_Parse(t *testing.T) {
+	keys, err := apikey.Parse([]string{"acme:secret", "globex:token:5"}, 60)
+	if err != nil {
+		t.Fatalf("parsing keys: %v", err)
+	}
+
+	exp := []apikey.Key{
+		{Name: "acme", Token: "secret", Rate: 60},
+		{Name: "globex", Token: "token", Rate: 5},
+	}
+	if len(keys) != len(exp) || keys[0] != exp[0] || keys[1] != exp[1] {
+		t.Fatalf("got keys %+v, exp %+v", keys, exp)
+	}
+
+	for _, specs := range [][]string{
+		{"acme"},
+		{"acme:"},
+		{"acme:secret:fast"},
+		{"acme:secret:-1"},
+		{"acme:secret", "acme:other"},
+	} {
+		if _, err := apikey.Parse(specs, 60); err == nil {
+			t.Fatalf("parsing %v should fail", specs)
+		}
+	}
+}
+
+func Test_Allow(t *testing.T) {
+	keys := apikey.New([]apikey.Key{
+		{Name: "acme", Token: "secret", Rate: 2},
+		{Name: "globex", Token: "token"},
+	})
+
+	now := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	if _, _, err := keys.Allow("", now); !errors.Is(err, apikey.ErrMissingKey) {
+		t.Fatalf("request without a key should be rejected, got %v", err)
+	}
+	if _, _, err := keys.Allow("guess", now); !errors.Is(err, apikey.ErrUnknownKey) {
+		t.Fatalf("request with an unknown key should be rejected, got %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		name, _, err := keys.Allow("secret", now.Add(time.Duration(i)*time.Second))
+		if err != nil {
+			t.Fatalf("request %d should be allowed: %v", i, err)
+		}
+		if name != "acme" {
+			t.Fatalf("got key %q, exp acme", name)
+		}
+	}
+
+	_, retry, err := keys.Allow("secret", now.Add(20*time.Second))
+	if !errors.Is(err, apikey.ErrRateLimited) {
+		t.Fatalf("request over the rate should be rejected, got %v", err)
+	}
+	if retry != 40*time.Second {
+		t.Fatalf("got retry after %v, exp 40s", retry)
+	}
+
+	// The limit starts over with the next window.
+	if _, _, err := keys.Allow("secret", now.Add(apikey.Window)); err != nil {
+		t.Fatalf("request in the next window should be allowed: %v", err)
+	}
+
+	// A key without a rate is never limited.
+	for i := 0; i < 100; i++ {
+		if _, _, err := keys.Allow("token", now); err != nil {
+			t.Fatalf("request %d with an unlimited key should be allowed: %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// Package audit maintains the audit log of state-changing API calls.
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/qcbit/blockchain/foundation/rotate"
+)
+
+// Record represents a single state-changing API call.
+type Record struct {
+	Time        time.Time `json:"time"`
+	TraceID     string    `json:"traceid"`
+	Mux         string    `json:"mux"`
+	Who         string    `json:"who"`
+	Method      string    `json:"method"`
+	Path        string    `json:"path"`
+	PayloadHash string    `json:"payload_hash"`
+	StatusCode  int       `json:"status_code"`
+	Outcome     string    `json:"outcome"`
+}
+
+// The set of outcomes for a record.
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Filter represents the set of values to filter records by when querying.
+// Empty fields are not used for filtering.
+type Filter struct {
+	Who     string
+	Path    string
+	Outcome string
+	Limit   int
+}
+
+// Log writes audit records as JSON lines to a file that is rotated once
+// it reaches the max size. Only maxFiles rotated files are kept.
+type Log struct {
+	mu   sync.Mutex
+	file *rotate.File
+}
+
+// New constructs an audit log writing to the specified file.
+func New(path string, maxSize int64, maxFiles int) (*Log, error) {
+	file, err := rotate.New(path, maxSize, maxFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Log{file: file}, nil
+}
+
+// Close closes the audit file.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.file.Close()
+}
+
+// Write adds the record to the audit file, rotating the file when needed.
+func (l *Log) Write(record Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, err = l.file.Write(data)
+	return err
+}
+
+// Query returns the most recent records matching the filter, newest first.
+func (l *Log) Query(filter Filter) ([]Record, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Read from the oldest rotated file to the current file.
+	var records []Record
+	for _, path := range l.file.Paths() {
+		err := readFile(path, func(record Record) {
+			if filter.Who != "" && record.Who != filter.Who {
+				return
+			}
+			if filter.Path != "" && record.Path != filter.Path {
+				return
+			}
+			if filter.Outcome != "" && record.Outcome != filter.Outcome {
+				return
+			}
+			records = append(records, record)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if filter.Limit > 0 && len(records) > filter.Limit {
+		records = records[len(records)-filter.Limit:]
+	}
+
+	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+		records[i], records[j] = records[j], records[i]
+	}
+
+	return records, nil
+}
+
+// readFile reads each record in the file. A missing file has no records.
+func readFile(path string, fn func(record Record)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		fn(record)
+	}
+
+	return scanner.Err()
+}
+
+// =============================================================================
+
+// The record being built for a request is carried in the context so
+// handlers can identify who is making the call once they know.
+
+// ctxKey represents the type of value for the context key.
+type ctxKey int
+
+// key is how the record is stored/retrieved.
+const key ctxKey = 1
+
+// Set sets the record into the context.
+func Set(ctx context.Context, record *Record) context.Context {
+	return context.WithValue(ctx, key, record)
+}
+
+// SetWho records who is making the call, such as the peer that
+// signed a gossip message.
+func SetWho(ctx context.Context, who string) {
+	if v, ok := ctx.Value(key).(*Record); ok {
+		v.Who = who
+	}
+}
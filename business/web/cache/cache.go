@@ -0,0 +1,135 @@
+// Package cache maintains the responses of hot read endpoints so explorers
+// polling the node don't rebuild the same response for every request.
+package cache
+
+import (
+	"expvar"
+	"sync"
+)
+
+// stats holds the cache counters published through expvar. Everything
+// inside of expvar is registered as a singleton so the counters are
+// published once under the "response_cache" name.
+var stats = struct {
+	vars   *expvar.Map
+	hits   *expvar.Int
+	misses *expvar.Int
+}{
+	vars:   expvar.NewMap("response_cache"),
+	hits:   new(expvar.Int),
+	misses: new(expvar.Int),
+}
+
+func init() {
+	stats.vars.Set("hits", stats.hits)
+	stats.vars.Set("misses", stats.misses)
+}
+
+// Entry represents a response held in the cache. A response about a single
+// account is kept until the account changes, any other response is kept
+// while the version it was built for is current.
+type Entry struct {
+	Version     string
+	Account     string
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+// Cache holds responses by request. The version function returns what the
+// responses are built from, such as the latest block hash, so a response
+// is never served once the chain has moved on.
+type Cache struct {
+	mu         sync.RWMutex
+	version    func() string
+	maxEntries int
+	entries    map[string]Entry
+}
+
+// New constructs a cache holding at most maxEntries responses.
+func New(version func() string, maxEntries int) *Cache {
+	c := Cache{
+		version:    version,
+		maxEntries: maxEntries,
+		entries:    make(map[string]Entry),
+	}
+
+	return &c
+}
+
+// Version returns the current version responses are built for.
+func (c *Cache) Version() string {
+	return c.version()
+}
+
+// Get returns the response held for the key if it's still current.
+func (c *Cache) Get(key string) (Entry, bool) {
+	c.mu.RLock()
+	entry, exists := c.entries[key]
+	c.mu.RUnlock()
+
+	if !exists || (entry.Account == "" && entry.Version != c.version()) {
+		stats.misses.Add(1)
+		return Entry{}, false
+	}
+
+	stats.hits.Add(1)
+	return entry, true
+}
+
+// Put holds the response for the key. The entry's version must be taken
+// before the response was built so a response built while the chain moved
+// on isn't served as current.
+func (c *Cache) Put(key string, entry Entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// The version is checked under the lock so an invalidation for the
+	// next version can't run between the check and the write.
+	if entry.Version != c.version() {
+		return
+	}
+
+	// Start over rather than track the age of entries, the cache refills
+	// on the next requests.
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= c.maxEntries {
+		c.entries = make(map[string]Entry)
+	}
+
+	c.entries[key] = entry
+}
+
+// Invalidate removes the responses about the specified accounts and the
+// responses built for a version that's no longer current.
+func (c *Cache) Invalidate(accounts []string) {
+	changed := make(map[string]struct{}, len(accounts))
+	for _, account := range accounts {
+		changed[account] = struct{}{}
+	}
+
+	version := c.version()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, entry := range c.entries {
+		if entry.Account == "" {
+			if entry.Version != version {
+				delete(c.entries, key)
+			}
+			continue
+		}
+
+		if _, exists := changed[entry.Account]; exists {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// Len returns the number of responses held.
+func (c *Cache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return len(c.entries)
+}
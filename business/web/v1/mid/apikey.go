@@ -0,0 +1,49 @@
+package mid
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/qcbit/blockchain/business/web/apikey"
+	v1Web "github.com/qcbit/blockchain/business/web/v1"
+	"github.com/qcbit/blockchain/foundation/web"
+)
+
+// APIKey requires requests to carry one of the keys in the X-API-Key header
+// and holds each key to its rate limit. CORS preflight requests are let
+// through since browsers never send the header with them. A nil set of
+// keys leaves the API open.
+func APIKey(keys *apikey.Keys) web.Middleware {
+
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if keys == nil || r.Method == http.MethodOptions {
+				return handler(ctx, w, r)
+			}
+
+			_, retry, err := keys.Allow(r.Header.Get("X-API-Key"), time.Now())
+			switch {
+			case errors.Is(err, apikey.ErrRateLimited):
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retry.Seconds()))))
+				return v1Web.NewRequestError(err, http.StatusTooManyRequests)
+
+			case err != nil:
+				return v1Web.NewRequestError(err, http.StatusUnauthorized)
+			}
+
+			// Call the next handler.
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
@@ -0,0 +1,64 @@
+package mid_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/qcbit/blockchain/business/web/apikey"
+	"github.com/qcbit/blockchain/business/web/v1/mid"
+	"github.com/qcbit/blockchain/foundation/web"
+)
+
+func Test_APIKey(t *testing.T) {
+	keys := apikey.New([]apikey.Key{{Name: "acme", Token: "secret", Rate: 1}})
+
+	app := web.NewApp(
+		make(chan os.Signal, 1),
+		mid.Logger(zap.NewNop().Sugar()),
+		mid.Errors(zap.NewNop().Sugar()),
+		mid.APIKey(keys),
+		mid.Panics(),
+	)
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return web.Respond(ctx, w, nil, http.StatusNoContent)
+	}
+	app.Handle(http.MethodGet, "v1", "/genesis/list", handler)
+	app.Handle(http.MethodOptions, "", "/*", handler)
+
+	send := func(method string, key string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/v1/genesis/list", nil)
+		if key != "" {
+			r.Header.Set("X-API-Key", key)
+		}
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := send(http.MethodGet, ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("request without a key: got status %d, exp %d", w.Code, http.StatusUnauthorized)
+	}
+	if w := send(http.MethodGet, "guess"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("request with an unknown key: got status %d, exp %d", w.Code, http.StatusUnauthorized)
+	}
+	if w := send(http.MethodOptions, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("preflight request: got status %d, exp %d", w.Code, http.StatusNoContent)
+	}
+	if w := send(http.MethodGet, "secret"); w.Code != http.StatusNoContent {
+		t.Fatalf("request with a key: got status %d, exp %d", w.Code, http.StatusNoContent)
+	}
+
+	w := send(http.MethodGet, "secret")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("request over the rate: got status %d, exp %d", w.Code, http.StatusTooManyRequests)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Fatal("request over the rate should say when to retry")
+	}
+}
@@ -0,0 +1,107 @@
+package mid
+
+import (
+	"context"
+	"crypto/sha256"
+	"hash"
+	"io"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"go.uber.org/zap"
+
+	"github.com/qcbit/blockchain/business/web/audit"
+	"github.com/qcbit/blockchain/foundation/web"
+)
+
+// Audit records who made every state-changing call against the mux, a hash
+// of the payload that was read and the outcome of the call into the audit log.
+func Audit(log *zap.SugaredLogger, auditLog *audit.Log, mux string) web.Middleware {
+
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				return handler(ctx, w, r)
+			}
+
+			// If the context is missing this value, request the service
+			// to be shutdown gracefully.
+			v, err := web.GetValues(ctx)
+			if err != nil {
+				return web.NewShutdownError("web value missing from context")
+			}
+
+			// Until a handler knows better, the caller is identified by
+			// the API key provided or the remote address.
+			record := audit.Record{
+				Time:    v.Now,
+				TraceID: v.TraceID,
+				Mux:     mux,
+				Who:     who(r),
+				Method:  r.Method,
+				Path:    r.URL.Path,
+			}
+			ctx = audit.Set(ctx, &record)
+
+			// Hash the payload as it's read by the handler so the body
+			// is never read more than the handler chooses to.
+			hb := hashBody{
+				ReadCloser: r.Body,
+				hash:       sha256.New(),
+			}
+			r.Body = &hb
+
+			// Call the next handler.
+			err = handler(ctx, w, r)
+
+			record.PayloadHash = hexutil.Encode(hb.hash.Sum(nil))
+			record.StatusCode = v.StatusCode
+			record.Outcome = audit.OutcomeSuccess
+			if err != nil || v.StatusCode >= http.StatusBadRequest {
+				record.Outcome = audit.OutcomeFailure
+			}
+
+			if err := auditLog.Write(record); err != nil {
+				log.Errorw("audit", "traceid", v.TraceID, "ERROR", err)
+			}
+
+			// Return the error so it can be handled further up the chain.
+			return err
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// who identifies the caller by the API key provided or the remote address.
+// Only the start of the API key is recorded so the audit log can't leak it.
+func who(r *http.Request) string {
+	key := r.Header.Get("X-API-Key")
+	if key == "" {
+		return r.RemoteAddr
+	}
+
+	if len(key) > 6 {
+		key = key[:6]
+	}
+	return "apikey:" + key + "..."
+}
+
+// hashBody hashes the request body as it's being read.
+type hashBody struct {
+	io.ReadCloser
+	hash hash.Hash
+}
+
+// Read implements the io.Reader interface.
+func (hb *hashBody) Read(p []byte) (int, error) {
+	n, err := hb.ReadCloser.Read(p)
+	hb.hash.Write(p[:n])
+	return n, err
+}
@@ -0,0 +1,91 @@
+package mid
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/peer"
+	"github.com/qcbit/blockchain/foundation/blockchain/protocol"
+	"github.com/qcbit/blockchain/foundation/web"
+)
+
+// Bandwidth accounts the traffic of each request to the peer that made it
+// and holds back the response while the peer is over its bandwidth cap.
+func Bandwidth(bw *peer.Bandwidth) web.Middleware {
+
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			host := protocol.RequestedBy(r)
+			bw.RequestReceived(host)
+
+			body := meteredBody{ReadCloser: r.Body, bw: bw, host: host}
+			r.Body = &body
+
+			mw := meteredWriter{ResponseWriter: w, bw: bw, host: host}
+
+			// Call the next handler.
+			return handler(ctx, &mw, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// meteredBody counts the bytes of the request body as they're read.
+type meteredBody struct {
+	io.ReadCloser
+	bw   *peer.Bandwidth
+	host string
+}
+
+// Read implements the io.Reader interface.
+func (mb *meteredBody) Read(p []byte) (int, error) {
+	n, err := mb.ReadCloser.Read(p)
+	mb.bw.Received(mb.host, int64(n))
+	return n, err
+}
+
+// meteredWriter counts the bytes of the response and writes them no faster
+// than the peer's bandwidth cap allows.
+type meteredWriter struct {
+	http.ResponseWriter
+	bw   *peer.Bandwidth
+	host string
+}
+
+// Write sends the data in chunks of at most a second's worth of the cap.
+func (mw *meteredWriter) Write(data []byte) (int, error) {
+	chunk := len(data)
+	if limit := mw.bw.Limit(); limit > 0 && int64(chunk) > limit {
+		chunk = int(limit)
+	}
+
+	var written int
+	for written < len(data) {
+		end := written + chunk
+		if end > len(data) {
+			end = len(data)
+		}
+
+		mw.bw.Send(mw.host, int64(end-written))
+
+		n, err := mw.ResponseWriter.Write(data[written:end])
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+
+	return written, nil
+}
+
+// Unwrap lets an http.ResponseController reach the underlying writer.
+func (mw *meteredWriter) Unwrap() http.ResponseWriter {
+	return mw.ResponseWriter
+}
@@ -0,0 +1,31 @@
+package mid
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/qcbit/blockchain/foundation/web"
+)
+
+// MaxBodySize limits the number of bytes that can be read from the request
+// body. Reading past the limit fails the decoding of the request.
+func MaxBodySize(n int64) web.Middleware {
+
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+
+			// Replace the body with a reader that stops at the limit.
+			r.Body = http.MaxBytesReader(w, r.Body, n)
+
+			// Call the next handler.
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
@@ -0,0 +1,94 @@
+package mid
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/qcbit/blockchain/business/web/cache"
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+	"github.com/qcbit/blockchain/foundation/web"
+)
+
+// Cache serves GET requests from the response cache and holds successful
+// responses for the next request. When accountParam names a route
+// parameter, the response is about that account alone and is held until
+// the account changes instead of until the next block. The X-Cache header
+// reports whether the response came from the cache. Compression must be
+// applied before the cache so responses are held uncompressed.
+func Cache(c *cache.Cache, accountParam string) web.Middleware {
+
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if c == nil || r.Method != http.MethodGet {
+				return handler(ctx, w, r)
+			}
+
+			key := r.URL.RequestURI()
+
+			if entry, ok := c.Get(key); ok {
+				w.Header().Set("Content-Type", entry.ContentType)
+				w.Header().Set("X-Cache", "HIT")
+
+				web.SetStatusCode(ctx, entry.Status)
+				w.WriteHeader(entry.Status)
+				_, err := w.Write(entry.Body)
+				return err
+			}
+
+			entry := cache.Entry{
+				Version: c.Version(),
+			}
+			if accountParam != "" {
+				accountID, err := database.ToAccountID(web.Param(r, accountParam))
+				if err != nil {
+					return handler(ctx, w, r)
+				}
+				entry.Account = string(accountID)
+			}
+
+			w.Header().Set("X-Cache", "MISS")
+
+			rw := recordWriter{ResponseWriter: w, status: http.StatusOK}
+
+			// Call the next handler.
+			if err := handler(ctx, &rw, r); err != nil {
+				return err
+			}
+
+			if rw.status == http.StatusOK {
+				entry.Status = rw.status
+				entry.ContentType = w.Header().Get("Content-Type")
+				entry.Body = rw.body
+				c.Put(key, entry)
+			}
+
+			return nil
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// recordWriter keeps a copy of the response written through it.
+type recordWriter struct {
+	http.ResponseWriter
+	status int
+	body   []byte
+}
+
+// WriteHeader records the status code before writing it.
+func (rw *recordWriter) WriteHeader(status int) {
+	rw.status = status
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the data before writing it.
+func (rw *recordWriter) Write(data []byte) (int, error) {
+	rw.body = append(rw.body, data...)
+	return rw.ResponseWriter.Write(data)
+}
@@ -0,0 +1,81 @@
+package mid_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/qcbit/blockchain/business/web/cache"
+	"github.com/qcbit/blockchain/business/web/v1/mid"
+	"github.com/qcbit/blockchain/foundation/web"
+)
+
+func Test_Cache(t *testing.T) {
+	app := web.NewApp(
+		make(chan os.Signal, 1),
+		mid.Logger(zap.NewNop().Sugar()),
+		mid.Errors(zap.NewNop().Sugar()),
+		mid.Panics(),
+	)
+
+	version := "0xblock1"
+	c := cache.New(func() string { return version }, 10)
+
+	calls := make(map[string]int)
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		calls[r.URL.Path]++
+		return web.Respond(ctx, w, version, http.StatusOK)
+	}
+
+	app.Handle(http.MethodGet, "v1", "/genesis/list", handler, mid.Compress(), mid.Cache(c, ""))
+	app.Handle(http.MethodGet, "v1", "/accounts/:account/summary", handler, mid.Cache(c, "account"))
+
+	get := func(path string, gzip bool) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		if gzip {
+			r.Header.Set("Accept-Encoding", "gzip")
+		}
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, r)
+		return w
+	}
+
+	const account = "0xF01813E4B85e178A83e29B8E7bF26BD830a25f32"
+	summary := "/v1/accounts/" + account + "/summary"
+
+	if w := get("/v1/genesis/list", true); w.Header().Get("X-Cache") != "MISS" {
+		t.Fatalf("first request should miss, got %q", w.Header().Get("X-Cache"))
+	}
+	w := get("/v1/genesis/list", false)
+	if w.Header().Get("X-Cache") != "HIT" || w.Header().Get("Content-Encoding") != "" || !strings.Contains(w.Body.String(), "0xblock1") {
+		t.Fatalf("second request should hit uncompressed, got %q %q %s", w.Header().Get("X-Cache"), w.Header().Get("Content-Encoding"), w.Body)
+	}
+	get(summary, false)
+
+	// A new block moves the version on.
+	version = "0xblock2"
+	c.Invalidate([]string{"0xother"})
+
+	if w := get("/v1/genesis/list", false); w.Header().Get("X-Cache") != "MISS" || !strings.Contains(w.Body.String(), "0xblock2") {
+		t.Fatalf("request after a new block should miss, got %q %s", w.Header().Get("X-Cache"), w.Body)
+	}
+	if w := get(summary, false); w.Header().Get("X-Cache") != "HIT" {
+		t.Fatalf("summary of an account the block didn't change should hit, got %q", w.Header().Get("X-Cache"))
+	}
+
+	// A block changing the account drops its summary.
+	version = "0xblock3"
+	c.Invalidate([]string{account})
+
+	if w := get(summary, false); w.Header().Get("X-Cache") != "MISS" {
+		t.Fatalf("summary of a changed account should miss, got %q", w.Header().Get("X-Cache"))
+	}
+	if calls["/v1/genesis/list"] != 2 || calls[summary] != 2 {
+		t.Fatalf("handler calls: got %v", calls)
+	}
+}
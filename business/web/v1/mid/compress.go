@@ -0,0 +1,125 @@
+package mid
+
+import (
+	"compress/gzip"
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/qcbit/blockchain/foundation/web"
+)
+
+// compressMinSize is the smallest response worth compressing. Smaller
+// responses can grow once the gzip header and footer are added.
+const compressMinSize = 1024
+
+// Compress gzips the response when the client says it accepts gzip in the
+// Accept-Encoding header. Blocks and transactions are sent between nodes as
+// JSON which compresses well, cutting the bandwidth a sync uses.
+func Compress() web.Middleware {
+
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			if !acceptsGzip(r) {
+				return handler(ctx, w, r)
+			}
+
+			cw := compressWriter{ResponseWriter: w}
+			defer cw.close()
+
+			// Call the next handler.
+			return handler(ctx, &cw, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// acceptsGzip reports whether the client accepts a gzipped response.
+func acceptsGzip(r *http.Request) bool {
+	for _, enc := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(enc), ";")
+		if strings.TrimSpace(name) == "gzip" && strings.ReplaceAll(params, " ", "") != "q=0" {
+			return true
+		}
+	}
+	return false
+}
+
+// compressWriter holds back the status code until the first write so the
+// response is only compressed when it's big enough to benefit.
+type compressWriter struct {
+	http.ResponseWriter
+	status  int
+	decided bool
+	gw      *gzip.Writer
+}
+
+// WriteHeader records the status code to write with the first write.
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.decided {
+		return
+	}
+	cw.status = status
+}
+
+// Write decides on the encoding of the response on the first write.
+func (cw *compressWriter) Write(data []byte) (int, error) {
+	if !cw.decided {
+		cw.decide(len(data))
+	}
+
+	if cw.gw != nil {
+		return cw.gw.Write(data)
+	}
+	return cw.ResponseWriter.Write(data)
+}
+
+// Unwrap lets an http.ResponseController reach the underlying writer.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// decide writes the headers with the status code, compressing the response
+// unless it's small or the handler already encoded it.
+func (cw *compressWriter) decide(size int) {
+	cw.decided = true
+
+	hdr := cw.Header()
+	compress := size >= compressMinSize &&
+		hdr.Get("Content-Encoding") == "" &&
+		hdr.Get("Content-Type") != "application/gzip"
+
+	if compress {
+		hdr.Set("Content-Encoding", "gzip")
+		hdr.Del("Content-Length")
+		cw.gw = gzip.NewWriter(cw.ResponseWriter)
+	}
+
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+}
+
+// close writes a response without a body and flushes the compressed data.
+func (cw *compressWriter) close() {
+	if !cw.decided {
+		cw.decided = true
+		if cw.status != 0 {
+			cw.ResponseWriter.WriteHeader(cw.status)
+		}
+		return
+	}
+
+	if cw.gw != nil {
+		cw.gw.Close()
+	}
+}
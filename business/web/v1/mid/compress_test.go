@@ -0,0 +1,92 @@
+package mid_test
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/qcbit/blockchain/business/web/v1/mid"
+	"github.com/qcbit/blockchain/foundation/web"
+)
+
+func Test_CompressNegotiated(t *testing.T) {
+	app := web.NewApp(
+		make(chan os.Signal, 1),
+		mid.Logger(zap.NewNop().Sugar()),
+		mid.Errors(zap.NewNop().Sugar()),
+		mid.Panics(),
+	)
+
+	blocks := make([]string, 100)
+	for i := range blocks {
+		blocks[i] = strings.Repeat("0", 64)
+	}
+
+	list := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return web.Respond(ctx, w, blocks, http.StatusOK)
+	}
+	status := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return web.Respond(ctx, w, "ok", http.StatusOK)
+	}
+	empty := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return web.Respond(ctx, w, nil, http.StatusNoContent)
+	}
+
+	app.Handle(http.MethodGet, "v1", "/node/block/list", list, mid.Compress())
+	app.Handle(http.MethodGet, "v1", "/node/status", status, mid.Compress())
+	app.Handle(http.MethodGet, "v1", "/node/block/wait", empty, mid.Compress())
+
+	r := httptest.NewRequest(http.MethodGet, "/v1/node/block/list", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK || w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected a gzipped %d response, got %d %q", http.StatusOK, w.Code, w.Header().Get("Content-Encoding"))
+	}
+
+	gr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("opening gzipped response: %v", err)
+	}
+	var got []string
+	if err := json.NewDecoder(gr).Decode(&got); err != nil {
+		t.Fatalf("decoding gzipped response: %v", err)
+	}
+	if len(got) != len(blocks) {
+		t.Fatalf("expected %d blocks, got %d", len(blocks), len(got))
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/v1/node/block/list", nil)
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, r)
+
+	if w.Header().Get("Content-Encoding") != "" {
+		t.Fatal("expected no compression without Accept-Encoding")
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/v1/node/status", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, r)
+
+	if w.Header().Get("Content-Encoding") != "" || !strings.Contains(w.Body.String(), "ok") {
+		t.Fatalf("expected a small response to be sent as is, got %q", w.Body.String())
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/v1/node/block/wait", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Fatalf("expected an empty %d response, got %d", http.StatusNoContent, w.Code)
+	}
+}
@@ -0,0 +1,49 @@
+package mid
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/qcbit/blockchain/foundation/web"
+)
+
+// Deadline attaches a deadline to the context of each request so the work
+// a handler asks the blockchain to do, like reading a big range of blocks
+// from disk, stops once the server can no longer write the response.
+func Deadline(timeout time.Duration) web.Middleware {
+
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if timeout <= 0 {
+				return handler(ctx, w, r)
+			}
+
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			// Call the next handler.
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// ExtendDeadline replaces the request deadline for handlers that are meant
+// to outlast it, like a long poll. The context keeps the request values and
+// is still canceled when the client goes away.
+func ExtendDeadline(ctx context.Context, r *http.Request, timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	stop := context.AfterFunc(r.Context(), cancel)
+
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
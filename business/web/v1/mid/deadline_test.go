@@ -0,0 +1,72 @@
+package mid_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/qcbit/blockchain/business/web/v1/mid"
+	"github.com/qcbit/blockchain/foundation/web"
+)
+
+func Test_DeadlineStopsSlowHandlers(t *testing.T) {
+	shutdown := make(chan os.Signal, 1)
+
+	app := web.NewApp(
+		shutdown,
+		mid.Logger(zap.NewNop().Sugar()),
+		mid.Errors(zap.NewNop().Sugar()),
+		mid.Deadline(50*time.Millisecond),
+		mid.Panics(),
+	)
+
+	// A handler scanning more blocks than it has time for.
+	slow := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	// A long poll outlasting the request deadline.
+	wait := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		ctx, cancel := mid.ExtendDeadline(ctx, r, time.Second)
+		defer cancel()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		return web.Respond(ctx, w, nil, http.StatusNoContent)
+	}
+
+	app.Handle(http.MethodGet, "v1", "/node/block/list/:from/:to", slow)
+	app.Handle(http.MethodGet, "v1", "/block/wait", wait)
+
+	r := httptest.NewRequest(http.MethodGet, "/v1/node/block/list/1/latest", nil)
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, r)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status %d past the deadline, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+
+	select {
+	case sig := <-shutdown:
+		t.Fatalf("expected the node to keep running, got shutdown signal %v", sig)
+	default:
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/v1/block/wait", nil)
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d for an extended deadline, got %d", http.StatusNoContent, w.Code)
+	}
+}
@@ -0,0 +1,34 @@
+package mid
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/qcbit/blockchain/foundation/web"
+)
+
+// Deprecated marks the responses of a route as deprecated and links to the
+// same route in the successor version of the api.
+func Deprecated(version string, successor string) web.Middleware {
+
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			path := strings.Replace(r.URL.Path, "/"+version+"/", "/"+successor+"/", 1)
+
+			w.Header().Set("Deprecation", "true")
+			w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", path))
+
+			// Call the next handler.
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
@@ -33,12 +33,26 @@ func Errors(log *zap.SugaredLogger) web.Middleware {
 			if err := handler(ctx, w, r); err != nil {
 
 				// Log the error.
-				log.Errorw("ERROR", "traceid", v.TraceID, "ERROR", err)
+				log.Errorw("ERROR", "traceid", v.TraceID, "method", r.Method, "path", r.URL.Path,
+					"remoteaddr", r.RemoteAddr, "ERROR", err)
 
 				// Build out the error response.
 				var er v1Web.ErrorResponse
 				var status int
+				var maxBytesErr *http.MaxBytesError
 				switch {
+				case errors.As(err, &maxBytesErr):
+					er = v1Web.ErrorResponse{
+						Error: "request body too large",
+					}
+					status = http.StatusRequestEntityTooLarge
+
+				case errors.Is(err, context.DeadlineExceeded):
+					er = v1Web.ErrorResponse{
+						Error: "request deadline exceeded",
+					}
+					status = http.StatusServiceUnavailable
+
 				case validate.IsFieldErrors(err):
 					fieldErrors := validate.GetFieldErrors(err)
 					er = v1Web.ErrorResponse{
@@ -50,7 +64,8 @@ func Errors(log *zap.SugaredLogger) web.Middleware {
 				case v1Web.IsRequestError(err):
 					reqErr := v1Web.GetRequestError(err)
 					er = v1Web.ErrorResponse{
-						Error: reqErr.Error(),
+						Error:  reqErr.Error(),
+						Fields: reqErr.Fields,
 					}
 					status = reqErr.Status
 
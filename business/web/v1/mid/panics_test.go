@@ -0,0 +1,62 @@
+package mid_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/qcbit/blockchain/business/web/v1/mid"
+	"github.com/qcbit/blockchain/foundation/web"
+)
+
+func Test_PanicReturns500AndKeepsServing(t *testing.T) {
+	shutdown := make(chan os.Signal, 1)
+
+	// Use the same middleware chain the node muxes are constructed with.
+	app := web.NewApp(
+		shutdown,
+		mid.Logger(zap.NewNop().Sugar()),
+		mid.Errors(zap.NewNop().Sugar()),
+		mid.Metrics(),
+		mid.Cors("*"),
+		mid.Panics(),
+	)
+
+	panics := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		var block *struct{ Number uint64 }
+		_ = block.Number // A malformed block dereferencing nil.
+		return nil
+	}
+	healthy := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return web.Respond(ctx, w, nil, http.StatusNoContent)
+	}
+
+	app.Handle(http.MethodPost, "v1", "/node/block/propose", panics)
+	app.Handle(http.MethodGet, "v1", "/node/status", healthy)
+
+	r := httptest.NewRequest(http.MethodPost, "/v1/node/block/propose", nil)
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, r)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status %d for a panic, got %d", http.StatusInternalServerError, w.Code)
+	}
+
+	select {
+	case sig := <-shutdown:
+		t.Fatalf("expected the node to keep running, got shutdown signal %v", sig)
+	default:
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/v1/node/status", nil)
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d after a panic, got %d", http.StatusNoContent, w.Code)
+	}
+}
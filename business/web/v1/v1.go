@@ -14,12 +14,19 @@ type ErrorResponse struct {
 type RequestError struct {
 	Err    error
 	Status int
+	Fields map[string]string
 }
 
 // NewRequestError wraps a provided error with an HTTP status code. This
 // function should be used when handlers encounter expected errors.
 func NewRequestError(err error, status int) error {
-	return &RequestError{err, status}
+	return &RequestError{Err: err, Status: status}
+}
+
+// NewRequestErrorFields wraps a provided error with an HTTP status code and
+// the fields that explain it to the client.
+func NewRequestErrorFields(err error, status int, fields map[string]string) error {
+	return &RequestError{Err: err, Status: status, Fields: fields}
 }
 
 // Error implements the error interface. It uses the default message of the
@@ -0,0 +1,251 @@
+// Package backup writes the node's data to a gzipped tarball while the node
+// runs and restores it on startup for disaster recovery.
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+	"github.com/qcbit/blockchain/foundation/blockchain/peer"
+)
+
+// The set of entries in the tarball. Every block is written to its own
+// file in the blocks folder named after the block number.
+const (
+	blocksDir   = "blocks"
+	mempoolFile = "mempool.json"
+	peersFile   = "peers.json"
+	keyFile     = "node.ecdsa"
+)
+
+// Source is a consistent view of the node's data at the moment of the
+// backup. The storage must not have blocks added while it's being read.
+type Source struct {
+	Storage     database.Storage
+	LatestBlock uint64
+	Mempool     []database.BlockTx
+	Peers       []peer.Peer
+}
+
+// Contents is what was restored from a tarball. The mempool and peers are
+// handed back so they can be loaded into the node once it's constructed.
+type Contents struct {
+	Blocks  uint64
+	Mempool []database.BlockTx
+	Peers   []peer.Peer
+	Key     bool
+}
+
+// Write writes a gzipped tarball of the chain up to and including the latest
+// block, the mempool, the known peers and the node key to the writer.
+func Write(w io.Writer, src Source, keyPath string) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	now := time.Now()
+
+	// The key goes first so a restore can check it before the chain
+	// is touched.
+	if keyPath != "" {
+		key, err := os.ReadFile(keyPath)
+		if err != nil {
+			return fmt.Errorf("reading node key: %w", err)
+		}
+
+		if err := writeFile(tw, keyFile, key, 0600, now); err != nil {
+			return err
+		}
+	}
+
+	for num := uint64(1); num <= src.LatestBlock; num++ {
+		blockData, err := src.Storage.GetBlock(num)
+		if err != nil {
+			return fmt.Errorf("reading block %d: %w", num, err)
+		}
+
+		if err := writeJSON(tw, path.Join(blocksDir, blockName(num)), blockData, now); err != nil {
+			return err
+		}
+	}
+
+	if err := writeJSON(tw, mempoolFile, src.Mempool, now); err != nil {
+		return err
+	}
+
+	if err := writeJSON(tw, peersFile, src.Peers, now); err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	return gw.Close()
+}
+
+// Restore replaces the chain in storage with the blocks in the tarball. The
+// node key is written to the key path when there's no key there yet and must
+// match the existing key otherwise. A mismatched key leaves the chain as is.
+// The key in the tarball is skipped when there's no key path, for nodes
+// that keep their key outside the accounts folder.
+func Restore(r io.Reader, storage database.Storage, keyPath string) (Contents, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return Contents{}, fmt.Errorf("opening backup: %w", err)
+	}
+	defer gr.Close()
+
+	var contents Contents
+
+	// The chain in storage is only cleared once the backup is known to
+	// belong to this node and the first block is read.
+	var reset bool
+	resetStorage := func() error {
+		if reset {
+			return nil
+		}
+		reset = true
+
+		if err := storage.Reset(); err != nil {
+			return fmt.Errorf("clearing chain: %w", err)
+		}
+		return nil
+	}
+
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Contents{}, fmt.Errorf("reading backup: %w", err)
+		}
+
+		switch name := hdr.Name; {
+		case strings.HasPrefix(name, blocksDir+"/"):
+			num, err := strconv.ParseUint(strings.TrimSuffix(path.Base(name), ".json"), 10, 64)
+			if err != nil {
+				return Contents{}, fmt.Errorf("invalid block entry %q", name)
+			}
+
+			if err := resetStorage(); err != nil {
+				return Contents{}, err
+			}
+
+			// Blocks are written in order so any gap means a damaged backup.
+			if num != contents.Blocks+1 {
+				return Contents{}, fmt.Errorf("block %d is missing from the backup", contents.Blocks+1)
+			}
+
+			var blockData database.BlockData
+			if err := json.NewDecoder(tr).Decode(&blockData); err != nil {
+				return Contents{}, fmt.Errorf("decoding block %d: %w", num, err)
+			}
+
+			if err := storage.Write(blockData); err != nil {
+				return Contents{}, fmt.Errorf("writing block %d: %w", num, err)
+			}
+			contents.Blocks = num
+
+		case name == mempoolFile:
+			if err := json.NewDecoder(tr).Decode(&contents.Mempool); err != nil {
+				return Contents{}, fmt.Errorf("decoding mempool: %w", err)
+			}
+
+		case name == peersFile:
+			if err := json.NewDecoder(tr).Decode(&contents.Peers); err != nil {
+				return Contents{}, fmt.Errorf("decoding peers: %w", err)
+			}
+
+		case name == keyFile && keyPath != "":
+			if err := restoreKey(tr, keyPath); err != nil {
+				return Contents{}, err
+			}
+			contents.Key = true
+		}
+	}
+
+	// A backup of a chain without blocks still replaces the chain.
+	if err := resetStorage(); err != nil {
+		return Contents{}, err
+	}
+
+	return contents, nil
+}
+
+// =============================================================================
+
+// restoreKey writes the node key to the key path unless a key is already
+// there, in which case the keys must match.
+func restoreKey(r io.Reader, keyPath string) error {
+	key, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("reading node key: %w", err)
+	}
+
+	existing, err := os.ReadFile(keyPath)
+	switch {
+	case err == nil:
+		if !bytes.Equal(bytes.TrimSpace(existing), bytes.TrimSpace(key)) {
+			return fmt.Errorf("node key in backup does not match the key at %s", keyPath)
+		}
+		return nil
+
+	case errors.Is(err, fs.ErrNotExist):
+		if err := os.MkdirAll(path.Dir(keyPath), 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(keyPath, key, 0600)
+
+	default:
+		return err
+	}
+}
+
+// writeJSON adds the value to the tarball as a JSON file.
+func writeJSON(tw *tar.Writer, name string, value any, modTime time.Time) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("encoding %s: %w", name, err)
+	}
+
+	return writeFile(tw, name, data, 0644, modTime)
+}
+
+// writeFile adds the data to the tarball as a file.
+func writeFile(tw *tar.Writer, name string, data []byte, mode int64, modTime time.Time) error {
+	hdr := tar.Header{
+		Name:    name,
+		Mode:    mode,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	}
+
+	if err := tw.WriteHeader(&hdr); err != nil {
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// blockName returns the name of the file a block is written to.
+func blockName(num uint64) string {
+	return strconv.FormatUint(num, 10) + ".json"
+}
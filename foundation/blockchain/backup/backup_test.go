@@ -0,0 +1,121 @@
+package backup_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/backup"
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+	"github.com/qcbit/blockchain/foundation/blockchain/peer"
+	"github.com/qcbit/blockchain/foundation/blockchain/storage/disk"
+)
+
+func Test_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+
+	storage, err := disk.New(filepath.Join(dir, "chain"))
+	if err != nil {
+		t.Fatalf("constructing storage: %v", err)
+	}
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	keyPath := filepath.Join(dir, "node.ecdsa")
+	if err := crypto.SaveECDSA(keyPath, key); err != nil {
+		t.Fatalf("saving key: %v", err)
+	}
+
+	tx, err := database.NewTx(1, database.PublicKeyToAccountID(key.PublicKey), "0xbEE6ACE826eC3DE1B6349888B9151B92522F7F76", 100, 1, 0, nil)
+	if err != nil {
+		t.Fatalf("constructing tx: %v", err)
+	}
+	signedTx, err := tx.Sign(key)
+	if err != nil {
+		t.Fatalf("signing tx: %v", err)
+	}
+	blockTx := database.NewBlockTx(signedTx, 15, 1)
+
+	var prevBlock database.Block
+	for i := 0; i < 2; i++ {
+		block, err := database.NewBlock(database.POWArgs{
+			BeneficiaryID: blockTx.FromID,
+			Difficulty:    1,
+			PrevBlock:     prevBlock,
+			Trans:         []database.BlockTx{blockTx},
+			EvHandler:     func(v string, args ...any) {},
+		})
+		if err != nil {
+			t.Fatalf("building block: %v", err)
+		}
+		if err := storage.Write(database.NewBlockData(block)); err != nil {
+			t.Fatalf("writing block: %v", err)
+		}
+		prevBlock = block
+	}
+
+	src := backup.Source{
+		Storage:     storage,
+		LatestBlock: 2,
+		Mempool:     []database.BlockTx{blockTx},
+		Peers:       []peer.Peer{peer.New("0.0.0.0:9280")},
+	}
+
+	var buf bytes.Buffer
+	if err := backup.Write(&buf, src, keyPath); err != nil {
+		t.Fatalf("writing backup: %v", err)
+	}
+
+	restoreDir := t.TempDir()
+	restoreStorage, err := disk.New(filepath.Join(restoreDir, "chain"))
+	if err != nil {
+		t.Fatalf("constructing storage: %v", err)
+	}
+	restoreKeyPath := filepath.Join(restoreDir, "accounts", "node.ecdsa")
+
+	contents, err := backup.Restore(bytes.NewReader(buf.Bytes()), restoreStorage, restoreKeyPath)
+	if err != nil {
+		t.Fatalf("restoring backup: %v", err)
+	}
+
+	if contents.Blocks != 2 || len(contents.Mempool) != 1 || len(contents.Peers) != 1 || !contents.Key {
+		t.Fatalf("should restore everything in the backup: got %+v", contents)
+	}
+
+	blockData, err := restoreStorage.GetBlock(2)
+	if err != nil {
+		t.Fatalf("reading restored block: %v", err)
+	}
+	if blockData.Hash != prevBlock.Hash() {
+		t.Fatalf("should restore the same block: got %s, exp %s", blockData.Hash, prevBlock.Hash())
+	}
+
+	if _, err := crypto.LoadECDSA(restoreKeyPath); err != nil {
+		t.Fatalf("should restore the node key: %v", err)
+	}
+
+	// A node with a different key must not take over this node's chain.
+	other, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	if err := os.Remove(restoreKeyPath); err != nil {
+		t.Fatalf("removing key: %v", err)
+	}
+	if err := crypto.SaveECDSA(restoreKeyPath, other); err != nil {
+		t.Fatalf("saving key: %v", err)
+	}
+
+	if _, err := backup.Restore(bytes.NewReader(buf.Bytes()), restoreStorage, restoreKeyPath); err == nil {
+		t.Fatal("restore should fail when the node key doesn't match")
+	}
+
+	if _, err := restoreStorage.GetBlock(1); err != nil {
+		t.Fatalf("failed restore should leave the chain alone: %v", err)
+	}
+}
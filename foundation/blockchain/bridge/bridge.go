@@ -0,0 +1,143 @@
+// Package bridge moves value from one chain to another. A transfer to the
+// bridge account on the source chain locks the value there and, once the
+// block holding it has enough confirmations, a relayer releases the same
+// value from the bridge account on the destination chain. The ledger
+// remembers the locks that were released so none is released twice.
+package bridge
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+)
+
+// The set of bridge transactions, kept in the data of the transaction.
+const (
+	KindLock    = "lock"
+	KindRelease = "release"
+)
+
+// Lock represents where the value locked by a transfer to the bridge
+// account is released.
+type Lock struct {
+	ChainID uint16             `json:"chain_id"`
+	ToID    database.AccountID `json:"to"`
+}
+
+// Release represents the lock a release transaction pays out.
+type Release struct {
+	Source string `json:"source"`
+	TxHash string `json:"tx_hash"`
+}
+
+// txData represents the data of a bridge transaction.
+type txData struct {
+	Bridge string `json:"bridge"`
+	*Lock
+	*Release
+}
+
+// LockData returns the data of a transfer to the bridge account that
+// releases the value to the account on the destination chain.
+func LockData(chainID uint16, toID database.AccountID) ([]byte, error) {
+	if chainID == 0 {
+		return nil, errors.New("destination chain ID is required")
+	}
+	if !toID.IsAccountID() {
+		return nil, fmt.Errorf("invalid destination account %q", toID)
+	}
+
+	return json.Marshal(txData{
+		Bridge: KindLock,
+		Lock:   &Lock{ChainID: chainID, ToID: toID.Checksummed()},
+	})
+}
+
+// ParseLock returns the lock a transaction asks for. The bool is false
+// when the transaction isn't a lock.
+func ParseLock(tx database.Tx) (Lock, bool) {
+	var data txData
+	if err := json.Unmarshal(tx.Data, &data); err != nil || data.Bridge != KindLock || data.Lock == nil {
+		return Lock{}, false
+	}
+
+	if data.ChainID == 0 || !data.ToID.IsAccountID() {
+		return Lock{}, false
+	}
+
+	return *data.Lock, true
+}
+
+// ReleaseData returns the data of the transaction that releases the value
+// of the event on the destination chain.
+func ReleaseData(ev Event) ([]byte, error) {
+	return json.Marshal(txData{
+		Bridge:  KindRelease,
+		Release: &Release{Source: ev.Key, TxHash: ev.TxHash},
+	})
+}
+
+// ParseRelease returns the lock a transaction releases. The bool is false
+// when the transaction isn't a release.
+func ParseRelease(tx database.Tx) (Release, bool) {
+	var data txData
+	if err := json.Unmarshal(tx.Data, &data); err != nil || data.Bridge != KindRelease || data.Release == nil {
+		return Release{}, false
+	}
+
+	return *data.Release, true
+}
+
+// =============================================================================
+
+// Event represents value locked on the source chain to be released on the
+// destination chain.
+type Event struct {
+	Key         string             `json:"key"`
+	SourceChain uint16             `json:"source_chain"`
+	Block       uint64             `json:"block"`
+	TxHash      string             `json:"tx_hash"`
+	FromID      database.AccountID `json:"from"`
+	ToID        database.AccountID `json:"to"`
+	Value       uint64             `json:"value"`
+}
+
+// EventKey returns the key that identifies the lock. A sender's nonce can
+// only be mined once, so the key doesn't depend on the block or the hash
+// algorithm the chain used for it.
+func EventKey(tx database.Tx) string {
+	return fmt.Sprintf("%d/%s/%d", tx.ChainID, tx.FromID.Checksummed(), tx.Nonce)
+}
+
+// Events returns the locks in the block that are released on the
+// destination chain. The transactions are hashed with the algorithm the
+// source chain used for the block.
+func Events(block database.Block, bridgeID database.AccountID, destChainID uint16, algorithm string) []Event {
+	bridgeID = bridgeID.Checksummed()
+
+	var events []Event
+	for _, tx := range block.MerkleTree.Values() {
+		if tx.ToID.Checksummed() != bridgeID || tx.FromID.Checksummed() == bridgeID || tx.Value == 0 {
+			continue
+		}
+
+		lock, ok := ParseLock(tx.Tx)
+		if !ok || lock.ChainID != destChainID {
+			continue
+		}
+
+		events = append(events, Event{
+			Key:         EventKey(tx.Tx),
+			SourceChain: tx.ChainID,
+			Block:       block.Header.Number,
+			TxHash:      tx.TxHash(algorithm),
+			FromID:      tx.FromID,
+			ToID:        lock.ToID,
+			Value:       tx.Value,
+		})
+	}
+
+	return events
+}
@@ -0,0 +1,156 @@
+package bridge_test
+
+import (
+	"crypto/ecdsa"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/bridge"
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+	"github.com/qcbit/blockchain/foundation/blockchain/signature"
+)
+
+const (
+	sourceChain = 1
+	destChain   = 7
+	bob         = "0xbEE6ACE826eC3DE1B6349888B9151B92522F7F76"
+)
+
+func Test_Events(t *testing.T) {
+	alice := newKey(t)
+	bridgeKey := newKey(t)
+	bridgeID := database.PublicKeyToAccountID(bridgeKey.PublicKey)
+
+	lock, err := bridge.LockData(destChain, bob)
+	if err != nil {
+		t.Fatalf("should be able to build lock data: %s", err)
+	}
+	otherChain, err := bridge.LockData(destChain+1, bob)
+	if err != nil {
+		t.Fatalf("should be able to build lock data: %s", err)
+	}
+
+	trans := []database.BlockTx{
+		newBlockTx(t, alice, bridgeID, 1, 100, lock),
+		newBlockTx(t, alice, bridgeID, 2, 100, nil),        // A plain transfer to the bridge.
+		newBlockTx(t, alice, bob, 3, 100, lock),            // Not sent to the bridge.
+		newBlockTx(t, alice, bridgeID, 4, 100, otherChain), // Released on another chain.
+		newBlockTx(t, bridgeKey, bridgeID, 1, 100, lock),   // Sent by the bridge itself.
+	}
+
+	block, err := database.NewBlock(database.POWArgs{
+		Version:       1,
+		BeneficiaryID: bridgeID,
+		Difficulty:    1,
+		MiningReward:  50,
+		PrevBlock:     database.Block{},
+		StateRoot:     "0x00",
+		Trans:         trans,
+		EvHandler:     func(v string, args ...any) {},
+	})
+	if err != nil {
+		t.Fatalf("should be able to build block: %s", err)
+	}
+
+	events := bridge.Events(block, bridgeID, destChain, signature.HashKeccak256)
+	if len(events) != 1 {
+		t.Fatalf("should find only the lock released on the destination chain: got %d events", len(events))
+	}
+
+	ev := events[0]
+	if ev.ToID != bob || ev.Value != 100 || ev.SourceChain != sourceChain || ev.Block != block.Header.Number {
+		t.Fatalf("should describe the lock: got %+v", ev)
+	}
+	if ev.Key != bridge.EventKey(trans[0].Tx) || ev.TxHash != trans[0].TxHash(signature.HashKeccak256) {
+		t.Fatalf("should identify the lock transaction: got %+v", ev)
+	}
+
+	data, err := bridge.ReleaseData(ev)
+	if err != nil {
+		t.Fatalf("should be able to build release data: %s", err)
+	}
+	release, ok := bridge.ParseRelease(database.Tx{Data: data})
+	if !ok || release.Source != ev.Key || release.TxHash != ev.TxHash {
+		t.Fatalf("should get back the released lock: got %+v", release)
+	}
+	if _, ok := bridge.ParseLock(database.Tx{Data: data}); ok {
+		t.Fatal("a release should not parse as a lock")
+	}
+}
+
+func Test_Ledger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bridge", "ledger.json")
+
+	ledger, err := bridge.OpenLedger(path)
+	if err != nil {
+		t.Fatalf("should be able to open a new ledger: %s", err)
+	}
+	if ledger.Next() != 1 {
+		t.Fatalf("a new ledger should start at block 1, got %d", ledger.Next())
+	}
+
+	first, added, err := ledger.Add(bridge.Event{Key: "1/a/1"}, 5)
+	if err != nil || !added || first.Nonce != 5 || first.Status != bridge.StatusPending {
+		t.Fatalf("should add the lock with the next nonce: got %+v, %t, %v", first, added, err)
+	}
+
+	// The nonce keeps going up even when the node's next nonce hasn't
+	// caught up with the releases still in flight.
+	second, _, err := ledger.Add(bridge.Event{Key: "1/a/2"}, 5)
+	if err != nil || second.Nonce != 6 {
+		t.Fatalf("should assign the nonce after the last one: got %+v, %v", second, err)
+	}
+
+	again, added, err := ledger.Add(bridge.Event{Key: "1/a/1"}, 9)
+	if err != nil || added || again.Nonce != 5 {
+		t.Fatalf("should not add a lock twice: got %+v, %t, %v", again, added, err)
+	}
+
+	if err := ledger.SetStatus("1/a/1", bridge.StatusConfirmed); err != nil {
+		t.Fatalf("should be able to set the status: %s", err)
+	}
+	if err := ledger.Scanned(12); err != nil {
+		t.Fatalf("should be able to record the scan: %s", err)
+	}
+
+	// Everything is there after a restart.
+	reopened, err := bridge.OpenLedger(path)
+	if err != nil {
+		t.Fatalf("should be able to reopen the ledger: %s", err)
+	}
+	if reopened.Next() != 13 {
+		t.Fatalf("should resume after the scanned block, got %d", reopened.Next())
+	}
+	if !reopened.Processed("1/a/1") || !reopened.Processed("1/a/2") || reopened.Processed("1/a/3") {
+		t.Fatal("should remember the processed locks")
+	}
+
+	unconfirmed := reopened.Unconfirmed()
+	if len(unconfirmed) != 1 || unconfirmed[0].Key != "1/a/2" || unconfirmed[0].Nonce != 6 {
+		t.Fatalf("should only have the second release unconfirmed: got %+v", unconfirmed)
+	}
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("should be able to generate key: %s", err)
+	}
+	return key
+}
+
+func newBlockTx(t *testing.T, key *ecdsa.PrivateKey, to database.AccountID, nonce uint64, value uint64, data []byte) database.BlockTx {
+	tx, err := database.NewTx(sourceChain, database.PublicKeyToAccountID(key.PublicKey), to, value, nonce, 0, data)
+	if err != nil {
+		t.Fatalf("should be able to create tx: %s", err)
+	}
+
+	signedTx, err := tx.Sign(key)
+	if err != nil {
+		t.Fatalf("should be able to sign tx: %s", err)
+	}
+
+	return database.NewBlockTx(signedTx, 1, 1)
+}
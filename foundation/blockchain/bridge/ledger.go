@@ -0,0 +1,206 @@
+package bridge
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// The set of states a release goes through on the destination chain.
+const (
+	StatusPending   = "pending"   // Recorded, not yet accepted by the destination node.
+	StatusSubmitted = "submitted" // In the destination node's mempool.
+	StatusConfirmed = "confirmed" // The nonce of the release has been mined.
+)
+
+// Transfer represents the release of a lock and the nonce it's released
+// with. The nonce is assigned once, before the release is first submitted,
+// so submitting it again after a failure or a restart can't release the
+// lock twice: the destination chain only mines one transaction per nonce.
+type Transfer struct {
+	Event
+	Nonce  uint64 `json:"nonce"`
+	Status string `json:"status"`
+}
+
+// ledgerFile represents the ledger on disk.
+type ledgerFile struct {
+	Next      uint64     `json:"next_block"`
+	Transfers []Transfer `json:"transfers"`
+}
+
+// Ledger tracks how far the source chain has been scanned and the locks
+// that have been processed. Every change is written to its file before the
+// call returns.
+type Ledger struct {
+	mu    sync.RWMutex
+	path  string
+	file  ledgerFile
+	byKey map[string]int
+}
+
+// OpenLedger loads the ledger kept in the file, starting an empty one when
+// the file doesn't exist yet.
+func OpenLedger(path string) (*Ledger, error) {
+	l := Ledger{
+		path:  path,
+		file:  ledgerFile{Next: 1},
+		byKey: make(map[string]int),
+	}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return &l, nil
+	case err != nil:
+		return nil, fmt.Errorf("reading ledger: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &l.file); err != nil {
+		return nil, fmt.Errorf("decoding ledger %s: %w", path, err)
+	}
+	for i, tr := range l.file.Transfers {
+		l.byKey[tr.Key] = i
+	}
+
+	return &l, nil
+}
+
+// Next returns the number of the next source block to scan.
+func (l *Ledger) Next() uint64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.file.Next
+}
+
+// Scanned records every lock up to the block has been processed.
+func (l *Ledger) Scanned(number uint64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if number < l.file.Next {
+		return nil
+	}
+	l.file.Next = number + 1
+
+	return l.save()
+}
+
+// Processed reports whether the lock has already been recorded.
+func (l *Ledger) Processed(key string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	_, exists := l.byKey[key]
+	return exists
+}
+
+// Add records the lock as pending and assigns the nonce it's released
+// with, the next nonce of the bridge account or the one after the last
+// assigned nonce, whichever is higher. The bool is false when the lock was
+// already recorded, in which case the existing transfer is returned.
+func (l *Ledger) Add(ev Event, nextNonce uint64) (Transfer, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if i, exists := l.byKey[ev.Key]; exists {
+		return l.file.Transfers[i], false, nil
+	}
+
+	if n := len(l.file.Transfers); n > 0 && l.file.Transfers[n-1].Nonce >= nextNonce {
+		nextNonce = l.file.Transfers[n-1].Nonce + 1
+	}
+
+	tr := Transfer{
+		Event:  ev,
+		Nonce:  nextNonce,
+		Status: StatusPending,
+	}
+	l.file.Transfers = append(l.file.Transfers, tr)
+	l.byKey[ev.Key] = len(l.file.Transfers) - 1
+
+	if err := l.save(); err != nil {
+		l.file.Transfers = l.file.Transfers[:len(l.file.Transfers)-1]
+		delete(l.byKey, ev.Key)
+		return Transfer{}, false, err
+	}
+
+	return tr, true, nil
+}
+
+// SetStatus records the status of the lock's release.
+func (l *Ledger) SetStatus(key string, status string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	i, exists := l.byKey[key]
+	if !exists {
+		return fmt.Errorf("lock %s is not in the ledger", key)
+	}
+	if l.file.Transfers[i].Status == status {
+		return nil
+	}
+
+	prev := l.file.Transfers[i].Status
+	l.file.Transfers[i].Status = status
+	if err := l.save(); err != nil {
+		l.file.Transfers[i].Status = prev
+		return err
+	}
+
+	return nil
+}
+
+// Unconfirmed returns the transfers whose release hasn't been mined yet in
+// the order of their nonces.
+func (l *Ledger) Unconfirmed() []Transfer {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var out []Transfer
+	for _, tr := range l.file.Transfers {
+		if tr.Status != StatusConfirmed {
+			out = append(out, tr)
+		}
+	}
+
+	return out
+}
+
+// Transfers returns every transfer in the ledger.
+func (l *Ledger) Transfers() []Transfer {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return append([]Transfer(nil), l.file.Transfers...)
+}
+
+// save writes the ledger to a temporary file that replaces the ledger's
+// file, so a crash never leaves half of it written. The caller must hold
+// the lock.
+func (l *Ledger) save() error {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(l.file, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, l.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return nil
+}
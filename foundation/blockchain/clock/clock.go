@@ -0,0 +1,66 @@
+// Package clock provides the source of time for the blockchain so time can
+// be controlled when testing timestamp rules without sleeping.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock represents the behavior required to tell the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// System is the clock that uses the time of the system.
+type System struct{}
+
+// Now implements the Clock interface by returning the system time.
+func (System) Now() time.Time {
+	return time.Now()
+}
+
+// OrSystem returns the clock or the system clock when it's nil.
+func OrSystem(c Clock) Clock {
+	if c == nil {
+		return System{}
+	}
+	return c
+}
+
+// =============================================================================
+
+// Manual is a clock that only moves when told to.
+type Manual struct {
+	mu  sync.RWMutex
+	now time.Time
+}
+
+// NewManual constructs a manual clock set to the specified time.
+func NewManual(now time.Time) *Manual {
+	return &Manual{now: now}
+}
+
+// Now implements the Clock interface by returning the time that was set.
+func (m *Manual) Now() time.Time {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.now
+}
+
+// Set changes the time of the clock.
+func (m *Manual) Set(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.now = now
+}
+
+// Advance moves the clock forward by the duration.
+func (m *Manual) Advance(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.now = m.now.Add(d)
+}
@@ -0,0 +1,67 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Drift estimates how far the local clock is from the clocks of other nodes.
+// The latest offset seen from each node is kept and the median is taken so
+// a single node with a bad clock, or lying about it, can't move the estimate.
+type Drift struct {
+	mu      sync.RWMutex
+	offsets map[string]time.Duration
+}
+
+// NewDrift constructs a drift estimate with no offsets seen.
+func NewDrift() *Drift {
+	return &Drift{
+		offsets: make(map[string]time.Duration),
+	}
+}
+
+// Observe records the time a source reported against the local times the
+// request for it was sent and the response received. The source's time is
+// taken to be halfway through the round trip.
+func (d *Drift) Observe(source string, sent time.Time, received time.Time, reported time.Time) {
+	local := sent.Add(received.Sub(sent) / 2)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.offsets[source] = local.Sub(reported)
+}
+
+// Forget removes the offset of the source, like when a peer is removed.
+func (d *Drift) Forget(source string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.offsets, source)
+}
+
+// Estimate returns the median of how far the local clock is ahead of the
+// sources, negative when it's behind, and the number of sources it's taken
+// from.
+func (d *Drift) Estimate() (time.Duration, int) {
+	d.mu.RLock()
+	offsets := make([]time.Duration, 0, len(d.offsets))
+	for _, offset := range d.offsets {
+		offsets = append(offsets, offset)
+	}
+	d.mu.RUnlock()
+
+	if len(offsets) == 0 {
+		return 0, 0
+	}
+
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+
+	mid := len(offsets) / 2
+	if len(offsets)%2 == 0 {
+		return (offsets[mid-1] + offsets[mid]) / 2, len(offsets)
+	}
+
+	return offsets[mid], len(offsets)
+}
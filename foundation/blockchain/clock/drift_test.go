@@ -0,0 +1,41 @@
+package clock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/clock"
+)
+
+func Test_Drift(t *testing.T) {
+	d := clock.NewDrift()
+
+	if drift, n := d.Estimate(); drift != 0 || n != 0 {
+		t.Fatalf("no offsets should estimate no drift, got %s from %d", drift, n)
+	}
+
+	now := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	// The peer's time is taken halfway through the round trip, so a peer
+	// reporting that time has the same clock.
+	d.Observe("a", now, now.Add(200*time.Millisecond), now.Add(100*time.Millisecond))
+	if drift, n := d.Estimate(); drift != 0 || n != 1 {
+		t.Fatalf("got drift %s from %d, exp 0s from 1", drift, n)
+	}
+
+	// The local clock is ahead of b and c, a single peer far off doesn't
+	// move the median.
+	d.Observe("b", now, now, now.Add(-2*time.Second))
+	d.Observe("c", now, now, now.Add(-3*time.Second))
+	d.Observe("d", now, now, now.Add(time.Hour))
+	d.Observe("e", now, now, now.Add(-2*time.Second))
+	if drift, n := d.Estimate(); drift != 2*time.Second || n != 5 {
+		t.Fatalf("got drift %s from %d, exp 2s from 5", drift, n)
+	}
+
+	// An even number of offsets takes the middle two.
+	d.Forget("e")
+	if drift, n := d.Estimate(); drift != time.Second || n != 4 {
+		t.Fatalf("got drift %s from %d, exp 1s from 4", drift, n)
+	}
+}
@@ -5,14 +5,61 @@ import (
 	"errors"
 	"unicode"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 )
 
-// Account represents an account on the blockchain.
+// Account represents an account on the blockchain. The balance includes
+// any amounts that are locked and can't be spent yet.
 type Account struct {
 	AccountID AccountID
 	Nonce     uint64
 	Balance   uint64
+	Locks     []Lock `json:",omitempty"`
+	Frozen    bool   `json:",omitempty"` // Transactions from the account are rejected.
+}
+
+// Lock represents an amount of an account's balance that can't be spent
+// until the chain reaches the specified block number.
+type Lock struct {
+	Amount     uint64
+	UntilBlock uint64
+}
+
+// Locked returns the amount of the balance that can't be spent in the
+// block with the specified number.
+func (a Account) Locked(blockNumber uint64) uint64 {
+	var locked uint64
+	for _, lock := range a.Locks {
+		if lock.UntilBlock > blockNumber {
+			locked += lock.Amount
+		}
+	}
+
+	if locked > a.Balance {
+		return a.Balance
+	}
+	return locked
+}
+
+// Spendable returns the amount of the balance that can be spent in the
+// block with the specified number.
+func (a Account) Spendable(blockNumber uint64) uint64 {
+	return a.Balance - a.Locked(blockNumber)
+}
+
+// unlock drops the locks that have expired by the block with the
+// specified number.
+func (a Account) unlock(blockNumber uint64) Account {
+	var locks []Lock
+	for _, lock := range a.Locks {
+		if lock.UntilBlock > blockNumber {
+			locks = append(locks, lock)
+		}
+	}
+	a.Locks = locks
+
+	return a
 }
 
 // newAccount creates a new account with the given account ID and balance.
@@ -30,12 +77,41 @@ func newAccount(accountID AccountID, balance uint64) Account {
 // 20 bytes of the hash of the public key.
 type AccountID string
 
+// The set of errors for an account ID that can't be used.
+var (
+	ErrInvalidAccountID = errors.New("invalid format")
+	ErrInvalidChecksum  = errors.New("invalid checksum")
+	ErrMissingChecksum  = errors.New("missing checksum")
+)
+
 // ToAccountID converts a hex-encoded string to an account ID and validates
-// the string is formatted correctly.
+// the string is formatted correctly. An account ID in mixed case must match
+// its checksum while one in a single case has no checksum to check. The
+// account ID is returned in its checksummed form.
 func ToAccountID(hex string) (AccountID, error) {
 	a := AccountID(hex)
-	if !a.IsAccountID() {
-		return "", errors.New("invalid format")
+	if !a.isHexAccountID() {
+		return "", ErrInvalidAccountID
+	}
+
+	checksummed := a.Checksummed()
+	if a.HasChecksum() && a != checksummed {
+		return "", ErrInvalidChecksum
+	}
+
+	return checksummed, nil
+}
+
+// ToChecksummedAccountID is like ToAccountID but also rejects account IDs
+// written in a single case since a typo in them can't be caught.
+func ToChecksummedAccountID(hex string) (AccountID, error) {
+	a, err := ToAccountID(hex)
+	if err != nil {
+		return "", err
+	}
+
+	if !AccountID(hex).HasChecksum() {
+		return "", ErrMissingChecksum
 	}
 
 	return a, nil
@@ -46,8 +122,44 @@ func PublicKeyToAccountID(publicKey ecdsa.PublicKey) AccountID {
 	return AccountID(crypto.PubkeyToAddress(publicKey).String())
 }
 
-// IsAccountID returns true if the account ID is valid.
+// IsAccountID returns true if the account ID is valid. An account ID in
+// mixed case must match its checksum.
 func (a AccountID) IsAccountID() bool {
+	if !a.isHexAccountID() {
+		return false
+	}
+
+	return !a.HasChecksum() || a == a.Checksummed()
+}
+
+// HasChecksum reports whether the account ID is written in mixed case,
+// which is how the checksum is encoded (EIP-55).
+func (a AccountID) HasChecksum() bool {
+	if has0xPrefix(a) {
+		a = a[2:]
+	}
+
+	var lower, upper bool
+	for _, c := range []byte(a) {
+		switch {
+		case 'a' <= c && c <= 'f':
+			lower = true
+		case 'A' <= c && c <= 'F':
+			upper = true
+		}
+	}
+
+	return lower && upper
+}
+
+// Checksummed returns the account ID with the checksum encoded in the case
+// of its letters (EIP-55).
+func (a AccountID) Checksummed() AccountID {
+	return AccountID(common.HexToAddress(string(a)).Hex())
+}
+
+// isHexAccountID returns true if the account ID is 20 hex-encoded bytes.
+func (a AccountID) isHexAccountID() bool {
 	const addressLength = 20
 
 	if has0xPrefix(a) {
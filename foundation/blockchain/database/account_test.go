@@ -0,0 +1,46 @@
+package database_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+)
+
+func Test_AccountIDChecksum(t *testing.T) {
+	const checksummed = "0xF01813E4B85e178A83e29B8E7bF26BD830a25f32"
+
+	tt := []struct {
+		name   string
+		hex    string
+		err    error
+		strict error
+	}{
+		{"checksummed", checksummed, nil, nil},
+		{"lower", strings.ToLower(checksummed), nil, database.ErrMissingChecksum},
+		{"upper", "0x" + strings.ToUpper(checksummed[2:]), nil, database.ErrMissingChecksum},
+		{"typo", "0xF01813E4B85e178A83e29B8E7bF26BD830a25f33", database.ErrInvalidChecksum, database.ErrInvalidChecksum},
+		{"wrong case", "0xf01813E4B85e178A83e29B8E7bF26BD830a25f32", database.ErrInvalidChecksum, database.ErrInvalidChecksum},
+		{"short", checksummed[:40], database.ErrInvalidAccountID, database.ErrInvalidAccountID},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			a, err := database.ToAccountID(tst.hex)
+			if !errors.Is(err, tst.err) {
+				t.Fatalf("converting: got %v, exp %v", err, tst.err)
+			}
+			if err == nil && a != checksummed {
+				t.Fatalf("account ID should be checksummed: got %s, exp %s", a, checksummed)
+			}
+			if got := database.AccountID(tst.hex).IsAccountID(); got != (tst.err == nil) {
+				t.Fatalf("valid account ID: got %t, exp %t", got, tst.err == nil)
+			}
+
+			if _, err := database.ToChecksummedAccountID(tst.hex); !errors.Is(err, tst.strict) {
+				t.Fatalf("converting strictly: got %v, exp %v", err, tst.strict)
+			}
+		})
+	}
+}
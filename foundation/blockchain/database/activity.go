@@ -0,0 +1,168 @@
+package database
+
+// AccountSummary represents the overview of an account's activity in the
+// mined transactions. Fees paid include the gas fees of the transactions
+// the account sponsored and the tips it gave.
+type AccountSummary struct {
+	AccountID      AccountID `json:"account"`
+	Transactions   int       `json:"transactions"`
+	TotalSent      uint64    `json:"total_sent"`
+	TotalReceived  uint64    `json:"total_received"`
+	FeesPaid       uint64    `json:"fees_paid"`
+	FirstBlock     uint64    `json:"first_block"`
+	LastBlock      uint64    `json:"last_block"`
+	Counterparties int       `json:"counterparties"`
+}
+
+// indexedTx represents what a mined transaction did for one of the
+// accounts taking part in it.
+type indexedTx struct {
+	blockNumber  uint64
+	txHash       string
+	counterparty AccountID // Empty when the account has no counterparty.
+	sent         uint64
+	received     uint64
+	fee          uint64
+}
+
+// Summary returns the overview of the account's activity. An account that
+// has never taken part in a mined transaction has an empty summary.
+func (db *Database) Summary(accountID AccountID) AccountSummary {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	summary := AccountSummary{
+		AccountID: accountID,
+	}
+
+	archive := db.archive[accountID]
+	txs := db.txIndex[accountID]
+	if len(txs) == 0 && archive.transactions == 0 {
+		return summary
+	}
+
+	txHashes := make(map[string]struct{})
+	counterparties := make(map[AccountID]struct{})
+	for id := range archive.counterparties {
+		counterparties[id] = struct{}{}
+	}
+	for _, tx := range txs {
+		txHashes[tx.txHash] = struct{}{}
+		if tx.counterparty != "" {
+			counterparties[tx.counterparty] = struct{}{}
+		}
+
+		summary.TotalSent += tx.sent
+		summary.TotalReceived += tx.received
+		summary.FeesPaid += tx.fee
+	}
+
+	summary.TotalSent += archive.sent
+	summary.TotalReceived += archive.received
+	summary.FeesPaid += archive.fees
+
+	summary.Transactions = archive.transactions + len(txHashes)
+	summary.FirstBlock = archive.firstBlock
+	summary.LastBlock = archive.lastBlock
+	if len(txs) > 0 {
+		if archive.transactions == 0 {
+			summary.FirstBlock = txs[0].blockNumber
+		}
+		summary.LastBlock = txs[len(txs)-1].blockNumber
+	}
+	summary.Counterparties = len(counterparties)
+
+	return summary
+}
+
+// =============================================================================
+
+// indexTx adds what the transaction did for the accounts taking part in it
+// to the index. A transaction that failed only cost its gas payer the gas
+// fee. The caller must hold the lock.
+func (db *Database) indexTx(block Block, tx BlockTx, gasFee uint64, err error) {
+	entry := indexedTx{
+		blockNumber: block.Header.Number,
+		txHash:      tx.TxHash(db.HashAlgorithm(block.Header.Number)),
+	}
+
+	if err != nil {
+		if gasFee > 0 {
+			payer := entry
+			payer.fee = gasFee
+			db.txIndex[tx.GasPayer()] = append(db.txIndex[tx.GasPayer()], payer)
+		}
+		return
+	}
+
+	from := entry
+	from.sent = tx.Value
+	from.fee = tx.Tip
+	if tx.ToID != tx.FromID {
+		from.counterparty = tx.ToID
+	}
+
+	to := entry
+	to.received = tx.Value
+	if tx.ToID != tx.FromID {
+		to.counterparty = tx.FromID
+	}
+
+	payerID := tx.GasPayer()
+	if payerID == tx.FromID {
+		from.fee += gasFee
+	}
+
+	db.txIndex[tx.FromID] = append(db.txIndex[tx.FromID], from)
+	if tx.ToID != tx.FromID {
+		db.txIndex[tx.ToID] = append(db.txIndex[tx.ToID], to)
+	}
+	if payerID != tx.FromID && gasFee > 0 {
+		payer := entry
+		payer.fee = gasFee
+		db.txIndex[payerID] = append(db.txIndex[payerID], payer)
+	}
+}
+
+// unindexBlock removes the transactions of the block from the index. The
+// block must be the latest so its transactions are last for every account.
+// The caller must hold the lock.
+func (db *Database) unindexBlock(number uint64) {
+	for accountID, txs := range db.txIndex {
+		n := len(txs)
+		for n > 0 && txs[n-1].blockNumber == number {
+			n--
+		}
+
+		switch {
+		case n == 0:
+			delete(db.txIndex, accountID)
+		case n < len(txs):
+			db.txIndex[accountID] = txs[:n]
+		}
+	}
+}
+
+// mergeIndex puts the transactions indexed in the other database before the
+// ones in this database for blocks the other database ends before. The
+// caller must hold the lock.
+func (db *Database) mergeIndex(other *Database, through uint64) {
+	for accountID, txs := range other.txIndex {
+		var merged []indexedTx
+		for _, tx := range txs {
+			if tx.blockNumber <= through {
+				merged = append(merged, tx)
+			}
+		}
+		if len(merged) == 0 {
+			continue
+		}
+
+		for _, tx := range db.txIndex[accountID] {
+			if tx.blockNumber > through {
+				merged = append(merged, tx)
+			}
+		}
+		db.txIndex[accountID] = merged
+	}
+}
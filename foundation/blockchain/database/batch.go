@@ -0,0 +1,116 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// The set of buckets the records derived from a block are written to.
+const (
+	BucketReceipts = "receipts" // Receipt of each transaction keyed by its hash.
+	BucketIndex    = "index"    // Transactions each account took part in keyed by block number.
+)
+
+// BatchStorage interface represents the behavior required to be implemented
+// by storage that can commit several records at once. A block and the
+// records derived from it either all reach storage or none do, so a crash
+// can't leave a block without its receipts and index entries.
+type BatchStorage interface {
+	NewBatch() (Batch, error)
+	Record(bucket string, key string) ([]byte, error)
+}
+
+// Batch interface represents a set of writes that are committed or rolled
+// back together. Nothing written to the batch can be read from storage
+// until it's committed. A batch can't be used after Commit or Rollback.
+type Batch interface {
+	Write(blockData BlockData) error
+	Put(bucket string, key string, value []byte) error
+	Commit() error
+	Rollback() error
+}
+
+// Commit writes the block to storage with the receipts of its transactions
+// and the index entries of the accounts that took part in them. The block
+// must already be applied to the accounts. Storage that can't commit a
+// batch only gets the block.
+func (db *Database) Commit(block Block) error {
+	bs, ok := db.storage.(BatchStorage)
+	if !ok {
+		return db.storage.Write(NewBlockData(block))
+	}
+
+	batch, err := bs.NewBatch()
+	if err != nil {
+		return fmt.Errorf("starting batch: %w", err)
+	}
+
+	if err := db.writeBatch(batch, block); err != nil {
+		batch.Rollback()
+		return err
+	}
+
+	if err := batch.Commit(); err != nil {
+		return fmt.Errorf("committing block %d: %w", block.Header.Number, err)
+	}
+
+	return nil
+}
+
+// writeBatch adds the block and the records derived from it to the batch.
+func (db *Database) writeBatch(batch Batch, block Block) error {
+	if err := batch.Write(NewBlockData(block)); err != nil {
+		return fmt.Errorf("writing block %d: %w", block.Header.Number, err)
+	}
+
+	db.mu.RLock()
+	receipts := make(map[string]Receipt)
+	algorithm := db.HashAlgorithm(block.Header.Number)
+	for _, tx := range block.MerkleTree.Values() {
+		txHash := tx.TxHash(algorithm)
+		if receipt, exists := db.receipts[txHash]; exists {
+			receipts[txHash] = receipt
+		}
+	}
+	index := db.blockIndex(block.Header.Number)
+	db.mu.RUnlock()
+
+	for txHash, receipt := range receipts {
+		data, err := json.Marshal(receipt)
+		if err != nil {
+			return err
+		}
+		if err := batch.Put(BucketReceipts, txHash, data); err != nil {
+			return fmt.Errorf("writing receipt %s: %w", txHash, err)
+		}
+	}
+
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	if err := batch.Put(BucketIndex, strconv.FormatUint(block.Header.Number, 10), data); err != nil {
+		return fmt.Errorf("writing index of block %d: %w", block.Header.Number, err)
+	}
+
+	return nil
+}
+
+// blockIndex returns the hashes of the transactions in the block each
+// account took part in. The block must be the latest so its transactions
+// are last for every account. The caller must hold the lock.
+func (db *Database) blockIndex(number uint64) map[AccountID][]string {
+	index := make(map[AccountID][]string)
+	for accountID, txs := range db.txIndex {
+		n := len(txs)
+		for n > 0 && txs[n-1].blockNumber == number {
+			n--
+		}
+		for _, tx := range txs[n:] {
+			index[accountID] = append(index[accountID], tx.txHash)
+		}
+	}
+
+	return index
+}
@@ -9,14 +9,64 @@ import (
 	"math/big"
 	"time"
 
+	"github.com/qcbit/blockchain/foundation/blockchain/clock"
+	"github.com/qcbit/blockchain/foundation/blockchain/genesis"
 	"github.com/qcbit/blockchain/foundation/blockchain/merkle"
 	"github.com/qcbit/blockchain/foundation/blockchain/signature"
 )
 
+// MaxExtraData is the most bytes of extra data a block can carry.
+const MaxExtraData = 32
+
 // ErrChainForked is returned from validateNextBlock if another node's chain
 // is two or more blocks ahead of ours.
 var ErrChainForked = errors.New("blockchain forked, start resync")
 
+// The set of checks a block is validated against.
+const (
+	CheckFork         = "fork"
+	CheckVersion      = "version"
+	CheckDifficulty   = "difficulty"
+	CheckMiningReward = "mining_reward"
+	CheckExtraData    = "extra_data"
+	CheckSolved       = "solved"
+	CheckNumber       = "number"
+	CheckPrevHash     = "prev_hash"
+	CheckTimestamp    = "timestamp"
+	CheckStateRoot    = "state_root"
+	CheckMerkleRoot   = "merkle_root"
+)
+
+// BlockError is returned from ValidateBlock with the check the block failed
+// and the values that failed it so the reason can be reported to the node
+// that proposed the block.
+type BlockError struct {
+	Check    string
+	Got      string
+	Expected string
+	Err      error
+}
+
+// newBlockError constructs the error for the failed check.
+func newBlockError(check string, got any, expected any, err error) *BlockError {
+	return &BlockError{
+		Check:    check,
+		Got:      fmt.Sprint(got),
+		Expected: fmt.Sprint(expected),
+		Err:      err,
+	}
+}
+
+// Error implements the error interface.
+func (be *BlockError) Error() string {
+	return be.Err.Error()
+}
+
+// Unwrap returns the error describing the failed check.
+func (be *BlockError) Unwrap() error {
+	return be.Err
+}
+
 //-----------------------------------------------------------------------------
 
 // BlockData represents what can be serialized to disk and over the network.
@@ -37,9 +87,11 @@ func NewBlockData(block Block) BlockData {
 	return blockData
 }
 
-// ToBlock converts a storage block into a database block.
-func ToBlock(blockData BlockData) (Block, error) {
-	tree, err := merkle.NewTree(blockData.Trans)
+// ToBlock converts a storage block into a database block. The block and its
+// transactions are hashed with the algorithm, which must be the one in
+// effect at the block's height on the chain the block belongs to.
+func ToBlock(algorithm string, blockData BlockData) (Block, error) {
+	tree, err := newMerkleTree(algorithm, blockData.Trans)
 	if err != nil {
 		return Block{}, err
 	}
@@ -47,6 +99,7 @@ func ToBlock(blockData BlockData) (Block, error) {
 	block := Block{
 		Header:     blockData.Header,
 		MerkleTree: tree,
+		algorithm:  algorithm,
 	}
 
 	return block, nil
@@ -56,37 +109,63 @@ func ToBlock(blockData BlockData) (Block, error) {
 
 // BlockHeader represents common information required for each block.
 type BlockHeader struct {
-	Number        uint64    `json:"number"`          // Ethereum: Block number in the chain.
-	PrevBlockHash string    `json:"prev_block_hash"` // Bitcoin: Hash of the previous block.
-	TimeStamp     uint64    `json:"timestamp"`       // Bitcoin: Time the block was mined.
-	BeneficiaryID AccountID `json:"beneficiary"`     // Ethereum: The account who is receiving fees and tips.
-	Difficulty    uint16    `json:"difficulty"`      // Ethereum: The number of 0's needed to solve the hash solution.
-	MiningReward  uint64    `json:"mining_reward"`   // Ethereum: The reward for mining this block.
-	StateRoot     string    `json:"state_root"`      // Ethereum: Represents the hash of the accounts and their balances.
-	TransRoot     string    `json:"trans_root"`      // Both: Represents the merkle root hash for the transactions.
-	Nonce         uint64    `json:"nonce"`           // Both: Value identified to solve the hash solution.
+	Version       uint16    `json:"version,omitempty"`    // Both: Layout of the header, selected by the upgrade schedule.
+	Number        uint64    `json:"number"`               // Ethereum: Block number in the chain.
+	PrevBlockHash string    `json:"prev_block_hash"`      // Bitcoin: Hash of the previous block.
+	TimeStamp     uint64    `json:"timestamp"`            // Bitcoin: Time the block was mined.
+	BeneficiaryID AccountID `json:"beneficiary"`          // Ethereum: The account who is receiving fees and tips.
+	Difficulty    uint16    `json:"difficulty"`           // Ethereum: The number of 0's needed to solve the hash solution.
+	MiningReward  uint64    `json:"mining_reward"`        // Ethereum: The reward for mining this block.
+	StateRoot     string    `json:"state_root"`           // Ethereum: Represents the hash of the accounts and their balances.
+	TransRoot     string    `json:"trans_root"`           // Both: Represents the merkle root hash for the transactions.
+	Nonce         uint64    `json:"nonce"`                // Both: Value identified to solve the hash solution.
+	ExtraData     string    `json:"extra_data,omitempty"` // Ethereum: Data the miner tags the block with.
 }
 
 // Block represents a group of transactions bundled together.
 type Block struct {
 	Header     BlockHeader
 	MerkleTree *merkle.Tree[BlockTx]
+	algorithm  string // Hash algorithm in effect at the block's height.
 }
 
 // POWArgs represents the arguments required to solve the proof of work.
 type POWArgs struct {
+	Version       uint16
 	BeneficiaryID AccountID
 	Difficulty    uint16
 	MiningReward  uint64
 	PrevBlock     Block
 	StateRoot     string
+	ExtraData     string
+	HashAlgorithm string // SHA-256 is used when empty.
 	Trans         []BlockTx
 	EvHandler     func(v string, args ...any)
+	Clock         clock.Clock // The system clock is used when nil.
 }
 
 // POW constructs a new Block and performs the work to find a nonce
 // that solves the cryptographic hash puzzle.
 func POW(ctx context.Context, args POWArgs) (Block, error) {
+	block, err := NewBlock(args)
+	if err != nil {
+		return Block{}, err
+	}
+
+	// Perform the POW algorithm to find the nonce that solves the hash puzzle.
+	if err := block.performPOW(ctx, args.EvHandler); err != nil {
+		return Block{}, err
+	}
+
+	return block, nil
+}
+
+// NewBlock constructs the block to be mined from the arguments without
+// performing the work to solve the cryptographic hash puzzle.
+func NewBlock(args POWArgs) (Block, error) {
+	if len(args.ExtraData) > MaxExtraData {
+		return Block{}, fmt.Errorf("extra data is %d bytes, can't be more than %d", len(args.ExtraData), MaxExtraData)
+	}
 
 	// When mining the first block, the previous block's hash will be zero.
 	prevBlockHash := signature.ZeroHash
@@ -96,7 +175,12 @@ func POW(ctx context.Context, args POWArgs) (Block, error) {
 
 	// Construct a merkle tree from the transaction for this block.
 	// The root of this tree will be part of the block to be mined.
-	tree, err := merkle.NewTree(args.Trans)
+	algorithm := args.HashAlgorithm
+	if algorithm == "" {
+		algorithm = signature.HashSHA256
+	}
+
+	tree, err := newMerkleTree(algorithm, args.Trans)
 	if err != nil {
 		return Block{}, err
 	}
@@ -104,22 +188,20 @@ func POW(ctx context.Context, args POWArgs) (Block, error) {
 	// Construct the block to be mined.
 	block := Block{
 		Header: BlockHeader{
+			Version:       args.Version,
 			Number:        args.PrevBlock.Header.Number + 1,
 			PrevBlockHash: prevBlockHash,
-			TimeStamp:     uint64(time.Now().UTC().UnixMilli()),
+			TimeStamp:     uint64(clock.OrSystem(args.Clock).Now().UTC().UnixMilli()),
 			BeneficiaryID: args.BeneficiaryID,
 			Difficulty:    args.Difficulty,
 			MiningReward:  args.MiningReward,
 			StateRoot:     args.StateRoot,
 			TransRoot:     tree.RootHex(),
 			Nonce:         0, // Will be identified by the POW algorithm.
+			ExtraData:     args.ExtraData,
 		},
 		MerkleTree: tree,
-	}
-
-	// Perform the POW algorithm to find the nonce that solves the hash puzzle.
-	if err := block.performPOW(ctx, args.EvHandler); err != nil {
-		return Block{}, err
+		algorithm:  algorithm,
 	}
 
 	return block, nil
@@ -192,43 +274,102 @@ func (b Block) Hash() string {
 	//   to follow the latest set of blocks being produced. The do not validate
 	//   blocks, but can prove a transaction is in a block.
 
-	return signature.Hash(b.Header)
+	return signature.HashWith(b.HashAlgorithm(), b.Header)
+}
+
+// HashAlgorithm returns the algorithm the block is hashed with. Chains
+// created before the hash algorithm became configurable use SHA-256, so that
+// is used for a block that wasn't built for a chain.
+func (b Block) HashAlgorithm() string {
+	if b.algorithm == "" {
+		return signature.HashSHA256
+	}
+
+	return b.algorithm
+}
+
+// HashWith returns the unique hash for the Block using the specified hash
+// algorithm. This is used by a process, like an external miner, that
+// doesn't have the genesis to know the algorithm in effect at the block.
+func (b Block) HashWith(algorithm string) string {
+	if b.Header.Number == 0 {
+		return signature.ZeroHash
+	}
+
+	return signature.HashWith(algorithm, b.Header)
+}
+
+// IsSolved reports whether the nonce in the header solves the hash puzzle
+// for the block's difficulty.
+func (b Block) IsSolved() bool {
+	return isHashSolved(b.Header.Difficulty, b.Hash())
+}
+
+// IsSolvedWith reports whether the nonce in the header solves the hash
+// puzzle for the block's difficulty when hashed with the algorithm.
+func (b Block) IsSolvedWith(algorithm string) bool {
+	return isHashSolved(b.Header.Difficulty, b.HashWith(algorithm))
 }
 
-// ValidateBlock takes a block and validates it to be included into the blockchain.
-func (b Block) ValidateBlock(previousBlock Block, stateRoot string, evHandler func(v string, args ...any)) error {
+// ValidateBlock takes a block and validates it to be included into the blockchain
+// under the chain rules in effect at the block's height. The check the block
+// fails is reported as a *BlockError.
+func (b Block) ValidateBlock(previousBlock Block, stateRoot string, rules genesis.Rules, evHandler func(v string, args ...any)) error {
 	evHandler("database: ValidateBlock: validate: blk[%d]: check: chain is not forked", b.Header.Number)
 
 	// The node that sent this block has a chain that is two or more blocks
 	// ahead. This means there has been a fork and this node id on the wrong side.
 	nextNumber := previousBlock.Header.Number + 1
 	if b.Header.Number >= (nextNumber + 2) {
-		return ErrChainForked
+		return newBlockError(CheckFork, b.Header.Number, nextNumber, ErrChainForked)
+	}
+
+	evHandler("database: ValidateBlock: validate: blk[%d]: check: header version matches the chain rules", b.Header.Number)
+
+	if b.Header.Version != rules.HeaderVersion {
+		return newBlockError(CheckVersion, b.Header.Version, rules.HeaderVersion, fmt.Errorf("block header version does not match the chain rules, got %d, expected %d", b.Header.Version, rules.HeaderVersion))
 	}
 
 	evHandler("database: ValidateBlock: validate: blk[%d]: check: block difficulty is the same or greater than parent", b.Header.Number)
 
-	if b.Header.Difficulty < previousBlock.Header.Difficulty {
-		return fmt.Errorf("block difficulty is less than previous block, parent[%d]: block[%d]", previousBlock.Header.Difficulty, b.Header.Difficulty)
+	// An upgrade can lower the difficulty below the parent's difficulty.
+	minDifficulty := previousBlock.Header.Difficulty
+	if rules.Difficulty < minDifficulty {
+		minDifficulty = rules.Difficulty
+	}
+	if b.Header.Difficulty < minDifficulty {
+		return newBlockError(CheckDifficulty, b.Header.Difficulty, minDifficulty, fmt.Errorf("block difficulty is less than previous block, parent[%d]: block[%d]", previousBlock.Header.Difficulty, b.Header.Difficulty))
+	}
+
+	evHandler("database: ValidateBlock: validate: blk[%d]: check: mining reward matches the chain rules", b.Header.Number)
+
+	if b.Header.MiningReward != rules.MiningReward {
+		return newBlockError(CheckMiningReward, b.Header.MiningReward, rules.MiningReward, fmt.Errorf("block mining reward does not match the chain rules, got %d, expected %d", b.Header.MiningReward, rules.MiningReward))
+	}
+
+	evHandler("database: ValidateBlock: validate: blk[%d]: check: extra data is within the size limit", b.Header.Number)
+
+	if len(b.Header.ExtraData) > MaxExtraData {
+		return newBlockError(CheckExtraData, len(b.Header.ExtraData), MaxExtraData, fmt.Errorf("block extra data is %d bytes, can't be more than %d", len(b.Header.ExtraData), MaxExtraData))
 	}
 
 	evHandler("database: ValidateBlock: validate: blk[%d]: check: block has been solved", b.Header.Number)
 
 	hash := b.Hash()
 	if !isHashSolved(b.Header.Difficulty, hash) {
-		return fmt.Errorf("Invalid block hash, block[%d]: hash[%s]", b.Header.Number, hash)
+		return newBlockError(CheckSolved, hash, fmt.Sprintf("difficulty %d", b.Header.Difficulty), fmt.Errorf("Invalid block hash, block[%d]: hash[%s]", b.Header.Number, hash))
 	}
 
 	evHandler("database: ValidateBlock: validate: blk[%d]: check: block number is the next number", b.Header.Number)
 
 	if b.Header.Number != nextNumber {
-		return fmt.Errorf("this block is not the next block in the chain. Got %d, expected %d", b.Header.Number, nextNumber)
+		return newBlockError(CheckNumber, b.Header.Number, nextNumber, fmt.Errorf("this block is not the next block in the chain. Got %d, expected %d", b.Header.Number, nextNumber))
 	}
 
 	evHandler("database: ValidateBlock: validate: blk[%d]: check: parent hash matches parent block", b.Header.Number)
 
 	if b.Header.PrevBlockHash != previousBlock.Hash() {
-		return fmt.Errorf("parent block hash does not match our known parent block. Got %s, expected: %s", b.Header.PrevBlockHash, previousBlock.Hash())
+		return newBlockError(CheckPrevHash, b.Header.PrevBlockHash, previousBlock.Hash(), fmt.Errorf("parent block hash does not match our known parent block. Got %s, expected: %s", b.Header.PrevBlockHash, previousBlock.Hash()))
 	}
 
 	if previousBlock.Header.TimeStamp > 0 {
@@ -237,7 +378,7 @@ func (b Block) ValidateBlock(previousBlock Block, stateRoot string, evHandler fu
 		parentTime := time.Unix(int64(previousBlock.Header.TimeStamp), 0)
 		blockTime := time.Unix(int64(b.Header.TimeStamp), 0)
 		if blockTime.Before(parentTime) {
-			return fmt.Errorf("block's timestamp %s is less than the previous block %s.", blockTime, parentTime)
+			return newBlockError(CheckTimestamp, b.Header.TimeStamp, previousBlock.Header.TimeStamp, fmt.Errorf("block's timestamp %s is less than the previous block %s.", blockTime, parentTime))
 		}
 
 		// This is a check that Ethereum does, but we can't because of run time.
@@ -252,18 +393,31 @@ func (b Block) ValidateBlock(previousBlock Block, stateRoot string, evHandler fu
 	evHandler("database: ValidateBlock: validate: blk[%d]: check: state root hash does match current database", b.Header.Number)
 
 	if b.Header.StateRoot != stateRoot {
-		return fmt.Errorf("state of the accounts are incorrect. got: %s, expected: %s", b.Header.StateRoot, stateRoot)
+		return newBlockError(CheckStateRoot, b.Header.StateRoot, stateRoot, fmt.Errorf("state of the accounts are incorrect. got: %s, expected: %s", b.Header.StateRoot, stateRoot))
 	}
 
 	evHandler("database: ValidateBlock: validate: blk[%d]: check: merkle root hash matches the transactions", b.Header.Number)
 
 	if b.Header.TransRoot != b.MerkleTree.RootHex() {
-		return fmt.Errorf("merkle root does not match transactions. got: %s, expected: %s", b.MerkleTree.RootHex(), b.Header.TransRoot)
+		return newBlockError(CheckMerkleRoot, b.Header.TransRoot, b.MerkleTree.RootHex(), fmt.Errorf("merkle root does not match transactions. got: %s, expected: %s", b.MerkleTree.RootHex(), b.Header.TransRoot))
 	}
 
 	return nil
 }
 
+// newMerkleTree constructs a merkle tree for the transactions hashing the
+// transactions and the tree with the same algorithm.
+func newMerkleTree(algorithm string, trans []BlockTx) (*merkle.Tree[BlockTx], error) {
+	leafHash := func(tx BlockTx) ([]byte, error) {
+		return tx.HashWith(algorithm)
+	}
+
+	return merkle.NewTree(trans,
+		merkle.WithHashStrategy[BlockTx](signature.HashStrategy(algorithm)),
+		merkle.WithLeafHash[BlockTx](leafHash),
+	)
+}
+
 // isHashSolved checks the hash to make sure it complies with
 // the POW rules. We need to match a difficulty number of 0's.
 func isHashSolved(difficulty uint16, hash string) bool {
@@ -0,0 +1,199 @@
+package database_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/clock"
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+	"github.com/qcbit/blockchain/foundation/blockchain/genesis"
+	"github.com/qcbit/blockchain/foundation/blockchain/signature"
+)
+
+func Test_BlockTimestamps(t *testing.T) {
+	start := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewManual(start)
+	ev := func(v string, args ...any) {}
+
+	mine := func(prevBlock database.Block) database.Block {
+		block, err := database.POW(context.Background(), database.POWArgs{
+			Difficulty: 1,
+			PrevBlock:  prevBlock,
+			Trans:      []database.BlockTx{database.NewBlockTxAt(signedTx(t), 15, 1, clk.Now())},
+			EvHandler:  ev,
+			Clock:      clk,
+		})
+		if err != nil {
+			t.Fatalf("mining block: %v", err)
+		}
+		return block
+	}
+
+	rules := genesis.Rules{Difficulty: 1}
+
+	parent := mine(database.Block{})
+	if got, exp := parent.Header.TimeStamp, uint64(start.UnixMilli()); got != exp {
+		t.Fatalf("block should be stamped by the clock: got %d, exp %d", got, exp)
+	}
+
+	clk.Advance(time.Minute)
+	if err := mine(parent).ValidateBlock(parent, "", rules, ev); err != nil {
+		t.Fatalf("block mined after its parent should validate: %v", err)
+	}
+
+	clk.Set(start.Add(-time.Hour))
+	if err := mine(parent).ValidateBlock(parent, "", rules, ev); err == nil {
+		t.Fatal("block mined before its parent should not validate")
+	}
+}
+
+func Test_BlockExtraData(t *testing.T) {
+	ev := func(v string, args ...any) {}
+	rules := genesis.Rules{Difficulty: 1}
+
+	args := database.POWArgs{
+		Difficulty: 1,
+		Trans:      []database.BlockTx{database.NewBlockTx(signedTx(t), 15, 1)},
+		ExtraData:  "pool-1",
+		EvHandler:  ev,
+	}
+
+	block, err := database.POW(context.Background(), args)
+	if err != nil {
+		t.Fatalf("mining block: %v", err)
+	}
+	if err := block.ValidateBlock(database.Block{}, "", rules, ev); err != nil {
+		t.Fatalf("tagged block should validate: %v", err)
+	}
+
+	// The extra data is covered by the hash so it can't be changed.
+	tampered := block
+	tampered.Header.ExtraData = "pool-2"
+	if tampered.Hash() == block.Hash() {
+		t.Fatal("extra data should be covered by the block hash")
+	}
+
+	args.ExtraData = strings.Repeat("x", database.MaxExtraData+1)
+	if _, err := database.NewBlock(args); err == nil {
+		t.Fatal("block with too much extra data should not be built")
+	}
+
+	block.Header.ExtraData = args.ExtraData
+	if err := block.ValidateBlock(database.Block{}, "", rules, ev); err == nil {
+		t.Fatal("block with too much extra data should not validate")
+	}
+}
+
+func Test_BlockVersion(t *testing.T) {
+	ev := func(v string, args ...any) {}
+
+	args := database.POWArgs{
+		Difficulty: 1,
+		Trans:      []database.BlockTx{database.NewBlockTx(signedTx(t), 15, 1)},
+		EvHandler:  ev,
+	}
+
+	original, err := database.NewBlock(args)
+	if err != nil {
+		t.Fatalf("building block: %v", err)
+	}
+
+	// A version 0 header encodes as it did before versioning existed.
+	data, err := json.Marshal(original.Header)
+	if err != nil {
+		t.Fatalf("marshaling header: %v", err)
+	}
+	if strings.Contains(string(data), `"version"`) {
+		t.Fatalf("version 0 header should leave the version out: got %s", data)
+	}
+
+	args.Version = genesis.HeaderVersion1
+	block, err := database.POW(context.Background(), args)
+	if err != nil {
+		t.Fatalf("mining block: %v", err)
+	}
+
+	if err := block.ValidateBlock(database.Block{}, "", genesis.Rules{Difficulty: 1, HeaderVersion: genesis.HeaderVersion1}, ev); err != nil {
+		t.Fatalf("block with the expected version should validate: %v", err)
+	}
+
+	// The version is covered by the hash so it can't be changed.
+	tampered := block
+	tampered.Header.Version = genesis.HeaderVersion0
+	if tampered.Hash() == block.Hash() {
+		t.Fatal("version should be covered by the block hash")
+	}
+
+	err = block.ValidateBlock(database.Block{}, "", genesis.Rules{Difficulty: 1}, ev)
+
+	var blockErr *database.BlockError
+	if !errors.As(err, &blockErr) || blockErr.Check != database.CheckVersion {
+		t.Fatalf("block with another version should fail the version check: got %v", err)
+	}
+}
+
+func Test_BlockError(t *testing.T) {
+	ev := func(v string, args ...any) {}
+	rules := genesis.Rules{Difficulty: 1}
+
+	block, err := database.POW(context.Background(), database.POWArgs{
+		Difficulty: 1,
+		StateRoot:  "mined",
+		Trans:      []database.BlockTx{database.NewBlockTx(signedTx(t), 15, 1)},
+		EvHandler:  ev,
+	})
+	if err != nil {
+		t.Fatalf("mining block: %v", err)
+	}
+
+	err = block.ValidateBlock(database.Block{}, "expected", rules, ev)
+
+	var blockErr *database.BlockError
+	if !errors.As(err, &blockErr) {
+		t.Fatalf("failed check should be reported as a block error: got %v", err)
+	}
+
+	exp := database.BlockError{Check: database.CheckStateRoot, Got: "mined", Expected: "expected"}
+	if blockErr.Check != exp.Check || blockErr.Got != exp.Got || blockErr.Expected != exp.Expected {
+		t.Fatalf("block error: got %+v, exp %+v", *blockErr, exp)
+	}
+}
+
+func Fuzz_ToBlock(f *testing.F) {
+	blockData := database.BlockData{
+		Header: database.BlockHeader{
+			Number:     1,
+			Difficulty: 1,
+		},
+		Trans: []database.BlockTx{database.NewBlockTx(signedTx(f), 15, 1)},
+	}
+	data, err := json.Marshal(blockData)
+	if err != nil {
+		f.Fatalf("marshaling block: %v", err)
+	}
+	f.Add(data)
+	f.Add([]byte(`{"hash":"","block":{"number":1},"trans":[]}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var blockData database.BlockData
+		if err := json.Unmarshal(data, &blockData); err != nil {
+			return
+		}
+
+		block, err := database.ToBlock(signature.HashSHA256, blockData)
+		if err != nil {
+			return
+		}
+
+		// Validation must reject bad input without panicking.
+		ev := func(v string, args ...any) {}
+		block.ValidateBlock(database.Block{}, "", genesis.Rules{Difficulty: 1}, ev)
+		for _, tx := range block.MerkleTree.Values() {
+			tx.Validate(1)
+		}
+	})
+}
@@ -0,0 +1,107 @@
+package database
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/signature"
+)
+
+// Checkpoint represents the accounts as they were after a block so a light
+// client can start from the block instead of replaying the chain from
+// genesis. The state root is the hash of the accounts. The block and the
+// accounts are hashed with the algorithm in effect at the block.
+type Checkpoint struct {
+	ChainID       uint16      `json:"chain_id"`
+	HashAlgorithm string      `json:"hash_algorithm"`
+	Header        BlockHeader `json:"header"`
+	BlockHash     string      `json:"block_hash"`
+	StateRoot     string      `json:"state_root"`
+	Accounts      []Account   `json:"accounts"`
+}
+
+// NewCheckpoint constructs the checkpoint of the accounts as they were after
+// the block, hashed with the specified algorithm.
+func NewCheckpoint(chainID uint16, algorithm string, block Block, accounts map[AccountID]Account) Checkpoint {
+	cp := Checkpoint{
+		ChainID:       chainID,
+		HashAlgorithm: algorithm,
+		Header:        block.Header,
+		BlockHash:     block.HashWith(algorithm),
+		StateRoot:     hashAccounts(algorithm, accounts),
+		Accounts:      make([]Account, 0, len(accounts)),
+	}
+
+	for _, account := range accounts {
+		cp.Accounts = append(cp.Accounts, account)
+	}
+	sort.Sort(byAccount(cp.Accounts))
+
+	return cp
+}
+
+// Sign signs the checkpoint with the private key of the node serving it.
+func (cp Checkpoint) Sign(privateKey *ecdsa.PrivateKey) (SignedCheckpoint, error) {
+	v, r, s, err := signature.Sign(cp, privateKey)
+	if err != nil {
+		return SignedCheckpoint{}, err
+	}
+
+	return SignedCheckpoint{
+		Checkpoint: cp,
+		V:          v,
+		R:          r,
+		S:          s,
+	}, nil
+}
+
+// SignedCheckpoint represents a checkpoint signed by the node serving it.
+// A light client only trusts a checkpoint signed by a node it trusts.
+type SignedCheckpoint struct {
+	Checkpoint
+	V *big.Int `json:"v"`
+	R *big.Int `json:"r"`
+	S *big.Int `json:"s"`
+}
+
+// Verify checks the checkpoint is consistent and returns the account of the
+// node that signed it. The block hash must match the header and the state
+// root must match the accounts.
+func (scp SignedCheckpoint) Verify() (AccountID, error) {
+	if err := signature.VerifySignature(scp.V, scp.R, scp.S); err != nil {
+		return "", fmt.Errorf("invalid signature: %w", err)
+	}
+
+	address, err := signature.FromAddress(scp.Checkpoint, scp.V, scp.R, scp.S)
+	if err != nil {
+		return "", fmt.Errorf("failed to get address: %w", err)
+	}
+
+	if !signature.IsHashAlgorithm(scp.HashAlgorithm) {
+		return "", fmt.Errorf("hash algorithm %q does not exist", scp.HashAlgorithm)
+	}
+
+	if hash := (Block{Header: scp.Header}).HashWith(scp.HashAlgorithm); hash != scp.BlockHash {
+		return "", fmt.Errorf("block hash does not match the header, got %s, expected %s", scp.BlockHash, hash)
+	}
+
+	accounts := make(map[AccountID]Account, len(scp.Accounts))
+	for _, account := range scp.Accounts {
+		if _, exists := accounts[account.AccountID]; exists {
+			return "", fmt.Errorf("account %s is listed twice", account.AccountID)
+		}
+		accounts[account.AccountID] = account
+	}
+	if len(accounts) == 0 {
+		return "", errors.New("checkpoint has no accounts")
+	}
+
+	if root := hashAccounts(scp.HashAlgorithm, accounts); root != scp.StateRoot {
+		return "", fmt.Errorf("state root does not match the accounts, got %s, expected %s", scp.StateRoot, root)
+	}
+
+	return AccountID(address), nil
+}
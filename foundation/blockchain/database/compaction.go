@@ -0,0 +1,42 @@
+package database
+
+// Compactor interface represents the behavior storage can implement to report
+// the space the chain uses and to reclaim it.
+type Compactor interface {
+	Usage(latestBlock uint64) (Usage, error)
+	Compact(latestBlock uint64, retention Retention, progress func(done, total uint64)) (Compaction, error)
+}
+
+// Usage represents the space the chain uses in storage broken down by
+// component. Stale files are files the chain doesn't reach, like blocks
+// written past a gap or temporary files left behind by a crash.
+type Usage struct {
+	Blocks     int64 `json:"blocks"`
+	BlockFiles int   `json:"block_files"`
+	Stale      int64 `json:"stale"`
+	StaleFiles int   `json:"stale_files"`
+	Total      int64 `json:"total"`
+	Offloaded  int   `json:"offloaded"` // Blocks moved to a cold store.
+}
+
+// Retention decides what compaction leaves untouched. The latest blocks are
+// kept in a human readable format since those are the ones looked at by hand.
+// Storage with a cold tier keeps the latest blocks locally and moves the
+// rest to it. The database only keeps the history of the latest blocks in
+// memory.
+type Retention struct {
+	ReadableBlocks uint64 // Number of latest blocks not compacted.
+	LocalBlocks    uint64 // Number of latest blocks kept out of the cold tier, zero keeps all.
+	HistoryBlocks  uint64 // Number of latest blocks with diffs, receipts and indexed transactions in memory, zero keeps all.
+}
+
+// Compaction represents the outcome of compacting storage.
+type Compaction struct {
+	Compacted   int    `json:"compacted"`
+	Pruned      int    `json:"pruned"`
+	Before      int64  `json:"before"`
+	After       int64  `json:"after"`
+	Reclaimed   int64  `json:"reclaimed"`
+	Offloaded   int    `json:"offloaded"`
+	LatestBlock uint64 `json:"latest_block"`
+}
@@ -15,16 +15,22 @@ import (
 
 // Storage interface represents the behavior required to be implemented by
 // any package providing support for reading and writing the blockchain.
+// ForEachFrom starts at the specified block and ForEach at block 1.
 type Storage interface {
 	Write(blockData BlockData) error
 	GetBlock(num uint64) (BlockData, error)
 	ForEach() Iterator
+	ForEachFrom(num uint64) Iterator
 	Close() error
 	Reset() error
 }
 
 // Iterator interface represents the behavior required to be implemented
-// by any package providing support to iterate over the blocks.
+// by any package providing support to iterate over the blocks. Iterators
+// can be used while blocks are written. An iterator should stop at the
+// block that was latest when it was created, and reading a block must
+// return either the old or the new copy of a block being written, never
+// a partial one.
 type Iterator interface {
 	Next() (BlockData, error)
 	Done() bool
@@ -36,15 +42,61 @@ type Database struct {
 	genesis     genesis.Genesis
 	latestBlock Block
 	accounts    map[AccountID]Account
+	diffs       map[uint64][]AccountDiff
+	receipts    map[string]Receipt
+	txIndex     map[AccountID][]indexedTx // Mined transactions each account took part in.
+	archive     map[AccountID]archivedTxs // Totals of the indexed transactions that left the history.
+	history     uint64                    // Number of latest blocks with history in memory, zero keeps all.
+	undo        undo
 	storage     Storage
+	pending     *ChainSnapshot // Snapshot loaded on startup that hasn't been audited.
 }
 
 // New constructs a new database and applies account genesis information.
 // It reads/writes the blockchain database on disk if a dbPath is provided.
-func New(genesis genesis.Genesis, storage Storage, evHandler func(v string, args ...any)) (*Database, error) {
+func New(genesis genesis.Genesis, storage Storage, evHandler func(v string, args ...any), options ...Option) (*Database, error) {
+	if err := useGenesis(genesis); err != nil {
+		return nil, err
+	}
+
+	db, err := newDatabase(genesis, storage, evHandler)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, option := range options {
+		option(db)
+	}
+
+	// Read all the blocks from storage and apply them to the accounts.
+	if err := db.replay(0, evHandler); err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// useGenesis checks the hash algorithms of the chain described by the
+// genesis exist before any block, transaction or state hashing takes place.
+func useGenesis(genesis genesis.Genesis) error {
+	if !signature.IsHashAlgorithm(genesis.HashAlgorithm()) {
+		return fmt.Errorf("hash algorithm %q does not exist", genesis.HashAlgorithm())
+	}
+
+	// Hash blocks with the algorithm in effect at their height.
+	return genesis.ValidateUpgrades()
+}
+
+// newDatabase constructs a database holding the accounts of the genesis
+// block without any blocks applied.
+func newDatabase(genesis genesis.Genesis, storage Storage, evHandler func(v string, args ...any)) (*Database, error) {
 	db := Database{
 		genesis:  genesis,
 		accounts: make(map[AccountID]Account),
+		diffs:    make(map[uint64][]AccountDiff),
+		receipts: make(map[string]Receipt),
+		txIndex:  make(map[AccountID][]indexedTx),
+		archive:  make(map[AccountID]archivedTxs),
 		storage:  storage,
 	}
 
@@ -59,31 +111,68 @@ func New(genesis genesis.Genesis, storage Storage, evHandler func(v string, args
 		evHandler("Account: %s, Balance: %d", accountID, balance)
 	}
 
-	// Read all the blocks from storage.
-	iter := db.ForEach()
-	for block, err := iter.Next(); !iter.Done(); block, err = iter.Next() {
+	// Lock the parts of the genesis balances that vest over time.
+	for accountStr, locks := range genesis.Locks {
+		accountID, err := ToAccountID(accountStr)
 		if err != nil {
 			return nil, err
 		}
 
-		// Validate the block values and cryptographic audit trail.
-		if err := block.ValidateBlock(db.latestBlock, db.HashState(), evHandler); err != nil {
-			return nil, err
+		account, exists := db.accounts[accountID]
+		if !exists {
+			return nil, fmt.Errorf("locks for account %s without a balance", accountID)
 		}
 
-		// Update the database with the transaction information.
-		for _, tx := range block.MerkleTree.Values() {
-			db.ApplyTransaction(block, tx)
+		var locked uint64
+		for _, lock := range locks {
+			account.Locks = append(account.Locks, Lock{Amount: lock.Amount, UntilBlock: lock.UntilBlock})
+			locked += lock.Amount
 		}
-		db.ApplyMiningReward(block)
+		if locked > account.Balance {
+			return nil, fmt.Errorf("locks for account %s are more than its balance", accountID)
+		}
+		db.accounts[accountID] = account
 
-		// Update the current latest block.
-		db.latestBlock = block
+		evHandler("Account: %s, Locks: %v", accountID, account.Locks)
 	}
 
 	return &db, nil
 }
 
+// Reset clears the chain in storage and puts the accounts back to the
+// balances of the genesis block, as if the node was starting a new chain.
+// The accounts that existed before the reset are returned.
+func (db *Database) Reset(evHandler func(v string, args ...any)) ([]AccountID, error) {
+	fresh, err := newDatabase(db.genesis, db.storage, evHandler)
+	if err != nil {
+		return nil, err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if err := db.storage.Reset(); err != nil {
+		return nil, fmt.Errorf("clearing chain: %w", err)
+	}
+
+	accounts := make([]AccountID, 0, len(db.accounts))
+	for accountID := range db.accounts {
+		accounts = append(accounts, accountID)
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i] < accounts[j] })
+
+	db.latestBlock = Block{}
+	db.accounts = fresh.accounts
+	db.diffs = fresh.diffs
+	db.receipts = fresh.receipts
+	db.txIndex = fresh.txIndex
+	db.archive = fresh.archive
+	db.undo = undo{}
+	db.pending = nil
+
+	return accounts, nil
+}
+
 // Write adds a new block to the chain.
 func (db *Database) Write(block Block) error {
 	return db.storage.Write(NewBlockData(block))
@@ -92,7 +181,32 @@ func (db *Database) Write(block Block) error {
 // ForEach returns an iterator to walk through all
 // the blocks starting from the genesis block.
 func (db *Database) ForEach() DatabaseIterator {
-	return DatabaseIterator{iterator: db.storage.ForEach()}
+	return DatabaseIterator{db: db, iterator: db.storage.ForEach()}
+}
+
+// ForEachFrom returns an iterator to walk through the chain as of the
+// latest block starting from the specified block. Blocks accepted after
+// the iterator is created aren't returned, and if the chain is rolled back
+// under the iterator it fails with ErrChainChanged instead of mixing blocks
+// from both chains.
+func (db *Database) ForEachFrom(num uint64) DatabaseIterator {
+	return db.iterate(num, db.LatestBlock().Header.Number)
+}
+
+// iterate returns an iterator to walk through the blocks from the specified
+// block through the specified block.
+func (db *Database) iterate(from uint64, through uint64) DatabaseIterator {
+	if from == 0 {
+		from = 1
+	}
+
+	return DatabaseIterator{
+		db:       db,
+		iterator: db.storage.ForEachFrom(from),
+		bounded:  true,
+		next:     from,
+		through:  through,
+	}
 }
 
 // Remove removes an account from the database.
@@ -116,6 +230,23 @@ func (db *Database) Query(accountID AccountID) (Account, error) {
 	return account, nil
 }
 
+// QueryAccounts returns the specified accounts that exist together with the
+// latest block they're current for. The accounts are read at once so they
+// all reflect the same block.
+func (db *Database) QueryAccounts(accountIDs []AccountID) (Block, map[AccountID]Account) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	accounts := make(map[AccountID]Account, len(accountIDs))
+	for _, accountID := range accountIDs {
+		if account, exists := db.accounts[accountID]; exists {
+			accounts[accountID] = account
+		}
+	}
+
+	return db.latestBlock, accounts
+}
+
 // Copy makes a copy of the current accounts in the database.
 func (db *Database) Copy() map[AccountID]Account {
 	db.mu.RLock()
@@ -143,19 +274,24 @@ func (db *Database) LatestBlock() Block {
 }
 
 // HashState returns a hash based on the contents of the accounts and
-// their balances. This is added to each block and checked by peers.
+// their balances. This is added to each block and checked by peers, so
+// it's hashed with the algorithm in effect for the block after the latest.
 func (db *Database) HashState() string {
-	accounts := make([]Account, 0, len(db.accounts))
 	db.mu.RLock()
-	{
-		for _, account := range db.accounts {
-			accounts = append(accounts, account)
-		}
+	defer db.mu.RUnlock()
+
+	return hashAccounts(db.HashAlgorithm(db.latestBlock.Header.Number+1), db.accounts)
+}
+
+// hashAccounts returns a hash of the accounts sorted by account ID.
+func hashAccounts(algorithm string, accountMap map[AccountID]Account) string {
+	accounts := make([]Account, 0, len(accountMap))
+	for _, account := range accountMap {
+		accounts = append(accounts, account)
 	}
-	db.mu.RUnlock()
 
 	sort.Sort(byAccount(accounts))
-	return signature.Hash(accounts)
+	return signature.HashWith(algorithm, accounts)
 }
 
 // ApplyMiningReward gives the specified account the mining reward.
@@ -169,69 +305,189 @@ func (db *Database) ApplyMiningReward(block Block) {
 	db.accounts[block.Header.BeneficiaryID] = account
 }
 
-// ApplyTransaction performs the business logic for applying a transaction to the database.
-func (db *Database) ApplyTransaction(block Block, tx BlockTx) error {
+// ApplyTransaction performs the business logic for applying a transaction to
+// the database. A receipt is recorded with the outcome.
+func (db *Database) ApplyTransaction(block Block, tx BlockTx) (err error) {
 	db.mu.Lock()
 	defer db.mu.Unlock()
 
-	// Capture these accounts from the database.
-	from, exists := db.accounts[tx.FromID]
-	if !exists {
-		from = newAccount(tx.FromID, 0)
+	var gasFee uint64
+	defer func() {
+		db.recordReceipt(block, tx, gasFee, err)
+		db.indexTx(block, tx, gasFee, err)
+	}()
+
+	// Nothing is taken from a frozen account, not even the gas fee.
+	if db.accounts[tx.FromID].Frozen {
+		return fmt.Errorf("account %s is frozen", tx.FromID)
 	}
 
-	to, exists := db.accounts[tx.ToID]
-	if !exists {
-		to = newAccount(tx.ToID, 0)
+	// An expired transaction isn't applied at all so its nonce stays free.
+	if tx.Expired(block.Header.Number) {
+		return fmt.Errorf("transaction expired at block %d", tx.ValidUntilBlock)
 	}
 
-	bnfc, exists := db.accounts[block.Header.BeneficiaryID]
-	if !exists {
-		bnfc = newAccount(block.Header.BeneficiaryID, 0)
+	// A sponsor is only charged for a transaction it signed.
+	if tx.Sponsor != nil {
+		if err := tx.ValidateSponsor(); err != nil {
+			return err
+		}
+
+		if db.accounts[tx.Sponsor.AccountID].Frozen {
+			return fmt.Errorf("sponsor account %s is frozen", tx.Sponsor.AccountID)
+		}
+	}
+
+	// CORE NOTE: The from, to and beneficiary accounts can be the same account,
+	// like a miner sending to someone in a block they mined. Every change is
+	// applied to the accounts in the database as it happens so no change is
+	// lost by writing back a stale copy of the same account. Blocks before
+	// the shared accounts upgrade are applied the way they were mined.
+	if !db.genesis.RulesAt(block.Header.Number).SharedAccounts {
+		gasFee, err = db.applyCopies(block, tx)
+		return err
 	}
 
 	// The account needs to pay the gas fee regardless. Take the
-	// remaining balance if the account doesn't hold enough for the
-	// full amount of gas. This is the only way to stop bad actors.
+	// remaining spendable balance if the account doesn't hold enough
+	// for the full amount of gas. This is the only way to stop bad actors.
+	// The sponsor of a sponsored transaction pays in place of the sender.
+	// Expired locks are dropped whenever the account pays for a transaction.
+	payerID := tx.GasPayer()
+	payer := db.account(payerID).unlock(block.Header.Number)
+	gasFee = tx.GasPrice * tx.GasUnits
+	if spendable := payer.Spendable(block.Header.Number); gasFee > spendable {
+		gasFee = spendable
+	}
+	payer.Balance -= gasFee
+	db.accounts[payerID] = payer
+	db.credit(block.Header.BeneficiaryID, gasFee)
+
+	// Perform basic accounting checks. Locked funds can't be spent.
+	from := db.account(tx.FromID).unlock(block.Header.Number)
+	spendable := from.Spendable(block.Header.Number)
+	{
+		if tx.Nonce != (from.Nonce + 1) {
+			return fmt.Errorf("invalid transaction nonce: got %d, expected %d", tx.Nonce, from.Nonce+1)
+		}
+
+		if spendable == 0 || spendable < (tx.Value+tx.Tip) {
+			return fmt.Errorf("invalid transaction, insufficient funds: spendable %d, needed %d", spendable, (tx.Value + tx.Tip))
+		}
+
+		if err := db.ValidateGovernance(tx.Tx); err != nil {
+			return fmt.Errorf("invalid governance transaction: %w", err)
+		}
+	}
+
+	// Take the value and tip from the account and update the nonce
+	// for the next transaction check.
+	from.Balance -= tx.Value + tx.Tip
+	from.Nonce = tx.Nonce
+	db.accounts[tx.FromID] = from
+
+	// Update the balances between the two parties.
+	db.credit(tx.ToID, tx.Value)
+
+	// A vesting transfer locks the value in the recipient account.
+	if tx.LockUntil > block.Header.Number && tx.Value > 0 {
+		to := db.account(tx.ToID)
+		to.Locks = append(to.Locks, Lock{Amount: tx.Value, UntilBlock: tx.LockUntil})
+		db.accounts[tx.ToID] = to
+	}
+
+	// Give the beneficiary the tip.
+	db.credit(block.Header.BeneficiaryID, tx.Tip)
+
+	if tx.Governance != nil {
+		db.applyGovernance(*tx.Governance)
+	}
+
+	return nil
+}
+
+// applyCopies applies the transaction to copies of the from, to and
+// beneficiary accounts taken up front, writing each copy back in turn. When
+// the same account is used in more than one role the copy written last wins
+// and the other changes are lost. This is how blocks were applied before the
+// shared accounts upgrade, so it must not change. The caller must hold the
+// lock.
+func (db *Database) applyCopies(block Block, tx BlockTx) (uint64, error) {
+	// Capture these accounts from the database before anything is written.
+	payerID := tx.GasPayer()
+	payer := db.account(payerID).unlock(block.Header.Number)
+	from := db.account(tx.FromID).unlock(block.Header.Number)
+	to := db.account(tx.ToID)
+	bnfc := db.account(block.Header.BeneficiaryID)
+
 	gasFee := tx.GasPrice * tx.GasUnits
-	if gasFee > from.Balance {
-		gasFee = from.Balance
+	if spendable := payer.Spendable(block.Header.Number); gasFee > spendable {
+		gasFee = spendable
 	}
-	from.Balance -= gasFee
+	payer.Balance -= gasFee
 	bnfc.Balance += gasFee
 
-	// Make sure these changes get applied.
-	db.accounts[tx.FromID] = from
+	db.accounts[payerID] = payer
 	db.accounts[block.Header.BeneficiaryID] = bnfc
 
-	// Perform basic accounting checks.
+	// The sender pays its own gas out of the same copy.
+	if payerID == tx.FromID {
+		from = payer
+	}
+
+	spendable := from.Spendable(block.Header.Number)
 	{
 		if tx.Nonce != (from.Nonce + 1) {
-			return fmt.Errorf("invalid transaction nonce: got %d, expected %d", tx.Nonce, from.Nonce+1)
+			return gasFee, fmt.Errorf("invalid transaction nonce: got %d, expected %d", tx.Nonce, from.Nonce+1)
 		}
 
-		if from.Balance == 0 || from.Balance < (tx.Value+tx.Tip) {
-			return fmt.Errorf("invalid transaction, insufficient funds: balance %d, needed %d", from.Balance, (tx.Value + tx.Tip))
+		if spendable == 0 || spendable < (tx.Value+tx.Tip) {
+			return gasFee, fmt.Errorf("invalid transaction, insufficient funds: spendable %d, needed %d", spendable, (tx.Value + tx.Tip))
+		}
+
+		if err := db.ValidateGovernance(tx.Tx); err != nil {
+			return gasFee, fmt.Errorf("invalid governance transaction: %w", err)
 		}
 	}
 
-	// Update the balances between the two parties.
 	from.Balance -= tx.Value
 	to.Balance += tx.Value
+	if tx.LockUntil > block.Header.Number && tx.Value > 0 {
+		to.Locks = append(to.Locks, Lock{Amount: tx.Value, UntilBlock: tx.LockUntil})
+	}
 
-	// Give the beneficiary the tip.
 	from.Balance -= tx.Tip
 	bnfc.Balance += tx.Tip
 
-	// Update the nonce for the next transaction check.
 	from.Nonce = tx.Nonce
 
-	// Update the final changes to these accounts.
 	db.accounts[tx.FromID] = from
 	db.accounts[tx.ToID] = to
 	db.accounts[block.Header.BeneficiaryID] = bnfc
 
-	return nil
+	if tx.Governance != nil {
+		db.applyGovernance(*tx.Governance)
+	}
+
+	return gasFee, nil
+}
+
+// account returns the account from the database or a new account with no
+// balance if it doesn't exist yet. The caller must hold the lock.
+func (db *Database) account(accountID AccountID) Account {
+	account, exists := db.accounts[accountID]
+	if !exists {
+		account = newAccount(accountID, 0)
+	}
+	return account
+}
+
+// credit adds the amount to the account's balance. The caller must
+// hold the lock.
+func (db *Database) credit(accountID AccountID, amount uint64) {
+	account := db.account(accountID)
+	account.Balance += amount
+	db.accounts[accountID] = account
 }
 
 // GetBlock searches the blockchain on disk to locate and
@@ -242,28 +498,59 @@ func (db *Database) GetBlock(num uint64) (Block, error) {
 		return Block{}, err
 	}
 
-	return ToBlock(blockData)
+	return db.ToBlock(blockData)
 }
 
 //-----------------------------------------------------------------------------
 
+// ErrChainChanged is returned by an iterator when the chain it was walking
+// was rolled back and replaced by a competing block.
+var ErrChainChanged = errors.New("chain changed while iterating")
+
 // DatabaseIterator provides support for iterating over the blocks
 // in the blockchain database using the configured storage option.
 type DatabaseIterator struct {
+	db       *Database
 	iterator Iterator
+	bounded  bool   // Stop after the through block.
+	next     uint64 // Number of the next block returned.
+	through  uint64 // Latest block when the iterator was created.
+	prevHash string // Hash of the block returned last.
+	eoc      bool
 }
 
 // Next retrieves the next block from disk.
 func (di *DatabaseIterator) Next() (Block, error) {
+	if di.eoc {
+		return Block{}, errors.New("end of chain")
+	}
+
+	if di.bounded && di.next > di.through {
+		di.eoc = true
+		return Block{}, nil
+	}
+
 	blockData, err := di.iterator.Next()
 	if err != nil {
 		return Block{}, err
 	}
+	if di.iterator.Done() {
+		di.eoc = true
+		return Block{}, nil
+	}
+
+	if di.bounded {
+		if blockData.Header.Number != di.next || (di.prevHash != "" && blockData.Header.PrevBlockHash != di.prevHash) {
+			return Block{}, fmt.Errorf("block %d: %w", di.next, ErrChainChanged)
+		}
+		di.prevHash = blockData.Hash
+		di.next++
+	}
 
-	return ToBlock(blockData)
+	return di.db.ToBlock(blockData)
 }
 
 // Done returns the end of the chain value.
 func (di *DatabaseIterator) Done() bool {
-	return di.iterator.Done()
+	return di.eoc || di.iterator.Done()
 }
@@ -0,0 +1,1482 @@
+package database_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+	"github.com/qcbit/blockchain/foundation/blockchain/genesis"
+	"github.com/qcbit/blockchain/foundation/blockchain/signature"
+)
+
+// The parameters for the chain the transaction sequences are applied to.
+const (
+	startBalance = 1_000_000
+	miningReward = 50
+	gasPrice     = 15
+	transPerBlk  = 3
+	opSize       = 5
+	maxOps       = 30
+)
+
+// Fuzz_ApplyTransaction applies sequences of transactions built from the
+// fuzz input and checks the invariants of the account state hold after
+// every transaction and that replaying the chain from storage reproduces
+// the same state.
+func Fuzz_ApplyTransaction(f *testing.F) {
+	f.Add([]byte{0, 1, 10, 1, 1})
+	f.Add([]byte{0, 1, 10, 1, 1, 1, 2, 200, 5, 1, 2, 0, 255, 9, 0})
+	f.Add([]byte{0, 1, 255, 255, 1, 0, 1, 255, 255, 1, 0, 1, 255, 255, 1, 0, 1, 255, 255, 1})
+	f.Add([]byte{2, 2, 0, 0, 8, 1, 1, 0, 0, 16, 0, 0, 0, 0, 24})
+
+	keys := make([]*ecdsa.PrivateKey, 3)
+	for i := range keys {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			f.Fatalf("generating key: %v", err)
+		}
+		keys[i] = key
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if len(data) > maxOps*opSize {
+			data = data[:maxOps*opSize]
+		}
+
+		gen := genesis.Genesis{
+			Date:          time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC),
+			Version:       genesis.ChainVersion2,
+			ChainID:       1,
+			TransPerBlock: transPerBlk,
+			Difficulty:    1,
+			MiningReward:  miningReward,
+			GasPrice:      gasPrice,
+			Balances:      make(map[string]uint64),
+			Upgrades:      []genesis.Upgrade{{Height: 1, SharedAccounts: true}},
+		}
+
+		ids := make([]database.AccountID, len(keys))
+		for i, key := range keys {
+			ids[i] = database.PublicKeyToAccountID(key.PublicKey)
+			gen.Balances[string(ids[i])] = startBalance
+		}
+		beneficiary := ids[0]
+
+		ev := func(v string, args ...any) {}
+		storage := newMemStorage()
+
+		db, err := database.New(gen, storage, ev)
+		if err != nil {
+			t.Fatalf("constructing database: %v", err)
+		}
+
+		supply := uint64(len(keys) * startBalance)
+
+		// Build the transactions from the fuzz input, grouping them into blocks.
+		var trans []database.BlockTx
+		for i := 0; i+opSize <= len(data); i += opSize {
+			op := data[i : i+opSize]
+
+			from := int(op[0]) % len(keys)
+			to := int(op[1]) % len(keys)
+			if to == from {
+				to = (from + 1) % len(keys)
+			}
+
+			account, _ := db.Query(ids[from])
+			nonce := account.Nonce + 1
+			for _, tx := range trans {
+				if tx.FromID == ids[from] {
+					nonce++
+				}
+			}
+
+			// Every eighth operation uses a nonce that is out of order.
+			if op[4]%8 == 0 {
+				nonce += 2
+			}
+
+			tx, err := database.NewTx(gen.ChainID, ids[from], ids[to], uint64(op[2])*10_000, nonce, uint64(op[3]), nil)
+			if err != nil {
+				t.Fatalf("constructing tx: %v", err)
+			}
+
+			signedTx, err := tx.Sign(keys[from])
+			if err != nil {
+				t.Fatalf("signing tx: %v", err)
+			}
+
+			trans = append(trans, database.NewBlockTx(signedTx, gen.GasPrice, 1))
+
+			if len(trans) == transPerBlk || i+2*opSize > len(data) {
+				mineBlock(t, db, beneficiary, trans)
+				supply += miningReward
+				trans = nil
+			}
+
+			checkInvariants(t, db, supply)
+		}
+
+		// Replaying the chain from storage must produce the same state.
+		replay, err := database.New(gen, storage, ev)
+		if err != nil {
+			t.Fatalf("replaying chain: %v", err)
+		}
+
+		if got, exp := replay.HashState(), db.HashState(); got != exp {
+			t.Fatalf("replay should produce the same state root: got %s, exp %s", got, exp)
+		}
+
+		if got, exp := replay.LatestBlock().Hash(), db.LatestBlock().Hash(); got != exp {
+			t.Fatalf("replay should produce the same latest block: got %s, exp %s", got, exp)
+		}
+	})
+}
+
+func Test_Vesting(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 3)
+	ids := make([]database.AccountID, len(keys))
+	for i := range keys {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("generating key: %v", err)
+		}
+		keys[i] = key
+		ids[i] = database.PublicKeyToAccountID(key.PublicKey)
+	}
+	owner, recipient, beneficiary := ids[0], ids[1], ids[2]
+
+	gen := genesis.Genesis{
+		Date:          time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Version:       genesis.ChainVersion2,
+		ChainID:       1,
+		TransPerBlock: transPerBlk,
+		Difficulty:    1,
+		MiningReward:  miningReward,
+		GasPrice:      gasPrice,
+		Balances:      map[string]uint64{string(owner): 1_000},
+		Locks:         map[string][]genesis.Lock{string(owner): {{Amount: 900, UntilBlock: 3}}},
+	}
+
+	db, err := database.New(gen, newMemStorage(), func(v string, args ...any) {})
+	if err != nil {
+		t.Fatalf("constructing database: %v", err)
+	}
+
+	send := func(nonce uint64, value uint64, lockUntil uint64) database.BlockTx {
+		tx, err := database.NewTx(gen.ChainID, owner, recipient, value, nonce, 0, nil)
+		if err != nil {
+			t.Fatalf("constructing tx: %v", err)
+		}
+		tx.LockUntil = lockUntil
+
+		signedTx, err := tx.Sign(keys[0])
+		if err != nil {
+			t.Fatalf("signing tx: %v", err)
+		}
+		return database.NewBlockTx(signedTx, gen.GasPrice, 1)
+	}
+
+	// Block 1: only the unlocked 100 can be spent, less the gas.
+	mineBlock(t, db, beneficiary, []database.BlockTx{send(1, 500, 0)})
+
+	account, _ := db.Query(owner)
+	if account.Nonce != 0 || account.Balance != 1_000-gasPrice {
+		t.Fatalf("spend of locked funds should fail: nonce %d, balance %d", account.Nonce, account.Balance)
+	}
+	if got, exp := account.Spendable(2), uint64(100-gasPrice); got != exp {
+		t.Fatalf("spendable should exclude the locked funds: got %d, exp %d", got, exp)
+	}
+
+	// Block 2: a vesting transfer locks the value in the recipient account.
+	mineBlock(t, db, beneficiary, []database.BlockTx{send(1, 50, 10)})
+
+	account, _ = db.Query(recipient)
+	if account.Balance != 50 || account.Locked(3) != 50 || account.Locked(10) != 0 {
+		t.Fatalf("vesting transfer should lock the value until block 10: balance %d, locks %v", account.Balance, account.Locks)
+	}
+
+	// Block 3: the genesis lock has expired so the funds can be spent.
+	mineBlock(t, db, beneficiary, []database.BlockTx{send(2, 500, 0)})
+
+	account, _ = db.Query(owner)
+	if account.Nonce != 2 || len(account.Locks) != 0 {
+		t.Fatalf("spend of vested funds should succeed: nonce %d, locks %v", account.Nonce, account.Locks)
+	}
+}
+
+func Test_Governance(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 4)
+	ids := make([]database.AccountID, len(keys))
+	for i := range keys {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("generating key: %v", err)
+		}
+		keys[i] = key
+		ids[i] = database.PublicKeyToAccountID(key.PublicKey)
+	}
+	sender, suspect, outsider := ids[0], ids[3], ids[2]
+
+	gen := genesis.Genesis{
+		Date:          time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Version:       genesis.ChainVersion2,
+		ChainID:       1,
+		TransPerBlock: transPerBlk,
+		Difficulty:    1,
+		MiningReward:  miningReward,
+		GasPrice:      gasPrice,
+		Balances:      map[string]uint64{string(sender): 1_000, string(suspect): 1_000},
+		Validators:    []string{string(ids[0]), string(ids[1])},
+	}
+
+	db, err := database.New(gen, newMemStorage(), func(v string, args ...any) {})
+	if err != nil {
+		t.Fatalf("constructing database: %v", err)
+	}
+
+	freeze := func(signers ...int) database.BlockTx {
+		tx, err := database.NewTx(gen.ChainID, sender, suspect, 0, 1, 0, nil)
+		if err != nil {
+			t.Fatalf("constructing tx: %v", err)
+		}
+
+		proposal, err := database.NewProposal(gen.ChainID, sender, 1, database.GovernanceFreeze, suspect)
+		if err != nil {
+			t.Fatalf("constructing proposal: %v", err)
+		}
+
+		gov := database.Governance{Action: database.GovernanceFreeze, AccountID: suspect}
+		for _, i := range signers {
+			approval, err := proposal.Approve(keys[i])
+			if err != nil {
+				t.Fatalf("approving proposal: %v", err)
+			}
+			gov.Approvals = append(gov.Approvals, approval)
+		}
+		tx.Governance = &gov
+
+		signedTx, err := tx.Sign(keys[0])
+		if err != nil {
+			t.Fatalf("signing tx: %v", err)
+		}
+		return database.NewBlockTx(signedTx, gen.GasPrice, 1)
+	}
+
+	if err := db.ValidateGovernance(freeze(0, 2).Tx); err == nil {
+		t.Fatal("approval from a non validator should be rejected")
+	}
+
+	// Both validators must approve, the same validator twice isn't enough.
+	mineBlock(t, db, outsider, []database.BlockTx{freeze(0, 0)})
+	if db.IsFrozen(suspect) {
+		t.Fatal("account should not be frozen without a quorum")
+	}
+
+	root := db.HashState()
+	mineBlock(t, db, outsider, []database.BlockTx{freeze(0, 1)})
+	if !db.IsFrozen(suspect) {
+		t.Fatal("account should be frozen with a quorum")
+	}
+	if frozen := db.Frozen(); len(frozen) != 1 || frozen[0] != suspect {
+		t.Fatalf("frozen accounts should list the account: got %v", frozen)
+	}
+	if db.HashState() == root {
+		t.Fatal("frozen set should be part of the state root")
+	}
+
+	// Nothing can leave a frozen account, not even the gas fee.
+	tx, err := database.NewTx(gen.ChainID, suspect, sender, 100, 1, 0, nil)
+	if err != nil {
+		t.Fatalf("constructing tx: %v", err)
+	}
+	signedTx, err := tx.Sign(keys[3])
+	if err != nil {
+		t.Fatalf("signing tx: %v", err)
+	}
+	mineBlock(t, db, outsider, []database.BlockTx{database.NewBlockTx(signedTx, gen.GasPrice, 1)})
+
+	account, _ := db.Query(suspect)
+	if account.Nonce != 0 || account.Balance != 1_000 {
+		t.Fatalf("frozen account should be untouched: nonce %d, balance %d", account.Nonce, account.Balance)
+	}
+}
+
+func Test_Upgrades(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	sender := database.PublicKeyToAccountID(key.PublicKey)
+
+	reward := uint64(10)
+	gen := genesis.Genesis{
+		Date:          time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Version:       genesis.ChainVersion1,
+		ChainID:       1,
+		TransPerBlock: transPerBlk,
+		Difficulty:    1,
+		MiningReward:  miningReward,
+		GasPrice:      gasPrice,
+		Balances:      map[string]uint64{string(sender): 1_000},
+		Upgrades: []genesis.Upgrade{
+			{Height: 1, SharedAccounts: true},
+			{Height: 3, MiningReward: &reward, HashAlgorithm: signature.HashKeccak256},
+		},
+	}
+
+	storage := newMemStorage()
+	db, err := database.New(gen, storage, func(v string, args ...any) {})
+	if err != nil {
+		t.Fatalf("constructing database: %v", err)
+	}
+
+	var hashes []string
+	for nonce := uint64(1); nonce <= 4; nonce++ {
+		tx, err := database.NewTx(gen.ChainID, sender, "0xbEE6ACE826eC3DE1B6349888B9151B92522F7F76", 10, nonce, 0, nil)
+		if err != nil {
+			t.Fatalf("constructing tx: %v", err)
+		}
+		signedTx, err := tx.Sign(key)
+		if err != nil {
+			t.Fatalf("signing tx: %v", err)
+		}
+
+		mineBlock(t, db, sender, []database.BlockTx{database.NewBlockTx(signedTx, gasPrice, 1)})
+		hashes = append(hashes, db.LatestBlock().Hash())
+	}
+
+	if got := db.HashAlgorithm(db.LatestBlock().Header.Number); got != signature.HashKeccak256 {
+		t.Fatalf("hash algorithm should switch at the upgrade height: got %s", got)
+	}
+
+	if got := db.LatestBlock().Header.MiningReward; got != reward {
+		t.Fatalf("mining reward should change at the upgrade height: got %d, exp %d", got, reward)
+	}
+
+	// Block headers keep the hash they were mined with.
+	block, err := db.GetBlock(2)
+	if err != nil {
+		t.Fatalf("getting block: %v", err)
+	}
+	if block.Hash() != hashes[1] {
+		t.Fatalf("block before the upgrade should keep its hash: got %s, exp %s", block.Hash(), hashes[1])
+	}
+
+	// Replaying the chain must validate every block under its own rules.
+	replay, err := database.New(gen, storage, func(v string, args ...any) {})
+	if err != nil {
+		t.Fatalf("replaying chain: %v", err)
+	}
+
+	if got, exp := replay.HashState(), db.HashState(); got != exp {
+		t.Fatalf("replay should produce the same state root: got %s, exp %s", got, exp)
+	}
+
+	// Another chain in the same process hashes with its own schedule and
+	// leaves the hashes of this chain alone.
+	other := gen
+	other.Version = genesis.ChainVersion2
+	other.Upgrades = nil
+	if _, err := database.New(other, newMemStorage(), func(v string, args ...any) {}); err != nil {
+		t.Fatalf("constructing other database: %v", err)
+	}
+	if block, err = db.GetBlock(2); err != nil || block.Hash() != hashes[1] {
+		t.Fatalf("block should keep its hash when another chain is built: got %s, exp %s", block.Hash(), hashes[1])
+	}
+
+	// A block paying the old reward after the upgrade is rejected.
+	stale := db.LatestBlock()
+	stale.Header.MiningReward = miningReward
+	if err := stale.ValidateBlock(block, "", db.Rules(stale.Header.Number), func(v string, args ...any) {}); err == nil {
+		t.Fatal("block with the old mining reward should not validate")
+	}
+}
+
+func Test_SharedAccounts(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	miner := database.PublicKeyToAccountID(key.PublicKey)
+	recipient := database.AccountID("0xbEE6ACE826eC3DE1B6349888B9151B92522F7F76")
+
+	gen := genesis.Genesis{
+		Date:          time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Version:       genesis.ChainVersion2,
+		ChainID:       1,
+		TransPerBlock: transPerBlk,
+		Difficulty:    1,
+		MiningReward:  miningReward,
+		GasPrice:      gasPrice,
+		Balances:      map[string]uint64{string(miner): 1_000},
+		Upgrades:      []genesis.Upgrade{{Height: 2, SharedAccounts: true}},
+	}
+
+	storage := newMemStorage()
+	db, err := database.New(gen, storage, func(v string, args ...any) {})
+	if err != nil {
+		t.Fatalf("constructing database: %v", err)
+	}
+
+	send := func(to database.AccountID, nonce uint64) database.BlockTx {
+		tx, err := database.NewTx(gen.ChainID, miner, to, 100, nonce, 5, nil)
+		if err != nil {
+			t.Fatalf("constructing tx: %v", err)
+		}
+		signedTx, err := tx.Sign(key)
+		if err != nil {
+			t.Fatalf("signing tx: %v", err)
+		}
+		return database.NewBlockTx(signedTx, gasPrice, 1)
+	}
+
+	// Before the upgrade a self-send writes the recipient's copy last, so
+	// the account is only credited the value and the nonce is never taken.
+	block := database.Block{Header: database.BlockHeader{Number: 1, BeneficiaryID: recipient}}
+	if err := db.ApplyTransaction(block, send(miner, 1)); err != nil {
+		t.Fatalf("applying tx: %v", err)
+	}
+	account, _ := db.Query(miner)
+	if account.Balance != 1_000+100 || account.Nonce != 0 {
+		t.Fatalf("self-send before the upgrade should apply as mined: balance %d, nonce %d", account.Balance, account.Nonce)
+	}
+
+	// The miner sends from the account receiving the block's fees. Before
+	// the upgrade the beneficiary's copy is written last, so the miner keeps
+	// the value and the nonce is never taken.
+	block.Header.BeneficiaryID = miner
+	if err := db.ApplyTransaction(block, send(recipient, 1)); err != nil {
+		t.Fatalf("applying tx: %v", err)
+	}
+	account, _ = db.Query(miner)
+	if account.Balance != 1_100+gasPrice+5 || account.Nonce != 0 {
+		t.Fatalf("block before the upgrade should apply as mined: balance %d, nonce %d", account.Balance, account.Nonce)
+	}
+
+	// From the upgrade every change to the shared account is kept.
+	block.Header.Number = 2
+	if err := db.ApplyTransaction(block, send(recipient, 1)); err != nil {
+		t.Fatalf("applying tx: %v", err)
+	}
+	account, _ = db.Query(miner)
+	if account.Balance != 1_100+gasPrice+5-100 || account.Nonce != 1 {
+		t.Fatalf("block after the upgrade should keep every change: balance %d, nonce %d", account.Balance, account.Nonce)
+	}
+}
+
+func Test_Expiration(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 2)
+	ids := make([]database.AccountID, len(keys))
+	for i := range keys {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("generating key: %v", err)
+		}
+		keys[i] = key
+		ids[i] = database.PublicKeyToAccountID(key.PublicKey)
+	}
+	sender, beneficiary := ids[0], ids[1]
+
+	gen := genesis.Genesis{
+		Date:          time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Version:       genesis.ChainVersion2,
+		ChainID:       1,
+		TransPerBlock: transPerBlk,
+		Difficulty:    1,
+		MiningReward:  miningReward,
+		GasPrice:      gasPrice,
+		Balances:      map[string]uint64{string(sender): 1_000},
+	}
+
+	db, err := database.New(gen, newMemStorage(), func(v string, args ...any) {})
+	if err != nil {
+		t.Fatalf("constructing database: %v", err)
+	}
+
+	send := func(nonce uint64, validUntil uint64) database.BlockTx {
+		tx, err := database.NewTx(gen.ChainID, sender, beneficiary, 100, nonce, 0, nil)
+		if err != nil {
+			t.Fatalf("constructing tx: %v", err)
+		}
+		tx.ValidUntilBlock = validUntil
+
+		signedTx, err := tx.Sign(keys[0])
+		if err != nil {
+			t.Fatalf("signing tx: %v", err)
+		}
+		return database.NewBlockTx(signedTx, gen.GasPrice, 1)
+	}
+
+	// Block 1: the transaction is still valid in its last block.
+	mineBlock(t, db, beneficiary, []database.BlockTx{send(1, 1)})
+
+	account, _ := db.Query(sender)
+	if account.Nonce != 1 || account.Balance != 1_000-100-gasPrice {
+		t.Fatalf("transaction valid until block 1 should be applied: nonce %d, balance %d", account.Nonce, account.Balance)
+	}
+
+	// Block 2: an expired transaction isn't applied and costs no gas.
+	expired := send(2, 1)
+	mineBlock(t, db, beneficiary, []database.BlockTx{expired})
+
+	account, _ = db.Query(sender)
+	if account.Nonce != 1 || account.Balance != 1_000-100-gasPrice {
+		t.Fatalf("expired transaction should not be applied: nonce %d, balance %d", account.Nonce, account.Balance)
+	}
+	if receipt, _ := db.Receipt(expired.TxHash(signature.HashKeccak256)); receipt.Status != database.ReceiptFailed {
+		t.Fatalf("expired transaction should fail, got %q", receipt.Status)
+	}
+
+	// Block 3: the nonce of the expired transaction is used again.
+	mineBlock(t, db, beneficiary, []database.BlockTx{send(2, 0)})
+
+	account, _ = db.Query(sender)
+	if account.Nonce != 2 {
+		t.Fatalf("nonce of the expired transaction should be reused: nonce %d", account.Nonce)
+	}
+}
+
+func Test_Sponsor(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 4)
+	ids := make([]database.AccountID, len(keys))
+	for i := range keys {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("generating key: %v", err)
+		}
+		keys[i] = key
+		ids[i] = database.PublicKeyToAccountID(key.PublicKey)
+	}
+	sender, sponsor, recipient, beneficiary := ids[0], ids[1], ids[2], ids[3]
+
+	gen := genesis.Genesis{
+		Date:          time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Version:       genesis.ChainVersion2,
+		ChainID:       1,
+		TransPerBlock: transPerBlk,
+		Difficulty:    1,
+		MiningReward:  miningReward,
+		GasPrice:      gasPrice,
+		Balances:      map[string]uint64{string(sender): 100, string(sponsor): 1_000},
+	}
+
+	db, err := database.New(gen, newMemStorage(), func(v string, args ...any) {})
+	if err != nil {
+		t.Fatalf("constructing database: %v", err)
+	}
+
+	send := func(nonce uint64, sponsorKey *ecdsa.PrivateKey) database.BlockTx {
+		tx, err := database.NewTx(gen.ChainID, sender, recipient, 100, nonce, 0, nil)
+		if err != nil {
+			t.Fatalf("constructing tx: %v", err)
+		}
+		tx.Sponsor = &database.Sponsor{AccountID: sponsor}
+
+		// Sign as the sponsor, or forge the sponsor signature.
+		sig, err := tx.SponsorSign(keys[1])
+		if err != nil {
+			t.Fatalf("sponsor signing tx: %v", err)
+		}
+		if sponsorKey != keys[1] {
+			forged := tx
+			forged.Sponsor = &database.Sponsor{AccountID: database.PublicKeyToAccountID(sponsorKey.PublicKey)}
+			if sig, err = forged.SponsorSign(sponsorKey); err != nil {
+				t.Fatalf("forging sponsor signature: %v", err)
+			}
+		}
+		tx.Sponsor.Signature = sig
+
+		signedTx, err := tx.Sign(keys[0])
+		if err != nil {
+			t.Fatalf("signing tx: %v", err)
+		}
+		return database.NewBlockTx(signedTx, gen.GasPrice, 1)
+	}
+
+	// A forged sponsor signature is rejected and nobody pays for it.
+	forged := send(1, keys[2])
+	if err := forged.Validate(gen.ChainID); err == nil {
+		t.Fatal("forged sponsor signature should not validate")
+	}
+	mineBlock(t, db, beneficiary, []database.BlockTx{forged})
+
+	if account, _ := db.Query(sponsor); account.Balance != 1_000 {
+		t.Fatalf("sponsor should not pay for a forged signature: balance %d", account.Balance)
+	}
+
+	// The sponsor pays the gas fee and the sender only the value.
+	sponsored := send(1, keys[1])
+	if err := sponsored.Validate(gen.ChainID); err != nil {
+		t.Fatalf("sponsored tx should validate: %v", err)
+	}
+	mineBlock(t, db, beneficiary, []database.BlockTx{sponsored})
+
+	account, _ := db.Query(sender)
+	if account.Nonce != 1 || account.Balance != 0 {
+		t.Fatalf("sender should only pay the value: nonce %d, balance %d", account.Nonce, account.Balance)
+	}
+	if account, _ := db.Query(sponsor); account.Balance != 1_000-gasPrice {
+		t.Fatalf("sponsor should pay the gas fee: balance %d", account.Balance)
+	}
+	if account, _ := db.Query(recipient); account.Balance != 100 {
+		t.Fatalf("recipient should receive the value: balance %d", account.Balance)
+	}
+}
+
+func Test_AccountsAt(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 3)
+	ids := make([]database.AccountID, len(keys))
+	for i := range keys {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("generating key: %v", err)
+		}
+		keys[i] = key
+		ids[i] = database.PublicKeyToAccountID(key.PublicKey)
+	}
+	sender, recipient, beneficiary := ids[0], ids[1], ids[2]
+
+	gen := genesis.Genesis{
+		Date:          time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Version:       genesis.ChainVersion2,
+		ChainID:       1,
+		TransPerBlock: transPerBlk,
+		Difficulty:    1,
+		MiningReward:  miningReward,
+		GasPrice:      gasPrice,
+		Balances:      map[string]uint64{string(sender): 1_000},
+	}
+
+	db, err := database.New(gen, newMemStorage(), func(v string, args ...any) {})
+	if err != nil {
+		t.Fatalf("constructing database: %v", err)
+	}
+
+	send := func(nonce uint64) database.BlockTx {
+		tx, err := database.NewTx(gen.ChainID, sender, recipient, 100, nonce, 0, nil)
+		if err != nil {
+			t.Fatalf("constructing tx: %v", err)
+		}
+
+		signedTx, err := tx.Sign(keys[0])
+		if err != nil {
+			t.Fatalf("signing tx: %v", err)
+		}
+		return database.NewBlockTx(signedTx, gen.GasPrice, 1)
+	}
+
+	mineBlock(t, db, beneficiary, []database.BlockTx{send(1)})
+	mineBlock(t, db, beneficiary, []database.BlockTx{send(2)})
+
+	tt := []struct {
+		block     uint64
+		sender    database.Account
+		recipient bool
+	}{
+		{0, database.Account{AccountID: sender, Balance: 1_000}, false},
+		{1, database.Account{AccountID: sender, Nonce: 1, Balance: 1_000 - 100 - gasPrice}, true},
+		{2, database.Account{AccountID: sender, Nonce: 2, Balance: 1_000 - 2*(100+gasPrice)}, true},
+	}
+
+	for _, tst := range tt {
+		accounts, err := db.AccountsAt(tst.block)
+		if err != nil {
+			t.Fatalf("block %d: querying accounts: %v", tst.block, err)
+		}
+
+		if got := accounts[sender]; got.Nonce != tst.sender.Nonce || got.Balance != tst.sender.Balance {
+			t.Fatalf("block %d: sender should be nonce %d, balance %d: got nonce %d, balance %d", tst.block, tst.sender.Nonce, tst.sender.Balance, got.Nonce, got.Balance)
+		}
+		if _, exists := accounts[recipient]; exists != tst.recipient {
+			t.Fatalf("block %d: recipient exists should be %t", tst.block, tst.recipient)
+		}
+	}
+
+	if _, err := db.AccountsAt(3); err == nil {
+		t.Fatal("accounts past the latest block should not be returned")
+	}
+
+	// Accounts queried together are current for the latest block and
+	// accounts that don't exist are left out.
+	unknown := database.AccountID("0xbEE6ACE826eC3DE1B6349888B9151B92522F7F76")
+	latest, accounts := db.QueryAccounts([]database.AccountID{sender, recipient, unknown})
+	if latest.Header.Number != 2 {
+		t.Fatalf("accounts should be current for block 2, got %d", latest.Header.Number)
+	}
+	if len(accounts) != 2 || accounts[sender].Nonce != 2 || accounts[recipient].Balance != 200 {
+		t.Fatalf("got accounts %+v", accounts)
+	}
+}
+
+func Test_ReplaySignatures(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 2)
+	ids := make([]database.AccountID, len(keys))
+	for i := range keys {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("generating key: %v", err)
+		}
+		keys[i] = key
+		ids[i] = database.PublicKeyToAccountID(key.PublicKey)
+	}
+	sender, recipient := ids[0], ids[1]
+
+	gen := genesis.Genesis{
+		Date:          time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Version:       genesis.ChainVersion2,
+		ChainID:       1,
+		TransPerBlock: transPerBlk,
+		Difficulty:    1,
+		MiningReward:  miningReward,
+		GasPrice:      gasPrice,
+		Balances:      map[string]uint64{string(sender): 1_000},
+	}
+
+	send := func(nonce uint64, key *ecdsa.PrivateKey) database.BlockTx {
+		tx, err := database.NewTx(gen.ChainID, sender, recipient, 100, nonce, 0, nil)
+		if err != nil {
+			t.Fatalf("constructing tx: %v", err)
+		}
+
+		signedTx, err := tx.Sign(key)
+		if err != nil {
+			t.Fatalf("signing tx: %v", err)
+		}
+		return database.NewBlockTx(signedTx, gen.GasPrice, 1)
+	}
+
+	storage := newMemStorage()
+	db, err := database.New(gen, storage, func(v string, args ...any) {})
+	if err != nil {
+		t.Fatalf("constructing database: %v", err)
+	}
+	mineBlock(t, db, recipient, []database.BlockTx{send(1, keys[0]), send(2, keys[0])})
+
+	if _, err := database.New(gen, storage, func(v string, args ...any) {}); err != nil {
+		t.Fatalf("replaying signed blocks: %v", err)
+	}
+
+	// The recipient signs a transfer out of the sender's account.
+	mineBlock(t, db, recipient, []database.BlockTx{send(3, keys[1])})
+
+	if _, err := database.New(gen, storage, func(v string, args ...any) {}); err == nil {
+		t.Fatal("replaying a block with a forged signature should fail")
+	}
+}
+
+func Test_Rollback(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 3)
+	ids := make([]database.AccountID, len(keys))
+	for i := range keys {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("generating key: %v", err)
+		}
+		keys[i] = key
+		ids[i] = database.PublicKeyToAccountID(key.PublicKey)
+	}
+	sender, recipient, beneficiary := ids[0], ids[1], ids[2]
+
+	gen := genesis.Genesis{
+		Date:          time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Version:       genesis.ChainVersion2,
+		ChainID:       1,
+		TransPerBlock: transPerBlk,
+		Difficulty:    1,
+		MiningReward:  miningReward,
+		GasPrice:      gasPrice,
+		Balances:      map[string]uint64{string(sender): 1_000},
+	}
+
+	db, err := database.New(gen, newMemStorage(), func(v string, args ...any) {})
+	if err != nil {
+		t.Fatalf("constructing database: %v", err)
+	}
+
+	send := func(nonce uint64) database.BlockTx {
+		tx, err := database.NewTx(gen.ChainID, sender, recipient, 100, nonce, 0, nil)
+		if err != nil {
+			t.Fatalf("constructing tx: %v", err)
+		}
+
+		signedTx, err := tx.Sign(keys[0])
+		if err != nil {
+			t.Fatalf("signing tx: %v", err)
+		}
+		return database.NewBlockTx(signedTx, gen.GasPrice, 1)
+	}
+
+	if _, err := db.ParentStateRoot(); err == nil {
+		t.Fatal("the genesis block should not be rolled back")
+	}
+
+	mineBlock(t, db, beneficiary, []database.BlockTx{send(1)})
+	parent := db.LatestBlock()
+	parentRoot := db.HashState()
+
+	tx := send(2)
+	mineBlock(t, db, beneficiary, []database.BlockTx{tx})
+
+	changed := db.ChangedAccounts(db.LatestBlock().Header.Number)
+	if len(changed) != 3 {
+		t.Fatalf("block should change the sender, recipient and beneficiary, got %v", changed)
+	}
+
+	root, err := db.ParentStateRoot()
+	if err != nil {
+		t.Fatalf("hashing the parent state: %v", err)
+	}
+	if root != parentRoot {
+		t.Fatalf("parent state root should match the state after the parent: got %s, exp %s", root, parentRoot)
+	}
+
+	if err := db.Rollback(database.Block{}); err == nil {
+		t.Fatal("rolling back to a block that isn't the parent should fail")
+	}
+
+	if err := db.Rollback(parent); err != nil {
+		t.Fatalf("rolling back: %v", err)
+	}
+
+	if got := db.LatestBlock().Hash(); got != parent.Hash() {
+		t.Fatalf("latest block should be the parent: got %s, exp %s", got, parent.Hash())
+	}
+	if got := db.HashState(); got != parentRoot {
+		t.Fatalf("state should match the parent: got %s, exp %s", got, parentRoot)
+	}
+	if _, exists := db.Receipt(tx.TxHash(signature.HashKeccak256)); exists {
+		t.Fatal("receipts of the rolled back block should be removed")
+	}
+
+	if err := db.Rollback(database.Block{}); err == nil {
+		t.Fatal("only the latest block should be rolled back once")
+	}
+}
+
+func Test_Reset(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 2)
+	ids := make([]database.AccountID, len(keys))
+	for i := range keys {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("generating key: %v", err)
+		}
+		keys[i] = key
+		ids[i] = database.PublicKeyToAccountID(key.PublicKey)
+	}
+	sender, beneficiary := ids[0], ids[1]
+
+	gen := genesis.Genesis{
+		Date:          time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Version:       genesis.ChainVersion2,
+		ChainID:       1,
+		TransPerBlock: transPerBlk,
+		Difficulty:    1,
+		MiningReward:  miningReward,
+		GasPrice:      gasPrice,
+		Balances:      map[string]uint64{string(sender): 1_000},
+	}
+
+	storage := newMemStorage()
+	db, err := database.New(gen, storage, func(v string, args ...any) {})
+	if err != nil {
+		t.Fatalf("constructing database: %v", err)
+	}
+	genesisRoot := db.HashState()
+
+	tx, err := database.NewTx(gen.ChainID, sender, beneficiary, 100, 1, 0, nil)
+	if err != nil {
+		t.Fatalf("constructing tx: %v", err)
+	}
+	signedTx, err := tx.Sign(keys[0])
+	if err != nil {
+		t.Fatalf("signing tx: %v", err)
+	}
+	blockTx := database.NewBlockTx(signedTx, gen.GasPrice, 1)
+	mineBlock(t, db, beneficiary, []database.BlockTx{blockTx})
+
+	accounts, err := db.Reset(func(v string, args ...any) {})
+	if err != nil {
+		t.Fatalf("resetting: %v", err)
+	}
+	if len(accounts) != 2 {
+		t.Fatalf("should return the accounts before the reset, got %v", accounts)
+	}
+
+	if db.LatestBlock().Header.Number != 0 {
+		t.Fatalf("latest block should be the genesis block, got %d", db.LatestBlock().Header.Number)
+	}
+	if got := db.HashState(); got != genesisRoot {
+		t.Fatalf("state should match the genesis: got %s, exp %s", got, genesisRoot)
+	}
+	if _, err := db.Query(beneficiary); err == nil {
+		t.Fatal("accounts created by the blocks should be removed")
+	}
+	if _, exists := db.Receipt(blockTx.TxHash(signature.HashKeccak256)); exists {
+		t.Fatal("receipts should be removed")
+	}
+	if _, err := storage.GetBlock(1); err == nil {
+		t.Fatal("blocks should be removed from storage")
+	}
+
+	// The same transaction can be mined again on the new chain.
+	mineBlock(t, db, beneficiary, []database.BlockTx{blockTx})
+	if account, _ := db.Query(sender); account.Nonce != 1 {
+		t.Fatalf("transaction should apply on the new chain, nonce %d", account.Nonce)
+	}
+}
+
+func Test_ForEachFrom(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	sender := database.PublicKeyToAccountID(key.PublicKey)
+
+	gen := genesis.Genesis{
+		Date:          time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC),
+		ChainID:       1,
+		TransPerBlock: transPerBlk,
+		Difficulty:    1,
+		MiningReward:  miningReward,
+		GasPrice:      gasPrice,
+		Balances:      map[string]uint64{string(sender): 1_000},
+		Upgrades:      []genesis.Upgrade{{Height: 1, SharedAccounts: true}},
+	}
+
+	storage := newMemStorage()
+	db, err := database.New(gen, storage, func(v string, args ...any) {})
+	if err != nil {
+		t.Fatalf("constructing database: %v", err)
+	}
+
+	var nonce uint64
+	mine := func() {
+		nonce++
+		tx, err := database.NewTx(gen.ChainID, sender, sender, 1, nonce, 0, nil)
+		if err != nil {
+			t.Fatalf("constructing tx: %v", err)
+		}
+		signedTx, err := tx.Sign(key)
+		if err != nil {
+			t.Fatalf("signing tx: %v", err)
+		}
+		mineBlock(t, db, sender, []database.BlockTx{database.NewBlockTx(signedTx, gen.GasPrice, 1)})
+	}
+
+	for i := 0; i < 3; i++ {
+		mine()
+	}
+
+	// Blocks accepted while iterating aren't returned.
+	var numbers []uint64
+	iter := db.ForEachFrom(2)
+	for block, err := iter.Next(); !iter.Done(); block, err = iter.Next() {
+		if err != nil {
+			t.Fatalf("iterating: %v", err)
+		}
+		numbers = append(numbers, block.Header.Number)
+
+		if block.Header.Number == 2 {
+			mine()
+		}
+	}
+	if fmt.Sprint(numbers) != "[2 3]" {
+		t.Fatalf("iterating should walk the chain as of the latest block, got %v", numbers)
+	}
+
+	iter = db.ForEachFrom(5)
+	if _, err := iter.Next(); err != nil || !iter.Done() {
+		t.Fatalf("iterating past the latest block should end the chain: %v", err)
+	}
+
+	// A block replaced under the iterator fails it.
+	iter = db.ForEachFrom(1)
+	if _, err := iter.Next(); err != nil {
+		t.Fatalf("iterating: %v", err)
+	}
+	storage.Write(database.BlockData{Hash: "0xother", Header: database.BlockHeader{Number: 2, PrevBlockHash: "0xother"}})
+	if _, err := iter.Next(); !errors.Is(err, database.ErrChainChanged) {
+		t.Fatalf("replaced block should fail the iterator, got %v", err)
+	}
+}
+
+func Test_Summary(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 3)
+	ids := make([]database.AccountID, len(keys))
+	for i := range keys {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("generating key: %v", err)
+		}
+		keys[i] = key
+		ids[i] = database.PublicKeyToAccountID(key.PublicKey)
+	}
+	sender, recipient, beneficiary := ids[0], ids[1], ids[2]
+
+	gen := genesis.Genesis{
+		Date:          time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Version:       genesis.ChainVersion2,
+		ChainID:       1,
+		TransPerBlock: transPerBlk,
+		Difficulty:    1,
+		MiningReward:  miningReward,
+		GasPrice:      gasPrice,
+		Balances:      map[string]uint64{string(sender): 1_000},
+	}
+
+	db, err := database.New(gen, newMemStorage(), func(v string, args ...any) {})
+	if err != nil {
+		t.Fatalf("constructing database: %v", err)
+	}
+
+	send := func(nonce uint64, tip uint64) database.BlockTx {
+		tx, err := database.NewTx(gen.ChainID, sender, recipient, 100, nonce, tip, nil)
+		if err != nil {
+			t.Fatalf("constructing tx: %v", err)
+		}
+
+		signedTx, err := tx.Sign(keys[0])
+		if err != nil {
+			t.Fatalf("signing tx: %v", err)
+		}
+		return database.NewBlockTx(signedTx, gen.GasPrice, 1)
+	}
+
+	// The transaction with the nonce gap fails but still pays the gas fee.
+	paid, gap := send(1, 5), send(5, 0)
+	mineBlock(t, db, beneficiary, []database.BlockTx{paid, gap})
+	parent := db.LatestBlock()
+
+	// The receipts record what the beneficiary was paid.
+	if receipt, _ := db.Receipt(paid.TxHash(signature.HashKeccak256)); receipt.GasFee != gasPrice || receipt.Tip != 5 {
+		t.Fatalf("receipt should record the gas fee and tip: got %+v", receipt)
+	}
+	if receipt, _ := db.Receipt(gap.TxHash(signature.HashKeccak256)); receipt.GasFee != gasPrice || receipt.Tip != 0 {
+		t.Fatalf("receipt of a failed tx should only record the gas fee: got %+v", receipt)
+	}
+
+	mineBlock(t, db, beneficiary, []database.BlockTx{send(2, 0)})
+	if got := db.Summary(sender); got.Transactions != 3 || got.LastBlock != 2 {
+		t.Fatalf("summary should include the latest block: got %+v", got)
+	}
+
+	if err := db.Rollback(parent); err != nil {
+		t.Fatalf("rolling back: %v", err)
+	}
+
+	tt := []struct {
+		name string
+		got  database.AccountSummary
+		exp  database.AccountSummary
+	}{
+		{"sender", db.Summary(sender), database.AccountSummary{AccountID: sender, Transactions: 2, TotalSent: 100, FeesPaid: 2*gasPrice + 5, FirstBlock: 1, LastBlock: 1, Counterparties: 1}},
+		{"recipient", db.Summary(recipient), database.AccountSummary{AccountID: recipient, Transactions: 1, TotalReceived: 100, FirstBlock: 1, LastBlock: 1, Counterparties: 1}},
+		{"beneficiary", db.Summary(beneficiary), database.AccountSummary{AccountID: beneficiary}},
+	}
+
+	for _, tst := range tt {
+		if tst.got != tst.exp {
+			t.Fatalf("%s: got %+v, exp %+v", tst.name, tst.got, tst.exp)
+		}
+	}
+}
+
+func Test_History(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	sender := database.PublicKeyToAccountID(key.PublicKey)
+	recipient := database.AccountID("0xbEE6ACE826eC3DE1B6349888B9151B92522F7F76")
+	beneficiary := database.AccountID("0xF01813E4B85e178A83e29B8E7bF26BD830a25f32")
+
+	gen := genesis.Genesis{
+		Date:          time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Version:       genesis.ChainVersion2,
+		ChainID:       1,
+		TransPerBlock: transPerBlk,
+		Difficulty:    1,
+		MiningReward:  miningReward,
+		GasPrice:      gasPrice,
+		Balances:      map[string]uint64{string(sender): 1_000},
+	}
+
+	ev := func(v string, args ...any) {}
+	all, err := database.New(gen, newMemStorage(), ev)
+	if err != nil {
+		t.Fatalf("constructing database: %v", err)
+	}
+	db, err := database.New(gen, newMemStorage(), ev, database.WithRetention(database.Retention{HistoryBlocks: 2}))
+	if err != nil {
+		t.Fatalf("constructing database: %v", err)
+	}
+
+	var first database.BlockTx
+	for nonce := uint64(1); nonce <= 4; nonce++ {
+		tx, err := database.NewTx(gen.ChainID, sender, recipient, 10, nonce, 1, nil)
+		if err != nil {
+			t.Fatalf("constructing tx: %v", err)
+		}
+		signedTx, err := tx.Sign(key)
+		if err != nil {
+			t.Fatalf("signing tx: %v", err)
+		}
+		blockTx := database.NewBlockTx(signedTx, gasPrice, 1)
+		if nonce == 1 {
+			first = blockTx
+		}
+
+		mineBlock(t, all, beneficiary, []database.BlockTx{blockTx})
+		mineBlock(t, db, beneficiary, []database.BlockTx{blockTx})
+	}
+
+	// Only the latest blocks keep their diffs and receipts.
+	if _, err := db.Diff(2); err == nil {
+		t.Fatal("diff of a block that left the history should be dropped")
+	}
+	if _, err := db.Diff(3); err != nil {
+		t.Fatalf("diff of a block in the history should be kept: %v", err)
+	}
+	if _, err := db.AccountsAt(2); err != nil {
+		t.Fatalf("accounts at the oldest block in the history: %v", err)
+	}
+	if _, exists := db.Receipt(first.TxHash(signature.HashKeccak256)); exists {
+		t.Fatal("receipt of a block that left the history should be dropped")
+	}
+
+	// The summaries still cover the whole chain.
+	for _, accountID := range []database.AccountID{sender, recipient, beneficiary} {
+		if got, exp := db.Summary(accountID), all.Summary(accountID); got != exp {
+			t.Fatalf("summary of %s should cover the whole chain: got %+v, exp %+v", accountID, got, exp)
+		}
+	}
+}
+
+func Test_Snapshot(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 2)
+	ids := make([]database.AccountID, len(keys))
+	for i := range keys {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("generating key: %v", err)
+		}
+		keys[i] = key
+		ids[i] = database.PublicKeyToAccountID(key.PublicKey)
+	}
+	sender, recipient := ids[0], ids[1]
+
+	gen := genesis.Genesis{
+		Date:          time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Version:       genesis.ChainVersion2,
+		ChainID:       1,
+		TransPerBlock: transPerBlk,
+		Difficulty:    1,
+		MiningReward:  miningReward,
+		GasPrice:      gasPrice,
+		Balances:      map[string]uint64{string(sender): 1_000},
+	}
+
+	send := func(nonce uint64) database.BlockTx {
+		tx, err := database.NewTx(gen.ChainID, sender, recipient, 100, nonce, 0, nil)
+		if err != nil {
+			t.Fatalf("constructing tx: %v", err)
+		}
+
+		signedTx, err := tx.Sign(keys[0])
+		if err != nil {
+			t.Fatalf("signing tx: %v", err)
+		}
+		return database.NewBlockTx(signedTx, gen.GasPrice, 1)
+	}
+
+	ev := func(v string, args ...any) {}
+	storage := newMemStorage()
+
+	// Without a snapshot the chain is replayed.
+	db, err := database.NewFromSnapshot(gen, storage, ev)
+	if err != nil {
+		t.Fatalf("constructing database: %v", err)
+	}
+	if !db.Validated() {
+		t.Fatal("a replayed chain should be validated")
+	}
+
+	first := send(1)
+	mineBlock(t, db, recipient, []database.BlockTx{first})
+	mineBlock(t, db, recipient, []database.BlockTx{send(2)})
+	if err := storage.WriteSnapshot(db.ChainSnapshot()); err != nil {
+		t.Fatalf("writing snapshot: %v", err)
+	}
+	mineBlock(t, db, recipient, []database.BlockTx{send(3)})
+
+	// The blocks after the snapshot are applied on startup and the
+	// blocks before it once the chain is audited.
+	lazy, err := database.NewFromSnapshot(gen, storage, ev)
+	if err != nil {
+		t.Fatalf("constructing database from snapshot: %v", err)
+	}
+	if lazy.Validated() {
+		t.Fatal("a chain loaded from a snapshot should not be validated")
+	}
+	if got, exp := lazy.HashState(), db.HashState(); got != exp {
+		t.Fatalf("state should match the replayed chain: got %s, exp %s", got, exp)
+	}
+	if _, exists := lazy.Receipt(first.TxHash(signature.HashKeccak256)); exists {
+		t.Fatal("receipts before the snapshot should wait for the audit")
+	}
+
+	if err := lazy.Audit(ev); err != nil {
+		t.Fatalf("auditing: %v", err)
+	}
+	if !lazy.Validated() {
+		t.Fatal("an audited chain should be validated")
+	}
+	if _, exists := lazy.Receipt(first.TxHash(signature.HashKeccak256)); !exists {
+		t.Fatal("receipts before the snapshot should be added by the audit")
+	}
+	if _, err := lazy.AccountsAt(0); err != nil {
+		t.Fatalf("accounts at genesis after the audit: %v", err)
+	}
+
+	// A snapshot that doesn't match the chain fails the audit.
+	snapshot := db.ChainSnapshot()
+	for i := range snapshot.Accounts {
+		snapshot.Accounts[i].Balance++
+	}
+	if err := storage.WriteSnapshot(snapshot); err != nil {
+		t.Fatalf("writing snapshot: %v", err)
+	}
+
+	forged, err := database.NewFromSnapshot(gen, storage, ev)
+	if err != nil {
+		t.Fatalf("constructing database from snapshot: %v", err)
+	}
+	if err := forged.Audit(ev); err == nil {
+		t.Fatal("auditing a forged snapshot should fail")
+	}
+	if forged.Validated() {
+		t.Fatal("a chain that fails the audit should not be validated")
+	}
+}
+
+// mineBlock mines a block for the transactions under the chain rules for
+// the next block, applies it to the database and writes it to storage.
+func mineBlock(t *testing.T, db *database.Database, beneficiary database.AccountID, trans []database.BlockTx) {
+	rules := db.Rules(db.LatestBlock().Header.Number + 1)
+
+	block, err := database.POW(context.Background(), database.POWArgs{
+		Version:       rules.HeaderVersion,
+		BeneficiaryID: beneficiary,
+		Difficulty:    rules.Difficulty,
+		MiningReward:  rules.MiningReward,
+		PrevBlock:     db.LatestBlock(),
+		StateRoot:     db.HashState(),
+		HashAlgorithm: rules.HashAlgorithm,
+		Trans:         trans,
+		EvHandler:     func(v string, args ...any) {},
+	})
+	if err != nil {
+		t.Fatalf("mining block: %v", err)
+	}
+
+	snapshot := db.Snapshot(block)
+	for _, tx := range block.MerkleTree.Values() {
+		before, _ := db.Query(tx.FromID)
+
+		err := db.ApplyTransaction(block, tx)
+
+		after, _ := db.Query(tx.FromID)
+		switch {
+		case after.Nonce < before.Nonce:
+			t.Fatalf("nonce should never go backwards: before %d, after %d", before.Nonce, after.Nonce)
+		case err == nil && after.Nonce != tx.Nonce:
+			t.Fatalf("applied tx should set the nonce: got %d, exp %d", after.Nonce, tx.Nonce)
+		case err != nil && after.Nonce != before.Nonce:
+			t.Fatalf("failed tx should not change the nonce: before %d, after %d", before.Nonce, after.Nonce)
+		}
+	}
+	db.ApplyMiningReward(block)
+	db.RecordDiff(block, snapshot)
+	db.UpdateLatestBlock(block)
+
+	if err := db.Write(block); err != nil {
+		t.Fatalf("writing block: %v", err)
+	}
+}
+
+// checkInvariants checks that no value has been created or destroyed and
+// that no balance has underflowed.
+func checkInvariants(t *testing.T, db *database.Database, supply uint64) {
+	var total uint64
+	for id, account := range db.Copy() {
+		if account.Balance > supply {
+			t.Fatalf("balance underflow for %s: %d", id, account.Balance)
+		}
+		total += account.Balance
+	}
+
+	if total != supply {
+		t.Fatalf("total supply should be conserved: got %d, exp %d", total, supply)
+	}
+}
+
+// =============================================================================
+
+// memStorage keeps the blocks in memory. This implements the
+// database.Storage and database.SnapshotStorage interfaces.
+type memStorage struct {
+	mu       sync.RWMutex
+	blocks   map[uint64]database.BlockData
+	snapshot *database.ChainSnapshot
+}
+
+func newMemStorage() *memStorage {
+	return &memStorage{blocks: make(map[uint64]database.BlockData)}
+}
+
+func (ms *memStorage) Write(blockData database.BlockData) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.blocks[blockData.Header.Number] = blockData
+	return nil
+}
+
+func (ms *memStorage) GetBlock(num uint64) (database.BlockData, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	blockData, exists := ms.blocks[num]
+	if !exists {
+		return database.BlockData{}, fmt.Errorf("block %d: %w", num, fs.ErrNotExist)
+	}
+	return blockData, nil
+}
+
+func (ms *memStorage) WriteSnapshot(snapshot database.ChainSnapshot) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.snapshot = &snapshot
+	return nil
+}
+
+func (ms *memStorage) ReadSnapshot() (database.ChainSnapshot, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	if ms.snapshot == nil {
+		return database.ChainSnapshot{}, database.ErrNoSnapshot
+	}
+	return *ms.snapshot, nil
+}
+
+func (ms *memStorage) ForEach() database.Iterator {
+	return &memIterator{storage: ms}
+}
+
+func (ms *memStorage) ForEachFrom(num uint64) database.Iterator {
+	if num == 0 {
+		num = 1
+	}
+	return &memIterator{storage: ms, current: num - 1}
+}
+
+func (ms *memStorage) Close() error {
+	return nil
+}
+
+func (ms *memStorage) Reset() error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.blocks = make(map[uint64]database.BlockData)
+	return nil
+}
+
+// memIterator walks the blocks in memory. This implements the
+// database.Iterator interface.
+type memIterator struct {
+	storage *memStorage
+	current uint64
+	eoc     bool
+}
+
+func (mi *memIterator) Next() (database.BlockData, error) {
+	if mi.eoc {
+		return database.BlockData{}, errors.New("end of chain")
+	}
+
+	mi.current++
+	blockData, err := mi.storage.GetBlock(mi.current)
+	if errors.Is(err, fs.ErrNotExist) {
+		mi.eoc = true
+		return database.BlockData{}, nil
+	}
+
+	return blockData, err
+}
+
+func (mi *memIterator) Done() bool {
+	return mi.eoc
+}
+
+func Test_Checkpoint(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	node := database.PublicKeyToAccountID(key.PublicKey)
+
+	gen := genesis.Genesis{
+		Date:          time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC),
+		ChainID:       1,
+		TransPerBlock: transPerBlk,
+		Difficulty:    1,
+		MiningReward:  miningReward,
+		GasPrice:      gasPrice,
+		Balances:      map[string]uint64{string(node): 1_000},
+	}
+
+	db, err := database.New(gen, newMemStorage(), func(v string, args ...any) {})
+	if err != nil {
+		t.Fatalf("constructing database: %v", err)
+	}
+
+	block := database.Block{Header: database.BlockHeader{Number: 1, BeneficiaryID: node}}
+	checkpoint, err := database.NewCheckpoint(gen.ChainID, db.HashAlgorithm(block.Header.Number), block, db.Copy()).Sign(key)
+	if err != nil {
+		t.Fatalf("signing checkpoint: %v", err)
+	}
+
+	signer, err := checkpoint.Verify()
+	if err != nil {
+		t.Fatalf("verifying checkpoint: %v", err)
+	}
+	if signer != node {
+		t.Fatalf("signer: got %s, exp %s", signer, node)
+	}
+
+	tampered := checkpoint
+	tampered.Accounts = append([]database.Account(nil), checkpoint.Accounts...)
+	tampered.Accounts[0].Balance++
+	if _, err := tampered.Verify(); err == nil {
+		t.Fatal("checkpoint with a tampered balance should fail verification")
+	}
+
+	tampered = checkpoint
+	tampered.Header.Number++
+	if _, err := tampered.Verify(); err == nil {
+		t.Fatal("checkpoint with a tampered header should fail verification")
+	}
+}
@@ -0,0 +1,213 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+)
+
+// AccountDiff represents the change a block made to an account.
+type AccountDiff struct {
+	AccountID AccountID `json:"account"`
+	Before    Account   `json:"before"`
+	After     Account   `json:"after"`
+}
+
+// undo holds the accounts the latest block could change as they were before
+// the block was applied so the block can be rolled back.
+type undo struct {
+	number uint64
+	before map[AccountID]Account
+}
+
+// Snapshot returns a copy of the accounts the block can change. It's taken
+// before the block is applied and handed to RecordDiff after. Accounts that
+// don't exist yet are the zero value without an account ID.
+func (db *Database) Snapshot(block Block) map[AccountID]Account {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	ids := []AccountID{block.Header.BeneficiaryID}
+	for _, tx := range block.MerkleTree.Values() {
+		ids = append(ids, tx.FromID, tx.ToID)
+		if tx.Sponsor != nil {
+			ids = append(ids, tx.Sponsor.AccountID)
+		}
+		if tx.Governance != nil {
+			ids = append(ids, tx.Governance.AccountID)
+		}
+	}
+
+	before := make(map[AccountID]Account)
+	for _, id := range ids {
+		before[id] = db.accounts[id]
+	}
+
+	return before
+}
+
+// RecordDiff compares the accounts captured before the block was applied
+// with the accounts now and stores the changes made by the block. The
+// history of blocks that are no longer among the latest is dropped.
+func (db *Database) RecordDiff(block Block, before map[AccountID]Account) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.undo = undo{
+		number: block.Header.Number,
+		before: before,
+	}
+
+	diffs := []AccountDiff{}
+	for id, account := range before {
+		after := db.account(id)
+		if after.Balance == account.Balance && after.Nonce == account.Nonce && after.Frozen == account.Frozen {
+			continue
+		}
+
+		diffs = append(diffs, AccountDiff{
+			AccountID: id,
+			Before:    account,
+			After:     after,
+		})
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].AccountID < diffs[j].AccountID
+	})
+
+	db.diffs[block.Header.Number] = diffs
+	db.prune(block.Header.Number)
+}
+
+// Diff returns the changes made to the accounts by the specified block.
+func (db *Database) Diff(num uint64) ([]AccountDiff, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	diffs, exists := db.diffs[num]
+	if !exists {
+		return nil, fmt.Errorf("no diff for block %d", num)
+	}
+
+	return diffs, nil
+}
+
+// ChangedAccounts returns the accounts whose state or mined transactions
+// the specified block changed ordered by account ID. The block must be the
+// latest block.
+func (db *Database) ChangedAccounts(num uint64) []AccountID {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	changed := make(map[AccountID]struct{})
+	for _, diff := range db.diffs[num] {
+		changed[diff.AccountID] = struct{}{}
+	}
+	for accountID := range db.blockIndex(num) {
+		changed[accountID] = struct{}{}
+	}
+
+	accounts := make([]AccountID, 0, len(changed))
+	for accountID := range changed {
+		accounts = append(accounts, accountID)
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i] < accounts[j]
+	})
+
+	return accounts
+}
+
+// AccountsAt returns the accounts as they were after the block with the
+// specified number was applied. The current accounts are rolled back one
+// block at a time using the recorded diffs.
+func (db *Database) AccountsAt(num uint64) (map[AccountID]Account, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	latest := db.latestBlock.Header.Number
+	if num > latest {
+		return nil, fmt.Errorf("block %d is past the latest block %d", num, latest)
+	}
+
+	accounts := make(map[AccountID]Account, len(db.accounts))
+	for accountID, account := range db.accounts {
+		accounts[accountID] = account
+	}
+
+	for n := latest; n > num; n-- {
+		diffs, exists := db.diffs[n]
+		if !exists {
+			return nil, fmt.Errorf("no diff for block %d", n)
+		}
+
+		for _, diff := range diffs {
+			if diff.Before.AccountID == "" {
+				delete(accounts, diff.AccountID)
+				continue
+			}
+			accounts[diff.AccountID] = diff.Before
+		}
+	}
+
+	return accounts, nil
+}
+
+// ParentStateRoot returns the hash of the accounts as they were before the
+// latest block was applied, hashed with the algorithm in effect for the
+// latest block like the state root in its header.
+func (db *Database) ParentStateRoot() (string, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	if db.undo.number == 0 || db.undo.number != db.latestBlock.Header.Number {
+		return "", fmt.Errorf("block %d can't be rolled back", db.latestBlock.Header.Number)
+	}
+
+	accounts := make(map[AccountID]Account, len(db.accounts))
+	for accountID, account := range db.accounts {
+		accounts[accountID] = account
+	}
+	db.undo.apply(accounts)
+
+	return hashAccounts(db.HashAlgorithm(db.latestBlock.Header.Number), accounts), nil
+}
+
+// Rollback undoes the latest block so a competing block with the same
+// parent can take its place. Only the latest block can be rolled back.
+func (db *Database) Rollback(parent Block) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	latest := db.latestBlock
+	if db.undo.number == 0 || db.undo.number != latest.Header.Number {
+		return fmt.Errorf("block %d can't be rolled back", latest.Header.Number)
+	}
+	if parent.Header.Number+1 != latest.Header.Number || latest.Header.PrevBlockHash != parent.Hash() {
+		return fmt.Errorf("block %d is not the parent of block %d", parent.Header.Number, latest.Header.Number)
+	}
+
+	db.undo.apply(db.accounts)
+	algorithm := db.HashAlgorithm(latest.Header.Number)
+	for _, tx := range latest.MerkleTree.Values() {
+		delete(db.receipts, tx.TxHash(algorithm))
+	}
+	db.unindexBlock(latest.Header.Number)
+	delete(db.diffs, latest.Header.Number)
+
+	db.latestBlock = parent
+	db.undo = undo{}
+
+	return nil
+}
+
+// apply puts the accounts back the way they were before the block.
+func (u undo) apply(accounts map[AccountID]Account) {
+	for accountID, account := range u.before {
+		if account.AccountID == "" {
+			delete(accounts, accountID)
+			continue
+		}
+		accounts[accountID] = account
+	}
+}
@@ -0,0 +1,152 @@
+package database
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/signature"
+)
+
+// The set of governance actions a quorum of validators can take.
+const (
+	GovernanceFreeze   = "freeze"
+	GovernanceUnfreeze = "unfreeze"
+)
+
+// Governance represents an action on an account taken by a quorum of the
+// chain's validators. A transaction carrying it is a governance transaction.
+type Governance struct {
+	Action    string    `json:"action"`
+	AccountID AccountID `json:"account_id"`
+	Approvals []string  `json:"approvals"` // Validator signatures of the proposal.
+}
+
+// Proposal is what each validator signs to approve a governance action.
+// It's bound to the sender and nonce of the governance transaction so an
+// approval can't be replayed in another transaction.
+type Proposal struct {
+	ChainID   uint16    `json:"chain_id"`
+	FromID    AccountID `json:"from"`
+	Nonce     uint64    `json:"nonce"`
+	Action    string    `json:"action"`
+	AccountID AccountID `json:"account_id"`
+}
+
+// NewProposal constructs the proposal for the specified action to be taken
+// by the transaction with the specified sender and nonce.
+func NewProposal(chainID uint16, fromID AccountID, nonce uint64, action string, accountID AccountID) (Proposal, error) {
+	if action != GovernanceFreeze && action != GovernanceUnfreeze {
+		return Proposal{}, fmt.Errorf("unknown governance action %q", action)
+	}
+
+	if !fromID.IsAccountID() {
+		return Proposal{}, errors.New("from account is not properly formatted")
+	}
+
+	if !accountID.IsAccountID() {
+		return Proposal{}, errors.New("governed account is not properly formatted")
+	}
+
+	proposal := Proposal{
+		ChainID:   chainID,
+		FromID:    fromID,
+		Nonce:     nonce,
+		Action:    action,
+		AccountID: accountID,
+	}
+
+	return proposal, nil
+}
+
+// Approve signs the proposal with the validator's private key and returns
+// the signature in the [R|S|V] format.
+func (p Proposal) Approve(privateKey *ecdsa.PrivateKey) (string, error) {
+	v, r, s, err := signature.Sign(p, privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	return signature.SignatureString(v, r, s), nil
+}
+
+// =============================================================================
+
+// ValidateGovernance checks the governance transaction names a known action
+// and is approved by a quorum of distinct validators.
+func (db *Database) ValidateGovernance(tx Tx) error {
+	if tx.Governance == nil {
+		return nil
+	}
+
+	proposal, err := NewProposal(tx.ChainID, tx.FromID, tx.Nonce, tx.Governance.Action, tx.Governance.AccountID)
+	if err != nil {
+		return err
+	}
+
+	validators := make(map[AccountID]bool)
+	for _, validator := range db.genesis.Validators {
+		validators[AccountID(validator)] = true
+	}
+
+	approved := make(map[AccountID]bool)
+	for _, approval := range tx.Governance.Approvals {
+		v, r, s, err := signature.FromSignatureString(approval)
+		if err != nil {
+			return fmt.Errorf("invalid approval: %w", err)
+		}
+
+		if err := signature.VerifySignature(v, r, s); err != nil {
+			return fmt.Errorf("invalid approval: %w", err)
+		}
+
+		address, err := signature.FromAddress(proposal, v, r, s)
+		if err != nil {
+			return fmt.Errorf("invalid approval: %w", err)
+		}
+
+		if !validators[AccountID(address)] {
+			return fmt.Errorf("approval from %s who is not a validator", address)
+		}
+		approved[AccountID(address)] = true
+	}
+
+	if quorum := db.genesis.Quorum(); len(approved) < quorum {
+		return fmt.Errorf("governance action approved by %d validators, needs %d", len(approved), quorum)
+	}
+
+	return nil
+}
+
+// IsFrozen reports whether transactions from the account are rejected.
+func (db *Database) IsFrozen(accountID AccountID) bool {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	return db.accounts[accountID].Frozen
+}
+
+// Frozen returns the accounts that are frozen sorted by account ID.
+func (db *Database) Frozen() []AccountID {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var frozen []AccountID
+	for accountID, account := range db.accounts {
+		if account.Frozen {
+			frozen = append(frozen, accountID)
+		}
+	}
+	sort.Slice(frozen, func(i, j int) bool { return frozen[i] < frozen[j] })
+
+	return frozen
+}
+
+// applyGovernance performs the governance action. The caller must hold the
+// lock and have validated the transaction.
+func (db *Database) applyGovernance(gov Governance) {
+	account := db.account(gov.AccountID)
+	account.Frozen = gov.Action == GovernanceFreeze
+	db.accounts[gov.AccountID] = account
+}
@@ -0,0 +1,163 @@
+package database
+
+import (
+	"encoding/json"
+)
+
+// CORE NOTE: The diffs, receipts and indexed transactions of every block are
+// kept in memory, so without a limit they grow with the chain. Only the ones
+// of the latest HistoryBlocks blocks are kept. Older receipts are read back
+// from storage that commits them with their block, and the indexed
+// transactions of older blocks are folded into totals per account so the
+// account summaries still cover the whole chain. Older diffs are gone, so
+// the accounts can only be rolled back as far as the history goes.
+
+// Option represents a setting used when constructing a database.
+type Option func(db *Database)
+
+// WithRetention limits the history kept in memory to the latest blocks
+// set by the retention.
+func WithRetention(retention Retention) Option {
+	return func(db *Database) {
+		db.history = retention.HistoryBlocks
+	}
+}
+
+// archivedTxs represents the indexed transactions of an account in the
+// blocks that left the history.
+type archivedTxs struct {
+	transactions   int
+	sent           uint64
+	received       uint64
+	fees           uint64
+	firstBlock     uint64
+	lastBlock      uint64
+	counterparties map[AccountID]struct{}
+}
+
+// add folds the indexed transactions of one block into the archive.
+func (a *archivedTxs) add(txs []indexedTx) {
+	if len(txs) == 0 {
+		return
+	}
+
+	if a.counterparties == nil {
+		a.counterparties = make(map[AccountID]struct{})
+	}
+
+	txHashes := make(map[string]struct{})
+	for _, tx := range txs {
+		txHashes[tx.txHash] = struct{}{}
+		if tx.counterparty != "" {
+			a.counterparties[tx.counterparty] = struct{}{}
+		}
+
+		a.sent += tx.sent
+		a.received += tx.received
+		a.fees += tx.fee
+	}
+
+	if a.transactions == 0 || txs[0].blockNumber < a.firstBlock {
+		a.firstBlock = txs[0].blockNumber
+	}
+	if last := txs[len(txs)-1].blockNumber; last > a.lastBlock {
+		a.lastBlock = last
+	}
+	a.transactions += len(txHashes)
+}
+
+// merge folds the other archive into this one.
+func (a *archivedTxs) merge(other archivedTxs) {
+	if other.transactions == 0 {
+		return
+	}
+
+	if a.counterparties == nil {
+		a.counterparties = make(map[AccountID]struct{})
+	}
+	for accountID := range other.counterparties {
+		a.counterparties[accountID] = struct{}{}
+	}
+
+	if a.transactions == 0 || other.firstBlock < a.firstBlock {
+		a.firstBlock = other.firstBlock
+	}
+	if other.lastBlock > a.lastBlock {
+		a.lastBlock = other.lastBlock
+	}
+
+	a.transactions += other.transactions
+	a.sent += other.sent
+	a.received += other.received
+	a.fees += other.fees
+}
+
+// prune drops the history of the blocks that are no longer among the latest
+// history blocks as of the specified latest block. The caller must hold
+// the lock.
+func (db *Database) prune(latest uint64) {
+	if db.history == 0 || latest <= db.history {
+		return
+	}
+	oldest := latest - db.history + 1
+
+	for num := range db.diffs {
+		if num < oldest {
+			delete(db.diffs, num)
+		}
+	}
+
+	for txHash, receipt := range db.receipts {
+		if receipt.BlockNumber < oldest {
+			delete(db.receipts, txHash)
+		}
+	}
+
+	for accountID, txs := range db.txIndex {
+		n := 0
+		for n < len(txs) && txs[n].blockNumber < oldest {
+			n++
+		}
+		if n == 0 {
+			continue
+		}
+
+		archive := db.archive[accountID]
+		for start := 0; start < n; {
+			end := start
+			for end < n && txs[end].blockNumber == txs[start].blockNumber {
+				end++
+			}
+			archive.add(txs[start:end])
+			start = end
+		}
+		db.archive[accountID] = archive
+
+		if n == len(txs) {
+			delete(db.txIndex, accountID)
+			continue
+		}
+		db.txIndex[accountID] = append([]indexedTx(nil), txs[n:]...)
+	}
+}
+
+// storedReceipt reads the receipt of the transaction from storage that
+// commits the receipts with their block.
+func (db *Database) storedReceipt(txHash string) (Receipt, bool) {
+	bs, ok := db.storage.(BatchStorage)
+	if !ok {
+		return Receipt{}, false
+	}
+
+	data, err := bs.Record(BucketReceipts, txHash)
+	if err != nil {
+		return Receipt{}, false
+	}
+
+	var receipt Receipt
+	if err := json.Unmarshal(data, &receipt); err != nil {
+		return Receipt{}, false
+	}
+
+	return receipt, true
+}
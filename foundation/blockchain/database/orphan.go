@@ -0,0 +1,17 @@
+package database
+
+// OrphanStorage interface represents the behavior storage can implement to
+// keep the blocks that lost to a competing block at the same height.
+type OrphanStorage interface {
+	WriteOrphan(orphan Orphan) error
+	Orphans() ([]Orphan, error)
+}
+
+// Orphan represents a valid block that lost to a competing block at the
+// same height and the block it lost to.
+type Orphan struct {
+	Block      BlockData `json:"block"`
+	WinnerHash string    `json:"winner_hash"`
+	Reason     string    `json:"reason"`
+	SeenAt     uint64    `json:"seen_at"` // Unix time in milliseconds.
+}
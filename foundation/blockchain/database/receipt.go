@@ -0,0 +1,52 @@
+package database
+
+// The set of statuses for a receipt.
+const (
+	ReceiptSuccess = "success"
+	ReceiptFailed  = "failed"
+)
+
+// Receipt represents the outcome of applying a mined transaction. A
+// transaction that fails is still part of the block and pays its gas fee.
+// The gas fee and tip are what the beneficiary of the block was paid.
+type Receipt struct {
+	BlockNumber uint64 `json:"block_number"`
+	Status      string `json:"status"`
+	Error       string `json:"error,omitempty"`
+	GasFee      uint64 `json:"gas_fee,omitempty"`
+	Tip         uint64 `json:"tip,omitempty"`
+}
+
+// Receipt returns the receipt for the transaction with the specified hash
+// if the transaction has been mined. Receipts of blocks that left the
+// history are read from storage when it keeps them.
+func (db *Database) Receipt(txHash string) (Receipt, bool) {
+	db.mu.RLock()
+	receipt, exists := db.receipts[txHash]
+	pending := db.pending != nil
+	db.mu.RUnlock()
+
+	if exists || pending {
+		return receipt, exists
+	}
+
+	return db.storedReceipt(txHash)
+}
+
+// recordReceipt stores the outcome of applying the transaction. The
+// caller must hold the lock.
+func (db *Database) recordReceipt(block Block, tx BlockTx, gasFee uint64, err error) {
+	receipt := Receipt{
+		BlockNumber: block.Header.Number,
+		Status:      ReceiptSuccess,
+		GasFee:      gasFee,
+		Tip:         tx.Tip,
+	}
+	if err != nil {
+		receipt.Status = ReceiptFailed
+		receipt.Error = err.Error()
+		receipt.Tip = 0
+	}
+
+	db.receipts[tx.TxHash(db.HashAlgorithm(block.Header.Number))] = receipt
+}
@@ -0,0 +1,113 @@
+package database
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// replayProgress is the number of blocks replayed between progress events.
+const replayProgress = 1000
+
+// replay rebuilds the accounts by applying every block in storage up to and
+// including the block with the specified number, zero being the end of the
+// chain. Large chains take a while so progress is reported as blocks are
+// applied.
+func (db *Database) replay(through uint64, evHandler func(v string, args ...any)) error {
+	start := time.Now()
+	workers := runtime.NumCPU()
+
+	// A database loaded from a snapshot is audited up to the block the
+	// snapshot was taken at while new blocks are being written.
+	iter := db.ForEach()
+	if through != 0 {
+		iter = db.iterate(1, through)
+	}
+
+	var blocks uint64
+	for block, err := iter.Next(); !iter.Done(); block, err = iter.Next() {
+		if err != nil {
+			return err
+		}
+
+		if err := db.applyBlock(block, workers, evHandler); err != nil {
+			return err
+		}
+
+		blocks++
+		if blocks%replayProgress == 0 {
+			evHandler("database: replay: blocks[%d]: latest[%d]: elapsed[%s]", blocks, block.Header.Number, time.Since(start).Round(time.Millisecond))
+		}
+	}
+
+	evHandler("database: replay: completed: blocks[%d]: elapsed[%s]", blocks, time.Since(start).Round(time.Millisecond))
+
+	return nil
+}
+
+// applyBlock validates the block against the latest block and applies it to
+// the accounts.
+func (db *Database) applyBlock(block Block, workers int, evHandler func(v string, args ...any)) error {
+
+	// Validate the block values and cryptographic audit trail.
+	if err := block.ValidateBlock(db.LatestBlock(), db.HashState(), db.Rules(block.Header.Number), evHandler); err != nil {
+		return err
+	}
+
+	// Check the transactions were signed by the accounts sending them.
+	if err := verifySignatures(block, db.genesis.ChainID, workers); err != nil {
+		return err
+	}
+
+	// Update the database with the transaction information.
+	before := db.Snapshot(block)
+	for _, tx := range block.MerkleTree.Values() {
+		db.ApplyTransaction(block, tx)
+	}
+	db.ApplyMiningReward(block)
+	db.RecordDiff(block, before)
+
+	// Update the current latest block.
+	db.UpdateLatestBlock(block)
+
+	return nil
+}
+
+// verifySignatures validates the transactions of the block with a pool of
+// workers since recovering the signer of a transaction is expensive. The
+// first transaction found to be invalid is reported.
+func verifySignatures(block Block, chainID uint16, workers int) error {
+	trans := block.MerkleTree.Values()
+	if len(trans) < workers {
+		workers = len(trans)
+	}
+
+	errs := make([]error, len(trans))
+	work := make(chan int)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				errs[i] = trans[i].Validate(chainID)
+			}
+		}()
+	}
+
+	for i := range trans {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("block %d: tx %s: %w", block.Header.Number, trans[i].TxHash(block.HashAlgorithm()), err)
+		}
+	}
+
+	return nil
+}
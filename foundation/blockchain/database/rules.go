@@ -0,0 +1,30 @@
+package database
+
+import "github.com/qcbit/blockchain/foundation/blockchain/genesis"
+
+// CORE NOTE: Upgrades in the genesis file change the chain parameters at a
+// block height so the network can evolve without wiping the chain. The hash
+// algorithm used for transactions, merkle trees and the state is switched
+// as the chain reaches the height of an upgrade. A block header is always
+// hashed with the algorithm in effect at its own height so the hashes of
+// older blocks never change and the chain of parent hashes stays intact.
+// The algorithm is taken from the genesis of the database the block belongs
+// to, so chains with different schedules can run in the same process.
+
+// Rules returns the chain parameters in effect for the block with the
+// specified number.
+func (db *Database) Rules(blockNumber uint64) genesis.Rules {
+	return db.genesis.RulesAt(blockNumber)
+}
+
+// HashAlgorithm returns the hash algorithm in effect for the block with the
+// specified number.
+func (db *Database) HashAlgorithm(blockNumber uint64) string {
+	return db.genesis.RulesAt(blockNumber).HashAlgorithm
+}
+
+// ToBlock converts a storage block of this chain into a database block
+// hashed with the algorithm in effect at its height.
+func (db *Database) ToBlock(blockData BlockData) (Block, error) {
+	return ToBlock(db.HashAlgorithm(blockData.Header.Number), blockData)
+}
@@ -0,0 +1,220 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"runtime"
+	"time"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/genesis"
+)
+
+// ErrNoSnapshot is returned by storage that has no snapshot to read.
+var ErrNoSnapshot = errors.New("no snapshot")
+
+// SnapshotStorage interface represents the behavior storage can implement to
+// keep the accounts as they were at a block so a node can start serving the
+// chain without replaying every block first.
+type SnapshotStorage interface {
+	WriteSnapshot(snapshot ChainSnapshot) error
+	ReadSnapshot() (ChainSnapshot, error)
+}
+
+// ChainSnapshot represents the accounts as they were after the block
+// was applied.
+type ChainSnapshot struct {
+	Block    BlockData `json:"block"`
+	Accounts []Account `json:"accounts"`
+}
+
+// NewFromSnapshot constructs a database from the latest snapshot in storage
+// so the node can start without replaying the chain. The blocks written
+// after the snapshot are applied, the ones before it are left for Audit to
+// check. The chain is replayed like New does when there is no snapshot that
+// can be used.
+func NewFromSnapshot(genesis genesis.Genesis, storage Storage, evHandler func(v string, args ...any), options ...Option) (*Database, error) {
+	if err := useGenesis(genesis); err != nil {
+		return nil, err
+	}
+
+	db, err := newDatabase(genesis, storage, evHandler)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, option := range options {
+		option(db)
+	}
+
+	loaded, err := db.loadSnapshot(evHandler)
+	if err != nil {
+		return nil, err
+	}
+
+	if !loaded {
+		if err := db.replay(0, evHandler); err != nil {
+			return nil, err
+		}
+	}
+
+	return db, nil
+}
+
+// ChainSnapshot returns the accounts as they are after the latest block.
+func (db *Database) ChainSnapshot() ChainSnapshot {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	accounts := make([]Account, 0, len(db.accounts))
+	for _, account := range db.accounts {
+		accounts = append(accounts, account)
+	}
+
+	return ChainSnapshot{
+		Block:    NewBlockData(db.latestBlock),
+		Accounts: accounts,
+	}
+}
+
+// Validated reports whether every block in the chain has been validated.
+// It's false until a database loaded from a snapshot is audited.
+func (db *Database) Validated() bool {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	return db.pending == nil
+}
+
+// Audit replays the chain up to the block the database was loaded from and
+// checks the accounts match the snapshot. The diffs, receipts and indexed
+// transactions of the replayed blocks are added so they can be queried like
+// any other block.
+func (db *Database) Audit(evHandler func(v string, args ...any)) error {
+	db.mu.RLock()
+	snapshot := db.pending
+	db.mu.RUnlock()
+
+	if snapshot == nil {
+		return nil
+	}
+
+	audit, err := newDatabase(db.genesis, db.storage, evHandler)
+	if err != nil {
+		return err
+	}
+	audit.history = db.history
+
+	number := snapshot.Block.Header.Number
+	if err := audit.replay(number, evHandler); err != nil {
+		return err
+	}
+
+	if latest := audit.latestBlock; latest.Header.Number != number || latest.Hash() != snapshot.Block.Hash {
+		return fmt.Errorf("chain ends at block %d, snapshot is of block %d", latest.Header.Number, number)
+	}
+
+	accounts := make(map[AccountID]Account, len(snapshot.Accounts))
+	for _, account := range snapshot.Accounts {
+		accounts[account.AccountID] = account
+	}
+	if algorithm := db.HashAlgorithm(number); hashAccounts(algorithm, audit.accounts) != hashAccounts(algorithm, accounts) {
+		return fmt.Errorf("accounts at block %d don't match the snapshot", number)
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for num, diffs := range audit.diffs {
+		if _, exists := db.diffs[num]; !exists {
+			db.diffs[num] = diffs
+		}
+	}
+	for txHash, receipt := range audit.receipts {
+		if _, exists := db.receipts[txHash]; !exists {
+			db.receipts[txHash] = receipt
+		}
+	}
+	db.mergeIndex(audit, number)
+	for accountID, archive := range audit.archive {
+		merged := db.archive[accountID]
+		merged.merge(archive)
+		db.archive[accountID] = merged
+	}
+	db.prune(db.latestBlock.Header.Number)
+	db.pending = nil
+
+	return nil
+}
+
+// =============================================================================
+
+// loadSnapshot replaces the genesis accounts with the accounts in the latest
+// snapshot and applies the blocks written after it. It reports false when
+// there is no snapshot that can be used.
+func (db *Database) loadSnapshot(evHandler func(v string, args ...any)) (bool, error) {
+	ss, ok := db.storage.(SnapshotStorage)
+	if !ok {
+		return false, nil
+	}
+
+	snapshot, err := ss.ReadSnapshot()
+	if err != nil {
+		if !errors.Is(err, ErrNoSnapshot) {
+			evHandler("database: snapshot: skipped: ERROR: %s", err)
+		}
+		return false, nil
+	}
+
+	number := snapshot.Block.Header.Number
+	stored, err := db.storage.GetBlock(number)
+	if err != nil || stored.Hash != snapshot.Block.Hash {
+		evHandler("database: snapshot: skipped: block[%d] is not in the chain", number)
+		return false, nil
+	}
+
+	block, err := db.ToBlock(snapshot.Block)
+	if err != nil {
+		return false, err
+	}
+
+	accounts := make(map[AccountID]Account, len(snapshot.Accounts))
+	for _, account := range snapshot.Accounts {
+		accounts[account.AccountID] = account
+	}
+
+	db.accounts = accounts
+	db.latestBlock = block
+	db.pending = &snapshot
+
+	evHandler("database: snapshot: loaded: block[%d]: accounts[%d]", number, len(accounts))
+
+	// Apply the blocks written since the snapshot was taken.
+	start := time.Now()
+	workers := runtime.NumCPU()
+
+	var blocks uint64
+	for num := number + 1; ; num++ {
+		blockData, err := db.storage.GetBlock(num)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				break
+			}
+			return false, err
+		}
+
+		block, err := db.ToBlock(blockData)
+		if err != nil {
+			return false, err
+		}
+
+		if err := db.applyBlock(block, workers, evHandler); err != nil {
+			return false, err
+		}
+		blocks++
+	}
+
+	evHandler("database: snapshot: caught up: blocks[%d]: elapsed[%s]", blocks, time.Since(start).Round(time.Millisecond))
+
+	return true, nil
+}
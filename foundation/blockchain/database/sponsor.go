@@ -0,0 +1,90 @@
+package database
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/signature"
+)
+
+// Sponsor names the account paying the gas fee of a transaction in place of
+// the sender, letting an app cover the fees of its users. The sponsor agrees
+// to pay by signing the transaction before the sender does.
+type Sponsor struct {
+	AccountID AccountID `json:"account_id"`
+	Signature string    `json:"signature"` // Sponsor signature of the transaction.
+}
+
+// SponsorSign signs the transaction with the sponsor's private key and
+// returns the signature in the [R|S|V] format. The sender signs the
+// transaction after the signature is added.
+func (tx Tx) SponsorSign(privateKey *ecdsa.PrivateKey) (string, error) {
+	if tx.Sponsor == nil {
+		return "", errors.New("transaction has no sponsor")
+	}
+
+	if PublicKeyToAccountID(privateKey.PublicKey) != tx.Sponsor.AccountID {
+		return "", errors.New("private key does not belong to the sponsor")
+	}
+
+	v, r, s, err := signature.Sign(tx.sponsoredTx(), privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	return signature.SignatureString(v, r, s), nil
+}
+
+// ValidateSponsor checks the sponsor of the transaction signed it.
+func (tx Tx) ValidateSponsor() error {
+	if tx.Sponsor == nil {
+		return nil
+	}
+
+	if !tx.Sponsor.AccountID.IsAccountID() {
+		return errors.New("invalid sponsor ID")
+	}
+
+	if tx.Sponsor.AccountID == tx.FromID {
+		return errors.New("from and sponsor IDs are the same")
+	}
+
+	v, r, s, err := signature.FromSignatureString(tx.Sponsor.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid sponsor signature: %w", err)
+	}
+
+	if err := signature.VerifySignature(v, r, s); err != nil {
+		return fmt.Errorf("invalid sponsor signature: %w", err)
+	}
+
+	address, err := signature.FromAddress(tx.sponsoredTx(), v, r, s)
+	if err != nil {
+		return fmt.Errorf("invalid sponsor signature: %w", err)
+	}
+
+	if address != string(tx.Sponsor.AccountID) {
+		return errors.New("sponsor address does not match signature")
+	}
+
+	return nil
+}
+
+// GasPayer returns the account paying the gas fee of the transaction.
+func (tx Tx) GasPayer() AccountID {
+	if tx.Sponsor != nil {
+		return tx.Sponsor.AccountID
+	}
+	return tx.FromID
+}
+
+// sponsoredTx returns the transaction the sponsor signs, which is the
+// transaction without the sponsor signature.
+func (tx Tx) sponsoredTx() Tx {
+	sponsor := *tx.Sponsor
+	sponsor.Signature = ""
+	tx.Sponsor = &sponsor
+
+	return tx
+}
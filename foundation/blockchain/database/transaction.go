@@ -3,12 +3,17 @@ package database
 import (
 	"bytes"
 	"crypto/ecdsa"
+	"encoding/binary"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"math/big"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+
 	"github.com/qcbit/blockchain/foundation/blockchain/signature"
 )
 
@@ -21,6 +26,22 @@ type Tx struct {
 	Nonce   uint64    `json:"nonce"`    // Ethereum: Unique number for the transaction.
 	Tip     uint64    `json:"tip"`      // Ethereum: The unit amount to tip the miner.
 	Data    []byte    `json:"data"`     // Ethereum: The input data for the transaction.
+
+	// LockUntil makes the transaction a vesting transfer. The value stays
+	// locked in the recipient account until the chain reaches this block.
+	LockUntil uint64 `json:"lock_until,omitempty"`
+
+	// Governance makes the transaction a governance transaction that
+	// takes an action on an account once approved by the validators.
+	Governance *Governance `json:"governance,omitempty"`
+
+	// ValidUntilBlock is the last block the transaction can be mined in.
+	// Once the chain is past it, the nonce can safely be used again.
+	ValidUntilBlock uint64 `json:"valid_until_block,omitempty"`
+
+	// Sponsor makes the transaction a sponsored transaction where the
+	// sponsor pays the gas fee and the sender only the value and tip.
+	Sponsor *Sponsor `json:"sponsor,omitempty"`
 }
 
 // NewTx creates a new transaction.
@@ -43,6 +64,12 @@ func NewTx(chainID uint16, fromID, toID AccountID, value, nonce, tip uint64, dat
 	}, nil
 }
 
+// Expired reports whether the transaction can no longer be mined in the
+// block with the specified number.
+func (tx Tx) Expired(blockNumber uint64) bool {
+	return tx.ValidUntilBlock != 0 && blockNumber > tx.ValidUntilBlock
+}
+
 // Sign signs the transaction.
 func (tx Tx) Sign(privateKey *ecdsa.PrivateKey) (SignedTx, error) {
 	// Sign the transaction with the private key to produce a signature.
@@ -101,9 +128,21 @@ func (tx SignedTx) Validate(chainID uint16) error {
 		return errors.New("from address does not match signature")
 	}
 
+	if err := tx.ValidateSponsor(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// TxHash returns the hash that identifies the signed transaction. Unlike
+// the hash of a block transaction, it's known to the wallet before the
+// transaction is submitted. The hash algorithm is the one in effect for the
+// block the transaction is, or will be, included in.
+func (tx SignedTx) TxHash(algorithm string) string {
+	return signature.HashWith(algorithm, tx)
+}
+
 // SignatureString returns the signature as a string.
 func (tx SignedTx) SignatureString() string {
 	return signature.SignatureString(tx.V, tx.R, tx.S)
@@ -114,6 +153,306 @@ func (tx SignedTx) String() string {
 	return fmt.Sprintf("%s: %d", tx.FromID, tx.Nonce)
 }
 
+// CORE NOTE: The raw encoding is a compact binary form of a signed transaction
+// so it can be produced by signing libraries in other languages without having
+// to reproduce the JSON document. The fields are written in this order using
+// big endian for all numbers:
+//
+//	version(1) | chain_id(2) | from(20) | to(20) | value(8) | nonce(8) |
+//	tip(8) | data_len(4) | data(data_len) | fields(1) | optional fields |
+//	signature(65) [R|S|V]
+//
+// The version byte selects the layout so it can change without breaking the
+// transactions already encoded. The fields byte has a bit set for each of the
+// optional fields that follow, written in the order of their bits:
+//
+//	lock_until(8)
+//	valid_until_block(8)
+//	action_len(1) | action | account(20) | approvals(1) | approval(65)...
+//	sponsor(20) | sponsor_signature(65) [R|S|V]
+//
+// Account IDs are stored as raw address bytes and are always decoded back in
+// their checksum form, so only transactions using checksum account IDs can
+// be encoded. Signatures are decoded back in their lower case hex form. An
+// empty data field is decoded as no data.
+
+// RawTxVersion1 is the version of the raw encoding described above.
+const RawTxVersion1 = 1
+
+// The set of bits in the fields byte of the raw encoding.
+const (
+	rawLockUntil       = 1 << 0
+	rawValidUntilBlock = 1 << 1
+	rawGovernance      = 1 << 2
+	rawSponsor         = 1 << 3
+)
+
+// rawFixedLength is the number of bytes in a raw transaction without data
+// or optional fields.
+const rawFixedLength = 1 + 2 + 20 + 20 + 8 + 8 + 8 + 4 + 1 + crypto.SignatureLength
+
+// EncodeRaw returns the canonical raw encoding of the signed transaction.
+func (tx SignedTx) EncodeRaw() ([]byte, error) {
+	from, err := toAddress(tx.FromID)
+	if err != nil {
+		return nil, fmt.Errorf("from: %w", err)
+	}
+
+	to, err := toAddress(tx.ToID)
+	if err != nil {
+		return nil, fmt.Errorf("to: %w", err)
+	}
+
+	if tx.V == nil || tx.R == nil || tx.S == nil {
+		return nil, errors.New("transaction is not signed")
+	}
+
+	raw := make([]byte, 0, rawFixedLength+len(tx.Data))
+	raw = append(raw, RawTxVersion1)
+	raw = binary.BigEndian.AppendUint16(raw, tx.ChainID)
+	raw = append(raw, from.Bytes()...)
+	raw = append(raw, to.Bytes()...)
+	raw = binary.BigEndian.AppendUint64(raw, tx.Value)
+	raw = binary.BigEndian.AppendUint64(raw, tx.Nonce)
+	raw = binary.BigEndian.AppendUint64(raw, tx.Tip)
+	raw = binary.BigEndian.AppendUint32(raw, uint32(len(tx.Data)))
+	raw = append(raw, tx.Data...)
+
+	var fields byte
+	if tx.LockUntil != 0 {
+		fields |= rawLockUntil
+	}
+	if tx.ValidUntilBlock != 0 {
+		fields |= rawValidUntilBlock
+	}
+	if tx.Governance != nil {
+		fields |= rawGovernance
+	}
+	if tx.Sponsor != nil {
+		fields |= rawSponsor
+	}
+	raw = append(raw, fields)
+
+	if tx.LockUntil != 0 {
+		raw = binary.BigEndian.AppendUint64(raw, tx.LockUntil)
+	}
+
+	if tx.ValidUntilBlock != 0 {
+		raw = binary.BigEndian.AppendUint64(raw, tx.ValidUntilBlock)
+	}
+
+	if gov := tx.Governance; gov != nil {
+		if len(gov.Action) == 0 || len(gov.Action) > 255 {
+			return nil, errors.New("governance action must be 1 to 255 bytes")
+		}
+		if len(gov.Approvals) == 0 || len(gov.Approvals) > 255 {
+			return nil, errors.New("governance approvals must number 1 to 255")
+		}
+
+		account, err := toAddress(gov.AccountID)
+		if err != nil {
+			return nil, fmt.Errorf("governance account: %w", err)
+		}
+
+		raw = append(raw, byte(len(gov.Action)))
+		raw = append(raw, gov.Action...)
+		raw = append(raw, account.Bytes()...)
+		raw = append(raw, byte(len(gov.Approvals)))
+		for _, approval := range gov.Approvals {
+			sig, err := toSignature(approval)
+			if err != nil {
+				return nil, fmt.Errorf("governance approval: %w", err)
+			}
+			raw = append(raw, sig...)
+		}
+	}
+
+	if sponsor := tx.Sponsor; sponsor != nil {
+		account, err := toAddress(sponsor.AccountID)
+		if err != nil {
+			return nil, fmt.Errorf("sponsor: %w", err)
+		}
+
+		sig, err := toSignature(sponsor.Signature)
+		if err != nil {
+			return nil, fmt.Errorf("sponsor signature: %w", err)
+		}
+
+		raw = append(raw, account.Bytes()...)
+		raw = append(raw, sig...)
+	}
+
+	raw = append(raw, signature.ToSignatureBytesWithQID(tx.V, tx.R, tx.S)...)
+
+	return raw, nil
+}
+
+// EncodeRawHex returns the canonical raw encoding of the signed
+// transaction as a 0x prefixed hex string.
+func (tx SignedTx) EncodeRawHex() (string, error) {
+	raw, err := tx.EncodeRaw()
+	if err != nil {
+		return "", err
+	}
+
+	return hexutil.Encode(raw), nil
+}
+
+// DecodeRaw converts the canonical raw encoding back into a signed transaction.
+func DecodeRaw(raw []byte) (SignedTx, error) {
+	if len(raw) < rawFixedLength {
+		return SignedTx{}, fmt.Errorf("raw transaction too short: got %d bytes, need at least %d", len(raw), rawFixedLength)
+	}
+
+	if raw[0] != RawTxVersion1 {
+		return SignedTx{}, fmt.Errorf("raw transaction version %d is not supported", raw[0])
+	}
+
+	r := rawReader{raw: raw[1:]}
+
+	var tx SignedTx
+	tx.ChainID = binary.BigEndian.Uint16(r.next(2))
+	tx.FromID = AccountID(common.BytesToAddress(r.next(20)).Hex())
+	tx.ToID = AccountID(common.BytesToAddress(r.next(20)).Hex())
+	tx.Value = binary.BigEndian.Uint64(r.next(8))
+	tx.Nonce = binary.BigEndian.Uint64(r.next(8))
+	tx.Tip = binary.BigEndian.Uint64(r.next(8))
+
+	dataLen := binary.BigEndian.Uint32(r.next(4))
+	if uint64(dataLen) > uint64(len(raw)) {
+		return SignedTx{}, fmt.Errorf("raw transaction data length mismatch: data_len %d, total %d bytes", dataLen, len(raw))
+	}
+	if data := r.next(int(dataLen)); len(data) > 0 {
+		tx.Data = make([]byte, len(data))
+		copy(tx.Data, data)
+	}
+
+	fields := r.next(1)
+	if r.err != nil {
+		return SignedTx{}, fmt.Errorf("raw transaction data length mismatch: data_len %d, total %d bytes", dataLen, len(raw))
+	}
+	if fields[0]&^(rawLockUntil|rawValidUntilBlock|rawGovernance|rawSponsor) != 0 {
+		return SignedTx{}, fmt.Errorf("raw transaction has unknown fields %08b", fields[0])
+	}
+
+	if fields[0]&rawLockUntil != 0 {
+		if tx.LockUntil = binary.BigEndian.Uint64(r.next(8)); tx.LockUntil == 0 && r.err == nil {
+			return SignedTx{}, errors.New("raw transaction lock_until is zero")
+		}
+	}
+
+	if fields[0]&rawValidUntilBlock != 0 {
+		if tx.ValidUntilBlock = binary.BigEndian.Uint64(r.next(8)); tx.ValidUntilBlock == 0 && r.err == nil {
+			return SignedTx{}, errors.New("raw transaction valid_until_block is zero")
+		}
+	}
+
+	if fields[0]&rawGovernance != 0 {
+		var gov Governance
+		gov.Action = string(r.next(int(r.byte())))
+		gov.AccountID = AccountID(common.BytesToAddress(r.next(20)).Hex())
+		approvals := int(r.byte())
+		for i := 0; i < approvals; i++ {
+			gov.Approvals = append(gov.Approvals, hexutil.Encode(r.next(crypto.SignatureLength)))
+		}
+		if r.err == nil && (gov.Action == "" || approvals == 0) {
+			return SignedTx{}, errors.New("raw transaction governance needs an action and approvals")
+		}
+		tx.Governance = &gov
+	}
+
+	if fields[0]&rawSponsor != 0 {
+		tx.Sponsor = &Sponsor{
+			AccountID: AccountID(common.BytesToAddress(r.next(20)).Hex()),
+			Signature: hexutil.Encode(r.next(crypto.SignatureLength)),
+		}
+	}
+
+	sig := r.next(crypto.SignatureLength)
+	if r.err != nil {
+		return SignedTx{}, r.err
+	}
+	if len(r.raw) != 0 {
+		return SignedTx{}, fmt.Errorf("raw transaction has %d bytes after the signature", len(r.raw))
+	}
+
+	tx.R = big.NewInt(0).SetBytes(sig[:32])
+	tx.S = big.NewInt(0).SetBytes(sig[32:64])
+	tx.V = big.NewInt(0).SetBytes([]byte{sig[64]})
+
+	return tx, nil
+}
+
+// DecodeRawHex converts a 0x prefixed hex string holding the canonical
+// raw encoding back into a signed transaction.
+func DecodeRawHex(rawHex string) (SignedTx, error) {
+	raw, err := hexutil.Decode(rawHex)
+	if err != nil {
+		return SignedTx{}, err
+	}
+
+	return DecodeRaw(raw)
+}
+
+// rawReader reads the fields of a raw transaction in order. Once the raw
+// transaction runs out, every read returns zero bytes and the error is set.
+type rawReader struct {
+	raw []byte
+	err error
+}
+
+// next returns the next n bytes.
+func (r *rawReader) next(n int) []byte {
+	if r.err != nil || n > len(r.raw) {
+		r.err = errors.New("raw transaction too short for its fields")
+		return make([]byte, n)
+	}
+
+	b := r.raw[:n]
+	r.raw = r.raw[n:]
+
+	return b
+}
+
+// byte returns the next byte.
+func (r *rawReader) byte() byte {
+	return r.next(1)[0]
+}
+
+// toSignature converts the signature string into its bytes, making sure
+// the string is in the form the raw encoding decodes it back to.
+func toSignature(sig string) ([]byte, error) {
+	b, err := hexutil.Decode(sig)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b) != crypto.SignatureLength {
+		return nil, fmt.Errorf("invalid signature length %d", len(b))
+	}
+
+	if hexutil.Encode(b) != sig {
+		return nil, errors.New("signature is not in lower case hex form")
+	}
+
+	return b, nil
+}
+
+// toAddress converts the account ID into its address bytes, making
+// sure the account ID is in its checksum form.
+func toAddress(accountID AccountID) (common.Address, error) {
+	if !accountID.IsAccountID() {
+		return common.Address{}, errors.New("invalid account ID")
+	}
+
+	address := common.HexToAddress(string(accountID))
+	if address.Hex() != string(accountID) {
+		return common.Address{}, errors.New("account ID is not in checksum form")
+	}
+
+	return address, nil
+}
+
 // ----------------------------------------------------------------------------
 
 // BlockTx represents a transaction in a block, which includes the timestamp and gas fees.
@@ -124,19 +463,31 @@ type BlockTx struct {
 	GasUnits  uint64 `json:"gas_units"` // Ethereum: The gas units in the block.
 }
 
-// NewBlockTx creates a new block transaction.
+// NewBlockTx creates a new block transaction timestamped by the system clock.
 func NewBlockTx(tx SignedTx, gasPrice, gasUnits uint64) BlockTx {
+	return NewBlockTxAt(tx, gasPrice, gasUnits, time.Now())
+}
+
+// NewBlockTxAt creates a new block transaction with the specified timestamp.
+func NewBlockTxAt(tx SignedTx, gasPrice, gasUnits uint64, now time.Time) BlockTx {
 	return BlockTx{
 		SignedTx:  tx,
-		TimeStamp: uint64(time.Now().UTC().UnixMilli()),
+		TimeStamp: uint64(now.UTC().UnixMilli()),
 		GasPrice:  gasPrice,
 		GasUnits:  gasUnits,
 	}
 }
 
-// Hash implements the merkle Hashable interface to hash a block transaction.
+// Hash implements the merkle Hashable interface to hash a block transaction
+// with SHA-256, the original hash algorithm. Blocks hash their transactions
+// with HashWith and the algorithm in effect at the block's height.
 func (tx BlockTx) Hash() ([]byte, error) {
-	str := signature.Hash(tx)
+	return tx.HashWith(signature.HashSHA256)
+}
+
+// HashWith hashes the block transaction with the specified algorithm.
+func (tx BlockTx) HashWith(algorithm string) ([]byte, error) {
+	str := signature.HashWith(algorithm, tx)
 	// Remove the 0x prefix.
 	return hex.DecodeString(str[2:])
 }
@@ -0,0 +1,149 @@
+package database_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+	"github.com/qcbit/blockchain/foundation/blockchain/signature"
+)
+
+// signedTx constructs a signed transaction for seeding the fuzz corpus.
+func signedTx(t testing.TB) database.SignedTx {
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+
+	tx, err := database.NewTx(1, database.PublicKeyToAccountID(privateKey.PublicKey),
+		"0xbEE6ACE826eC3DE1B6349888B9151B92522F7F76", 100, 1, 10, []byte("data"))
+	if err != nil {
+		t.Fatalf("constructing tx: %v", err)
+	}
+
+	signedTx, err := tx.Sign(privateKey)
+	if err != nil {
+		t.Fatalf("signing tx: %v", err)
+	}
+
+	return signedTx
+}
+
+// optionalTx constructs a signed transaction using every optional field.
+func optionalTx(t testing.TB) database.SignedTx {
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+
+	approval := signedTx(t).SignatureString()
+
+	tx, err := database.NewTx(1, database.PublicKeyToAccountID(privateKey.PublicKey),
+		"0xbEE6ACE826eC3DE1B6349888B9151B92522F7F76", 100, 1, 10, nil)
+	if err != nil {
+		t.Fatalf("constructing tx: %v", err)
+	}
+	tx.LockUntil = 50
+	tx.ValidUntilBlock = 20
+	tx.Governance = &database.Governance{
+		Action:    database.GovernanceFreeze,
+		AccountID: "0xF01813E4B85e178A83e29B8E7bF26BD830a25f32",
+		Approvals: []string{approval, approval},
+	}
+	tx.Sponsor = &database.Sponsor{
+		AccountID: "0xdd6B972ffcc631a62CAE1BB9d80b7ff429c8ebA4",
+		Signature: approval,
+	}
+
+	signedTx, err := tx.Sign(privateKey)
+	if err != nil {
+		t.Fatalf("signing tx: %v", err)
+	}
+
+	return signedTx
+}
+
+func Test_RawOptionalFields(t *testing.T) {
+	exp := optionalTx(t)
+
+	raw, err := exp.EncodeRaw()
+	if err != nil {
+		t.Fatalf("encoding tx: %v", err)
+	}
+	if raw[0] != database.RawTxVersion1 {
+		t.Fatalf("raw transaction should start with its version, got %d", raw[0])
+	}
+
+	got, err := database.DecodeRaw(raw)
+	if err != nil {
+		t.Fatalf("decoding tx: %v", err)
+	}
+
+	gotJSON, _ := json.Marshal(got)
+	expJSON, _ := json.Marshal(exp)
+	if !bytes.Equal(gotJSON, expJSON) {
+		t.Fatalf("decoded tx should match:\ngot %s\nexp %s", gotJSON, expJSON)
+	}
+
+	// The signature still covers the decoded optional fields.
+	address, err := signature.FromAddress(got.Tx, got.V, got.R, got.S)
+	if err != nil || address != string(exp.FromID) {
+		t.Fatalf("decoded tx should be signed by the sender: got %s, %v", address, err)
+	}
+
+	raw[0] = 0
+	if _, err := database.DecodeRaw(raw); err == nil {
+		t.Fatal("unknown raw version should not decode")
+	}
+}
+
+func Fuzz_DecodeRaw(f *testing.F) {
+	for _, tx := range []database.SignedTx{signedTx(f), optionalTx(f)} {
+		raw, err := tx.EncodeRaw()
+		if err != nil {
+			f.Fatalf("encoding tx: %v", err)
+		}
+		f.Add(raw)
+	}
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		tx, err := database.DecodeRaw(raw)
+		if err != nil {
+			return
+		}
+
+		// Any raw transaction that decodes must encode back to the same bytes.
+		got, err := tx.EncodeRaw()
+		if err != nil {
+			t.Fatalf("encoding a decoded tx: %v", err)
+		}
+		if !bytes.Equal(got, raw) {
+			t.Fatalf("raw encoding is not canonical: got %x, exp %x", got, raw)
+		}
+
+		tx.Validate(1)
+	})
+}
+
+func Fuzz_SignedTxJSON(f *testing.F) {
+	data, err := json.Marshal(signedTx(f))
+	if err != nil {
+		f.Fatalf("marshaling tx: %v", err)
+	}
+	f.Add(data)
+	f.Add([]byte(`{"chain_id":1,"from_id":"0xbEE6ACE826eC3DE1B6349888B9151B92522F7F76"}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var tx database.BlockTx
+		if err := json.Unmarshal(data, &tx); err != nil {
+			return
+		}
+
+		// Validation must reject bad input without panicking.
+		tx.Validate(1)
+	})
+}
@@ -3,24 +3,91 @@ package genesis
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
+	"sort"
+	"strings"
 	"time"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/signature"
+)
+
+// The set of chain versions. A genesis file without a version represents
+// a chain created before versioning existed and is treated as version 1.
+const (
+	ChainVersion1 = 1 // Blocks, transactions and state are hashed with SHA-256.
+	ChainVersion2 = 2 // Blocks, transactions and state are hashed with Keccak-256.
 )
 
-// Genesis is the genesis file.
+// The set of block header versions. Blocks start at version 0 and an upgrade
+// selects a later version so header changes can be introduced at a fork
+// height. A version 0 header leaves the version out of its hash so blocks
+// mined before versioning existed keep their hash.
+const (
+	HeaderVersion0 = 0 // The original header.
+	HeaderVersion1 = 1 // The version is covered by the block hash.
+
+	MaxHeaderVersion = HeaderVersion1
+)
+
+// Genesis is the genesis file. The keys of the balances and the validators
+// are either hex account IDs or names of accounts in the accounts folder.
 type Genesis struct {
 	Date          time.Time         `json:"date"`
+	Version       uint16            `json:"version"`
 	ChainID       uint16            `json:"chain_id"`
 	TransPerBlock uint16            `json:"trans_per_block"`
 	Difficulty    uint16            `json:"difficulty"`
 	MiningReward  uint64            `json:"mining_reward"`
 	GasPrice      uint64            `json:"gas_price"`
 	Balances      map[string]uint64 `json:"balances"`
+	Locks         map[string][]Lock `json:"locks,omitempty"`
+	Validators    []string          `json:"validators,omitempty"`
+	GovQuorum     uint16            `json:"governance_quorum,omitempty"`
+	Upgrades      []Upgrade         `json:"upgrades,omitempty"`
+}
+
+// Upgrade changes the chain parameters from the block at the specified
+// height onwards. Parameters that are left out keep their previous value.
+// The reward and gas price are pointers since zero is a valid value.
+type Upgrade struct {
+	Height        uint64  `json:"height"`
+	Difficulty    uint16  `json:"difficulty,omitempty"`
+	MiningReward  *uint64 `json:"mining_reward,omitempty"`
+	GasPrice      *uint64 `json:"gas_price,omitempty"`
+	HashAlgorithm string  `json:"hash_algorithm,omitempty"`
+	HeaderVersion uint16  `json:"header_version,omitempty"`
+
+	// SharedAccounts applies every change to an account that is used in
+	// more than one role of a transaction, like a miner sending from the
+	// account that receives the block's fees. Once set it stays set.
+	SharedAccounts bool `json:"shared_accounts,omitempty"`
+}
+
+// Rules represents the chain parameters in effect for a block.
+type Rules struct {
+	Difficulty     uint16 `json:"difficulty"`
+	MiningReward   uint64 `json:"mining_reward"`
+	GasPrice       uint64 `json:"gas_price"`
+	HashAlgorithm  string `json:"hash_algorithm"`
+	HeaderVersion  uint16 `json:"header_version"`
+	SharedAccounts bool   `json:"shared_accounts"`
+}
+
+// Lock represents an amount of an account's genesis balance that can't be
+// spent until the chain reaches the specified block number.
+type Lock struct {
+	Amount     uint64 `json:"amount"`
+	UntilBlock uint64 `json:"until_block"`
 }
 
 // Load loads the genesis file.
 func Load() (Genesis, error) {
-	path := "zblock/genesis.json"
+	return LoadFile("zblock/genesis.json")
+}
+
+// LoadFile opens and consumes the genesis file at the specified path.
+func LoadFile(path string) (Genesis, error) {
 	content, err := os.ReadFile(path)
 	if err != nil {
 		return Genesis{}, err
@@ -34,3 +101,166 @@ func Load() (Genesis, error) {
 
 	return genesis, nil
 }
+
+// ResolveNames replaces the names used as keys in the balances and locks
+// with the account IDs the resolve function returns for them. Keys that are
+// already hex account IDs are kept. Every name that can't be resolved is
+// listed in the returned error.
+func (g *Genesis) ResolveNames(resolve func(name string) (accountID string, ok bool)) error {
+	missing := make(map[string]bool)
+
+	accountID := func(key string) (string, bool) {
+		if IsAccountID(key) {
+			return key, true
+		}
+		accountID, ok := resolve(key)
+		if !ok {
+			missing[key] = true
+		}
+		return accountID, ok
+	}
+
+	balances := make(map[string]uint64, len(g.Balances))
+	for key, balance := range g.Balances {
+		id, ok := accountID(key)
+		if !ok {
+			continue
+		}
+		if _, exists := balances[id]; exists {
+			return fmt.Errorf("account %s is allocated more than once", id)
+		}
+		balances[id] = balance
+	}
+
+	var locks map[string][]Lock
+	if g.Locks != nil {
+		locks = make(map[string][]Lock, len(g.Locks))
+	}
+	for key, accountLocks := range g.Locks {
+		id, ok := accountID(key)
+		if !ok {
+			continue
+		}
+		if _, exists := locks[id]; exists {
+			return fmt.Errorf("account %s has locks listed more than once", id)
+		}
+		locks[id] = accountLocks
+	}
+
+	var validators []string
+	for _, key := range g.Validators {
+		if id, ok := accountID(key); ok {
+			validators = append(validators, id)
+		}
+	}
+
+	if len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unable to resolve genesis names: %s", strings.Join(names, ", "))
+	}
+
+	g.Balances = balances
+	g.Locks = locks
+	g.Validators = validators
+
+	return nil
+}
+
+// Quorum returns the number of validators that must approve a governance
+// action. A majority of the validators is needed unless the genesis file
+// sets the quorum.
+func (g Genesis) Quorum() int {
+	if g.GovQuorum > 0 {
+		return int(g.GovQuorum)
+	}
+
+	return len(g.Validators)/2 + 1
+}
+
+// IsAccountID reports whether the balance key is written as a hex account
+// ID rather than a name.
+func IsAccountID(key string) bool {
+	return len(key) == 42 && strings.HasPrefix(key, "0x")
+}
+
+// RulesAt returns the chain parameters in effect for the block with the
+// specified number by applying the upgrades scheduled up to that height.
+func (g Genesis) RulesAt(blockNumber uint64) Rules {
+	rules := Rules{
+		Difficulty:    g.Difficulty,
+		MiningReward:  g.MiningReward,
+		GasPrice:      g.GasPrice,
+		HashAlgorithm: g.HashAlgorithm(),
+	}
+
+	for _, upgrade := range g.Upgrades {
+		if upgrade.Height > blockNumber {
+			break
+		}
+
+		if upgrade.Difficulty != 0 {
+			rules.Difficulty = upgrade.Difficulty
+		}
+		if upgrade.MiningReward != nil {
+			rules.MiningReward = *upgrade.MiningReward
+		}
+		if upgrade.GasPrice != nil {
+			rules.GasPrice = *upgrade.GasPrice
+		}
+		if upgrade.HashAlgorithm != "" {
+			rules.HashAlgorithm = upgrade.HashAlgorithm
+		}
+		if upgrade.HeaderVersion != 0 {
+			rules.HeaderVersion = upgrade.HeaderVersion
+		}
+		if upgrade.SharedAccounts {
+			rules.SharedAccounts = true
+		}
+	}
+
+	return rules
+}
+
+// ValidateUpgrades checks the upgrades are scheduled in order of height
+// after the genesis block and only select supported hash algorithms and
+// header versions. The header version can't go back to an earlier version.
+func (g Genesis) ValidateUpgrades() error {
+	var height uint64
+	var headerVersion uint16
+	for _, upgrade := range g.Upgrades {
+		if upgrade.Height <= height {
+			return fmt.Errorf("upgrade at height %d must be after height %d", upgrade.Height, height)
+		}
+		height = upgrade.Height
+
+		if upgrade.HashAlgorithm != "" && !signature.IsHashAlgorithm(upgrade.HashAlgorithm) {
+			return fmt.Errorf("upgrade at height %d: hash algorithm %q does not exist", upgrade.Height, upgrade.HashAlgorithm)
+		}
+
+		if upgrade.HeaderVersion != 0 {
+			if upgrade.HeaderVersion > MaxHeaderVersion {
+				return fmt.Errorf("upgrade at height %d: header version %d is not supported, the latest is %d", upgrade.Height, upgrade.HeaderVersion, MaxHeaderVersion)
+			}
+			if upgrade.HeaderVersion < headerVersion {
+				return fmt.Errorf("upgrade at height %d: header version %d is before version %d", upgrade.Height, upgrade.HeaderVersion, headerVersion)
+			}
+			headerVersion = upgrade.HeaderVersion
+		}
+	}
+
+	return nil
+}
+
+// HashAlgorithm returns the hash algorithm used by the chain based on
+// the chain version.
+func (g Genesis) HashAlgorithm() string {
+	if g.Version >= ChainVersion2 {
+		return signature.HashKeccak256
+	}
+
+	return signature.HashSHA256
+}
@@ -0,0 +1,140 @@
+package genesis_test
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/genesis"
+	"github.com/qcbit/blockchain/foundation/blockchain/signature"
+)
+
+func Test_ResolveNames(t *testing.T) {
+	accounts := map[string]string{
+		"kennedy": "0xF01813E4B85e178A83e29B8E7bF26BD830a25f32",
+	}
+	resolve := func(name string) (string, bool) {
+		accountID, ok := accounts[name]
+		return accountID, ok
+	}
+
+	gen := genesis.Genesis{
+		Balances: map[string]uint64{
+			"kennedy": 100,
+			"0xdd6B972ffcc631a62CAE1BB9d80b7ff429c8ebA4": 200,
+		},
+	}
+	if err := gen.ResolveNames(resolve); err != nil {
+		t.Fatalf("should resolve the names: %s", err)
+	}
+
+	if got := gen.Balances["0xF01813E4B85e178A83e29B8E7bF26BD830a25f32"]; got != 100 {
+		t.Fatalf("should allocate the name's balance to its account: got %d", got)
+	}
+	if got := gen.Balances["0xdd6B972ffcc631a62CAE1BB9d80b7ff429c8ebA4"]; got != 200 {
+		t.Fatalf("should keep the account's balance: got %d", got)
+	}
+
+	gen.Balances = map[string]uint64{"pavel": 1, "ceasar": 2, "kennedy": 3}
+	err := gen.ResolveNames(resolve)
+	if err == nil || !strings.Contains(err.Error(), "ceasar, pavel") {
+		t.Fatalf("should list every unresolvable name: got %v", err)
+	}
+
+	gen.Balances = map[string]uint64{"kennedy": 1, "0xF01813E4B85e178A83e29B8E7bF26BD830a25f32": 2}
+	if err := gen.ResolveNames(resolve); err == nil {
+		t.Fatal("should reject an account allocated twice")
+	}
+}
+
+func Test_HeaderVersion(t *testing.T) {
+	gen := genesis.Genesis{
+		Upgrades: []genesis.Upgrade{
+			{Height: 5, HashAlgorithm: "keccak256"},
+			{Height: 10, HeaderVersion: genesis.HeaderVersion1},
+		},
+	}
+	if err := gen.ValidateUpgrades(); err != nil {
+		t.Fatalf("should accept the upgrades: %s", err)
+	}
+
+	tt := []struct {
+		number uint64
+		exp    uint16
+	}{
+		{number: 1, exp: genesis.HeaderVersion0},
+		{number: 9, exp: genesis.HeaderVersion0},
+		{number: 10, exp: genesis.HeaderVersion1},
+		{number: 50, exp: genesis.HeaderVersion1},
+	}
+	for _, tst := range tt {
+		if got := gen.RulesAt(tst.number).HeaderVersion; got != tst.exp {
+			t.Fatalf("block %d: should use header version %d: got %d", tst.number, tst.exp, got)
+		}
+	}
+
+	gen.Upgrades = append(gen.Upgrades, genesis.Upgrade{Height: 20, HeaderVersion: genesis.MaxHeaderVersion + 1})
+	if err := gen.ValidateUpgrades(); err == nil {
+		t.Fatal("should reject a header version that isn't supported")
+	}
+}
+
+func Test_HashAlgorithm(t *testing.T) {
+	value := map[string]uint64{"balance": 100}
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("marshaling value: %s", err)
+	}
+	legacy := sha256.Sum256(data)
+
+	tt := []struct {
+		name    string
+		version uint16
+		exp     string
+		hash    string
+	}{
+		{name: "unversioned", version: 0, exp: signature.HashSHA256, hash: hexutil.Encode(legacy[:])},
+		{name: "version1", version: genesis.ChainVersion1, exp: signature.HashSHA256, hash: hexutil.Encode(legacy[:])},
+		{name: "version2", version: genesis.ChainVersion2, exp: signature.HashKeccak256, hash: hexutil.Encode(crypto.Keccak256(data))},
+	}
+	for _, tst := range tt {
+		gen := genesis.Genesis{Version: tst.version}
+
+		alg := gen.RulesAt(1).HashAlgorithm
+		if alg != tst.exp {
+			t.Fatalf("%s: should hash with %s: got %s", tst.name, tst.exp, alg)
+		}
+
+		if got := signature.HashWith(alg, value); got != tst.hash {
+			t.Fatalf("%s: should hash with %s: got %s, exp %s", tst.name, alg, got, tst.hash)
+		}
+	}
+
+	// The genesis file shipped with the node starts a version 2 chain.
+	content, err := os.ReadFile("../../../zblock/genesis.json")
+	if err != nil {
+		t.Fatalf("reading genesis file: %s", err)
+	}
+	var gen genesis.Genesis
+	if err := json.Unmarshal(content, &gen); err != nil {
+		t.Fatalf("decoding genesis file: %s", err)
+	}
+	if gen.Version != genesis.ChainVersion2 || gen.HashAlgorithm() != signature.HashKeccak256 {
+		t.Fatalf("genesis file should be a version 2 chain hashed with %s: got version %d, %s", signature.HashKeccak256, gen.Version, gen.HashAlgorithm())
+	}
+
+	// An upgrade switches the algorithm from its height onwards.
+	gen.Version = genesis.ChainVersion1
+	gen.Upgrades = []genesis.Upgrade{{Height: 5, HashAlgorithm: signature.HashKeccak256}}
+	if got := gen.RulesAt(4).HashAlgorithm; got != signature.HashSHA256 {
+		t.Fatalf("should hash with %s before the upgrade: got %s", signature.HashSHA256, got)
+	}
+	if got := gen.RulesAt(5).HashAlgorithm; got != signature.HashKeccak256 {
+		t.Fatalf("should hash with %s from the upgrade: got %s", signature.HashKeccak256, got)
+	}
+}
@@ -0,0 +1,192 @@
+package keystore
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+)
+
+// ErrPassphrase is returned when an encrypted key can't be opened with the
+// passphrase provided.
+var ErrPassphrase = errors.New("wrong passphrase")
+
+// kdfIterations is how many rounds of PBKDF2 the passphrase goes through
+// before it becomes the encryption key.
+const kdfIterations = 600_000
+
+// encryptedKey represents a private key encrypted with a passphrase as it's
+// written to disk. The account is kept in the clear so the file can be
+// matched to an account without the passphrase.
+type encryptedKey struct {
+	Version    int                `json:"version"`
+	Account    database.AccountID `json:"account"`
+	KDF        string             `json:"kdf"`
+	Iterations int                `json:"iterations"`
+	Salt       []byte             `json:"salt"`
+	Nonce      []byte             `json:"nonce"`
+	Ciphertext []byte             `json:"ciphertext"`
+}
+
+// Encrypt seals the private key with the passphrase using AES-256-GCM and
+// a key derived from the passphrase with PBKDF2-SHA256.
+func Encrypt(privateKey *ecdsa.PrivateKey, passphrase []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, errors.New("passphrase is required")
+	}
+
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(passphrase, salt, kdfIterations)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	ek := encryptedKey{
+		Version:    1,
+		Account:    database.PublicKeyToAccountID(privateKey.PublicKey),
+		KDF:        "pbkdf2-sha256",
+		Iterations: kdfIterations,
+		Salt:       salt,
+		Nonce:      nonce,
+	}
+	ek.Ciphertext = aead.Seal(nil, nonce, crypto.FromECDSA(privateKey), []byte(ek.Account))
+
+	return json.MarshalIndent(ek, "", "\t")
+}
+
+// Decrypt opens a private key sealed by Encrypt.
+func Decrypt(data []byte, passphrase []byte) (*ecdsa.PrivateKey, error) {
+	var ek encryptedKey
+	if err := json.Unmarshal(data, &ek); err != nil {
+		return nil, fmt.Errorf("decoding encrypted key: %w", err)
+	}
+	if ek.Version != 1 || ek.KDF != "pbkdf2-sha256" || ek.Iterations <= 0 {
+		return nil, fmt.Errorf("unsupported encrypted key: version[%d] kdf[%s]", ek.Version, ek.KDF)
+	}
+
+	aead, err := newAEAD(passphrase, ek.Salt, ek.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	if len(ek.Nonce) != aead.NonceSize() {
+		return nil, errors.New("invalid nonce in encrypted key")
+	}
+
+	// The account is authenticated along with the key so it can't be
+	// swapped for another one in the file.
+	raw, err := aead.Open(nil, ek.Nonce, ek.Ciphertext, []byte(ek.Account))
+	if err != nil {
+		return nil, ErrPassphrase
+	}
+
+	return crypto.ToECDSA(raw)
+}
+
+// =============================================================================
+
+// Encrypted reads keys encrypted with a passphrase kept in name.key files in
+// a folder. The passphrase is asked for the first time a key is read.
+type Encrypted struct {
+	Folder     string
+	Passphrase func(name string) ([]byte, error)
+}
+
+// Path returns the file the key with the name is kept in.
+func (e Encrypted) Path(name string) string {
+	return filepath.Join(e.Folder, name+".key")
+}
+
+// PrivateKey reads the key from its file and decrypts it.
+func (e Encrypted) PrivateKey(ctx context.Context, name string) (*ecdsa.PrivateKey, error) {
+	path := e.Path(name)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%s: %w", path, ErrNotFound)
+		}
+		return nil, err
+	}
+
+	if e.Passphrase == nil {
+		return nil, errors.New("no passphrase provided")
+	}
+
+	passphrase, err := e.Passphrase(name)
+	if err != nil {
+		return nil, fmt.Errorf("reading passphrase: %w", err)
+	}
+
+	privateKey, err := Decrypt(data, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return privateKey, nil
+}
+
+// =============================================================================
+
+// newAEAD derives the encryption key from the passphrase.
+func newAEAD(passphrase []byte, salt []byte, iterations int) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(pbkdf2(passphrase, salt, iterations, 32))
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// pbkdf2 derives a key of the length from the password as described in
+// RFC 8018 using HMAC-SHA256.
+func pbkdf2(password []byte, salt []byte, iterations int, length int) []byte {
+	prf := hmac.New(sha256.New, password)
+	size := prf.Size()
+	blocks := (length + size - 1) / size
+
+	var key []byte
+	u := make([]byte, size)
+	t := make([]byte, size)
+	for i := 1; i <= blocks; i++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.Write(prf, binary.BigEndian, uint32(i))
+		u = prf.Sum(u[:0])
+		copy(t, u)
+
+		for n := 1; n < iterations; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+
+		key = append(key, t...)
+	}
+
+	return key[:length]
+}
@@ -0,0 +1,96 @@
+// Package keystore loads the private key a node signs blocks and messages
+// with. Keys can be read from the accounts folder, an environment variable,
+// a passphrase encrypted file or a secrets manager, so deployments don't
+// have to ship raw keys in the accounts folder.
+package keystore
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ErrNotFound is returned when a provider has no key for the name.
+var ErrNotFound = errors.New("key not found")
+
+// Provider loads the private key kept under a name. A secrets manager such
+// as a cloud KMS is plugged in by implementing it.
+type Provider interface {
+	PrivateKey(ctx context.Context, name string) (*ecdsa.PrivateKey, error)
+}
+
+// ProviderFunc adapts a function to a provider.
+type ProviderFunc func(ctx context.Context, name string) (*ecdsa.PrivateKey, error)
+
+// PrivateKey calls the function.
+func (f ProviderFunc) PrivateKey(ctx context.Context, name string) (*ecdsa.PrivateKey, error) {
+	return f(ctx, name)
+}
+
+// =============================================================================
+
+// Folder reads raw keys stored as hex in name.ecdsa files in a folder.
+type Folder string
+
+// Path returns the file the key with the name is kept in.
+func (f Folder) Path(name string) string {
+	return filepath.Join(string(f), name+".ecdsa")
+}
+
+// PrivateKey reads the key from its file.
+func (f Folder) PrivateKey(ctx context.Context, name string) (*ecdsa.PrivateKey, error) {
+	path := f.Path(name)
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%s: %w", path, ErrNotFound)
+	}
+
+	privateKey, err := crypto.LoadECDSA(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return privateKey, nil
+}
+
+// =============================================================================
+
+// Env reads the key as hex from an environment variable, ignoring the name.
+// The variable is cleared once it's read so it isn't passed on to child
+// processes.
+type Env string
+
+// PrivateKey reads the key from the variable.
+func (e Env) PrivateKey(ctx context.Context, name string) (*ecdsa.PrivateKey, error) {
+	value, ok := os.LookupEnv(string(e))
+	if !ok || value == "" {
+		return nil, fmt.Errorf("variable %s: %w", string(e), ErrNotFound)
+	}
+	os.Unsetenv(string(e))
+
+	privateKey, err := ParseHex(value)
+	if err != nil {
+		return nil, fmt.Errorf("variable %s: %w", string(e), err)
+	}
+
+	return privateKey, nil
+}
+
+// ParseHex parses a private key written as hex, with or without the 0x
+// prefix.
+func ParseHex(value string) (*ecdsa.PrivateKey, error) {
+	value = strings.TrimSpace(value)
+	value = strings.TrimPrefix(strings.TrimPrefix(value, "0x"), "0X")
+
+	privateKey, err := crypto.HexToECDSA(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+
+	return privateKey, nil
+}
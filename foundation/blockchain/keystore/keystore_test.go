@@ -0,0 +1,139 @@
+package keystore_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/keystore"
+)
+
+const keyHex = "fae85851bdf5c9f49923722ce38f3c1defcfd3619ef5453230a58ad805499959"
+
+func Test_Providers(t *testing.T) {
+	ctx := context.Background()
+
+	want, err := crypto.HexToECDSA(keyHex)
+	if err != nil {
+		t.Fatalf("Should be able to parse the key: %s", err)
+	}
+
+	folder := t.TempDir()
+	if err := crypto.SaveECDSA(filepath.Join(folder, "miner1.ecdsa"), want); err != nil {
+		t.Fatalf("Should be able to save the key: %s", err)
+	}
+
+	pk, err := keystore.Folder(folder).PrivateKey(ctx, "miner1")
+	if err != nil || !pk.Equal(want) {
+		t.Fatalf("Should read the key from the folder: %v", err)
+	}
+	if _, err := keystore.Folder(folder).PrivateKey(ctx, "miner2"); !errors.Is(err, keystore.ErrNotFound) {
+		t.Fatalf("Should not find a missing key: %v", err)
+	}
+
+	t.Setenv("TEST_NODE_KEY", "0x"+keyHex)
+	pk, err = keystore.Env("TEST_NODE_KEY").PrivateKey(ctx, "miner1")
+	if err != nil || !pk.Equal(want) {
+		t.Fatalf("Should read the key from the variable: %v", err)
+	}
+	if _, ok := os.LookupEnv("TEST_NODE_KEY"); ok {
+		t.Fatal("Should clear the variable once the key is read.")
+	}
+	if _, err := keystore.Env("TEST_NODE_KEY").PrivateKey(ctx, "miner1"); !errors.Is(err, keystore.ErrNotFound) {
+		t.Fatalf("Should not find a key in a missing variable: %v", err)
+	}
+}
+
+func Test_Encrypted(t *testing.T) {
+	ctx := context.Background()
+
+	want, err := crypto.HexToECDSA(keyHex)
+	if err != nil {
+		t.Fatalf("Should be able to parse the key: %s", err)
+	}
+
+	data, err := keystore.Encrypt(want, []byte("correct horse"))
+	if err != nil {
+		t.Fatalf("Should be able to encrypt the key: %s", err)
+	}
+	if bytes.Contains(data, []byte(keyHex)) {
+		t.Fatal("Should not write the key in the clear.")
+	}
+
+	if _, err := keystore.Decrypt(data, []byte("battery staple")); !errors.Is(err, keystore.ErrPassphrase) {
+		t.Fatalf("Should reject the wrong passphrase: %v", err)
+	}
+
+	folder := t.TempDir()
+	if err := os.WriteFile(filepath.Join(folder, "miner1.key"), data, 0600); err != nil {
+		t.Fatalf("Should be able to write the key: %s", err)
+	}
+
+	var asked string
+	enc := keystore.Encrypted{
+		Folder: folder,
+		Passphrase: func(name string) ([]byte, error) {
+			asked = name
+			return []byte("correct horse"), nil
+		},
+	}
+
+	pk, err := enc.PrivateKey(ctx, "miner1")
+	if err != nil || !pk.Equal(want) {
+		t.Fatalf("Should decrypt the key with the passphrase: %v", err)
+	}
+	if asked != "miner1" {
+		t.Fatalf("Should ask the passphrase for miner1, got %q", asked)
+	}
+}
+
+func Test_Vault(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v1/kv/data/node/miner1":
+			w.Write([]byte(`{"data":{"data":{"private_key":"` + keyHex + `"}}}`))
+		default:
+			http.Error(w, `{"errors":[]}`, http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	if _, err := keystore.NewVault(keystore.VaultConfig{Address: srv.URL}); err == nil {
+		t.Fatal("Should require a token.")
+	}
+
+	v, err := keystore.NewVault(keystore.VaultConfig{Address: srv.URL, Token: "token", Mount: "kv"})
+	if err != nil {
+		t.Fatalf("Should be able to construct the provider: %s", err)
+	}
+
+	pk, err := v.PrivateKey(context.Background(), "node/miner1")
+	if err != nil {
+		t.Fatalf("Should read the key from the secret: %s", err)
+	}
+	if got := hex.EncodeToString(crypto.FromECDSA(pk)); got != keyHex {
+		t.Fatalf("Should read the right key, got %s", got)
+	}
+
+	if _, err := v.PrivateKey(context.Background(), "node/miner2"); !errors.Is(err, keystore.ErrNotFound) {
+		t.Fatalf("Should not find a missing secret: %v", err)
+	}
+
+	denied, _ := keystore.NewVault(keystore.VaultConfig{Address: srv.URL, Token: "wrong", Mount: "kv"})
+	if _, err := denied.PrivateKey(context.Background(), "node/miner1"); err == nil || errors.Is(err, keystore.ErrNotFound) {
+		t.Fatalf("Should report a denied request: %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package keystore
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// VaultConfig represents what's needed to read keys from a HashiCorp Vault
+// KV version 2 secrets engine.
+type VaultConfig struct {
+	Address string       // Base URL of the server such as https://vault:8200.
+	Token   string       // Token allowed to read the secrets.
+	Mount   string       // Path the secrets engine is mounted at, secret when empty.
+	Field   string       // Field of the secret holding the hex key, private_key when empty.
+	Client  *http.Client // Used for the requests, the default client when nil.
+}
+
+// Vault reads keys kept as hex in secrets named after the key.
+type Vault struct {
+	address *url.URL
+	token   string
+	mount   string
+	field   string
+	client  *http.Client
+}
+
+// NewVault constructs a provider reading from the server in the config.
+func NewVault(cfg VaultConfig) (*Vault, error) {
+	address, err := url.Parse(cfg.Address)
+	if err != nil {
+		return nil, fmt.Errorf("parsing address: %w", err)
+	}
+	if address.Scheme != "http" && address.Scheme != "https" || address.Host == "" {
+		return nil, fmt.Errorf("address %q must be an http or https URL", cfg.Address)
+	}
+	if cfg.Token == "" {
+		return nil, errors.New("token is required")
+	}
+
+	mount := strings.Trim(cfg.Mount, "/")
+	if mount == "" {
+		mount = "secret"
+	}
+
+	field := cfg.Field
+	if field == "" {
+		field = "private_key"
+	}
+
+	client := cfg.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	v := Vault{
+		address: address,
+		token:   cfg.Token,
+		mount:   mount,
+		field:   field,
+		client:  client,
+	}
+
+	return &v, nil
+}
+
+// PrivateKey reads the latest version of the secret named after the key.
+func (v *Vault) PrivateKey(ctx context.Context, name string) (*ecdsa.PrivateKey, error) {
+	u := v.address.JoinPath("v1", v.mount, "data", name)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("vault secret %s/%s: %w", v.mount, name, ErrNotFound)
+	default:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("vault secret %s/%s: status %d: %s", v.mount, name, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var secret struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&secret); err != nil {
+		return nil, fmt.Errorf("decoding vault secret: %w", err)
+	}
+
+	value, ok := secret.Data.Data[v.field].(string)
+	if !ok {
+		return nil, fmt.Errorf("vault secret %s/%s has no %s field: %w", v.mount, name, v.field, ErrNotFound)
+	}
+
+	privateKey, err := ParseHex(value)
+	if err != nil {
+		return nil, fmt.Errorf("vault secret %s/%s: %w", v.mount, name, err)
+	}
+
+	return privateKey, nil
+}
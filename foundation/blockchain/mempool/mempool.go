@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"sort"
 	"strings"
 	"sync"
 
@@ -46,8 +47,9 @@ func (mp *Mempool) Count() int {
 	return len(mp.pool)
 }
 
-// Upsert adds or replaces a transaction in the mempool
-func (mp *Mempool) Upsert(tx database.BlockTx) error {
+// Upsert adds or replaces a transaction in the mempool. When a transaction
+// with the same account and nonce is replaced, it's returned.
+func (mp *Mempool) Upsert(tx database.BlockTx) (database.BlockTx, bool, error) {
 	mp.mu.Lock()
 	defer mp.mu.Unlock()
 
@@ -60,36 +62,76 @@ func (mp *Mempool) Upsert(tx database.BlockTx) error {
 	// For now, this blockchain is not imposing any limits.
 	key, err := mapKey(tx)
 	if err != nil {
-		return err
+		return database.BlockTx{}, false, err
 	}
 
 	// Ethereum requires a 10% bump in the tip to replace an existing
 	// transaction in the mempool and so do we. We want to limit users
 	// from this sort of behavior.
-	if etx, exists := mp.pool[key]; exists {
+	etx, exists := mp.pool[key]
+	if exists {
 		if tx.Tip < uint64(math.Round(float64(etx.Tip)*1.10)) {
-			return errors.New("replacing a transaction requires a 10% bump in the tip")
+			return database.BlockTx{}, false, errors.New("replacing a transaction requires a 10% bump in the tip")
 		}
 	}
 
 	mp.pool[key] = tx
 
-	return nil
+	return etx, exists, nil
 }
 
-// Delete removes a transaction from the mempool.
-func (mp *Mempool) Delete(tx database.BlockTx) error {
+// Delete removes a transaction from the mempool and reports whether it was
+// in the mempool.
+func (mp *Mempool) Delete(tx database.BlockTx) (bool, error) {
 	mp.mu.Lock()
 	defer mp.mu.Unlock()
 
 	key, err := mapKey(tx)
 	if err != nil {
-		return err
+		return false, err
 	}
 
+	_, exists := mp.pool[key]
 	delete(mp.pool, key)
 
-	return nil
+	return exists, nil
+}
+
+// DeleteExpired removes the transactions that can no longer be mined in the
+// block with the specified number and returns them.
+func (mp *Mempool) DeleteExpired(blockNumber uint64) []database.BlockTx {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	var removed []database.BlockTx
+	for key, tx := range mp.pool {
+		if tx.Expired(blockNumber) {
+			delete(mp.pool, key)
+			removed = append(removed, tx)
+		}
+	}
+
+	return removed
+}
+
+// ByAccount returns the transactions in the mempool sent by the
+// specified account, ordered by nonce.
+func (mp *Mempool) ByAccount(accountID database.AccountID) []database.BlockTx {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+
+	var trans []database.BlockTx
+	for key, tx := range mp.pool {
+		if accountFromMapKey(key) == accountID {
+			trans = append(trans, tx)
+		}
+	}
+
+	sort.Slice(trans, func(i, j int) bool {
+		return trans[i].Nonce < trans[j].Nonce
+	})
+
+	return trans
 }
 
 // Truncate clears all the transactions from the pool
@@ -138,6 +180,85 @@ func (mp *Mempool) PickBest(howMany ...uint16) []database.BlockTx {
 	return mp.selectFn(m, number)
 }
 
+// Position represents where a transaction would be picked from the mempool
+// if it were added. Position counts from 1 across the blocks and Blocks is
+// how many blocks until the one the transaction would be mined in. When the
+// transaction isn't picked within the blocks looked at, both are the least
+// they could be.
+type Position struct {
+	Position int
+	Blocks   int
+	Included bool // Whether the transaction is picked within the blocks looked at.
+}
+
+// previewAccount is the account a previewed transaction is taken to be
+// sent from so it isn't mixed up with the transactions in the mempool.
+const previewAccount = database.AccountID("preview")
+
+// Preview returns where the transaction would be picked if it were added to
+// the mempool, by picking perBlock transactions at a time with the configured
+// sort strategy for at most maxBlocks blocks. The transaction is taken to be
+// the only one of its account. The mempool isn't changed.
+func (mp *Mempool) Preview(tx database.BlockTx, perBlock int, maxBlocks int) Position {
+	tx.FromID = previewAccount
+
+	mp.mu.RLock()
+	remaining := make(map[string]database.BlockTx, len(mp.pool)+1)
+	for key, tx := range mp.pool {
+		remaining[key] = tx
+	}
+	mp.mu.RUnlock()
+
+	key, err := mapKey(tx)
+	if err != nil {
+		return Position{}
+	}
+	remaining[key] = tx
+
+	var picked int
+	for block := 1; block <= maxBlocks && len(remaining) > 0; block++ {
+
+		// The selection algorithms reorder the slices they are given, so
+		// they are built again for every block.
+		m := make(map[database.AccountID][]database.BlockTx)
+		for key, tx := range remaining {
+			m[accountFromMapKey(key)] = append(m[accountFromMapKey(key)], tx)
+		}
+
+		trans := mp.selectFn(m, perBlock)
+		if len(trans) == 0 {
+			break
+		}
+
+		// Within the block the transaction ranks behind the ones with a
+		// better tip, the order of the block doesn't change when it's mined.
+		for _, ptx := range trans {
+			if ptx.FromID != previewAccount {
+				continue
+			}
+
+			rank := 1
+			for _, other := range trans {
+				if other.Tip > tx.Tip {
+					rank++
+				}
+			}
+			return Position{Position: picked + rank, Blocks: block, Included: true}
+		}
+
+		for _, tx := range trans {
+			key, err := mapKey(tx)
+			if err != nil {
+				return Position{}
+			}
+			delete(remaining, key)
+		}
+		picked += len(trans)
+	}
+
+	return Position{Position: picked + 1, Blocks: maxBlocks + 1}
+}
+
 // ------------------------------------------
 
 // mapKey is used to generate a map key.
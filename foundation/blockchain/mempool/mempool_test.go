@@ -0,0 +1,131 @@
+package mempool_test
+
+import (
+	"crypto/ecdsa"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+	"github.com/qcbit/blockchain/foundation/blockchain/mempool"
+)
+
+func Test_Preview(t *testing.T) {
+	mp, err := mempool.NewWithStrategy("tip")
+	if err != nil {
+		t.Fatalf("constructing mempool: %v", err)
+	}
+
+	// Four accounts each with a transaction, tipping 10, 20, 30 and 40.
+	for i := 1; i <= 4; i++ {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("generating key: %v", err)
+		}
+		if _, _, err := mp.Upsert(newTx(t, key, 1, uint64(i*10), 0)); err != nil {
+			t.Fatalf("adding tx: %v", err)
+		}
+	}
+
+	preview := func(tip uint64) database.BlockTx {
+		return database.BlockTx{SignedTx: database.SignedTx{Tx: database.Tx{Nonce: 1, Tip: tip}}}
+	}
+
+	tt := []struct {
+		name     string
+		tip      uint64
+		maxBlock int
+		exp      mempool.Position
+	}{
+		{"best tip", 50, 10, mempool.Position{Position: 1, Blocks: 1, Included: true}},
+		{"second block", 25, 10, mempool.Position{Position: 3, Blocks: 2, Included: true}},
+		{"last", 0, 10, mempool.Position{Position: 5, Blocks: 3, Included: true}},
+		{"beyond", 0, 2, mempool.Position{Position: 5, Blocks: 3}},
+	}
+
+	for _, tst := range tt {
+		if got := mp.Preview(preview(tst.tip), 2, tst.maxBlock); got != tst.exp {
+			t.Fatalf("%s: got %+v, exp %+v", tst.name, got, tst.exp)
+		}
+	}
+
+	if mp.Count() != 4 {
+		t.Fatalf("previewing should leave the mempool alone, got %d transactions", mp.Count())
+	}
+}
+
+func Test_Changes(t *testing.T) {
+	mp, err := mempool.New()
+	if err != nil {
+		t.Fatalf("constructing mempool: %v", err)
+	}
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+
+	first := newTx(t, key, 1, 10, 0)
+	if _, replaced, err := mp.Upsert(first); err != nil || replaced {
+		t.Fatalf("adding tx: replaced %t, err %v", replaced, err)
+	}
+
+	// A replacement needs a 10% bump in the tip and returns the
+	// transaction it replaced.
+	if _, _, err := mp.Upsert(newTx(t, key, 1, 10, 0)); err == nil {
+		t.Fatal("replacing without a bump in the tip should be rejected")
+	}
+	second := newTx(t, key, 1, 11, 0)
+	old, replaced, err := mp.Upsert(second)
+	if err != nil || !replaced {
+		t.Fatalf("replacing tx: replaced %t, err %v", replaced, err)
+	}
+	if old.Tip != first.Tip {
+		t.Fatalf("replaced tx should be returned, got tip %d", old.Tip)
+	}
+
+	if exists, err := mp.Delete(second); err != nil || !exists {
+		t.Fatalf("deleting tx: exists %t, err %v", exists, err)
+	}
+	if exists, err := mp.Delete(second); err != nil || exists {
+		t.Fatalf("deleting tx twice: exists %t, err %v", exists, err)
+	}
+
+	// Only the transaction valid until block 5 is removed once block 6
+	// is next.
+	if _, _, err := mp.Upsert(newTx(t, key, 1, 10, 5)); err != nil {
+		t.Fatalf("adding tx: %v", err)
+	}
+	if _, _, err := mp.Upsert(newTx(t, key, 2, 10, 0)); err != nil {
+		t.Fatalf("adding tx: %v", err)
+	}
+	if expired := mp.DeleteExpired(5); len(expired) != 0 {
+		t.Fatalf("no tx should be expired yet, got %d", len(expired))
+	}
+	expired := mp.DeleteExpired(6)
+	if len(expired) != 1 || expired[0].Nonce != 1 {
+		t.Fatalf("tx with nonce 1 should be expired, got %+v", expired)
+	}
+	if mp.Count() != 1 {
+		t.Fatalf("one tx should be left, got %d", mp.Count())
+	}
+}
+
+// newTx constructs a transaction from the key's account valid until the
+// specified block, zero for no limit.
+func newTx(t *testing.T, key *ecdsa.PrivateKey, nonce uint64, tip uint64, validUntil uint64) database.BlockTx {
+	to := database.AccountID("0xbEE6ACE826eC3DE1B6349888B9151B92522F7F76")
+
+	tx, err := database.NewTx(1, database.PublicKeyToAccountID(key.PublicKey), to, 1, nonce, tip, nil)
+	if err != nil {
+		t.Fatalf("constructing tx: %v", err)
+	}
+	tx.ValidUntilBlock = validUntil
+
+	signedTx, err := tx.Sign(key)
+	if err != nil {
+		t.Fatalf("signing tx: %v", err)
+	}
+
+	return database.NewBlockTx(signedTx, 1, 1)
+}
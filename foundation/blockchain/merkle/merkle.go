@@ -32,6 +32,7 @@ type Tree[T Hashable[T]] struct {
 	Leafs        []*Node[T]
 	MerkleRoot   []byte
 	hashStrategy func() hash.Hash
+	leafHash     func(value T) ([]byte, error)
 }
 
 // WithHashStrategy is used to change the default hash strategy of using sha256
@@ -42,6 +43,16 @@ func WithHashStrategy[T Hashable[T]](hashStrategy func() hash.Hash) func(t *Tree
 	}
 }
 
+// WithLeafHash is used to hash the data in the leaves with the specified
+// function instead of the Hash method of the data when constructing a new
+// tree. This allows the data to be hashed with the same algorithm as the
+// tree.
+func WithLeafHash[T Hashable[T]](leafHash func(value T) ([]byte, error)) func(t *Tree[T]) {
+	return func(t *Tree[T]) {
+		t.leafHash = leafHash
+	}
+}
+
 // NewTree constructs a new merkle tree that uses data of some type T that
 // exhibits the behavior defined by the Hashable interface.
 func NewTree[T Hashable[T]](values []T, options ...func(t *Tree[T])) (*Tree[T], error) {
@@ -49,6 +60,7 @@ func NewTree[T Hashable[T]](values []T, options ...func(t *Tree[T])) (*Tree[T],
 
 	t := Tree[T]{
 		hashStrategy: defaultHashStrategy,
+		leafHash:     T.Hash,
 	}
 
 	for _, option := range options {
@@ -72,7 +84,7 @@ func (t *Tree[T]) Generate(values []T) error {
 
 	var leafs []*Node[T]
 	for _, value := range values {
-		hash, err := value.Hash()
+		hash, err := t.leafHash(value)
 		if err != nil {
 			return err
 		}
@@ -295,7 +307,7 @@ type Node[T Hashable[T]] struct {
 // each level and returning the resulting hash of the node.
 func (n *Node[T]) verify() ([]byte, error) {
 	if n.leaf {
-		return n.Value.Hash()
+		return n.Tree.leafHash(n.Value)
 	}
 
 	rightBytes, err := n.Right.verify()
@@ -319,7 +331,7 @@ func (n *Node[T]) verify() ([]byte, error) {
 // CalculateHash is a helper function that calculates the hash of the node.
 func (n *Node[T]) CalculateHash() ([]byte, error) {
 	if n.leaf {
-		return n.Value.Hash()
+		return n.Tree.leafHash(n.Value)
 	}
 
 	h := n.Tree.hashStrategy()
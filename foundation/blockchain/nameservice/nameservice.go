@@ -60,6 +60,16 @@ func (ns *NameService) Lookup(accountID database.AccountID) string {
 	return name
 }
 
+// Resolve returns the account ID for the given account name.
+func (ns *NameService) Resolve(name string) (database.AccountID, bool) {
+	for accountID, accountName := range ns.accounts {
+		if accountName == name {
+			return accountID, true
+		}
+	}
+	return "", false
+}
+
 // Copy returns a copy of the NameService.
 func (ns *NameService) Copy() map[database.AccountID]string {
 	accounts := make(map[database.AccountID]string, len(ns.accounts))
@@ -0,0 +1,135 @@
+// Package netfault provides a transport for the calls nodes make to each
+// other that adds latency, drops calls and reorders them so consensus and
+// sync can be tested under a bad network. It's for testing only, a node in
+// a real network should never run with it.
+package netfault
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrDropped is returned for a call the transport decided to drop.
+var ErrDropped = errors.New("call dropped by fault injection")
+
+// Config represents the faults injected into the calls. The same seed
+// produces the same faults for the same sequence of calls so experiments
+// can be repeated.
+type Config struct {
+	Latency      time.Duration // Added to every call.
+	Jitter       time.Duration // Most random latency added on top.
+	DropRate     float64       // Fraction of calls dropped, from 0 to 1.
+	ReorderRate  float64       // Fraction of calls held back so later calls overtake them.
+	ReorderDelay time.Duration // How long a call is held back.
+	Seed         int64
+}
+
+// Enabled reports whether the configuration injects any faults.
+func (cfg Config) Enabled() bool {
+	return cfg.Latency > 0 || cfg.Jitter > 0 || cfg.DropRate > 0 || cfg.ReorderRate > 0
+}
+
+// Validate checks the rates are fractions.
+func (cfg Config) Validate() error {
+	if cfg.DropRate < 0 || cfg.DropRate > 1 {
+		return fmt.Errorf("drop rate %v must be between 0 and 1", cfg.DropRate)
+	}
+	if cfg.ReorderRate < 0 || cfg.ReorderRate > 1 {
+		return fmt.Errorf("reorder rate %v must be between 0 and 1", cfg.ReorderRate)
+	}
+	if cfg.Latency < 0 || cfg.Jitter < 0 || cfg.ReorderDelay < 0 {
+		return errors.New("latency, jitter and reorder delay can't be negative")
+	}
+	return nil
+}
+
+// =============================================================================
+
+// Transport wraps a transport and injects faults into the calls made
+// through it.
+type Transport struct {
+	cfg  Config
+	base http.RoundTripper
+
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// New constructs a transport injecting the configured faults into the calls
+// made through the base transport. The default transport is used when base
+// is nil.
+func New(cfg Config, base http.RoundTripper) (*Transport, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	t := Transport{
+		cfg:  cfg,
+		base: base,
+		rnd:  rand.New(rand.NewSource(cfg.Seed)),
+	}
+
+	return &t, nil
+}
+
+// RoundTrip implements the http.RoundTripper interface. The call waits out
+// its latency before it's dropped or made so a dropped call looks like a
+// call that timed out.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	delay, drop := t.plan()
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			closeBody(req)
+			return nil, req.Context().Err()
+		}
+	}
+
+	if drop {
+		closeBody(req)
+		return nil, fmt.Errorf("%w: %s %s", ErrDropped, req.Method, req.URL)
+	}
+
+	return t.base.RoundTrip(req)
+}
+
+// plan decides the faults of the next call. The decisions are made in the
+// order the calls are made so a seed always plans the same faults.
+func (t *Transport) plan() (delay time.Duration, drop bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delay = t.cfg.Latency
+	if t.cfg.Jitter > 0 {
+		delay += time.Duration(t.rnd.Int63n(int64(t.cfg.Jitter)))
+	}
+
+	if t.rnd.Float64() < t.cfg.ReorderRate {
+		delay += t.cfg.ReorderDelay
+	}
+
+	drop = t.rnd.Float64() < t.cfg.DropRate
+
+	return delay, drop
+}
+
+// closeBody closes the request body as a transport must when it doesn't
+// send the request.
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
@@ -0,0 +1,65 @@
+package netfault_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/netfault"
+)
+
+func Test_Faults(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	// calls makes a number of calls and returns which of them were dropped.
+	calls := func(cfg netfault.Config, n int) []bool {
+		transport, err := netfault.New(cfg, nil)
+		if err != nil {
+			t.Fatalf("constructing transport: %v", err)
+		}
+		client := http.Client{Transport: transport}
+
+		dropped := make([]bool, n)
+		for i := range dropped {
+			resp, err := client.Get(srv.URL)
+			switch {
+			case errors.Is(err, netfault.ErrDropped):
+				dropped[i] = true
+			case err != nil:
+				t.Fatalf("call %d: %v", i, err)
+			default:
+				resp.Body.Close()
+			}
+		}
+		return dropped
+	}
+
+	cfg := netfault.Config{DropRate: 0.5, Seed: 7}
+
+	first, second := calls(cfg, 40), calls(cfg, 40)
+	var drops int
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("the same seed should drop the same calls, call %d differs", i)
+		}
+		if first[i] {
+			drops++
+		}
+	}
+	if drops == 0 || drops == len(first) {
+		t.Fatalf("about half the calls should be dropped: got %d of %d", drops, len(first))
+	}
+
+	start := time.Now()
+	calls(netfault.Config{Latency: 50 * time.Millisecond}, 2)
+	if d := time.Since(start); d < 100*time.Millisecond {
+		t.Fatalf("calls should be delayed by the latency, took %v", d)
+	}
+
+	if _, err := netfault.New(netfault.Config{DropRate: 2}, nil); err == nil {
+		t.Fatal("a drop rate above 1 should be rejected")
+	}
+}
@@ -0,0 +1,131 @@
+// Package payout splits the mining reward of the blocks a node mines among
+// the contributors of a mining pool and keeps a ledger of the payouts.
+package payout
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+)
+
+// TxData marks the transfers that pay out a mining reward so they can be
+// told apart from the other transactions sent by the pool account.
+var TxData = []byte("pool payout")
+
+// IsPayout reports whether the transaction pays out a mining reward.
+func IsPayout(tx database.Tx) bool {
+	return bytes.Equal(tx.Data, TxData)
+}
+
+// Share represents the percentage of every mining reward that is paid to
+// a contributor of the pool.
+type Share struct {
+	AccountID database.AccountID `json:"account"`
+	Percent   uint64             `json:"percent"`
+}
+
+// Validate checks the shares add up to no more than the whole reward.
+func Validate(shares []Share) error {
+	var total uint64
+	for _, share := range shares {
+		if !share.AccountID.IsAccountID() {
+			return fmt.Errorf("invalid contributor account %q", share.AccountID)
+		}
+		if share.Percent == 0 {
+			return fmt.Errorf("contributor %s has no share", share.AccountID)
+		}
+		total += share.Percent
+	}
+
+	if total > 100 {
+		return errors.New("shares add up to more than 100 percent")
+	}
+
+	return nil
+}
+
+// Amount represents what a contributor is paid out of a mining reward.
+type Amount struct {
+	Share
+	Amount uint64 `json:"amount"`
+}
+
+// Split divides the reward by the shares. Amounts are rounded down and
+// what's left stays with the pool account.
+func Split(reward uint64, shares []Share) []Amount {
+	amounts := make([]Amount, len(shares))
+	for i, share := range shares {
+		amounts[i] = Amount{
+			Share:  share,
+			Amount: reward * share.Percent / 100,
+		}
+	}
+
+	return amounts
+}
+
+// =============================================================================
+
+// Transfer represents a transfer that paid a contributor and its outcome.
+type Transfer struct {
+	AccountID database.AccountID `json:"account"`
+	Amount    uint64             `json:"amount"`
+	TxHash    string             `json:"tx_hash"`
+	Status    string             `json:"status"`
+	Error     string             `json:"error,omitempty"`
+}
+
+// Payout represents how the reward of a block was paid out.
+type Payout struct {
+	BlockNumber uint64     `json:"block_number"`
+	BlockHash   string     `json:"block_hash"`
+	Reward      uint64     `json:"reward"`
+	Paid        uint64     `json:"paid"`
+	Transfers   []Transfer `json:"transfers"`
+}
+
+// Ledger keeps the most recent payouts.
+type Ledger struct {
+	mu      sync.RWMutex
+	max     int
+	payouts []Payout
+}
+
+// NewLedger constructs a ledger that keeps up to max payouts.
+func NewLedger(max int) *Ledger {
+	return &Ledger{
+		max: max,
+	}
+}
+
+// Add records the payout, dropping the oldest one when the ledger is full.
+func (l *Ledger) Add(payout Payout) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.payouts = append(l.payouts, payout)
+	if len(l.payouts) > l.max {
+		l.payouts = l.payouts[len(l.payouts)-l.max:]
+	}
+}
+
+// List returns up to limit payouts starting with the most recent. A limit
+// of zero or less returns every payout.
+func (l *Ledger) List(limit int) []Payout {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if limit <= 0 || limit > len(l.payouts) {
+		limit = len(l.payouts)
+	}
+
+	out := make([]Payout, 0, limit)
+	for i := len(l.payouts) - 1; i >= 0 && len(out) < limit; i-- {
+		out = append(out, l.payouts[i])
+	}
+
+	return out
+}
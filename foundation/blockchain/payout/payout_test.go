@@ -0,0 +1,48 @@
+package payout_test
+
+import (
+	"testing"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/payout"
+)
+
+const (
+	alice = "0xF01813E4B85e178A83e29B8E7bF26BD830a25f32"
+	bob   = "0xbEE6ACE826eC3DE1B6349888B9151B92522F7F76"
+)
+
+func Test_Split(t *testing.T) {
+	shares := []payout.Share{
+		{AccountID: alice, Percent: 50},
+		{AccountID: bob, Percent: 33},
+	}
+	if err := payout.Validate(shares); err != nil {
+		t.Fatalf("shares should validate: %v", err)
+	}
+
+	amounts := payout.Split(700, shares)
+	if amounts[0].Amount != 350 || amounts[1].Amount != 231 {
+		t.Fatalf("amounts should be rounded down: got %d and %d", amounts[0].Amount, amounts[1].Amount)
+	}
+
+	over := append(shares, payout.Share{AccountID: alice, Percent: 20})
+	if err := payout.Validate(over); err == nil {
+		t.Fatal("shares over 100 percent should not validate")
+	}
+}
+
+func Test_Ledger(t *testing.T) {
+	ledger := payout.NewLedger(2)
+	for n := uint64(1); n <= 3; n++ {
+		ledger.Add(payout.Payout{BlockNumber: n})
+	}
+
+	payouts := ledger.List(0)
+	if len(payouts) != 2 || payouts[0].BlockNumber != 3 || payouts[1].BlockNumber != 2 {
+		t.Fatalf("ledger should keep the latest payouts first: got %+v", payouts)
+	}
+
+	if payouts := ledger.List(1); len(payouts) != 1 || payouts[0].BlockNumber != 3 {
+		t.Fatalf("ledger should respect the limit: got %+v", payouts)
+	}
+}
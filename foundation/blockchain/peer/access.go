@@ -0,0 +1,86 @@
+package peer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrPeerDenied is returned when a peer isn't allowed to take part in
+// the network.
+var ErrPeerDenied = errors.New("peer denied")
+
+// Access represents which peers may register with this node, gossip to it
+// and propose blocks, for permissioned networks such as a private
+// consortium. Peers are named by host and by the account of the key they
+// sign their messages with. A denied peer is always rejected. An empty
+// allowlist allows every peer that isn't denied.
+type Access struct {
+	AllowHosts []string
+	DenyHosts  []string
+	AllowKeys  []string
+	DenyKeys   []string
+}
+
+// Enabled reports whether any peer is restricted.
+func (a Access) Enabled() bool {
+	return len(a.AllowHosts) > 0 || len(a.DenyHosts) > 0 || len(a.AllowKeys) > 0 || len(a.DenyKeys) > 0
+}
+
+// CheckHost returns ErrPeerDenied when the host isn't allowed. Hosts are
+// compared normalized so the lists can spell them either way.
+func (a Access) CheckHost(host string) error {
+	return check("host", NormalizeHost(host), normalizeHosts(a.AllowHosts), normalizeHosts(a.DenyHosts))
+}
+
+// CheckKey returns ErrPeerDenied when the account a peer signs with
+// isn't allowed.
+func (a Access) CheckKey(account string) error {
+	return check("key", account, a.AllowKeys, a.DenyKeys)
+}
+
+// Check returns ErrPeerDenied when either the host or the account the
+// peer signed its message with isn't allowed.
+func (a Access) Check(host string, account string) error {
+	if err := a.CheckHost(host); err != nil {
+		return err
+	}
+
+	return a.CheckKey(account)
+}
+
+// check applies the allowlist and denylist to the value. Accounts are
+// compared without regard to case since they are hex.
+func check(kind string, value string, allow []string, deny []string) error {
+	for _, v := range deny {
+		if strings.EqualFold(v, value) {
+			return fmt.Errorf("%w: %s %s is denied", ErrPeerDenied, kind, value)
+		}
+	}
+
+	if len(allow) == 0 {
+		return nil
+	}
+
+	for _, v := range allow {
+		if strings.EqualFold(v, value) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s %s is not allowed", ErrPeerDenied, kind, value)
+}
+
+// normalizeHosts returns the hosts normalized.
+func normalizeHosts(hosts []string) []string {
+	if len(hosts) == 0 {
+		return nil
+	}
+
+	normalized := make([]string, len(hosts))
+	for i, host := range hosts {
+		normalized[i] = NormalizeHost(host)
+	}
+
+	return normalized
+}
@@ -0,0 +1,60 @@
+package peer_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/peer"
+)
+
+func Test_Access(t *testing.T) {
+	const (
+		member   = "0.0.0.0:9080"
+		outsider = "0.0.0.0:9180"
+		key      = "0xF01813E4B85e178A83e29B8E7bF26BD830a25f32"
+		other    = "0xdd6B972ffcc631a62CAE1BB9d80b7ff429c8ebA4"
+	)
+
+	var open peer.Access
+	if open.Enabled() || open.Check(outsider, other) != nil {
+		t.Fatal("access without lists should allow every peer")
+	}
+
+	access := peer.Access{
+		AllowHosts: []string{member, outsider},
+		DenyHosts:  []string{outsider},
+		AllowKeys:  []string{key},
+	}
+
+	tt := []struct {
+		name    string
+		host    string
+		account string
+		allowed bool
+	}{
+		{"member", member, key, true},
+		{"key in lower case", member, "0xf01813e4b85e178a83e29b8e7bf26bd830a25f32", true},
+		{"deny wins over allow", outsider, key, false},
+		{"host not allowed", "0.0.0.0:9280", key, false},
+		{"key not allowed", member, other, false},
+	}
+
+	for _, tst := range tt {
+		err := access.Check(tst.host, tst.account)
+		if tst.allowed && err != nil {
+			t.Fatalf("%s: should be allowed: %v", tst.name, err)
+		}
+		if !tst.allowed && !errors.Is(err, peer.ErrPeerDenied) {
+			t.Fatalf("%s: should be denied, got %v", tst.name, err)
+		}
+	}
+
+	// Registering only names the host.
+	if err := access.CheckHost(member); err != nil {
+		t.Fatalf("member host should be allowed: %v", err)
+	}
+	denied := peer.Access{DenyKeys: []string{other}}
+	if err := denied.CheckKey(other); !errors.Is(err, peer.ErrPeerDenied) {
+		t.Fatalf("denied key should be rejected, got %v", err)
+	}
+}
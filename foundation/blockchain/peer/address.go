@@ -0,0 +1,80 @@
+package peer
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// resolveTimeout is how long resolving the name of a peer may take before
+// the peer is known by its name alone.
+const resolveTimeout = 2 * time.Second
+
+// NormalizeHost returns the host:port of a peer in one form so the same node
+// isn't known twice under different spellings. Names are lowercased without
+// a trailing dot, IPv4 addresses mapped into IPv6 are written as IPv4, IPv6
+// addresses are compressed and bracketed and ports lose leading zeros. A
+// host that can't be parsed is returned trimmed.
+func NormalizeHost(host string) string {
+	host = strings.TrimSpace(host)
+
+	name, port, err := net.SplitHostPort(host)
+	if err != nil {
+		// A bare IPv6 address has too many colons to carry a port.
+		if addr, err := netip.ParseAddr(strings.Trim(host, "[]")); err == nil {
+			return addr.Unmap().String()
+		}
+		return strings.ToLower(strings.TrimSuffix(host, "."))
+	}
+
+	if n, err := strconv.ParseUint(port, 10, 16); err == nil {
+		port = strconv.FormatUint(n, 10)
+	}
+
+	if addr, err := netip.ParseAddr(name); err == nil {
+		return net.JoinHostPort(addr.Unmap().String(), port)
+	}
+
+	return net.JoinHostPort(strings.ToLower(strings.TrimSuffix(name, ".")), port)
+}
+
+// isName reports whether the normalized host is named rather than
+// addressed, so resolving it can find the addresses it's also known by.
+func isName(host string) bool {
+	name, _, err := net.SplitHostPort(host)
+	if err != nil {
+		return false
+	}
+
+	_, err = netip.ParseAddr(name)
+	return err != nil
+}
+
+// resolve returns the addresses the named host is also known by, with the
+// port of the host. Nothing is returned when there's no resolver or the
+// name doesn't resolve.
+func resolve(resolver Resolver, host string) []string {
+	if resolver == nil || !isName(host) {
+		return nil
+	}
+
+	name, port, _ := net.SplitHostPort(host)
+
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+
+	ips, err := resolver.LookupHost(ctx, name)
+	if err != nil {
+		return nil
+	}
+
+	addrs := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		addrs = append(addrs, NormalizeHost(net.JoinHostPort(ip, port)))
+	}
+
+	return addrs
+}
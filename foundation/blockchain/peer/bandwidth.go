@@ -0,0 +1,130 @@
+package peer
+
+import (
+	"sync"
+	"time"
+)
+
+// Usage represents the traffic exchanged with a peer.
+type Usage struct {
+	BytesSent        int64 `json:"bytes_sent"`
+	BytesReceived    int64 `json:"bytes_received"`
+	RequestsSent     int64 `json:"requests_sent"`
+	RequestsReceived int64 `json:"requests_received"`
+}
+
+// meter tracks the traffic of a single peer and how much data can be sent
+// to the peer before the cap is reached.
+type meter struct {
+	usage  Usage
+	tokens float64
+	last   time.Time
+}
+
+// Bandwidth tracks the traffic exchanged with each peer and caps the rate
+// data is sent to any one peer so a syncing peer can't saturate the uplink.
+type Bandwidth struct {
+	mu    sync.Mutex
+	limit int64 // Bytes per second sent to a peer, zero is unlimited.
+	peers map[string]*meter
+}
+
+// NewBandwidth constructs a bandwidth tracker sending at most limit bytes
+// per second to any one peer. A limit of zero doesn't cap the traffic.
+func NewBandwidth(limit int64) *Bandwidth {
+	return &Bandwidth{
+		limit: limit,
+		peers: make(map[string]*meter),
+	}
+}
+
+// Limit returns the number of bytes per second that can be sent to a peer.
+func (b *Bandwidth) Limit() int64 {
+	return b.limit
+}
+
+// RequestSent counts a request made to the peer.
+func (b *Bandwidth) RequestSent(host string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.meter(host).usage.RequestsSent++
+}
+
+// RequestReceived counts a request made by the peer.
+func (b *Bandwidth) RequestReceived(host string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.meter(host).usage.RequestsReceived++
+}
+
+// Received counts the bytes received from the peer.
+func (b *Bandwidth) Received(host string, n int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.meter(host).usage.BytesReceived += n
+}
+
+// Send counts the bytes about to be sent to the peer and blocks until they
+// can be sent without going over the cap.
+func (b *Bandwidth) Send(host string, n int64) {
+	b.mu.Lock()
+
+	m := b.meter(host)
+	m.usage.BytesSent += n
+
+	if b.limit <= 0 {
+		b.mu.Unlock()
+		return
+	}
+
+	// The peer earns the limit in bytes every second up to a second's
+	// worth. Sending more than was earned puts the peer in debt which
+	// is paid off by waiting.
+	now := time.Now()
+	m.tokens += now.Sub(m.last).Seconds() * float64(b.limit)
+	if m.tokens > float64(b.limit) {
+		m.tokens = float64(b.limit)
+	}
+	m.last = now
+	m.tokens -= float64(n)
+
+	var wait time.Duration
+	if m.tokens < 0 {
+		wait = time.Duration(-m.tokens / float64(b.limit) * float64(time.Second))
+	}
+	b.mu.Unlock()
+
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+}
+
+// Usage returns a copy of the traffic exchanged with each peer.
+func (b *Bandwidth) Usage() map[string]Usage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	usage := make(map[string]Usage, len(b.peers))
+	for host, m := range b.peers {
+		usage[host] = m.usage
+	}
+
+	return usage
+}
+
+// meter returns the meter for the peer. The caller must hold the lock.
+func (b *Bandwidth) meter(host string) *meter {
+	m, exists := b.peers[host]
+	if !exists {
+		m = &meter{
+			tokens: float64(b.limit),
+			last:   time.Now(),
+		}
+		b.peers[host] = m
+	}
+
+	return m
+}
@@ -0,0 +1,43 @@
+package peer_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/peer"
+)
+
+func Test_BandwidthCap(t *testing.T) {
+	const host = "0.0.0.0:9280"
+
+	bw := peer.NewBandwidth(10_000)
+
+	// A second's worth of the cap is sent right away.
+	start := time.Now()
+	bw.Send(host, 10_000)
+	if d := time.Since(start); d > 50*time.Millisecond {
+		t.Fatalf("expected the burst to be sent right away, took %v", d)
+	}
+
+	// Going over the cap waits for the peer to earn the bytes.
+	start = time.Now()
+	bw.Send(host, 2_000)
+	if d := time.Since(start); d < 150*time.Millisecond {
+		t.Fatalf("expected to wait for the cap, took %v", d)
+	}
+
+	// Other peers have their own cap.
+	start = time.Now()
+	bw.Send("0.0.0.0:9380", 10_000)
+	if d := time.Since(start); d > 50*time.Millisecond {
+		t.Fatalf("expected another peer to be sent to right away, took %v", d)
+	}
+
+	bw.RequestReceived(host)
+	bw.Received(host, 300)
+
+	usage := bw.Usage()[host]
+	if usage.BytesSent != 12_000 || usage.BytesReceived != 300 || usage.RequestsReceived != 1 {
+		t.Fatalf("unexpected usage %+v", usage)
+	}
+}
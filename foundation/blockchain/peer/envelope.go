@@ -0,0 +1,247 @@
+package peer
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/signature"
+)
+
+// EnvelopeMaxAge is how far the timestamp of an envelope can be from the
+// receiving node's clock before the envelope is rejected as a replay.
+const EnvelopeMaxAge = 2 * time.Minute
+
+// Set of error variables for accepting envelopes.
+var (
+	ErrEnvelopeReplayed = errors.New("envelope was already received")
+	ErrSignerMismatch   = errors.New("envelope host is signed by another account")
+	ErrSignerUnbound    = errors.New("envelope host is not bound to the signer")
+)
+
+// Envelope wraps a gossip message, such as a proposed block or a shared
+// transaction, with the signature of the node sending it. This identifies
+// the node behind every message for peer scoring and spam attribution.
+type Envelope struct {
+	Host      string          `json:"host"`
+	Version   uint16          `json:"version,omitempty"` // Protocol version of the payload.
+	TimeStamp uint64          `json:"timestamp"`
+	Payload   json.RawMessage `json:"payload"`
+	V         *big.Int        `json:"v"`
+	R         *big.Int        `json:"r"`
+	S         *big.Int        `json:"s"`
+}
+
+// envelopeContent represents the part of the envelope that is signed.
+type envelopeContent struct {
+	Host      string          `json:"host"`
+	Version   uint16          `json:"version,omitempty"`
+	TimeStamp uint64          `json:"timestamp"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// NewEnvelope wraps the value in an envelope signed by the node's private key
+// and stamped with the specified time.
+func NewEnvelope(host string, value any, privateKey *ecdsa.PrivateKey, now time.Time) (Envelope, error) {
+	return NewVersionedEnvelope(host, 0, value, privateKey, now)
+}
+
+// NewVersionedEnvelope wraps the value encoded in the specified protocol
+// version in an envelope signed by the node's private key. The original
+// version is left out so peers that don't know about versions can still
+// verify the envelope.
+func NewVersionedEnvelope(host string, version uint16, value any, privateKey *ecdsa.PrivateKey, now time.Time) (Envelope, error) {
+	payload, err := json.Marshal(value)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	content := envelopeContent{
+		Host:      host,
+		Version:   version,
+		TimeStamp: uint64(now.UTC().UnixMilli()),
+		Payload:   payload,
+	}
+
+	v, r, s, err := signature.Sign(content, privateKey)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	env := Envelope{
+		Host:      content.Host,
+		Version:   content.Version,
+		TimeStamp: content.TimeStamp,
+		Payload:   content.Payload,
+		V:         v,
+		R:         r,
+		S:         s,
+	}
+
+	return env, nil
+}
+
+// Verify checks the envelope was signed properly and is not being replayed
+// outside of the EnvelopeMaxAge window around the specified time. It returns
+// the account of the node that signed the envelope. Replays inside the
+// window are caught by the Guard.
+func (e Envelope) Verify(now time.Time) (string, error) {
+	if e.V == nil || e.R == nil || e.S == nil {
+		return "", errors.New("envelope is not signed")
+	}
+
+	sent := time.UnixMilli(int64(e.TimeStamp))
+	if age := now.Sub(sent); age > EnvelopeMaxAge || age < -EnvelopeMaxAge {
+		return "", fmt.Errorf("envelope timestamp %s is outside the allowed window", sent.UTC())
+	}
+
+	if err := signature.VerifySignature(e.V, e.R, e.S); err != nil {
+		return "", fmt.Errorf("invalid signature: %w", err)
+	}
+
+	content := envelopeContent{
+		Host:      e.Host,
+		Version:   e.Version,
+		TimeStamp: e.TimeStamp,
+		Payload:   e.Payload,
+	}
+
+	address, err := signature.FromAddress(content, e.V, e.R, e.S)
+	if err != nil {
+		return "", fmt.Errorf("failed to get address: %w", err)
+	}
+
+	return address, nil
+}
+
+// Hash returns the hash of the signed part of the envelope, which identifies
+// the envelope no matter how the signature is encoded.
+func (e Envelope) Hash() string {
+	content := envelopeContent{
+		Host:      e.Host,
+		Version:   e.Version,
+		TimeStamp: e.TimeStamp,
+		Payload:   e.Payload,
+	}
+
+	return signature.HashWith(signature.HashSHA256, content)
+}
+
+// Decode unmarshals the payload of the envelope into the specified value.
+// Unknown fields and any data following the payload are rejected.
+func (e Envelope) Decode(value any) error {
+	decoder := json.NewDecoder(bytes.NewReader(e.Payload))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(value); err != nil {
+		return err
+	}
+
+	if err := decoder.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		return errors.New("payload must only contain a single JSON document")
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// Guard accepts the envelopes received from peers. An envelope is accepted
+// once within the EnvelopeMaxAge window, so replaying it is caught both
+// inside and outside the window. A host can be bound to the account that
+// signs for it so no other node can send envelopes in the host's name.
+type Guard struct {
+	mu      sync.Mutex
+	seen    map[string]time.Time // Envelopes by signer and hash and when they leave the window.
+	signers map[string]string    // Accounts the bound hosts are signed by.
+	pruned  time.Time
+}
+
+// NewGuard constructs a guard with no envelopes seen and no hosts bound.
+func NewGuard() *Guard {
+	return &Guard{
+		seen:    make(map[string]time.Time),
+		signers: make(map[string]string),
+	}
+}
+
+// Accept verifies the envelope at the specified time and makes sure it
+// wasn't accepted before. A bound host must be signed by its account. It
+// returns the account of the node that signed the envelope.
+func (g *Guard) Accept(env Envelope, now time.Time) (string, error) {
+	signer, err := env.Verify(now)
+	if err != nil {
+		return "", err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if account, exists := g.signers[NormalizeHost(env.Host)]; exists && !strings.EqualFold(account, signer) {
+		return "", fmt.Errorf("%w: host %s, signer %s", ErrSignerMismatch, env.Host, signer)
+	}
+
+	// Envelopes that left the window can't be verified again, so
+	// they are forgotten at most once a window.
+	if now.Sub(g.pruned) > EnvelopeMaxAge {
+		for hash, expires := range g.seen {
+			if now.After(expires) {
+				delete(g.seen, hash)
+			}
+		}
+		g.pruned = now
+	}
+
+	// The same content signed by different accounts are different envelopes.
+	key := signer + env.Hash()
+	if _, exists := g.seen[key]; exists {
+		return "", ErrEnvelopeReplayed
+	}
+	g.seen[key] = time.UnixMilli(int64(env.TimeStamp)).Add(EnvelopeMaxAge)
+
+	return signer, nil
+}
+
+// Bind binds the host to the account that signs for it. A host already
+// bound to another account is not changed and ErrSignerMismatch is returned.
+func (g *Guard) Bind(host string, account string) error {
+	host = NormalizeHost(host)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if bound, exists := g.signers[host]; exists {
+		if !strings.EqualFold(bound, account) {
+			return fmt.Errorf("%w: host %s, signer %s", ErrSignerMismatch, host, account)
+		}
+		return nil
+	}
+
+	g.signers[host] = account
+
+	return nil
+}
+
+// Bound reports whether the host is bound to the account.
+func (g *Guard) Bound(host string, account string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	bound, exists := g.signers[NormalizeHost(host)]
+	return exists && strings.EqualFold(bound, account)
+}
+
+// Unbind removes the binding of the host so it can be bound again, like
+// when the peer is removed.
+func (g *Guard) Unbind(host string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	delete(g.signers, NormalizeHost(host))
+}
@@ -0,0 +1,90 @@
+package peer_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/peer"
+)
+
+func Test_Guard(t *testing.T) {
+	const host = "0.0.0.0:9080"
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	other, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+
+	now := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	guard := peer.NewGuard()
+
+	env, err := peer.NewEnvelope(host, "block", key, now)
+	if err != nil {
+		t.Fatalf("constructing envelope: %v", err)
+	}
+
+	signer, err := guard.Accept(env, now.Add(time.Second))
+	if err != nil {
+		t.Fatalf("accepting envelope: %v", err)
+	}
+	if signer != crypto.PubkeyToAddress(key.PublicKey).String() {
+		t.Fatalf("signer should be the account of the key, got %s", signer)
+	}
+
+	// The same envelope is only accepted once inside the window.
+	if _, err := guard.Accept(env, now.Add(time.Minute)); !errors.Is(err, peer.ErrEnvelopeReplayed) {
+		t.Fatalf("replayed envelope should be rejected, got %v", err)
+	}
+
+	// Outside the window the timestamp rejects it.
+	if _, err := guard.Accept(env, now.Add(peer.EnvelopeMaxAge+time.Second)); err == nil {
+		t.Fatal("envelope outside the window should be rejected")
+	}
+
+	// Once the host is bound, another account can't sign for it.
+	if guard.Bound(host, signer) {
+		t.Fatal("host should not be bound by accepting an envelope")
+	}
+	if err := guard.Bind(host, signer); err != nil {
+		t.Fatalf("binding host: %v", err)
+	}
+	if !guard.Bound(host, signer) {
+		t.Fatal("host should be bound to the signer")
+	}
+	forged, err := peer.NewEnvelope(host, "block", other, now)
+	if err != nil {
+		t.Fatalf("constructing envelope: %v", err)
+	}
+	if _, err := guard.Accept(forged, now); !errors.Is(err, peer.ErrSignerMismatch) {
+		t.Fatalf("envelope signed by another account should be rejected, got %v", err)
+	}
+
+	// An unbound host can be signed by the new account.
+	guard.Unbind(host)
+	if _, err := guard.Accept(forged, now); err != nil {
+		t.Fatalf("accepting envelope after unbinding: %v", err)
+	}
+}
+
+func Test_EnvelopeDecode(t *testing.T) {
+	for payload, ok := range map[string]bool{
+		`"block"`:   true,
+		`"block"}`:  false,
+		`"block"]`:  false,
+		`"block"""`: false,
+	} {
+		env := peer.Envelope{Payload: []byte(payload)}
+
+		var value string
+		if err := env.Decode(&value); (err == nil) != ok {
+			t.Fatalf("decoding %s: got error %v, exp ok %t", payload, err, ok)
+		}
+	}
+}
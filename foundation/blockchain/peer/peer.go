@@ -1,61 +1,199 @@
 // Package peer maintains the peer related information such as the set of known peers and their status.
 package peer
 
-import "sync"
+import (
+	"errors"
+	"math/big"
+	"sync"
+)
+
+// ErrPeersFull is returned when there is no slot left for a new peer.
+var ErrPeersFull = errors.New("peers full")
 
 // Peer represents information about a Node in the network.
 type Peer struct {
 	Host string
 }
 
-// New constructs a new info value.
+// New constructs a new info value with the host normalized.
 func New(host string) Peer {
 	return Peer{
-		Host: host,
+		Host: NormalizeHost(host),
 	}
 }
 
-// Match validates if the specified host matches this node.
+// Match validates if the specified host matches this node, however either
+// of them is spelled.
 func (p Peer) Match(host string) bool {
-	return p.Host == host
+	return NormalizeHost(p.Host) == NormalizeHost(host)
 }
 
 //---------------------------------------------------------------------
 
 // PeerStatus represents information about the status of any given peer.
 type PeerStatus struct {
-	LatestBlockHash   string `json:"latest_block_hash"`
-	LatestBlockNumber uint64 `json:"latest_block_number"`
-	KnownPeers        []Peer `json:"known_peers"`
+	LatestBlockHash    string `json:"latest_block_hash"`
+	LatestBlockNumber  uint64 `json:"latest_block_number"`
+	KnownPeers         []Peer `json:"known_peers"`
+	ProtocolVersion    uint16 `json:"protocol_version"`
+	MinProtocolVersion uint16 `json:"min_protocol_version"` // Oldest protocol version the peer still speaks.
+	Build              string `json:"build,omitempty"`
+	FullyValidated     bool   `json:"fully_validated"`      // Every block in the chain has been validated.
+	ClockDrift         int64  `json:"clock_drift_ms"`       // Milliseconds the clock is ahead of the median of the peers, negative when behind.
+	RelayOnly          bool   `json:"relay_only,omitempty"` // The node has no beneficiary and never mines.
+
+	// The node's signature over the chain head, see SignStatus.
+	TimeStamp uint64   `json:"timestamp"`
+	V         *big.Int `json:"v"`
+	R         *big.Int `json:"r"`
+	S         *big.Int `json:"s"`
+}
+
+// PeerVersion represents the versions a peer reported in its status and
+// whether it said it only relays.
+type PeerVersion struct {
+	Protocol    uint16
+	MinProtocol uint16
+	Build       string
+	RelayOnly   bool
 }
 
 //---------------------------------------------------------------------
 
+// Direction represents which side asked for a peer to be known.
+type Direction int
+
+// The directions a peer can be known from.
+const (
+	Inbound  Direction = iota // The peer registered with this node.
+	Outbound                  // This node learned about the peer.
+)
+
+// Limits represents the most peers a node keeps so the network doesn't
+// grow into every node knowing every other node. A zero limit is no limit.
+// Peers added on startup don't take a slot.
+type Limits struct {
+	MaxPeers    int // Most inbound and outbound peers together.
+	MaxInbound  int // Most peers that registered with this node.
+	MaxOutbound int // Most peers this node learned about.
+}
+
 // PeerSet represents the data representation to maintain a set of known peers.
+// Peers are kept by their normalized host. A peer known by name is also
+// found by the addresses the name resolved to when it was added, so the
+// same node referenced by name and by address is only known once.
 type PeerSet struct {
-	mu  sync.RWMutex
-	set map[Peer]struct{}
+	mu       sync.RWMutex
+	set      map[Peer]struct{}
+	versions map[Peer]PeerVersion
+	limits   Limits
+	slots    map[Peer]Direction
+	quality  map[Peer]PeerQuality
+	aliases  map[string]Peer // Addresses named peers resolved to.
+	resolver Resolver
 }
 
 // NewPeerSet constructs a new info set to manage node peer information.
 func NewPeerSet() *PeerSet {
 	return &PeerSet{
-		set: make(map[Peer]struct{}),
+		set:      make(map[Peer]struct{}),
+		versions: make(map[Peer]PeerVersion),
+		slots:    make(map[Peer]Direction),
+		quality:  make(map[Peer]PeerQuality),
+		aliases:  make(map[string]Peer),
 	}
 }
 
+// SetResolver sets how the names of peers are resolved to the addresses
+// they're also known by. Names aren't resolved without one.
+func (ps *PeerSet) SetResolver(resolver Resolver) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	ps.resolver = resolver
+}
+
+// SetLimits sets the most peers the set takes in a slot.
+func (ps *PeerSet) SetLimits(limits Limits) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	ps.limits = limits
+}
+
 // Add adds a new node to the set.
 func (ps *PeerSet) Add(peer Peer) bool {
+	peer, addrs := ps.identify(peer)
+
 	ps.mu.Lock()
 	defer ps.mu.Unlock()
 
-	_, exists := ps.set[peer]
-	if !exists {
-		ps.set[peer] = struct{}{}
-		return true
+	if ps.known(peer, addrs) {
+		return false
 	}
 
-	return false
+	ps.insert(peer, addrs)
+
+	return true
+}
+
+// AddSlot adds a new node to the set in a slot for the direction. The
+// ErrPeersFull error is returned when there is no slot left.
+func (ps *PeerSet) AddSlot(peer Peer, dir Direction) (bool, error) {
+	peer, addrs := ps.identify(peer)
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if ps.known(peer, addrs) {
+		return false, nil
+	}
+
+	inbound, outbound := ps.slotsTaken()
+
+	full := func(n int, max int) bool { return max > 0 && n >= max }
+	switch {
+	case full(inbound+outbound, ps.limits.MaxPeers),
+		dir == Inbound && full(inbound, ps.limits.MaxInbound),
+		dir == Outbound && full(outbound, ps.limits.MaxOutbound):
+		return false, ErrPeersFull
+	}
+
+	ps.insert(peer, addrs)
+	ps.slots[peer] = dir
+
+	return true, nil
+}
+
+// Slots returns the number of inbound and outbound slots taken.
+func (ps *PeerSet) Slots() (inbound int, outbound int) {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	return ps.slotsTaken()
+}
+
+// slotsTaken counts the slots taken. The caller must hold the lock.
+func (ps *PeerSet) slotsTaken() (inbound int, outbound int) {
+	for _, dir := range ps.slots {
+		switch dir {
+		case Inbound:
+			inbound++
+		case Outbound:
+			outbound++
+		}
+	}
+
+	return inbound, outbound
+}
+
+// Contains reports whether the peer is in the set.
+func (ps *PeerSet) Contains(peer Peer) bool {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	_, exists := ps.set[ps.canonical(peer)]
+	return exists
 }
 
 // Remove removes a peer node from the set.
@@ -63,20 +201,111 @@ func (ps *PeerSet) Remove(peer Peer) {
 	ps.mu.Lock()
 	defer ps.mu.Unlock()
 
+	peer = ps.canonical(peer)
+	for addr, p := range ps.aliases {
+		if p == peer {
+			delete(ps.aliases, addr)
+		}
+	}
+
 	delete(ps.set, peer)
+	delete(ps.versions, peer)
+	delete(ps.slots, peer)
+	delete(ps.quality, peer)
+}
+
+// SetVersion records the versions the peer reported in its status.
+func (ps *PeerSet) SetVersion(peer Peer, version PeerVersion) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	peer = ps.canonical(peer)
+	if _, exists := ps.set[peer]; exists {
+		ps.versions[peer] = version
+	}
+}
+
+// Version returns the protocol version the peer reported it speaks. A peer
+// that hasn't reported a version is assumed to speak the original one.
+func (ps *PeerSet) Version(peer Peer) uint16 {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	return ps.versions[ps.canonical(peer)].Protocol
+}
+
+// Reported returns the versions the peer reported in its status and
+// whether the peer has reported them.
+func (ps *PeerSet) Reported(peer Peer) (PeerVersion, bool) {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	version, exists := ps.versions[ps.canonical(peer)]
+	return version, exists
 }
 
-// Copy returns a list of the known peers.
+// Copy returns a list of the known peers, leaving out the one with the host.
 func (ps *PeerSet) Copy(host string) []Peer {
 	ps.mu.RLock()
 	defer ps.mu.RUnlock()
 
+	skip := ps.canonical(Peer{Host: host})
+
 	peers := make([]Peer, 0, len(ps.set))
 	for peer := range ps.set {
-		if !peer.Match(host) {
+		if host == "" || peer != skip {
 			peers = append(peers, peer)
 		}
 	}
 
 	return peers
 }
+
+// identify normalizes the host of the peer and resolves the addresses it's
+// also known by. It's called without the lock held since resolving can
+// take a while.
+func (ps *PeerSet) identify(peer Peer) (Peer, []string) {
+	peer = New(peer.Host)
+
+	ps.mu.RLock()
+	resolver := ps.resolver
+	ps.mu.RUnlock()
+
+	return peer, resolve(resolver, peer.Host)
+}
+
+// canonical returns the peer the host is known as, which is the named peer
+// when the host is one of its addresses. The caller must hold the lock.
+func (ps *PeerSet) canonical(peer Peer) Peer {
+	peer = New(peer.Host)
+	if named, exists := ps.aliases[peer.Host]; exists {
+		return named
+	}
+
+	return peer
+}
+
+// known reports whether the peer or any of its addresses is known. The
+// caller must hold the lock.
+func (ps *PeerSet) known(peer Peer, addrs []string) bool {
+	if _, exists := ps.set[ps.canonical(peer)]; exists {
+		return true
+	}
+
+	for _, addr := range addrs {
+		if _, exists := ps.set[ps.canonical(Peer{Host: addr})]; exists {
+			return true
+		}
+	}
+
+	return false
+}
+
+// insert adds the peer with the addresses it's also known by. The caller
+// must hold the lock.
+func (ps *PeerSet) insert(peer Peer, addrs []string) {
+	ps.set[peer] = struct{}{}
+	for _, addr := range addrs {
+		ps.aliases[addr] = peer
+	}
+}
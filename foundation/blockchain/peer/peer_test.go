@@ -0,0 +1,159 @@
+package peer_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/peer"
+)
+
+func Test_PeerLimits(t *testing.T) {
+	ps := peer.NewPeerSet()
+
+	// Peers known before the limits are set don't take a slot.
+	ps.Add(peer.New("0.0.0.0:9080"))
+	ps.SetLimits(peer.Limits{MaxPeers: 3, MaxInbound: 2})
+
+	for _, host := range []string{"0.0.0.0:9180", "0.0.0.0:9280"} {
+		if _, err := ps.AddSlot(peer.New(host), peer.Inbound); err != nil {
+			t.Fatalf("adding inbound peer %s: %v", host, err)
+		}
+	}
+
+	if _, err := ps.AddSlot(peer.New("0.0.0.0:9380"), peer.Inbound); !errors.Is(err, peer.ErrPeersFull) {
+		t.Fatalf("inbound slots should be full: got %v", err)
+	}
+
+	// A known peer is never turned away.
+	if added, err := ps.AddSlot(peer.New("0.0.0.0:9180"), peer.Inbound); added || err != nil {
+		t.Fatalf("known peer: got added[%v] err[%v]", added, err)
+	}
+
+	if _, err := ps.AddSlot(peer.New("0.0.0.0:9480"), peer.Outbound); err != nil {
+		t.Fatalf("adding outbound peer: %v", err)
+	}
+
+	if _, err := ps.AddSlot(peer.New("0.0.0.0:9580"), peer.Outbound); !errors.Is(err, peer.ErrPeersFull) {
+		t.Fatalf("all slots should be full: got %v", err)
+	}
+
+	// Removing a peer frees its slot.
+	ps.Remove(peer.New("0.0.0.0:9180"))
+	if in, out := ps.Slots(); in != 1 || out != 1 {
+		t.Fatalf("slots: got in[%d] out[%d], exp in[1] out[1]", in, out)
+	}
+	if _, err := ps.AddSlot(peer.New("0.0.0.0:9580"), peer.Outbound); err != nil {
+		t.Fatalf("adding outbound peer after remove: %v", err)
+	}
+}
+
+func Test_NormalizeHost(t *testing.T) {
+	tests := []struct {
+		host string
+		exp  string
+	}{
+		{"0.0.0.0:9080", "0.0.0.0:9080"},
+		{" Node1.Example.COM.:9080 ", "node1.example.com:9080"},
+		{"[::ffff:10.0.0.1]:9080", "10.0.0.1:9080"},
+		{"[2001:DB8:0:0::1]:09080", "[2001:db8::1]:9080"},
+		{"2001:db8::1", "2001:db8::1"},
+		{"localhost", "localhost"},
+	}
+
+	for _, tt := range tests {
+		if got := peer.NormalizeHost(tt.host); got != tt.exp {
+			t.Errorf("%q: got %q, exp %q", tt.host, got, tt.exp)
+		}
+	}
+
+	if !peer.New("[2001:db8::1]:9080").Match("[2001:0db8:0::1]:9080") {
+		t.Error("the same IPv6 address spelled differently should match")
+	}
+}
+
+func Test_PeerSetAliases(t *testing.T) {
+	ps := peer.NewPeerSet()
+	ps.SetResolver(fakeResolver{hosts: []string{"10.0.0.1", "fd00::1"}})
+
+	if !ps.Add(peer.New("Node1.example.com:9080")) {
+		t.Fatal("the named peer should be added")
+	}
+
+	// The same node referenced by address or spelled differently is
+	// already known.
+	for _, host := range []string{"node1.example.com.:9080", "10.0.0.1:9080", "[fd00::1]:9080", "[::ffff:10.0.0.1]:9080"} {
+		if ps.Add(peer.New(host)) {
+			t.Errorf("%s: should already be known", host)
+		}
+		if !ps.Contains(peer.New(host)) {
+			t.Errorf("%s: should be contained", host)
+		}
+	}
+
+	// Another port is another node.
+	if added, err := ps.AddSlot(peer.New("10.0.0.1:9180"), peer.Outbound); !added || err != nil {
+		t.Fatalf("another port: got added[%v] err[%v]", added, err)
+	}
+
+	ps.SetVersion(peer.New("10.0.0.1:9080"), peer.PeerVersion{Protocol: 2})
+	if v := ps.Version(peer.New("node1.example.com:9080")); v != 2 {
+		t.Fatalf("version set by address: got %d, exp 2", v)
+	}
+
+	if peers := ps.Copy("10.0.0.1:9080"); len(peers) != 1 || peers[0].Host != "10.0.0.1:9180" {
+		t.Fatalf("copy without the named peer: got %v", peers)
+	}
+
+	// Removing the peer by address forgets its name too.
+	ps.Remove(peer.New("[fd00::1]:9080"))
+	if ps.Contains(peer.New("node1.example.com:9080")) || ps.Contains(peer.New("10.0.0.1:9080")) {
+		t.Fatal("the removed peer should be forgotten by name and address")
+	}
+	if len(ps.Copy("")) != 1 {
+		t.Fatalf("one peer should be left: got %v", ps.Copy(""))
+	}
+}
+
+func Test_PeerRank(t *testing.T) {
+	ps := peer.NewPeerSet()
+
+	fast, slow, failing, unknown := peer.New("10.0.0.1:9080"), peer.New("10.0.0.2:9080"), peer.New("10.0.0.3:9080"), peer.New("10.0.0.4:9080")
+	for _, p := range []peer.Peer{fast, slow, failing, unknown} {
+		ps.Add(p)
+	}
+
+	ps.ObserveRequest(fast, 10*time.Millisecond, 1<<20, 100*time.Millisecond)
+	ps.ObserveRequest(slow, 200*time.Millisecond, 1<<20, 2*time.Second)
+	ps.ObserveRequest(failing, time.Millisecond, 1<<20, 10*time.Millisecond)
+	ps.ObserveFailure(failing)
+
+	exp := []peer.Peer{fast, slow, unknown, failing}
+	if got := ps.Rank([]peer.Peer{unknown, failing, slow, fast}); !reflect.DeepEqual(got, exp) {
+		t.Fatalf("rank: got %v, exp %v", got, exp)
+	}
+
+	// The fast peer degrades and falls behind the slow one.
+	for i := 0; i < 10; i++ {
+		ps.ObserveRequest(fast, time.Second, 1<<20, 5*time.Second)
+	}
+	if got := ps.Rank([]peer.Peer{fast, slow}); got[0] != slow {
+		t.Fatalf("degraded peer should rank after the slow one: got %v", got)
+	}
+
+	// A success clears the failures.
+	ps.ObserveRequest(failing, time.Millisecond, 1<<20, 10*time.Millisecond)
+	if q, _ := ps.Quality(failing); q.Failures != 0 || q.Samples != 2 {
+		t.Fatalf("quality after success: got %+v", q)
+	}
+	if got := ps.Rank([]peer.Peer{slow, failing}); got[0] != failing {
+		t.Fatalf("recovered peer should rank first: got %v", got)
+	}
+
+	// Removing the peer forgets its quality.
+	ps.Remove(failing)
+	if _, ok := ps.Quality(failing); ok {
+		t.Fatal("removed peer should have no quality")
+	}
+}
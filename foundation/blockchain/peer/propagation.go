@@ -0,0 +1,103 @@
+package peer
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxLatencySamples is the number of the most recent latencies kept for
+// each peer.
+const maxLatencySamples = 256
+
+// Latency represents how long the blocks a peer proposed took from being
+// mined to being accepted by this node. The percentiles are in milliseconds
+// over the most recent blocks.
+type Latency struct {
+	Blocks int64 `json:"blocks"`
+	P50    int64 `json:"p50_ms"`
+	P90    int64 `json:"p90_ms"`
+	P99    int64 `json:"p99_ms"`
+	Max    int64 `json:"max_ms"`
+}
+
+// samples holds the most recent latencies of a single peer.
+type samples struct {
+	blocks int64
+	ring   []time.Duration
+	next   int
+}
+
+// Propagation tracks how long the blocks each peer proposes take to reach
+// this node so slow peers and slow links in the network stand out.
+type Propagation struct {
+	mu    sync.Mutex
+	peers map[string]*samples
+}
+
+// NewPropagation constructs a tracker of block propagation latency.
+func NewPropagation() *Propagation {
+	return &Propagation{
+		peers: make(map[string]*samples),
+	}
+}
+
+// Record adds the time it took the block the peer proposed to go from being
+// mined to being accepted. A negative latency from clocks being out of step
+// counts as none.
+func (p *Propagation) Record(host string, latency time.Duration) {
+	if latency < 0 {
+		latency = 0
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s, exists := p.peers[host]
+	if !exists {
+		s = &samples{}
+		p.peers[host] = s
+	}
+
+	s.blocks++
+	if len(s.ring) < maxLatencySamples {
+		s.ring = append(s.ring, latency)
+		return
+	}
+	s.ring[s.next] = latency
+	s.next = (s.next + 1) % maxLatencySamples
+}
+
+// Latencies returns the propagation latency of the blocks proposed by
+// each peer.
+func (p *Propagation) Latencies() map[string]Latency {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	latencies := make(map[string]Latency, len(p.peers))
+	for host, s := range p.peers {
+		sorted := append([]time.Duration(nil), s.ring...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+		latencies[host] = Latency{
+			Blocks: s.blocks,
+			P50:    percentile(sorted, 50),
+			P90:    percentile(sorted, 90),
+			P99:    percentile(sorted, 99),
+			Max:    sorted[len(sorted)-1].Milliseconds(),
+		}
+	}
+
+	return latencies
+}
+
+// percentile returns the latency in milliseconds at the percentile of the
+// sorted latencies using the nearest rank.
+func percentile(sorted []time.Duration, pct int) int64 {
+	rank := (pct*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+
+	return sorted[rank-1].Milliseconds()
+}
@@ -0,0 +1,36 @@
+package peer_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/peer"
+)
+
+func Test_PropagationPercentiles(t *testing.T) {
+	const host = "0.0.0.0:9280"
+
+	p := peer.NewPropagation()
+	for i := 1; i <= 100; i++ {
+		p.Record(host, time.Duration(i)*time.Millisecond)
+	}
+	p.Record("0.0.0.0:9380", -time.Second)
+
+	got := p.Latencies()[host]
+	exp := peer.Latency{Blocks: 100, P50: 50, P90: 90, P99: 99, Max: 100}
+	if got != exp {
+		t.Fatalf("latency: got %+v, exp %+v", got, exp)
+	}
+
+	if got := p.Latencies()["0.0.0.0:9380"]; got.Max != 0 {
+		t.Fatalf("a block from the future should count as no latency: got %+v", got)
+	}
+
+	// Only the most recent blocks count towards the percentiles.
+	for i := 0; i < 1000; i++ {
+		p.Record(host, time.Second)
+	}
+	if got := p.Latencies()[host]; got.Blocks != 1100 || got.P50 != 1000 {
+		t.Fatalf("old blocks should age out: got %+v", got)
+	}
+}
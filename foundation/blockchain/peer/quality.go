@@ -0,0 +1,136 @@
+package peer
+
+import (
+	"sort"
+	"time"
+)
+
+// The weight a new sample gets in the moving averages, so a peer that
+// slows down is ranked lower after a few requests.
+const qualityWeight = 0.3
+
+// minThroughputBytes is the smallest response the throughput is measured
+// from, smaller responses mostly measure the round trip.
+const minThroughputBytes = 4 << 10
+
+// rankBytes is the size of the response peers are ranked on fetching,
+// about a batch of blocks.
+const rankBytes = 1 << 20
+
+// PeerQuality represents how well a peer has been responding to the
+// requests this node made.
+type PeerQuality struct {
+	Latency    time.Duration // Moving average of the time to the response headers.
+	Throughput float64       // Moving average of the bytes per second of large responses, zero when unknown.
+	Failures   int           // Requests failed in a row since the last success.
+	Samples    int           // Requests that succeeded.
+}
+
+// cost returns about how long fetching a batch of blocks from the peer
+// takes.
+func (q PeerQuality) cost() time.Duration {
+	cost := q.Latency
+	if q.Throughput > 0 {
+		cost += time.Duration(rankBytes / q.Throughput * float64(time.Second))
+	}
+	return cost
+}
+
+// ObserveRequest records a request the peer responded to, taking latency
+// until the response headers arrived and the bytes of the response read in
+// elapsed time.
+func (ps *PeerSet) ObserveRequest(peer Peer, latency time.Duration, bytes int64, elapsed time.Duration) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	peer = ps.canonical(peer)
+	if _, exists := ps.set[peer]; !exists {
+		return
+	}
+
+	q := ps.quality[peer]
+	q.Latency = average(q.Latency, latency, q.Samples == 0)
+	if bytes >= minThroughputBytes && elapsed > 0 {
+		throughput := float64(bytes) / elapsed.Seconds()
+		if q.Throughput == 0 {
+			q.Throughput = throughput
+		} else {
+			q.Throughput += qualityWeight * (throughput - q.Throughput)
+		}
+	}
+	q.Failures = 0
+	q.Samples++
+
+	ps.quality[peer] = q
+}
+
+// ObserveFailure records a request the peer didn't respond to.
+func (ps *PeerSet) ObserveFailure(peer Peer) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	peer = ps.canonical(peer)
+	if _, exists := ps.set[peer]; !exists {
+		return
+	}
+
+	q := ps.quality[peer]
+	q.Failures++
+	ps.quality[peer] = q
+}
+
+// Quality returns how well the peer has been responding and whether any
+// request was made to it.
+func (ps *PeerSet) Quality(peer Peer) (PeerQuality, bool) {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	q, exists := ps.quality[ps.canonical(peer)]
+	return q, exists
+}
+
+// Rank returns the peers ordered from the one expected to serve blocks the
+// fastest. Peers failing their requests come last, then peers that were
+// never measured so they get tried after the known fast ones.
+func (ps *PeerSet) Rank(peers []Peer) []Peer {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	type ranked struct {
+		peer Peer
+		q    PeerQuality
+	}
+
+	rs := make([]ranked, len(peers))
+	for i, p := range peers {
+		rs[i] = ranked{peer: p, q: ps.quality[ps.canonical(p)]}
+	}
+
+	sort.SliceStable(rs, func(i, j int) bool {
+		a, b := rs[i].q, rs[j].q
+		switch {
+		case a.Failures != b.Failures:
+			return a.Failures < b.Failures
+		case (a.Samples == 0) != (b.Samples == 0):
+			return a.Samples > 0
+		case a.cost() != b.cost():
+			return a.cost() < b.cost()
+		}
+		return rs[i].peer.Host < rs[j].peer.Host
+	})
+
+	out := make([]Peer, len(rs))
+	for i, r := range rs {
+		out[i] = r.peer
+	}
+
+	return out
+}
+
+// average moves the average toward the sample, or starts it at the sample.
+func average(avg time.Duration, sample time.Duration, first bool) time.Duration {
+	if first {
+		return sample
+	}
+	return avg + time.Duration(qualityWeight*float64(sample-avg))
+}
@@ -0,0 +1,79 @@
+package peer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+// Resolver represents the DNS lookups used to find bootstrap peers. The
+// net.Resolver implements it.
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// Seeder finds bootstrap peers from the records of a DNS name so a new node
+// doesn't depend on a single origin node to join the network. The TXT records
+// list peers as host:port separated by spaces or commas and the A records
+// list the addresses of peers listening on the default port.
+type Seeder struct {
+	name     string
+	port     string
+	resolver Resolver
+}
+
+// NewSeeder constructs a seeder for the DNS name. Addresses from A records
+// are given the port. The default resolver is used when resolver is nil.
+func NewSeeder(name string, port string, resolver Resolver) *Seeder {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	return &Seeder{
+		name:     name,
+		port:     port,
+		resolver: resolver,
+	}
+}
+
+// Name returns the DNS name the peers are found from.
+func (s *Seeder) Name() string {
+	return s.name
+}
+
+// Peers resolves the DNS name and returns the peers it lists. An error is
+// only returned when neither kind of record could be resolved.
+func (s *Seeder) Peers(ctx context.Context) ([]Peer, error) {
+	hosts := make(map[string]struct{})
+
+	txts, txtErr := s.resolver.LookupTXT(ctx, s.name)
+	for _, txt := range txts {
+		for _, host := range strings.FieldsFunc(txt, func(r rune) bool { return r == ',' || r == ' ' }) {
+			if _, _, err := net.SplitHostPort(host); err != nil {
+				continue
+			}
+			hosts[host] = struct{}{}
+		}
+	}
+
+	addrs, hostErr := s.resolver.LookupHost(ctx, s.name)
+	for _, addr := range addrs {
+		hosts[net.JoinHostPort(addr, s.port)] = struct{}{}
+	}
+
+	if txtErr != nil && hostErr != nil {
+		return nil, fmt.Errorf("resolving seed %s: %w", s.name, errors.Join(txtErr, hostErr))
+	}
+
+	peers := make([]Peer, 0, len(hosts))
+	for host := range hosts {
+		peers = append(peers, New(host))
+	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i].Host < peers[j].Host })
+
+	return peers, nil
+}
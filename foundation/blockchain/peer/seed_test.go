@@ -0,0 +1,58 @@
+package peer_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/peer"
+)
+
+type fakeResolver struct {
+	hosts   []string
+	hostErr error
+	txts    []string
+	txtErr  error
+}
+
+func (r fakeResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	return r.hosts, r.hostErr
+}
+
+func (r fakeResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	return r.txts, r.txtErr
+}
+
+func Test_Seeder(t *testing.T) {
+	resolver := fakeResolver{
+		hosts: []string{"10.0.0.1", "fd00::1"},
+		txts:  []string{"10.0.0.2:9180 10.0.0.3:9280", "10.0.0.1:9080,not-a-peer"},
+	}
+
+	peers, err := peer.NewSeeder("seed.example.com", "9080", resolver).Peers(context.Background())
+	if err != nil {
+		t.Fatalf("resolving peers: %v", err)
+	}
+
+	exp := []peer.Peer{
+		peer.New("10.0.0.1:9080"),
+		peer.New("10.0.0.2:9180"),
+		peer.New("10.0.0.3:9280"),
+		peer.New("[fd00::1]:9080"),
+	}
+	if !reflect.DeepEqual(peers, exp) {
+		t.Fatalf("peers: got %v, exp %v", peers, exp)
+	}
+
+	// One kind of record missing isn't an error.
+	resolver.hostErr = errors.New("no such host")
+	if _, err := peer.NewSeeder("seed.example.com", "9080", resolver).Peers(context.Background()); err != nil {
+		t.Fatalf("a missing A record should not fail: %v", err)
+	}
+
+	resolver.txtErr = errors.New("no such host")
+	if _, err := peer.NewSeeder("seed.example.com", "9080", resolver).Peers(context.Background()); err == nil {
+		t.Fatal("failing to resolve any record should fail")
+	}
+}
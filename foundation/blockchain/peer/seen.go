@@ -0,0 +1,79 @@
+package peer
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Seen remembers the hashes of the most recent blocks and transactions
+// gossiped to this node so the copies sent by other peers are acknowledged
+// without being validated again. The least recently seen hash is forgotten
+// once the cache is full.
+type Seen struct {
+	mu     sync.Mutex
+	size   int
+	order  *list.List
+	hashes map[string]*list.Element
+}
+
+// NewSeen constructs a cache remembering up to size hashes.
+func NewSeen(size int) *Seen {
+	return &Seen{
+		size:   size,
+		order:  list.New(),
+		hashes: make(map[string]*list.Element),
+	}
+}
+
+// Contains reports whether the hash was seen, which makes it the most
+// recently seen hash.
+func (s *Seen) Contains(hash string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, exists := s.hashes[hash]
+	if exists {
+		s.order.MoveToFront(elem)
+	}
+
+	return exists
+}
+
+// Add remembers the hash as the most recently seen hash.
+func (s *Seen) Add(hash string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, exists := s.hashes[hash]; exists {
+		s.order.MoveToFront(elem)
+		return
+	}
+
+	s.hashes[hash] = s.order.PushFront(hash)
+
+	if s.order.Len() > s.size {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.hashes, oldest.Value.(string))
+	}
+}
+
+// Forget removes the hash so it's validated the next time it's seen.
+func (s *Seen) Forget(hash string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, exists := s.hashes[hash]; exists {
+		s.order.Remove(elem)
+		delete(s.hashes, hash)
+	}
+}
+
+// Reset forgets every hash.
+func (s *Seen) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.order.Init()
+	s.hashes = make(map[string]*list.Element)
+}
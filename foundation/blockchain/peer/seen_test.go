@@ -0,0 +1,32 @@
+package peer_test
+
+import (
+	"testing"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/peer"
+)
+
+func Test_Seen(t *testing.T) {
+	seen := peer.NewSeen(2)
+
+	seen.Add("a")
+	seen.Add("b")
+	if !seen.Contains("a") || !seen.Contains("b") {
+		t.Fatal("added hashes should be seen")
+	}
+
+	// Touching a makes b the least recently seen hash.
+	seen.Contains("a")
+	seen.Add("c")
+	if seen.Contains("b") {
+		t.Fatal("least recently seen hash should be forgotten")
+	}
+	if !seen.Contains("a") || !seen.Contains("c") {
+		t.Fatal("recently seen hashes should be kept")
+	}
+
+	seen.Forget("a")
+	if seen.Contains("a") {
+		t.Fatal("forgotten hash should not be seen")
+	}
+}
@@ -0,0 +1,72 @@
+package peer
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/signature"
+)
+
+// statusContent represents the part of the status that is signed. It's the
+// chain head a node syncs against, so a proxy or man in the middle can't
+// report a bogus head in the name of the peer.
+type statusContent struct {
+	LatestBlockHash   string `json:"latest_block_hash"`
+	LatestBlockNumber uint64 `json:"latest_block_number"`
+	TimeStamp         uint64 `json:"timestamp"`
+}
+
+// SignStatus stamps the status with the specified time and signs its chain
+// head with the node's private key.
+func SignStatus(status *PeerStatus, privateKey *ecdsa.PrivateKey, now time.Time) error {
+	content := statusContent{
+		LatestBlockHash:   status.LatestBlockHash,
+		LatestBlockNumber: status.LatestBlockNumber,
+		TimeStamp:         uint64(now.UTC().UnixMilli()),
+	}
+
+	v, r, s, err := signature.Sign(content, privateKey)
+	if err != nil {
+		return err
+	}
+
+	status.TimeStamp = content.TimeStamp
+	status.V = v
+	status.R = r
+	status.S = s
+
+	return nil
+}
+
+// VerifyStatus checks the chain head of the status was signed properly and
+// the status was stamped within the EnvelopeMaxAge window around the
+// specified time. It returns the account of the node that signed it.
+func VerifyStatus(status PeerStatus, now time.Time) (string, error) {
+	if status.V == nil || status.R == nil || status.S == nil {
+		return "", errors.New("status is not signed")
+	}
+
+	sent := time.UnixMilli(int64(status.TimeStamp))
+	if age := now.Sub(sent); age > EnvelopeMaxAge || age < -EnvelopeMaxAge {
+		return "", fmt.Errorf("status timestamp %s is outside the allowed window", sent.UTC())
+	}
+
+	if err := signature.VerifySignature(status.V, status.R, status.S); err != nil {
+		return "", fmt.Errorf("invalid signature: %w", err)
+	}
+
+	content := statusContent{
+		LatestBlockHash:   status.LatestBlockHash,
+		LatestBlockNumber: status.LatestBlockNumber,
+		TimeStamp:         status.TimeStamp,
+	}
+
+	address, err := signature.FromAddress(content, status.V, status.R, status.S)
+	if err != nil {
+		return "", fmt.Errorf("failed to get address: %w", err)
+	}
+
+	return address, nil
+}
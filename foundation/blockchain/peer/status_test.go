@@ -0,0 +1,52 @@
+package peer_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/peer"
+)
+
+func Test_Status(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+
+	now := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	status := peer.PeerStatus{
+		LatestBlockHash:   "0x0000a9a7ad5dee8ea3b9bb0d0a6a2a0a2f0f6e6b0d4bc6a1c2d3e4f5a6b7c8d9",
+		LatestBlockNumber: 10,
+	}
+	if err := peer.SignStatus(&status, key, now); err != nil {
+		t.Fatalf("signing status: %v", err)
+	}
+
+	signer, err := peer.VerifyStatus(status, now.Add(time.Second))
+	if err != nil {
+		t.Fatalf("verifying status: %v", err)
+	}
+	if signer != crypto.PubkeyToAddress(key.PublicKey).String() {
+		t.Fatalf("signer should be the account of the key, got %s", signer)
+	}
+
+	// A changed chain head is signed by some other account.
+	bogus := status
+	bogus.LatestBlockNumber = 1000
+	if signer, err := peer.VerifyStatus(bogus, now); err == nil && signer == crypto.PubkeyToAddress(key.PublicKey).String() {
+		t.Fatal("status with a changed chain head should not verify as the node's")
+	}
+
+	// A stale status is rejected.
+	if _, err := peer.VerifyStatus(status, now.Add(peer.EnvelopeMaxAge+time.Second)); err == nil {
+		t.Fatal("status outside the window should be rejected")
+	}
+
+	// An unsigned status is rejected.
+	if _, err := peer.VerifyStatus(peer.PeerStatus{LatestBlockNumber: 10}, now); err == nil {
+		t.Fatal("unsigned status should be rejected")
+	}
+}
@@ -0,0 +1,387 @@
+package protocol
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+	"github.com/qcbit/blockchain/foundation/blockchain/signature"
+)
+
+// Tx represents a transaction on the wire. Binary data and the signature
+// are hex encoded with a 0x prefix.
+type Tx struct {
+	ChainID         uint16               `json:"chain_id"`
+	From            database.AccountID   `json:"from"`
+	To              database.AccountID   `json:"to"`
+	Value           uint64               `json:"value"`
+	Nonce           uint64               `json:"nonce"`
+	Tip             uint64               `json:"tip"`
+	Data            string               `json:"data"`
+	LockUntil       uint64               `json:"lock_until,omitempty"`
+	Governance      *database.Governance `json:"governance,omitempty"`
+	ValidUntilBlock uint64               `json:"valid_until_block,omitempty"`
+	Sponsor         *database.Sponsor    `json:"sponsor,omitempty"`
+	Signature       string               `json:"signature"`
+	TimeStamp       uint64               `json:"timestamp"`
+	GasPrice        uint64               `json:"gas_price"`
+	GasUnits        uint64               `json:"gas_units"`
+}
+
+// FromBlockTx converts the database transaction into its wire representation.
+func FromBlockTx(tx database.BlockTx) Tx {
+	var data string
+	if tx.Data != nil {
+		data = hexutil.Encode(tx.Data)
+	}
+
+	return Tx{
+		ChainID:         tx.ChainID,
+		From:            tx.FromID,
+		To:              tx.ToID,
+		Value:           tx.Value,
+		Nonce:           tx.Nonce,
+		Tip:             tx.Tip,
+		Data:            data,
+		LockUntil:       tx.LockUntil,
+		Governance:      tx.Governance,
+		ValidUntilBlock: tx.ValidUntilBlock,
+		Sponsor:         tx.Sponsor,
+		Signature:       tx.SignatureString(),
+		TimeStamp:       tx.TimeStamp,
+		GasPrice:        tx.GasPrice,
+		GasUnits:        tx.GasUnits,
+	}
+}
+
+// ToBlockTx converts the wire transaction into the database transaction.
+// An empty data field converts to no data so the signature still matches.
+func (tx Tx) ToBlockTx() (database.BlockTx, error) {
+	var data []byte
+	if tx.Data != "" {
+		var err error
+		if data, err = hexutil.Decode(tx.Data); err != nil {
+			return database.BlockTx{}, errors.New("invalid transaction data")
+		}
+	}
+
+	v, r, s, err := signature.FromSignatureString(tx.Signature)
+	if err != nil {
+		return database.BlockTx{}, errors.New("invalid transaction signature")
+	}
+
+	blockTx := database.BlockTx{
+		SignedTx: database.SignedTx{
+			Tx: database.Tx{
+				ChainID:         tx.ChainID,
+				FromID:          tx.From,
+				ToID:            tx.To,
+				Value:           tx.Value,
+				Nonce:           tx.Nonce,
+				Tip:             tx.Tip,
+				Data:            data,
+				LockUntil:       tx.LockUntil,
+				Governance:      tx.Governance,
+				ValidUntilBlock: tx.ValidUntilBlock,
+				Sponsor:         tx.Sponsor,
+			},
+			V: v,
+			R: r,
+			S: s,
+		},
+		TimeStamp: tx.TimeStamp,
+		GasPrice:  tx.GasPrice,
+		GasUnits:  tx.GasUnits,
+	}
+
+	return blockTx, nil
+}
+
+// Block represents a block on the wire.
+type Block struct {
+	Hash          string             `json:"hash"`
+	Number        uint64             `json:"number"`
+	PrevBlockHash string             `json:"prev_block_hash"`
+	TimeStamp     uint64             `json:"timestamp"`
+	Beneficiary   database.AccountID `json:"beneficiary"`
+	Difficulty    uint16             `json:"difficulty"`
+	MiningReward  uint64             `json:"mining_reward"`
+	StateRoot     string             `json:"state_root"`
+	TransRoot     string             `json:"trans_root"`
+	Nonce         uint64             `json:"nonce"`
+	ExtraData     string             `json:"extra_data,omitempty"`
+	HeaderVersion uint16             `json:"header_version,omitempty"`
+	Trans         []Tx               `json:"trans"`
+}
+
+// FromBlock converts the database block into its wire representation.
+func FromBlock(block database.Block) Block {
+	values := block.MerkleTree.Values()
+
+	trans := make([]Tx, len(values))
+	for i, tx := range values {
+		trans[i] = FromBlockTx(tx)
+	}
+
+	return Block{
+		Hash:          block.Hash(),
+		Number:        block.Header.Number,
+		PrevBlockHash: block.Header.PrevBlockHash,
+		TimeStamp:     block.Header.TimeStamp,
+		Beneficiary:   block.Header.BeneficiaryID,
+		Difficulty:    block.Header.Difficulty,
+		MiningReward:  block.Header.MiningReward,
+		StateRoot:     block.Header.StateRoot,
+		TransRoot:     block.Header.TransRoot,
+		Nonce:         block.Header.Nonce,
+		ExtraData:     block.Header.ExtraData,
+		HeaderVersion: block.Header.Version,
+		Trans:         trans,
+	}
+}
+
+// ToBlock converts the wire block into the database block, rebuilding the
+// merkle tree for the transactions with the hash algorithm.
+func (b Block) ToBlock(algorithm string) (database.Block, error) {
+	trans := make([]database.BlockTx, len(b.Trans))
+	for i, tx := range b.Trans {
+		blockTx, err := tx.ToBlockTx()
+		if err != nil {
+			return database.Block{}, err
+		}
+		trans[i] = blockTx
+	}
+
+	blockData := database.BlockData{
+		Hash: b.Hash,
+		Header: database.BlockHeader{
+			Version:       b.HeaderVersion,
+			Number:        b.Number,
+			PrevBlockHash: b.PrevBlockHash,
+			TimeStamp:     b.TimeStamp,
+			BeneficiaryID: b.Beneficiary,
+			Difficulty:    b.Difficulty,
+			MiningReward:  b.MiningReward,
+			StateRoot:     b.StateRoot,
+			TransRoot:     b.TransRoot,
+			Nonce:         b.Nonce,
+			ExtraData:     b.ExtraData,
+		},
+		Trans: trans,
+	}
+
+	return database.ToBlock(algorithm, blockData)
+}
+
+// =============================================================================
+
+// TxMessage shares a transaction with a peer.
+type TxMessage struct {
+	Version uint16 `json:"version"`
+	Tx      Tx     `json:"tx"`
+}
+
+// BlockMessage proposes a block to a peer.
+type BlockMessage struct {
+	Version uint16 `json:"version"`
+	Block   Block  `json:"block"`
+}
+
+// BlocksMessage returns a range of blocks to a peer.
+type BlocksMessage struct {
+	Version uint16  `json:"version"`
+	Blocks  []Block `json:"blocks"`
+}
+
+// MempoolMessage returns the transactions in the mempool to a peer.
+type MempoolMessage struct {
+	Version uint16 `json:"version"`
+	Trans   []Tx   `json:"trans"`
+}
+
+// =============================================================================
+
+// EncodeTx returns the value to send for the transaction in the
+// specified protocol version.
+func EncodeTx(version uint16, tx database.BlockTx) any {
+	if version == VersionLegacy {
+		return tx
+	}
+
+	return TxMessage{
+		Version: version,
+		Tx:      FromBlockTx(tx),
+	}
+}
+
+// DecodeTx decodes a transaction sent in any supported protocol version.
+func DecodeTx(data []byte) (database.BlockTx, error) {
+	switch version := messageVersion(data); version {
+	case VersionLegacy:
+		var tx database.BlockTx
+		if err := decode(data, &tx); err != nil {
+			return database.BlockTx{}, err
+		}
+		return tx, nil
+
+	case Version1:
+		var msg TxMessage
+		if err := decode(data, &msg); err != nil {
+			return database.BlockTx{}, err
+		}
+		return msg.Tx.ToBlockTx()
+
+	default:
+		return database.BlockTx{}, unsupported(version)
+	}
+}
+
+// EncodeBlock returns the value to send for the block in the specified
+// protocol version.
+func EncodeBlock(version uint16, block database.Block) any {
+	if version == VersionLegacy {
+		return database.NewBlockData(block)
+	}
+
+	return BlockMessage{
+		Version: version,
+		Block:   FromBlock(block),
+	}
+}
+
+// DecodeBlock decodes a block sent in any supported protocol version. The
+// block is hashed with the algorithm the chain uses at the block's height.
+func DecodeBlock(data []byte, hashAlgorithm func(blockNumber uint64) string) (database.Block, error) {
+	switch version := messageVersion(data); version {
+	case VersionLegacy:
+		var blockData database.BlockData
+		if err := decode(data, &blockData); err != nil {
+			return database.Block{}, err
+		}
+		return database.ToBlock(hashAlgorithm(blockData.Header.Number), blockData)
+
+	case Version1:
+		var msg BlockMessage
+		if err := decode(data, &msg); err != nil {
+			return database.Block{}, err
+		}
+		return msg.Block.ToBlock(hashAlgorithm(msg.Block.Number))
+
+	default:
+		return database.Block{}, unsupported(version)
+	}
+}
+
+// EncodeBlocks returns the value to send for the blocks in the specified
+// protocol version.
+func EncodeBlocks(version uint16, blocks []database.Block) any {
+	if version == VersionLegacy {
+		blocksData := make([]database.BlockData, len(blocks))
+		for i, block := range blocks {
+			blocksData[i] = database.NewBlockData(block)
+		}
+		return blocksData
+	}
+
+	msg := BlocksMessage{
+		Version: version,
+		Blocks:  make([]Block, len(blocks)),
+	}
+	for i, block := range blocks {
+		msg.Blocks[i] = FromBlock(block)
+	}
+
+	return msg
+}
+
+// DecodeBlocks decodes blocks sent in any supported protocol version. Each
+// block is hashed with the algorithm the chain uses at the block's height.
+func DecodeBlocks(data []byte, hashAlgorithm func(blockNumber uint64) string) ([]database.Block, error) {
+	switch version := messageVersion(data); version {
+	case VersionLegacy:
+		var blocksData []database.BlockData
+		if err := decode(data, &blocksData); err != nil {
+			return nil, err
+		}
+
+		blocks := make([]database.Block, len(blocksData))
+		for i, blockData := range blocksData {
+			block, err := database.ToBlock(hashAlgorithm(blockData.Header.Number), blockData)
+			if err != nil {
+				return nil, err
+			}
+			blocks[i] = block
+		}
+		return blocks, nil
+
+	case Version1:
+		var msg BlocksMessage
+		if err := decode(data, &msg); err != nil {
+			return nil, err
+		}
+
+		blocks := make([]database.Block, len(msg.Blocks))
+		for i, b := range msg.Blocks {
+			block, err := b.ToBlock(hashAlgorithm(b.Number))
+			if err != nil {
+				return nil, err
+			}
+			blocks[i] = block
+		}
+		return blocks, nil
+
+	default:
+		return nil, unsupported(version)
+	}
+}
+
+// EncodeMempool returns the value to send for the mempool transactions
+// in the specified protocol version.
+func EncodeMempool(version uint16, trans []database.BlockTx) any {
+	if version == VersionLegacy {
+		return trans
+	}
+
+	msg := MempoolMessage{
+		Version: version,
+		Trans:   make([]Tx, len(trans)),
+	}
+	for i, tx := range trans {
+		msg.Trans[i] = FromBlockTx(tx)
+	}
+
+	return msg
+}
+
+// DecodeMempool decodes mempool transactions sent in any supported
+// protocol version.
+func DecodeMempool(data []byte) ([]database.BlockTx, error) {
+	switch version := messageVersion(data); version {
+	case VersionLegacy:
+		var trans []database.BlockTx
+		if err := decode(data, &trans); err != nil {
+			return nil, err
+		}
+		return trans, nil
+
+	case Version1:
+		var msg MempoolMessage
+		if err := decode(data, &msg); err != nil {
+			return nil, err
+		}
+
+		trans := make([]database.BlockTx, len(msg.Trans))
+		for i, tx := range msg.Trans {
+			blockTx, err := tx.ToBlockTx()
+			if err != nil {
+				return nil, err
+			}
+			trans[i] = blockTx
+		}
+		return trans, nil
+
+	default:
+		return nil, unsupported(version)
+	}
+}
@@ -0,0 +1,127 @@
+// Package protocol defines the messages nodes exchange with each other and
+// the conversions between them and the database types. Keeping the messages
+// separate lets the storage layout change without breaking the network.
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// The set of protocol versions. The legacy version is the original protocol
+// where the database types are sent as is, it's what a peer that doesn't
+// report a version speaks.
+const (
+	VersionLegacy  uint16 = 0
+	Version1       uint16 = 1
+	CurrentVersion        = Version1
+)
+
+// MinVersion is the oldest protocol version this node still speaks. Peers
+// that can't speak a version between the minimum and the current version
+// can't exchange messages with this node.
+const MinVersion = VersionLegacy
+
+// Header is the http header a node uses to tell a peer the protocol
+// version it speaks.
+const Header = "Protocol-Version"
+
+// HostHeader is the http header a node uses to tell a peer the host it's
+// known by so the peer can account for the traffic they exchange.
+const HostHeader = "Node-Host"
+
+// ErrUnsupportedVersion is returned when a message uses a protocol version
+// this node doesn't know how to decode.
+var ErrUnsupportedVersion = errors.New("unsupported protocol version")
+
+// ErrIncompatibleVersion is returned when a peer doesn't speak any protocol
+// version this node speaks.
+var ErrIncompatibleVersion = errors.New("incompatible protocol version")
+
+// Compatible reports whether a peer speaking the protocol versions from
+// minVersion to version shares a version with this node.
+func Compatible(version uint16, minVersion uint16) bool {
+	return version >= MinVersion && minVersion <= CurrentVersion
+}
+
+// CheckVersion returns an error when a message in the specified protocol
+// version can't be understood by this node.
+func CheckVersion(version uint16) error {
+	if version < MinVersion || version > CurrentVersion {
+		return fmt.Errorf("%w: %d, this node speaks %d to %d", ErrIncompatibleVersion, version, MinVersion, CurrentVersion)
+	}
+	return nil
+}
+
+// Negotiate returns the protocol version to use with a peer that speaks
+// the specified version.
+func Negotiate(peerVersion uint16) uint16 {
+	if peerVersion > CurrentVersion {
+		return CurrentVersion
+	}
+	return peerVersion
+}
+
+// Requested returns the protocol version to respond to the request with.
+// A request without the header comes from a peer speaking the legacy version.
+func Requested(r *http.Request) uint16 {
+	version, err := strconv.ParseUint(r.Header.Get(Header), 10, 16)
+	if err != nil {
+		return VersionLegacy
+	}
+	return Negotiate(uint16(version))
+}
+
+// RequestedBy returns the host of the node that made the request. A request
+// without the header is identified by the address it came from.
+func RequestedBy(r *http.Request) string {
+	if host := r.Header.Get(HostHeader); host != "" {
+		return host
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// =============================================================================
+
+// messageVersion returns the version of the encoded message. Legacy
+// messages don't carry a version.
+func messageVersion(data []byte) uint16 {
+	var msg struct {
+		Version *uint16 `json:"version"`
+	}
+	if err := json.Unmarshal(data, &msg); err != nil || msg.Version == nil {
+		return VersionLegacy
+	}
+	return *msg.Version
+}
+
+// decode unmarshals the message into the specified value. Unknown fields
+// and any data following the message are rejected.
+func decode(data []byte, value any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(value); err != nil {
+		return err
+	}
+
+	if decoder.More() {
+		return errors.New("message must only contain a single JSON document")
+	}
+
+	return nil
+}
+
+// unsupported returns the error for a message with an unknown version.
+func unsupported(version uint16) error {
+	return fmt.Errorf("%w: %d", ErrUnsupportedVersion, version)
+}
@@ -0,0 +1,124 @@
+package protocol_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+	"github.com/qcbit/blockchain/foundation/blockchain/protocol"
+	"github.com/qcbit/blockchain/foundation/blockchain/signature"
+)
+
+func Test_TxRoundTrip(t *testing.T) {
+	tx := newBlockTx(t, []byte{1, 2, 3})
+
+	for _, version := range []uint16{protocol.VersionLegacy, protocol.Version1} {
+		data, err := json.Marshal(protocol.EncodeTx(version, tx))
+		if err != nil {
+			t.Fatalf("version %d: should be able to marshal tx: %s", version, err)
+		}
+
+		got, err := protocol.DecodeTx(data)
+		if err != nil {
+			t.Fatalf("version %d: should be able to decode tx: %s", version, err)
+		}
+
+		if !got.Equals(tx) || got.TxHash(signature.HashKeccak256) != tx.TxHash(signature.HashKeccak256) {
+			t.Fatalf("version %d: should get back the same tx: got %s, exp %s", version, got, tx)
+		}
+
+		if err := got.Validate(tx.ChainID); err != nil {
+			t.Fatalf("version %d: signature should still validate: %s", version, err)
+		}
+	}
+}
+
+func Test_BlockRoundTrip(t *testing.T) {
+	trans := []database.BlockTx{newBlockTx(t, nil), newBlockTx(t, []byte{})}
+
+	block, err := database.NewBlock(database.POWArgs{
+		Version:       1,
+		BeneficiaryID: trans[0].FromID,
+		Difficulty:    1,
+		MiningReward:  50,
+		PrevBlock:     database.Block{},
+		StateRoot:     "0x00",
+		HashAlgorithm: signature.HashKeccak256,
+		Trans:         trans,
+		EvHandler:     func(v string, args ...any) {},
+	})
+	if err != nil {
+		t.Fatalf("should be able to build block: %s", err)
+	}
+
+	for _, version := range []uint16{protocol.VersionLegacy, protocol.Version1} {
+		data, err := json.Marshal(protocol.EncodeBlocks(version, []database.Block{block}))
+		if err != nil {
+			t.Fatalf("version %d: should be able to marshal blocks: %s", version, err)
+		}
+
+		blocks, err := protocol.DecodeBlocks(data, func(uint64) string { return signature.HashKeccak256 })
+		if err != nil {
+			t.Fatalf("version %d: should be able to decode blocks: %s", version, err)
+		}
+
+		if len(blocks) != 1 || blocks[0].Hash() != block.Hash() {
+			t.Fatalf("version %d: should get back the same block", version)
+		}
+
+		if got, exp := blocks[0].MerkleTree.RootHex(), block.MerkleTree.RootHex(); got != exp {
+			t.Fatalf("version %d: should get back the same transactions: got %s, exp %s", version, got, exp)
+		}
+	}
+}
+
+func Test_UnsupportedVersion(t *testing.T) {
+	_, err := protocol.DecodeTx([]byte(`{"version": 99, "tx": {}}`))
+	if !errors.Is(err, protocol.ErrUnsupportedVersion) {
+		t.Fatalf("should reject an unknown version: got %v", err)
+	}
+
+	if got := protocol.Negotiate(99); got != protocol.CurrentVersion {
+		t.Fatalf("should speak the current version to newer peers: got %d", got)
+	}
+}
+
+func Test_IncompatibleVersion(t *testing.T) {
+	if !protocol.Compatible(protocol.CurrentVersion+5, protocol.CurrentVersion) {
+		t.Fatal("a newer peer that still speaks the current version should be compatible")
+	}
+
+	if protocol.Compatible(protocol.CurrentVersion+5, protocol.CurrentVersion+1) {
+		t.Fatal("a peer that no longer speaks the current version should be incompatible")
+	}
+
+	if err := protocol.CheckVersion(protocol.CurrentVersion); err != nil {
+		t.Fatalf("should accept messages in the current version: %v", err)
+	}
+
+	if err := protocol.CheckVersion(protocol.CurrentVersion + 1); !errors.Is(err, protocol.ErrIncompatibleVersion) {
+		t.Fatalf("should reject messages in a newer version: got %v", err)
+	}
+}
+
+func newBlockTx(t *testing.T, data []byte) database.BlockTx {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("should be able to generate key: %s", err)
+	}
+
+	tx, err := database.NewTx(1, database.PublicKeyToAccountID(key.PublicKey), "0xbEE6ACE826eC3DE1B6349888B9151B92522F7F76", 100, 1, 5, data)
+	if err != nil {
+		t.Fatalf("should be able to construct tx: %s", err)
+	}
+
+	signedTx, err := tx.Sign(key)
+	if err != nil {
+		t.Fatalf("should be able to sign tx: %s", err)
+	}
+
+	return database.NewBlockTx(signedTx, 15, 1)
+}
@@ -2,11 +2,12 @@
 package signature
 
 import (
-	"crypto/sha256"
 	"crypto/ecdsa"
+	"crypto/sha256"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -19,15 +20,62 @@ const ZeroHash = "0x000000000000000000000000000000000000000000000000000000000000
 // QID is an arbitrary value added to the v component of the signature similar to Ethereum and Bitcoin.
 const QID = 29
 
-// Hash returns a unique hash for the data.
-func Hash(value any) string {
+// StampPrefix is written in front of the data before it's hashed and signed
+// to identify the data as being signed by the blockchain. The length of the
+// data in decimal follows it.
+const StampPrefix = "\x19Q Signed Message:\n"
+
+// StampHashAlgorithm is the algorithm the stamped data is hashed with before
+// it's signed. It doesn't change with the chain's hash algorithm.
+const StampHashAlgorithm = HashKeccak256
+
+// The set of hash algorithms that can be used to hash blockchain data.
+const (
+	HashSHA256    = "sha256"
+	HashKeccak256 = "keccak256"
+)
+
+// hashStrategies maps the supported hash algorithms to their constructors.
+var hashStrategies = map[string]func() hash.Hash{
+	HashSHA256:    sha256.New,
+	HashKeccak256: func() hash.Hash { return crypto.NewKeccakState() },
+}
+
+// IsHashAlgorithm reports whether the hash algorithm is supported.
+func IsHashAlgorithm(algorithm string) bool {
+	_, exists := hashStrategies[algorithm]
+	return exists
+}
+
+// HashStrategy returns the constructor for the hash algorithm so the merkle
+// tree can hash with the same algorithm as the data. Chains created before
+// the hash algorithm became configurable use SHA-256, so that is returned
+// for an algorithm that doesn't exist.
+func HashStrategy(algorithm string) func() hash.Hash {
+	if strategy, exists := hashStrategies[algorithm]; exists {
+		return strategy
+	}
+
+	return sha256.New
+}
+
+// HashWith returns a unique hash for the data using the specified algorithm.
+// The algorithm is part of the chain rules in effect for the data being
+// hashed, so it's always passed in rather than held by this package.
+func HashWith(algorithm string, value any) string {
+	strategy, exists := hashStrategies[algorithm]
+	if !exists {
+		return ZeroHash
+	}
+
 	data, err := json.Marshal(value)
 	if err != nil {
 		return ZeroHash
 	}
 
-	hash := sha256.Sum256(data)
-	return hexutil.Encode(hash[:])
+	h := strategy()
+	h.Write(data)
+	return hexutil.Encode(h.Sum(nil))
 }
 
 // Sign uses the specified private key to sign the data.
@@ -86,6 +134,25 @@ func SignatureString(v, r, s *big.Int) string {
 	return hexutil.Encode(ToSignatureBytesWithQID(v, r, s))
 }
 
+// FromSignatureString converts a signature in the [R|S|V] format returned
+// by SignatureString back into the v, r, s components.
+func FromSignatureString(sig string) (v, r, s *big.Int, err error) {
+	sigBytes, err := hexutil.Decode(sig)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if len(sigBytes) != crypto.SignatureLength {
+		return nil, nil, nil, fmt.Errorf("invalid signature length %d", len(sigBytes))
+	}
+
+	r = big.NewInt(0).SetBytes(sigBytes[:32])
+	s = big.NewInt(0).SetBytes(sigBytes[32:64])
+	v = big.NewInt(0).SetBytes([]byte{sigBytes[64]})
+
+	return v, r, s, nil
+}
+
 // ToSignatureBytesWithQID converts the v, r, s components into the original 65 bytes signature with QID.
 func ToSignatureBytesWithQID(v, r, s *big.Int) []byte {
 	sig := ToSignatureBytes(v, r, s)
@@ -95,8 +162,9 @@ func ToSignatureBytesWithQID(v, r, s *big.Int) []byte {
 
 // ----------------------------------------------------------------------------
 
-// stamp returns a 32-byte hash of the data with the stamp embedded.
-func stamp(value any) ([]byte, error) {
+// Stamped returns the bytes that are hashed to produce the data a
+// signature is made over: the stamp followed by the marshaled data.
+func Stamped(value any) ([]byte, error) {
 	// Marshal the data.
 	v, err := json.Marshal(value)
 	if err != nil {
@@ -104,16 +172,30 @@ func stamp(value any) ([]byte, error) {
 	}
 
 	// This stamp is used to identify the data as being signed by the blockchain.
-	stamp := []byte(fmt.Sprintf("\x19Q Signed Message:\n%d", len(v)))
+	stamp := []byte(fmt.Sprintf("%s%d", StampPrefix, len(v)))
+
+	return append(stamp, v...), nil
+}
+
+// stamp returns a 32-byte hash of the data with the stamp embedded.
+func stamp(value any) ([]byte, error) {
+	stamped, err := Stamped(value)
+	if err != nil {
+		return nil, err
+	}
 
 	// Stamp the data outputting a 32-byte hash.
-	data := crypto.Keccak256(stamp, v)
+	data := crypto.Keccak256(stamped)
 
 	return data, nil
 }
 
 // VerifySignature verifies the signature conforms to the standards.
 func VerifySignature(v, r, s *big.Int) error {
+	if v == nil || r == nil || s == nil {
+		return errors.New("missing signature values")
+	}
+
 	// Check the recovery id is either 0 or 1.
 	uintV := v.Uint64() - QID
 	if uintV != 0 && uintV != 1 {
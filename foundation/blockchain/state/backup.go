@@ -0,0 +1,22 @@
+package state
+
+import (
+	"github.com/qcbit/blockchain/foundation/blockchain/backup"
+)
+
+// Backup holds back new blocks while fn runs so the chain in storage doesn't
+// change during a backup. Blocks proposed in the meantime wait until the
+// backup completes. The function is given the node's data at that moment.
+func (s *State) Backup(fn func(src backup.Source) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	src := backup.Source{
+		Storage:     s.storage,
+		LatestBlock: s.db.LatestBlock().Header.Number,
+		Mempool:     s.mempool.PickBest(),
+		Peers:       s.knownPeers.Copy(s.host),
+	}
+
+	return fn(src)
+}
@@ -0,0 +1,127 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+)
+
+// The set of strategies for rotating the account paid for mining a block.
+const (
+	RotateRoundRobin = "round-robin"
+	RotateWeighted   = "weighted"
+)
+
+// Beneficiary represents an account that takes turns being paid for the
+// blocks this node mines. The weight is only used by the weighted strategy.
+type Beneficiary struct {
+	AccountID database.AccountID `json:"account"`
+	Weight    uint64             `json:"weight,omitempty"`
+}
+
+// Rotation represents how the account paid for the blocks this node mines
+// changes from block to block. Without beneficiaries the node's own
+// account is paid for every block.
+type Rotation struct {
+	Strategy      string        `json:"strategy"`
+	Beneficiaries []Beneficiary `json:"beneficiaries"`
+}
+
+// validate checks the rotation can pick a beneficiary for every block.
+func (r Rotation) validate() error {
+	switch r.Strategy {
+	case RotateRoundRobin, RotateWeighted:
+	default:
+		return fmt.Errorf("unknown strategy %q, must be %s or %s", r.Strategy, RotateRoundRobin, RotateWeighted)
+	}
+
+	var total uint64
+	for _, ben := range r.Beneficiaries {
+		if !ben.AccountID.IsAccountID() {
+			return fmt.Errorf("invalid beneficiary account %q", ben.AccountID)
+		}
+		total += ben.Weight
+	}
+
+	if r.Strategy == RotateWeighted && len(r.Beneficiaries) > 0 && total == 0 {
+		return errors.New("weighted rotation needs at least one beneficiary with a weight")
+	}
+
+	return nil
+}
+
+// pick returns the beneficiary for the block with the specified number.
+// The choice only depends on the block number so every beneficiary gets
+// its share over a window of blocks no matter which blocks are lost to
+// other nodes.
+func (r Rotation) pick(blockNumber uint64) (database.AccountID, bool) {
+	if len(r.Beneficiaries) == 0 {
+		return "", false
+	}
+
+	if r.Strategy == RotateRoundRobin {
+		return r.Beneficiaries[blockNumber%uint64(len(r.Beneficiaries))].AccountID, true
+	}
+
+	var total uint64
+	for _, ben := range r.Beneficiaries {
+		total += ben.Weight
+	}
+
+	slot := blockNumber % total
+	for _, ben := range r.Beneficiaries {
+		if slot < ben.Weight {
+			return ben.AccountID, true
+		}
+		slot -= ben.Weight
+	}
+
+	return "", false
+}
+
+// =============================================================================
+
+// Rotation returns how the account paid for mined blocks is rotated.
+func (s *State) Rotation() Rotation {
+	s.rotationMu.RLock()
+	defer s.rotationMu.RUnlock()
+
+	rotation := s.rotation
+	rotation.Beneficiaries = append([]Beneficiary(nil), s.rotation.Beneficiaries...)
+
+	return rotation
+}
+
+// SetRotation replaces how the account paid for mined blocks is rotated.
+// It takes effect from the next block that's mined.
+func (s *State) SetRotation(rotation Rotation) error {
+	if err := rotation.validate(); err != nil {
+		return err
+	}
+
+	s.rotationMu.Lock()
+	defer s.rotationMu.Unlock()
+
+	s.rotation = Rotation{
+		Strategy:      rotation.Strategy,
+		Beneficiaries: append([]Beneficiary(nil), rotation.Beneficiaries...),
+	}
+
+	s.evHandler("state: SetRotation: strategy[%s]: beneficiaries[%d]", rotation.Strategy, len(rotation.Beneficiaries))
+
+	return nil
+}
+
+// beneficiary returns the account paid for mining the block with the
+// specified number.
+func (s *State) beneficiary(blockNumber uint64) database.AccountID {
+	s.rotationMu.RLock()
+	defer s.rotationMu.RUnlock()
+
+	if accountID, ok := s.rotation.pick(blockNumber); ok {
+		return accountID
+	}
+
+	return s.beneficiaryID
+}
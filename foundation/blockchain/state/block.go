@@ -3,6 +3,7 @@ package state
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/qcbit/blockchain/foundation/blockchain/database"
 )
@@ -10,11 +11,19 @@ import (
 // ErrNoTransactions is returned when there are no transactions
 var ErrNoTransactions = errors.New("no transactions in the mempool")
 
+// ErrProposalsFull is returned when too many proposed blocks are waiting to
+// be processed.
+var ErrProposalsFull = errors.New("too many proposed blocks waiting to be processed")
+
 // MineNewBlock attempts to create a new block with a
 // proper hash that can become the next block in the chain.
 func (s *State) MineNewBlock(ctx context.Context) (database.Block, error) {
 	defer s.evHandler("viewer: MineNewBlock: MINING: completed")
 
+	if !s.CanMine() {
+		return database.Block{}, ErrRelayOnly
+	}
+
 	s.evHandler("state: MineNewBlock: MINING: check mempool count")
 
 	// Are there enough transactions in the pool?
@@ -25,22 +34,10 @@ func (s *State) MineNewBlock(ctx context.Context) (database.Block, error) {
 	// Pick the best transactions from the mempool.
 	trans := s.mempool.PickBest(s.genesis.TransPerBlock)
 
-	// If PoA, drop the difficulty to 1 to speed up the mining process.
-	difficulty := s.genesis.Difficulty
-	if s.Consensus() == ConsensusPOA {
-		difficulty = 1
-	}
-
 	// Attempt to create a new block by solving the POW puzzle. This can be canceled.
-	block, err := database.POW(ctx, database.POWArgs{
-		BeneficiaryID: s.beneficiaryID,
-		Difficulty:    difficulty,
-		MiningReward:  s.genesis.MiningReward,
-		PrevBlock:     s.db.LatestBlock(),
-		StateRoot:     s.db.HashState(),
-		Trans:         trans,
-		EvHandler:     s.evHandler,
-	})
+	t := s.clock.Now()
+	block, err := database.POW(ctx, s.powArgs(trans))
+	s.recordMiningDuration(s.clock.Now().Sub(t))
 	if err != nil {
 		return database.Block{}, err
 	}
@@ -60,6 +57,73 @@ func (s *State) MineNewBlock(ctx context.Context) (database.Block, error) {
 	return block, nil
 }
 
+// Candidate represents the block this node would mine right now and the
+// fees it would expect to collect from the transactions.
+type Candidate struct {
+	Block   database.Block
+	GasFees uint64
+	Tips    uint64
+}
+
+// MiningCandidate returns the block this node would build from the mempool
+// right now without mining it.
+func (s *State) MiningCandidate() (Candidate, error) {
+	if s.mempool.Count() == 0 {
+		return Candidate{}, ErrNoTransactions
+	}
+
+	trans := s.mempool.PickBest(s.genesis.TransPerBlock)
+
+	block, err := database.NewBlock(s.powArgs(trans))
+	if err != nil {
+		return Candidate{}, err
+	}
+
+	// The fees are an estimate since gas is capped by the balance of the
+	// account and a transaction that fails doesn't pay its tip.
+	candidate := Candidate{
+		Block: block,
+	}
+	for _, tx := range trans {
+		candidate.GasFees += tx.GasPrice * tx.GasUnits
+		candidate.Tips += tx.Tip
+	}
+
+	return candidate, nil
+}
+
+// powArgs returns the arguments for building the next block from the
+// specified transactions under the chain rules in effect for that block.
+func (s *State) powArgs(trans []database.BlockTx) database.POWArgs {
+	prevBlock := s.db.LatestBlock()
+	rules := s.db.Rules(prevBlock.Header.Number + 1)
+
+	// If PoA, drop the difficulty to 1 to speed up the mining process.
+	difficulty := rules.Difficulty
+	if s.Consensus() == ConsensusPOA {
+		difficulty = 1
+	}
+
+	args := database.POWArgs{
+		Version:       rules.HeaderVersion,
+		BeneficiaryID: s.beneficiary(prevBlock.Header.Number + 1),
+		Difficulty:    difficulty,
+		MiningReward:  rules.MiningReward,
+		PrevBlock:     prevBlock,
+		StateRoot:     s.db.HashState(),
+		ExtraData:     s.extraData,
+		HashAlgorithm: rules.HashAlgorithm,
+		Trans:         trans,
+		EvHandler:     s.evHandler,
+		Clock:         s.clock,
+	}
+
+	// Pay the pool contributors their share of the reward in the same block.
+	args.Trans = append(args.Trans, s.payoutTxs(args)...)
+
+	return args
+}
+
 // ProcessProposedBlock takes a block received from a peer, validates,
 // if valid, adds the block to the local blockchain.
 func (s *State) ProcessProposedBlock(block database.Block) error {
@@ -67,17 +131,32 @@ func (s *State) ProcessProposedBlock(block database.Block) error {
 		block.Header.PrevBlockHash, block.Hash(), len(block.MerkleTree.Values()))
 	defer s.evHandler("state: ProcessProposedBlock: completed: newBlk[%s]", block.Hash())
 
-	// Validate the block and then update the blockchain database.
-	if err := s.validateUpdateDatabase(block); err != nil {
-		return err
-	}
+	// Validate the block and then update the blockchain database. The
+	// worker stops mining when it's told the block was accepted.
+	return s.validateUpdateDatabase(block)
+}
 
-	// Stop runMiningOperation
-	s.Worker.SignalCancelMining()
+// QueueProposedBlock hands the block received from a peer to the worker to
+// be validated and added to the blockchain without making the peer wait.
+// The ErrProposalsFull error is returned when too many blocks are waiting.
+func (s *State) QueueProposedBlock(host string, block database.Block) error {
+	if !s.Worker.SignalProposeBlock(host, block) {
+		return ErrProposalsFull
+	}
 
 	return nil
 }
 
+// RecordPropagation records how long the block the peer proposed took from
+// being mined to being accepted by this node.
+func (s *State) RecordPropagation(host string, block database.Block) {
+	mined := time.UnixMilli(int64(block.Header.TimeStamp))
+	latency := s.clock.Now().Sub(mined)
+
+	s.latency.Record(host, latency)
+	s.evHandler("state: RecordPropagation: peer[%s]: blk[%d]: latency[%s]", host, block.Header.Number, latency.Round(time.Millisecond))
+}
+
 //---------------------------------------------------
 
 // validateUpdateDatabase takes the block and validates the block against the
@@ -89,31 +168,41 @@ func (s *State) validateUpdateDatabase(block database.Block) error {
 
 	s.evHandler("state: validateUpdateDatabase: validate block")
 
-	// CORE NOTE: Logic could be added to determine which node mined the block.
-	// If the block is mined by this node, even if a peer beats it to this method
-	// for the same block number, the peer block could be replaced with this node's
-	// and attempt to have other peers accept its block instead.
-
-	if err := block.ValidateBlock(s.db.LatestBlock(), s.db.HashState(), s.evHandler); err != nil {
-		return err
+	// A block for the height of the latest block competes with the latest
+	// block. The lower hash wins and the latest block is replaced when it
+	// loses, otherwise this node keeps its block and proposes it again.
+	if latest := s.db.LatestBlock(); block.Header.Number > 0 && block.Header.Number == latest.Header.Number && block.Hash() != latest.Hash() {
+		if err := s.competingBlock(block, latest); err != nil {
+			return err
+		}
 	}
 
-	s.evHandler("state: validateUpdateDatabase: write to disk")
+	if err := block.ValidateBlock(s.db.LatestBlock(), s.db.HashState(), s.db.Rules(block.Header.Number), s.evHandler); err != nil {
+		if errors.Is(err, database.ErrChainForked) {
+			s.publishForkDetected(block)
+		}
 
-	// Write the new block to the chain on disk.
-	if err := s.db.Write(block); err != nil {
+		var blockErr *database.BlockError
+		if errors.As(err, &blockErr) {
+			s.evHandler("state: validateUpdateDatabase: REJECTED: blk[%d]: check[%s]: got[%s]: expected[%s]", block.Header.Number, blockErr.Check, blockErr.Got, blockErr.Expected)
+		}
 		return err
 	}
+
+	s.evHandler("state: validateUpdateDatabase: update accounts")
+
+	// The block is applied to the accounts before it's written so the
+	// receipts and index entries it produces commit with it.
+	parent := s.db.LatestBlock()
 	s.db.UpdateLatestBlock(block)
 
-	s.evHandler("state: validateUpdateDatabase: update accounts and remove from mempool")
+	// Capture the accounts this block can change so the
+	// changes the block made can be recorded.
+	before := s.db.Snapshot(block)
 
 	// Process the transactions and update the accounts.
 	for _, tx := range block.MerkleTree.Values() {
-		s.evHandler("state: validateUpdateDatabase: tx[%s] update and remove", tx)
-
-		// Remove this transaction from the mempool.
-		s.mempool.Delete(tx)
+		s.evHandler("state: validateUpdateDatabase: tx[%s] update", tx)
 
 		// Apply the balance changes based on this transaction.
 		if err := s.db.ApplyTransaction(block, tx); err != nil {
@@ -126,9 +215,54 @@ func (s *State) validateUpdateDatabase(block database.Block) error {
 
 	// Apply the mining reward for this block.
 	s.db.ApplyMiningReward(block)
+	s.db.RecordDiff(block, before)
+
+	s.evHandler("state: validateUpdateDatabase: write to disk")
+
+	// Write the new block with its receipts and index entries to the chain
+	// on disk. If that fails the accounts are put back to the parent.
+	if err := s.db.Commit(block); err != nil {
+		if rbErr := s.db.Rollback(parent); rbErr != nil {
+			s.evHandler("state: validateUpdateDatabase: ERROR: rollback: %s", rbErr)
+		}
+		return err
+	}
+	s.seenBlocks.Add(block.Hash())
+
+	s.evHandler("state: validateUpdateDatabase: remove from mempool")
 
-	// Send an event about this new block
-	// s.blockEvent(block)
+	// Remove the mined transactions from the mempool.
+	var mined []database.BlockTx
+	for _, tx := range block.MerkleTree.Values() {
+		if exists, _ := s.mempool.Delete(tx); exists {
+			mined = append(mined, tx)
+		}
+	}
+
+	// Transactions that can't be mined in the next block never will be.
+	expired := s.mempool.DeleteExpired(block.Header.Number + 1)
+	if len(expired) > 0 {
+		s.evHandler("state: validateUpdateDatabase: removed expired txs[%d]", len(expired))
+	}
+
+	// Work handed out to external miners is now stale.
+	s.resetWork()
+
+	// Wake up everyone waiting on a new block.
+	close(s.newBlock)
+	s.newBlock = make(chan struct{})
+
+	// Tell the subscribers about this new block, starting with the
+	// accounts it changed so caches are fresh when the block is seen.
+	s.publishAccountsChanged(block, s.db.ChangedAccounts(block.Header.Number), false)
+	s.publishBlockAccepted(block)
+
+	for _, tx := range mined {
+		s.publishMempoolRemoved(tx, block, true, "mined")
+	}
+	for _, tx := range expired {
+		s.publishMempoolRemoved(tx, block, false, "expired")
+	}
 
 	return nil
 }
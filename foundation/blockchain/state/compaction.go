@@ -0,0 +1,59 @@
+package state
+
+import (
+	"errors"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+)
+
+// compactProgressEvery is how many block files are looked at between
+// progress events while storage is compacted.
+const compactProgressEvery = 100
+
+// ErrNotCompactable is returned when the storage can't report or reclaim
+// the space it uses.
+var ErrNotCompactable = errors.New("storage does not support compaction")
+
+// StorageUsage returns the space the chain uses in storage.
+func (s *State) StorageUsage() (database.Usage, error) {
+	c, ok := s.storage.(database.Compactor)
+	if !ok {
+		return database.Usage{}, ErrNotCompactable
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return c.Usage(s.db.LatestBlock().Header.Number)
+}
+
+// Compact reclaims space in storage according to the retention config. Like
+// a backup, blocks proposed in the meantime wait until compaction completes.
+func (s *State) Compact() (database.Compaction, error) {
+	c, ok := s.storage.(database.Compactor)
+	if !ok {
+		return database.Compaction{}, ErrNotCompactable
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.evHandler("state: Compact: started: readable[%d]", s.retention.ReadableBlocks)
+
+	progress := func(done, total uint64) {
+		if done%compactProgressEvery == 0 || done == total {
+			s.evHandler("state: Compact: progress: blocks[%d/%d]", done, total)
+		}
+	}
+
+	result, err := c.Compact(s.db.LatestBlock().Header.Number, s.retention, progress)
+	if err != nil {
+		s.evHandler("state: Compact: ERROR: %s", err)
+		return result, err
+	}
+
+	s.evHandler("state: Compact: completed: compacted[%d]: pruned[%d]: offloaded[%d]: reclaimed[%d]",
+		result.Compacted, result.Pruned, result.Offloaded, result.Reclaimed)
+
+	return result, nil
+}
@@ -0,0 +1,124 @@
+package state
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+	"github.com/qcbit/blockchain/foundation/blockchain/genesis"
+)
+
+// QueryAccountsAt returns the accounts as they were after the specified
+// block was applied, sorted by account ID.
+func (s *State) QueryAccountsAt(num uint64) ([]database.Account, error) {
+	if num == QueryLatest {
+		num = s.db.LatestBlock().Header.Number
+	}
+
+	accounts, err := s.db.AccountsAt(num)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]database.Account, 0, len(accounts))
+	for _, account := range accounts {
+		out = append(out, account)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].AccountID < out[j].AccountID
+	})
+
+	return out, nil
+}
+
+// ExportGenesis returns a genesis file for a new chain that starts with
+// the accounts as they were after the specified block. The new chain uses
+// the rules in effect at that block and keeps the upgrades scheduled after
+// it. Nonces start over since the new chain has no transactions yet and
+// locks are moved to the same distance from the new genesis block.
+func (s *State) ExportGenesis(num uint64) (genesis.Genesis, error) {
+	if num == QueryLatest {
+		num = s.db.LatestBlock().Header.Number
+	}
+
+	accounts, err := s.QueryAccountsAt(num)
+	if err != nil {
+		return genesis.Genesis{}, err
+	}
+
+	gen := s.genesis
+	rules := gen.RulesAt(num)
+
+	gen.Date = s.clock.Now().UTC()
+	gen.Difficulty = rules.Difficulty
+	gen.MiningReward = rules.MiningReward
+	gen.GasPrice = rules.GasPrice
+	if rules.HashAlgorithm != gen.HashAlgorithm() {
+		gen.Version = genesis.ChainVersion2
+	}
+
+	gen.Balances = make(map[string]uint64, len(accounts))
+	gen.Locks = nil
+	for _, account := range accounts {
+		if account.Balance == 0 {
+			continue
+		}
+		gen.Balances[string(account.AccountID)] = account.Balance
+
+		for _, lock := range account.Locks {
+			if lock.UntilBlock <= num {
+				continue
+			}
+			if gen.Locks == nil {
+				gen.Locks = make(map[string][]genesis.Lock)
+			}
+			gen.Locks[string(account.AccountID)] = append(gen.Locks[string(account.AccountID)], genesis.Lock{
+				Amount:     lock.Amount,
+				UntilBlock: lock.UntilBlock - num,
+			})
+		}
+	}
+
+	gen.Upgrades = nil
+	for _, upgrade := range s.genesis.Upgrades {
+		if upgrade.Height > num {
+			upgrade.Height -= num
+			gen.Upgrades = append(gen.Upgrades, upgrade)
+		}
+	}
+
+	// Accounts shared between roles keep every change from the first block.
+	if rules.SharedAccounts {
+		if len(gen.Upgrades) == 0 || gen.Upgrades[0].Height != 1 {
+			gen.Upgrades = append([]genesis.Upgrade{{Height: 1}}, gen.Upgrades...)
+		}
+		gen.Upgrades[0].SharedAccounts = true
+	}
+
+	return gen, nil
+}
+
+// QueryCheckpoint returns the accounts as they were after the specified
+// block signed by this node so a light client that trusts this node can
+// start from the block.
+func (s *State) QueryCheckpoint(num uint64) (database.SignedCheckpoint, error) {
+	if s.privateKey == nil {
+		return database.SignedCheckpoint{}, errors.New("node has no key to sign checkpoints")
+	}
+
+	if num == QueryLatest {
+		num = s.db.LatestBlock().Header.Number
+	}
+
+	block, err := s.db.GetBlock(num)
+	if err != nil {
+		return database.SignedCheckpoint{}, err
+	}
+
+	accounts, err := s.db.AccountsAt(num)
+	if err != nil {
+		return database.SignedCheckpoint{}, err
+	}
+
+	return database.NewCheckpoint(s.genesis.ChainID, s.db.HashAlgorithm(block.Header.Number), block, accounts).Sign(s.privateKey)
+}
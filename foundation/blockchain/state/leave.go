@@ -0,0 +1,92 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/peer"
+)
+
+// CORE NOTE: A node shutting down tells its peers it's leaving so they drop
+// it right away. Otherwise they keep gossiping to it and selecting it to
+// mine blocks until a status request fails on the next peer operation. The
+// notice is best effort, a node that crashes is still found out the slow way.
+// A notice is only taken from the account the peer's host is bound to.
+
+// leaveTimeout is how long shutdown waits for the peers to take the notice.
+const leaveTimeout = 2 * time.Second
+
+// LeaveNotice represents the notice a node sends its peers when it shuts
+// down. The node leaving is the host of the envelope carrying it.
+type LeaveNotice struct {
+	Reason string `json:"reason"`
+}
+
+// NetSendLeavingToPeers tells the known peers this node is leaving the
+// network, all at once so a slow peer doesn't hold up the shutdown.
+func (s *State) NetSendLeavingToPeers(reason string) {
+	s.evHandler("state: NetSendLeavingToPeers: started")
+	defer s.evHandler("state: NetSendLeavingToPeers: completed")
+
+	ctx, cancel := context.WithTimeout(context.Background(), leaveTimeout)
+	defer cancel()
+
+	envs := newEnvelopes(s, func(version uint16) any {
+		return LeaveNotice{Reason: reason}
+	})
+
+	var wg sync.WaitGroup
+	for _, p := range s.KnownExternalPeers() {
+		env, err := envs.forPeer(p)
+		if err != nil {
+			s.evHandler("state: NetSendLeavingToPeers: peer[%s]: ERROR: %s", p, err)
+			continue
+		}
+
+		wg.Add(1)
+		go func(p peer.Peer) {
+			defer wg.Done()
+
+			url := fmt.Sprintf("%s/peers/leave", fmt.Sprintf(baseURL, p.Host))
+			if err := s.sendWithContext(ctx, http.MethodPost, url, env, nil); err != nil {
+				s.evHandler("state: NetSendLeavingToPeers: peer[%s]: WARNING: %s", p, err)
+			}
+		}(p)
+	}
+
+	wg.Wait()
+}
+
+// AcceptLeaveNotice verifies the envelope carrying a leave notice and
+// returns the account that signed it. Unlike other envelopes it never binds
+// the host, otherwise any key could evict a peer that isn't bound yet and
+// take over its host. Only a host already bound to the signer can leave.
+func (s *State) AcceptLeaveNotice(env peer.Envelope) (string, error) {
+	signer, err := s.envelopes.Accept(env, s.clock.Now())
+	if err != nil {
+		return "", err
+	}
+
+	if !s.envelopes.Bound(env.Host, signer) {
+		return "", fmt.Errorf("%w: host %s, signer %s", peer.ErrSignerUnbound, env.Host, signer)
+	}
+
+	return signer, nil
+}
+
+// RemoveLeavingPeer drops a peer that said it's leaving. It reports whether
+// the peer was known.
+func (s *State) RemoveLeavingPeer(host string, notice LeaveNotice) bool {
+	p := peer.New(host)
+	if !s.knownPeers.Contains(p) {
+		return false
+	}
+
+	s.RemoveKnownPeer(p)
+	s.evHandler("state: RemoveLeavingPeer: peer[%s]: reason[%s]", p, notice.Reason)
+
+	return true
+}
@@ -2,14 +2,21 @@ package state
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/qcbit/blockchain/foundation/blockchain/database"
 	"github.com/qcbit/blockchain/foundation/blockchain/peer"
+	"github.com/qcbit/blockchain/foundation/blockchain/protocol"
 )
 
 const baseURL = "http://%s/v1/node"
@@ -23,11 +30,49 @@ func (s *State) NetRequestPeerStatus(p peer.Peer) (peer.PeerStatus, error) {
 	url := fmt.Sprintf("%s/status", fmt.Sprintf(baseURL, p.Host))
 
 	var ps peer.PeerStatus
-	if err := send(http.MethodGet, url, nil, &ps); err != nil {
+	sent := s.clock.Now()
+	if err := s.send(http.MethodGet, url, nil, &ps); err != nil {
 		return peer.PeerStatus{}, err
 	}
 
-	s.evHandler("state: NetRequestPeerStatus: peer-node[%s]: latest-blknum[%d]: peer-list[%s]", p, ps.LatestBlockNumber, ps.KnownPeers)
+	// The peer's clock is sampled before the status is verified since a
+	// clock too far off fails the timestamp check, which is what the drift
+	// estimate has to find. The median keeps a single bad sample out.
+	if ps.TimeStamp > 0 {
+		s.drift.Observe(p.Host, sent, s.clock.Now(), time.UnixMilli(int64(ps.TimeStamp)))
+	}
+
+	// A status that isn't signed by the account the peer's host is bound
+	// to could make the node resync against a chain head that doesn't exist.
+	signer, err := peer.VerifyStatus(ps, s.clock.Now())
+	if err != nil {
+		return peer.PeerStatus{}, fmt.Errorf("peer status: %w", err)
+	}
+	if err := s.envelopes.Bind(p.Host, signer); err != nil {
+		return peer.PeerStatus{}, fmt.Errorf("peer status: %w", err)
+	}
+
+	s.evHandler("state: NetRequestPeerStatus: peer-node[%s]: latest-blknum[%d]: peer-list[%s]: protocol[%d]", p, ps.LatestBlockNumber, ps.KnownPeers, ps.ProtocolVersion)
+
+	// Remember the protocol version so messages sent to the peer use it.
+	s.knownPeers.SetVersion(p, peer.PeerVersion{
+		Protocol:    ps.ProtocolVersion,
+		MinProtocol: ps.MinProtocolVersion,
+		Build:       ps.Build,
+		RelayOnly:   ps.RelayOnly,
+	})
+
+	// A peer that shares no protocol version with this node can't be
+	// synced with, its messages would fail to decode.
+	if !protocol.Compatible(ps.ProtocolVersion, ps.MinProtocolVersion) {
+		s.evHandler("state: NetRequestPeerStatus: INCOMPATIBLE: peer-node[%s]: protocol[%d-%d]: build[%s]: node-protocol[%d-%d]",
+			p, ps.MinProtocolVersion, ps.ProtocolVersion, ps.Build, protocol.MinVersion, protocol.CurrentVersion)
+		return ps, fmt.Errorf("%w: peer speaks %d to %d, this node speaks %d to %d",
+			protocol.ErrIncompatibleVersion, ps.MinProtocolVersion, ps.ProtocolVersion, protocol.MinVersion, protocol.CurrentVersion)
+	}
+
+	// Track how far behind the peers this node is.
+	s.noteHighestBlock(ps.LatestBlockNumber)
 
 	return ps, nil
 }
@@ -39,8 +84,13 @@ func (s *State) NetRequestPeerMempool(p peer.Peer) ([]database.BlockTx, error) {
 
 	url := fmt.Sprintf("%s/tx/list", fmt.Sprintf(baseURL, p.Host))
 
-	var mempool []database.BlockTx
-	if err := send(http.MethodGet, url, nil, &mempool); err != nil {
+	var data json.RawMessage
+	if err := s.send(http.MethodGet, url, nil, &data); err != nil {
+		return nil, err
+	}
+
+	mempool, err := protocol.DecodeMempool(data)
+	if err != nil {
 		return nil, err
 	}
 
@@ -67,22 +117,29 @@ func (s *State) NetRequestPeerBlocks(p peer.Peer) error {
 	from := s.LatestBlock().Header.Number + 1
 	url := fmt.Sprintf("%s/block/list/%d/latest", fmt.Sprintf(baseURL, p.Host), from)
 
-	var blocksData []database.BlockData
-	if err := send(http.MethodGet, url, nil, &blocksData); err != nil {
+	var data json.RawMessage
+	if err := s.send(http.MethodGet, url, nil, &data); err != nil {
 		return err
 	}
 
-	s.evHandler("state: NetRequestPeerBlocks: found blocks[%d]", len(blocksData))
+	// The peer responds with no content when it has no new blocks.
+	if len(data) == 0 {
+		return nil
+	}
 
-	for _, blockData := range blocksData {
-		block, err := database.ToBlock(blockData)
-		if err != nil {
-			return err
-		}
+	blocks, err := protocol.DecodeBlocks(data, s.db.HashAlgorithm)
+	if err != nil {
+		return err
+	}
+
+	s.evHandler("state: NetRequestPeerBlocks: found blocks[%d]", len(blocks))
 
+	for _, block := range blocks {
+		s.noteHighestBlock(block.Header.Number)
 		if err := s.ProcessProposedBlock(block); err != nil {
 			return err
 		}
+		s.syncedBlock(block.Header.Number)
 	}
 
 	return nil
@@ -94,15 +151,36 @@ func (s *State) NetSendNodeAvailableToPeers() {
 	s.evHandler("state: NetSendNodeAvailableToPeers: started")
 	defer s.evHandler("state: NetSendNodeAvailableToPeers: completed")
 
-	host := peer.Peer{Host: s.Host()}
+	host := peer.New(s.Host())
 
 	for _, p := range s.KnownExternalPeers() {
 		s.evHandler("state: NetSendNodeAvailableToPeer: send: host[%s] to peer[%s]", host, p)
 
 		url := fmt.Sprintf("%s/peers", fmt.Sprintf(baseURL, p.Host))
 
-		if err := send(http.MethodPost, url, host, nil); err != nil {
+		if err := s.send(http.MethodPost, url, host, nil); err != nil {
 			s.evHandler("state: NetSendNodeAvailableToPeer: WARNING: %s", err)
+
+			// A peer without a slot for this node offers other peers
+			// to join the network through.
+			var pe *peerError
+			if errors.As(err, &pe) && pe.msg == peer.ErrPeersFull.Error() {
+				s.addAlternatePeers(pe.fields["peers"])
+			}
+		}
+	}
+}
+
+// addAlternatePeers adds the peers offered by a peer that had no slot for
+// this node.
+func (s *State) addAlternatePeers(hosts string) {
+	for _, host := range strings.Split(hosts, ",") {
+		if host == "" || peer.New(host).Match(s.host) {
+			continue
+		}
+
+		if s.AddKnownPeer(peer.New(host)) {
+			s.evHandler("state: addAlternatePeers: adding peer node %s", host)
 		}
 	}
 }
@@ -119,12 +197,23 @@ func (s *State) NetSendTxToPeers(tx database.BlockTx) {
 	// based on the mempool key it received.
 
 	// For now, this blockchain just sends the full transaction.
+	envs := newEnvelopes(s, func(version uint16) any {
+		return protocol.EncodeTx(version, tx)
+	})
+
+	r := retry{
+		gossip: gossip{
+			kind:   "tx",
+			id:     tx.TxHash(s.Rules().HashAlgorithm),
+			path:   "tx/submit",
+			encode: envs.encode,
+		},
+	}
+
 	for _, peer := range s.KnownExternalPeers() {
 		s.evHandler("state: NetSendTxToPeers: send: tx[%s] to peer[%s]", tx, peer)
 
-		url := fmt.Sprintf("%s/tx/submit", fmt.Sprintf(baseURL, peer.Host))
-
-		if err := send(http.MethodPost, url, tx, nil); err != nil {
+		if err := s.sendGossip(envs, peer, r); err != nil {
 			s.evHandler("state: NetSendTxToPeers: WARNING: %s", err)
 		}
 	}
@@ -135,26 +224,81 @@ func (s *State) NetSendBlockToPeers(block database.Block) error {
 	s.evHandler("state: NetSendBlockToPeers: started:")
 	defer s.evHandler("state: NetSendBlockToPeers: completed")
 
+	envs := newEnvelopes(s, func(version uint16) any {
+		return protocol.EncodeBlock(version, block)
+	})
+
+	r := retry{
+		gossip: gossip{
+			kind:   "block",
+			id:     block.Hash(),
+			path:   "block/propose",
+			encode: envs.encode,
+		},
+	}
+
+	// A peer that can't be reached doesn't stop the block reaching the
+	// rest of the peers.
+	var errs []error
 	for _, peer := range s.KnownExternalPeers() {
 		s.evHandler("state: NetSendBlockToPeers: send: block[%s] to peer[%s]", block.Hash(), peer)
 
-		url := fmt.Sprintf("%s/block/propose", fmt.Sprintf(baseURL, peer.Host))
-
-		var status struct {
-			Status string `json:"status"`
-		}
-		if err := send(http.MethodPost, url, database.NewBlockData(block), &status); err != nil {
-			return fmt.Errorf("%s: %s", peer.Host, err)
+		if err := s.sendGossip(envs, peer, r); err != nil {
+			errs = append(errs, err)
 		}
 	}
-	return nil
+
+	return errors.Join(errs...)
 }
 
 //-----------------------------------------------------------------
 
+// envelopes signs a message once for every protocol version the
+// peers it's sent to speak.
+type envelopes struct {
+	state  *State
+	encode func(version uint16) any
+	envs   map[uint16]peer.Envelope
+}
+
+// newEnvelopes constructs the envelopes for the message the encode
+// function returns for a protocol version.
+func newEnvelopes(s *State, encode func(version uint16) any) *envelopes {
+	return &envelopes{
+		state:  s,
+		encode: encode,
+		envs:   make(map[uint16]peer.Envelope),
+	}
+}
+
+// forPeer returns the envelope for the protocol version the peer speaks.
+func (e *envelopes) forPeer(p peer.Peer) (peer.Envelope, error) {
+	version := protocol.Negotiate(e.state.knownPeers.Version(p))
+
+	if env, exists := e.envs[version]; exists {
+		return env, nil
+	}
+
+	env, err := peer.NewVersionedEnvelope(e.state.host, version, e.encode(version), e.state.privateKey, e.state.clock.Now())
+	if err != nil {
+		return peer.Envelope{}, err
+	}
+	e.envs[version] = env
+
+	return env, nil
+}
+
 // send is a helper function to send HTTP requests to a node.
-func send(method string, url string, dataSend any, dataRecv any) error {
+func (s *State) send(method string, url string, dataSend any, dataRecv any) error {
+	return s.sendWithContext(context.Background(), method, url, dataSend, dataRecv)
+}
+
+// sendWithContext sends the HTTP request to a node and gives up on the
+// request when the context is canceled. The traffic is accounted to the
+// node and the request body is held back while the node is over its cap.
+func (s *State) sendWithContext(ctx context.Context, method string, url string, dataSend any, dataRecv any) error {
 	var req *http.Request
+	var size int64
 
 	switch {
 	case dataSend != nil:
@@ -162,43 +306,145 @@ func send(method string, url string, dataSend any, dataRecv any) error {
 		if err != nil {
 			return err
 		}
-		req, err = http.NewRequest(method, url, bytes.NewReader(data))
+		req, err = http.NewRequestWithContext(ctx, method, url, bytes.NewReader(data))
 		if err != nil {
 			return err
 		}
+		size = int64(len(data))
 
 	default:
 		var err error
-		req, err = http.NewRequest(method, url, nil)
+		req, err = http.NewRequestWithContext(ctx, method, url, nil)
 		if err != nil {
 			return err
 		}
 	}
 
-	var client http.Client
+	// Tell the peer the protocol version this node speaks, the host this
+	// node is known by and that blocks and transactions can be sent
+	// compressed.
+	req.Header.Set(protocol.Header, strconv.Itoa(int(protocol.CurrentVersion)))
+	req.Header.Set(protocol.HostHeader, s.host)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	host := req.URL.Host
+	s.bandwidth.RequestSent(host)
+	s.bandwidth.Send(host, size)
+
+	client := http.Client{
+		Transport: s.transport,
+	}
+	sent := s.clock.Now()
 	resp, err := client.Do(req)
 	if err != nil {
+		s.knownPeers.ObserveFailure(peer.New(host))
 		return err
 	}
 	defer resp.Body.Close()
 
+	// How quickly the peer responds ranks it for syncing blocks. A peer
+	// failing on its side is as good as down.
+	latency := s.clock.Now().Sub(sent)
+	if resp.StatusCode >= http.StatusInternalServerError {
+		s.knownPeers.ObserveFailure(peer.New(host))
+	}
+
+	// Count the bytes as they came over the wire.
+	cr := countingReader{Reader: resp.Body}
+	defer func() {
+		s.bandwidth.Received(host, cr.n)
+		if resp.StatusCode < http.StatusInternalServerError {
+			s.knownPeers.ObserveRequest(peer.New(host), latency, cr.n, s.clock.Now().Sub(sent))
+		}
+	}()
+
 	if resp.StatusCode == http.StatusNoContent {
 		return nil
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		msg, err := io.ReadAll(resp.Body)
+	// Since the encoding was asked for explicitly, the response
+	// isn't decompressed for us.
+	var body io.Reader = &cr
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		gr, err := gzip.NewReader(&cr)
 		if err != nil {
 			return err
 		}
-		return errors.New(string(msg))
+		defer gr.Close()
+		body = gr
+	}
+
+	// A peer that queues the request to be processed later accepts it.
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		msg, err := io.ReadAll(body)
+		if err != nil {
+			return err
+		}
+		return responseError(resp.StatusCode, msg)
 	}
 
 	if dataRecv != nil {
-		if err := json.NewDecoder(resp.Body).Decode(dataRecv); err != nil {
+		if err := json.NewDecoder(body).Decode(dataRecv); err != nil {
 			return err
 		}
 	}
 
 	return nil
 }
+
+// responseError converts the body of a failed response to an error. A
+// node's error response is reported with the fields that explain it, like
+// the check a proposed block failed.
+func responseError(status int, msg []byte) error {
+	var er struct {
+		Error  string            `json:"error"`
+		Fields map[string]string `json:"fields"`
+	}
+	if err := json.Unmarshal(msg, &er); err != nil || er.Error == "" {
+		return &peerError{status: status, msg: string(msg)}
+	}
+
+	return &peerError{status: status, msg: er.Error, fields: er.Fields}
+}
+
+// peerError represents an error a peer responded with and the fields that
+// explain it.
+type peerError struct {
+	status int
+	msg    string
+	fields map[string]string
+}
+
+// Error implements the error interface.
+func (pe *peerError) Error() string {
+	if len(pe.fields) == 0 {
+		return pe.msg
+	}
+
+	keys := make([]string, 0, len(pe.fields))
+	for key := range pe.fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(pe.msg)
+	for _, key := range keys {
+		fmt.Fprintf(&b, ": %s[%s]", key, pe.fields[key])
+	}
+
+	return b.String()
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	io.Reader
+	n int64
+}
+
+// Read implements the io.Reader interface.
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.Reader.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,149 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+	"github.com/qcbit/blockchain/foundation/blockchain/payout"
+)
+
+// ErrBlockOrphaned is returned when a block loses to a competing block
+// at the same height.
+var ErrBlockOrphaned = errors.New("block lost to a competing block at the same height")
+
+// maxOrphans is the number of orphans kept in memory. Storage that keeps
+// orphans keeps all of them.
+const maxOrphans = 1000
+
+// QueryOrphans returns the blocks that lost to a competing block at the
+// same height ordered by block number.
+func (s *State) QueryOrphans() []database.Orphan {
+	s.orphanMu.RLock()
+	defer s.orphanMu.RUnlock()
+
+	return append([]database.Orphan(nil), s.orphans...)
+}
+
+// =============================================================================
+
+// loadOrphans reads the orphans kept by storage.
+func (s *State) loadOrphans() error {
+	store, ok := s.storage.(database.OrphanStorage)
+	if !ok {
+		return nil
+	}
+
+	orphans, err := store.Orphans()
+	if err != nil {
+		return err
+	}
+	if len(orphans) > maxOrphans {
+		orphans = orphans[len(orphans)-maxOrphans:]
+	}
+
+	s.orphanMu.Lock()
+	defer s.orphanMu.Unlock()
+
+	s.orphans = orphans
+
+	return nil
+}
+
+// competingBlock resolves a block for the height of the latest block that
+// isn't the latest block. Both blocks are checked against the same parent
+// and the block with the lower hash wins so every node settles on the same
+// block no matter the order they arrived in. When the latest block wins
+// the competing block is recorded as an orphan and the latest block is
+// proposed to the peers again. When the competing block wins the latest
+// block is rolled back, its transactions go back to the mempool and nil
+// is returned so the competing block can be applied in its place.
+// The caller must hold the state lock.
+func (s *State) competingBlock(block database.Block, latest database.Block) error {
+	var parent database.Block
+	if block.Header.Number > 1 {
+		var err error
+		if parent, err = s.db.GetBlock(block.Header.Number - 1); err != nil {
+			return err
+		}
+	}
+
+	stateRoot, err := s.db.ParentStateRoot()
+	if err != nil {
+		return err
+	}
+
+	if err := block.ValidateBlock(parent, stateRoot, s.db.Rules(block.Header.Number), s.evHandler); err != nil {
+		return err
+	}
+
+	if latest.Hash() < block.Hash() {
+		s.recordOrphan(block, latest, "lost the tie-break to the latest block")
+
+		go func() {
+			if err := s.NetSendBlockToPeers(latest); err != nil {
+				s.evHandler("state: competingBlock: WARNING: %s", err)
+			}
+		}()
+
+		return fmt.Errorf("%w: block[%d]: hash[%s]: winner[%s]", ErrBlockOrphaned, block.Header.Number, block.Hash(), latest.Hash())
+	}
+
+	changed := s.db.ChangedAccounts(latest.Header.Number)
+	if err := s.db.Rollback(parent); err != nil {
+		return err
+	}
+	s.publishAccountsChanged(latest, changed, true)
+
+	s.seenBlocks.Forget(latest.Hash())
+	s.evHandler("state: competingBlock: rolled back blk[%d]: hash[%s]", latest.Header.Number, latest.Hash())
+
+	poolID, _ := s.poolAccount()
+	for _, tx := range latest.MerkleTree.Values() {
+		if tx.FromID == poolID && payout.IsPayout(tx.Tx) {
+			continue
+		}
+		old, replaced, err := s.mempool.Upsert(tx)
+		if err != nil {
+			s.evHandler("state: competingBlock: WARNING: %s", err)
+			continue
+		}
+		s.publishMempoolUpsert(tx, old, replaced, "block rolled back")
+	}
+
+	s.recordOrphan(latest, block, "lost the tie-break to a competing block")
+
+	return nil
+}
+
+// recordOrphan keeps the block that lost to the winning block.
+func (s *State) recordOrphan(block database.Block, winner database.Block, reason string) {
+	orphan := database.Orphan{
+		Block:      database.NewBlockData(block),
+		WinnerHash: winner.Hash(),
+		Reason:     reason,
+		SeenAt:     uint64(s.clock.Now().UTC().UnixMilli()),
+	}
+
+	s.orphanMu.Lock()
+	for _, known := range s.orphans {
+		if known.Block.Hash == orphan.Block.Hash {
+			s.orphanMu.Unlock()
+			return
+		}
+	}
+	s.orphans = append(s.orphans, orphan)
+	if len(s.orphans) > maxOrphans {
+		s.orphans = s.orphans[len(s.orphans)-maxOrphans:]
+	}
+	s.orphanMu.Unlock()
+
+	s.stats.orphans.Add(1)
+	s.evHandler("state: recordOrphan: blk[%d]: hash[%s]: winner[%s]: %s", block.Header.Number, orphan.Block.Hash, orphan.WinnerHash, reason)
+
+	if store, ok := s.storage.(database.OrphanStorage); ok {
+		if err := store.WriteOrphan(orphan); err != nil {
+			s.evHandler("state: recordOrphan: WARNING: %s", err)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package state
+
+import (
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+	"github.com/qcbit/blockchain/foundation/blockchain/payout"
+)
+
+// maxPayouts is the number of payouts kept in the ledger.
+const maxPayouts = 1000
+
+// Payouts returns up to limit payouts of mining rewards to the pool
+// contributors starting with the most recent.
+func (s *State) Payouts(limit int) []payout.Payout {
+	return s.payouts.List(limit)
+}
+
+// PoolShares returns the shares of every mining reward paid to the pool
+// contributors.
+func (s *State) PoolShares() []payout.Share {
+	return append([]payout.Share(nil), s.poolShares...)
+}
+
+// =============================================================================
+
+// poolAccount returns the account that pays out the mining rewards. Only
+// the node's own account can pay out since it holds the key to sign for it.
+func (s *State) poolAccount() (database.AccountID, bool) {
+	if len(s.poolShares) == 0 || !s.CanMine() {
+		return "", false
+	}
+
+	return database.PublicKeyToAccountID(s.privateKey.PublicKey), true
+}
+
+// payoutTxs returns the transfers that pay the pool contributors their
+// share of the reward for the block when the pool account is paid for it.
+// The transfers follow the transactions picked for the block so their
+// nonces come after any the pool account sent itself.
+func (s *State) payoutTxs(args database.POWArgs) []database.BlockTx {
+	poolID, ok := s.poolAccount()
+	if !ok || args.BeneficiaryID != poolID {
+		return nil
+	}
+
+	var nonce uint64
+	if account, err := s.db.Query(poolID); err == nil {
+		nonce = account.Nonce
+	}
+	for _, tx := range args.Trans {
+		if tx.FromID == poolID && tx.Nonce > nonce {
+			nonce = tx.Nonce
+		}
+	}
+
+	const oneUnitOfGas = 1
+	gasPrice := s.db.Rules(args.PrevBlock.Header.Number + 1).GasPrice
+
+	var trans []database.BlockTx
+	for _, amount := range payout.Split(args.MiningReward, s.poolShares) {
+		if amount.Amount == 0 {
+			continue
+		}
+
+		nonce++
+		tx, err := database.NewTx(s.genesis.ChainID, poolID, amount.AccountID, amount.Amount, nonce, 0, payout.TxData)
+		if err != nil {
+			s.evHandler("state: payoutTxs: WARNING: %s", err)
+			return nil
+		}
+
+		signedTx, err := tx.Sign(s.privateKey)
+		if err != nil {
+			s.evHandler("state: payoutTxs: WARNING: %s", err)
+			return nil
+		}
+
+		trans = append(trans, database.NewBlockTxAt(signedTx, gasPrice, oneUnitOfGas, s.clock.Now()))
+	}
+
+	return trans
+}
+
+// recordPayout adds the payout of the block's reward to the ledger when the
+// block was paid to the pool account.
+func (s *State) recordPayout(block database.Block) {
+	poolID, ok := s.poolAccount()
+	if !ok || block.Header.BeneficiaryID != poolID {
+		return
+	}
+
+	p := payout.Payout{
+		BlockNumber: block.Header.Number,
+		BlockHash:   block.Hash(),
+		Reward:      block.Header.MiningReward,
+	}
+
+	algorithm := s.db.HashAlgorithm(block.Header.Number)
+	for _, tx := range block.MerkleTree.Values() {
+		if tx.FromID != poolID || !payout.IsPayout(tx.Tx) {
+			continue
+		}
+
+		transfer := payout.Transfer{
+			AccountID: tx.ToID,
+			Amount:    tx.Value,
+			TxHash:    tx.TxHash(algorithm),
+		}
+		if receipt, exists := s.db.Receipt(transfer.TxHash); exists {
+			transfer.Status = receipt.Status
+			transfer.Error = receipt.Error
+		}
+		if transfer.Status == database.ReceiptSuccess {
+			p.Paid += transfer.Amount
+		}
+
+		p.Transfers = append(p.Transfers, transfer)
+	}
+
+	if len(p.Transfers) > 0 {
+		s.payouts.Add(p)
+	}
+}
+
+// loadPayouts fills the ledger with the payouts found in the chain.
+func (s *State) loadPayouts() error {
+	if _, ok := s.poolAccount(); !ok {
+		return nil
+	}
+
+	iter := s.db.ForEach()
+	for block, err := iter.Next(); !iter.Done(); block, err = iter.Next() {
+		if err != nil {
+			return err
+		}
+		s.recordPayout(block)
+	}
+
+	return nil
+}
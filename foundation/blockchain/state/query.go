@@ -1,6 +1,10 @@
 package state
 
-import "github.com/qcbit/blockchain/foundation/blockchain/database"
+import (
+	"context"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+)
 
 // QueryLatest represents to query the latest block in the chain.
 const QueryLatest = ^uint64(0) >> 1
@@ -12,9 +16,80 @@ func (s *State) QueryAccount(account database.AccountID) (database.Account, erro
 	return s.db.Query(account)
 }
 
+// QueryAccounts returns the specified accounts that exist as of the
+// returned latest block.
+func (s *State) QueryAccounts(accountIDs []database.AccountID) (database.Block, map[database.AccountID]database.Account) {
+	return s.db.QueryAccounts(accountIDs)
+}
+
+// QueryFrozen returns the accounts frozen by governance transactions.
+func (s *State) QueryFrozen() []database.AccountID {
+	return s.db.Frozen()
+}
+
+// QueryNonce returns the nonce of the account's last mined transaction and
+// the next nonce the account can use, taking into account the account's
+// transactions waiting in the mempool. Only transactions that follow each
+// other without a gap are counted since a gap stops the rest being mined.
+func (s *State) QueryNonce(accountID database.AccountID) (confirmed uint64, next uint64) {
+	if account, err := s.db.Query(accountID); err == nil {
+		confirmed = account.Nonce
+	}
+
+	next = confirmed + 1
+	for _, tx := range s.mempool.ByAccount(accountID) {
+		if tx.Nonce == next {
+			next++
+		}
+	}
+
+	return confirmed, next
+}
+
+// QuerySummary returns the overview of the account's activity in the mined
+// transactions.
+func (s *State) QuerySummary(accountID database.AccountID) database.AccountSummary {
+	return s.db.Summary(accountID)
+}
+
+// QueryReceipt returns the receipt of the mined transaction with the
+// specified hash.
+func (s *State) QueryReceipt(txHash string) (database.Receipt, bool) {
+	return s.db.Receipt(txHash)
+}
+
+// WaitForBlock blocks until a block with a number higher than the specified
+// number is accepted and returns the latest block. An error is returned if
+// the context is canceled or times out first.
+func (s *State) WaitForBlock(ctx context.Context, after uint64) (database.Block, error) {
+	for {
+		s.mu.RLock()
+		latest := s.db.LatestBlock()
+		newBlock := s.newBlock
+		s.mu.RUnlock()
+
+		if latest.Header.Number > after {
+			return latest, nil
+		}
+
+		select {
+		case <-newBlock:
+		case <-ctx.Done():
+			return database.Block{}, ctx.Err()
+		}
+	}
+}
+
+// QueryBlockDiff returns the changes the specified block made to the accounts.
+func (s *State) QueryBlockDiff(num uint64) ([]database.AccountDiff, error) {
+	return s.db.Diff(num)
+}
+
 // QueryBlocksByNumber returns the set of blocks based on block numbers.
-// This function reads the blockchain from disk first.
-func (s *State) QueryBlocksByNumber(from, to uint64) []database.Block {
+// This function reads the blockchain from disk first and stops when the
+// context is canceled. The blocks are read from the chain as of the latest
+// block when the query started, so blocks accepted meanwhile aren't mixed in.
+func (s *State) QueryBlocksByNumber(ctx context.Context, from, to uint64) ([]database.Block, error) {
 	if from == QueryLatest {
 		from = s.db.LatestBlock().Header.Number
 		to = from
@@ -24,14 +99,21 @@ func (s *State) QueryBlocksByNumber(from, to uint64) []database.Block {
 	}
 
 	var out []database.Block
-	for i := from; i <= to; i++ {
-		block, err := s.db.GetBlock(i)
+	iter := s.db.ForEachFrom(from)
+	for block, err := iter.Next(); !iter.Done(); block, err = iter.Next() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		if err != nil {
 			s.evHandler("state: getblock: ERROR: %s", err)
-			return nil
+			return nil, nil
+		}
+		if block.Header.Number > to {
+			break
 		}
 		out = append(out, block)
 	}
 
-	return out
+	return out, nil
 }
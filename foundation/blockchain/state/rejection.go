@@ -0,0 +1,81 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+	"github.com/qcbit/blockchain/foundation/blockchain/peer"
+)
+
+// CORE NOTE: A proposed block is validated by the worker after the peer that
+// proposed it has been told the block was queued. When the block is rejected
+// the verdict is sent back to that peer, with the check the block failed and
+// the values that failed it, so the reason doesn't have to be found in the
+// logs of both nodes.
+
+// rejectionTimeout is how long sending the verdict on a rejected block to
+// the peer that proposed it can take.
+const rejectionTimeout = 5 * time.Second
+
+// BlockRejection represents the verdict on a proposed block that wasn't
+// accepted. The check and values are set when a check of the block failed.
+type BlockRejection struct {
+	Number   uint64 `json:"number"`
+	Hash     string `json:"hash"`
+	Error    string `json:"error"`
+	Check    string `json:"check,omitempty"`
+	Got      string `json:"got,omitempty"`
+	Expected string `json:"expected,omitempty"`
+}
+
+// NetSendBlockRejection tells the peer that proposed the block why it
+// wasn't accepted.
+func (s *State) NetSendBlockRejection(host string, block database.Block, err error) {
+	rejection := BlockRejection{
+		Number: block.Header.Number,
+		Hash:   block.Hash(),
+		Error:  err.Error(),
+	}
+
+	var blockErr *database.BlockError
+	if errors.As(err, &blockErr) {
+		rejection.Check = blockErr.Check
+		rejection.Got = blockErr.Got
+		rejection.Expected = blockErr.Expected
+	}
+
+	envs := newEnvelopes(s, func(version uint16) any {
+		return rejection
+	})
+
+	p := peer.New(host)
+	env, err := envs.forPeer(p)
+	if err != nil {
+		s.evHandler("state: NetSendBlockRejection: peer[%s]: ERROR: %s", p, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rejectionTimeout)
+	defer cancel()
+
+	url := fmt.Sprintf("%s/block/rejected", fmt.Sprintf(baseURL, p.Host))
+	if err := s.sendWithContext(ctx, http.MethodPost, url, env, nil); err != nil {
+		s.evHandler("state: NetSendBlockRejection: peer[%s]: WARNING: %s", p, err)
+	}
+}
+
+// BlockRejected records the verdict of the peer on a block this node
+// proposed.
+func (s *State) BlockRejected(host string, rejection BlockRejection) {
+	if rejection.Check == "" {
+		s.evHandler("state: BlockRejected: REJECTED: peer[%s]: blk[%d]: hash[%s]: %s", host, rejection.Number, rejection.Hash, rejection.Error)
+		return
+	}
+
+	s.evHandler("state: BlockRejected: REJECTED: peer[%s]: blk[%d]: hash[%s]: check[%s]: got[%s]: expected[%s]",
+		host, rejection.Number, rejection.Hash, rejection.Check, rejection.Got, rejection.Expected)
+}
@@ -0,0 +1,19 @@
+package state
+
+import "errors"
+
+// CORE NOTE: A relay node takes part in the network like any other node. It
+// gossips transactions and blocks and validates everything it accepts, but
+// it has no beneficiary to pay so it never mines. Its peers are told in its
+// status so the PoA selection never picks it to propose a block. The key it
+// signs its peer messages with only identifies the node and holds no value.
+
+// ErrRelayOnly is returned when a node without a beneficiary is asked to
+// mine a block.
+var ErrRelayOnly = errors.New("node has no beneficiary and only relays blocks")
+
+// CanMine reports whether the node has a beneficiary to pay for the blocks
+// it mines. A node without one only relays and validates.
+func (s *State) CanMine() bool {
+	return s.beneficiaryID != ""
+}
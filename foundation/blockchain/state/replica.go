@@ -0,0 +1,67 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/peer"
+	"github.com/qcbit/blockchain/foundation/blockchain/protocol"
+)
+
+// CORE NOTE: A read replica doesn't take part in the network. It doesn't
+// gossip, mine or accept transactions. It tails the blocks accepted by a
+// primary node, validates and applies them like any other proposed block
+// and serves the read API. Operators can run as many replicas as they
+// need to handle the read traffic of explorers and wallets.
+
+// ErrReadReplica is returned when a transaction is submitted to a node that
+// is a read replica.
+var ErrReadReplica = errors.New("node is a read replica and does not accept transactions")
+
+// IsReadReplica reports whether the node follows a primary node instead of
+// taking part in the network.
+func (s *State) IsReadReplica() bool {
+	return s.primary != ""
+}
+
+// Primary returns the primary node a read replica follows.
+func (s *State) Primary() peer.Peer {
+	return peer.New(s.primary)
+}
+
+// NetWaitPeerBlocks holds a request open with the peer until it accepts
+// blocks this node does not have or the wait is over, and applies the blocks
+// it responds with. It reports how many blocks were applied.
+func (s *State) NetWaitPeerBlocks(ctx context.Context, p peer.Peer, wait time.Duration) (int, error) {
+	after := s.LatestBlock().Header.Number
+	url := fmt.Sprintf("%s/block/wait?after=%d&timeout=%s", fmt.Sprintf(baseURL, p.Host), after, wait)
+
+	var data json.RawMessage
+	if err := s.sendWithContext(ctx, http.MethodGet, url, nil, &data); err != nil {
+		return 0, err
+	}
+
+	// The peer responds with no content when no block arrived in time.
+	if len(data) == 0 {
+		return 0, nil
+	}
+
+	blocks, err := protocol.DecodeBlocks(data, s.db.HashAlgorithm)
+	if err != nil {
+		return 0, err
+	}
+
+	s.evHandler("state: NetWaitPeerBlocks: %s: after[%d]: found blocks[%d]", p, after, len(blocks))
+
+	for i, block := range blocks {
+		if err := s.ProcessProposedBlock(block); err != nil {
+			return i, err
+		}
+	}
+
+	return len(blocks), nil
+}
@@ -0,0 +1,144 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/peer"
+	"github.com/qcbit/blockchain/foundation/blockchain/protocol"
+)
+
+// CORE NOTE: Resetting the chain is only meant for development networks
+// that are restarted from genesis over and over. Every node of the network
+// has to allow it, since a node that keeps the old chain hands it back to
+// the nodes that reset the next time they sync with it.
+
+// ErrResetDisabled is returned when the chain is asked to reset on a node
+// that doesn't allow it.
+var ErrResetDisabled = errors.New("chain reset is not allowed on this node")
+
+// ChainReset represents what a reset of the chain removed.
+type ChainReset struct {
+	Blocks   uint64 `json:"blocks"`
+	Mempool  int    `json:"mempool"`
+	Accounts int    `json:"accounts"`
+}
+
+// ChainResetNotice represents the notice a node sends its peers after
+// resetting its chain, asking them to reset the same chain.
+type ChainResetNotice struct {
+	ChainID uint16 `json:"chain_id"`
+}
+
+// PeerReset represents how a peer responded to the notice.
+type PeerReset struct {
+	Host  string `json:"host"`
+	Reset bool   `json:"reset"`
+	Error string `json:"error,omitempty"`
+}
+
+// ResetAllowed reports whether the chain can be reset on this node.
+func (s *State) ResetAllowed() bool {
+	return s.allowReset
+}
+
+// ResetChain wipes the blocks, puts the accounts back to the genesis
+// balances and clears the mempool along with everything the node remembers
+// about the old chain, so the next block mined or synced is block 1.
+func (s *State) ResetChain(reason string) (ChainReset, error) {
+	if !s.allowReset {
+		return ChainReset{}, ErrResetDisabled
+	}
+
+	s.evHandler("state: ResetChain: started: reason[%s]", reason)
+	defer s.evHandler("state: ResetChain: completed")
+
+	// Hold back new blocks while the chain is replaced.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	latest := s.db.LatestBlock()
+
+	accounts, err := s.db.Reset(s.evHandler)
+	if err != nil {
+		return ChainReset{}, err
+	}
+
+	trans := s.mempool.PickBest()
+	s.mempool.Truncate()
+
+	s.resetWork()
+	s.seenBlocks.Reset()
+	s.seenTxs.Reset()
+	s.retries.clear()
+
+	s.orphanMu.Lock()
+	s.orphans = nil
+	s.orphanMu.Unlock()
+
+	s.syncMu.Lock()
+	s.syncing = syncState{}
+	s.syncMu.Unlock()
+
+	// The accounts are rolled back to the genesis block and the mempool
+	// is emptied.
+	genesisBlock := s.db.LatestBlock()
+	for _, tx := range trans {
+		s.publishMempoolRemoved(tx, genesisBlock, false, "chain reset")
+	}
+	s.publishAccountsChanged(genesisBlock, accounts, true)
+
+	reset := ChainReset{
+		Blocks:   latest.Header.Number,
+		Mempool:  len(trans),
+		Accounts: len(accounts),
+	}
+
+	s.evHandler("state: ResetChain: blocks[%d]: mempool[%d]: accounts[%d]", reset.Blocks, reset.Mempool, reset.Accounts)
+
+	return reset, nil
+}
+
+// NetSendChainResetToPeers asks the known peers to reset the chain too.
+// Peers that don't allow it keep their chain and are reported with the
+// error they responded with.
+func (s *State) NetSendChainResetToPeers() []PeerReset {
+	s.evHandler("state: NetSendChainResetToPeers: started")
+	defer s.evHandler("state: NetSendChainResetToPeers: completed")
+
+	notice := ChainResetNotice{
+		ChainID: s.genesis.ChainID,
+	}
+
+	var resets []PeerReset
+	for _, p := range s.KnownExternalPeers() {
+		pr := PeerReset{
+			Host:  p.Host,
+			Reset: true,
+		}
+
+		if err := s.sendChainReset(p, notice); err != nil {
+			s.evHandler("state: NetSendChainResetToPeers: WARNING: peer[%s]: %s", p, err)
+			pr.Reset = false
+			pr.Error = err.Error()
+		}
+
+		resets = append(resets, pr)
+	}
+
+	return resets
+}
+
+// sendChainReset sends the notice to the peer signed by this node.
+func (s *State) sendChainReset(p peer.Peer, notice ChainResetNotice) error {
+	version := protocol.Negotiate(s.knownPeers.Version(p))
+
+	env, err := peer.NewVersionedEnvelope(s.host, version, notice, s.privateKey, s.clock.Now())
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/chain/reset/notice", fmt.Sprintf(baseURL, p.Host))
+	return s.send(http.MethodPost, url, env, nil)
+}
@@ -0,0 +1,196 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/peer"
+)
+
+// CORE NOTE: A transaction or block that couldn't be sent to a peer because
+// the peer was briefly unreachable is queued for that peer and sent again,
+// waiting twice as long after every failure. Without this the mempools of
+// the nodes drift apart after an outage until the next block is synced. A
+// peer that rejects the data is never sent it again.
+
+// The limits on sending gossip again.
+const (
+	retryBaseBackoff = time.Second
+	retryMaxBackoff  = time.Minute
+	retryMaxAttempts = 8
+	retryMaxPerPeer  = 100 // Oldest gossip is dropped past this many.
+)
+
+// gossip represents a transaction or block sent to the peers.
+type gossip struct {
+	kind   string // tx or block, used in events.
+	id     string
+	path   string
+	encode func(version uint16) any
+}
+
+// retry represents gossip waiting to be sent to a peer again.
+type retry struct {
+	gossip   gossip
+	attempts int
+	next     time.Time
+}
+
+// retryQueue holds the gossip waiting to be sent again to each peer.
+type retryQueue struct {
+	mu    sync.Mutex
+	peers map[peer.Peer][]retry
+}
+
+// newRetryQueue constructs an empty retry queue.
+func newRetryQueue() *retryQueue {
+	return &retryQueue{
+		peers: make(map[peer.Peer][]retry),
+	}
+}
+
+// add queues the gossip for the peer after a failed attempt. It returns
+// false when the gossip ran out of attempts.
+func (rq *retryQueue) add(p peer.Peer, r retry, now time.Time) bool {
+	r.attempts++
+	if r.attempts > retryMaxAttempts {
+		return false
+	}
+
+	backoff := retryBaseBackoff << (r.attempts - 1)
+	if backoff > retryMaxBackoff {
+		backoff = retryMaxBackoff
+	}
+	r.next = now.Add(backoff)
+
+	rq.mu.Lock()
+	defer rq.mu.Unlock()
+
+	queue := append(rq.peers[p], r)
+	if len(queue) > retryMaxPerPeer {
+		queue = queue[len(queue)-retryMaxPerPeer:]
+	}
+	rq.peers[p] = queue
+
+	return true
+}
+
+// due removes and returns the gossip that is due to be sent again.
+func (rq *retryQueue) due(now time.Time) map[peer.Peer][]retry {
+	rq.mu.Lock()
+	defer rq.mu.Unlock()
+
+	due := make(map[peer.Peer][]retry)
+	for p, queue := range rq.peers {
+		var waiting []retry
+		for _, r := range queue {
+			if now.Before(r.next) {
+				waiting = append(waiting, r)
+				continue
+			}
+			due[p] = append(due[p], r)
+		}
+
+		if len(waiting) == 0 {
+			delete(rq.peers, p)
+			continue
+		}
+		rq.peers[p] = waiting
+	}
+
+	return due
+}
+
+// clear drops the gossip waiting for every peer.
+func (rq *retryQueue) clear() {
+	rq.mu.Lock()
+	defer rq.mu.Unlock()
+
+	rq.peers = make(map[peer.Peer][]retry)
+}
+
+// pending returns the number of gossip waiting to be sent again to each
+// peer.
+func (rq *retryQueue) pending() map[string]int {
+	rq.mu.Lock()
+	defer rq.mu.Unlock()
+
+	pending := make(map[string]int, len(rq.peers))
+	for p, queue := range rq.peers {
+		pending[p.Host] = len(queue)
+	}
+
+	return pending
+}
+
+// =============================================================================
+
+// NetRetryGossip sends the transactions and blocks that failed to reach a
+// peer again once they are due. Gossip for a peer that is no longer known
+// is dropped.
+func (s *State) NetRetryGossip() {
+	due := s.retries.due(s.clock.Now())
+	if len(due) == 0 {
+		return
+	}
+
+	known := make(map[peer.Peer]bool)
+	for _, p := range s.KnownExternalPeers() {
+		known[p] = true
+	}
+
+	for p, retries := range due {
+		if !known[p] {
+			s.evHandler("state: NetRetryGossip: peer[%s]: no longer known, dropped[%d]", p.Host, len(retries))
+			continue
+		}
+
+		for _, r := range retries {
+			s.evHandler("state: NetRetryGossip: peer[%s]: %s[%s]: attempt[%d]", p.Host, r.gossip.kind, r.gossip.id, r.attempts+1)
+			if err := s.sendGossip(newEnvelopes(s, r.gossip.encode), p, r); err != nil {
+				s.evHandler("state: NetRetryGossip: WARNING: %s", err)
+			}
+		}
+	}
+}
+
+// sendGossip sends the gossip to the peer and queues it to be sent again
+// when the peer couldn't be reached.
+func (s *State) sendGossip(envs *envelopes, p peer.Peer, r retry) error {
+	env, err := envs.forPeer(p)
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/%s", fmt.Sprintf(baseURL, p.Host), r.gossip.path)
+
+	err = s.send(http.MethodPost, url, env, nil)
+	if err == nil {
+		return nil
+	}
+	err = fmt.Errorf("%s: %w", p.Host, err)
+
+	if !retryable(err) {
+		return err
+	}
+
+	if !s.retries.add(p, r, s.clock.Now()) {
+		s.evHandler("state: sendGossip: peer[%s]: %s[%s]: giving up after %d attempts", p.Host, r.gossip.kind, r.gossip.id, retryMaxAttempts)
+	}
+
+	return err
+}
+
+// retryable reports whether the failure to send gossip was the peer being
+// unreachable or busy rather than the peer rejecting it.
+func retryable(err error) bool {
+	var pe *peerError
+	if errors.As(err, &pe) {
+		return pe.status >= http.StatusInternalServerError
+	}
+
+	return true
+}
@@ -0,0 +1,92 @@
+package state
+
+import (
+	"context"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+)
+
+// BlockRevenue represents what the beneficiary of a block was paid for
+// mining it. The tips and gas fees are taken from the receipts of the
+// block's transactions.
+type BlockRevenue struct {
+	Number      uint64
+	Hash        string
+	Beneficiary database.AccountID
+	Reward      uint64
+	Tips        uint64
+	GasFees     uint64
+	Trans       int
+	Missing     int // Transactions without a receipt to tell what they paid.
+}
+
+// Total returns everything the beneficiary was paid for the block.
+func (br BlockRevenue) Total() uint64 {
+	return br.Reward + br.Tips + br.GasFees
+}
+
+// QueryRevenue returns the revenue of the blocks from the specified number
+// to the latest block that paid one of the accounts. Without accounts the
+// blocks that paid this node's beneficiaries are returned.
+func (s *State) QueryRevenue(ctx context.Context, from uint64, accounts []database.AccountID) ([]BlockRevenue, error) {
+	if len(accounts) == 0 {
+		accounts = s.beneficiaries()
+	}
+
+	paid := make(map[database.AccountID]struct{}, len(accounts))
+	for _, accountID := range accounts {
+		paid[accountID] = struct{}{}
+	}
+
+	var out []BlockRevenue
+	iter := s.db.ForEachFrom(from)
+	for block, err := iter.Next(); !iter.Done(); block, err = iter.Next() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if _, exists := paid[block.Header.BeneficiaryID]; !exists {
+			continue
+		}
+
+		br := BlockRevenue{
+			Number:      block.Header.Number,
+			Hash:        block.Hash(),
+			Beneficiary: block.Header.BeneficiaryID,
+			Reward:      block.Header.MiningReward,
+		}
+
+		algorithm := s.db.HashAlgorithm(block.Header.Number)
+		for _, tx := range block.MerkleTree.Values() {
+			br.Trans++
+
+			receipt, exists := s.db.Receipt(tx.TxHash(algorithm))
+			if !exists {
+				br.Missing++
+				continue
+			}
+			br.Tips += receipt.Tip
+			br.GasFees += receipt.GasFee
+		}
+
+		out = append(out, br)
+	}
+
+	return out, nil
+}
+
+// beneficiaries returns the accounts paid for the blocks this node mines.
+func (s *State) beneficiaries() []database.AccountID {
+	s.rotationMu.RLock()
+	defer s.rotationMu.RUnlock()
+
+	accounts := []database.AccountID{s.beneficiaryID}
+	for _, ben := range s.rotation.Beneficiaries {
+		accounts = append(accounts, ben.AccountID)
+	}
+
+	return accounts
+}
@@ -0,0 +1,66 @@
+package state
+
+import (
+	"time"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+)
+
+// FullyValidated reports whether every block in the chain has been
+// validated. A node started from a snapshot serves the chain before the
+// blocks the snapshot was taken from are audited.
+func (s *State) FullyValidated() bool {
+	return s.db.Validated()
+}
+
+// AuditFailed returns a channel that receives the error of a background
+// audit that failed. The snapshot the node serves from doesn't match the
+// chain, so the node must stop serving reads and mining.
+func (s *State) AuditFailed() <-chan error {
+	return s.auditFailed
+}
+
+// =============================================================================
+
+// audit checks the blocks the snapshot was taken from. A failed audit
+// leaves the chain marked as not fully validated and is reported on the
+// AuditFailed channel.
+func (s *State) audit() {
+	s.evHandler("state: audit: started")
+	start := time.Now()
+
+	if err := s.db.Audit(s.evHandler); err != nil {
+		s.evHandler("state: audit: ERROR: %s", err)
+		s.auditFailed <- err
+		return
+	}
+
+	s.evHandler("state: audit: completed: elapsed[%s]", time.Since(start).Round(time.Millisecond))
+}
+
+// writeSnapshot keeps the accounts as they are after the latest block in
+// storage. The snapshot of a chain that hasn't been audited is left as is
+// so the next start audits it instead.
+func (s *State) writeSnapshot() {
+	store, ok := s.storage.(database.SnapshotStorage)
+	if !ok || !s.db.Validated() {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// A chain without blocks has nothing worth keeping.
+	if s.db.LatestBlock().Header.Number == 0 {
+		return
+	}
+
+	snapshot := s.db.ChainSnapshot()
+
+	if err := store.WriteSnapshot(snapshot); err != nil {
+		s.evHandler("state: snapshot: ERROR: %s", err)
+		return
+	}
+
+	s.evHandler("state: snapshot: written: block[%d]: accounts[%d]", snapshot.Block.Header.Number, len(snapshot.Accounts))
+}
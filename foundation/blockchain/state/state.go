@@ -3,12 +3,22 @@
 package state
 
 import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
 	"sync"
+	"time"
 
+	"github.com/qcbit/blockchain/foundation/blockchain/clock"
 	"github.com/qcbit/blockchain/foundation/blockchain/database"
 	"github.com/qcbit/blockchain/foundation/blockchain/genesis"
 	"github.com/qcbit/blockchain/foundation/blockchain/mempool"
+	"github.com/qcbit/blockchain/foundation/blockchain/netfault"
+	"github.com/qcbit/blockchain/foundation/blockchain/payout"
 	"github.com/qcbit/blockchain/foundation/blockchain/peer"
+	"github.com/qcbit/blockchain/foundation/blockchain/webhook"
 )
 
 // The set of different consensus algorithms that can be used.
@@ -17,26 +27,41 @@ const (
 	ConsensusPOA = "POA"
 )
 
+// ErrClockDrift is returned when the node's clock is too far off the clocks
+// of its peers.
+var ErrClockDrift = errors.New("clock drifted from peers")
+
 // EventHandler defines a function that is called when events
 // occur in the processing of persisting blocks.
 type EventHandler func(v string, args ...any)
 
 // Worker interface represents the behavior required to be implemented by any
 // package providing support for mining,peer updates,and transaction sharing.
+// The worker learns about new transactions and blocks by subscribing to the
+// state like any other component.
 type Worker interface {
 	Shutdown()
 	Sync()
-	SignalStartMining()
-	SignalCancelMining()
-	SignalShareTx(blockTx database.BlockTx)
+	SignalProposeBlock(host string, block database.Block) bool
 }
 
+// maxAlternatePeers is the most peers offered to a peer turned away for
+// lack of slots.
+const maxAlternatePeers = 8
+
+// The most blocks and transactions remembered as seen when gossiped.
+const (
+	maxSeenBlocks = 1_000
+	maxSeenTxs    = 10_000
+)
+
 //------------------------------------------------------------
 
 // Config represents the configuration required to
 // start the blockchain node.
 type Config struct {
-	BeneficiaryID  database.AccountID
+	BeneficiaryID  database.AccountID // Empty for a node that only relays and validates.
+	PrivateKey     *ecdsa.PrivateKey  // Signs the messages sent to peers.
 	Host           string
 	Storage        database.Storage
 	Genesis        genesis.Genesis
@@ -44,6 +69,20 @@ type Config struct {
 	SelectStrategy string
 	EvHandler      EventHandler
 	Consensus      string
+	Clock          clock.Clock        // The system clock is used when nil.
+	Retention      database.Retention // What compacting storage leaves untouched.
+	Primary        string             // Private host of the primary node a read replica follows.
+	PeerBandwidth  int64              // Bytes per second sent to any one peer, zero is unlimited.
+	Rotation       Rotation           // Accounts paid in turn for mined blocks instead of the beneficiary.
+	PoolShares     []payout.Share     // Shares of the mining reward paid out to pool contributors.
+	ExtraData      string             // Tag added to the blocks this node mines.
+	LazyLoad       bool               // Start from the latest snapshot and audit the chain in the background.
+	Faults         netfault.Config    // Faults injected into the calls made to peers, for testing only.
+	PeerLimits     peer.Limits        // Most peers kept, zero limits are no limit.
+	PeerAccess     peer.Access        // Peers allowed to register, gossip and propose blocks.
+	MaxClockDrift  time.Duration      // Most the clock can be off the peers' median before warning, zero never warns.
+	Chain          string             // Name of an extra chain hosted in the process, empty for the main chain.
+	AllowReset     bool               // Lets the chain be wiped back to genesis, for development networks only.
 }
 
 // State manages the blockchain database.
@@ -51,15 +90,49 @@ type State struct {
 	mu sync.RWMutex
 
 	beneficiaryID database.AccountID
+	privateKey    *ecdsa.PrivateKey
 	host          string
 	evHandler     EventHandler
 	consensus     string
-
-	knownPeers *peer.PeerSet
-	storage    database.Storage
-	genesis    genesis.Genesis
-	mempool    *mempool.Mempool
-	db         *database.Database
+	clock         clock.Clock
+	retention     database.Retention
+	primary       string
+	extraData     string
+	maxDrift      time.Duration
+	allowReset    bool
+	chain         string // Name of an extra chain hosted in the process, empty for the main chain.
+	stats         *chainStats
+
+	knownPeers  *peer.PeerSet
+	peerAccess  peer.Access
+	bandwidth   *peer.Bandwidth
+	transport   http.RoundTripper // Used for the calls made to peers, the default when nil.
+	latency     *peer.Propagation
+	seenBlocks  *peer.Seen // Blocks accepted, so copies from other peers aren't validated again.
+	seenTxs     *peer.Seen // Transactions added to the mempool, so copies aren't validated again.
+	envelopes   *peer.Guard
+	drift       *clock.Drift // How far the clock is from the clocks of the peers.
+	retries     *retryQueue
+	storage     database.Storage
+	genesis     genesis.Genesis
+	mempool     *mempool.Mempool
+	db          *database.Database
+	webhooks    *webhook.Webhooks
+	newBlock    chan struct{} // Closed and replaced when a block is accepted.
+	auditFailed chan error    // Receives the error of a failed background audit.
+	workMu      sync.Mutex
+	work        workStore
+	subMu       sync.RWMutex
+	subs        map[int]Subscriber // Components told about state changes.
+	nextSubID   int
+	rotationMu  sync.RWMutex
+	rotation    Rotation
+	poolShares  []payout.Share
+	payouts     *payout.Ledger
+	orphanMu    sync.RWMutex
+	orphans     []database.Orphan
+	syncMu      sync.RWMutex
+	syncing     syncState
 
 	Worker Worker
 }
@@ -73,8 +146,44 @@ func New(cfg Config) (*State, error) {
 		}
 	}
 
-	// Access the storage for the blockchain.
-	db, err := database.New(cfg.Genesis, cfg.Storage, ev)
+	// An empty rotation pays the beneficiary for every block.
+	if cfg.Rotation.Strategy == "" {
+		cfg.Rotation.Strategy = RotateRoundRobin
+	}
+	if err := cfg.Rotation.validate(); err != nil {
+		return nil, err
+	}
+
+	if err := payout.Validate(cfg.PoolShares); err != nil {
+		return nil, err
+	}
+
+	if len(cfg.ExtraData) > database.MaxExtraData {
+		return nil, fmt.Errorf("extra data is %d bytes, can't be more than %d", len(cfg.ExtraData), database.MaxExtraData)
+	}
+
+	// Inject faults into the calls made to peers when testing how the
+	// node behaves under a bad network.
+	var transport http.RoundTripper
+	if cfg.Faults.Enabled() {
+		t, err := netfault.New(cfg.Faults, nil)
+		if err != nil {
+			return nil, err
+		}
+		transport = t
+
+		ev("state: New: WARNING: injecting network faults: latency[%s]: jitter[%s]: drop[%v]: reorder[%v]: seed[%d]",
+			cfg.Faults.Latency, cfg.Faults.Jitter, cfg.Faults.DropRate, cfg.Faults.ReorderRate, cfg.Faults.Seed)
+	}
+
+	// Access the storage for the blockchain. A lazy load serves the chain
+	// from the latest snapshot while the blocks before it are audited.
+	newDatabase := database.New
+	if cfg.LazyLoad {
+		newDatabase = database.NewFromSnapshot
+	}
+
+	db, err := newDatabase(cfg.Genesis, cfg.Storage, ev, database.WithRetention(cfg.Retention))
 	if err != nil {
 		return nil, err
 	}
@@ -85,22 +194,83 @@ func New(cfg Config) (*State, error) {
 		return nil, err
 	}
 
+	// The peers already known were configured for the node and don't
+	// take a slot.
+	cfg.KnownPeers.SetLimits(cfg.PeerLimits)
+
 	// The Worker is not set here. The call to worker.Run() will assign
 	// itself and start everything up and running for the node.
 
 	// Create the State to provide support for managing the blockchain.
-	return &State{
+	state := State{
 		beneficiaryID: cfg.BeneficiaryID,
+		privateKey:    cfg.PrivateKey,
 		storage:       cfg.Storage,
 		evHandler:     ev,
-		host:          cfg.Host,
+		host:          peer.NormalizeHost(cfg.Host),
 		consensus:     cfg.Consensus,
+		clock:         clock.OrSystem(cfg.Clock),
+		retention:     cfg.Retention,
+		primary:       cfg.Primary,
+		extraData:     cfg.ExtraData,
+		maxDrift:      cfg.MaxClockDrift,
+		allowReset:    cfg.AllowReset,
+		chain:         cfg.Chain,
+		stats:         newChainStats(cfg.Chain),
+
+		knownPeers:  cfg.KnownPeers,
+		peerAccess:  cfg.PeerAccess,
+		bandwidth:   peer.NewBandwidth(cfg.PeerBandwidth),
+		transport:   transport,
+		latency:     peer.NewPropagation(),
+		seenBlocks:  peer.NewSeen(maxSeenBlocks),
+		seenTxs:     peer.NewSeen(maxSeenTxs),
+		envelopes:   peer.NewGuard(),
+		drift:       clock.NewDrift(),
+		retries:     newRetryQueue(),
+		genesis:     cfg.Genesis,
+		mempool:     mempool,
+		db:          db,
+		webhooks:    webhook.New(cfg.Host, cfg.PrivateKey, cfg.Clock, ev),
+		newBlock:    make(chan struct{}),
+		auditFailed: make(chan error, 1),
+		work:        workStore{blocks: make(map[string]database.Block)},
+		subs:        make(map[int]Subscriber),
+		rotation:    cfg.Rotation,
+		poolShares:  cfg.PoolShares,
+		payouts:     payout.NewLedger(maxPayouts),
+	}
+
+	// Recover the blocks that lost to competing blocks.
+	if err := state.loadOrphans(); err != nil {
+		return nil, err
+	}
+
+	// Recover the payouts of the blocks already in the chain.
+	if err := state.loadPayouts(); err != nil {
+		return nil, err
+	}
+
+	// Publish the blockchain internals on the debug mux.
+	state.publishStats()
 
-		knownPeers: cfg.KnownPeers,
-		genesis:    cfg.Genesis,
-		mempool:    mempool,
-		db:         db,
-	}, nil
+	// Notify the webhook subscriptions of any matching transactions
+	// in the blocks that are accepted.
+	state.Subscribe(SubscriberFuncs{
+		BlockAccepted: state.webhooks.Notify,
+	})
+
+	// Record the payouts to the pool contributors in the accepted blocks.
+	state.Subscribe(SubscriberFuncs{
+		BlockAccepted: state.recordPayout,
+	})
+
+	// Check the blocks the snapshot was taken from in the background.
+	if !db.Validated() {
+		go state.audit()
+	}
+
+	return &state, nil
 }
 
 // Shutdown cleanly brings the node down.
@@ -116,6 +286,18 @@ func (s *State) Shutdown() error {
 	// Stop all blockchain writing activity.
 	s.Worker.Shutdown()
 
+	// Tell the peers this node is gone so they stop sending to it. A
+	// read replica never joined the network.
+	if !s.IsReadReplica() {
+		s.NetSendLeavingToPeers("shutting down")
+	}
+
+	// Stop any webhook notifications from being retried.
+	s.webhooks.Shutdown()
+
+	// Keep the accounts so the next start doesn't replay the chain.
+	s.writeSnapshot()
+
 	return nil
 }
 
@@ -124,6 +306,11 @@ func (s *State) Consensus() string {
 	return s.consensus
 }
 
+// Clock returns the clock used to tell time.
+func (s *State) Clock() clock.Clock {
+	return s.clock
+}
+
 // LatestBlock returns a copy of the current latest block.
 func (s *State) LatestBlock() database.Block {
 	return s.db.LatestBlock()
@@ -134,6 +321,17 @@ func (s *State) Genesis() genesis.Genesis {
 	return s.genesis
 }
 
+// HashAlgorithm returns the hash algorithm in effect for the block with
+// the specified number.
+func (s *State) HashAlgorithm(blockNumber uint64) string {
+	return s.db.HashAlgorithm(blockNumber)
+}
+
+// Rules returns the chain parameters in effect for the next block.
+func (s *State) Rules() genesis.Rules {
+	return s.db.Rules(s.db.LatestBlock().Header.Number + 1)
+}
+
 // MempoolLength returns the number of transactions in the mempool.
 func (s *State) MempoolLength() int {
 	return s.mempool.Count()
@@ -144,9 +342,32 @@ func (s *State) Mempool() []database.BlockTx {
 	return s.mempool.PickBest()
 }
 
+// PreviewMempool returns where a transaction paying the specified tip would
+// be picked for the next blocks if it were added to the mempool, looking
+// at most maxBlocks blocks ahead.
+func (s *State) PreviewMempool(tip uint64, maxBlocks int) mempool.Position {
+	tx := database.BlockTx{
+		SignedTx: database.SignedTx{
+			Tx: database.Tx{
+				Nonce: 1,
+				Tip:   tip,
+			},
+		},
+	}
+
+	return s.mempool.Preview(tx, int(s.genesis.TransPerBlock), maxBlocks)
+}
+
 // UpsertMempool adds a new transaction to the mempool
 func (s *State) UpsertMempool(tx database.BlockTx) error {
-	return s.mempool.Upsert(tx)
+	old, replaced, err := s.mempool.Upsert(tx)
+	if err != nil {
+		return err
+	}
+
+	s.publishMempoolUpsert(tx, old, replaced, "synced from a peer")
+
+	return nil
 }
 
 // Accounts returns a copy of the database accounts.
@@ -159,23 +380,209 @@ func (s *State) Host() string {
 	return s.host
 }
 
+// Chain returns the name of the extra chain hosted in the process, empty
+// for the main chain.
+func (s *State) Chain() string {
+	return s.chain
+}
+
+// Bandwidth returns the traffic accounting of the peers this node
+// exchanges data with.
+func (s *State) Bandwidth() *peer.Bandwidth {
+	return s.bandwidth
+}
+
+// Propagation returns how long the blocks proposed by each peer took to
+// reach this node.
+func (s *State) Propagation() *peer.Propagation {
+	return s.latency
+}
+
+// SeenBlock reports whether the block was already accepted by this node
+// so a copy gossiped by another peer can be acknowledged without being
+// validated again.
+func (s *State) SeenBlock(hash string) bool {
+	return s.seenBlocks.Contains(hash)
+}
+
+// SeenTx reports whether the transaction was already added to the mempool
+// so a copy gossiped by another peer can be acknowledged without being
+// validated again.
+func (s *State) SeenTx(hash string) bool {
+	return s.seenTxs.Contains(hash)
+}
+
+// AcceptEnvelope verifies the envelope sent by a peer against the state's
+// clock and returns the account of the node that signed it. An envelope is
+// only accepted once, and the host of a known peer is bound to the account
+// that first signs for it so no other node can send in its name.
+func (s *State) AcceptEnvelope(env peer.Envelope) (string, error) {
+	signer, err := s.envelopes.Accept(env, s.clock.Now())
+	if err != nil {
+		return "", err
+	}
+
+	if s.knownPeers.Contains(peer.New(env.Host)) {
+		if err := s.envelopes.Bind(env.Host, signer); err != nil {
+			return "", err
+		}
+	}
+
+	return signer, nil
+}
+
+// SignStatus signs the chain head reported in the node's status with the
+// node's private key so peers can tell it came from this node.
+func (s *State) SignStatus(status *peer.PeerStatus) error {
+	return peer.SignStatus(status, s.privateKey, s.clock.Now())
+}
+
+// ClockDrift returns the median of how far the node's clock is ahead of the
+// clocks of its peers, negative when it's behind, and the number of peers it
+// was taken from.
+func (s *State) ClockDrift() (time.Duration, int) {
+	return s.drift.Estimate()
+}
+
+// CheckClockDrift returns the ErrClockDrift error when the node's clock is
+// further off the clocks of its peers than allowed. Block timestamps mined
+// or validated with such a clock can't be trusted.
+func (s *State) CheckClockDrift() error {
+	drift, peers := s.drift.Estimate()
+	if s.maxDrift <= 0 || peers == 0 || (drift <= s.maxDrift && drift >= -s.maxDrift) {
+		return nil
+	}
+
+	s.stats.driftWarnings.Add(1)
+
+	return fmt.Errorf("%w: clock is %s off the median of %d peers, at most %s allowed", ErrClockDrift, drift, peers, s.maxDrift)
+}
+
 // KnownExternalPeers retrieves a copy of the known peer list without including this node.
 func (s *State) KnownExternalPeers() []peer.Peer {
 	return s.knownPeers.Copy(s.host)
 }
 
-// AddKnownPeer adds a new peer to the known peer list.
-func (s *State) AddKnownPeer(peer peer.Peer) bool {
-	return s.knownPeers.Add(peer)
+// PeerVersion returns the versions the peer reported in its status and
+// whether the peer has reported them.
+func (s *State) PeerVersion(p peer.Peer) (peer.PeerVersion, bool) {
+	return s.knownPeers.Reported(p)
+}
+
+// PeerQuality returns how well the peer has been responding to this node
+// and whether any request was made to it.
+func (s *State) PeerQuality(p peer.Peer) (peer.PeerQuality, bool) {
+	return s.knownPeers.Quality(p)
+}
+
+// RankPeers orders the peers from the one expected to serve blocks the
+// fastest, going by how they responded to this node so far.
+func (s *State) RankPeers(peers []peer.Peer) []peer.Peer {
+	return s.knownPeers.Rank(peers)
+}
+
+// AddKnownPeer adds a new peer this node learned about to the known peer
+// list. The peer isn't added when the outbound slots are full or the peer
+// isn't allowed.
+func (s *State) AddKnownPeer(p peer.Peer) bool {
+	if err := s.peerAccess.CheckHost(p.Host); err != nil {
+		s.evHandler("state: AddKnownPeer: %s", err)
+		return false
+	}
+
+	added, err := s.knownPeers.AddSlot(p, peer.Outbound)
+	if err != nil {
+		s.evHandler("state: AddKnownPeer: %s: %s", p.Host, err)
+	}
+
+	return added
+}
+
+// AddInboundPeer adds a peer that registered with this node to the known
+// peer list. The peer.ErrPeersFull error is returned when the inbound slots
+// are full and the peer.ErrPeerDenied error when the peer isn't allowed.
+func (s *State) AddInboundPeer(p peer.Peer) (bool, error) {
+	if err := s.peerAccess.CheckHost(p.Host); err != nil {
+		return false, err
+	}
+
+	return s.knownPeers.AddSlot(p, peer.Inbound)
+}
+
+// AlternatePeers returns known peers a peer turned away for lack of slots
+// can try instead. The peers are picked at random so the turned away peers
+// spread across the network.
+func (s *State) AlternatePeers(p peer.Peer) []peer.Peer {
+	var peers []peer.Peer
+	for _, known := range s.KnownExternalPeers() {
+		if known != p {
+			peers = append(peers, known)
+		}
+	}
+
+	rand.Shuffle(len(peers), func(i, j int) { peers[i], peers[j] = peers[j], peers[i] })
+	if len(peers) > maxAlternatePeers {
+		peers = peers[:maxAlternatePeers]
+	}
+
+	return peers
+}
+
+// CheckPeer returns the peer.ErrPeerDenied error when the peer that sent a
+// gossiped message, named by its host and the account it signed with,
+// isn't allowed to take part in the network.
+func (s *State) CheckPeer(host string, account string) error {
+	return s.peerAccess.Check(host, account)
 }
 
 // RemoveKnownPeer removes a peer from the known peer list.
 func (s *State) RemoveKnownPeer(peer peer.Peer) {
 	s.knownPeers.Remove(peer)
+	s.envelopes.Unbind(peer.Host)
+	s.drift.Forget(peer.Host)
+}
+
+// Webhooks returns a copy of the webhook subscriptions.
+func (s *State) Webhooks() []webhook.Subscription {
+	return s.webhooks.Copy()
+}
+
+// AddWebhook registers a subscription to be notified when matching
+// transactions are mined.
+func (s *State) AddWebhook(sub webhook.Subscription) (webhook.Subscription, error) {
+	return s.webhooks.Add(sub)
+}
+
+// RemoveWebhook removes the webhook subscription.
+func (s *State) RemoveWebhook(id string) error {
+	return s.webhooks.Remove(id)
 }
 
 // KnownPeers retrieves a copy of the full known peer list which
-// includes this node. Used by the PoA selection algorithm.
+// includes this node. Used by the PoA selection algorithm so peers that
+// aren't allowed and peers that only relay are left out.
 func (s *State) KnownPeers() []peer.Peer {
-	return s.knownPeers.Copy("")
+	peers := s.knownPeers.Copy("")
+
+	allowed := peers[:0]
+	for _, p := range peers {
+		if s.peerAccess.Enabled() && s.peerAccess.CheckHost(p.Host) != nil {
+			continue
+		}
+
+		switch {
+		case p.Match(s.host):
+			if !s.CanMine() {
+				continue
+			}
+		default:
+			if version, ok := s.knownPeers.Reported(p); ok && version.RelayOnly {
+				continue
+			}
+		}
+
+		allowed = append(allowed, p)
+	}
+
+	return allowed
 }
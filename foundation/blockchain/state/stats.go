@@ -0,0 +1,122 @@
+package state
+
+import (
+	"expvar"
+	"time"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+)
+
+// stats holds the blockchain internals published through expvar. Everything
+// inside of expvar is registered as a singleton so the "blockchain" name is
+// published once. The main chain of the process is published at the top and
+// every extra chain under "chains" by its name.
+var stats = struct {
+	vars   *expvar.Map
+	chains *expvar.Map
+}{
+	vars:   expvar.NewMap("blockchain"),
+	chains: new(expvar.Map).Init(),
+}
+
+// chainStats holds the internals of one chain published through expvar.
+type chainStats struct {
+	vars           *expvar.Map
+	miningDuration *expvar.String
+	blocksAccepted *expvar.Int
+	txsAdded       *expvar.Int
+	forksDetected  *expvar.Int
+	orphans        *expvar.Int
+	driftWarnings  *expvar.Int
+}
+
+// newChainStats constructs the counters for the chain with the specified
+// name, empty for the main chain.
+func newChainStats(chain string) *chainStats {
+	cs := chainStats{
+		vars:           stats.vars,
+		miningDuration: new(expvar.String),
+		blocksAccepted: new(expvar.Int),
+		txsAdded:       new(expvar.Int),
+		forksDetected:  new(expvar.Int),
+		orphans:        new(expvar.Int),
+		driftWarnings:  new(expvar.Int),
+	}
+
+	if chain != "" {
+		cs.vars = new(expvar.Map).Init()
+		stats.chains.Set(chain, cs.vars)
+		stats.vars.Set("chains", stats.chains)
+	}
+
+	return &cs
+}
+
+// sizer is implemented by storage that can report how much space it uses.
+type sizer interface {
+	Size() (int64, error)
+}
+
+// publishStats points the vars of the chain at this state. Values that are
+// cheap to read are computed when the vars are requested.
+func (s *State) publishStats() {
+	vars := s.stats.vars
+
+	vars.Set("latest_block_number", expvar.Func(func() any {
+		return s.db.LatestBlock().Header.Number
+	}))
+	vars.Set("latest_block_hash", expvar.Func(func() any {
+		return s.db.LatestBlock().Hash()
+	}))
+	vars.Set("mempool_length", expvar.Func(func() any {
+		return s.mempool.Count()
+	}))
+	vars.Set("peer_count", expvar.Func(func() any {
+		return len(s.KnownExternalPeers())
+	}))
+	vars.Set("consensus", expvar.Func(func() any {
+		return s.consensus
+	}))
+	vars.Set("last_mining_duration", s.stats.miningDuration)
+	vars.Set("storage_size", expvar.Func(func() any {
+		sz, ok := s.storage.(sizer)
+		if !ok {
+			return nil
+		}
+		size, err := sz.Size()
+		if err != nil {
+			return nil
+		}
+		return size
+	}))
+	vars.Set("peer_bandwidth", expvar.Func(func() any {
+		return s.bandwidth.Usage()
+	}))
+	vars.Set("block_propagation", expvar.Func(func() any {
+		return s.latency.Latencies()
+	}))
+	vars.Set("gossip_retries", expvar.Func(func() any {
+		return s.retries.pending()
+	}))
+	vars.Set("clock_drift_ms", expvar.Func(func() any {
+		drift, _ := s.drift.Estimate()
+		return drift.Milliseconds()
+	}))
+	vars.Set("clock_drift_warnings", s.stats.driftWarnings)
+	vars.Set("blocks_accepted", s.stats.blocksAccepted)
+	vars.Set("txs_added", s.stats.txsAdded)
+	vars.Set("forks_detected", s.stats.forksDetected)
+	vars.Set("orphans_recorded", s.stats.orphans)
+
+	// Count the state changes as they happen.
+	s.Subscribe(SubscriberFuncs{
+		BlockAccepted: func(database.Block) { s.stats.blocksAccepted.Add(1) },
+		TxAdded:       func(database.BlockTx) { s.stats.txsAdded.Add(1) },
+		ForkDetected:  func(database.Block) { s.stats.forksDetected.Add(1) },
+	})
+}
+
+// recordMiningDuration updates the duration of the last mining operation.
+func (s *State) recordMiningDuration(d time.Duration) {
+	s.stats.miningDuration.Set(d.String())
+}
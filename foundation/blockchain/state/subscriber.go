@@ -0,0 +1,219 @@
+package state
+
+import (
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+)
+
+// Subscriber interface represents the behavior required to be implemented by
+// any component that needs to react to changes in the state. The methods are
+// called synchronously while the state is being changed, so they must not
+// block or call back into the state to change it.
+type Subscriber interface {
+	OnBlockAccepted(block database.Block)
+	OnTxAdded(tx database.BlockTx)
+	OnForkDetected(block database.Block)
+	OnAccountsChanged(change AccountsChange)
+	OnMempoolChanged(change MempoolChange)
+}
+
+// AccountsChange represents the accounts changed by a block that was
+// accepted or rolled back. Caches holding these accounts are stale, every
+// other account is the same as before the block.
+type AccountsChange struct {
+	Block      uint64               `json:"block"`
+	Hash       string               `json:"hash"`
+	RolledBack bool                 `json:"rolled_back"`
+	Accounts   []database.AccountID `json:"accounts"`
+}
+
+// The set of changes to the mempool.
+const (
+	MempoolAdded    = "added"
+	MempoolReplaced = "replaced"
+	MempoolDropped  = "dropped"
+	MempoolMined    = "mined"
+)
+
+// MempoolChange represents a transaction that entered or left the mempool,
+// so a client can keep a live view of the mempool without fetching all of
+// it. A replaced transaction names the transaction it took the place of
+// and a mined transaction the block it was mined in.
+type MempoolChange struct {
+	Change   string             `json:"change"`
+	Hash     string             `json:"hash"`
+	Replaces string             `json:"replaces,omitempty"`
+	From     database.AccountID `json:"from"`
+	Nonce    uint64             `json:"nonce"`
+	Tip      uint64             `json:"tip"`
+	Block    uint64             `json:"block,omitempty"`
+	Reason   string             `json:"reason"`
+}
+
+// SubscriberFuncs implements the Subscriber interface with functions so a
+// component can subscribe to just the events it cares about. A nil function
+// ignores the event.
+type SubscriberFuncs struct {
+	BlockAccepted   func(block database.Block)
+	TxAdded         func(tx database.BlockTx)
+	ForkDetected    func(block database.Block)
+	AccountsChanged func(change AccountsChange)
+	MempoolChanged  func(change MempoolChange)
+}
+
+// OnBlockAccepted implements the Subscriber interface.
+func (sf SubscriberFuncs) OnBlockAccepted(block database.Block) {
+	if sf.BlockAccepted != nil {
+		sf.BlockAccepted(block)
+	}
+}
+
+// OnTxAdded implements the Subscriber interface.
+func (sf SubscriberFuncs) OnTxAdded(tx database.BlockTx) {
+	if sf.TxAdded != nil {
+		sf.TxAdded(tx)
+	}
+}
+
+// OnForkDetected implements the Subscriber interface.
+func (sf SubscriberFuncs) OnForkDetected(block database.Block) {
+	if sf.ForkDetected != nil {
+		sf.ForkDetected(block)
+	}
+}
+
+// OnAccountsChanged implements the Subscriber interface.
+func (sf SubscriberFuncs) OnAccountsChanged(change AccountsChange) {
+	if sf.AccountsChanged != nil {
+		sf.AccountsChanged(change)
+	}
+}
+
+// OnMempoolChanged implements the Subscriber interface.
+func (sf SubscriberFuncs) OnMempoolChanged(change MempoolChange) {
+	if sf.MempoolChanged != nil {
+		sf.MempoolChanged(change)
+	}
+}
+
+// =============================================================================
+
+// Subscribe registers the subscriber to be told about state changes. The
+// returned function removes the subscriber from the registry.
+func (s *State) Subscribe(sub Subscriber) (unsubscribe func()) {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+
+	id := s.nextSubID
+	s.nextSubID++
+	s.subs[id] = sub
+
+	return func() {
+		s.subMu.Lock()
+		defer s.subMu.Unlock()
+
+		delete(s.subs, id)
+	}
+}
+
+// copySubscribers returns the current subscribers so events can be
+// published without holding the registry lock.
+func (s *State) copySubscribers() []Subscriber {
+	s.subMu.RLock()
+	defer s.subMu.RUnlock()
+
+	subs := make([]Subscriber, 0, len(s.subs))
+	for _, sub := range s.subs {
+		subs = append(subs, sub)
+	}
+
+	return subs
+}
+
+// publishBlockAccepted tells every subscriber the block was added to the chain.
+func (s *State) publishBlockAccepted(block database.Block) {
+	for _, sub := range s.copySubscribers() {
+		sub.OnBlockAccepted(block)
+	}
+}
+
+// publishTxAdded tells every subscriber the transaction was added to the mempool.
+func (s *State) publishTxAdded(tx database.BlockTx) {
+	for _, sub := range s.copySubscribers() {
+		sub.OnTxAdded(tx)
+	}
+}
+
+// publishForkDetected tells every subscriber the block shows this node is
+// on the wrong side of a fork.
+func (s *State) publishForkDetected(block database.Block) {
+	for _, sub := range s.copySubscribers() {
+		sub.OnForkDetected(block)
+	}
+}
+
+// publishAccountsChanged tells every subscriber which accounts the block
+// changed when it was accepted or rolled back.
+func (s *State) publishAccountsChanged(block database.Block, accounts []database.AccountID, rolledBack bool) {
+	change := AccountsChange{
+		Block:      block.Header.Number,
+		Hash:       block.Hash(),
+		RolledBack: rolledBack,
+		Accounts:   accounts,
+	}
+
+	s.evHandler("viewer: accountsChanged: blk[%d]: rolledBack[%t]: accounts[%d]", change.Block, rolledBack, len(accounts))
+
+	for _, sub := range s.copySubscribers() {
+		sub.OnAccountsChanged(change)
+	}
+}
+
+// publishMempoolUpsert tells every subscriber the transaction was added to
+// the mempool for the specified reason, replacing the old transaction when
+// there was one with the same account and nonce.
+func (s *State) publishMempoolUpsert(tx database.BlockTx, old database.BlockTx, replaced bool, reason string) {
+	algorithm := s.Rules().HashAlgorithm
+
+	change := MempoolChange{
+		Change: MempoolAdded,
+		Hash:   tx.TxHash(algorithm),
+		From:   tx.FromID,
+		Nonce:  tx.Nonce,
+		Tip:    tx.Tip,
+		Reason: reason,
+	}
+	if replaced {
+		change.Change = MempoolReplaced
+		change.Replaces = old.TxHash(algorithm)
+	}
+
+	s.publishMempoolChanged(change)
+}
+
+// publishMempoolRemoved tells every subscriber the transaction left the
+// mempool because it was mined in the block or dropped for the reason.
+func (s *State) publishMempoolRemoved(tx database.BlockTx, block database.Block, mined bool, reason string) {
+	change := MempoolChange{
+		Change: MempoolDropped,
+		Hash:   tx.TxHash(s.db.HashAlgorithm(block.Header.Number)),
+		From:   tx.FromID,
+		Nonce:  tx.Nonce,
+		Tip:    tx.Tip,
+		Reason: reason,
+	}
+	if mined {
+		change.Change = MempoolMined
+		change.Block = block.Header.Number
+	}
+
+	s.publishMempoolChanged(change)
+}
+
+// publishMempoolChanged tells every subscriber about the change to the mempool.
+func (s *State) publishMempoolChanged(change MempoolChange) {
+	s.evHandler("viewer: mempoolChanged: %s: tx[%s]: from[%s]: nonce[%d]: reason[%s]", change.Change, change.Hash, change.From, change.Nonce, change.Reason)
+
+	for _, sub := range s.copySubscribers() {
+		sub.OnMempoolChanged(change)
+	}
+}
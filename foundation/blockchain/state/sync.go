@@ -0,0 +1,95 @@
+package state
+
+import (
+	"time"
+)
+
+// SyncProgress represents how far the node is through catching up with the
+// blocks its peers have.
+type SyncProgress struct {
+	Syncing       bool          // The node is behind the highest block a peer reported.
+	StartingBlock uint64        // Latest block when the sync started.
+	CurrentBlock  uint64        // Latest block now.
+	HighestBlock  uint64        // Highest block reported by a peer.
+	Remaining     time.Duration // Estimated from the pace so far, zero when unknown.
+}
+
+// syncState tracks the sync in progress.
+type syncState struct {
+	starting  uint64
+	highest   uint64
+	startedAt time.Time
+}
+
+// SyncProgress returns how far the node is through syncing with its peers.
+func (s *State) SyncProgress() SyncProgress {
+	current := s.LatestBlock().Header.Number
+
+	s.syncMu.RLock()
+	defer s.syncMu.RUnlock()
+
+	return s.syncProgress(current)
+}
+
+// =============================================================================
+
+// syncProgress works out the progress for the current block. The caller
+// must hold the sync lock.
+func (s *State) syncProgress(current uint64) SyncProgress {
+	progress := SyncProgress{
+		Syncing:       current < s.syncing.highest,
+		StartingBlock: s.syncing.starting,
+		CurrentBlock:  current,
+		HighestBlock:  s.syncing.highest,
+	}
+
+	if !progress.Syncing {
+		progress.StartingBlock = current
+		if progress.HighestBlock < current {
+			progress.HighestBlock = current
+		}
+		return progress
+	}
+
+	if synced := current - s.syncing.starting; synced > 0 {
+		elapsed := s.clock.Now().Sub(s.syncing.startedAt)
+		progress.Remaining = elapsed * time.Duration(s.syncing.highest-current) / time.Duration(synced)
+	}
+
+	return progress
+}
+
+// noteHighestBlock records the latest block reported by a peer. A sync
+// starts from the current block when the node falls behind.
+func (s *State) noteHighestBlock(number uint64) {
+	current := s.LatestBlock().Header.Number
+
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	if number <= s.syncing.highest || number <= current {
+		return
+	}
+
+	if current >= s.syncing.highest {
+		s.syncing.starting = current
+		s.syncing.startedAt = s.clock.Now()
+	}
+	s.syncing.highest = number
+
+	s.evHandler("state: sync: started: current[%d]: highest[%d]", current, number)
+}
+
+// syncedBlock reports the progress after a block from a peer is accepted.
+func (s *State) syncedBlock(number uint64) {
+	s.syncMu.RLock()
+	defer s.syncMu.RUnlock()
+
+	progress := s.syncProgress(number)
+	if progress.Syncing {
+		s.evHandler("state: sync: progress: current[%d]: highest[%d]: remaining[%s]", progress.CurrentBlock, progress.HighestBlock, progress.Remaining.Round(time.Second))
+		return
+	}
+
+	s.evHandler("state: sync: caught up: current[%d]", progress.CurrentBlock)
+}
@@ -1,6 +1,8 @@
 package state
 
 import (
+	"fmt"
+
 	"github.com/qcbit/blockchain/foundation/blockchain/database"
 )
 
@@ -16,17 +18,136 @@ func (s *State) UpsertWalletTransaction(signedTx database.SignedTx) error {
 		return err
 	}
 
+	if err := s.validateAdmission(signedTx.Tx); err != nil {
+		return err
+	}
+
 	const oneUnitOfGas = 1
-	tx := database.NewBlockTx(signedTx, s.genesis.GasPrice, oneUnitOfGas)
-	if err := s.mempool.Upsert(tx); err != nil {
+	gasPrice := s.Rules().GasPrice
+	tx := database.NewBlockTxAt(signedTx, gasPrice, oneUnitOfGas, s.clock.Now())
+	old, replaced, err := s.mempool.Upsert(tx)
+	if err != nil {
 		return err
 	}
+	s.seenTxs.Add(signedTx.TxHash(s.Rules().HashAlgorithm))
 
-	s.Worker.SignalStartMining()
+	s.publishTxAdded(tx)
+	s.publishMempoolUpsert(tx, old, replaced, "submitted by a wallet")
 
 	return nil
 }
 
+// The set of checks a transaction goes through before it's accepted.
+const (
+	TxCheckChainID   = "chain_id"
+	TxCheckSignature = "signature"
+	TxCheckAdmission = "admission"
+	TxCheckNonce     = "nonce"
+	TxCheckGas       = "gas"
+	TxCheckBalance   = "balance"
+)
+
+// TxCheck represents the outcome of one of the checks.
+type TxCheck struct {
+	Name  string
+	Error string // Empty when the check passed.
+}
+
+// Debit represents an amount taken from an account by a transaction.
+type Debit struct {
+	AccountID database.AccountID
+	Amount    uint64
+	Reason    string
+}
+
+// TxVerdict represents whether a transaction would be accepted and what
+// it costs if it's mined in the next block.
+type TxVerdict struct {
+	Valid     bool
+	Checks    []TxCheck
+	TxHash    string
+	NextNonce uint64 // Nonce the account's next transaction must use.
+	GasFee    uint64
+	Debits    []Debit
+}
+
+// ValidateTransaction runs every check a wallet transaction goes through
+// against the current state without adding it to the mempool. All the
+// checks are run so the verdict lists every problem with the transaction.
+func (s *State) ValidateTransaction(signedTx database.SignedTx) TxVerdict {
+	const oneUnitOfGas = 1
+	next := s.db.LatestBlock().Header.Number + 1
+	tx := database.NewBlockTxAt(signedTx, s.Rules().GasPrice, oneUnitOfGas, s.clock.Now())
+
+	confirmed, nextNonce := s.QueryNonce(tx.FromID)
+	verdict := TxVerdict{
+		Valid:     true,
+		TxHash:    signedTx.TxHash(s.db.HashAlgorithm(next)),
+		NextNonce: nextNonce,
+		GasFee:    tx.GasPrice * tx.GasUnits,
+	}
+
+	check := func(name string, err error) {
+		result := TxCheck{Name: name}
+		if err != nil {
+			result.Error = err.Error()
+			verdict.Valid = false
+		}
+		verdict.Checks = append(verdict.Checks, result)
+	}
+
+	var err error
+	if tx.ChainID != s.genesis.ChainID {
+		err = fmt.Errorf("invalid chain ID: got %d, expected %d", tx.ChainID, s.genesis.ChainID)
+	}
+	check(TxCheckChainID, err)
+
+	// The chain ID is checked on its own above.
+	check(TxCheckSignature, signedTx.Validate(signedTx.ChainID))
+
+	check(TxCheckAdmission, s.validateAdmission(tx.Tx))
+
+	// A transaction with a nonce already in the mempool replaces it.
+	err = nil
+	if tx.Nonce <= confirmed || tx.Nonce > nextNonce {
+		err = fmt.Errorf("invalid transaction nonce: got %d, expected %d", tx.Nonce, nextNonce)
+	}
+	check(TxCheckNonce, err)
+
+	// The sponsor of a sponsored transaction pays the gas fee in place of
+	// the sender.
+	payerID := tx.GasPayer()
+	spendable := func(accountID database.AccountID) uint64 {
+		account, _ := s.db.Query(accountID)
+		return account.Spendable(next)
+	}
+
+	err = nil
+	if available := spendable(payerID); available < verdict.GasFee {
+		err = fmt.Errorf("insufficient funds in %s for gas: spendable %d, needed %d", payerID, available, verdict.GasFee)
+	}
+	check(TxCheckGas, err)
+
+	needed := tx.Value + tx.Tip
+	if payerID == tx.FromID {
+		needed += verdict.GasFee
+	}
+
+	err = nil
+	if available := spendable(tx.FromID); available < needed {
+		err = fmt.Errorf("insufficient funds: spendable %d, needed %d", available, needed)
+	}
+	check(TxCheckBalance, err)
+
+	verdict.Debits = append(verdict.Debits, Debit{AccountID: tx.FromID, Amount: tx.Value, Reason: "value"})
+	if tx.Tip > 0 {
+		verdict.Debits = append(verdict.Debits, Debit{AccountID: tx.FromID, Amount: tx.Tip, Reason: "tip"})
+	}
+	verdict.Debits = append(verdict.Debits, Debit{AccountID: payerID, Amount: verdict.GasFee, Reason: "gas"})
+
+	return verdict
+}
+
 // UpsertNodeTransaction accepts a transaction from a node for inclusion.
 func (s *State) UpsertNodeTransaction(tx database.BlockTx) error {
 	// Check the signed transaction has a proper signature, the from matches
@@ -35,11 +156,45 @@ func (s *State) UpsertNodeTransaction(tx database.BlockTx) error {
 		return err
 	}
 
-	if err := s.mempool.Upsert(tx); err != nil {
+	if err := s.validateAdmission(tx.Tx); err != nil {
+		return err
+	}
+
+	old, replaced, err := s.mempool.Upsert(tx)
+	if err != nil {
 		return err
 	}
+	s.seenTxs.Add(tx.TxHash(s.Rules().HashAlgorithm))
 
-	s.Worker.SignalStartMining()
+	s.publishTxAdded(tx)
+	s.publishMempoolUpsert(tx, old, replaced, "shared by a node")
+
+	return nil
+}
+
+// validateAdmission rejects transactions from or sponsored by frozen
+// accounts, expired transactions and governance transactions without a
+// quorum of validator approvals.
+func (s *State) validateAdmission(tx database.Tx) error {
+	if s.IsReadReplica() {
+		return ErrReadReplica
+	}
+
+	if tx.Expired(s.db.LatestBlock().Header.Number + 1) {
+		return fmt.Errorf("transaction expired at block %d", tx.ValidUntilBlock)
+	}
+
+	if s.db.IsFrozen(tx.FromID) {
+		return fmt.Errorf("account %s is frozen", tx.FromID)
+	}
+
+	if tx.Sponsor != nil && s.db.IsFrozen(tx.Sponsor.AccountID) {
+		return fmt.Errorf("sponsor account %s is frozen", tx.Sponsor.AccountID)
+	}
+
+	if err := s.db.ValidateGovernance(tx); err != nil {
+		return fmt.Errorf("invalid governance transaction: %w", err)
+	}
 
 	return nil
 }
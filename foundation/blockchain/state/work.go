@@ -0,0 +1,113 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+)
+
+// CORE NOTE: An external miner doesn't need the transactions to solve the
+// hash puzzle, only the block header. The node builds the block and hands
+// out the header as work. When the miner finds a nonce that solves the
+// header, it submits the nonce back and the node finishes the block.
+
+// maxWork is the number of outstanding pieces of work the node remembers.
+const maxWork = 32
+
+// Work represents a block header an external miner needs to solve.
+type Work struct {
+	ID            string               `json:"id"`
+	HashAlgorithm string               `json:"hash_algorithm"`
+	Header        database.BlockHeader `json:"header"`
+}
+
+// workStore keeps the blocks handed out as work by ID.
+type workStore struct {
+	blocks map[string]database.Block
+	order  []string
+}
+
+// MiningWork returns the work for the block this node would mine right now.
+// The same work is returned until a new block is accepted or the selected
+// transactions change so miners don't restart for no reason.
+func (s *State) MiningWork() (Work, error) {
+	cand, err := s.MiningCandidate()
+	if err != nil {
+		return Work{}, err
+	}
+	block := cand.Block
+
+	s.workMu.Lock()
+	defer s.workMu.Unlock()
+
+	for id, b := range s.work.blocks {
+		if b.Header.PrevBlockHash == block.Header.PrevBlockHash && b.Header.TransRoot == block.Header.TransRoot {
+			return s.newWork(id, b), nil
+		}
+	}
+
+	id := uuid.NewString()
+	s.work.blocks[id] = block
+	s.work.order = append(s.work.order, id)
+
+	if len(s.work.order) > maxWork {
+		delete(s.work.blocks, s.work.order[0])
+		s.work.order = s.work.order[1:]
+	}
+
+	return s.newWork(id, block), nil
+}
+
+// SubmitWork takes the nonce an external miner found for the work, and if
+// it solves the block, adds the block to the chain and proposes it to peers.
+func (s *State) SubmitWork(id string, nonce uint64) (database.Block, error) {
+	s.workMu.Lock()
+	block, exists := s.work.blocks[id]
+	s.workMu.Unlock()
+
+	if !exists {
+		return database.Block{}, errors.New("work does not exist or is stale")
+	}
+
+	block.Header.Nonce = nonce
+	if !block.IsSolved() {
+		return database.Block{}, fmt.Errorf("nonce %d does not solve the block", nonce)
+	}
+
+	s.evHandler("state: SubmitWork: MINING: SOLVED: work[%s]: newBlk[%s]", id, block.Hash())
+
+	// This node stops mining the same block when the worker
+	// is told the block was accepted.
+	if err := s.validateUpdateDatabase(block); err != nil {
+		return database.Block{}, err
+	}
+
+	if err := s.NetSendBlockToPeers(block); err != nil {
+		s.evHandler("state: SubmitWork: proposeBlockToPeers: WARNING: %s", err)
+	}
+
+	return block, nil
+}
+
+// newWork constructs the work for the block.
+func (s *State) newWork(id string, block database.Block) Work {
+	return Work{
+		ID:            id,
+		HashAlgorithm: s.db.HashAlgorithm(block.Header.Number),
+		Header:        block.Header,
+	}
+}
+
+// resetWork forgets all outstanding work since it was built on top of a
+// block that is no longer the latest.
+func (s *State) resetWork() {
+	s.workMu.Lock()
+	defer s.workMu.Unlock()
+
+	s.work = workStore{
+		blocks: make(map[string]database.Block),
+	}
+}
@@ -0,0 +1,208 @@
+package disk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+)
+
+// CORE NOTE: A batch stages its files in a folder of its own inside the
+// database path. Committing journals the staged files in the write-ahead
+// log and then renames each one into place, see wal.go. Renames are atomic
+// so a reader sees the old file or the new one.
+
+// batchPrefix starts the name of the folders batches stage their files in.
+const batchPrefix = "batch-"
+
+// NewBatch starts a batch of writes that are committed together.
+func (d *Disk) NewBatch() (database.Batch, error) {
+	dir, err := os.MkdirTemp(d.dbPath, batchPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return &diskBatch{disk: d, dir: dir}, nil
+}
+
+// Record returns the value stored under the key in the bucket.
+func (d *Disk) Record(bucket string, key string) ([]byte, error) {
+	if err := checkName(bucket); err != nil {
+		return nil, err
+	}
+	if err := checkName(key); err != nil {
+		return nil, err
+	}
+
+	return os.ReadFile(path.Join(d.dbPath, bucket, key+".json"))
+}
+
+// checkName makes sure a bucket or key can be used as a file name inside
+// the database path.
+func checkName(name string) error {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("invalid name %q", name)
+	}
+
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+// diskBatch represents the files staged by a batch. This implements the
+// database Batch interface.
+type diskBatch struct {
+	disk  *Disk
+	dir   string
+	files []string // Paths of the staged files relative to the database path.
+}
+
+// Write stages the block to be written to the file labeled with the block
+// number and the latest block pointer to be moved to it.
+func (b *diskBatch) Write(blockData database.BlockData) error {
+	data, err := json.MarshalIndent(blockData, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := b.stage(strconv.FormatUint(blockData.Header.Number, 10)+".json", data); err != nil {
+		return err
+	}
+
+	pointer, err := json.Marshal(latest{Number: blockData.Header.Number, Hash: blockData.Hash})
+	if err != nil {
+		return err
+	}
+
+	return b.stage(latestFile, pointer)
+}
+
+// Put stages the value to be written under the key in the bucket.
+func (b *diskBatch) Put(bucket string, key string, value []byte) error {
+	if err := checkName(bucket); err != nil {
+		return err
+	}
+	if err := checkName(key); err != nil {
+		return err
+	}
+
+	return b.stage(path.Join(bucket, key+".json"), value)
+}
+
+// Commit journals the staged files in the write-ahead log and moves them
+// into place. The latest block pointer is moved last so it never points at
+// a block whose records aren't in place.
+func (b *diskBatch) Commit() error {
+	sort.SliceStable(b.files, func(i, j int) bool {
+		return b.files[j] == latestFile && b.files[i] != latestFile
+	})
+
+	record := walRecord{Op: walCommit, Batch: path.Base(b.dir), Files: b.files}
+	if err := b.disk.appendWAL(record); err != nil {
+		b.Rollback()
+		return err
+	}
+
+	if err := b.apply(b.files); err != nil {
+		return err
+	}
+
+	return b.disk.appendWAL(walRecord{Op: walDone, Batch: record.Batch})
+}
+
+// Rollback throws away the staged files.
+func (b *diskBatch) Rollback() error {
+	return os.RemoveAll(b.dir)
+}
+
+// stage writes the file to the batch folder under a flattened name.
+// Staging the same file again replaces it.
+func (b *diskBatch) stage(name string, data []byte) error {
+	if err := writeFileSync(path.Join(b.dir, stagedName(name)), data); err != nil {
+		return err
+	}
+
+	for _, staged := range b.files {
+		if staged == name {
+			return nil
+		}
+	}
+	b.files = append(b.files, name)
+
+	return nil
+}
+
+// apply moves the staged files journaled for the batch into place and
+// removes the batch folder. Files already moved by an earlier attempt
+// are skipped. The folders the files were moved to are flushed so the
+// renames are on disk before the commit is marked done.
+func (b *diskBatch) apply(files []string) error {
+	dirs := make(map[string]struct{})
+	for _, name := range files {
+		target := path.Join(b.disk.dbPath, name)
+		if err := os.MkdirAll(path.Dir(target), 0755); err != nil {
+			return err
+		}
+
+		err := os.Rename(path.Join(b.dir, stagedName(name)), target)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		dirs[path.Dir(target)] = struct{}{}
+	}
+
+	for dir := range dirs {
+		if err := syncDir(dir); err != nil {
+			return err
+		}
+	}
+
+	return os.RemoveAll(b.dir)
+}
+
+// stagedName flattens the path of a file so it can be staged in the batch
+// folder.
+func stagedName(name string) string {
+	return strings.ReplaceAll(name, "/", "~")
+}
+
+// writeFileSync writes the file and flushes it to disk.
+func writeFileSync(name string, data []byte) error {
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// syncDir flushes the entries of the folder to disk.
+func syncDir(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,162 @@
+package disk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+)
+
+// CORE NOTE: Nodes on small disks can keep old blocks in an object store.
+// Compaction moves the block files past the blocks kept locally to the
+// store, leaving the hash and header of each block in the headers folder
+// so the chain can still be walked by header without the store. Reading an
+// offloaded block goes through to the store. A block is uploaded and its
+// header written before the local file is removed, so a crash in between
+// only means the block is uploaded again next time.
+
+// headersFolder is the folder inside the database path that holds the
+// headers of the blocks moved to the cold store.
+const headersFolder = "headers"
+
+// coldTimeout is how long a call to the cold store can take.
+const coldTimeout = 30 * time.Second
+
+// ColdStore interface represents the behavior required to be implemented
+// by an object store old blocks are moved to. Get must return an error
+// wrapping fs.ErrNotExist for a key that isn't stored.
+type ColdStore interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// WithColdStore is used to move the blocks past the ones kept locally to
+// the specified object store when storage is compacted.
+func WithColdStore(cold ColdStore) func(d *Disk) {
+	return func(d *Disk) {
+		d.cold = cold
+	}
+}
+
+// Header returns the hash and header of the specified block without its
+// transactions. Headers of offloaded blocks are read locally.
+func (d *Disk) Header(num uint64) (database.BlockData, error) {
+	data, err := os.ReadFile(d.headerPath(num))
+	if errors.Is(err, fs.ErrNotExist) {
+		blockData, err := d.GetBlock(num)
+		if err != nil {
+			return database.BlockData{}, err
+		}
+		blockData.Trans = nil
+		return blockData, nil
+	}
+	if err != nil {
+		return database.BlockData{}, err
+	}
+
+	var blockData database.BlockData
+	if err := json.Unmarshal(data, &blockData); err != nil {
+		return database.BlockData{}, err
+	}
+
+	return blockData, nil
+}
+
+// offload moves the block files up to and including the specified block to
+// the cold store and returns how many were moved.
+func (d *Disk) offload(through uint64) (int, error) {
+	if err := os.MkdirAll(path.Join(d.dbPath, headersFolder), 0755); err != nil {
+		return 0, err
+	}
+
+	var offloaded int
+	for num := uint64(1); num <= through; num++ {
+		data, err := os.ReadFile(d.getPath(num))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return offloaded, err
+		}
+
+		var blockData database.BlockData
+		if err := json.Unmarshal(data, &blockData); err != nil {
+			return offloaded, fmt.Errorf("reading block %d: %w", num, err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), coldTimeout)
+		err = d.cold.Put(ctx, coldKey(num), data)
+		cancel()
+		if err != nil {
+			return offloaded, fmt.Errorf("uploading block %d: %w", num, err)
+		}
+
+		header, err := json.Marshal(database.BlockData{Hash: blockData.Hash, Header: blockData.Header})
+		if err != nil {
+			return offloaded, err
+		}
+		if err := writeFileSync(d.headerPath(num), header); err != nil {
+			return offloaded, err
+		}
+
+		if err := os.Remove(d.getPath(num)); err != nil {
+			return offloaded, err
+		}
+		offloaded++
+	}
+
+	return offloaded, nil
+}
+
+// getCold reads the specified block through from the cold store. The block
+// must match the header kept locally.
+func (d *Disk) getCold(num uint64) (database.BlockData, error) {
+	header, err := d.Header(num)
+	if err != nil {
+		return database.BlockData{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), coldTimeout)
+	defer cancel()
+
+	data, err := d.cold.Get(ctx, coldKey(num))
+	if err != nil {
+		return database.BlockData{}, fmt.Errorf("downloading block %d: %w", num, err)
+	}
+
+	var blockData database.BlockData
+	if err := json.Unmarshal(data, &blockData); err != nil {
+		return database.BlockData{}, fmt.Errorf("reading block %d: %w", num, err)
+	}
+
+	if blockData.Hash != header.Hash {
+		return database.BlockData{}, fmt.Errorf("block %d from the cold store has hash %s, exp %s", num, blockData.Hash, header.Hash)
+	}
+
+	return blockData, nil
+}
+
+// isOffloaded reports whether the specified block was moved to the cold
+// store.
+func (d *Disk) isOffloaded(num uint64) bool {
+	_, err := os.Stat(d.headerPath(num))
+	return err == nil
+}
+
+// headerPath forms the path to the header of the specified block.
+func (d *Disk) headerPath(num uint64) string {
+	return path.Join(d.dbPath, headersFolder, strconv.FormatUint(num, 10)+".json")
+}
+
+// coldKey forms the key the specified block is stored under in the cold
+// store.
+func coldKey(num uint64) string {
+	return "blocks/" + strconv.FormatUint(num, 10) + ".json"
+}
@@ -3,30 +3,65 @@
 package disk
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/fs"
 	"os"
 	"path"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/qcbit/blockchain/foundation/blockchain/database"
 )
 
+// orphansFolder is the folder inside the database path that holds the
+// blocks that lost to a competing block.
+const orphansFolder = "orphans"
+
+// snapshotFolder is the folder inside the database path that holds the
+// snapshot of the accounts a node can start from.
+const snapshotFolder = "snapshot"
+
+// latestFile is the file inside the database path that points at the
+// latest block of the chain.
+const latestFile = "latest.json"
+
+// latest represents the block the latest block pointer points at.
+type latest struct {
+	Number uint64 `json:"number"`
+	Hash   string `json:"hash"`
+}
+
 // Disk represents the serialization implementation for reading and storing blocks
 // in their own separate files on disk. This implements the database.Storage interface.
 type Disk struct {
 	dbPath string
+	mu     sync.Mutex // Serializes writes to the write-ahead log.
+	cold   ColdStore  // Old blocks are moved here when set.
 }
 
 // New constructs a Disk value for use.
-func New(dbPath string) (*Disk, error) {
+func New(dbPath string, options ...func(d *Disk)) (*Disk, error) {
 	if err := os.MkdirAll(dbPath, 0755); err != nil {
 		return nil, err
 	}
 
-	return &Disk{dbPath: dbPath}, nil
+	d := Disk{dbPath: dbPath}
+	for _, option := range options {
+		option(&d)
+	}
+
+	// Replay or roll back the batches left by a node that stopped while
+	// committing them.
+	if err := d.recover(); err != nil {
+		return nil, err
+	}
+
+	return &d, nil
 }
 
 // Close in this implemenation has nothing to do since a new file is
@@ -36,36 +71,32 @@ func (d *Disk) Close() error {
 }
 
 // Write takes the specified database blocks and stores
-// it on disk in a file labeled with the block number.
+// it on disk in a file labeled with the block number. The block is written
+// as a batch of one so a crash never leaves a partial block file.
 func (d *Disk) Write(blockData database.BlockData) error {
-
-	// Marshal the block for writing to disk in a more human readable format.
-	data, err := json.MarshalIndent(blockData, "", "  ")
+	batch, err := d.NewBatch()
 	if err != nil {
 		return err
 	}
 
-	// Create a new file for this block and name it based on the block number.
-	f, err := os.OpenFile(d.getPath(blockData.Header.Number), os.O_CREATE|os.O_RDWR, 0600)
-	if err != nil {
+	if err := batch.Write(blockData); err != nil {
+		batch.Rollback()
 		return err
 	}
-	defer d.Close()
 
-	// Write the new block to disk.
-	if _, err := f.Write(data); err != nil {
-		return err
-	}
-
-	return nil
+	return batch.Commit()
 }
 
 // GetBlock searches the blockchain on disk to locate and
-// return the contents of the specified block by number.
+// return the contents of the specified block by number. Blocks moved to
+// the cold store are read from there.
 func (d *Disk) GetBlock(num uint64) (database.BlockData, error) {
 	// Open the block file for the specified number.
 	f, err := os.OpenFile(d.getPath(num), os.O_RDONLY, 0600)
 	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) && d.cold != nil && d.isOffloaded(num) {
+			return d.getCold(num)
+		}
 		return database.BlockData{}, err
 	}
 	defer f.Close()
@@ -82,7 +113,40 @@ func (d *Disk) GetBlock(num uint64) (database.BlockData, error) {
 
 // ForEach returns an iterator to walk through all the blocks starting with block number 1.
 func (d *Disk) ForEach() database.Iterator {
-	return &diskIterator{storage: d}
+	return d.ForEachFrom(1)
+}
+
+// ForEachFrom returns an iterator to walk through the blocks starting with
+// the specified block number. The iterator stops at the block the latest
+// block pointer pointed at when it was created, so blocks written while
+// iterating and block files past the pointer are never read. Storage
+// written before the pointer existed is read until a block is missing.
+func (d *Disk) ForEachFrom(num uint64) database.Iterator {
+	if num == 0 {
+		num = 1
+	}
+
+	di := diskIterator{storage: d, current: num - 1}
+	if l, err := d.readLatest(); err == nil {
+		di.last = l.Number
+	}
+
+	return &di
+}
+
+// readLatest returns the block the latest block pointer points at.
+func (d *Disk) readLatest() (latest, error) {
+	data, err := os.ReadFile(path.Join(d.dbPath, latestFile))
+	if err != nil {
+		return latest{}, err
+	}
+
+	var l latest
+	if err := json.Unmarshal(data, &l); err != nil {
+		return latest{}, err
+	}
+
+	return l, nil
 }
 
 // Reset will clear out the blockchain on disk.
@@ -94,6 +158,295 @@ func (d *Disk) Reset() error {
 	return os.MkdirAll(d.dbPath, 0755)
 }
 
+// Usage returns the space used by the block files of the chain up to and
+// including the latest block and by the stale files the chain doesn't reach.
+// Blocks moved to the cold store are only counted.
+func (d *Disk) Usage(latestBlock uint64) (database.Usage, error) {
+	entries, err := os.ReadDir(d.dbPath)
+	if err != nil {
+		return database.Usage{}, err
+	}
+
+	var usage database.Usage
+	for _, entry := range entries {
+		if entry.IsDir() || isMeta(entry.Name()) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		if num, ok := blockNumber(entry.Name()); ok && num <= latestBlock {
+			usage.Blocks += info.Size()
+			usage.BlockFiles++
+		} else {
+			usage.Stale += info.Size()
+			usage.StaleFiles++
+		}
+	}
+	usage.Total = usage.Blocks + usage.Stale
+
+	headers, err := os.ReadDir(path.Join(d.dbPath, headersFolder))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return database.Usage{}, err
+	}
+	usage.Offloaded = len(headers)
+
+	return usage, nil
+}
+
+// Compact rewrites the block files older than the retained readable blocks
+// without indentation and removes the stale files. With a cold store, the
+// block files older than the retained local blocks are moved to it first.
+// The progress function
+// is called after each block file is looked at. New blocks must not be
+// written while the storage is being compacted.
+func (d *Disk) Compact(latestBlock uint64, retention database.Retention, progress func(done, total uint64)) (database.Compaction, error) {
+	before, err := d.Usage(latestBlock)
+	if err != nil {
+		return database.Compaction{}, err
+	}
+
+	result := database.Compaction{
+		Before:      before.Total,
+		LatestBlock: latestBlock,
+	}
+
+	if d.cold != nil && retention.LocalBlocks != 0 && latestBlock > retention.LocalBlocks {
+		offloaded, err := d.offload(latestBlock - retention.LocalBlocks)
+		result.Offloaded = offloaded
+		if err != nil {
+			return result, fmt.Errorf("offloading blocks: %w", err)
+		}
+	}
+
+	var total uint64
+	if latestBlock > retention.ReadableBlocks {
+		total = latestBlock - retention.ReadableBlocks
+	}
+
+	for num := uint64(1); num <= total; num++ {
+		if d.isOffloaded(num) {
+			continue
+		}
+
+		compacted, err := d.compactBlock(num)
+		if err != nil {
+			return result, fmt.Errorf("compacting block %d: %w", num, err)
+		}
+		if compacted {
+			result.Compacted++
+		}
+
+		if progress != nil {
+			progress(num, total)
+		}
+	}
+
+	entries, err := os.ReadDir(d.dbPath)
+	if err != nil {
+		return result, err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || isMeta(entry.Name()) {
+			continue
+		}
+
+		if num, ok := blockNumber(entry.Name()); ok && num <= latestBlock {
+			continue
+		}
+
+		if err := os.Remove(path.Join(d.dbPath, entry.Name())); err != nil {
+			return result, fmt.Errorf("pruning %s: %w", entry.Name(), err)
+		}
+		result.Pruned++
+	}
+
+	after, err := d.Usage(latestBlock)
+	if err != nil {
+		return result, err
+	}
+	result.After = after.Total
+	result.Reclaimed = result.Before - result.After
+
+	return result, nil
+}
+
+// Size returns the number of bytes used by the block files on disk.
+func (d *Disk) Size() (int64, error) {
+	entries, err := os.ReadDir(d.dbPath)
+	if err != nil {
+		return 0, err
+	}
+
+	var size int64
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		size += info.Size()
+	}
+
+	return size, nil
+}
+
+// WriteOrphan stores the orphan in the orphans folder in a file labeled
+// with the block number and hash so competing blocks don't overwrite
+// each other.
+func (d *Disk) WriteOrphan(orphan database.Orphan) error {
+	dir := path.Join(d.dbPath, orphansFolder)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(orphan, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%d-%s.json", orphan.Block.Header.Number, orphan.Block.Hash)
+	return os.WriteFile(path.Join(dir, name), data, 0600)
+}
+
+// Orphans returns the orphans stored in the orphans folder ordered by
+// block number.
+func (d *Disk) Orphans() ([]database.Orphan, error) {
+	entries, err := os.ReadDir(path.Join(d.dbPath, orphansFolder))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var orphans []database.Orphan
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		data, err := os.ReadFile(path.Join(d.dbPath, orphansFolder, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		var orphan database.Orphan
+		if err := json.Unmarshal(data, &orphan); err != nil {
+			return nil, fmt.Errorf("reading orphan %s: %w", entry.Name(), err)
+		}
+		orphans = append(orphans, orphan)
+	}
+
+	sort.SliceStable(orphans, func(i, j int) bool {
+		return orphans[i].Block.Header.Number < orphans[j].Block.Header.Number
+	})
+
+	return orphans, nil
+}
+
+// WriteSnapshot stores the snapshot in the snapshot folder replacing the
+// previous one. The file is replaced in a single rename so a node starting
+// up never reads a partial snapshot.
+func (d *Disk) WriteSnapshot(snapshot database.ChainSnapshot) error {
+	dir := path.Join(d.dbPath, snapshotFolder)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	snapshotPath := path.Join(dir, "latest.json")
+	tmp := snapshotPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, snapshotPath); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return nil
+}
+
+// ReadSnapshot returns the snapshot in the snapshot folder.
+func (d *Disk) ReadSnapshot() (database.ChainSnapshot, error) {
+	data, err := os.ReadFile(path.Join(d.dbPath, snapshotFolder, "latest.json"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return database.ChainSnapshot{}, database.ErrNoSnapshot
+		}
+		return database.ChainSnapshot{}, err
+	}
+
+	var snapshot database.ChainSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return database.ChainSnapshot{}, fmt.Errorf("reading snapshot: %w", err)
+	}
+
+	return snapshot, nil
+}
+
+// compactBlock rewrites the block file without indentation. The file is
+// replaced in a single rename so a reader never sees a partial block.
+func (d *Disk) compactBlock(num uint64) (bool, error) {
+	blockPath := d.getPath(num)
+
+	data, err := os.ReadFile(blockPath)
+	if err != nil {
+		return false, err
+	}
+
+	if !bytes.ContainsRune(data, '\n') {
+		return false, nil
+	}
+
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return false, err
+	}
+
+	tmp := blockPath + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0600); err != nil {
+		return false, err
+	}
+
+	if err := os.Rename(tmp, blockPath); err != nil {
+		os.Remove(tmp)
+		return false, err
+	}
+
+	return true, nil
+}
+
+// blockNumber returns the number of the block stored in the named file.
+func blockNumber(name string) (uint64, bool) {
+	base, found := strings.CutSuffix(name, ".json")
+	if !found {
+		return 0, false
+	}
+
+	num, err := strconv.ParseUint(base, 10, 64)
+	if err != nil || num == 0 {
+		return 0, false
+	}
+
+	return num, true
+}
+
+// isMeta reports whether the named file holds the bookkeeping of the
+// storage rather than a block.
+func isMeta(name string) bool {
+	return name == latestFile || name == walFile
+}
+
 // getPath forms the path to the specified block.
 func (d *Disk) getPath(blockNum uint64) string {
 	name := strconv.FormatUint(blockNum, 10)
@@ -107,6 +460,7 @@ func (d *Disk) getPath(blockNum uint64) string {
 type diskIterator struct {
 	storage *Disk  // Access to the storage API.
 	current uint64 // Current block number being iterated.
+	last    uint64 // Block the latest block pointer points at, 0 without one.
 	eoc     bool   // End of chain flag.
 }
 
@@ -117,12 +471,18 @@ func (di *diskIterator) Next() (database.BlockData, error) {
 	}
 
 	di.current++
+	if di.last != 0 && di.current > di.last {
+		di.eoc = true
+		return database.BlockData{}, nil
+	}
+
 	blockData, err := di.storage.GetBlock(di.current)
 	if errors.Is(err, fs.ErrNotExist) {
 		di.eoc = true
+		return database.BlockData{}, nil
 	}
 
-	return blockData, nil
+	return blockData, err
 }
 
 // Done returns the end of the chain flag.
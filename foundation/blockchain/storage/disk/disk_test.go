@@ -0,0 +1,410 @@
+package disk_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+	"github.com/qcbit/blockchain/foundation/blockchain/storage/disk"
+)
+
+func Test_Compact(t *testing.T) {
+	dir := t.TempDir()
+
+	storage, err := disk.New(dir)
+	if err != nil {
+		t.Fatalf("constructing storage: %v", err)
+	}
+
+	for num := uint64(1); num <= 3; num++ {
+		blockData := database.BlockData{
+			Hash:   "0x0",
+			Header: database.BlockHeader{Number: num, Difficulty: 1},
+		}
+		if err := storage.Write(blockData); err != nil {
+			t.Fatalf("writing block %d: %v", num, err)
+		}
+	}
+
+	// Files the chain doesn't reach.
+	for _, name := range []string{"5.json", "4.json.tmp"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0600); err != nil {
+			t.Fatalf("writing %s: %v", name, err)
+		}
+	}
+
+	usage, err := storage.Usage(3)
+	if err != nil {
+		t.Fatalf("reading usage: %v", err)
+	}
+	if usage.BlockFiles != 3 || usage.StaleFiles != 2 {
+		t.Fatalf("usage should have 3 block files and 2 stale files, got %d and %d", usage.BlockFiles, usage.StaleFiles)
+	}
+
+	var events []uint64
+	result, err := storage.Compact(3, database.Retention{ReadableBlocks: 1}, func(done, total uint64) {
+		events = append(events, done)
+	})
+	if err != nil {
+		t.Fatalf("compacting: %v", err)
+	}
+
+	if result.Compacted != 2 || result.Pruned != 2 {
+		t.Fatalf("should compact 2 blocks and prune 2 files, got %d and %d", result.Compacted, result.Pruned)
+	}
+	if result.Reclaimed <= 0 || result.After != result.Before-result.Reclaimed {
+		t.Fatalf("should reclaim space, got %+v", result)
+	}
+	if len(events) != 2 {
+		t.Fatalf("should report progress for 2 blocks, got %d", len(events))
+	}
+
+	for num := uint64(1); num <= 3; num++ {
+		blockData, err := storage.GetBlock(num)
+		if err != nil {
+			t.Fatalf("reading block %d after compaction: %v", num, err)
+		}
+		if blockData.Header.Number != num {
+			t.Fatalf("block %d should be intact, got %d", num, blockData.Header.Number)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "3.json"))
+	if err != nil {
+		t.Fatalf("reading latest block: %v", err)
+	}
+	if !bytes.ContainsRune(data, '\n') {
+		t.Fatal("latest block should be left readable")
+	}
+
+	usage, err = storage.Usage(3)
+	if err != nil {
+		t.Fatalf("reading usage: %v", err)
+	}
+	if usage.StaleFiles != 0 || usage.Total != result.After {
+		t.Fatalf("usage should match the compaction, got %+v", usage)
+	}
+}
+
+func Test_Orphans(t *testing.T) {
+	storage, err := disk.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("constructing storage: %v", err)
+	}
+
+	orphans, err := storage.Orphans()
+	if err != nil || len(orphans) != 0 {
+		t.Fatalf("storage should start without orphans, got %d: %v", len(orphans), err)
+	}
+
+	// Competing blocks at the same height are kept side by side.
+	for _, o := range []struct {
+		num  uint64
+		hash string
+	}{{2, "0xb"}, {1, "0xa"}, {2, "0xc"}} {
+		orphan := database.Orphan{
+			Block:      database.BlockData{Hash: o.hash, Header: database.BlockHeader{Number: o.num}},
+			WinnerHash: "0xf",
+		}
+		if err := storage.WriteOrphan(orphan); err != nil {
+			t.Fatalf("writing orphan %s: %v", o.hash, err)
+		}
+	}
+
+	orphans, err = storage.Orphans()
+	if err != nil {
+		t.Fatalf("reading orphans: %v", err)
+	}
+	if len(orphans) != 3 || orphans[0].Block.Hash != "0xa" || orphans[2].Block.Header.Number != 2 {
+		t.Fatalf("orphans should be ordered by block number, got %+v", orphans)
+	}
+
+	// Orphans don't count as stale files of the chain.
+	usage, err := storage.Usage(0)
+	if err != nil {
+		t.Fatalf("reading usage: %v", err)
+	}
+	if usage.StaleFiles != 0 {
+		t.Fatalf("orphans should not be stale files, got %d", usage.StaleFiles)
+	}
+}
+
+func Test_Batch(t *testing.T) {
+	dir := t.TempDir()
+
+	storage, err := disk.New(dir)
+	if err != nil {
+		t.Fatalf("constructing storage: %v", err)
+	}
+
+	blockData := database.BlockData{Hash: "0x1", Header: database.BlockHeader{Number: 1}}
+
+	// A rolled back batch leaves nothing behind.
+	batch, err := storage.NewBatch()
+	if err != nil {
+		t.Fatalf("starting batch: %v", err)
+	}
+	if err := batch.Write(blockData); err != nil {
+		t.Fatalf("writing block: %v", err)
+	}
+	if err := batch.Put(database.BucketReceipts, "0xabc", []byte(`{"status":"success"}`)); err != nil {
+		t.Fatalf("writing receipt: %v", err)
+	}
+	if _, err := storage.GetBlock(1); err == nil {
+		t.Fatal("block should not be readable before the batch is committed")
+	}
+	if err := batch.Rollback(); err != nil {
+		t.Fatalf("rolling back: %v", err)
+	}
+	if _, err := storage.GetBlock(1); err == nil {
+		t.Fatal("block should not be readable after the batch is rolled back")
+	}
+
+	// A committed batch writes every record.
+	batch, err = storage.NewBatch()
+	if err != nil {
+		t.Fatalf("starting batch: %v", err)
+	}
+	if err := batch.Put(database.BucketReceipts, "../escape", nil); err == nil {
+		t.Fatal("key should not be able to leave the bucket")
+	}
+	if err := batch.Write(blockData); err != nil {
+		t.Fatalf("writing block: %v", err)
+	}
+	if err := batch.Put(database.BucketReceipts, "0xabc", []byte(`{"status":"success"}`)); err != nil {
+		t.Fatalf("writing receipt: %v", err)
+	}
+	if err := batch.Commit(); err != nil {
+		t.Fatalf("committing: %v", err)
+	}
+
+	if got, err := storage.GetBlock(1); err != nil || got.Hash != "0x1" {
+		t.Fatalf("block should be readable after the batch is committed, got %+v: %v", got, err)
+	}
+	if data, err := storage.Record(database.BucketReceipts, "0xabc"); err != nil || string(data) != `{"status":"success"}` {
+		t.Fatalf("receipt should be readable after the batch is committed, got %s: %v", data, err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("reading database path: %v", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && entry.Name() != database.BucketReceipts {
+			t.Fatalf("batch folder %s should be removed", entry.Name())
+		}
+	}
+}
+
+func Test_BatchRecovery(t *testing.T) {
+	dir := t.TempDir()
+
+	// A batch that was journaled before the node stopped.
+	committed := filepath.Join(dir, "batch-1")
+	if err := os.MkdirAll(committed, 0755); err != nil {
+		t.Fatalf("making batch folder: %v", err)
+	}
+	files := map[string]string{
+		"2.json":          `{"hash":"0x2","block":{"number":2},"trans":[]}`,
+		"index~2.json":    `{}`,
+		"latest.json":     `{"number":2,"hash":"0x2"}`,
+		"receipts~x.json": `{}`, // Not journaled, thrown away.
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(committed, name), []byte(data), 0600); err != nil {
+			t.Fatalf("writing %s: %v", name, err)
+		}
+	}
+
+	// A batch that didn't reach the log and one whose record was torn.
+	for _, name := range []string{"batch-2", "batch-3"} {
+		pending := filepath.Join(dir, name)
+		if err := os.MkdirAll(pending, 0755); err != nil {
+			t.Fatalf("making batch folder: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(pending, "3.json"), []byte(`{}`), 0600); err != nil {
+			t.Fatalf("writing block: %v", err)
+		}
+	}
+
+	wal := `{"op":"commit","batch":"batch-0","files":["1.json"]}
+{"op":"done","batch":"batch-0"}
+{"op":"commit","batch":"batch-1","files":["2.json","index/2.json","latest.json"]}
+{"op":"commit","batch":"batch-3","fi`
+	if err := os.WriteFile(filepath.Join(dir, "wal.log"), []byte(wal), 0600); err != nil {
+		t.Fatalf("writing log: %v", err)
+	}
+
+	storage, err := disk.New(dir)
+	if err != nil {
+		t.Fatalf("constructing storage: %v", err)
+	}
+
+	if got, err := storage.GetBlock(2); err != nil || got.Hash != "0x2" {
+		t.Fatalf("journaled batch should be replayed, got %+v: %v", got, err)
+	}
+	if _, err := storage.Record(database.BucketIndex, "2"); err != nil {
+		t.Fatalf("journaled batch should write its index entry: %v", err)
+	}
+	if _, err := storage.Record(database.BucketReceipts, "x"); err == nil {
+		t.Fatal("file that wasn't journaled should be thrown away")
+	}
+	if _, err := storage.GetBlock(3); err == nil {
+		t.Fatal("batch that didn't reach the log should be rolled back")
+	}
+
+	for _, name := range []string{"batch-1", "batch-2", "batch-3"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Fatalf("batch folder %s should be removed", name)
+		}
+	}
+
+	if info, err := os.Stat(filepath.Join(dir, "wal.log")); err != nil || info.Size() != 0 {
+		t.Fatalf("log should be truncated after recovery: %v", err)
+	}
+}
+
+func Test_LatestPointer(t *testing.T) {
+	dir := t.TempDir()
+
+	storage, err := disk.New(dir)
+	if err != nil {
+		t.Fatalf("constructing storage: %v", err)
+	}
+
+	for num := uint64(1); num <= 3; num++ {
+		blockData := database.BlockData{Hash: fmt.Sprintf("0x%d", num), Header: database.BlockHeader{Number: num}}
+		if err := storage.Write(blockData); err != nil {
+			t.Fatalf("writing block %d: %v", num, err)
+		}
+	}
+
+	// Block 3 loses to a competing block 2, leaving a stale block file.
+	if err := storage.Write(database.BlockData{Hash: "0x2b", Header: database.BlockHeader{Number: 2}}); err != nil {
+		t.Fatalf("writing competing block: %v", err)
+	}
+
+	var hashes []string
+	iter := storage.ForEach()
+	for blockData, err := iter.Next(); !iter.Done(); blockData, err = iter.Next() {
+		if err != nil {
+			t.Fatalf("iterating: %v", err)
+		}
+		hashes = append(hashes, blockData.Hash)
+	}
+
+	if exp := []string{"0x1", "0x2b"}; fmt.Sprint(hashes) != fmt.Sprint(exp) {
+		t.Fatalf("iterating should stop at the latest block pointer, got %v, exp %v", hashes, exp)
+	}
+
+	iter = storage.ForEachFrom(2)
+	if blockData, err := iter.Next(); err != nil || blockData.Hash != "0x2b" {
+		t.Fatalf("iterating should start at the specified block, got %+v: %v", blockData, err)
+	}
+	if _, err := iter.Next(); err != nil || !iter.Done() {
+		t.Fatalf("iterating from a block should stop at the latest block pointer: %v", err)
+	}
+
+	usage, err := storage.Usage(2)
+	if err != nil {
+		t.Fatalf("usage: %v", err)
+	}
+	if usage.BlockFiles != 2 || usage.StaleFiles != 1 {
+		t.Fatalf("usage should only count block files, got %+v", usage)
+	}
+}
+
+func Test_ColdStore(t *testing.T) {
+	cold := memStore{objects: make(map[string][]byte)}
+
+	storage, err := disk.New(t.TempDir(), disk.WithColdStore(&cold))
+	if err != nil {
+		t.Fatalf("constructing storage: %v", err)
+	}
+
+	for num := uint64(1); num <= 5; num++ {
+		blockData := database.BlockData{
+			Hash:   fmt.Sprintf("0x%d", num),
+			Header: database.BlockHeader{Number: num},
+			Trans:  []database.BlockTx{{}},
+		}
+		if err := storage.Write(blockData); err != nil {
+			t.Fatalf("writing block %d: %v", num, err)
+		}
+	}
+
+	result, err := storage.Compact(5, database.Retention{LocalBlocks: 2}, nil)
+	if err != nil {
+		t.Fatalf("compacting: %v", err)
+	}
+	if result.Offloaded != 3 || len(cold.objects) != 3 {
+		t.Fatalf("blocks past the local ones should be offloaded, got %d with %d objects", result.Offloaded, len(cold.objects))
+	}
+
+	usage, err := storage.Usage(5)
+	if err != nil {
+		t.Fatalf("usage: %v", err)
+	}
+	if usage.BlockFiles != 2 || usage.Offloaded != 3 {
+		t.Fatalf("usage should count local and offloaded blocks apart, got %+v", usage)
+	}
+
+	// Headers are read locally and blocks through the cold store.
+	header, err := storage.Header(1)
+	if err != nil || header.Hash != "0x1" || header.Trans != nil {
+		t.Fatalf("header should be kept locally, got %+v: %v", header, err)
+	}
+
+	var hashes []string
+	iter := storage.ForEach()
+	for blockData, err := iter.Next(); !iter.Done(); blockData, err = iter.Next() {
+		if err != nil {
+			t.Fatalf("iterating: %v", err)
+		}
+		if len(blockData.Trans) != 1 {
+			t.Fatalf("block %d should be read with its transactions", blockData.Header.Number)
+		}
+		hashes = append(hashes, blockData.Hash)
+	}
+	if exp := []string{"0x1", "0x2", "0x3", "0x4", "0x5"}; fmt.Sprint(hashes) != fmt.Sprint(exp) {
+		t.Fatalf("chain should be read through the cold store, got %v, exp %v", hashes, exp)
+	}
+
+	// A block in the store that doesn't match its header is refused.
+	cold.objects["blocks/2.json"] = []byte(`{"hash":"0xbad","block":{"number":2}}`)
+	if _, err := storage.GetBlock(2); err == nil {
+		t.Fatal("block that doesn't match its header should be refused")
+	}
+}
+
+// memStore keeps objects in memory. This implements the disk.ColdStore
+// interface.
+type memStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (ms *memStore) Put(ctx context.Context, key string, data []byte) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.objects[key] = data
+	return nil
+}
+
+func (ms *memStore) Get(ctx context.Context, key string) ([]byte, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	data, exists := ms.objects[key]
+	if !exists {
+		return nil, fs.ErrNotExist
+	}
+	return data, nil
+}
@@ -0,0 +1,183 @@
+package disk
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+// CORE NOTE: The write-ahead log is the commit point of every batch. The
+// files of a batch are staged and flushed first, then a commit record
+// listing them is appended to the log and flushed, and only then are the
+// block, index and pointer files touched. Once the files are in place a
+// done record is appended. On startup every commit without a done record
+// is replayed, which is safe to repeat since renaming a file that was
+// already moved is skipped. A batch without a commit record, including
+// one whose record was torn by the crash, is rolled back.
+
+// walFile is the write-ahead log inside the database path.
+const walFile = "wal.log"
+
+// walLimit is the size the log can grow to before it's truncated once no
+// commit is in flight.
+const walLimit = 1 << 20
+
+// The set of operations recorded in the log.
+const (
+	walCommit = "commit"
+	walDone   = "done"
+)
+
+// walRecord represents an entry in the write-ahead log.
+type walRecord struct {
+	Op    string   `json:"op"`
+	Batch string   `json:"batch"`
+	Files []string `json:"files,omitempty"`
+}
+
+// appendWAL writes the record to the end of the log. Commit records are
+// flushed to disk before returning.
+func (d *Disk) appendWAL(record walRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	f, err := os.OpenFile(path.Join(d.dbPath, walFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+
+	if record.Op == walCommit {
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return err
+		}
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return d.checkpointWAL()
+}
+
+// checkpointWAL truncates the log once it's past the limit and every
+// commit in it is done. The caller must hold the lock.
+func (d *Disk) checkpointWAL() error {
+	walPath := path.Join(d.dbPath, walFile)
+
+	info, err := os.Stat(walPath)
+	if err != nil || info.Size() < walLimit {
+		return err
+	}
+
+	pending, err := readWAL(walPath)
+	if err != nil || len(pending) > 0 {
+		return err
+	}
+
+	return os.Truncate(walPath, 0)
+}
+
+// recover replays the commits in the log that weren't done when the node
+// stopped and rolls back the batches that never reached the log.
+func (d *Disk) recover() error {
+	walPath := path.Join(d.dbPath, walFile)
+
+	pending, err := readWAL(walPath)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", walFile, err)
+	}
+
+	for _, record := range pending {
+		b := diskBatch{disk: d, dir: path.Join(d.dbPath, record.Batch)}
+		if err := b.apply(record.Files); err != nil {
+			return fmt.Errorf("replaying batch %s: %w", record.Batch, err)
+		}
+	}
+
+	entries, err := os.ReadDir(d.dbPath)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), batchPrefix) {
+			continue
+		}
+
+		if err := os.RemoveAll(path.Join(d.dbPath, entry.Name())); err != nil {
+			return fmt.Errorf("rolling back batch %s: %w", entry.Name(), err)
+		}
+	}
+
+	// Every commit in the log is now done.
+	if err := os.Truncate(walPath, 0); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// readWAL returns the commit records in the log without a done record in
+// the order they were committed. A record torn by a crash can only be the
+// last one and is ignored since its batch never reached the commit point.
+func readWAL(walPath string) ([]walRecord, error) {
+	f, err := os.Open(walPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var order []string
+	commits := make(map[string]walRecord)
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, walLimit)
+	for scanner.Scan() {
+		var record walRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			break
+		}
+
+		switch record.Op {
+		case walCommit:
+			if _, exists := commits[record.Batch]; !exists {
+				order = append(order, record.Batch)
+			}
+			commits[record.Batch] = record
+
+		case walDone:
+			delete(commits, record.Batch)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var pending []walRecord
+	for _, batch := range order {
+		if record, exists := commits[batch]; exists {
+			pending = append(pending, record)
+		}
+	}
+
+	return pending, nil
+}
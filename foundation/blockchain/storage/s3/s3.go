@@ -0,0 +1,231 @@
+// Package s3 provides a client for an S3 compatible object store such as
+// AWS S3 or MinIO so old blocks can be kept off the local disk. Requests
+// are signed with AWS signature version 4 and objects are addressed by
+// path so any endpoint works without DNS for the bucket.
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxObjectSize is the largest object read back from the store.
+const maxObjectSize = 64 << 20
+
+// Config represents what's needed to reach the bucket.
+type Config struct {
+	Endpoint  string       // Base URL of the store such as https://s3.us-east-1.amazonaws.com.
+	Region    string       // Region the bucket is in, us-east-1 when empty.
+	Bucket    string       // Bucket the objects are kept in.
+	Prefix    string       // Added in front of every key so buckets can be shared.
+	AccessKey string       // Access key ID of the credentials.
+	SecretKey string       // Secret access key of the credentials.
+	Client    *http.Client // Used for the requests, the default client when nil.
+}
+
+// Store represents a bucket in an S3 compatible object store.
+type Store struct {
+	endpoint *url.URL
+	region   string
+	bucket   string
+	prefix   string
+	access   string
+	secret   string
+	client   *http.Client
+}
+
+// New constructs a store for the bucket in the config.
+func New(cfg Config) (*Store, error) {
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("parsing endpoint: %w", err)
+	}
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" || endpoint.Host == "" {
+		return nil, fmt.Errorf("endpoint %q must be an http or https URL", cfg.Endpoint)
+	}
+	if cfg.Bucket == "" {
+		return nil, errors.New("bucket is required")
+	}
+	if cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, errors.New("access key and secret key are required")
+	}
+
+	region := cfg.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	client := cfg.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	s := Store{
+		endpoint: endpoint,
+		region:   region,
+		bucket:   cfg.Bucket,
+		prefix:   cfg.Prefix,
+		access:   cfg.AccessKey,
+		secret:   cfg.SecretKey,
+		client:   client,
+	}
+
+	return &s, nil
+}
+
+// Put stores the data under the key replacing what was there.
+func (s *Store) Put(ctx context.Context, key string, data []byte) error {
+	resp, err := s.do(ctx, http.MethodPut, key, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+
+	return nil
+}
+
+// Get returns the data stored under the key. An error wrapping
+// fs.ErrNotExist is returned when there is nothing stored under the key.
+func (s *Store) Get(ctx context.Context, key string) ([]byte, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("object %s: %w", key, fs.ErrNotExist)
+	default:
+		return nil, responseError(resp)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, maxObjectSize))
+}
+
+// do signs and sends the request for the object under the key.
+func (s *Store) do(ctx context.Context, method string, key string, body []byte) (*http.Response, error) {
+	u := *s.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.bucket + "/" + s.prefix + key
+	u.RawPath = escapePath(u.Path)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+
+	s.sign(req, body)
+
+	return s.client.Do(req)
+}
+
+// sign adds the AWS signature version 4 headers to the request.
+func (s *Store) sign(req *http.Request, body []byte) {
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	payloadHash := hashHex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	// The headers are signed in sorted order by their lower case names.
+	headers := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           amzDate,
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := day + "/" + s.region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hashHex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secret), day)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.access, scope, signedHeaders, signature))
+}
+
+// responseError turns a response the store didn't succeed with into an
+// error carrying the start of the body, which holds the S3 error code.
+func responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("object store: %s: %s", resp.Status, bytes.TrimSpace(body))
+}
+
+// escapePath encodes every byte of the path outside the unreserved set the
+// way the signature expects, keeping the slashes between segments.
+func escapePath(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+
+	return b.String()
+}
+
+// hashHex returns the hex encoded SHA256 hash of the data.
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// hmacSHA256 returns the HMAC of the data using the key.
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
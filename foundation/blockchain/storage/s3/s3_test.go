@@ -0,0 +1,87 @@
+package s3_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/storage/s3"
+)
+
+func Test_Store(t *testing.T) {
+	var mu sync.Mutex
+	objects := make(map[string][]byte)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=access/") || !strings.Contains(auth, "/eu-west-1/s3/aws4_request") {
+			http.Error(w, "bad authorization "+auth, http.StatusForbidden)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		sum := sha256.Sum256(body)
+		if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) {
+			http.Error(w, "bad content hash", http.StatusBadRequest)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.Method {
+		case http.MethodPut:
+			objects[r.URL.EscapedPath()] = body
+		case http.MethodGet:
+			data, exists := objects[r.URL.EscapedPath()]
+			if !exists {
+				http.Error(w, "NoSuchKey", http.StatusNotFound)
+				return
+			}
+			w.Write(data)
+		}
+	}))
+	defer srv.Close()
+
+	if _, err := s3.New(s3.Config{Endpoint: "ftp://host", Bucket: "b", AccessKey: "a", SecretKey: "s"}); err == nil {
+		t.Fatal("endpoint that isn't http should be rejected")
+	}
+
+	store, err := s3.New(s3.Config{
+		Endpoint:  srv.URL,
+		Region:    "eu-west-1",
+		Bucket:    "chain",
+		Prefix:    "testnet/",
+		AccessKey: "access",
+		SecretKey: "secret",
+	})
+	if err != nil {
+		t.Fatalf("constructing store: %v", err)
+	}
+
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "blocks/1 copy.json", []byte(`{"hash":"0x1"}`)); err != nil {
+		t.Fatalf("putting object: %v", err)
+	}
+	if _, exists := objects["/chain/testnet/blocks/1%20copy.json"]; !exists {
+		t.Fatalf("object should be stored by path under the bucket and prefix, got %v", objects)
+	}
+
+	data, err := store.Get(ctx, "blocks/1 copy.json")
+	if err != nil || string(data) != `{"hash":"0x1"}` {
+		t.Fatalf("getting object, got %s: %v", data, err)
+	}
+
+	if _, err := store.Get(ctx, "blocks/2.json"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("missing object should be reported as not existing, got %v", err)
+	}
+}
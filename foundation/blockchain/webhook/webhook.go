@@ -0,0 +1,228 @@
+// Package webhook maintains the subscriptions of operators who want to be
+// notified when transactions they care about are mined, and delivers
+// those notifications.
+package webhook
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/clock"
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+	"github.com/qcbit/blockchain/foundation/blockchain/peer"
+)
+
+// The set of values that control delivery of a notification. The wait
+// between attempts doubles after every failed attempt.
+const (
+	maxAttempts    = 5
+	initialBackoff = time.Second
+	requestTimeout = 5 * time.Second
+)
+
+// Subscription represents a callback URL and the filter transactions must
+// match to be sent to it. An empty account matches every account.
+type Subscription struct {
+	ID       string             `json:"id"`
+	URL      string             `json:"url"`
+	Account  database.AccountID `json:"account"`
+	MinValue uint64             `json:"min_value"`
+}
+
+// Match reports whether the transaction passes the subscription's filter.
+func (sub Subscription) Match(tx database.BlockTx) bool {
+	if sub.Account != "" && tx.FromID != sub.Account && tx.ToID != sub.Account {
+		return false
+	}
+
+	return tx.Value >= sub.MinValue
+}
+
+// Notification represents the set of mined transactions that matched
+// a subscription in a block. It's delivered inside a signed envelope
+// so the receiver can verify which node sent it.
+type Notification struct {
+	SubscriptionID string             `json:"subscription_id"`
+	BlockNumber    uint64             `json:"block_number"`
+	BlockHash      string             `json:"block_hash"`
+	Trans          []database.BlockTx `json:"trans"`
+}
+
+// =============================================================================
+
+// Webhooks manages the subscriptions and the delivery of notifications.
+type Webhooks struct {
+	mu         sync.RWMutex
+	subs       map[string]Subscription
+	host       string
+	privateKey *ecdsa.PrivateKey
+	clock      clock.Clock
+	evHandler  func(v string, args ...any)
+	client     http.Client
+	wg         sync.WaitGroup
+	shut       chan struct{}
+	shutOnce   sync.Once
+}
+
+// New constructs a Webhooks value that signs notifications with the
+// node's private key and stamps them with the clock.
+func New(host string, privateKey *ecdsa.PrivateKey, clk clock.Clock, evHandler func(v string, args ...any)) *Webhooks {
+	return &Webhooks{
+		subs:       make(map[string]Subscription),
+		host:       host,
+		privateKey: privateKey,
+		clock:      clock.OrSystem(clk),
+		evHandler:  evHandler,
+		client:     http.Client{Timeout: requestTimeout},
+		shut:       make(chan struct{}),
+	}
+}
+
+// Shutdown stops any retries and waits for deliveries in flight to finish.
+// It's safe to call more than once.
+func (wh *Webhooks) Shutdown() {
+	wh.shutOnce.Do(func() { close(wh.shut) })
+	wh.wg.Wait()
+}
+
+// Add registers a new subscription and returns it with its assigned ID.
+func (wh *Webhooks) Add(sub Subscription) (Subscription, error) {
+	u, err := url.Parse(sub.URL)
+	if err != nil {
+		return Subscription{}, fmt.Errorf("invalid url: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Subscription{}, errors.New("url must be an absolute http or https url")
+	}
+
+	if sub.Account != "" && !sub.Account.IsAccountID() {
+		return Subscription{}, errors.New("invalid account")
+	}
+
+	sub.ID = uuid.NewString()
+
+	wh.mu.Lock()
+	defer wh.mu.Unlock()
+
+	wh.subs[sub.ID] = sub
+
+	return sub, nil
+}
+
+// Remove deletes the subscription with the specified ID.
+func (wh *Webhooks) Remove(id string) error {
+	wh.mu.Lock()
+	defer wh.mu.Unlock()
+
+	if _, exists := wh.subs[id]; !exists {
+		return errors.New("subscription does not exist")
+	}
+	delete(wh.subs, id)
+
+	return nil
+}
+
+// Copy returns a copy of the current subscriptions.
+func (wh *Webhooks) Copy() []Subscription {
+	wh.mu.RLock()
+	defer wh.mu.RUnlock()
+
+	subs := make([]Subscription, 0, len(wh.subs))
+	for _, sub := range wh.subs {
+		subs = append(subs, sub)
+	}
+
+	return subs
+}
+
+// Notify sends a notification to every subscription with transactions in
+// the mined block that match its filter. Delivery happens in the background.
+func (wh *Webhooks) Notify(block database.Block) {
+	trans := block.MerkleTree.Values()
+
+	for _, sub := range wh.Copy() {
+		n := Notification{
+			SubscriptionID: sub.ID,
+			BlockNumber:    block.Header.Number,
+			BlockHash:      block.Hash(),
+		}
+		for _, tx := range trans {
+			if sub.Match(tx) {
+				n.Trans = append(n.Trans, tx)
+			}
+		}
+
+		if len(n.Trans) == 0 {
+			continue
+		}
+
+		wh.wg.Add(1)
+		go func(sub Subscription) {
+			defer wh.wg.Done()
+			wh.deliver(sub, n)
+		}(sub)
+	}
+}
+
+// deliver posts the notification to the subscription's URL, retrying with
+// backoff until it's accepted, the attempts run out or shutdown is requested.
+func (wh *Webhooks) deliver(sub Subscription, n Notification) {
+	backoff := initialBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := wh.post(sub.URL, n)
+		if err == nil {
+			wh.evHandler("webhook: deliver: sub[%s]: blk[%d]: delivered: attempt[%d]", sub.ID, n.BlockNumber, attempt)
+			return
+		}
+
+		wh.evHandler("webhook: deliver: sub[%s]: blk[%d]: WARNING: attempt[%d]: %s", sub.ID, n.BlockNumber, attempt, err)
+
+		if attempt == maxAttempts {
+			wh.evHandler("webhook: deliver: sub[%s]: blk[%d]: ERROR: giving up", sub.ID, n.BlockNumber)
+			return
+		}
+
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-wh.shut:
+			return
+		}
+	}
+}
+
+// post signs the notification and sends it to the url. The envelope is
+// signed on every attempt so its timestamp is current.
+func (wh *Webhooks) post(url string, n Notification) error {
+	env, err := peer.NewEnvelope(wh.host, n, wh.privateKey, wh.clock.Now())
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+
+	resp, err := wh.client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	return nil
+}
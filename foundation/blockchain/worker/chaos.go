@@ -0,0 +1,131 @@
+package worker
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// CORE NOTE: Chaos mode exists to show the fork choice and orphan handling
+// hold up when the worker misbehaves. Mining signals arrive late, blocks and
+// transactions aren't always shared and the worker goroutines are killed and
+// restarted. Every decision is drawn from a seeded source so a run that
+// breaks the chain can be repeated. A node in a real network should never
+// run in chaos mode.
+
+// Chaos represents the faults injected into the worker.
+type Chaos struct {
+	Seed            int64
+	MaxSignalDelay  time.Duration // Most a mining signal is delayed.
+	GossipDropRate  float64       // Fraction of blocks and transactions not shared, from 0 to 1.
+	RestartInterval time.Duration // Average time between killing and restarting a goroutine.
+}
+
+// Enabled reports whether the chaos injects any faults.
+func (c Chaos) Enabled() bool {
+	return c.MaxSignalDelay > 0 || c.GossipDropRate > 0 || c.RestartInterval > 0
+}
+
+// WithChaos is used to inject faults into the worker for testing.
+func WithChaos(c Chaos) func(w *Worker) {
+	return func(w *Worker) {
+		if !c.Enabled() {
+			return
+		}
+
+		w.chaos = &chaos{
+			cfg: c,
+			rnd: rand.New(rand.NewSource(c.Seed)),
+		}
+	}
+}
+
+// =============================================================================
+
+// chaos draws the faults from the seeded source.
+type chaos struct {
+	cfg Chaos
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// signalDelay returns how long to hold back a mining signal.
+func (c *chaos) signalDelay() time.Duration {
+	if c == nil || c.cfg.MaxSignalDelay <= 0 {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return time.Duration(c.rnd.Int63n(int64(c.cfg.MaxSignalDelay)))
+}
+
+// dropGossip reports whether the next block or transaction shouldn't be
+// shared with the peers.
+func (c *chaos) dropGossip() bool {
+	if c == nil || c.cfg.GossipDropRate <= 0 {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.rnd.Float64() < c.cfg.GossipDropRate
+}
+
+// nextKill returns how long until a goroutine is killed. The time between
+// kills is random around the restart interval. A zero duration means the
+// goroutine is never killed.
+func (c *chaos) nextKill() time.Duration {
+	if c == nil || c.cfg.RestartInterval <= 0 {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return time.Duration(c.rnd.ExpFloat64() * float64(c.cfg.RestartInterval))
+}
+
+// =============================================================================
+
+// operation represents a worker goroutine. The goroutine returns once the
+// done channel is closed.
+type operation struct {
+	name string
+	run  func(done <-chan struct{})
+}
+
+// supervise runs the operation until shutdown. In chaos mode the operation
+// is killed and restarted at random times.
+func (w *Worker) supervise(op operation) {
+	for {
+		wait := w.chaos.nextKill()
+		if wait == 0 {
+			op.run(w.shut)
+			return
+		}
+
+		done := make(chan struct{})
+		finished := make(chan struct{})
+		go func() {
+			defer close(finished)
+			op.run(done)
+		}()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-w.shut:
+			timer.Stop()
+			close(done)
+			<-finished
+			return
+
+		case <-timer.C:
+			close(done)
+			<-finished
+			w.evHandler("worker: chaos: %s: goroutine killed after %v, restarting", op.name, wait.Round(time.Millisecond))
+		}
+	}
+}
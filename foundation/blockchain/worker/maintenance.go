@@ -0,0 +1,34 @@
+package worker
+
+import "time"
+
+// maintenanceOperations compacts storage on the configured interval.
+func (w *Worker) maintenanceOperations(done <-chan struct{}) {
+	w.evHandler("worker: maintenanceOperations: Goroutine started")
+	defer w.evHandler("worker: maintenanceOperations: Goroutine completed")
+
+	ticker := time.NewTicker(w.compactInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if !w.isShutdown() {
+				w.runCompactOperation()
+			}
+		case <-done:
+			w.evHandler("worker: maintenanceOperations: received shutdown signal")
+			return
+		}
+	}
+}
+
+// runCompactOperation reclaims space in storage.
+func (w *Worker) runCompactOperation() {
+	w.evHandler("worker: runCompactOperation: started")
+	defer w.evHandler("worker: runCompactOperation: completed")
+
+	if _, err := w.state.Compact(); err != nil {
+		w.evHandler("worker: runCompactOperation: ERROR: %s", err)
+	}
+}
@@ -1,6 +1,13 @@
 package worker
 
-import "github.com/qcbit/blockchain/foundation/blockchain/peer"
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/peer"
+	"github.com/qcbit/blockchain/foundation/blockchain/protocol"
+)
 
 // CORE NOTE: The p2p network is managed by this goroutine. There is
 // a single node that is considered the origin node. The defaults in
@@ -9,9 +16,14 @@ import "github.com/qcbit/blockchain/foundation/blockchain/peer"
 // peers on the network. The topology is all nodes having a connection
 // to all other nodes. If a node does not respond to a network call,
 // they are removed from the peer list until the next peer operation.
+// A seed DNS name can list more bootstrap peers so the origin node isn't
+// the only way into the network.
+
+// seedTimeout is how long resolving the seed DNS name can take.
+const seedTimeout = 5 * time.Second
 
 // peerOperations handles finding new peers.
-func (w *Worker) peerOperations() {
+func (w *Worker) peerOperations(done <-chan struct{}) {
 	w.evHandler("worker: peerOperations: Goroutine started")
 	defer w.evHandler("worker: peerOperations: Goroutine completed")
 
@@ -21,13 +33,26 @@ func (w *Worker) peerOperations() {
 	// Maybe handled by sync; therefore, duplication.
 	// w.runPeersOperation()
 
+	// DNS seeding is repeated so peers added to the records later are
+	// found. A nil channel is never ready when seeding is turned off.
+	var seedTicker <-chan time.Time
+	if w.seeder != nil && w.seedInterval > 0 {
+		ticker := time.NewTicker(w.seedInterval)
+		defer ticker.Stop()
+		seedTicker = ticker.C
+	}
+
 	for {
 		select {
 		case <-w.ticker.C:
 			if !w.isShutdown() {
 				w.runPeersOperation()
 			}
-		case <-w.shut:
+		case <-seedTicker:
+			if !w.isShutdown() {
+				w.seedPeers()
+			}
+		case <-done:
 			w.evHandler("worker: peerOperations: received shutdown signal")
 			return
 		}
@@ -45,6 +70,12 @@ func (w *Worker) runPeersOperation() {
 		if err != nil {
 			w.evHandler("worker: runPeersOperation: NetRequestPeerStatus: %s: ERROR: %s", peer.Host, err)
 
+			// Keep a peer on an incompatible version so it's reported
+			// with the version it speaks.
+			if errors.Is(err, protocol.ErrIncompatibleVersion) {
+				continue
+			}
+
 			// Since this peer is unavailable, remove it form the list.
 			w.state.RemoveKnownPeer(peer)
 
@@ -55,10 +86,40 @@ func (w *Worker) runPeersOperation() {
 		w.addNewPeers(status.KnownPeers)
 	}
 
+	// The statuses just received have refreshed the clock drift estimate.
+	w.checkClockDrift()
+
 	// Share with peers this node is available to participate in the network.
 	w.state.NetSendNodeAvailableToPeers()
 }
 
+// checkClockDrift warns when the node's clock is too far off the clocks of
+// its peers.
+func (w *Worker) checkClockDrift() {
+	if err := w.state.CheckClockDrift(); err != nil {
+		w.evHandler("worker: checkClockDrift: WARNING: %s", err)
+	}
+}
+
+// seedPeers resolves the seed DNS name and adds the peers it lists.
+func (w *Worker) seedPeers() {
+	if w.seeder == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), seedTimeout)
+	defer cancel()
+
+	peers, err := w.seeder.Peers(ctx)
+	if err != nil {
+		w.evHandler("worker: seedPeers: %s: ERROR: %s", w.seeder.Name(), err)
+		return
+	}
+
+	w.evHandler("worker: seedPeers: %s: found %d peers", w.seeder.Name(), len(peers))
+	w.addNewPeers(peers)
+}
+
 // addNewPeers takes the list of known peers and makes sure they are
 // included in the nodes list of known peers.
 func (w *Worker) addNewPeers(peers []peer.Peer) error {
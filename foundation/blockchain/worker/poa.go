@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/qcbit/blockchain/foundation/blockchain/clock"
 	"github.com/qcbit/blockchain/foundation/blockchain/state"
 )
 
@@ -16,20 +17,39 @@ import (
 // the beginning of each cycle the selection algorithm is executed which determines
 // if this node needs to mine the next block. If this node is not selected, it
 // waits for the next cycle to check the selection algorithm again.
+//
+// A selected node that is down would stall the network, so every node counts
+// the cycles that end with transactions waiting and no new block. After a
+// grace period the selection moves on to the next node in line, and to the
+// one after that if the fallback is down too. When the late proposer and the
+// fallback both mine the block, the competing blocks are settled on receipt
+// by the lower hash winning like any other pair of blocks at the same height.
 
 // cycleDuration sets the mining operation to happen every 5 seconds
 const secondsPerCycle = 5
 const cycleDuration = secondsPerCycle * time.Second
 
+// graceCycles is how many cycles the selected node can miss before the next
+// node in line is selected to mine the block.
+const graceCycles = 2
+
+// poaLiveness tracks whether the selected node is producing blocks.
+type poaLiveness struct {
+	latest  string // Hash of the latest block when the cycles started being counted.
+	waiting bool   // Transactions were waiting at the start of the last cycle.
+	missed  int    // Cycles that ended with transactions waiting and no new block.
+}
+
 // poaOperations handles mining.
-func (w *Worker) poaOperations() {
+func (w *Worker) poaOperations(done <-chan struct{}) {
 	w.evHandler("worker: poaOperations: Goroutine started")
 	defer w.evHandler("worker: poaOperations: Goroutine completed")
 
 	ticker := time.NewTicker(cycleDuration)
+	defer ticker.Stop()
 
 	// Start on a secondsPerCycle mark: e.g. MM.00, MM.05, MM.10, MM.15, etc.
-	resetTicker(ticker, secondsPerCycle*time.Second)
+	resetTicker(ticker, w.state.Clock(), secondsPerCycle*time.Second)
 
 	for {
 		select {
@@ -37,13 +57,13 @@ func (w *Worker) poaOperations() {
 			if !w.isShutdown() {
 				w.runPoaOperation()
 			}
-		case <-w.shut:
+		case <-done:
 			w.evHandler("worker: poaOperations: shutdown signal received")
 			return
 		}
 
 		// Reset the ticker for the next cycle.
-		resetTicker(ticker, 0)
+		resetTicker(ticker, w.state.Clock(), 0)
 	}
 }
 
@@ -54,9 +74,11 @@ func (w *Worker) runPoaOperation() {
 	w.evHandler("worker: runPoaOperation: started")
 	defer w.evHandler("worker: runPoaOperation: completed")
 
-	// Run the selection algorithm.
-	peer := w.selection()
-	w.evHandler("worker: runPoaOperation: SELECTED: %s", peer)
+	// Run the selection algorithm, falling back to the next node in line
+	// when the selected one hasn't been producing blocks.
+	round := w.proposerRound()
+	peer := w.selection(round)
+	w.evHandler("worker: runPoaOperation: SELECTED: %s: round[%d]", peer, round)
 
 	// If not selected, return and wait for the new block.
 	if peer != w.state.Host() {
@@ -77,8 +99,9 @@ func (w *Worker) runPoaOperation() {
 	default:
 	}
 
-	// Create a context so mining can be canceled.
-	ctx, cancel := context.WithCancel(context.Background())
+	// Create a context so mining can be canceled. This context is also
+	// canceled if the worker is forced to shut down.
+	ctx, cancel := context.WithCancel(w.ctx)
 	defer cancel()
 
 	// Can't return from this function until these goroutines are done.
@@ -106,9 +129,9 @@ func (w *Worker) runPoaOperation() {
 			wg.Done()
 		}()
 
-		t := time.Now()
+		t := w.state.Clock().Now()
 		block, err := w.state.MineNewBlock(ctx)
-		duration := time.Since(t)
+		duration := w.state.Clock().Now().Sub(t)
 
 		w.evHandler("worker: runPoaOperation: MINING: duration: %v", duration)
 
@@ -125,6 +148,10 @@ func (w *Worker) runPoaOperation() {
 		}
 
 		// The block is mined. Propose it to the network.
+		if w.chaos.dropGossip() {
+			w.evHandler("worker: runPoaOperation: MINING: chaos: block[%d] not proposed", block.Header.Number)
+			return
+		}
 		if err := w.state.NetSendBlockToPeers(block); err != nil {
 			w.evHandler("worker: runPoaOperation: MINING: proposeBlockToPeers: WARNING: %v", err)
 		}
@@ -134,14 +161,46 @@ func (w *Worker) runPoaOperation() {
 	wg.Wait()
 }
 
-// selection selects a peer to mine the next block.
-func (w *Worker) selection() string {
-	// Retrieve the known peers list which includes this node.
+// proposerRound returns how many nodes down the line the selection goes
+// since the selected node missed more than the grace cycles. It's zero
+// while blocks are being produced.
+func (w *Worker) proposerRound() int {
+	latest := w.state.LatestBlock().Hash()
+	waiting := w.state.MempoolLength() > 0
+
+	switch {
+	case latest != w.liveness.latest:
+		w.liveness = poaLiveness{latest: latest}
+	case w.liveness.waiting:
+		w.liveness.missed++
+	}
+	w.liveness.waiting = waiting
+
+	if w.liveness.missed < graceCycles {
+		return 0
+	}
+
+	round := w.liveness.missed - graceCycles + 1
+	w.evHandler("worker: proposerRound: WARNING: no block for %d cycles with transactions waiting: fallback round[%d]", w.liveness.missed, round)
+
+	return round
+}
+
+// selection selects a peer to mine the next block. The round moves the
+// selection to the nodes next in line after the one selected first.
+func (w *Worker) selection(round int) string {
+	// Retrieve the known peers list which includes this node. Peers that
+	// aren't allowed to propose blocks are left out.
 	peers := w.state.KnownPeers()
 
 	// Log info
 	w.evHandler("worker: selection: Host %s, known peers: %v", w.state.Host(), peers)
 
+	// No peer is allowed to propose, not even this node.
+	if len(peers) == 0 {
+		return ""
+	}
+
 	// Sort the current list of peers by host.
 	names := make([]string, len(peers))
 	for i, peer := range peers {
@@ -153,15 +212,17 @@ func (w *Worker) selection() string {
 	h := fnv.New32a()
 	h.Write([]byte(w.state.LatestBlock().Hash()))
 	integerHash := h.Sum32()
-	i := integerHash % uint32(len(names))
+	n := uint32(len(names))
+	i := (integerHash%n + uint32(round)%n) % n
 
 	// Return the name of the node selected.
 	return names[i]
 }
 
 // resetTicker ensures the next tick occurs on the described cadence.
-func resetTicker(ticker *time.Ticker, waitOnSecond time.Duration) {
-	nextTick := time.Now().Add(cycleDuration).Round(waitOnSecond)
-	diff := time.Until(nextTick)
+func resetTicker(ticker *time.Ticker, clk clock.Clock, waitOnSecond time.Duration) {
+	now := clk.Now()
+	nextTick := now.Add(cycleDuration).Round(waitOnSecond)
+	diff := nextTick.Sub(now)
 	ticker.Reset(diff)
 }
@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"sync"
-	"time"
 
 	"github.com/qcbit/blockchain/foundation/blockchain/state"
 )
@@ -15,7 +14,7 @@ import (
 // This operation can be canceled if a proposed block is received and is validated.
 
 // powOperations handles mining.
-func (w *Worker) powOperations() {
+func (w *Worker) powOperations(done <-chan struct{}) {
 	w.evHandler("worker: powOperations: goroutine started")
 	defer w.evHandler("worker: powOperations: goroutine completed")
 
@@ -25,7 +24,7 @@ func (w *Worker) powOperations() {
 			if !w.isShutdown() {
 				w.runPowOperation()
 			}
-		case <-w.shut:
+		case <-done:
 			w.evHandler("worker: powOperations: shutdown signal received")
 			return
 		}
@@ -60,8 +59,9 @@ func (w *Worker) runPowOperation() {
 	default:
 	}
 
-	// Create a context so mining can be canceled.
-	ctx, cancel := context.WithCancel(context.Background())
+	// Create a context so mining can be canceled. This context is also
+	// canceled if the worker is forced to shut down.
+	ctx, cancel := context.WithCancel(w.ctx)
 	defer cancel()
 
 	// Can't return from this function until these G's are complete.
@@ -89,9 +89,9 @@ func (w *Worker) runPowOperation() {
 			wg.Done()
 		}()
 
-		t := time.Now()
+		t := w.state.Clock().Now()
 		block, err := w.state.MineNewBlock(ctx)
-		duration := time.Since(t)
+		duration := w.state.Clock().Now().Sub(t)
 
 		w.evHandler("worker: runPowOperation: MINING: mining duration[%v]", duration)
 
@@ -108,6 +108,10 @@ func (w *Worker) runPowOperation() {
 		}
 		// BLOCK MINED. Propose the new block to the network.
 		// Log the error, but that's it.
+		if w.chaos.dropGossip() {
+			w.evHandler("worker: runPowOperation: MINING: chaos: block[%d] not proposed", block.Header.Number)
+			return
+		}
 		if err := w.state.NetSendBlockToPeers(block); err != nil {
 			w.evHandler("worker: runMiningOperation: MINING: proposeBlockToPeers: WARNING %s", err)
 		}
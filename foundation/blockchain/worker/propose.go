@@ -0,0 +1,70 @@
+package worker
+
+import (
+	"errors"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/database"
+)
+
+// CORE NOTE: Validating and writing a block proposed by a peer is performed
+// by this goroutine so a slow disk doesn't hold up the peer's request. The
+// request goroutine queues the block and the peer is told it was received.
+// Whether the block was accepted is reported as an event, and a rejected
+// block is reported back to the peer with the check it failed. By default
+// up to 32 blocks can be waiting before new proposals are turned away.
+
+// maxProposalRequests represents the default number of proposed blocks that
+// can be waiting to be processed before new proposals are turned away.
+const maxProposalRequests = 32
+
+// proposal represents a block proposed by a peer.
+type proposal struct {
+	host  string
+	block database.Block
+}
+
+//-----------------------------------------------------------------------------
+
+// proposalOperations handles processing the blocks proposed by peers.
+func (w *Worker) proposalOperations(done <-chan struct{}) {
+	w.evHandler("worker: proposalOperations: goroutine started")
+	defer w.evHandler("worker: proposalOperations: goroutine completed")
+
+	for {
+		select {
+		case p := <-w.proposals:
+			if !w.isShutdown() {
+				w.runProposalOperation(p)
+			}
+		case <-done:
+			w.evHandler("worker: proposalOperations: received shutdown signal")
+			return
+		}
+	}
+}
+
+// runProposalOperation validates the proposed block and adds it to the
+// blockchain when it's valid. The peer is told why a block was rejected
+// without holding up the next proposal.
+func (w *Worker) runProposalOperation(p proposal) {
+	blk := p.block.Header.Number
+
+	if err := w.state.ProcessProposedBlock(p.block); err != nil {
+		go w.state.NetSendBlockRejection(p.host, p.block, err)
+
+		var blockErr *database.BlockError
+		if errors.As(err, &blockErr) {
+			w.evHandler("worker: runProposalOperation: REJECTED: peer[%s]: blk[%d]: check[%s]: got[%s]: expected[%s]",
+				p.host, blk, blockErr.Check, blockErr.Got, blockErr.Expected)
+			return
+		}
+
+		w.evHandler("worker: runProposalOperation: REJECTED: peer[%s]: blk[%d]: %s", p.host, blk, err)
+		return
+	}
+
+	// Track how long blocks from the peer take to reach this node.
+	w.state.RecordPropagation(p.host, p.block)
+
+	w.evHandler("worker: runProposalOperation: ACCEPTED: peer[%s]: blk[%d]: hash[%s]", p.host, blk, p.block.Hash())
+}
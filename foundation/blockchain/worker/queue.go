@@ -0,0 +1,145 @@
+package worker
+
+import (
+	"expvar"
+	"fmt"
+	"time"
+)
+
+// CORE NOTE: The worker goroutines are signaled over buffered channels. A
+// mining signal only needs to be pending once, so a full mining channel
+// already has the signal waiting. A full channel of transactions to share or
+// blocks to process means the data is lost unless the sender waits, so the
+// policy decides whether to drop it right away or wait for room up to a
+// timeout. The depth of every channel and the signals dropped are published
+// through expvar so a node falling behind stands out.
+
+// The set of policies for a full queue.
+const (
+	QueueDrop  = "drop"
+	QueueBlock = "block"
+)
+
+// Queues represents the sizes of the worker channels and what happens when
+// one of them is full.
+type Queues struct {
+	StartMining  int
+	CancelMining int
+	TxShare      int
+	Proposals    int
+	Policy       string        // drop or block
+	BlockTimeout time.Duration // Most a sender waits for room with the block policy.
+}
+
+// defaultQueues are the sizes and policy used when none are configured.
+var defaultQueues = Queues{
+	StartMining:  1,
+	CancelMining: 1,
+	TxShare:      maxTxShareRequests,
+	Proposals:    maxProposalRequests,
+	Policy:       QueueDrop,
+}
+
+// Validate checks the sizes can hold a signal and the policy is known.
+func (q Queues) Validate() error {
+	if q.StartMining < 1 || q.CancelMining < 1 || q.TxShare < 1 || q.Proposals < 1 {
+		return fmt.Errorf("queue sizes must be at least 1: %+v", q)
+	}
+
+	switch q.Policy {
+	case QueueDrop:
+	case QueueBlock:
+		if q.BlockTimeout <= 0 {
+			return fmt.Errorf("block policy needs a timeout, got %v", q.BlockTimeout)
+		}
+	default:
+		return fmt.Errorf("unknown queue policy %q", q.Policy)
+	}
+
+	return nil
+}
+
+// WithQueues is used to change the sizes of the worker channels and what
+// happens when one of them is full. Invalid queues are ignored and the
+// defaults used.
+func WithQueues(q Queues) func(w *Worker) {
+	return func(w *Worker) {
+		if err := q.Validate(); err != nil {
+			w.evHandler("worker: WithQueues: WARNING: using the default queues: %s", err)
+			return
+		}
+
+		w.queues = q
+	}
+}
+
+// =============================================================================
+
+// queueStats holds the worker queues published through expvar. Everything
+// inside of expvar is registered as a singleton so the "worker" name is
+// published once. The worker of the main chain is published at the top and
+// the worker of every extra chain under "chains" by the chain's name.
+var queueStats = struct {
+	vars   *expvar.Map
+	chains *expvar.Map
+}{
+	vars:   expvar.NewMap("worker"),
+	chains: new(expvar.Map).Init(),
+}
+
+// publishQueues points the vars of the chain at this worker's channels.
+func (w *Worker) publishQueues() {
+	vars := queueStats.vars
+	if chain := w.state.Chain(); chain != "" {
+		vars = new(expvar.Map).Init()
+		queueStats.chains.Set(chain, vars)
+		queueStats.vars.Set("chains", queueStats.chains)
+	}
+
+	vars.Set("queue_depth", expvar.Func(func() any {
+		return map[string]int{
+			"start_mining":  len(w.startMining),
+			"cancel_mining": len(w.cancelMining),
+			"tx_share":      len(w.txSharing),
+			"proposals":     len(w.proposals),
+		}
+	}))
+	vars.Set("queue_capacity", expvar.Func(func() any {
+		return map[string]int{
+			"start_mining":  cap(w.startMining),
+			"cancel_mining": cap(w.cancelMining),
+			"tx_share":      cap(w.txSharing),
+			"proposals":     cap(w.proposals),
+		}
+	}))
+	vars.Set("queue_dropped", w.dropped)
+	vars.Set("queue_policy", expvar.Func(func() any {
+		return w.queues.Policy
+	}))
+}
+
+// enqueue sends the value on the channel following the queue policy and
+// reports whether it was sent. A value that isn't sent is counted as dropped
+// under the queue's name.
+func enqueue[T any](w *Worker, name string, ch chan T, v T) bool {
+	select {
+	case ch <- v:
+		return true
+	default:
+	}
+
+	if w.queues.Policy == QueueBlock {
+		timer := time.NewTimer(w.queues.BlockTimeout)
+		defer timer.Stop()
+
+		select {
+		case ch <- v:
+			return true
+		case <-timer.C:
+		case <-w.shut:
+		}
+	}
+
+	w.dropped.Add(name, 1)
+	return false
+}
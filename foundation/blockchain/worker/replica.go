@@ -0,0 +1,51 @@
+package worker
+
+import (
+	"context"
+	"time"
+)
+
+// The timings of a read replica tailing its primary node.
+const (
+	replicaWait       = 30 * time.Second // How long the primary holds a request for blocks.
+	replicaRetryDelay = 5 * time.Second  // How long to wait after the primary fails.
+)
+
+// replicaOperations tails the blocks accepted by the primary node.
+func (w *Worker) replicaOperations(done <-chan struct{}) {
+	w.evHandler("worker: replicaOperations: Goroutine started")
+	defer w.evHandler("worker: replicaOperations: Goroutine completed")
+
+	// Abandon a request held open by the primary on shutdown.
+	ctx, cancel := context.WithCancel(w.ctx)
+	defer cancel()
+	go func() {
+		<-done
+		cancel()
+	}()
+
+	primary := w.state.Primary()
+
+	for !isClosed(done) {
+		n, err := w.state.NetWaitPeerBlocks(ctx, primary, replicaWait)
+		if err != nil {
+			if isClosed(done) {
+				break
+			}
+
+			w.evHandler("worker: replicaOperations: NetWaitPeerBlocks: %s: ERROR: %s", primary.Host, err)
+
+			select {
+			case <-time.After(replicaRetryDelay):
+			case <-done:
+			}
+			continue
+		}
+
+		if n > 0 {
+			w.evHandler("worker: replicaOperations: %s: applied blocks[%d]: latest[%d]", primary.Host, n, w.state.LatestBlock().Header.Number)
+		}
+	}
+
+	w.evHandler("worker: replicaOperations: received shutdown signal")
+}
@@ -0,0 +1,32 @@
+package worker
+
+import "time"
+
+// CORE NOTE: Transactions and blocks that failed to reach a peer are sent
+// again by this goroutine. The state decides when each one is due, this
+// goroutine just checks often enough for the shortest backoff.
+
+// retryInterval represents how often the gossip waiting to be sent again
+// is checked.
+const retryInterval = time.Second
+
+// retryOperations handles sending gossip that failed to reach a peer again.
+func (w *Worker) retryOperations(done <-chan struct{}) {
+	w.evHandler("worker: retryOperations: goroutine started")
+	defer w.evHandler("worker: retryOperations: goroutine completed")
+
+	ticker := time.NewTicker(retryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if !w.isShutdown() {
+				w.state.NetRetryGossip()
+			}
+		case <-done:
+			w.evHandler("worker: retryOperations: received shutdown signal")
+			return
+		}
+	}
+}
@@ -3,30 +3,34 @@ package worker
 // CORE NOTE: Sharing new transactions received directly by a wallet is
 // performed by this goroutine. When a wallet transaction is received,
 // the request goroutine shares it with this goroutine to send it over the
-// p2p network. Up to 100 transactions can be pending to be sent before new
-// transactions are dropped and not sent.
+// p2p network. By default up to 100 transactions can be pending to be sent
+// before new transactions are dropped and not sent.
 
-// maxTxShareRequests represents the max number of pending tx network share
-// requests that can be outstanding before share requests are dropped. To keep
-// this simple, a buffered channel of this arbitrary number is being used. If
-// the channel does become full, requests for new transactions to be shared
-// will not be accepted.
+// maxTxShareRequests represents the default number of pending tx network
+// share requests that can be outstanding before share requests are dropped.
+// To keep this simple, a buffered channel is being used. If the channel does
+// become full, the queue policy decides whether the request waits for room
+// or is dropped.
 const maxTxShareRequests = 100
 
 //-----------------------------------------------------------------------------
 
 // shareTxOperations handles sharing new block transactions.
-func (w *Worker) shareTxOperations() {
+func (w *Worker) shareTxOperations(done <-chan struct{}) {
 	w.evHandler("worker: shareTxOperations: goroutine started")
 	defer w.evHandler("worker: shareTxOperations: goroutine completed")
 
 	for {
 		select {
 		case tx := <-w.txSharing:
+			if w.chaos.dropGossip() {
+				w.evHandler("worker: shareTxOperations: chaos: tx[%s] not shared", tx)
+				continue
+			}
 			if !w.isShutdown() {
 				w.state.NetSendTxToPeers(tx)
 			}
-		case <-w.shut:
+		case <-done:
 			w.evHandler("worker: shareTxOperations: received shutdown signal")
 			return
 		}
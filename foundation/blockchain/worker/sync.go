@@ -1,5 +1,12 @@
 package worker
 
+import (
+	"errors"
+
+	"github.com/qcbit/blockchain/foundation/blockchain/peer"
+	"github.com/qcbit/blockchain/foundation/blockchain/protocol"
+)
+
 // CORE NOTE: On startup or when reorganizing the blockchain, the node needs to be in
 // sync with the rest of the network. This includes the mempool and blockchain database.
 // This operation needs to finish before the node can participate in the network.
@@ -9,11 +16,22 @@ func (w *Worker) Sync() {
 	w.evHandler("worker: sync: started")
 	defer w.evHandler("worker: sync: completed")
 
+	// Find the bootstrap peers before asking anyone for their status.
+	w.seedPeers()
+
+	// The peers ahead of this node and the latest block they reported.
+	ahead := make(map[peer.Peer]uint64)
+
 	for _, peer := range w.state.KnownExternalPeers() {
 		// Retrieve the status of this peer.
 		peerStatus, err := w.state.NetRequestPeerStatus(peer)
 		if err != nil {
 			w.evHandler("worker: sync: queryPeerStatus: %s: ERROR: %s", peer.Host, err)
+
+			// Nothing the peer sends can be decoded.
+			if errors.Is(err, protocol.ErrIncompatibleVersion) {
+				continue
+			}
 		}
 
 		// Add new peers to this nodes list.
@@ -31,14 +49,46 @@ func (w *Worker) Sync() {
 
 		// If this peer has blocks we don't have, we need to add them.
 		if peerStatus.LatestBlockNumber > w.state.LatestBlock().Header.Number {
-			w.evHandler("worker: sync: retrievePeerBlocks: %s: latestBlockNumber[%d]", peer.Host, peerStatus.LatestBlockNumber)
-
-			if err := w.state.NetRequestPeerBlocks(peer); err != nil {
-				w.evHandler("worker: sync: retrievePeerBlocks: %s: ERROR: %s", peer.Host, err)
-			}
+			ahead[peer] = peerStatus.LatestBlockNumber
 		}
 	}
 
+	// The status requests measured how quickly each peer responds. The
+	// blocks are pulled from the fastest peer ahead of this node, falling
+	// back to the next one when a peer fails or has fewer blocks.
+	w.retrievePeerBlocks(ahead)
+
+	// Check the clock against the peers before taking part in mining.
+	w.checkClockDrift()
+
 	// Share with peers this node is available to participate in the network.
 	w.state.NetSendNodeAvailableToPeers()
 }
+
+// retrievePeerBlocks pulls the blocks this node doesn't have from the peers
+// ahead of it, best ranked first, until the node caught up with the highest
+// block reported.
+func (w *Worker) retrievePeerBlocks(ahead map[peer.Peer]uint64) {
+	if len(ahead) == 0 {
+		return
+	}
+
+	peers := make([]peer.Peer, 0, len(ahead))
+	for p := range ahead {
+		peers = append(peers, p)
+	}
+
+	for _, p := range w.state.RankPeers(peers) {
+		latest := w.state.LatestBlock().Header.Number
+		if ahead[p] <= latest {
+			continue
+		}
+
+		quality, _ := w.state.PeerQuality(p)
+		w.evHandler("worker: sync: retrievePeerBlocks: %s: latestBlockNumber[%d]: latency[%v]: failures[%d]", p.Host, ahead[p], quality.Latency, quality.Failures)
+
+		if err := w.state.NetRequestPeerBlocks(p); err != nil {
+			w.evHandler("worker: sync: retrievePeerBlocks: %s: ERROR: %s", p.Host, err)
+		}
+	}
+}
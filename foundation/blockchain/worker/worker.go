@@ -2,10 +2,13 @@
 package worker
 
 import (
+	"context"
+	"expvar"
 	"sync"
 	"time"
 
 	"github.com/qcbit/blockchain/foundation/blockchain/database"
+	"github.com/qcbit/blockchain/foundation/blockchain/peer"
 	"github.com/qcbit/blockchain/foundation/blockchain/state"
 )
 
@@ -13,48 +16,130 @@ import (
 // nodes and updating the blockchain on disk with missing blocks.
 const peerUpdateInterval = time.Second * 10
 
+// defaultShutdownTimeout represents the time the worker is given to complete
+// its goroutines on shutdown before any mining is forcefully canceled.
+const defaultShutdownTimeout = time.Second * 10
+
 // Worker manages the POW workflows for the blockchain.
 type Worker struct {
-	state        *state.State
-	wg           sync.WaitGroup
-	ticker       time.Ticker
-	shut         chan struct{}
-	startMining  chan bool
-	cancelMining chan bool
-	txSharing    chan database.BlockTx
-	evHandler    state.EventHandler
+	state           *state.State
+	wg              sync.WaitGroup
+	ticker          time.Ticker
+	shut            chan struct{}
+	startMining     chan bool
+	cancelMining    chan bool
+	txSharing       chan database.BlockTx
+	proposals       chan proposal
+	evHandler       state.EventHandler
+	shutdownTimeout time.Duration
+	compactInterval time.Duration      // Zero turns off the maintenance operations.
+	chaos           *chaos             // Faults injected for testing, nil when turned off.
+	seeder          *peer.Seeder       // Finds bootstrap peers from DNS, nil when turned off.
+	seedInterval    time.Duration      // Zero only seeds on startup.
+	queues          Queues             // Sizes of the channels and what happens when one is full.
+	dropped         *expvar.Map        // Signals dropped from a full channel by queue name.
+	liveness        poaLiveness        // Cycles the selected PoA proposer has missed.
+	ctx             context.Context    // Parent of all mining operations.
+	cancel          context.CancelFunc // Forcefully cancels all mining operations.
+}
+
+// WithShutdownTimeout is used to change the default time the worker is
+// given to shut down cleanly before mining is forcefully canceled.
+func WithShutdownTimeout(timeout time.Duration) func(w *Worker) {
+	return func(w *Worker) {
+		w.shutdownTimeout = timeout
+	}
+}
+
+// WithCompaction is used to compact storage on the specified interval.
+func WithCompaction(interval time.Duration) func(w *Worker) {
+	return func(w *Worker) {
+		w.compactInterval = interval
+	}
+}
+
+// WithSeeding is used to find bootstrap peers from DNS on startup and again
+// on the specified interval.
+func WithSeeding(seeder *peer.Seeder, interval time.Duration) func(w *Worker) {
+	return func(w *Worker) {
+		w.seeder = seeder
+		w.seedInterval = interval
+	}
 }
 
 // Run creates a worker, registers the worker with the state,
 // and starts all the background processes.
-func Run(st *state.State, evHandler state.EventHandler) {
+func Run(st *state.State, evHandler state.EventHandler, options ...func(w *Worker)) {
+	ctx, cancel := context.WithCancel(context.Background())
+
 	w := Worker{
-		state:        st,
-		ticker:       *time.NewTicker(peerUpdateInterval),
-		shut:         make(chan struct{}),
-		startMining:  make(chan bool, 1),
-		cancelMining: make(chan bool, 1),
-		txSharing:    make(chan database.BlockTx, maxTxShareRequests),
-		evHandler:    evHandler,
+		state:           st,
+		ticker:          *time.NewTicker(peerUpdateInterval),
+		shut:            make(chan struct{}),
+		evHandler:       evHandler,
+		shutdownTimeout: defaultShutdownTimeout,
+		queues:          defaultQueues,
+		dropped:         new(expvar.Map).Init(),
+		ctx:             ctx,
+		cancel:          cancel,
 	}
 
-	// Register the worker with the state.
-	st.Worker = &w
+	for _, option := range options {
+		option(&w)
+	}
 
-	// Update this node before starting any support goroutines.
-	w.Sync()
+	// The channels are sized once the options have been applied.
+	w.startMining = make(chan bool, w.queues.StartMining)
+	w.cancelMining = make(chan bool, w.queues.CancelMining)
+	w.txSharing = make(chan database.BlockTx, w.queues.TxShare)
+	w.proposals = make(chan proposal, w.queues.Proposals)
+	w.publishQueues()
+
+	// Register the worker with the state and subscribe to the
+	// state changes that start and stop mining.
+	st.Worker = &w
+	st.Subscribe(&w)
 
 	// Select the consensus operation to run.
-	consensusOperation := w.powOperations
+	consensusOperation := operation{"powOperations", w.powOperations}
 	if st.Consensus() == state.ConsensusPOA {
-		consensusOperation = w.poaOperations
+		consensusOperation = operation{"poaOperations", w.poaOperations}
 	}
 
-	// Load the set of operations to run.
-	operations := []func(){
-		w.peerOperations,
-		w.shareTxOperations,
-		consensusOperation,
+	// Load the set of operations to run. A read replica only follows its
+	// primary node and catches up as part of doing so.
+	var operations []operation
+	switch {
+	case st.IsReadReplica():
+		operations = []operation{
+			{"replicaOperations", w.replicaOperations},
+		}
+
+	default:
+		// Update this node before starting any support goroutines.
+		w.Sync()
+
+		operations = []operation{
+			{"peerOperations", w.peerOperations},
+			{"shareTxOperations", w.shareTxOperations},
+			{"proposalOperations", w.proposalOperations},
+			{"retryOperations", w.retryOperations},
+		}
+
+		// A node without a beneficiary only relays and validates blocks.
+		if st.CanMine() {
+			operations = append(operations, consensusOperation)
+		} else {
+			evHandler("worker: Run: no beneficiary: relaying only, %s not started", consensusOperation.name)
+		}
+	}
+	if w.compactInterval > 0 {
+		operations = append(operations, operation{"maintenanceOperations", w.maintenanceOperations})
+	}
+
+	if w.chaos != nil {
+		evHandler("worker: Run: WARNING: chaos mode: seed[%d]: signal-delay[%v]: gossip-drop[%v]: restart[%v]",
+			w.chaos.cfg.Seed, w.chaos.cfg.MaxSignalDelay, w.chaos.cfg.GossipDropRate, w.chaos.cfg.RestartInterval)
 	}
 
 	// Set the wait group to match the number of goroutines needed for the set of operations.
@@ -66,10 +151,10 @@ func Run(st *state.State, evHandler state.EventHandler) {
 
 	// Start the operations.
 	for _, op := range operations {
-		go func(op func()) {
+		go func(op operation) {
 			defer w.wg.Done()
 			hasStarted <- true
-			op()
+			w.supervise(op)
 		}(op)
 	}
 
@@ -80,9 +165,39 @@ func Run(st *state.State, evHandler state.EventHandler) {
 }
 
 //------------------------------------------------------------------------------
-// These methods implement the state.Worker interface.
+// These methods implement the state.Subscriber interface.
+
+// OnBlockAccepted cancels any mining in progress since the block being
+// mined is no longer built on the latest block.
+func (w *Worker) OnBlockAccepted(block database.Block) {
+	w.SignalCancelMining()
+}
 
-// Shutdown terminates the goroutine performing work.
+// OnTxAdded starts a mining operation for the new transaction.
+func (w *Worker) OnTxAdded(tx database.BlockTx) {
+	w.SignalStartMining()
+}
+
+// OnForkDetected reports the fork. Resyncing the chain is left to the
+// peer operations.
+func (w *Worker) OnForkDetected(block database.Block) {
+	w.evHandler("worker: OnForkDetected: WARNING: blk[%d]: chain forked", block.Header.Number)
+}
+
+// OnAccountsChanged is ignored since mining only cares about the blocks.
+func (w *Worker) OnAccountsChanged(change state.AccountsChange) {}
+
+// OnMempoolChanged is ignored since new transactions are signaled by
+// OnTxAdded.
+func (w *Worker) OnMempoolChanged(change state.MempoolChange) {}
+
+//------------------------------------------------------------------------------
+// These methods implement the state.Worker interface and signal the
+// worker goroutines.
+
+// Shutdown terminates the goroutine performing work. If the goroutines don't
+// complete before the shutdown timeout, any mining still in progress is
+// forcefully canceled and the partially mined block is discarded.
 func (w *Worker) Shutdown() {
 	w.evHandler("worker: shutdown: started")
 	defer w.evHandler("worker: shutdown: completed")
@@ -92,7 +207,25 @@ func (w *Worker) Shutdown() {
 
 	w.evHandler("worker: shutdown: terminate goroutine")
 	close(w.shut)
-	w.wg.Wait()
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		w.evHandler("worker: shutdown: CLEAN: goroutines completed")
+
+	case <-time.After(w.shutdownTimeout):
+		w.evHandler("worker: shutdown: DEADLINE: timeout[%v]: force cancel mining", w.shutdownTimeout)
+		w.cancel()
+		<-done
+		w.evHandler("worker: shutdown: UNCLEAN: goroutines completed after mining was forcefully canceled")
+	}
+
+	w.cancel()
 }
 
 // SignalStartMining starts a mining operation. If there is already a signal
@@ -107,6 +240,18 @@ func (w *Worker) SignalStartMining() {
 	// 	return
 	// }
 
+	// In chaos mode the signal arrives late.
+	if delay := w.chaos.signalDelay(); delay > 0 {
+		time.AfterFunc(delay, func() {
+			select {
+			case w.startMining <- true:
+			default:
+			}
+			w.evHandler("worker: SignalStartMining: chaos: mining signaled after %v", delay.Round(time.Millisecond))
+		})
+		return
+	}
+
 	select {
 	case w.startMining <- true:
 	default:
@@ -122,6 +267,18 @@ func (w *Worker) SignalCancelMining() {
 	// 	return
 	// }
 
+	// In chaos mode the signal arrives late. Shutdown can't wait on it.
+	if delay := w.chaos.signalDelay(); delay > 0 && !w.isShutdown() {
+		time.AfterFunc(delay, func() {
+			select {
+			case w.cancelMining <- true:
+			default:
+			}
+			w.evHandler("worker: SignalCancelMining: chaos: CANCEL: signaled after %v", delay.Round(time.Millisecond))
+		})
+		return
+	}
+
 	select {
 	case w.cancelMining <- true:
 	default:
@@ -129,22 +286,39 @@ func (w *Worker) SignalCancelMining() {
 	w.evHandler("worker: SignalCancelMining: CANCEL: signaled")
 }
 
-// SignalShareTx signals a share transaction operation. If maxTxShareRequests
-// signals exists in the channel, we won't send these.
+// SignalShareTx signals a share transaction operation. When the channel is
+// full the queue policy decides whether the transaction is dropped right away
+// or after waiting for room.
 func (w *Worker) SignalShareTx(blockTx database.BlockTx) {
-	select {
-	case w.txSharing <- blockTx:
-		w.evHandler("worker: SignalShareTx: share Tx signaled")
-	default:
-		w.evHandler("worker: SignalShareTx: queue full, transaction dropped and transactions won't be shared.")
+	if !enqueue(w, "tx_share", w.txSharing, blockTx) {
+		w.evHandler("worker: SignalShareTx: WARNING: queue full, tx[%s] dropped and won't be shared", blockTx)
+		return
 	}
+	w.evHandler("worker: SignalShareTx: share Tx signaled")
+}
+
+// SignalProposeBlock queues the block proposed by the peer to be processed.
+// False is returned when the channel is full and the queue policy gave up
+// on waiting for room.
+func (w *Worker) SignalProposeBlock(host string, block database.Block) bool {
+	if !enqueue(w, "proposals", w.proposals, proposal{host: host, block: block}) {
+		w.evHandler("worker: SignalProposeBlock: WARNING: peer[%s]: blk[%d]: queue full, proposal turned away", host, block.Header.Number)
+		return false
+	}
+	w.evHandler("worker: SignalProposeBlock: peer[%s]: blk[%d]: queued", host, block.Header.Number)
+	return true
 }
 
 // ------------------------------------------------------------------------------
 // isShutdown is used to test if a shutdown has been signaled.
 func (w *Worker) isShutdown() bool {
+	return isClosed(w.shut)
+}
+
+// isClosed is used to test if the channel has been closed.
+func isClosed(done <-chan struct{}) bool {
 	select {
-	case <-w.shut:
+	case <-done:
 		return true
 	default:
 		return false
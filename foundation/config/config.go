@@ -0,0 +1,283 @@
+// Package config loads settings from a YAML or TOML file as a source for the
+// conf package. The file sits underneath the environment and the flags, so
+// a value in the file is only used when neither of them sets it.
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ardanlabs/conf/v3"
+)
+
+// File is a conf.Parsers that loads the file named by the --config flag or
+// the PREFIX_CONFIG environment variable. The values in the file are handed
+// to conf as the environment variables it reads, for the ones that aren't
+// already set, so the defaults, the environment and the flags apply on top
+// the same way they do without a file.
+type File struct {
+	args []string
+}
+
+// NewFile constructs the source that finds the file in the command line
+// arguments, usually os.Args[1:].
+func NewFile(args []string) *File {
+	return &File{
+		args: args,
+	}
+}
+
+// Process implements the conf.Parsers interface. Keys the configuration
+// doesn't have and values that don't convert to the type of their key
+// are reported by key and line.
+func (f *File) Process(prefix string, cfg any) error {
+	path := Path(prefix, f.args)
+	if path == "" {
+		return nil
+	}
+
+	entries, err := Read(path)
+	if err != nil {
+		return err
+	}
+
+	fields, err := fieldsOf(prefix, cfg)
+	if err != nil {
+		return err
+	}
+
+	byKey := make(map[string]field, len(fields))
+	for _, fld := range fields {
+		byKey[fld.key] = fld
+	}
+
+	for _, entry := range entries {
+		fld, exists := byKey[entry.Key]
+		if !exists {
+			return fmt.Errorf("config file %s: line %d: unknown key %q", path, entry.Line, entry.Key)
+		}
+
+		if err := check(fld.value.Type(), entry.Value); err != nil {
+			return fmt.Errorf("config file %s: line %d: key %q: invalid value %q: %w", path, entry.Line, entry.Key, entry.Value, err)
+		}
+
+		if _, set := os.LookupEnv(fld.env); set {
+			continue
+		}
+		if err := os.Setenv(fld.env, entry.Value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Path returns the file named by the --config flag in the arguments or the
+// PREFIX_CONFIG environment variable. The flags end at the first argument
+// that isn't one, the same as conf reads them.
+func Path(prefix string, args []string) string {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || !strings.HasPrefix(arg, "-") {
+			break
+		}
+
+		name := strings.TrimLeft(arg, "-")
+		if value, ok := strings.CutPrefix(name, "config="); ok {
+			return value
+		}
+		if name == "config" && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+
+	return os.Getenv(strings.ToUpper(prefix) + "_CONFIG")
+}
+
+// Print writes the configuration as a TOML file that can be loaded with
+// --config. Masked keys are left commented out.
+func Print(w io.Writer, prefix string, cfg any) error {
+	fields, err := fieldsOf(prefix, cfg)
+	if err != nil {
+		return err
+	}
+
+	// Keys outside of a section have to come before the first one.
+	sort.SliceStable(fields, func(i, j int) bool {
+		return !strings.Contains(fields[i].key, ".") && strings.Contains(fields[j].key, ".")
+	})
+
+	var section string
+	for _, fld := range fields {
+		fieldSection, name := splitKey(fld.key)
+		if fieldSection != section {
+			section = fieldSection
+			fmt.Fprintf(w, "\n[%s]\n", section)
+		}
+
+		if fld.mask {
+			fmt.Fprintf(w, "# %s = \"xxxxxx\"\n", name)
+			continue
+		}
+		fmt.Fprintf(w, "%s = %s\n", name, format(fld.value))
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// field represents a setting of the configuration with the key it has in a
+// file and the environment variable conf reads it from.
+type field struct {
+	key   string
+	env   string
+	value reflect.Value
+	mask  bool
+}
+
+// fieldsOf returns the settings of the configuration in the order of the
+// struct. The environment variables are taken from the usage conf prints,
+// so the names match the ones it derives from the field names and tags.
+func fieldsOf(prefix string, cfg any) ([]field, error) {
+	usage, err := conf.UsageInfo(prefix, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	envPrefix := strings.ToUpper(prefix) + "_"
+	envs := make(map[string]string)
+	for _, env := range regexp.MustCompile(`\$`+regexp.QuoteMeta(envPrefix)+`[A-Z0-9_]+`).FindAllString(usage, -1) {
+		name := strings.TrimPrefix(env, "$"+envPrefix)
+		envs[normalize(name)] = name
+	}
+
+	var fields []field
+	var walk func(v reflect.Value, path []string)
+	walk = func(v reflect.Value, path []string) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			tag := sf.Tag.Get("conf")
+			if !sf.IsExported() || tag == "-" || sf.Type == reflect.TypeOf(conf.Args{}) || sf.Type == reflect.TypeOf(conf.Version{}) {
+				continue
+			}
+
+			fieldPath := append(append([]string{}, path...), sf.Name)
+
+			if sf.Type.Kind() == reflect.Struct {
+				if sf.Anonymous {
+					fieldPath = path
+				}
+				walk(v.Field(i), fieldPath)
+				continue
+			}
+
+			name, exists := envs[normalize(strings.Join(fieldPath, ""))]
+			if !exists {
+				continue
+			}
+
+			fields = append(fields, field{
+				key:   keyOf(fieldPath, name),
+				env:   envPrefix + name,
+				value: v.Field(i),
+				mask:  strings.Contains(tag, "mask"),
+			})
+		}
+	}
+
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return nil, conf.ErrInvalidStruct
+	}
+	walk(v.Elem(), nil)
+
+	return fields, nil
+}
+
+// keyOf returns the key of the field in a file, the words of the environment
+// variable in lower case with the struct names as sections, such as
+// cold_store.access_key for NODE_COLD_STORE_ACCESS_KEY.
+func keyOf(path []string, env string) string {
+	words := strings.Split(strings.ToLower(env), "_")
+
+	var parts []string
+	for _, name := range path[:len(path)-1] {
+		var taken []string
+		for len(words) > 0 && normalize(strings.Join(taken, "")) != normalize(name) {
+			taken = append(taken, words[0])
+			words = words[1:]
+		}
+		parts = append(parts, strings.Join(taken, "_"))
+	}
+	parts = append(parts, strings.Join(words, "_"))
+
+	return strings.Join(parts, ".")
+}
+
+// splitKey splits a key into its section and name.
+func splitKey(key string) (string, string) {
+	i := strings.LastIndex(key, ".")
+	if i < 0 {
+		return "", key
+	}
+	return key[:i], key[i+1:]
+}
+
+// normalize drops the case and separators so names can be compared.
+func normalize(name string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "", ".", "").Replace(name))
+}
+
+// check reports whether the value converts to the type the same way conf
+// converts it. Lists are separated by semicolons.
+func check(t reflect.Type, value string) error {
+	var err error
+
+	switch {
+	case t == reflect.TypeOf(time.Duration(0)):
+		_, err = time.ParseDuration(value)
+	case t.Kind() == reflect.Bool:
+		_, err = strconv.ParseBool(value)
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Int64:
+		_, err = strconv.ParseInt(value, 0, t.Bits())
+	case t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uint64:
+		_, err = strconv.ParseUint(value, 0, t.Bits())
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		_, err = strconv.ParseFloat(value, t.Bits())
+	case t.Kind() == reflect.Slice:
+		for _, item := range strings.Split(value, ";") {
+			if err := check(t.Elem(), item); err != nil {
+				return err
+			}
+		}
+	}
+
+	return err
+}
+
+// format returns the value as a TOML value.
+func format(v reflect.Value) string {
+	switch {
+	case v.Type() == reflect.TypeOf(time.Duration(0)):
+		return strconv.Quote(time.Duration(v.Int()).String())
+	case v.Kind() == reflect.String:
+		return strconv.Quote(v.String())
+	case v.Kind() == reflect.Slice:
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = format(v.Index(i))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+
+	return fmt.Sprintf("%v", v.Interface())
+}
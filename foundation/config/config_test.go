@@ -0,0 +1,174 @@
+package config_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/conf/v3"
+
+	"github.com/qcbit/blockchain/foundation/config"
+)
+
+type settings struct {
+	Config string
+	Web    struct {
+		ReadTimeout   time.Duration `conf:"default:5s"`
+		StringNumbers bool          `conf:"default:true"`
+		APIKeys       []string      `conf:"mask"`
+	}
+	ColdStore struct {
+		Region      string `conf:"default:us-east-1"`
+		LocalBlocks uint64 `conf:"default:10000"`
+	}
+}
+
+func Test_File(t *testing.T) {
+	const toml = `
+# Settings for the test node.
+[web]
+read_timeout = "7s"    # Longer than the default.
+string_numbers = false
+api_keys = ["wallet:secret", "explorer:other#token"]
+
+[cold_store]
+region = 'eu-west-1'
+local_blocks = 500
+`
+
+	const yaml = `
+# Settings for the test node.
+web:
+  read_timeout: 7s    # Longer than the default.
+  string_numbers: false
+  api_keys:
+    - wallet:secret
+    - "explorer:other#token"
+cold_store:
+  region: eu-west-1
+  local_blocks: 500
+`
+
+	for name, content := range map[string]string{"node.toml": toml, "node.yaml": yaml} {
+		t.Run(name, func(t *testing.T) {
+			path := writeFile(t, name, content)
+
+			// The environment is applied over the file.
+			t.Setenv("TEST_COLD_STORE_REGION", "us-west-2")
+
+			var cfg settings
+			if err := parse(t, &cfg, "--config", path); err != nil {
+				t.Fatalf("parsing: %v", err)
+			}
+
+			if cfg.Web.ReadTimeout != 7*time.Second {
+				t.Fatalf("read timeout should come from the file, got %s", cfg.Web.ReadTimeout)
+			}
+			if cfg.Web.StringNumbers {
+				t.Fatal("the file should turn off a setting that defaults to true")
+			}
+			if len(cfg.Web.APIKeys) != 2 || cfg.Web.APIKeys[1] != "explorer:other#token" {
+				t.Fatalf("api keys should come from the file, got %v", cfg.Web.APIKeys)
+			}
+			if cfg.ColdStore.Region != "us-west-2" {
+				t.Fatalf("region should come from the environment, got %s", cfg.ColdStore.Region)
+			}
+			if cfg.ColdStore.LocalBlocks != 500 {
+				t.Fatalf("local blocks should come from the file, got %d", cfg.ColdStore.LocalBlocks)
+			}
+		})
+	}
+}
+
+func Test_FileErrors(t *testing.T) {
+	tt := []struct {
+		name    string
+		content string
+		exp     string
+	}{
+		{"unknown key", "[web]\nread_timout = \"7s\"\n", `line 2: unknown key "web.read_timout"`},
+		{"invalid value", "[cold_store]\nlocal_blocks = -1\n", `line 2: key "cold_store.local_blocks": invalid value "-1"`},
+		{"duplicate key", "[web]\nread_timeout = \"1s\"\nread_timeout = \"2s\"\n", `line 3: key "web.read_timeout" is already set on line 2`},
+		{"bad syntax", "[web]\nread_timeout\n", "line 2: expected key = value"},
+	}
+
+	for _, tst := range tt {
+		var cfg settings
+		err := parse(t, &cfg, "--config="+writeFile(t, "node.toml", tst.content))
+		if err == nil || !strings.Contains(err.Error(), tst.exp) {
+			t.Fatalf("%s: got error %v, exp it to contain %s", tst.name, err, tst.exp)
+		}
+	}
+}
+
+func Test_Print(t *testing.T) {
+	var cfg settings
+	if err := parse(t, &cfg); err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	cfg.Web.APIKeys = []string{"wallet:secret"}
+
+	var buf bytes.Buffer
+	if err := config.Print(&buf, "TEST", &cfg); err != nil {
+		t.Fatalf("printing: %v", err)
+	}
+
+	if strings.Contains(buf.String(), "secret") {
+		t.Fatalf("masked values should not be printed:\n%s", buf.String())
+	}
+
+	// The printed configuration loads back as the same configuration,
+	// leaving out the masked keys.
+	entries, err := config.Read(writeFile(t, "node.toml", buf.String()))
+	if err != nil {
+		t.Fatalf("reading printed config: %v\n%s", err, buf.String())
+	}
+
+	exp := map[string]string{
+		"config":                  "",
+		"web.read_timeout":        "5s",
+		"web.string_numbers":      "true",
+		"cold_store.region":       "us-east-1",
+		"cold_store.local_blocks": "10000",
+	}
+	if len(entries) != len(exp) {
+		t.Fatalf("got %d entries, exp %d:\n%s", len(entries), len(exp), buf.String())
+	}
+	for _, entry := range entries {
+		if value, exists := exp[entry.Key]; !exists || value != entry.Value {
+			t.Fatalf("got %s = %q, exp %q", entry.Key, entry.Value, value)
+		}
+	}
+}
+
+// parse parses the configuration with the specified command line arguments
+// the way the node does.
+func parse(t *testing.T, cfg *settings, args ...string) error {
+	osArgs := os.Args
+	os.Args = append([]string{"node"}, args...)
+	t.Cleanup(func() { os.Args = osArgs })
+
+	// Setting the file's values in the environment is undone after the test.
+	for _, key := range []string{"TEST_WEB_READ_TIMEOUT", "TEST_WEB_STRING_NUMBERS", "TEST_WEB_API_KEYS", "TEST_COLD_STORE_LOCAL_BLOCKS", "TEST_COLD_STORE_REGION"} {
+		if value, set := os.LookupEnv(key); set {
+			t.Setenv(key, value)
+			continue
+		}
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+
+	_, err := conf.Parse("TEST", cfg, config.NewFile(os.Args[1:]))
+	return err
+}
+
+func writeFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("writing file: %v", err)
+	}
+	return path
+}
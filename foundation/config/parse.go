@@ -0,0 +1,310 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Entry represents a value set in a configuration file. Lists are joined
+// with semicolons, the way conf reads them from the environment.
+type Entry struct {
+	Key   string
+	Value string
+	Line  int
+}
+
+// Read loads the entries of a TOML file, or a YAML file when the extension
+// is .yaml or .yml. Only the part of the formats settings need is supported:
+// sections, keys with a string, number, boolean or list value, and comments.
+func Read(path string) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("config file: %w", err)
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	var entries []Entry
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		entries, err = parseYAML(lines)
+	default:
+		entries, err = parseTOML(lines)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	seen := make(map[string]int, len(entries))
+	for _, entry := range entries {
+		if line, exists := seen[entry.Key]; exists {
+			return nil, fmt.Errorf("config file %s: line %d: key %q is already set on line %d", path, entry.Line, entry.Key, line)
+		}
+		seen[entry.Key] = entry.Line
+	}
+
+	return entries, nil
+}
+
+// parseTOML reads [section] headers and key = value lines.
+func parseTOML(lines []string) ([]Entry, error) {
+	var entries []Entry
+	var section string
+
+	for i, line := range lines {
+		line = strings.TrimSpace(stripComment(line))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: unterminated section %q", i+1, line)
+			}
+			section = normalizeKey(line[1 : len(line)-1])
+			continue
+		}
+
+		key, raw, found := strings.Cut(line, "=")
+		if !found {
+			return nil, fmt.Errorf("line %d: expected key = value", i+1)
+		}
+
+		value, err := parseValue(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: key %q: %w", i+1, normalizeKey(key), err)
+		}
+
+		entries = append(entries, Entry{
+			Key:   joinKey(section, normalizeKey(key)),
+			Value: value,
+			Line:  i + 1,
+		})
+	}
+
+	return entries, nil
+}
+
+// parseYAML reads nested key: value lines, with lists written inline or as
+// - item lines under their key.
+func parseYAML(lines []string) ([]Entry, error) {
+	type parent struct {
+		indent  int
+		key     string
+		entry   int // Index of the entry the key starts, in case it's a list.
+		items   int
+		section bool
+	}
+
+	var entries []Entry
+	var stack []*parent
+
+	for i, line := range lines {
+		line = stripComment(line)
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if strings.HasPrefix(strings.TrimLeft(line, " "), "\t") {
+			return nil, fmt.Errorf("line %d: tabs can't be used to indent", i+1)
+		}
+
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		line = strings.TrimSpace(line)
+
+		// A list item belongs to the closest key above it without a value.
+		if line == "-" || strings.HasPrefix(line, "- ") {
+			for len(stack) > 0 && stack[len(stack)-1].indent > indent {
+				stack = stack[:len(stack)-1]
+			}
+			if len(stack) == 0 || stack[len(stack)-1].section {
+				return nil, fmt.Errorf("line %d: list item without a key", i+1)
+			}
+
+			item, err := parseScalar(strings.TrimSpace(strings.TrimPrefix(line, "-")))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+
+			list := stack[len(stack)-1]
+			if list.items > 0 {
+				entries[list.entry].Value += ";"
+			}
+			entries[list.entry].Value += item
+			list.items++
+			continue
+		}
+
+		key, raw, found := strings.Cut(line, ":")
+		if !found {
+			return nil, fmt.Errorf("line %d: expected key: value", i+1)
+		}
+		key = normalizeKey(key)
+		raw = strings.TrimSpace(raw)
+
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+
+		var section string
+		if len(stack) > 0 {
+			if stack[len(stack)-1].items > 0 {
+				return nil, fmt.Errorf("line %d: key %q inside of a list", i+1, key)
+			}
+			stack[len(stack)-1].section = true
+			section = stack[len(stack)-1].key
+		}
+		key = joinKey(section, key)
+
+		value, err := parseValue(raw)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: key %q: %w", i+1, key, err)
+		}
+
+		entries = append(entries, Entry{
+			Key:   key,
+			Value: value,
+			Line:  i + 1,
+		})
+
+		// A key without a value is a section or a list, which is only
+		// known from the lines under it.
+		if raw == "" {
+			stack = append(stack, &parent{indent: indent, key: key, entry: len(entries) - 1})
+		}
+	}
+
+	// Drop the entries of the keys that turned out to be sections.
+	sections := make(map[string]bool)
+	for _, entry := range entries {
+		if i := strings.LastIndex(entry.Key, "."); i >= 0 {
+			sections[entry.Key[:i]] = true
+		}
+	}
+
+	out := entries[:0]
+	for _, entry := range entries {
+		if !sections[entry.Key] {
+			out = append(out, entry)
+		}
+	}
+
+	return out, nil
+}
+
+// parseValue returns the value as conf reads it, a list joined with
+// semicolons or a scalar.
+func parseValue(raw string) (string, error) {
+	if !strings.HasPrefix(raw, "[") {
+		return parseScalar(raw)
+	}
+
+	if !strings.HasSuffix(raw, "]") {
+		return "", errors.New("unterminated list")
+	}
+
+	var items []string
+	for _, item := range splitList(raw[1 : len(raw)-1]) {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		value, err := parseScalar(item)
+		if err != nil {
+			return "", err
+		}
+		items = append(items, value)
+	}
+
+	return strings.Join(items, ";"), nil
+}
+
+// parseScalar returns the value of a quoted or bare string, number or
+// boolean.
+func parseScalar(raw string) (string, error) {
+	switch {
+	case strings.HasPrefix(raw, `"`):
+		value, err := strconv.Unquote(raw)
+		if err != nil {
+			return "", fmt.Errorf("invalid string %s", raw)
+		}
+		return value, nil
+
+	case strings.HasPrefix(raw, "'"):
+		if len(raw) < 2 || !strings.HasSuffix(raw, "'") {
+			return "", fmt.Errorf("invalid string %s", raw)
+		}
+		return raw[1 : len(raw)-1], nil
+	}
+
+	return raw, nil
+}
+
+// splitList splits the items of a list at the commas outside of quotes.
+func splitList(s string) []string {
+	var items []string
+	var quote rune
+	start := 0
+
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote && (quote == '\'' || i == 0 || s[i-1] != '\\') {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ',':
+			items = append(items, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(items, s[start:])
+}
+
+// stripComment removes a # comment that isn't inside of quotes.
+func stripComment(line string) string {
+	var quote rune
+
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote && (quote == '\'' || i == 0 || line[i-1] != '\\') {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#':
+			return line[:i]
+		}
+	}
+
+	return line
+}
+
+// normalizeKey returns the key in lower case with dashes as underscores.
+func normalizeKey(key string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(key)), "-", "_")
+}
+
+// joinKey returns the key inside of the section.
+func joinKey(section string, key string) string {
+	if section == "" {
+		return key
+	}
+	return section + "." + key
+}
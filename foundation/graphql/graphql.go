@@ -0,0 +1,308 @@
+// Package graphql executes GraphQL queries against a schema of resolvers.
+// It supports the part of the language used to read data: queries with
+// aliases, arguments, variables and nested selections. Mutations,
+// subscriptions, fragments, directives and introspection are not supported.
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// maxDepth is the most levels of selections a query can nest so a single
+// query can't walk the whole chain.
+const maxDepth = 10
+
+// Resolver resolves the value of a field. The value is a scalar, an Object,
+// a slice of Objects or nil.
+type Resolver func(ctx context.Context, args Args) (any, error)
+
+// Object represents a value with fields that are resolved when selected.
+type Object struct {
+	Name   string
+	Fields map[string]Resolver
+}
+
+// Request represents a query as it's posted to a GraphQL endpoint.
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// Error represents an error resolving the query or one of its fields.
+type Error struct {
+	Message string `json:"message"`
+	Path    []any  `json:"path,omitempty"`
+}
+
+// Response represents the result of a query. A field that failed to
+// resolve is null in the data and explained in the errors.
+type Response struct {
+	Data   any     `json:"data"`
+	Errors []Error `json:"errors,omitempty"`
+}
+
+// Execute runs the query of the request against the root object.
+func Execute(ctx context.Context, root Object, req Request) Response {
+	ops, err := parse(req.Query)
+	if err != nil {
+		return Response{Errors: []Error{{Message: err.Error()}}}
+	}
+
+	op, err := selectOperation(ops, req.OperationName)
+	if err != nil {
+		return Response{Errors: []Error{{Message: err.Error()}}}
+	}
+
+	vars := make(map[string]any, len(op.defaults)+len(req.Variables))
+	for name, v := range op.defaults {
+		vars[name] = v
+	}
+	for name, v := range req.Variables {
+		vars[name] = v
+	}
+
+	e := executor{vars: vars}
+	data := e.object(ctx, root, op.selections, nil)
+
+	return Response{Data: data, Errors: e.errs}
+}
+
+// selectOperation picks the operation to run by name. The name can be left
+// out when the document has a single operation.
+func selectOperation(ops []operation, name string) (operation, error) {
+	if name == "" {
+		if len(ops) > 1 {
+			return operation{}, fmt.Errorf("document has %d operations, the operation name is required", len(ops))
+		}
+		return ops[0], nil
+	}
+
+	for _, op := range ops {
+		if op.name == name {
+			return op, nil
+		}
+	}
+
+	return operation{}, fmt.Errorf("operation %q not found", name)
+}
+
+// =============================================================================
+
+// executor resolves the fields of an operation and collects the errors.
+type executor struct {
+	vars map[string]any
+	errs []Error
+}
+
+func (e *executor) fail(path []any, format string, args ...any) {
+	e.errs = append(e.errs, Error{
+		Message: fmt.Sprintf(format, args...),
+		Path:    append([]any(nil), path...),
+	})
+}
+
+// object resolves the selected fields of the object.
+func (e *executor) object(ctx context.Context, obj Object, selections []Field, path []any) result {
+	out := make(result, 0, len(selections))
+
+	for _, f := range selections {
+		fieldPath := append(path[:len(path):len(path)], f.Alias)
+
+		if f.Name == "__typename" {
+			out = append(out, entry{f.Alias, obj.Name})
+			continue
+		}
+
+		resolve, exists := obj.Fields[f.Name]
+		if !exists {
+			e.fail(fieldPath, "field %q not found on %s", f.Name, obj.Name)
+			out = append(out, entry{f.Alias, nil})
+			continue
+		}
+
+		args, err := e.args(f.Args)
+		if err != nil {
+			e.fail(fieldPath, "%s", err)
+			out = append(out, entry{f.Alias, nil})
+			continue
+		}
+
+		v, err := resolve(ctx, args)
+		if err != nil {
+			e.fail(fieldPath, "%s", err)
+			out = append(out, entry{f.Alias, nil})
+			continue
+		}
+
+		out = append(out, entry{f.Alias, e.complete(ctx, f, v, fieldPath)})
+	}
+
+	return out
+}
+
+// complete resolves the selections of a resolved value.
+func (e *executor) complete(ctx context.Context, f Field, v any, path []any) any {
+	switch v := v.(type) {
+	case nil:
+		return nil
+
+	case Object:
+		if len(f.Selections) == 0 {
+			e.fail(path, "field %q of type %s must have a selection of subfields", f.Name, v.Name)
+			return nil
+		}
+		return e.object(ctx, v, f.Selections, path)
+
+	case []Object:
+		if len(f.Selections) == 0 {
+			e.fail(path, "field %q must have a selection of subfields", f.Name)
+			return nil
+		}
+		list := make([]any, len(v))
+		for i, obj := range v {
+			list[i] = e.object(ctx, obj, f.Selections, append(path[:len(path):len(path)], i))
+		}
+		return list
+	}
+
+	if len(f.Selections) > 0 {
+		e.fail(path, "field %q is a scalar and can't have a selection of subfields", f.Name)
+		return nil
+	}
+
+	return v
+}
+
+// args replaces the variables in the arguments with their values.
+func (e *executor) args(raw map[string]any) (Args, error) {
+	args := make(Args, len(raw))
+	for name, v := range raw {
+		v, err := e.value(v)
+		if err != nil {
+			return nil, err
+		}
+		args[name] = v
+	}
+	return args, nil
+}
+
+func (e *executor) value(v any) (any, error) {
+	switch v := v.(type) {
+	case variable:
+		value, exists := e.vars[string(v)]
+		if !exists {
+			return nil, fmt.Errorf("variable $%s is not defined", v)
+		}
+		return value, nil
+
+	case []any:
+		list := make([]any, len(v))
+		for i, item := range v {
+			var err error
+			if list[i], err = e.value(item); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+
+	case map[string]any:
+		obj := make(map[string]any, len(v))
+		for name, item := range v {
+			var err error
+			if obj[name], err = e.value(item); err != nil {
+				return nil, err
+			}
+		}
+		return obj, nil
+	}
+
+	return v, nil
+}
+
+// =============================================================================
+
+// Args represents the arguments of a field with the variables replaced.
+type Args map[string]any
+
+// Uint returns the argument as an unsigned integer and whether it was
+// provided. Variables decoded from JSON are numbers with no fraction.
+func (a Args) Uint(name string) (uint64, bool, error) {
+	v, exists := a[name]
+	if !exists || v == nil {
+		return 0, false, nil
+	}
+
+	switch n := v.(type) {
+	case int64:
+		if n >= 0 {
+			return uint64(n), true, nil
+		}
+	case float64:
+		if n >= 0 && n == math.Trunc(n) && n <= math.MaxUint64 {
+			return uint64(n), true, nil
+		}
+	}
+
+	return 0, false, fmt.Errorf("argument %q must be a non-negative integer, got %v", name, v)
+}
+
+// String returns the argument as a string and whether it was provided.
+func (a Args) String(name string) (string, bool, error) {
+	v, exists := a[name]
+	if !exists || v == nil {
+		return "", false, nil
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return "", false, fmt.Errorf("argument %q must be a string, got %v", name, v)
+	}
+
+	return s, true, nil
+}
+
+// =============================================================================
+
+// entry represents a field in the response.
+type entry struct {
+	key   string
+	value any
+}
+
+// result represents the fields of an object in the response in the order
+// they were selected.
+type result []entry
+
+// MarshalJSON implements the json.Marshaler interface keeping the fields
+// in the order they were selected.
+func (r result) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+
+	for i, e := range r {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+
+		key, err := json.Marshal(e.key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(e.value)
+		if err != nil {
+			return nil, err
+		}
+
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
@@ -0,0 +1,122 @@
+package graphql_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/qcbit/blockchain/foundation/graphql"
+)
+
+func book(id uint64) graphql.Object {
+	return graphql.Object{
+		Name: "Book",
+		Fields: map[string]graphql.Resolver{
+			"id": func(ctx context.Context, args graphql.Args) (any, error) {
+				return id, nil
+			},
+			"title": func(ctx context.Context, args graphql.Args) (any, error) {
+				return fmt.Sprintf("book %d", id), nil
+			},
+			"broken": func(ctx context.Context, args graphql.Args) (any, error) {
+				return nil, errors.New("broken field")
+			},
+		},
+	}
+}
+
+var root = graphql.Object{
+	Name: "Query",
+	Fields: map[string]graphql.Resolver{
+		"book": func(ctx context.Context, args graphql.Args) (any, error) {
+			id, _, err := args.Uint("id")
+			if err != nil {
+				return nil, err
+			}
+			return book(id), nil
+		},
+		"books": func(ctx context.Context, args graphql.Args) (any, error) {
+			first, ok, err := args.Uint("first")
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				first = 2
+			}
+			var books []graphql.Object
+			for i := uint64(1); i <= first; i++ {
+				books = append(books, book(i))
+			}
+			return books, nil
+		},
+	},
+}
+
+func Test_Execute(t *testing.T) {
+	tt := []struct {
+		name string
+		req  graphql.Request
+		exp  string
+	}{
+		{
+			name: "nested",
+			req:  graphql.Request{Query: `{ books(first: 2) { id title } }`},
+			exp:  `{"data":{"books":[{"id":1,"title":"book 1"},{"id":2,"title":"book 2"}]}}`,
+		},
+		{
+			name: "alias and order",
+			req:  graphql.Request{Query: `query { b: book(id: 7) { title __typename id } }`},
+			exp:  `{"data":{"b":{"title":"book 7","__typename":"Book","id":7}}}`,
+		},
+		{
+			name: "variables",
+			req: graphql.Request{
+				Query:     `query Get($id: Int!, $n: Int = 1) { book(id: $id) { id } books(first: $n) { id } }`,
+				Variables: map[string]any{"id": float64(3)},
+			},
+			exp: `{"data":{"book":{"id":3},"books":[{"id":1}]}}`,
+		},
+		{
+			name: "field error",
+			req:  graphql.Request{Query: `{ book(id: 1) { id broken } }`},
+			exp:  `{"data":{"book":{"id":1,"broken":null}},"errors":[{"message":"broken field","path":["book","broken"]}]}`,
+		},
+		{
+			name: "unknown field",
+			req:  graphql.Request{Query: `{ author }`},
+			exp:  `{"data":{"author":null},"errors":[{"message":"field \"author\" not found on Query","path":["author"]}]}`,
+		},
+		{
+			name: "missing selection",
+			req:  graphql.Request{Query: `{ book(id: 1) }`},
+			exp:  `{"data":{"book":null},"errors":[{"message":"field \"book\" of type Book must have a selection of subfields","path":["book"]}]}`,
+		},
+		{
+			name: "fragments",
+			req:  graphql.Request{Query: `{ book(id: 1) { ...Fields } }`},
+			exp:  `{"data":null,"errors":[{"message":"fragments are not supported at 16"}]}`,
+		},
+		{
+			name: "mutation",
+			req:  graphql.Request{Query: `mutation { book(id: 1) { id } }`},
+			exp:  `{"data":null,"errors":[{"message":"mutation operations are not supported"}]}`,
+		},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			resp := graphql.Execute(context.Background(), root, tst.req)
+
+			got, err := json.Marshal(resp)
+			if err != nil {
+				t.Fatalf("marshaling response: %v", err)
+			}
+
+			if string(got) != tst.exp {
+				t.Fatalf("response:\ngot %s\nexp %s", got, tst.exp)
+			}
+		})
+	}
+}
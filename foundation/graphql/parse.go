@@ -0,0 +1,413 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Field represents a field selected in a query.
+type Field struct {
+	Alias      string // Name of the field in the response, the name when not aliased.
+	Name       string
+	Args       map[string]any
+	Selections []Field
+}
+
+// operation represents a query in a document.
+type operation struct {
+	name       string
+	defaults   map[string]any // Default values of the variables.
+	selections []Field
+}
+
+// variable represents a reference to a variable in an argument.
+type variable string
+
+// =============================================================================
+
+// token represents a lexical token of a query.
+type token struct {
+	kind  byte // One of the punctuators, 'n' for a name, 'i', 'f' or 's' for a value, 0 at the end.
+	value string
+	pos   int
+}
+
+// lex splits the query into tokens. Commas and comments are ignored.
+func lex(query string) ([]token, error) {
+	var tokens []token
+
+	for i := 0; i < len(query); {
+		c := query[i]
+
+		switch {
+		case c == ',' || unicode.IsSpace(rune(c)):
+			i++
+
+		case c == '#':
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+
+		case strings.IndexByte("{}():$!=[]@", c) >= 0:
+			tokens = append(tokens, token{kind: c, pos: i})
+			i++
+
+		case strings.HasPrefix(query[i:], "..."):
+			tokens = append(tokens, token{kind: '.', value: "...", pos: i})
+			i += 3
+
+		case c == '_' || unicode.IsLetter(rune(c)):
+			start := i
+			for i < len(query) && (query[i] == '_' || unicode.IsLetter(rune(query[i])) || unicode.IsDigit(rune(query[i]))) {
+				i++
+			}
+			tokens = append(tokens, token{kind: 'n', value: query[start:i], pos: start})
+
+		case c == '-' || unicode.IsDigit(rune(c)):
+			start := i
+			kind := byte('i')
+			i++
+			for i < len(query) && strings.IndexByte("0123456789.eE+-", query[i]) >= 0 {
+				if strings.IndexByte(".eE", query[i]) >= 0 {
+					kind = 'f'
+				}
+				i++
+			}
+			tokens = append(tokens, token{kind: kind, value: query[start:i], pos: start})
+
+		case c == '"':
+			start := i
+			i++
+			for i < len(query) && query[i] != '"' {
+				if query[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i >= len(query) {
+				return nil, fmt.Errorf("unterminated string at %d", start)
+			}
+			i++
+
+			s, err := strconv.Unquote(query[start:i])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at %d: %w", start, err)
+			}
+			tokens = append(tokens, token{kind: 's', value: s, pos: start})
+
+		default:
+			return nil, fmt.Errorf("unexpected character %q at %d", c, i)
+		}
+	}
+
+	return append(tokens, token{pos: len(query)}), nil
+}
+
+// =============================================================================
+
+// parser builds the operations of a document from its tokens.
+type parser struct {
+	tokens []token
+	next   int
+}
+
+// parse parses the operations of the document. Only queries are supported,
+// fragments and directives aren't.
+func parse(query string) ([]operation, error) {
+	tokens, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser{tokens: tokens}
+
+	var ops []operation
+	for p.peek().kind != 0 {
+		op, err := p.operation()
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, op)
+	}
+
+	if len(ops) == 0 {
+		return nil, fmt.Errorf("document has no operations")
+	}
+
+	return ops, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) advance() token {
+	t := p.tokens[p.next]
+	if t.kind != 0 {
+		p.next++
+	}
+	return t
+}
+
+func (p *parser) expect(kind byte) (token, error) {
+	t := p.advance()
+	if t.kind != kind {
+		return t, p.unexpected(t)
+	}
+	return t, nil
+}
+
+func (p *parser) unexpected(t token) error {
+	switch t.kind {
+	case 0:
+		return fmt.Errorf("unexpected end of document")
+	case 'n', 'i', 'f':
+		return fmt.Errorf("unexpected %q at %d", t.value, t.pos)
+	case 's':
+		return fmt.Errorf("unexpected string at %d", t.pos)
+	case '.':
+		return fmt.Errorf("fragments are not supported at %d", t.pos)
+	case '@':
+		return fmt.Errorf("directives are not supported at %d", t.pos)
+	default:
+		return fmt.Errorf("unexpected %q at %d", t.kind, t.pos)
+	}
+}
+
+// operation parses a query with or without the query keyword.
+func (p *parser) operation() (operation, error) {
+	op := operation{defaults: make(map[string]any)}
+
+	if t := p.peek(); t.kind == 'n' {
+		switch t.value {
+		case "query":
+			p.advance()
+		case "mutation", "subscription":
+			return op, fmt.Errorf("%s operations are not supported", t.value)
+		default:
+			return op, p.unexpected(t)
+		}
+
+		if p.peek().kind == 'n' {
+			op.name = p.advance().value
+		}
+
+		if p.peek().kind == '(' {
+			if err := p.variableDefinitions(op.defaults); err != nil {
+				return op, err
+			}
+		}
+	}
+
+	selections, err := p.selectionSet(0)
+	if err != nil {
+		return op, err
+	}
+	op.selections = selections
+
+	return op, nil
+}
+
+// variableDefinitions parses the variables of an operation and keeps their
+// default values. The types are only checked to be well formed.
+func (p *parser) variableDefinitions(defaults map[string]any) error {
+	p.advance()
+
+	for p.peek().kind != ')' {
+		if _, err := p.expect('$'); err != nil {
+			return err
+		}
+		name, err := p.expect('n')
+		if err != nil {
+			return err
+		}
+		if _, err := p.expect(':'); err != nil {
+			return err
+		}
+		if err := p.typeRef(); err != nil {
+			return err
+		}
+
+		if p.peek().kind == '=' {
+			p.advance()
+			v, err := p.value()
+			if err != nil {
+				return err
+			}
+			defaults[name.value] = v
+		}
+	}
+	p.advance()
+
+	return nil
+}
+
+// typeRef parses a type such as Int, String! or [String!]!.
+func (p *parser) typeRef() error {
+	if p.peek().kind == '[' {
+		p.advance()
+		if err := p.typeRef(); err != nil {
+			return err
+		}
+		if _, err := p.expect(']'); err != nil {
+			return err
+		}
+	} else if _, err := p.expect('n'); err != nil {
+		return err
+	}
+
+	if p.peek().kind == '!' {
+		p.advance()
+	}
+
+	return nil
+}
+
+// selectionSet parses the fields selected between braces.
+func (p *parser) selectionSet(depth int) ([]Field, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("query is nested more than %d levels", maxDepth)
+	}
+
+	if _, err := p.expect('{'); err != nil {
+		return nil, err
+	}
+
+	var fields []Field
+	for p.peek().kind != '}' {
+		f, err := p.field(depth)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+	p.advance()
+
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("selection set is empty")
+	}
+
+	return fields, nil
+}
+
+// field parses a field with its alias, arguments and selections.
+func (p *parser) field(depth int) (Field, error) {
+	name, err := p.expect('n')
+	if err != nil {
+		return Field{}, err
+	}
+
+	f := Field{Alias: name.value, Name: name.value}
+	if p.peek().kind == ':' {
+		p.advance()
+		if name, err = p.expect('n'); err != nil {
+			return Field{}, err
+		}
+		f.Name = name.value
+	}
+
+	if p.peek().kind == '(' {
+		p.advance()
+		f.Args = make(map[string]any)
+		for p.peek().kind != ')' {
+			arg, err := p.expect('n')
+			if err != nil {
+				return Field{}, err
+			}
+			if _, err := p.expect(':'); err != nil {
+				return Field{}, err
+			}
+			v, err := p.value()
+			if err != nil {
+				return Field{}, err
+			}
+			f.Args[arg.value] = v
+		}
+		p.advance()
+	}
+
+	if p.peek().kind == '{' {
+		if f.Selections, err = p.selectionSet(depth + 1); err != nil {
+			return Field{}, err
+		}
+	}
+
+	return f, nil
+}
+
+// value parses an argument value.
+func (p *parser) value() (any, error) {
+	t := p.advance()
+
+	switch t.kind {
+	case '$':
+		name, err := p.expect('n')
+		if err != nil {
+			return nil, err
+		}
+		return variable(name.value), nil
+
+	case 'i':
+		n, err := strconv.ParseInt(t.value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid int %q at %d", t.value, t.pos)
+		}
+		return n, nil
+
+	case 'f':
+		n, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid float %q at %d", t.value, t.pos)
+		}
+		return n, nil
+
+	case 's':
+		return t.value, nil
+
+	case 'n':
+		switch t.value {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		return t.value, nil
+
+	case '[':
+		list := []any{}
+		for p.peek().kind != ']' {
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		p.advance()
+		return list, nil
+
+	case '{':
+		obj := make(map[string]any)
+		for p.peek().kind != '}' {
+			name, err := p.expect('n')
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(':'); err != nil {
+				return nil, err
+			}
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			obj[name.value] = v
+		}
+		p.advance()
+		return obj, nil
+	}
+
+	return nil, p.unexpected(t)
+}
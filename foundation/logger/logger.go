@@ -5,23 +5,65 @@ package logger
 import (
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+
+	"github.com/qcbit/blockchain/foundation/rotate"
 )
 
+// Config represents the configuration for constructing a logger.
+type Config struct {
+	Service  string
+	Level    string // debug, info, warn or error.
+	Path     string // Optional file to write to in addition to stdout.
+	MaxSize  int64  // Size in bytes the file can grow to before it's rotated.
+	MaxFiles int    // Number of rotated files to keep.
+}
+
 // New constructs a Sugared Logger that writes to stdout and
 // provides human-readable timestamps.
 func New(service string) (*zap.SugaredLogger, error) {
+	log, _, err := NewWithConfig(Config{
+		Service: service,
+		Level:   "info",
+	})
+	return log, err
+}
+
+// NewWithConfig constructs a Sugared Logger that writes to stdout at the
+// configured level and optionally to a file that is rotated by size. The
+// returned level can be used to change the logging level at runtime.
+func NewWithConfig(cfg Config) (*zap.SugaredLogger, zap.AtomicLevel, error) {
+	level, err := zap.ParseAtomicLevel(cfg.Level)
+	if err != nil {
+		return nil, zap.AtomicLevel{}, err
+	}
+
 	config := zap.NewProductionConfig()
+	config.Level = level
 	config.OutputPaths = []string{"stdout"}
 	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 	config.DisableStacktrace = true
 	config.InitialFields = map[string]any{
-		"service": service,
+		"service": cfg.Service,
+	}
+
+	var options []zap.Option
+	if cfg.Path != "" {
+		file, err := rotate.New(cfg.Path, cfg.MaxSize, cfg.MaxFiles)
+		if err != nil {
+			return nil, zap.AtomicLevel{}, err
+		}
+
+		// Write the same log entries to the file as are written to stdout.
+		fileCore := zapcore.NewCore(zapcore.NewJSONEncoder(config.EncoderConfig), file, level)
+		options = append(options, zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return zapcore.NewTee(core, fileCore)
+		}))
 	}
 
-	log, err := config.Build()
+	log, err := config.Build(options...)
 	if err != nil {
-		return nil, err
+		return nil, zap.AtomicLevel{}, err
 	}
 
-	return log.Sugar(), nil
+	return log.Sugar(), level, nil
 }
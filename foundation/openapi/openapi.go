@@ -0,0 +1,270 @@
+// Package openapi generates an OpenAPI 3 document from the routes registered
+// with a web.App and the models the handlers take and return. The routes
+// come from the App itself so the document can't fall behind the mux, the
+// description of each route is registered in code next to its handlers.
+package openapi
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/qcbit/blockchain/foundation/web"
+)
+
+// Version is the version of the OpenAPI specification the document follows.
+const Version = "3.0.3"
+
+// Info describes the API in the document.
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Param describes a query string parameter of a route.
+type Param struct {
+	Name        string
+	Description string
+	Required    bool
+}
+
+// Operation describes what a route takes and returns. Request and Response
+// are values of the models, the schemas are generated from their types. A
+// nil Response means the route returns no content.
+type Operation struct {
+	Summary    string
+	Deprecated bool
+	Query      []Param
+	Request    any
+	Response   any
+	Status     int // Status of a successful response, 200 when left out.
+}
+
+// Operations maps routes in the "METHOD /path" form to their description.
+// The path is relative to the group the routes are registered under.
+type Operations map[string]Operation
+
+// =============================================================================
+
+// Spec collects the description of the routes and builds the document.
+type Spec struct {
+	info     Info
+	errModel any
+
+	mu     sync.RWMutex
+	ops    map[string]Operation
+	routes []web.Route
+}
+
+// New constructs a Spec for the API. The error model is the value returned
+// by every route when a request fails.
+func New(info Info, errModel any) *Spec {
+	return &Spec{
+		info:     info,
+		errModel: errModel,
+		ops:      make(map[string]Operation),
+	}
+}
+
+// Describe registers the description of routes in the group. The group is
+// joined to the paths the same way web.App.Handle does.
+func (s *Spec) Describe(group string, ops Operations) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, op := range ops {
+		method, path, _ := strings.Cut(key, " ")
+		if group != "" {
+			path = "/" + group + path
+		}
+		s.ops[method+" "+path] = op
+	}
+}
+
+// SetRoutes replaces the routes the document is built from, usually with
+// the routes of the App once they are all registered.
+func (s *Spec) SetRoutes(routes []web.Route) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.routes = routes
+}
+
+// Document builds the document for the routes. Routes without a
+// description are still listed so clients know they exist.
+func (s *Spec) Document() Document {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	doc := Document{
+		OpenAPI: Version,
+		Info:    s.info,
+		Paths:   make(map[string]map[string]operation),
+	}
+	schemas := newSchemas()
+
+	var errSchema *Schema
+	if s.errModel != nil {
+		errSchema = schemas.of(s.errModel)
+	}
+
+	for _, route := range s.routes {
+
+		// The preflight and catch all routes aren't part of the API.
+		if route.Method == http.MethodOptions || strings.Contains(route.Path, "*") {
+			continue
+		}
+
+		op := s.ops[route.Method+" "+route.Path]
+		path, params := toPath(route.Path)
+
+		o := operation{
+			OperationID: operationID(route.Method, route.Path),
+			Summary:     op.Summary,
+			Deprecated:  op.Deprecated,
+			Responses:   make(map[string]response),
+		}
+
+		for _, name := range params {
+			o.Parameters = append(o.Parameters, parameter{
+				Name:     name,
+				In:       "path",
+				Required: true,
+				Schema:   &Schema{Type: "string"},
+			})
+		}
+		for _, p := range op.Query {
+			o.Parameters = append(o.Parameters, parameter{
+				Name:        p.Name,
+				In:          "query",
+				Description: p.Description,
+				Required:    p.Required,
+				Schema:      &Schema{Type: "string"},
+			})
+		}
+
+		if op.Request != nil {
+			o.RequestBody = &requestBody{
+				Required: true,
+				Content:  jsonContent(schemas.of(op.Request)),
+			}
+		}
+
+		status := op.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		resp := response{Description: http.StatusText(status)}
+		if op.Response != nil {
+			resp.Content = jsonContent(schemas.of(op.Response))
+		}
+		o.Responses[strconv.Itoa(status)] = resp
+
+		if errSchema != nil {
+			o.Responses["default"] = response{
+				Description: "Error",
+				Content:     jsonContent(errSchema),
+			}
+		}
+
+		methods, exists := doc.Paths[path]
+		if !exists {
+			methods = make(map[string]operation)
+			doc.Paths[path] = methods
+		}
+		methods[strings.ToLower(route.Method)] = o
+	}
+
+	doc.Components.Schemas = schemas.components
+
+	return doc
+}
+
+// toPath converts the :name parameters of a route into the {name} form and
+// returns their names.
+func toPath(route string) (string, []string) {
+	segments := strings.Split(route, "/")
+
+	var params []string
+	for i, seg := range segments {
+		if strings.HasPrefix(seg, ":") {
+			params = append(params, seg[1:])
+			segments[i] = "{" + seg[1:] + "}"
+		}
+	}
+
+	return strings.Join(segments, "/"), params
+}
+
+// operationID names the operation after the method and path of the route
+// so generated clients get a stable method name, such as GET /v1/accounts/:account
+// becoming getV1AccountsByAccount.
+func operationID(method string, route string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+
+	for _, seg := range strings.Split(route, "/") {
+		if seg == "" {
+			continue
+		}
+		if strings.HasPrefix(seg, ":") {
+			b.WriteString("By")
+			seg = seg[1:]
+		}
+		b.WriteString(exportName(seg))
+	}
+
+	return b.String()
+}
+
+// =============================================================================
+
+// Document represents an OpenAPI document.
+type Document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       Info                            `json:"info"`
+	Paths      map[string]map[string]operation `json:"paths"`
+	Components Components                      `json:"components"`
+}
+
+// Components holds the schemas of the named models the paths refer to.
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+}
+
+type operation struct {
+	OperationID string              `json:"operationId"`
+	Summary     string              `json:"summary,omitempty"`
+	Deprecated  bool                `json:"deprecated,omitempty"`
+	Parameters  []parameter         `json:"parameters,omitempty"`
+	RequestBody *requestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]response `json:"responses"`
+}
+
+type parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type requestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type response struct {
+	Description string               `json:"description"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+type mediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+func jsonContent(schema *Schema) map[string]mediaType {
+	return map[string]mediaType{"application/json": {Schema: schema}}
+}
@@ -0,0 +1,150 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"testing"
+
+	"github.com/qcbit/blockchain/foundation/openapi"
+	"github.com/qcbit/blockchain/foundation/web"
+)
+
+type base struct {
+	ID string `json:"id"`
+}
+
+type item struct {
+	base
+	Name    string            `json:"name"`
+	Amount  *big.Int          `json:"amount"`
+	Tags    []string          `json:"tags,omitempty"`
+	Data    []byte            `json:"data"`
+	Labels  map[string]uint64 `json:"labels"`
+	Parent  *item             `json:"parent,omitempty"`
+	private int
+}
+
+type errResponse struct {
+	Error string `json:"error"`
+}
+
+func Test_Document(t *testing.T) {
+	spec := openapi.New(openapi.Info{Title: "test", Version: "1.0"}, errResponse{})
+
+	spec.Describe("v1", openapi.Operations{
+		"GET /items/:id": {
+			Summary:  "Returns the item.",
+			Query:    []openapi.Param{{Name: "expand"}},
+			Response: item{},
+		},
+		"POST /items": {
+			Summary:    "Adds the item.",
+			Deprecated: true,
+			Request:    item{},
+			Status:     http.StatusAccepted,
+		},
+	})
+
+	spec.SetRoutes([]web.Route{
+		{Method: http.MethodOptions, Path: "/*"},
+		{Method: http.MethodGet, Path: "/v1/items/:id"},
+		{Method: http.MethodPost, Path: "/v1/items"},
+		{Method: http.MethodGet, Path: "/v1/undocumented"},
+	})
+
+	data, err := json.Marshal(spec.Document())
+	if err != nil {
+		t.Fatalf("Should be able to marshal the document: %s", err)
+	}
+
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Paths   map[string]map[string]struct {
+			OperationID string `json:"operationId"`
+			Deprecated  bool   `json:"deprecated"`
+			Parameters  []struct {
+				Name string `json:"name"`
+				In   string `json:"in"`
+			} `json:"parameters"`
+			RequestBody *json.RawMessage           `json:"requestBody"`
+			Responses   map[string]json.RawMessage `json:"responses"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]struct {
+					Ref      string `json:"$ref"`
+					Type     string `json:"type"`
+					Format   string `json:"format"`
+					Nullable bool   `json:"nullable"`
+				} `json:"properties"`
+				Required []string `json:"required"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Should be able to unmarshal the document: %s", err)
+	}
+
+	if doc.OpenAPI != openapi.Version {
+		t.Fatalf("Should have the OpenAPI version: got %s", doc.OpenAPI)
+	}
+
+	if len(doc.Paths) != 3 {
+		t.Fatalf("Should list the three routes and not the preflight: got %d", len(doc.Paths))
+	}
+
+	get := doc.Paths["/v1/items/{id}"]["get"]
+	if get.OperationID != "getV1ItemsById" {
+		t.Fatalf("Should name the operation after the route: got %s", get.OperationID)
+	}
+	if len(get.Parameters) != 2 || get.Parameters[0].In != "path" || get.Parameters[1].In != "query" {
+		t.Fatalf("Should have the path and query parameters: got %+v", get.Parameters)
+	}
+	if _, exists := get.Responses["200"]; !exists {
+		t.Fatalf("Should have the 200 response")
+	}
+	if _, exists := get.Responses["default"]; !exists {
+		t.Fatalf("Should have the error response")
+	}
+
+	post := doc.Paths["/v1/items"]["post"]
+	if !post.Deprecated || post.RequestBody == nil {
+		t.Fatalf("Should be deprecated with a request body")
+	}
+	if _, exists := post.Responses["202"]; !exists {
+		t.Fatalf("Should have the 202 response")
+	}
+
+	if _, exists := doc.Paths["/v1/undocumented"]["get"]; !exists {
+		t.Fatalf("Should list the undocumented route")
+	}
+
+	schema, exists := doc.Components.Schemas["Item"]
+	if !exists {
+		t.Fatalf("Should have the item component")
+	}
+	if _, exists := schema.Properties["id"]; !exists {
+		t.Fatalf("Should promote the fields of the embedded struct")
+	}
+	if _, exists := schema.Properties["private"]; exists {
+		t.Fatalf("Should leave out unexported fields")
+	}
+	if p := schema.Properties["amount"]; p.Type != "integer" || !p.Nullable {
+		t.Fatalf("Should encode the big integer as a nullable integer: got %+v", p)
+	}
+	if p := schema.Properties["data"]; p.Type != "string" || p.Format != "byte" {
+		t.Fatalf("Should encode the bytes as a string: got %+v", p)
+	}
+	if p := schema.Properties["parent"]; p.Ref != "#/components/schemas/Item" {
+		t.Fatalf("Should refer to itself: got %+v", p)
+	}
+
+	required := map[string]bool{}
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+	if !required["name"] || required["tags"] || required["parent"] {
+		t.Fatalf("Should require the fields without omitempty: got %v", schema.Required)
+	}
+}
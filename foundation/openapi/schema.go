@@ -0,0 +1,216 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"math/big"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Schema represents the schema of a model or one of its fields.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// The set of types with a schema of their own instead of the one of their kind.
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	bigIntType        = reflect.TypeOf(big.Int{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// schemas generates the schemas of the models. Named structs are kept as
+// components and referred to by name, anonymous structs are inlined.
+type schemas struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func newSchemas() *schemas {
+	return &schemas{
+		components: make(map[string]*Schema),
+		names:      make(map[reflect.Type]string),
+	}
+}
+
+// of returns the schema of the value's type.
+func (s *schemas) of(v any) *Schema {
+	return s.schema(reflect.TypeOf(v))
+}
+
+func (s *schemas) schema(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Pointer {
+		schema := s.schema(t.Elem())
+		if schema.Ref == "" {
+			schema.Nullable = true
+		}
+		return schema
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case bigIntType:
+		return &Schema{Type: "integer"}
+	}
+
+	// Types that encode themselves can't be described from their fields,
+	// the ones that encode as text are strings.
+	if t.Kind() != reflect.String && (t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType)) {
+		return &Schema{}
+	}
+	if t.Kind() != reflect.String && (t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType)) {
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer", Format: "int32"}
+
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int64"}
+
+	case reflect.Uint64:
+		return &Schema{Type: "integer", Format: "uint64"}
+
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+
+	case reflect.String:
+		return &Schema{Type: "string"}
+
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.schema(t.Elem())}
+
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.schema(t.Elem())}
+
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + s.component(t)}
+	}
+
+	// Interfaces can hold any value.
+	return &Schema{}
+}
+
+// component returns the name of the component for the struct, generating
+// its schema the first time it's seen.
+func (s *schemas) component(t reflect.Type) string {
+	if name, exists := s.names[t]; exists {
+		return name
+	}
+
+	// Models in different packages can share a name, such as the models of
+	// two versions of the API, so the package's parent is added to tell them
+	// apart.
+	name := exportName(t.Name())
+	if _, taken := s.components[name]; taken {
+		name = exportName(path.Base(path.Dir(t.PkgPath()))) + name
+	}
+	for i, base := 2, name; ; i++ {
+		if _, taken := s.components[name]; !taken {
+			break
+		}
+		name = base + strconv.Itoa(i)
+	}
+
+	// Register the name before generating the schema so a struct that
+	// refers to itself gets a reference.
+	s.names[t] = name
+	s.components[name] = &Schema{}
+	*s.components[name] = *s.object(t)
+
+	return name
+}
+
+// object returns the schema of the struct's fields as they are encoded to
+// JSON. The fields of embedded structs are promoted and fields without
+// omitempty are required.
+func (s *schemas) object(t reflect.Type) *Schema {
+	schema := Schema{
+		Type:       "object",
+		Properties: make(map[string]*Schema),
+	}
+	s.fields(t, &schema)
+
+	return &schema
+}
+
+func (s *schemas) fields(t reflect.Type, schema *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				s.fields(ft, schema)
+				continue
+			}
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		schema.Properties[name] = s.schema(f.Type)
+		if !strings.Contains(","+opts+",", ",omitempty,") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// exportName returns the name with its first letter in upper case and
+// anything that isn't a letter or digit removed.
+func exportName(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
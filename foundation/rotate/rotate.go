@@ -0,0 +1,137 @@
+// Package rotate provides a file writer that rotates the file by size.
+package rotate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// File is a file that is rotated once it reaches the max size. Only maxFiles
+// rotated files are kept, named path.1 through path.N with path.1 being the
+// most recent. With no rotated files to keep the file is started over, and
+// with no max size the file is never rotated.
+type File struct {
+	mu       sync.Mutex
+	path     string
+	maxSize  int64
+	maxFiles int
+	file     *os.File
+	size     int64
+}
+
+// New opens the file for appending, creating the folder it lives in
+// if needed.
+func New(path string, maxSize int64, maxFiles int) (*File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	f := File{
+		path:     path,
+		maxSize:  maxSize,
+		maxFiles: maxFiles,
+	}
+
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+
+	return &f, nil
+}
+
+// Write implements the io.Writer interface. The file is rotated before
+// the write when the write would take the file past the max size.
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+
+	return n, err
+}
+
+// Sync implements the zapcore.WriteSyncer interface.
+func (f *File) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.file.Sync()
+}
+
+// Close closes the current file.
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.file.Close()
+}
+
+// Paths returns the paths of the files that can hold data, from the
+// oldest rotated file to the current file.
+func (f *File) Paths() []string {
+	paths := make([]string, 0, f.maxFiles+1)
+	for i := f.maxFiles; i >= 0; i-- {
+		paths = append(paths, f.rotatedPath(i))
+	}
+	return paths
+}
+
+// open opens the current file for appending.
+func (f *File) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	f.file = file
+	f.size = info.Size()
+
+	return nil
+}
+
+// rotate shifts the rotated files by one, dropping the oldest, and
+// starts a new current file.
+func (f *File) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+
+	for i := f.maxFiles - 1; i >= 0; i-- {
+		if err := os.Rename(f.rotatedPath(i), f.rotatedPath(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	// With no rotated files to keep, the current file is started over.
+	if f.maxFiles <= 0 {
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return f.open()
+}
+
+// rotatedPath returns the path for the rotated file number. The
+// current file is number 0.
+func (f *File) rotatedPath(n int) string {
+	if n == 0 {
+		return f.path
+	}
+	return fmt.Sprintf("%s.%d", f.path, n)
+}
@@ -0,0 +1,72 @@
+package rotate_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/qcbit/blockchain/foundation/rotate"
+)
+
+func Test_Rotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "node.log")
+
+	file, err := rotate.New(path, 10, 2)
+	if err != nil {
+		t.Fatalf("opening file: %v", err)
+	}
+	defer file.Close()
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := file.Write([]byte(line)); err != nil {
+			t.Fatalf("writing: %v", err)
+		}
+	}
+
+	// Only the two most recent rotated files are kept.
+	for path, exp := range map[string]string{path + ".2": "second\n", path + ".1": "third\n", path: "fourth\n"} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("reading %s: %v", path, err)
+		}
+		if string(data) != exp {
+			t.Fatalf("%s should hold %q, got %q", path, exp, data)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("the oldest rotated file should be dropped: %v", err)
+	}
+
+	if got := file.Paths(); len(got) != 3 || got[0] != path+".2" || got[2] != path {
+		t.Fatalf("paths should run from the oldest file to the current file, got %v", got)
+	}
+}
+
+func Test_RotateNoFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	file, err := rotate.New(path, 10, 0)
+	if err != nil {
+		t.Fatalf("opening file: %v", err)
+	}
+	defer file.Close()
+
+	// With no rotated files to keep the file is started over instead of
+	// growing past the max size.
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		if _, err := file.Write([]byte(line)); err != nil {
+			t.Fatalf("writing: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading: %v", err)
+	}
+	if string(data) != "third\n" {
+		t.Fatalf("file should be started over, got %q", data)
+	}
+	if _, err := os.Stat(path + ".1"); !os.IsNotExist(err) {
+		t.Fatalf("no rotated file should be kept: %v", err)
+	}
+}
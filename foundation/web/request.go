@@ -2,6 +2,8 @@ package web
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 
 	"github.com/dimfeld/httptreemux/v5"
@@ -17,6 +19,7 @@ func Param(r *http.Request, key string) string {
 // body is decoded into the provided value.
 //
 // If the provided value is a struct then it is checked for validation tags.
+// Unknown fields and any data following the JSON document are rejected.
 func Decode(r *http.Request, val any) error {
 	decoder := json.NewDecoder(r.Body)
 	decoder.DisallowUnknownFields()
@@ -24,5 +27,11 @@ func Decode(r *http.Request, val any) error {
 		return err
 	}
 
+	// Anything after the document, even a stray closing bracket that
+	// More doesn't report, fails to decode as another value.
+	if err := decoder.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		return errors.New("body must only contain a single JSON document")
+	}
+
 	return nil
 }
@@ -0,0 +1,35 @@
+package web_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/qcbit/blockchain/foundation/web"
+)
+
+func Test_Decode(t *testing.T) {
+	tt := []struct {
+		name string
+		body string
+		ok   bool
+	}{
+		{"document", `{"name":"bill"}`, true},
+		{"trailing space", "{\"name\":\"bill\"}\n", true},
+		{"second document", `{"name":"bill"}{"name":"jill"}`, false},
+		{"trailing brace", `{"name":"bill"}}`, false},
+		{"trailing bracket", `{"name":"bill"}]`, false},
+		{"unknown field", `{"age":10}`, false},
+	}
+
+	for _, tst := range tt {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(tst.body))
+
+		var val struct {
+			Name string `json:"name"`
+		}
+		if err := web.Decode(r, &val); (err == nil) != tst.ok {
+			t.Fatalf("%s: decoding %q: got error %v, exp ok %t", tst.name, tst.body, err, tst.ok)
+		}
+	}
+}
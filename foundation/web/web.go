@@ -23,6 +23,14 @@ type App struct {
 	*httptreemux.ContextMux
 	shutdown chan os.Signal
 	mw       []Middleware
+	routes   []Route
+}
+
+// Route represents a method and path pair registered with the App. The path
+// keeps the :name form of its parameters.
+type Route struct {
+	Method string
+	Path   string
 }
 
 // NewApp creates an App value that handle a set of routes for the application.
@@ -85,4 +93,11 @@ func (a *App) Handle(method string, group string, path string, handler Handler,
 		finalPath = "/" + group + path
 	}
 	a.ContextMux.Handle(method, finalPath, h)
+	a.routes = append(a.routes, Route{Method: method, Path: finalPath})
+}
+
+// Routes returns the routes registered with the App in the order they
+// were registered.
+func (a *App) Routes() []Route {
+	return append([]Route(nil), a.routes...)
 }